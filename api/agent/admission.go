@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// admissionQueue bounds the number of calls in flight on the agent. Once the
+// limit is reached, calls wait in per priority class queues and a finished
+// call hands its place to the oldest waiter of the highest priority class,
+// so that interactive functions can jump ahead of batch invocations.
+type admissionQueue struct {
+	lock         sync.Mutex
+	max          uint64
+	inflight     uint64
+	classes      map[string]int // class name to priority, 0 is the highest
+	defaultClass string
+	waiters      [][]chan struct{}
+}
+
+// newAdmissionQueue returns an admission queue allowing max calls in flight, zero
+// disables queueing altogether. classes are ordered from highest to lowest priority,
+// calls of unknown classes are queued as defaultClass.
+func newAdmissionQueue(max uint64, classes []string, defaultClass string) *admissionQueue {
+	if !hasClass(classes, defaultClass) {
+		classes = append(classes, defaultClass)
+	}
+
+	q := &admissionQueue{
+		max:     max,
+		classes: make(map[string]int, len(classes)),
+		waiters: make([][]chan struct{}, len(classes)),
+	}
+	for i, c := range classes {
+		q.classes[c] = i
+	}
+	q.defaultClass = defaultClass
+	return q
+}
+
+// parsePriorityClasses splits a comma separated list of priority classes
+func parsePriorityClasses(classes string) []string {
+	var res []string
+	for _, c := range strings.Split(classes, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func hasClass(classes []string, class string) bool {
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// className returns class if it is known, the default class otherwise
+func (q *admissionQueue) className(class string) string {
+	if _, ok := q.classes[class]; ok {
+		return class
+	}
+	return q.defaultClass
+}
+
+// admit blocks until the call may proceed or ctx is done. Each successful
+// admit must be followed by a release.
+func (q *admissionQueue) admit(ctx context.Context, class string) error {
+	if q == nil || q.max == 0 {
+		return nil
+	}
+
+	p := q.classes[q.className(class)]
+
+	q.lock.Lock()
+	if q.inflight < q.max {
+		q.inflight++
+		q.lock.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	q.waiters[p] = append(q.waiters[p], ready)
+	q.lock.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.lock.Lock()
+	for i, w := range q.waiters[p] {
+		if w == ready {
+			q.waiters[p] = append(q.waiters[p][:i], q.waiters[p][i+1:]...)
+			q.lock.Unlock()
+			return ctx.Err()
+		}
+	}
+	q.lock.Unlock()
+
+	// lost the race against release, pass our place on
+	q.release()
+	return ctx.Err()
+}
+
+// release hands the place of a finished call to the next waiter, if any
+func (q *admissionQueue) release() {
+	if q == nil || q.max == 0 {
+		return
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for p, waiters := range q.waiters {
+		if len(waiters) > 0 {
+			close(waiters[0])
+			q.waiters[p] = waiters[1:]
+			return
+		}
+	}
+	q.inflight--
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdmissionQueuePriority(t *testing.T) {
+	q := newAdmissionQueue(1, []string{"interactive", "default", "batch"}, "default")
+	ctx := context.Background()
+
+	if err := q.admit(ctx, "batch"); err != nil {
+		t.Fatalf("first call should be admitted immediately: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	order := make(chan string, 2)
+	wait := func(class string) {
+		defer wg.Done()
+		if err := q.admit(ctx, class); err != nil {
+			t.Errorf("call should be admitted: %v", err)
+		}
+		order <- class
+		q.release()
+	}
+
+	wg.Add(2)
+	go wait("batch")
+	time.Sleep(10 * time.Millisecond)
+	go wait("interactive")
+	time.Sleep(10 * time.Millisecond)
+
+	q.release()
+
+	if first := <-order; first != "interactive" {
+		t.Fatalf("interactive call should be admitted first, got %s", first)
+	}
+	if second := <-order; second != "batch" {
+		t.Fatalf("batch call should be admitted second, got %s", second)
+	}
+
+	wg.Wait()
+	if q.inflight != 0 {
+		t.Fatalf("no calls should be in flight, got %d", q.inflight)
+	}
+}
+
+func TestAdmissionQueueTimeout(t *testing.T) {
+	q := newAdmissionQueue(1, []string{"default"}, "default")
+
+	if err := q.admit(context.Background(), "unknown"); err != nil {
+		t.Fatalf("first call should be admitted immediately: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.admit(ctx, "default"); err != context.DeadlineExceeded {
+		t.Fatalf("queued call should time out, got %v", err)
+	}
+
+	q.release()
+	if q.inflight != 0 || len(q.waiters[0]) != 0 {
+		t.Fatalf("queue should be empty, inflight %d waiters %d", q.inflight, len(q.waiters[0]))
+	}
+}
@@ -10,12 +10,15 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"path/filepath"
 
 	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/chaos"
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/common/tracecontext"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/fnext"
@@ -28,7 +31,8 @@ import (
 )
 
 const (
-	pauseTimeout = 5 * time.Second // docker pause/unpause
+	pauseTimeout      = 5 * time.Second  // docker pause/unpause
+	checkpointTimeout = 30 * time.Second // docker checkpoint/restore
 )
 
 // TODO we should prob store async calls in db immediately since we're returning id (will 404 until post-execution)
@@ -99,11 +103,23 @@ type agent struct {
 	callListeners []fnext.CallListener
 
 	driver drivers.Driver
+	// containers run on windows, see pipeIOFS
+	windows bool
 
-	slotMgr *slotQueueMgr
-	evictor Evictor
+	slotMgr   *slotQueueMgr
+	evictor   Evictor
+	admission *admissionQueue
 	// track usage
 	resources ResourceTracker
+	// cores hot containers are pinned to, nil if pinning is disabled
+	cpus *cpuAllocator
+	// persistent volumes of apps
+	volumes *volumeManager
+	// read-only assets distributed to the host, nil if assets are disabled
+	assets *assetStore
+
+	// demand of each fn over a sliding window, nil if disabled
+	fnMetrics *fnMetrics
 
 	// used to track running calls / safe shutdown
 	shutWg              *common.WaitGroup
@@ -111,6 +127,9 @@ type agent struct {
 	disableAsyncDequeue bool
 
 	callOverrider CallOverrider
+	secrets       SecretSource
+	chainReads    ReadDataAccess
+	cancels       *callCancels
 	// deferred actions to call at end of initialisation
 	onStartup []func()
 }
@@ -121,6 +140,9 @@ type Option func(*agent) error
 // RegistryToken is a reserved call extensions key to pass registry token
 const RegistryToken = "FN_REGISTRY_TOKEN"
 
+// LBGroupID is a reserved call extensions key to pass the lb group a call was placed from
+const LBGroupID = "FN_LB_GROUP_ID"
+
 // New creates an Agent that executes functions locally as Docker containers.
 func New(da CallHandler, options ...Option) Agent {
 
@@ -136,7 +158,7 @@ func New(da CallHandler, options ...Option) Agent {
 	a.shutWg = common.NewWaitGroup()
 	a.da = da
 	a.slotMgr = NewSlotQueueMgr()
-	a.evictor = NewEvictor()
+	a.cancels = newCallCancels()
 
 	// Allow overriding config
 	for _, option := range options {
@@ -146,6 +168,14 @@ func New(da CallHandler, options ...Option) Agent {
 		}
 	}
 
+	if rda, ok := da.(ReadDataAccess); ok && a.chainReads == nil {
+		a.chainReads = rda
+	}
+
+	a.evictor = NewEvictorWithPolicy(a.cfg.EvictionPolicy)
+	a.admission = newAdmissionQueue(a.cfg.MaxInflightCalls, parsePriorityClasses(a.cfg.PriorityClasses), a.cfg.DefaultPriorityClass)
+	a.fnMetrics = newFnMetrics(a.cfg.FnMetricsWindow)
+
 	logrus.Infof("agent starting cfg=%+v", a.cfg)
 
 	if a.driver == nil {
@@ -155,9 +185,54 @@ func New(da CallHandler, options ...Option) Agent {
 		}
 		a.driver = d
 	}
+	// validated in NewConfig
+	if faults, _ := chaos.FromSpec(a.cfg.Chaos, a.cfg.EnableChaos); faults != nil {
+		logrus.Warn("injecting faults into the driver, see FN_CHAOS")
+		a.driver = chaos.NewDriver(a.driver, faults)
+	}
+	if r, ok := a.driver.(drivers.OSReporter); ok && r.OSType() == drivers.OSWindows {
+		a.windows = true
+	}
 
 	a.resources = NewResourceTracker(&a.cfg)
 
+	if a.cfg.PinnedCPUs != "" {
+		// validated in NewConfig
+		cores, _ := parseCPUList(a.cfg.PinnedCPUs)
+		a.cpus = newCPUAllocator(cores, readNUMANodes())
+	}
+
+	a.volumes = newVolumeManager(a.cfg.VolumesDir)
+	if a.cfg.VolumesDir != "" && a.shutWg.AddSession(1) {
+		go a.sweepVolumes()
+	}
+
+	if a.cfg.AssetsDir != "" {
+		assets, err := newAssetStore(a.cfg.AssetsDir, a.cfg.AssetsManifestURL)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to create assets dir")
+		}
+		a.assets = assets
+		if a.shutWg.AddSession(1) {
+			go a.syncAssets(a.cfg.AssetsPoll)
+		}
+	}
+
+	if a.cfg.EvictMemPressure > 0 {
+		if !a.shutWg.AddSession(1) {
+			logrus.Fatalf("cannot start agent, unable to add session")
+		}
+		go a.evictUnderPressure()
+	}
+
+	if a.cfg.EvictMemPSI > 0 {
+		if psi, err := newMemPressure(); err != nil {
+			logrus.WithError(err).Warn("cannot evict frozen containers under memory pressure")
+		} else if a.shutWg.AddSession(1) {
+			go a.evictFrozenUnderPressure(psi)
+		}
+	}
+
 	for _, sup := range a.onStartup {
 		sup()
 	}
@@ -224,12 +299,49 @@ func NewDockerDriver(cfg *Config) (drivers.Driver, error) {
 		PreForkCmd:           cfg.PreForkCmd,
 		PreForkUseOnce:       cfg.PreForkUseOnce,
 		PreForkNetworks:      cfg.PreForkNetworks,
+		PreForkPoolMaxSize:   cfg.PreForkPoolMaxSize,
+		PreForkLowWater:      cfg.PreForkLowWater,
+		PreForkHighWater:     cfg.PreForkHighWater,
+		PreForkScaleInterval: cfg.PreForkScaleInterval,
 		MaxTmpFsInodes:       cfg.MaxTmpFsInodes,
 		EnableReadOnlyRootFs: !cfg.DisableReadOnlyRootFs,
 		MaxRetries:           cfg.MaxDockerRetries,
+		CheckpointDir:        cfg.CheckpointDir,
+		InstanceID:           cfg.InstanceID,
+		ReapInterval:         cfg.DockerReapInterval,
+		BlkioDevices:         cfg.BlkioDevices,
+		DNS:                  cfg.DockerDNS,
+		DNSSearch:            cfg.DockerDNSSearch,
+		ExtraHosts:           cfg.DockerExtraHosts,
+		HTTPProxy:            cfg.HTTPProxy,
+		HTTPSProxy:           cfg.HTTPSProxy,
+		NoProxy:              cfg.NoProxy,
+		User:                 cfg.DockerUser,
+		RequireNonRoot:       cfg.DockerRequireNonRoot,
+		CapDrop:              cfg.DockerCapDrop,
+		CapAdd:               cfg.DockerCapAdd,
+		CapAllowed:           cfg.DockerCapAllowed,
+		NoNewPrivileges:      !cfg.DockerAllowNewPrivs,
 	})
 }
 
+// PreForkTuner is implemented by agents whose driver has a pool of pre-forked
+// containers, see drivers.PreForkTuner
+type PreForkTuner interface {
+	// SetPreForkWaterMarks changes the number of free containers below which
+	// the pool grows and above which it shrinks
+	SetPreForkWaterMarks(low, high uint64) error
+}
+
+// SetPreForkWaterMarks implements PreForkTuner
+func (a *agent) SetPreForkWaterMarks(low, high uint64) error {
+	t, ok := a.driver.(drivers.PreForkTuner)
+	if !ok {
+		return errors.New("the driver of the agent has no pre-fork pool")
+	}
+	return t.SetPreForkWaterMarks(low, high)
+}
+
 func (a *agent) Close() error {
 	var err error
 
@@ -259,6 +371,9 @@ func (a *agent) Submit(callI Call) error {
 	}
 	defer a.shutWg.DoneSession()
 
+	ctx, untrack := a.cancels.track(ctx, call)
+	defer untrack()
+
 	err := a.submit(ctx, call)
 	return err
 }
@@ -276,11 +391,32 @@ func (a *agent) submit(ctx context.Context, call *call) error {
 
 	a.startStateTrackers(ctx, call)
 	defer a.endStateTrackers(ctx, call)
+	defer a.fnMetrics.start(call.FnID)()
+
+	err := a.admit(ctx, call)
+	if err != nil {
+		return a.handleCallEnd(ctx, call, nil, err, false)
+	}
+	defer a.admission.release()
+	call.addTiming(ctx, models.PhaseQueueWait, time.Since(time.Time(call.CreatedAt)))
 
+	placeStart := time.Now()
 	slot, err := a.getSlot(ctx, call)
 	if err != nil {
 		return a.handleCallEnd(ctx, call, slot, err, false)
 	}
+	placement := time.Since(placeStart)
+
+	// the first call a container serves waited for it to start
+	if s, ok := slot.(*hotSlot); ok && s.container != nil {
+		if pull, create, ok := s.container.claimColdStart(); ok {
+			call.addTiming(ctx, models.PhaseImagePull, pull)
+			call.addTiming(ctx, models.PhaseContainerCreate, create)
+			placement -= pull + create
+		}
+		call.SetResolvedImage(s.container.imageDigest, s.container.platform)
+	}
+	call.addTiming(ctx, models.PhasePlacementWait, placement)
 
 	err = call.Start(ctx)
 	if err != nil {
@@ -296,10 +432,15 @@ func (a *agent) submit(ctx context.Context, call *call) error {
 
 	// Pass this error (nil or otherwise) to end directly, to store status, etc.
 	err = slot.exec(slotCtx, call)
-	return a.handleCallEnd(ctx, call, slot, err, true)
+	err = a.handleCallEnd(ctx, call, slot, err, true)
+	if err == nil {
+		a.chain(ctx, call)
+	}
+	return err
 }
 
 func (a *agent) handleCallEnd(ctx context.Context, call *call, slot Slot, err error, isStarted bool) error {
+	ctx, err = cancelledError(ctx, call, err)
 
 	if slot != nil {
 		slot.Close()
@@ -323,7 +464,7 @@ func (a *agent) handleCallEnd(ctx context.Context, call *call, slot Slot, err er
 		}
 	}
 
-	if err == context.Canceled {
+	if err == context.Canceled || err == models.ErrCallCancelled {
 		statsCanceled(ctx)
 	} else if err != nil {
 		statsErrors(ctx)
@@ -331,6 +472,29 @@ func (a *agent) handleCallEnd(ctx context.Context, call *call, slot Slot, err er
 	return err
 }
 
+// admit waits for the call to be admitted by the admission queue, which only
+// blocks if the agent is at its limit of calls in flight.
+func (a *agent) admit(ctx context.Context, call *call) error {
+	if call.Type == models.TypeAsync {
+		// as for getSlot, bound the wait of async calls
+		tmp, cancel := context.WithTimeout(ctx, time.Duration(call.Timeout)*time.Second)
+		ctx = tmp
+		defer cancel()
+	}
+
+	if a.admission == nil || a.cfg.MaxInflightCalls == 0 {
+		return nil
+	}
+
+	// validated on app update, an invalid annotation here uses the default class
+	class, _ := models.PriorityClassFromAnnotations(call.Annotations)
+
+	start := time.Now()
+	err := a.admission.admit(ctx, class)
+	statsAdmissionWait(ctx, time.Since(start), a.admission.className(class))
+	return err
+}
+
 // getSlot returns a Slot (or error) for the request to run. This will wait
 // for other containers to become idle or it may wait for resources to become
 // available to launch a new container.
@@ -421,6 +585,76 @@ func (a *agent) hotLauncher(ctx context.Context, call *call, caller *slotCaller)
 	}
 }
 
+// evictUnderPressure polls reserved memory and evicts idle containers, least
+// recently used first, while usage is above the configured pressure threshold.
+func (a *agent) evictUnderPressure() {
+	defer a.shutWg.DoneSession()
+
+	ticker := time.NewTicker(a.cfg.HotPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.shutWg.Closer(): // server shutdown
+			return
+		}
+
+		util := a.resources.GetUtilization()
+		limit := (util.MemUsed + util.MemAvail) / 100 * a.cfg.EvictMemPressure
+		if util.MemUsed <= limit {
+			continue
+		}
+
+		// evictor tracks memory in MB
+		need := (util.MemUsed - limit + Mem1MB - 1) / Mem1MB
+		for _, wait := range a.evictor.EvictIdle(need) {
+			select {
+			case <-wait:
+			case <-a.shutWg.Closer(): // server shutdown
+				return
+			}
+		}
+	}
+}
+
+// evictFrozenUnderPressure samples memory pressure and evicts the least
+// recently used frozen container while tasks stall on memory for more than
+// the configured share of time. Reservations may fit while actual usage does
+// not, eg. when memory is overcommitted.
+func (a *agent) evictFrozenUnderPressure(psi *memPressure) {
+	defer a.shutWg.DoneSession()
+
+	ticker := time.NewTicker(psiPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.shutWg.Closer(): // server shutdown
+			return
+		}
+
+		stalled, err := psi.sample()
+		if err != nil {
+			logrus.WithError(err).Error("error reading memory pressure")
+			continue
+		}
+		if stalled < a.cfg.EvictMemPSI {
+			continue
+		}
+
+		// evictor tracks memory in MB, any frozen container will do
+		for _, wait := range a.evictor.EvictFrozen(1) {
+			select {
+			case <-wait:
+			case <-a.shutWg.Closer(): // server shutdown
+				return
+			}
+		}
+	}
+}
+
 func tryNotify(notifyChan chan error, err error) {
 	if notifyChan != nil && err != nil {
 		select {
@@ -437,7 +671,7 @@ func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller)
 		return
 	}
 
-	state := NewContainerState()
+	state := NewWeightedContainerState(call.concurrency)
 	state.UpdateState(ctx, ContainerStateWait, call.slots)
 
 	mem := call.Memory + uint64(call.TmpFsSize)
@@ -463,14 +697,14 @@ func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller)
 	// Non-blocking mode only applies to cpu+mem, and if isNewContainerNeeded decided that we do not
 	// need to start a new container, then waiters will wait.
 	select {
-	case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, isNB):
+	case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, call.overcommit, isNB):
 	case <-time.After(a.cfg.HotPoll):
 		// Request routines are polling us with this a.cfg.HotPoll frequency. We can use this
 		// same timer to assume that we waited for cpu/mem long enough. Let's try to evict an
 		// idle container. We do this by submitting a non-blocking request and evicting required
 		// amount of resources.
 		select {
-		case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, true):
+		case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, call.overcommit, true):
 		case <-ctx.Done(): // timeout
 		case <-a.shutWg.Closer(): // server shutdown
 		}
@@ -484,7 +718,7 @@ func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller)
 				tryNotify(caller.notify, tok.Error())
 			} else {
 				needMem, needCpu := tok.NeededCapacity()
-				notifyChans = a.evictor.PerformEviction(call.slotHashId, needMem, uint64(needCpu))
+				notifyChans = a.evictor.PerformEviction(call.slotHashId, call.priority(), needMem, uint64(needCpu))
 				// For Non-blocking mode, if there's nothing to evict, we emit 503.
 				if len(notifyChans) == 0 && isNB {
 					tryNotify(caller.notify, models.ErrCallTimeoutServerBusy)
@@ -638,11 +872,19 @@ func createUDSRequest(ctx context.Context, call *call) *http.Request {
 	}
 
 	req.Header.Set("Fn-Call-Id", call.ID)
+	if call.RequestID != "" {
+		req.Header.Set(common.RequestIDHeader, call.RequestID)
+	}
 	deadline, ok := ctx.Deadline()
 	if ok {
 		deadlineStr := deadline.Format(time.RFC3339)
 		req.Header.Set("Fn-Deadline", deadlineStr)
 	}
+	// fns continue the trace of the call with the span dispatching it as their
+	// parent. containers serve many calls, the trace of each comes with it.
+	if span := trace.FromContext(ctx); span != nil {
+		tracecontext.Inject(span.SpanContext(), req.Header)
+	}
 
 	return req
 }
@@ -653,13 +895,35 @@ func (s *hotSlot) dispatch(ctx context.Context, call *call) error {
 
 	// TODO it's possible we can get rid of this (after getting rid of logs API) - may need for call id/debug mode still
 	// TODO there's a timeout race for swapping this back if the container doesn't get killed for timing out, and don't you forget it
-	swapBack := s.container.swap(call.stderr, &call.Stats)
-	defer swapBack()
+	// calls of a container serving several calls at once share its stderr and
+	// stats, see callsWriter
+	if s.container.calls != nil {
+		detach := s.container.calls.attach(newCallLogWriter(ctx, s.cfg.MaxCallLogSize, call.stderr), &call.Stats)
+		defer detach()
+	} else {
+		swapBack := s.container.swap(newCallLogWriter(ctx, s.cfg.MaxCallLogSize, call.stderr), &call.Stats)
+		defer swapBack()
+	}
 
-	resp, err := s.container.udsClient.Do(createUDSRequest(ctx, call))
+	if call.webSocket {
+		return s.dispatchWebSocket(ctx, call)
+	}
+
+	// the fn is a child of the exec span, which only covers the round trip
+	// to the container and not writing the response back
+	execCtx, execSpan := trace.StartSpan(ctx, "exec", trace.WithSpanKind(trace.SpanKindClient))
+	execSpan.AddAttributes(trace.StringAttribute("container_id", s.container.id))
+	execStart := time.Now()
+	resp, err := s.container.udsClient.Do(createUDSRequest(execCtx, call))
+	call.addTiming(ctx, models.PhaseExecution, time.Since(execStart))
+	endSpan(execSpan, err)
 	if err != nil {
 		// IMPORTANT: Container contract: If http-uds errors/timeout, container cannot continue
 		s.trySetError(err)
+		// the request was cut off for being over the limit of the fn
+		if call.reqBody.tooBig() {
+			return models.ErrRequestContentTooBig
+		}
 		// first filter out timeouts
 		if ctx.Err() == context.DeadlineExceeded {
 			return context.DeadlineExceeded
@@ -670,17 +934,24 @@ func (s *hotSlot) dispatch(ctx context.Context, call *call) error {
 
 	common.Logger(ctx).WithField("resp", resp).Debug("Got resp from UDS socket")
 
+	writeStart := time.Now()
 	ioErrChan := make(chan error, 1)
 	go func() {
-		ioErrChan <- s.writeResp(ctx, s.cfg.MaxResponseSize, resp, call.respWriter)
+		if err := captureNext(call, resp); err != nil {
+			ioErrChan <- err
+			return
+		}
+		ioErrChan <- s.writeResp(ctx, call.maxResponseSize, resp, call.respWriter)
 	}()
 
 	select {
 	case ioErr := <-ioErrChan:
+		call.addTiming(ctx, models.PhaseResponseWrite, time.Since(writeStart))
 		return ioErr
 	case <-ctx.Done():
-		if ctx.Err() == context.DeadlineExceeded {
-			// IMPORTANT: Container contract: If http-uds timeout, container cannot continue
+		if ctx.Err() == context.DeadlineExceeded || call.isCancelled() {
+			// IMPORTANT: Container contract: If http-uds timeout, container cannot continue.
+			// A cancelled call may still be running in the container, which is stopped.
 			s.trySetError(ctx.Err())
 		}
 		return ctx.Err()
@@ -758,6 +1029,14 @@ func tryQueueErr(err error, ch chan error) error {
 	return err
 }
 
+// endSpan ends span, marking it failed with err if any
+func endSpan(span *trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	span.End()
+}
+
 func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok ResourceToken, state ContainerState) {
 	// IMPORTANT: get a context that has a child span / logger but NO timeout
 	// TODO this is a 'FollowsFrom'
@@ -770,14 +1049,14 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 	var err error
 
 	id := id.New().String()
-	logger := logrus.WithFields(logrus.Fields{"id": id, "app_id": call.AppID, "fn_id": call.FnID, "image": call.Image, "memory": call.Memory, "cpus": call.CPUs, "idle_timeout": call.IdleTimeout})
+	logger := common.ComponentLogger(context.Background(), common.LogComponentAgent).WithFields(logrus.Fields{"id": id, common.FieldAppID: call.AppID, common.FieldFnID: call.FnID, "image": call.Image, "memory": call.Memory, "cpus": call.CPUs, "idle_timeout": call.IdleTimeout})
 	ctx, cancel := context.WithCancel(common.WithLogger(ctx, logger))
 
 	initialized := make(chan struct{}) // when closed, container is ready to handle requests
 	udsWait := make(chan error, 1)     // track UDS state and errors
 	errQueue := make(chan error, 1)    // errors to be reflected back to the slot queue
 
-	evictor := a.evictor.CreateEvictToken(call.slotHashId, call.Memory+uint64(call.TmpFsSize), uint64(call.CPUs))
+	evictor := a.evictor.CreateEvictToken(call.slotHashId, call.priority(), call.Memory+uint64(call.TmpFsSize), uint64(call.CPUs))
 
 	statsUtilization(ctx, a.resources.GetUtilization())
 	state.UpdateState(ctx, ContainerStateStart, call.slots)
@@ -810,6 +1089,9 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 			container.Close()
 		}
 
+		a.cpus.release(id)
+		a.volumes.release(id)
+
 		lastState := state.GetState()
 		state.UpdateState(ctx, ContainerStateDone, call.slots)
 
@@ -859,6 +1141,35 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 		return
 	}
 
+	if call.cpuPinning && a.cpus != nil {
+		if set, ok := a.cpus.allocate(id, coresFor(uint64(call.CPUs))); ok {
+			container.cpuSet = set
+		} else {
+			logger.Warn("no free cores to pin the container to, running it unpinned")
+		}
+	}
+
+	if len(call.volumes) > 0 {
+		mounts, err := a.volumes.mount(id, call.AppID, call.volumes)
+		if tryQueueErr(err, errQueue) != nil {
+			return
+		}
+		container.volumes = append(container.volumes, mounts...)
+	}
+
+	for name, dest := range call.assets {
+		blob, err := a.assets.path(name)
+		if tryQueueErr(err, errQueue) != nil {
+			return
+		}
+		container.volumes = append(container.volumes, [2]string{blob, filepath.Clean(dest) + ":ro"})
+	}
+
+	err = a.injectSecrets(ctx, call, container)
+	if tryQueueErr(err, errQueue) != nil {
+		return
+	}
+
 	cookie, err = a.driver.CreateCookie(ctx, container)
 	if tryQueueErr(err, errQueue) != nil {
 		return
@@ -870,24 +1181,40 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 	}
 
 	if needsPull {
+		ctx, span := trace.StartSpan(ctx, "docker_pull")
 		ctx, cancel := context.WithTimeout(ctx, a.cfg.HotPullTimeout)
+		pullStart := time.Now()
 		err = cookie.PullImage(ctx)
+		container.pullTime = time.Since(pullStart)
 		cancel()
 		if ctx.Err() == context.DeadlineExceeded {
 			err = models.ErrDockerPullTimeout
 		}
+		endSpan(span, err)
 		if tryQueueErr(err, errQueue) != nil {
 			return
 		}
 	}
 
-	err = cookie.CreateContainer(ctx)
+	if r, ok := cookie.(drivers.ImageResolver); ok {
+		container.imageDigest, container.platform = r.ResolvedImage()
+	}
+
+	// docker_create spans creating and starting the container until it is
+	// ready for calls, it ends with the container if that never happens.
+	createCtx, createSpan := trace.StartSpan(ctx, "docker_create")
+	defer createSpan.End()
+
+	createStart := time.Now()
+	err = cookie.CreateContainer(createCtx)
 	if tryQueueErr(err, errQueue) != nil {
+		endSpan(createSpan, err)
 		return
 	}
 
 	waiter, err := cookie.Run(ctx)
 	if tryQueueErr(err, errQueue) != nil {
+		endSpan(createSpan, err)
 		return
 	}
 
@@ -899,11 +1226,17 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 		// Notice how we do not distinguish between agent-shutdown, eviction, ctx.Done, etc. This is
 		// because monitoring go-routine may pick these events earlier and cancel the ctx.
 		initStart := time.Now()
+		initTimeout := a.cfg.HotStartTimeout
+		if call.InitTimeout > 0 {
+			initTimeout = time.Duration(call.InitTimeout) * time.Second
+		}
 
 		// INIT BARRIER HERE. Wait for the initialization go-routine signal
 		select {
 		case <-initialized:
 			statsContainerUDSInitLatency(ctx, initStart, time.Now(), "initialized")
+			container.createTime = time.Since(createStart)
+			createSpan.End()
 		case <-a.shutWg.Closer(): // agent shutdown
 			statsContainerUDSInitLatency(ctx, initStart, time.Now(), "canceled")
 			return
@@ -913,42 +1246,30 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 		case <-evictor.C: // eviction
 			statsContainerUDSInitLatency(ctx, initStart, time.Now(), "canceled")
 			return
-		case <-time.After(a.cfg.HotStartTimeout):
+		case <-time.After(initTimeout):
 			statsContainerUDSInitLatency(ctx, initStart, time.Now(), "timedout")
 			tryQueueErr(models.ErrContainerInitTimeout, errQueue)
 			return
 		}
 
-		for {
-			// Below we are rather defensive and poll on evictor/ctx
-			// to reduce the likelyhood of attempting to queue a hotSlot when these
-			// two cases occur.
-			select {
-			case <-ctx.Done():
-				return
-			case <-evictor.C: // eviction
-				return
-			default:
-			}
-
-			slot := &hotSlot{
-				done:          make(chan struct{}),
-				container:     container,
-				cfg:           &a.cfg,
-				containerSpan: trace.FromContext(ctx).SpanContext(),
-			}
-			if !a.runHotReq(ctx, call, state, logger, cookie, slot, evictor) {
-				return
-			}
-			// wait for this call to finish
-			// NOTE do NOT select with shutdown / other channels. slot handles this.
-			<-slot.done
+		if call.concurrency <= 1 {
+			a.serveHot(ctx, cancel, call, state, logger, cookie, container, evictor)
+			return
+		}
 
-			if slot.fatalErr != nil {
-				logger.WithError(slot.fatalErr).Info("hot function terminating")
-				return
-			}
+		// Each worker offers a slot of its own, the container shuts down once
+		// all of its workers have exited or any of them hit a fatal error.
+		workers := newContainerWorkers(state, evictor)
+		var wg sync.WaitGroup
+		for i := uint64(0); i < call.concurrency; i++ {
+			wg.Add(1)
+			go func(state *workerState) {
+				defer wg.Done()
+				defer state.UpdateState(ctx, ContainerStateDone, call.slots)
+				a.serveHot(ctx, cancel, call, state, logger, cookie, container, evictor)
+			}(workers.newWorker())
 		}
+		wg.Wait()
 	}()
 
 	runRes := waiter.Wait(ctx)
@@ -957,7 +1278,44 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 	}
 }
 
-//checkSocketDestination verifies that the socket file created by the FDK is valid and permitted - notably verifying that any symlinks are relative to the socket dir
+// serveHot queues slots of the container one at a time until the container is
+// shut down, idles out or a call fails fatally.
+func (a *agent) serveHot(ctx context.Context, cancel context.CancelFunc, call *call, state ContainerState, logger logrus.FieldLogger, cookie drivers.Cookie, container *container, evictor *EvictToken) {
+	for {
+		// Below we are rather defensive and poll on evictor/ctx
+		// to reduce the likelyhood of attempting to queue a hotSlot when these
+		// two cases occur.
+		select {
+		case <-ctx.Done():
+			return
+		case <-evictor.C: // eviction
+			return
+		default:
+		}
+
+		slot := &hotSlot{
+			done:          make(chan struct{}),
+			container:     container,
+			cfg:           &a.cfg,
+			containerSpan: trace.FromContext(ctx).SpanContext(),
+		}
+		if !a.runHotReq(ctx, call, state, logger, cookie, slot, evictor) {
+			return
+		}
+		// wait for this call to finish
+		// NOTE do NOT select with shutdown / other channels. slot handles this.
+		<-slot.done
+
+		if slot.fatalErr != nil {
+			logger.WithError(slot.fatalErr).Info("hot function terminating")
+			// other workers may still be serving calls, stop them too
+			cancel()
+			return
+		}
+	}
+}
+
+// checkSocketDestination verifies that the socket file created by the FDK is valid and permitted - notably verifying that any symlinks are relative to the socket dir
 func checkSocketDestination(filename string) error {
 	finfo, err := os.Lstat(filename)
 	if err != nil {
@@ -1057,21 +1415,59 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 
 	var err error
 	isFrozen := false
+	isCheckpointed := false
+
+	// a worker of a multiplexed container is evictable only along with its
+	// siblings and must not freeze or checkpoint calls of other workers
+	setEvictable := evictor.SetEvictable
+	worker, isWorker := state.(*workerState)
+	if isWorker {
+		setEvictable = worker.SetEvictable
+	}
 
+	idleTimeout := time.Duration(call.IdleTimeout) * time.Second
 	freezeTimer := time.NewTimer(a.cfg.FreezeIdle)
-	idleTimer := time.NewTimer(time.Duration(call.IdleTimeout) * time.Second)
+	freezeC := freezeTimer.C
+	// containers idle for as long as the freeze delay are shut down instead
+	if isWorker || a.cfg.FreezeIdle >= idleTimeout {
+		freezeC = nil
+	}
+	idleTimer := time.NewTimer(idleTimeout)
+
+	// started once the container is paused, nil channel never fires
+	var pausedTimer *time.Timer
+	var pausedC <-chan time.Time
+	startPausedTTL := func() {
+		if pausedTimer == nil && call.pausedTTL > 0 {
+			pausedTimer = time.NewTimer(call.pausedTTL)
+			pausedC = pausedTimer.C
+		}
+	}
+
+	// checkpoints are optional, nil channel never fires
+	var checkpointC <-chan time.Time
+	checkpointer, canCheckpoint := cookie.(drivers.Checkpointer)
+	if canCheckpoint && a.cfg.CheckpointIdle > 0 && !isWorker {
+		checkpointTimer := time.NewTimer(a.cfg.CheckpointIdle)
+		defer checkpointTimer.Stop()
+		checkpointC = checkpointTimer.C
+	}
 
 	defer func() {
-		evictor.SetEvictable(false)
+		setEvictable(false)
+		evictor.SetFrozen(false)
 		freezeTimer.Stop()
 		idleTimer.Stop()
+		if pausedTimer != nil {
+			pausedTimer.Stop()
+		}
 		// log if any error is encountered
 		if err != nil {
 			logger.WithError(err).Error("hot function failure")
 		}
 	}()
 
-	evictor.SetEvictable(true)
+	setEvictable(true)
 	state.UpdateState(ctx, ContainerStateIdle, call.slots)
 
 	s := call.slots.queueSlot(slot)
@@ -1082,7 +1478,7 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 		case <-ctx.Done(): // container shutdown
 		case <-a.shutWg.Closer(): // agent shutdown
 		case <-idleTimer.C:
-		case <-freezeTimer.C:
+		case <-freezeC:
 			if !isFrozen {
 				ctx, cancel := context.WithTimeout(ctx, pauseTimeout)
 				err = cookie.Freeze(ctx)
@@ -1091,15 +1487,40 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 					return false
 				}
 				isFrozen = true
+				evictor.SetFrozen(true)
 				state.UpdateState(ctx, ContainerStatePaused, call.slots)
+				startPausedTTL()
 			}
 			continue
+		case <-checkpointC:
+			// CRIU cannot dump a frozen cgroup, thaw first
+			if isFrozen {
+				ctx, cancel := context.WithTimeout(ctx, pauseTimeout)
+				err = cookie.Unfreeze(ctx)
+				cancel()
+				if err != nil {
+					return false
+				}
+				isFrozen = false
+				evictor.SetFrozen(false)
+			}
+			ctx, cancel := context.WithTimeout(ctx, checkpointTimeout)
+			err = checkpointer.Checkpoint(ctx)
+			cancel()
+			if err != nil {
+				return false
+			}
+			isCheckpointed = true
+			state.UpdateState(ctx, ContainerStatePaused, call.slots)
+			startPausedTTL()
+			continue
+		case <-pausedC: // paused for too long
 		case <-evictor.C:
 		}
 		break
 	}
 
-	evictor.SetEvictable(false)
+	setEvictable(false)
 
 	// if we can acquire token, that means we are here due to
 	// abort/shutdown/timeout, attempt to acquire and terminate,
@@ -1110,7 +1531,16 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 	}
 
 	// In case, timer/acquireSlot failure landed us here, make
-	// sure to unfreeze.
+	// sure to restore/unfreeze.
+	if isCheckpointed {
+		ctx, cancel := context.WithTimeout(ctx, checkpointTimeout)
+		err = checkpointer.Restore(ctx)
+		cancel()
+		if err != nil {
+			return false
+		}
+		isCheckpointed = false
+	}
 	if isFrozen {
 		ctx, cancel := context.WithTimeout(ctx, pauseTimeout)
 		err = cookie.Unfreeze(ctx)
@@ -1134,14 +1564,40 @@ type container struct {
 	image      string
 	env        map[string]string
 	extensions map[string]string
+	labels     map[string]string
 	memory     uint64
 	cpus       uint64
 	fsSize     uint64
 	tmpFsSize  uint64
 	iofs       iofs
+	udsDest    string
+	volumes    [][2]string
 	logCfg     drivers.LoggerConfig
 	close      func()
 
+	// concurrency is the number of calls multiplexed over udsClient
+	concurrency uint64
+	// calls are the calls in flight of a container serving several calls at
+	// once, nil if it serves one at a time
+	calls *callsWriter
+
+	// cores the container is pinned to, nil if it is not
+	cpuSet *cpuSet
+	// disk I/O limit, nil if unlimited
+	ioLimit *models.IOLimit
+	// egress bandwidth limit in bytes per second, 0 if unlimited
+	egressLimit uint64
+	// name resolution of the app, nil for the one of the agent
+	dns *models.DNSConfig
+	// proxies of the app, nil for the ones of the agent
+	proxy *models.ProxyConfig
+	// user of the app, empty for the one of the agent
+	user string
+	// capabilities the app adds and drops, nil if none
+	caps *models.Capabilities
+	// containers run with the container, nil if none
+	sidecar *models.SidecarConfig
+
 	stderr io.Writer
 
 	udsClient http.Client
@@ -1149,6 +1605,25 @@ type container struct {
 	// swapMu protects the stats swapping
 	swapMu sync.Mutex
 	stats  *drivers.Stats
+
+	// time it took to pull the image and to create the container until it
+	// was ready, claimed by the first call it serves, see claimColdStart
+	pullTime    time.Duration
+	createTime  time.Duration
+	coldClaimed int32
+
+	// digest and platform the image resolved to, recorded on every call
+	imageDigest string
+	platform    string
+}
+
+// claimColdStart returns the time it took to start the container to the first
+// call that claims it
+func (c *container) claimColdStart() (pull, create time.Duration, ok bool) {
+	if !atomic.CompareAndSwapInt32(&c.coldClaimed, 0, 1) {
+		return 0, 0, false
+	}
+	return c.pullTime, c.createTime, true
 }
 
 // newHotContainer creates a container that can be used for multiple sequential events
@@ -1159,7 +1634,11 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 
 	logger := common.Logger(ctx)
 
-	if cfg.IOFSEnableTmpfs {
+	udsDest, dial := iofsDockerMountDest, dialUDS
+	if call.npipe {
+		udsDest, dial = pipeDockerDest, dialPipe
+		iofs = newPipeIOFS()
+	} else if cfg.IOFSEnableTmpfs {
 		iofs, err = newTmpfsIOFS(ctx, cfg)
 	} else {
 		iofs, err = newDirectoryIOFS(ctx, cfg)
@@ -1169,7 +1648,11 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 		return nil
 	}
 
-	inotifyAwait(ctx, iofs.AgentPath(), udsWait)
+	if call.npipe {
+		pipeAwait(ctx, iofs.AgentPath(), udsWait)
+	} else {
+		inotifyAwait(ctx, iofs.AgentPath(), udsWait)
+	}
 
 	// IMPORTANT: we are not operating on a TTY allocated container. This means, stderr and stdout are multiplexed
 	// from the same stream internally via docker using a multiplexing protocol. Therefore, stderr/stdout *BOTH*
@@ -1197,33 +1680,105 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 		bufs = append(bufs, buf1)
 	}
 
+	labels := map[string]string{
+		drivers.LabelAppID:   call.AppID,
+		drivers.LabelFnID:    call.FnID,
+		drivers.LabelCallID:  call.ID,
+		drivers.LabelLBGroup: call.extensions[LBGroupID],
+	}
+
+	concurrency := call.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var calls *callsWriter
+	if concurrency > 1 {
+		calls = newCallsWriter()
+		if gw, ok := stderr.(common.GhostWriter); ok {
+			calls.idle = gw.Swap(calls)
+		}
+	}
+
+	// validated on app update, an invalid annotation here falls back to defaults
+	logDriver, logOpts, err := models.LogDriverFromAnnotations(call.Annotations)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Warn("ignoring invalid log driver annotations")
+	}
+
+	// validated on app update, an invalid annotation here falls back to the agent resolution
+	dns, err := models.DNSFromAnnotations(call.Annotations)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Warn("ignoring invalid dns annotation")
+	}
+
+	// validated on app update, an invalid annotation here falls back to the agent proxies
+	proxy, err := models.ProxyFromAnnotations(call.Annotations)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Warn("ignoring invalid proxy annotation")
+	}
+
+	// validated on app update, an invalid annotation here falls back to the agent user
+	user, err := models.UserFromAnnotations(call.Annotations)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Warn("ignoring invalid user annotation")
+	}
+
+	// validated on app update, an invalid annotation here runs with the agent capabilities
+	caps, err := models.CapabilitiesFromAnnotations(call.Annotations)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Warn("ignoring invalid capabilities annotation")
+	}
+
+	// validated on app update, an invalid annotation here runs no companions
+	sidecar, err := models.SidecarFromAnnotations(call.Annotations)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Warn("ignoring invalid sidecar annotation")
+	}
+
+	logTags := []drivers.LoggerTag{
+		{Name: "app_id", Value: call.AppID},
+		{Name: "fn_id", Value: call.FnID},
+	}
+	if call.NamespaceID != "" {
+		logTags = append(logTags, drivers.LoggerTag{Name: "namespace_id", Value: call.NamespaceID})
+	}
+
 	return &container{
 		id:         id, // XXX we could just let docker generate ids...
 		image:      call.Image,
 		env:        map[string]string(call.Config),
 		extensions: call.extensions,
+		labels:     labels,
 		memory:     call.Memory,
 		cpus:       uint64(call.CPUs),
 		fsSize:     cfg.MaxFsSize,
 		tmpFsSize:  uint64(call.TmpFsSize),
 		iofs:       iofs,
+		udsDest:    udsDest,
 		logCfg: drivers.LoggerConfig{
-			URL: strings.TrimSpace(call.SyslogURL),
-			Tags: []drivers.LoggerTag{
-				{Name: "app_id", Value: call.AppID},
-				{Name: "fn_id", Value: call.FnID},
-			},
+			URL:     strings.TrimSpace(call.SyslogURL),
+			Tags:    logTags,
+			Driver:  logDriver,
+			Options: logOpts,
 		},
-		stderr: stderr,
+		stderr:      stderr,
+		concurrency: concurrency,
+		calls:       calls,
+		ioLimit:     call.ioLimit,
+		egressLimit: call.egressLimit,
+		dns:         dns,
+		proxy:       proxy,
+		user:        user,
+		caps:        caps,
+		sidecar:     sidecar,
 		udsClient: http.Client{
 			Transport: &http.Transport{
-				MaxIdleConns:        1,
-				MaxIdleConnsPerHost: 1,
+				MaxIdleConns:        int(concurrency),
+				MaxIdleConnsPerHost: int(concurrency),
 				// XXX(reed): other settings ?
 				IdleConnTimeout: 1 * time.Second,
 				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-					var d net.Dialer
-					return d.DialContext(ctx, "unix", filepath.Join(iofs.AgentPath(), udsFilename))
+					return dial(ctx, iofs.AgentPath())
 				},
 			},
 		},
@@ -1265,7 +1820,7 @@ func (c *container) Id() string                         { return c.id }
 func (c *container) Command() string                    { return "" }
 func (c *container) Input() io.Reader                   { return common.NoopReadWriteCloser{} }
 func (c *container) Logger() (io.Writer, io.Writer)     { return c.stderr, c.stderr }
-func (c *container) Volumes() [][2]string               { return nil }
+func (c *container) Volumes() [][2]string               { return c.volumes }
 func (c *container) WorkDir() string                    { return "" }
 func (c *container) Close()                             { c.close() }
 func (c *container) Image() string                      { return c.image }
@@ -1276,10 +1831,72 @@ func (c *container) CPUs() uint64                       { return c.cpus }
 func (c *container) FsSize() uint64                     { return c.fsSize }
 func (c *container) TmpFsSize() uint64                  { return c.tmpFsSize }
 func (c *container) Extensions() map[string]string      { return c.extensions }
+func (c *container) Labels() map[string]string          { return c.labels }
 func (c *container) LoggerConfig() drivers.LoggerConfig { return c.logCfg }
 func (c *container) UDSAgentPath() string               { return c.iofs.AgentPath() }
 func (c *container) UDSDockerPath() string              { return c.iofs.DockerPath() }
-func (c *container) UDSDockerDest() string              { return iofsDockerMountDest }
+func (c *container) UDSDockerDest() string              { return c.udsDest }
+
+// CPUSet returns the cores and NUMA nodes the container is pinned to
+func (c *container) CPUSet() (cpus, mems string) {
+	if c.cpuSet == nil {
+		return "", ""
+	}
+	return formatCPUList(c.cpuSet.cpus), formatCPUList(c.cpuSet.mems)
+}
+
+// IOLimits returns the disk I/O limits of the container, zero if unlimited
+func (c *container) IOLimits() (readBps, writeBps, readIOPS, writeIOPS uint64) {
+	if c.ioLimit == nil {
+		return 0, 0, 0, 0
+	}
+	return c.ioLimit.ReadBps, c.ioLimit.WriteBps, c.ioLimit.ReadIOPS, c.ioLimit.WriteIOPS
+}
+
+// EgressLimit returns the egress bandwidth limit of the container
+func (c *container) EgressLimit() uint64 { return c.egressLimit }
+
+// DNS returns the name resolution of the app of the container
+func (c *container) DNS() (servers, search, extraHosts []string) {
+	if c.dns == nil {
+		return nil, nil, nil
+	}
+	return c.dns.Servers, c.dns.Search, c.dns.ExtraHosts
+}
+
+// Proxy returns the proxies of the app of the container, if it sets them
+func (c *container) Proxy() (httpProxy, httpsProxy, noProxy string, ok bool) {
+	if c.proxy == nil {
+		return "", "", "", false
+	}
+	return c.proxy.HTTPProxy, c.proxy.HTTPSProxy, c.proxy.NoProxy, true
+}
+
+// User returns the user the app of the container runs as, if it sets one
+func (c *container) User() string { return c.user }
+
+// Capabilities returns the capabilities the app of the container adds and drops
+func (c *container) Capabilities() (add, drop []string) {
+	if c.caps == nil {
+		return nil, nil
+	}
+	return c.caps.Add, c.caps.Drop
+}
+
+// Companions returns the init and sidecar containers of the app of the container
+func (c *container) Companions() (init, sidecar *drivers.Companion) {
+	if c.sidecar == nil {
+		return nil, nil
+	}
+	return companion(c.sidecar.Init), companion(c.sidecar.Sidecar)
+}
+
+func companion(c *models.CompanionContainer) *drivers.Companion {
+	if c == nil {
+		return nil
+	}
+	return &drivers.Companion{Image: c.Image, Cmd: c.Cmd, Env: c.Env, Memory: c.Memory * 1024 * 1024}
+}
 
 // WriteStat publishes each metric in the specified Stats structure as a histogram metric
 func (c *container) WriteStat(ctx context.Context, stat drivers.Stat) {
@@ -1294,6 +1911,9 @@ func (c *container) WriteStat(ctx context.Context, stat drivers.Stat) {
 		*(c.stats) = append(*(c.stats), stat)
 	}
 	c.swapMu.Unlock()
+	if c.calls != nil {
+		c.calls.writeStat(stat)
+	}
 }
 
 // DockerAuth implements the docker.AuthConfiguration interface.
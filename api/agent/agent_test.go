@@ -22,12 +22,14 @@ import (
 
 	_ "github.com/fnproject/fn/api/agent/drivers/docker"
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/common/tracecontext"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/logs"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/api/mqs"
 
 	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
 )
 
 func init() {
@@ -1419,3 +1421,25 @@ func TestContainerDisableIO(t *testing.T) {
 		t.Error("stderr is enabled, stderr should be disabled")
 	}
 }
+
+func TestCreateUDSRequestTraceContext(t *testing.T) {
+	req, err := http.NewRequest("POST", "http://127.0.0.1:8080/invoke/fn", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a traceparent of the client is replaced by the span dispatching the call
+	req.Header.Set(tracecontext.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	c := &call{Call: &models.Call{ID: id.New().String()}, req: req}
+
+	ctx, span := trace.StartSpan(context.Background(), "exec", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+
+	udsReq := createUDSRequest(ctx, c)
+	sc, ok := tracecontext.Parse(udsReq.Header.Get(tracecontext.TraceparentHeader), "")
+	if !ok {
+		t.Fatalf("expected a valid traceparent, got %q", udsReq.Header.Get(tracecontext.TraceparentHeader))
+	}
+	if sc.TraceID != span.SpanContext().TraceID || sc.SpanID != span.SpanContext().SpanID {
+		t.Fatalf("expected the span of the call %v, got %v", span.SpanContext(), sc)
+	}
+}
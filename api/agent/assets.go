@@ -0,0 +1,340 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// AssetManager is implemented by agents which distribute read-only assets to
+// their host ahead of time, see models.FnAssetsAnnotation
+type AssetManager interface {
+	// Assets returns the manifest of the agent with the state of each asset
+	Assets() ([]models.AssetStatus, error)
+	// PutAsset adds or replaces an asset of the manifest and downloads it
+	PutAsset(asset *models.Asset) error
+	// DeleteAsset removes an asset from the manifest
+	DeleteAsset(name string) error
+}
+
+// assetTmpPrefix prefixes the files assets are downloaded to
+const assetTmpPrefix = ".download-"
+
+// assetStore keeps the assets of a manifest in dir, in files named by their
+// sha256 checksum, so that replacing an asset does not change the file of
+// running containers. Assets are downloaded and verified before they are
+// renamed into place, files of assets no longer in the manifest are removed.
+// With a manifest url, the manifest is replaced by the one fetched from it on
+// every sync.
+type assetStore struct {
+	dir         string
+	manifestURL string
+	client      *http.Client
+
+	lock     sync.Mutex
+	manifest map[string]models.Asset
+	failures map[string]string // errors of the assets that failed by sha256
+
+	// kick triggers a sync
+	kick chan struct{}
+}
+
+func newAssetStore(dir, manifestURL string) (*assetStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &assetStore{
+		dir:         dir,
+		manifestURL: manifestURL,
+		client:      &http.Client{},
+		manifest:    make(map[string]models.Asset),
+		failures:    make(map[string]string),
+		kick:        make(chan struct{}, 1),
+	}, nil
+}
+
+func (s *assetStore) blob(sum string) string {
+	return filepath.Join(s.dir, sum)
+}
+
+// path returns the file of the asset name, or models.ErrAssetNotReady
+func (s *assetStore) path(name string) (string, error) {
+	if s == nil {
+		return "", models.ErrAssetsUnsupported
+	}
+	s.lock.Lock()
+	asset, ok := s.manifest[name]
+	s.lock.Unlock()
+	if !ok {
+		return "", models.ErrAssetNotFound
+	}
+	p := s.blob(asset.SHA256)
+	if _, err := os.Stat(p); err != nil {
+		return "", models.ErrAssetNotReady
+	}
+	return p, nil
+}
+
+func (s *assetStore) list() []models.AssetStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := make([]models.AssetStatus, 0, len(s.manifest))
+	for _, asset := range s.manifest {
+		status := models.AssetStatus{Asset: asset, Status: models.AssetPending}
+		if info, err := os.Stat(s.blob(asset.SHA256)); err == nil {
+			status.Status = models.AssetReady
+			status.Size = info.Size()
+		} else if msg, ok := s.failures[asset.SHA256]; ok {
+			status.Status = models.AssetFailed
+			status.Error = msg
+		}
+		res = append(res, status)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func (s *assetStore) put(asset *models.Asset) {
+	s.lock.Lock()
+	s.manifest[asset.Name] = *asset
+	delete(s.failures, asset.SHA256)
+	s.lock.Unlock()
+	s.trigger()
+}
+
+func (s *assetStore) remove(name string) error {
+	s.lock.Lock()
+	_, ok := s.manifest[name]
+	delete(s.manifest, name)
+	s.lock.Unlock()
+	if !ok {
+		return models.ErrAssetNotFound
+	}
+	s.trigger()
+	return nil
+}
+
+func (s *assetStore) trigger() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// sync fetches the manifest, if from a url, downloads the assets missing
+// from dir and removes the files of assets no longer in the manifest
+func (s *assetStore) sync(ctx context.Context) {
+	if s.manifestURL != "" {
+		if err := s.fetchManifest(ctx); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"url": s.manifestURL}).Error("error fetching asset manifest")
+		}
+	}
+
+	s.lock.Lock()
+	wanted := make(map[string]models.Asset, len(s.manifest))
+	for _, asset := range s.manifest {
+		if _, failed := s.failures[asset.SHA256]; !failed {
+			wanted[asset.SHA256] = asset
+		}
+	}
+	s.lock.Unlock()
+
+	for sum, asset := range wanted {
+		if _, err := os.Stat(s.blob(sum)); err == nil {
+			continue
+		}
+		if err := s.download(ctx, asset); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"asset": asset.Name, "url": asset.URL}).Error("error downloading asset")
+			if ctx.Err() != nil {
+				return
+			}
+			s.lock.Lock()
+			s.failures[sum] = err.Error()
+			s.lock.Unlock()
+		}
+	}
+
+	s.lock.Lock()
+	kept := make(map[string]bool, len(s.manifest))
+	for _, asset := range s.manifest {
+		kept[asset.SHA256] = true
+	}
+	s.lock.Unlock()
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		logrus.WithError(err).Error("error listing assets")
+		return
+	}
+	for _, f := range files {
+		// running containers keep the files they mounted
+		if !kept[f.Name()] && !strings.HasPrefix(f.Name(), assetTmpPrefix) {
+			os.Remove(filepath.Join(s.dir, f.Name()))
+		}
+	}
+}
+
+func (s *assetStore) fetchManifest(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodGet, s.manifestURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var assets []models.Asset
+	if err := json.NewDecoder(resp.Body).Decode(&assets); err != nil {
+		return err
+	}
+	manifest := make(map[string]models.Asset, len(assets))
+	for _, asset := range assets {
+		if err := asset.Validate(); err != nil {
+			return fmt.Errorf("invalid asset %s: %v", asset.Name, err)
+		}
+		manifest[asset.Name] = asset
+	}
+
+	s.lock.Lock()
+	s.manifest = manifest
+	s.lock.Unlock()
+	return nil
+}
+
+// download downloads an asset to a temporary file, verifies its checksum and
+// renames it into place
+func (s *assetStore) download(ctx context.Context, asset models.Asset) error {
+	req, err := http.NewRequest(http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	tmp, err := ioutil.TempFile(s.dir, assetTmpPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != asset.SHA256 {
+		return fmt.Errorf("checksum mismatch, got sha256 %s", sum)
+	}
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.blob(asset.SHA256))
+}
+
+// syncAssets syncs assets every poll, or when the manifest changes, until
+// the agent shuts down
+func (a *agent) syncAssets(poll time.Duration) {
+	defer a.shutWg.DoneSession()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-a.shutWg.Closer():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		a.assets.sync(ctx)
+		select {
+		case <-ticker.C:
+		case <-a.assets.kick:
+		case <-a.shutWg.Closer(): // server shutdown
+			return
+		}
+	}
+}
+
+func (a *agent) Assets() ([]models.AssetStatus, error) {
+	if a.assets == nil {
+		return nil, models.ErrAssetsUnsupported
+	}
+	return a.assets.list(), nil
+}
+
+func (a *agent) PutAsset(asset *models.Asset) error {
+	if a.assets == nil {
+		return models.ErrAssetsUnsupported
+	}
+	if err := asset.Validate(); err != nil {
+		return err
+	}
+	a.assets.put(asset)
+	return nil
+}
+
+func (a *agent) DeleteAsset(name string) error {
+	if a.assets == nil {
+		return models.ErrAssetsUnsupported
+	}
+	return a.assets.remove(name)
+}
+
+func (pr *pureRunner) Assets() ([]models.AssetStatus, error) {
+	if am, ok := pr.a.(AssetManager); ok {
+		return am.Assets()
+	}
+	return nil, models.ErrAssetsUnsupported
+}
+
+func (pr *pureRunner) PutAsset(asset *models.Asset) error {
+	if am, ok := pr.a.(AssetManager); ok {
+		return am.PutAsset(asset)
+	}
+	return models.ErrAssetsUnsupported
+}
+
+func (pr *pureRunner) DeleteAsset(name string) error {
+	if am, ok := pr.a.(AssetManager); ok {
+		return am.DeleteAsset(name)
+	}
+	return models.ErrAssetsUnsupported
+}
+
+var _ AssetManager = new(agent)
+var _ AssetManager = new(pureRunner)
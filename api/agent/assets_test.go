@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestAssetStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte("model weights")
+	h := sha256.Sum256(content)
+	sum := hex.EncodeToString(h[:])
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	s, err := newAssetStore(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.put(&models.Asset{Name: "model", URL: srv.URL, SHA256: sum})
+	if _, err := s.path("model"); err != models.ErrAssetNotReady {
+		t.Fatalf("expected the asset not to be ready before a sync, got %v", err)
+	}
+	if _, err := s.path("other"); err != models.ErrAssetNotFound {
+		t.Fatalf("expected an unknown asset not to be found, got %v", err)
+	}
+
+	s.sync(context.Background())
+	p, err := s.path("model")
+	if err != nil || p != filepath.Join(dir, sum) {
+		t.Fatalf("unexpected asset path %v %v", p, err)
+	}
+	if b, err := ioutil.ReadFile(p); err != nil || string(b) != string(content) {
+		t.Fatalf("unexpected asset content %q %v", b, err)
+	}
+
+	// assets failing their checksum are not kept
+	bad := "0000000000000000000000000000000000000000000000000000000000000000"
+	s.put(&models.Asset{Name: "bad", URL: srv.URL, SHA256: bad})
+	s.sync(context.Background())
+	if _, err := os.Stat(filepath.Join(dir, bad)); !os.IsNotExist(err) {
+		t.Fatalf("expected the asset failing its checksum to be removed, got %v", err)
+	}
+	status := s.list()
+	if len(status) != 2 || status[0].Status != models.AssetFailed || status[1].Status != models.AssetReady {
+		t.Fatalf("unexpected asset states %+v", status)
+	}
+
+	// files of assets removed from the manifest are removed
+	if err := s.remove("model"); err != nil {
+		t.Fatal(err)
+	}
+	s.sync(context.Background())
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Fatalf("expected the removed asset to be deleted, got %v", err)
+	}
+}
+
+func TestAssetStoreManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte("geo database")
+	h := sha256.Sum256(content)
+	sum := hex.EncodeToString(h[:])
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manifest" {
+			w.Write([]byte(`[{"name": "geoip", "url": "` + srv.URL + `/geoip", "sha256": "` + sum + `"}]`))
+			return
+		}
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	s, err := newAssetStore(dir, srv.URL+"/manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.sync(context.Background())
+	if p, err := s.path("geoip"); err != nil || p != filepath.Join(dir, sum) {
+		t.Fatalf("unexpected asset path %v %v", p, err)
+	}
+}
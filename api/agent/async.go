@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
 	"github.com/sirupsen/logrus"
 	"go.opencensus.io/tag"
@@ -120,4 +121,55 @@ func (a *agent) asyncRun(ctx context.Context, model *models.Call) {
 		id := call.Model().ID
 		logrus.WithFields(logrus.Fields{"id": id}).WithError(err).Error("error running async call")
 	}
+
+	a.retryAsync(ctx, call.Model())
+}
+
+// retryAsync queues a failed async call again according to the retry policy of
+// its fn, or moves it to the dead letter queue of the fn once it used up its attempts.
+func (a *agent) retryAsync(ctx context.Context, model *models.Call) {
+	if model.Status != "error" && model.Status != "timeout" {
+		return
+	}
+	policy, ok, err := models.RetryPolicyFromAnnotations(model.Annotations)
+	if err != nil || !ok {
+		return
+	}
+	dlh, ok := a.da.(DeadLetterHandler)
+	if !ok {
+		return
+	}
+
+	log := common.Logger(ctx).WithFields(common.CallFields("", model.FnID, model.ID))
+
+	attempt := model.Attempt
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt >= policy.MaxAttempts {
+		if err := dlh.DeadLetter(ctx, model); err != nil {
+			log.WithError(err).Error("error adding async call to dead letter queue")
+		}
+		return
+	}
+
+	retry := *model
+	retry.ID = id.New().String()
+	retry.Attempt = attempt + 1
+	retry.Delay = policy.Backoff(attempt)
+	retry.Status = "queued"
+	if retry.Delay > 0 {
+		retry.Status = "delayed"
+	}
+	retry.Error = ""
+	retry.Stats = nil
+	retry.CreatedAt = common.DateTime(time.Now())
+	retry.StartedAt = common.DateTime(time.Time{})
+	retry.CompletedAt = common.DateTime(time.Time{})
+
+	if err := dlh.Enqueue(ctx, &retry); err != nil {
+		log.WithError(err).Error("error queueing async call retry")
+		return
+	}
+	log.WithFields(logrus.Fields{"retry_id": retry.ID, "attempt": retry.Attempt}).Info("queued retry of failed async call")
 }
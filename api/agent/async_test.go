@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+// pushMQ records the calls pushed to it
+type pushMQ struct {
+	mqs.Mock
+	pushed []*models.Call
+}
+
+func (mq *pushMQ) Push(ctx context.Context, call *models.Call) (*models.Call, error) {
+	mq.pushed = append(mq.pushed, call)
+	return call, nil
+}
+
+func TestRetryAsync(t *testing.T) {
+	ctx := context.Background()
+	ls := logs.NewMock()
+	mq := &pushMQ{}
+	a := &agent{da: NewDirectCallDataAccess(ls, mq)}
+
+	annotations, err := models.EmptyAnnotations().With(models.FnRetryAnnotation, &models.RetryPolicy{MaxAttempts: 3, BackoffSeconds: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := &models.Call{ID: id.New().String(), FnID: "fn", Type: models.TypeAsync, Status: "error", Error: "boom", Annotations: annotations}
+
+	// failed attempts are retried with a doubling backoff
+	for i, delay := range []int32{10, 20} {
+		a.retryAsync(ctx, call)
+		if len(mq.pushed) != i+1 {
+			t.Fatalf("Expected attempt %d to be retried, got %d retries", i+1, len(mq.pushed))
+		}
+		retry := mq.pushed[i]
+		if retry.ID == call.ID || retry.Attempt != int32(i+2) || retry.Delay != delay || retry.Status != "delayed" || retry.Error != "" {
+			t.Fatalf("Unexpected retry of attempt %d %+v", i+1, retry)
+		}
+		call = &models.Call{ID: retry.ID, FnID: retry.FnID, Type: retry.Type, Attempt: retry.Attempt, Status: "timeout", Annotations: retry.Annotations}
+	}
+
+	// the last attempt goes to the dead letter queue
+	a.retryAsync(ctx, call)
+	if len(mq.pushed) != 2 {
+		t.Fatalf("Expected the last attempt not to be retried, got %d retries", len(mq.pushed))
+	}
+	letters, err := ls.(models.DeadLetterStore).GetDeadLetters(ctx, &models.CallFilter{FnID: "fn"})
+	if err != nil || len(letters.Items) != 1 || letters.Items[0].ID != call.ID {
+		t.Fatalf("Expected the last attempt to be a dead letter, got %+v %v", letters, err)
+	}
+
+	// successful calls and calls of fns without a retry policy are not retried
+	for _, c := range []*models.Call{
+		{ID: id.New().String(), FnID: "fn", Status: "success", Annotations: annotations},
+		{ID: id.New().String(), FnID: "fn", Status: "error"},
+	} {
+		a.retryAsync(ctx, c)
+	}
+	letters, _ = ls.(models.DeadLetterStore).GetDeadLetters(ctx, &models.CallFilter{FnID: "fn"})
+	if len(mq.pushed) != 2 || len(letters.Items) != 1 {
+		t.Fatalf("Expected calls not to be retried, got %d retries and %d dead letters", len(mq.pushed), len(letters.Items))
+	}
+}
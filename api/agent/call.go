@@ -5,16 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
-	"github.com/sirupsen/logrus"
 	"go.opencensus.io/trace"
 )
 
@@ -55,19 +57,28 @@ func FromHTTPFnRequest(app *models.App, fn *models.Fn, req *http.Request) CallOp
 			syslogURL = *app.SyslogURL
 		}
 
+		config, err := buildConfig(app, fn)
+		if err != nil {
+			return err
+		}
+
+		// validated on fn update, an invalid annotation here runs at the lowest priority
+		priority, _ := models.PriorityFromAnnotations(fn.Annotations)
+
 		c.Call = &models.Call{
 			ID:    id,
 			Image: fn.Image,
 			// Delay: 0,
 			Type: models.TypeSync,
 			// Payload: TODO,
-			Priority:    new(int32), // TODO this is crucial, apparently
+			Priority:    &priority,
 			Timeout:     fn.Timeout,
 			IdleTimeout: fn.IdleTimeout,
+			InitTimeout: fn.InitTimeout,
 			TmpFsSize:   0, // TODO clean up this
 			Memory:      fn.Memory,
 			CPUs:        0, // TODO clean up this
-			Config:      buildConfig(app, fn),
+			Config:      config,
 			// TODO - this wasn't really the intention here (that annotations would naturally cascade
 			// but seems to be necessary for some runner behaviour
 			Annotations: app.Annotations.MergeChange(fn.Annotations),
@@ -77,8 +88,11 @@ func FromHTTPFnRequest(app *models.App, fn *models.Fn, req *http.Request) CallOp
 			Method:      req.Method,
 			AppID:       app.ID,
 			AppName:     app.Name,
+			NamespaceID: app.NamespaceID,
 			FnID:        fn.ID,
 			SyslogURL:   syslogURL,
+
+			IdempotencyKey: req.Header.Get(models.IdempotencyKeyHeader),
 		}
 
 		c.req = req
@@ -86,7 +100,7 @@ func FromHTTPFnRequest(app *models.App, fn *models.Fn, req *http.Request) CallOp
 	}
 }
 
-func buildConfig(app *models.App, fn *models.Fn) models.Config {
+func buildConfig(app *models.App, fn *models.Fn) (models.Config, error) {
 	conf := make(models.Config, 8+len(app.Config)+len(fn.Config))
 	for k, v := range app.Config {
 		conf[k] = v
@@ -95,6 +109,12 @@ func buildConfig(app *models.App, fn *models.Fn) models.Config {
 		conf[k] = v
 	}
 
+	// secret references are left for the agent to resolve as containers start
+	conf, err := models.ResolveConfigRefs(conf, app.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	// XXX(reed): add trigger id to request headers on call?
 
 	conf["FN_MEMORY"] = fmt.Sprintf("%d", fn.Memory)
@@ -102,7 +122,7 @@ func buildConfig(app *models.App, fn *models.Fn) models.Config {
 	conf["FN_FN_ID"] = fn.ID
 	conf["FN_APP_ID"] = app.ID
 
-	return conf
+	return conf, nil
 }
 
 func reqURL(req *http.Request) string {
@@ -188,6 +208,48 @@ func InvokeDetached() CallOpt {
 	}
 }
 
+// InvokeAsync turns a call into an async call that is queued for execution
+// after delay seconds, the request body becomes the payload of the call.
+func InvokeAsync(delay int32) CallOpt {
+	return func(c *call) error {
+		if delay < 0 || delay > models.MaxCallDelay {
+			return models.ErrCallInvalidDelay
+		}
+
+		if err := limitRequestBody(c); err != nil {
+			return err
+		}
+		if c.req.Body != nil {
+			body, err := ioutil.ReadAll(c.req.Body)
+			if err != nil {
+				return err
+			}
+			c.Payload = string(body)
+		}
+		c.Type = models.TypeAsync
+		c.Delay = delay
+		if delay > 0 {
+			c.Status = "delayed"
+		} else {
+			c.Status = "queued"
+		}
+		c.Config["FN_TYPE"] = models.TypeAsync
+		return nil
+	}
+}
+
+// NewAsyncCallModel returns the model of an async call for a request to fn,
+// ready to be enqueued. Async calls are executed by agents dequeueing them.
+func NewAsyncCallModel(app *models.App, fn *models.Fn, req *http.Request, delay int32) (*models.Call, error) {
+	var c call
+	for _, o := range []CallOpt{FromHTTPFnRequest(app, fn, req), InvokeAsync(delay)} {
+		if err := o(&c); err != nil {
+			return nil, err
+		}
+	}
+	return c.Call, nil
+}
+
 // WithContext overrides the context on the call
 func WithContext(ctx context.Context) CallOpt {
 	return func(c *call) error {
@@ -240,10 +302,47 @@ func (a *agent) GetCall(opts ...CallOpt) (Call, error) {
 		c.Call.Config = make(models.Config)
 	}
 	c.Call.Config["FN_LISTENER"] = "unix:" + filepath.Join(iofsDockerMountDest, udsFilename)
+	if a.windows {
+		c.npipe = true
+		c.Call.Config["FN_LISTENER"] = "npipe:" + pipeDockerDest
+	}
 	c.Call.Config["FN_FORMAT"] = "http-stream" // TODO: remove this after fdk's forget what it means
+
+	// validated on fn update, an invalid annotation here falls back to serial calls
+	c.concurrency, _ = models.ConcurrencyFromAnnotations(c.Annotations)
+	if c.concurrency > 1 {
+		c.Call.Config["FN_CONCURRENCY"] = strconv.FormatUint(c.concurrency, 10)
+	}
+
+	// validated on fn update, an invalid annotation here allows overcommit
+	c.overcommit, _ = models.OvercommitFromAnnotations(c.Annotations)
+
+	// validated on fn update, an invalid annotation here runs unpinned
+	c.cpuPinning, _ = models.CPUPinningFromAnnotations(c.Annotations)
+
+	// validated on fn update, an invalid annotation here does not throttle
+	c.ioLimit, _ = models.IOLimitFromAnnotations(c.Annotations)
+	c.egressLimit, _ = models.EgressLimitFromAnnotations(c.Annotations)
+
+	// validated on app update, an invalid annotation here mounts no volumes
+	c.volumes, _ = models.VolumesFromAnnotations(c.Annotations)
+
+	// validated on fn update, an invalid annotation here mounts no assets
+	c.assets, _ = models.AssetsFromAnnotations(c.Annotations)
+
+	c.pausedTTL = a.cfg.PausedTTL
+	if ttl, ok, err := models.PausedTTLFromAnnotations(c.Annotations); ok && err == nil {
+		c.pausedTTL = ttl
+	}
+
+	// validated on fn update, invalid annotations here fall back to the server limits
+	if err := limitRequestBody(&c); err != nil {
+		return nil, err
+	}
+	c.maxResponseSize = maxResponseSize(&a.cfg, &c)
 	// TODO we could set type here too, for now, or anything else not based in fn/app/trigger config
 
-	setupCtx(&c)
+	setupCtx(&c, common.LogComponentAgent)
 
 	c.handler = a.da
 	c.ct = a
@@ -261,10 +360,23 @@ func (a *agent) GetCall(opts ...CallOpt) (Call, error) {
 	return &c, nil
 }
 
-func setupCtx(c *call) {
-	ctx, _ := common.LoggerWithFields(c.req.Context(),
-		logrus.Fields{"id": c.ID, "app_id": c.AppID, "fn_id": c.FnID})
-	c.req = c.req.WithContext(ctx)
+// setupCtx sets the logger of the call, logging at the level of component
+func setupCtx(c *call, component string) {
+	fields := common.CallFields(c.AppID, c.FnID, c.ID)
+	if c.NamespaceID != "" {
+		fields["namespace_id"] = c.NamespaceID
+	}
+	// calls keep the request id of the request which created them, calls
+	// from queues and runners get it back from their model
+	ctx := c.req.Context()
+	if rid := common.RequestIDFromContext(ctx); rid != "" && c.RequestID == "" {
+		c.RequestID = rid
+	} else if rid == "" && c.RequestID != "" {
+		ctx = common.WithRequestID(ctx, c.RequestID)
+		fields[common.RequestIDContextKey] = c.RequestID
+	}
+	ctx, _ = common.LoggerWithFields(common.WithComponent(ctx, component), fields)
+	c.req = c.req.WithContext(withNamespaceTag(ctx, c.NamespaceID))
 }
 
 type call struct {
@@ -279,9 +391,64 @@ type call struct {
 	requestState RequestState
 	slotHashId   string
 
+	// number of calls a hot container of this fn may serve at the same time
+	concurrency uint64
+
+	// whether the containers of this fn may overcommit the memory and cpu
+	// of the agent, see Config.MemOvercommit
+	overcommit bool
+
+	// whether the hot containers of this fn are pinned to dedicated cores,
+	// see Config.PinnedCPUs
+	cpuPinning bool
+
+	// disk I/O limit of the containers of this fn, nil if unlimited, see
+	// Config.BlkioDevices
+	ioLimit *models.IOLimit
+
+	// egress bandwidth of the containers of this fn in bytes per second,
+	// 0 if unlimited
+	egressLimit uint64
+
+	// the container listens on a named pipe rather than a unix socket, on
+	// windows
+	npipe bool
+
+	// persistent volumes of the app, see Config.VolumesDir
+	volumes []models.Volume
+
+	// container paths of the read-only assets of the fn by asset name, see
+	// Config.AssetsDir
+	assets map[string]string
+
+	// how long a hot container may stay paused before it is shut down
+	pausedTTL time.Duration
+
+	// body of req if the fn limits the size of requests, and the number of
+	// bytes its response may hold, 0 for no limit
+	reqBody         *limitedBody
+	maxResponseSize uint64
+
+	// the fn the call asked to be invoked next with its response, if any
+	next *chainDirective
+
+	// whether the call proxies a WebSocket connection to the container, see
+	// InvokeWebSocket
+	webSocket bool
+
+	// set once the call was cancelled, see CallCanceller
+	cancelled int32
+
 	// amount of time attributed to user-code execution
 	userExecTime *time.Duration
 
+	// time spent in each phase, copied to the model when the call ends, and
+	// the part of it runners reported, see AddTimings
+	timingsMu   sync.Mutex
+	timings     models.CallTimings
+	runnerTime  time.Duration
+	timingsDone bool
+
 	// LB & Pure Runner Extra Config
 	extensions map[string]string
 }
@@ -325,6 +492,60 @@ func (c *call) GetUserExecutionTime() *time.Duration {
 	return c.userExecTime
 }
 
+// AddTimings adds the phase timings a runner reported for the call
+func (c *call) AddTimings(timings models.CallTimings) {
+	c.timingsMu.Lock()
+	defer c.timingsMu.Unlock()
+	if c.timingsDone {
+		return
+	}
+	for phase, ms := range timings {
+		dur := time.Duration(ms) * time.Millisecond
+		c.timings.Add(phase, dur)
+		c.runnerTime += dur
+	}
+}
+
+// priority returns the priority of the call, 0 if not set
+func (c *call) priority() int32 {
+	if c.Priority == nil {
+		return 0
+	}
+	return *c.Priority
+}
+
+// SetResolvedImage records the digest and platform the image of the call
+// resolved to on the runner which executed it, if known
+func (c *call) SetResolvedImage(digest, platform string) {
+	if digest != "" {
+		c.ImageDigest = digest
+	}
+	if platform != "" {
+		c.Platform = platform
+	}
+}
+
+// addTiming records the time the call spent in phase on this node
+func (c *call) addTiming(ctx context.Context, phase string, dur time.Duration) {
+	if dur < 0 {
+		dur = 0
+	}
+	statsCallPhaseLatency(ctx, phase, dur)
+
+	c.timingsMu.Lock()
+	defer c.timingsMu.Unlock()
+	if !c.timingsDone {
+		c.timings.Add(phase, dur)
+	}
+}
+
+// getRunnerTime returns the time runners reported the call spent with them
+func (c *call) getRunnerTime() time.Duration {
+	c.timingsMu.Lock()
+	defer c.timingsMu.Unlock()
+	return c.runnerTime
+}
+
 func (c *call) Model() *models.Call { return c.Call }
 
 func (c *call) Start(ctx context.Context) error {
@@ -370,14 +591,23 @@ func (c *call) End(ctx context.Context, errIn error) error {
 		c.Status = "success"
 	case context.DeadlineExceeded:
 		c.Status = "timeout"
+		c.ErrorClass = models.ErrorClass(errIn)
+	case models.ErrCallCancelled:
+		c.Status = "cancelled"
 	default:
 		c.Status = "error"
 		c.Error = errIn.Error()
+		c.ErrorClass = models.ErrorClass(errIn)
 	}
 
 	// ensure stats histogram is reasonably bounded
 	c.Call.Stats = drivers.Decimate(240, c.Call.Stats)
 
+	// runners that are still sending their report are too late
+	c.timingsMu.Lock()
+	c.Timings, c.timingsDone = c.timings, true
+	c.timingsMu.Unlock()
+
 	if err := c.handler.Finish(ctx, c.Model(), c.stderr, c.Type == models.TypeAsync); err != nil {
 		common.Logger(ctx).WithError(err).Error("error finalizing call on datastore/mq")
 		// note: Not returning err here since the job could have already finished successfully.
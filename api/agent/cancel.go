@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// CallCanceller is implemented by agents which can cancel the calls they run
+type CallCanceller interface {
+	// CancelCall cancels the call with id if this agent runs it, returning
+	// the model of the call, or nil if it does not run such a call. The call
+	// ends with models.ErrCallCancelled and the status cancelled, a container
+	// still running it is stopped.
+	CancelCall(id string) *models.Call
+}
+
+// callCancels keeps the cancel funcs of the running calls of an agent
+type callCancels struct {
+	mu    sync.Mutex
+	calls map[string]*cancellableCall
+}
+
+type cancellableCall struct {
+	call   *call
+	cancel context.CancelFunc
+}
+
+func newCallCancels() *callCancels {
+	return &callCancels{calls: make(map[string]*cancellableCall)}
+}
+
+// track makes call cancellable until the returned func is called, the call
+// must run with the returned context
+func (cc *callCancels) track(ctx context.Context, c *call) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	entry := &cancellableCall{call: c, cancel: cancel}
+	cc.mu.Lock()
+	cc.calls[c.ID] = entry
+	cc.mu.Unlock()
+	return ctx, func() {
+		cc.mu.Lock()
+		// detached calls are tracked again once their request returned
+		if cc.calls[c.ID] == entry {
+			delete(cc.calls, c.ID)
+		}
+		cc.mu.Unlock()
+		cancel()
+	}
+}
+
+func (cc *callCancels) cancel(id string) *models.Call {
+	cc.mu.Lock()
+	cl, ok := cc.calls[id]
+	cc.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	atomic.StoreInt32(&cl.call.cancelled, 1)
+	cl.cancel()
+	return cl.call.Model()
+}
+
+func (c *call) isCancelled() bool {
+	return atomic.LoadInt32(&c.cancelled) == 1
+}
+
+// cancelledError returns models.ErrCallCancelled for errors of calls which
+// failed because they were cancelled, along with a context which is not done
+// to record their end with.
+func cancelledError(ctx context.Context, c *call, err error) (context.Context, error) {
+	if err != nil && c.isCancelled() {
+		return common.BackgroundContext(ctx), models.ErrCallCancelled
+	}
+	return ctx, err
+}
+
+func (a *agent) CancelCall(id string) *models.Call   { return a.cancels.cancel(id) }
+func (a *lbAgent) CancelCall(id string) *models.Call { return a.cancels.cancel(id) }
+
+var _ CallCanceller = new(agent)
+var _ CallCanceller = new(lbAgent)
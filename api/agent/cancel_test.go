@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestCallCancels(t *testing.T) {
+	cc := newCallCancels()
+	c := &call{Call: &models.Call{ID: "call"}}
+
+	if cc.cancel("call") != nil {
+		t.Fatal("expected no call to be cancelled before it runs")
+	}
+
+	ctx, untrack := cc.track(context.Background(), c)
+	// a detached call is tracked again while its request returns
+	ctx2, untrack2 := cc.track(context.Background(), c)
+	untrack()
+	if ctx.Err() == nil {
+		t.Fatal("expected the context of the request to be done once it returned")
+	}
+
+	if m := cc.cancel("call"); m == nil || m.ID != "call" {
+		t.Fatalf("expected the running call to be cancelled, got %v", m)
+	}
+	if ctx2.Err() != context.Canceled || !c.isCancelled() {
+		t.Fatal("expected the context of the call to be cancelled")
+	}
+	if _, err := cancelledError(ctx2, c, context.Canceled); err != models.ErrCallCancelled {
+		t.Fatalf("expected the call to end as cancelled, got %v", err)
+	}
+	if _, err := cancelledError(ctx2, &call{Call: c.Call}, errors.New("boom")); err.Error() != "boom" {
+		t.Fatalf("expected errors of other calls to be kept, got %v", err)
+	}
+
+	untrack2()
+	if cc.cancel("call") != nil {
+		t.Fatal("expected no call to be cancelled after it ran")
+	}
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// WithChainReads sets where the agent reads the fns that calls chain, see
+// models.NextFnHeader. Agents whose CallHandler is a ReadDataAccess read them
+// from it by default, other agents do not chain calls.
+func WithChainReads(rda ReadDataAccess) Option {
+	return func(a *agent) error {
+		a.chainReads = rda
+		return nil
+	}
+}
+
+// chainDirective is the fn a call asked to be invoked next with its response,
+// see models.NextFnHeader
+type chainDirective struct {
+	fnID        string
+	contentType string
+	payload     []byte
+}
+
+// captureNext records the next fn the response of call asks for, if any. The
+// body of such responses is buffered, to be both written out and sent to the
+// next fn.
+func captureNext(call *call, resp *http.Response) error {
+	fnID := resp.Header.Get(models.NextFnHeader)
+	if fnID == "" || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var r io.Reader = resp.Body
+	if call.maxResponseSize != 0 {
+		// one more byte than allowed for writing the response to fail
+		r = io.LimitReader(r, int64(call.maxResponseSize)+1)
+	}
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	call.next = &chainDirective{
+		fnID:        fnID,
+		contentType: resp.Header.Get("Content-Type"),
+		payload:     body,
+	}
+	return nil
+}
+
+// chain enqueues the call of the next fn call asked for, if any. Chaining
+// failures are logged, they do not fail call, which completed.
+func (a *agent) chain(ctx context.Context, call *call) {
+	next := call.next
+	if next == nil {
+		return
+	}
+	log := common.Logger(ctx).WithFields(logrus.Fields{"next_fn_id": next.fnID, "chain_depth": call.ChainDepth})
+
+	if call.ChainDepth+1 >= models.MaxChainDepth {
+		log.Error("not invoking the next fn of the call, its chain is too deep")
+		return
+	}
+	eda, ok := a.da.(EnqueueDataAccess)
+	if !ok || a.chainReads == nil {
+		log.Error("not invoking the next fn of the call, the agent cannot chain calls")
+		return
+	}
+
+	mCall, err := a.chainedCall(ctx, call, next)
+	if err != nil {
+		log.WithError(err).Error("not invoking the next fn of the call")
+		return
+	}
+	if err := eda.Enqueue(ctx, mCall); err != nil {
+		log.WithError(err).Error("error queueing the next fn of the call")
+		return
+	}
+	log.WithField("next_call_id", mCall.ID).Debug("queued the next fn of the call")
+}
+
+// chainedCall returns the async call of the next fn of call, which must be a
+// fn of the app of call
+func (a *agent) chainedCall(ctx context.Context, call *call, next *chainDirective) (*models.Call, error) {
+	fn, err := a.chainReads.GetFnByID(ctx, next.fnID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.AppID != call.AppID {
+		return nil, models.ErrFnsNotFound
+	}
+	app, err := a.chainReads.GetAppByID(ctx, fn.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/invoke/"+fn.ID, bytes.NewReader(next.payload))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if next.contentType != "" {
+		req.Header.Set("Content-Type", next.contentType)
+	}
+	chainID := call.ChainID
+	if chainID == "" {
+		chainID = call.ID
+	}
+	req.Header.Set(models.ParentCallHeader, call.ID)
+	req.Header.Set(models.ChainHeader, chainID)
+
+	mCall, err := NewAsyncCallModel(app, fn, req, 0)
+	if err != nil {
+		return nil, err
+	}
+	mCall.ParentCallID = call.ID
+	mCall.ChainDepth = call.ChainDepth + 1
+	mCall.ChainID = chainID
+	return mCall, nil
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestCaptureNext(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{models.NextFnHeader: {"next"}, "Content-Type": {"text/plain"}},
+		Body:       ioutil.NopCloser(strings.NewReader("hello")),
+	}
+	c := &call{Call: &models.Call{ID: "call"}}
+	if err := captureNext(c, resp); err != nil {
+		t.Fatal(err)
+	}
+	if c.next == nil || c.next.fnID != "next" || c.next.contentType != "text/plain" || string(c.next.payload) != "hello" {
+		t.Fatalf("expected the next fn to be captured, got %+v", c.next)
+	}
+	if b, _ := ioutil.ReadAll(resp.Body); string(b) != "hello" {
+		t.Fatalf("expected the response body to be kept, got %q", b)
+	}
+
+	resp = &http.Response{
+		StatusCode: http.StatusBadGateway,
+		Header:     http.Header{models.NextFnHeader: {"next"}},
+		Body:       ioutil.NopCloser(strings.NewReader("boom")),
+	}
+	c = &call{Call: &models.Call{ID: "call"}}
+	if err := captureNext(c, resp); err != nil || c.next != nil {
+		t.Fatalf("expected failed calls not to chain, got %+v %v", c.next, err)
+	}
+}
+
+func TestChainedCall(t *testing.T) {
+	app := &models.App{ID: "app", Name: "myapp"}
+	other := &models.App{ID: "other", Name: "otherapp"}
+	fn := &models.Fn{ID: "next", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	foreign := &models.Fn{ID: "foreign", AppID: other.ID, Image: "fnproject/fn-test-utils"}
+	a := &agent{chainReads: NewCachedDataAccess(datastore.NewMockInit([]*models.App{app, other}, []*models.Fn{fn, foreign}))}
+
+	c := &call{Call: &models.Call{ID: "parent", AppID: app.ID, ChainDepth: 2}}
+	mCall, err := a.chainedCall(context.Background(), c, &chainDirective{fnID: fn.ID, payload: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mCall.FnID != fn.ID || mCall.ParentCallID != "parent" || mCall.ChainDepth != 3 || mCall.ChainID != "parent" {
+		t.Fatalf("expected a call of the next fn linked to its parent, got %+v", mCall)
+	}
+
+	if _, err := a.chainedCall(context.Background(), c, &chainDirective{fnID: foreign.ID}); err != models.ErrFnsNotFound {
+		t.Fatalf("expected fns of other apps not to be chained, got %v", err)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"github.com/fnproject/fn/api/chaos"
 )
 
 // Config specifies various settings for an agent
@@ -13,7 +15,37 @@ type Config struct {
 	MinDockerVersion        string        `json:"min_docker_version"`
 	DockerNetworks          string        `json:"docker_networks"`
 	DockerLoadFile          string        `json:"docker_load_file"`
+	BlkioDevices            string        `json:"blkio_devices"`
+	DockerDNS               string        `json:"docker_dns"`
+	DockerDNSSearch         string        `json:"docker_dns_search"`
+	DockerExtraHosts        string        `json:"docker_extra_hosts"`
+	HTTPProxy               string        `json:"http_proxy"`
+	HTTPSProxy              string        `json:"https_proxy"`
+	NoProxy                 string        `json:"no_proxy"`
+	DockerUser              string        `json:"docker_user"`
+	DockerRequireNonRoot    bool          `json:"docker_require_non_root"`
+	DockerCapDrop           string        `json:"docker_cap_drop"`
+	DockerCapAdd            string        `json:"docker_cap_add"`
+	DockerCapAllowed        string        `json:"docker_cap_allowed"`
+	DockerAllowNewPrivs     bool          `json:"docker_allow_new_privileges"`
 	FreezeIdle              time.Duration `json:"freeze_idle_msecs"`
+	PausedTTL               time.Duration `json:"paused_ttl_msecs"`
+	EvictionPolicy          string        `json:"eviction_policy"`
+	EvictMemPressure        uint64        `json:"evict_mem_pressure_pct"`
+	EvictMemPSI             uint64        `json:"evict_mem_psi_pct"`
+	MemOvercommit           uint64        `json:"mem_overcommit_pct"`
+	CPUOvercommit           uint64        `json:"cpu_overcommit_pct"`
+	PinnedCPUs              string        `json:"pinned_cpus"`
+	VolumesDir              string        `json:"volumes_dir"`
+	AssetsDir               string        `json:"assets_dir"`
+	AssetsManifestURL       string        `json:"assets_manifest_url"`
+	AssetsPoll              time.Duration `json:"assets_poll_msecs"`
+	FnMetricsWindow         time.Duration `json:"fn_metrics_window_msecs"`
+	MaxInflightCalls        uint64        `json:"max_inflight_calls"`
+	PriorityClasses         string        `json:"priority_classes"`
+	DefaultPriorityClass    string        `json:"default_priority_class"`
+	CheckpointIdle          time.Duration `json:"checkpoint_idle_msecs"`
+	CheckpointDir           string        `json:"checkpoint_dir"`
 	HotPoll                 time.Duration `json:"hot_poll_msecs"`
 	HotLauncherTimeout      time.Duration `json:"hot_launcher_timeout_msecs"`
 	HotPullTimeout          time.Duration `json:"hot_pull_timeout_msecs"`
@@ -22,6 +54,7 @@ type Config struct {
 	DetachedHeadRoom        time.Duration `json:"detached_head_room_msecs"`
 	MaxResponseSize         uint64        `json:"max_response_size_bytes"`
 	MaxLogSize              uint64        `json:"max_log_size_bytes"`
+	MaxCallLogSize          uint64        `json:"max_call_log_size_bytes"`
 	MaxTotalCPU             uint64        `json:"max_total_cpu_mcpus"`
 	MaxTotalMemory          uint64        `json:"max_total_memory_bytes"`
 	MaxFsSize               uint64        `json:"max_fs_size_mb"`
@@ -30,6 +63,10 @@ type Config struct {
 	PreForkCmd              string        `json:"pre_fork_pool_cmd"`
 	PreForkUseOnce          uint64        `json:"pre_fork_use_once"`
 	PreForkNetworks         string        `json:"pre_fork_networks"`
+	PreForkPoolMaxSize      uint64        `json:"pre_fork_pool_max_size"`
+	PreForkLowWater         uint64        `json:"pre_fork_low_water"`
+	PreForkHighWater        uint64        `json:"pre_fork_high_water"`
+	PreForkScaleInterval    time.Duration `json:"pre_fork_scale_interval_msecs"`
 	EnableNBResourceTracker bool          `json:"enable_nb_resource_tracker"`
 	MaxTmpFsInodes          uint64        `json:"max_tmpfs_inodes"`
 	DisableReadOnlyRootFs   bool          `json:"disable_readonly_rootfs"`
@@ -39,6 +76,10 @@ type Config struct {
 	IOFSMountRoot           string        `json:"iofs_mount_root"`
 	IOFSOpts                string        `json:"iofs_opts"`
 	MaxDockerRetries        uint64        `json:"max_docker_retries"`
+	InstanceID              string        `json:"instance_id"`
+	DockerReapInterval      time.Duration `json:"docker_reap_interval_msecs"`
+	Chaos                   string        `json:"chaos"`
+	EnableChaos             bool          `json:"enable_chaos"`
 }
 
 const (
@@ -46,8 +87,104 @@ const (
 	EnvDockerNetworks = "FN_DOCKER_NETWORKS"
 	// EnvDockerLoadFile is a file location for a file that contains a tarball of a docker image to load on startup
 	EnvDockerLoadFile = "FN_DOCKER_LOAD_FILE"
+	// EnvBlkioDevices is a space separated list of block devices, e.g. /dev/sda, on which the disk I/O of
+	// containers of fns with the fnproject.io/fn/ioLimit annotation is throttled. Empty (default) ignores
+	// the annotation.
+	EnvBlkioDevices = "FN_BLKIO_DEVICES"
+	// EnvDockerDNS, EnvDockerDNSSearch and EnvDockerExtraHosts are space separated lists of DNS servers,
+	// DNS search domains and host:ip entries of /etc/hosts of containers, which apps may override with
+	// the fnproject.io/app/dns annotation. Empty (default) uses the resolution of docker.
+	EnvDockerDNS        = "FN_DOCKER_DNS"
+	EnvDockerDNSSearch  = "FN_DOCKER_DNS_SEARCH"
+	EnvDockerExtraHosts = "FN_DOCKER_EXTRA_HOSTS"
+	// EnvHTTPProxy, EnvHTTPSProxy and EnvNoProxy are injected into every container as HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY, in upper and lower case, unless fns configure them. Apps may replace
+	// them with the fnproject.io/app/proxy annotation. Empty (default) injects nothing.
+	EnvHTTPProxy  = "FN_HTTP_PROXY"
+	EnvHTTPSProxy = "FN_HTTPS_PROXY"
+	EnvNoProxy    = "FN_NO_PROXY"
+	// EnvDockerUser is the user, and optionally group, containers run as, e.g. 1000:1000, apps may
+	// override it with the fnproject.io/app/user annotation. Empty (default) runs as the image USER.
+	EnvDockerUser = "FN_DOCKER_USER"
+	// EnvDockerRequireNonRoot rejects containers which would run as root, whether from their image, the
+	// agent or their app, to enforce security baselines
+	EnvDockerRequireNonRoot = "FN_DOCKER_REQUIRE_NON_ROOT"
+	// EnvDockerCapDrop and EnvDockerCapAdd are space separated lists of the Linux capabilities, without
+	// their CAP_ prefix, dropped from and added to containers. By default every capability is dropped
+	// and a minimal set, see DefaultCapAdd, is added back.
+	EnvDockerCapDrop = "FN_DOCKER_CAP_DROP"
+	EnvDockerCapAdd  = "FN_DOCKER_CAP_ADD"
+	// EnvDockerCapAllowed is a space separated list of the capabilities apps may add with the
+	// fnproject.io/app/capabilities annotation, containers of apps adding others are rejected.
+	// Empty (default) lets apps add none.
+	EnvDockerCapAllowed = "FN_DOCKER_CAP_ALLOWED"
+	// EnvDockerAllowNewPrivs lets processes of containers gain privileges, e.g. through setuid
+	// binaries, which the no-new-privileges security option prevents by default
+	EnvDockerAllowNewPrivs = "FN_DOCKER_ALLOW_NEW_PRIVILEGES"
+	// EnvInstanceID identifies this agent on the docker host, containers are labeled with it. Defaults
+	// to the hostname, must be unique if more than one agent shares a docker daemon.
+	EnvInstanceID = "FN_AGENT_INSTANCE_ID"
+	// EnvDockerReapInterval is the interval at which containers labeled with this agent's instance id
+	// but no longer tracked by it, eg. after a crash, are removed once older than a minute. Zero
+	// (default) disables reaping.
+	EnvDockerReapInterval = "FN_DOCKER_REAP_INTERVAL_MSECS"
 	// EnvFreezeIdle is the delay between a container being last used and being frozen
 	EnvFreezeIdle = "FN_FREEZE_IDLE_MSECS"
+	// EnvPausedTTL is the time a paused container may stay resident before it is shut down, apps may override
+	// it with the fnproject.io/app/pausedTTL annotation. Zero (default) keeps paused containers until idle timeout.
+	EnvPausedTTL = "FN_PAUSED_TTL_MSECS"
+	// EnvEvictionPolicy selects which idle containers are evicted first to make room for others, either
+	// fifo (default, oldest container first) or lru (least recently used container first)
+	EnvEvictionPolicy = "FN_EVICTION_POLICY"
+	// EnvEvictMemPressure is the percentage of reserved memory above which idle containers are evicted
+	// in least recently used order until usage drops below it. Zero (default) disables it.
+	EnvEvictMemPressure = "FN_EVICT_MEM_PRESSURE_PCT"
+	// EnvEvictMemPSI is the percentage of time tasks stalled on memory, as reported by the cgroup or system
+	// pressure stall information, above which frozen containers are evicted in least recently used order
+	// until the pressure drops below it. Zero (default) disables it.
+	EnvEvictMemPSI = "FN_EVICT_MEM_PSI_PCT"
+	// EnvMemOvercommit and EnvCPUOvercommit are the percentage of the memory and cpu available to containers
+	// that their containers may reserve, e.g. 150 allows 1.5x subscription for bursty fns. Fns opt out with
+	// the fnproject.io/fn/overcommit annotation. 100 (default) does not overcommit.
+	EnvMemOvercommit = "FN_MEM_OVERCOMMIT_PCT"
+	EnvCPUOvercommit = "FN_CPU_OVERCOMMIT_PCT"
+	// EnvPinnedCPUs is the list of cores, in the cpuset format e.g. 4-15, that hot containers of fns with
+	// the fnproject.io/fn/cpuPinning annotation are pinned to, one container per core. Empty (default)
+	// disables pinning.
+	EnvPinnedCPUs = "FN_PINNED_CPUS"
+	// EnvVolumesDir is the directory the persistent volumes of apps, see the fnproject.io/app/volumes
+	// annotation, are kept in, with quotas and ttls. Empty (default) uses docker volumes instead.
+	EnvVolumesDir = "FN_VOLUMES_DIR"
+	// EnvAssetsDir is the directory read-only assets, see the fnproject.io/fn/assets annotation, are
+	// downloaded to ahead of calls and mounted from. Empty (default) disables assets.
+	EnvAssetsDir = "FN_ASSETS_DIR"
+	// EnvAssetsManifestURL is a url serving the json list of assets to distribute, polled every
+	// EnvAssetsPoll. Empty (default) only distributes the assets put through the admin api.
+	EnvAssetsManifestURL = "FN_ASSETS_MANIFEST_URL"
+	// EnvAssetsPoll is the interval at which assets are synced with their manifest
+	EnvAssetsPoll = "FN_ASSETS_POLL_MSECS"
+	// EnvFnMetricsWindow is the sliding window the concurrency, rate and latency of the calls of each
+	// fn are aggregated over, reported to the lb a runner registers with. Zero disables fn metrics.
+	EnvFnMetricsWindow = "FN_FN_METRICS_WINDOW_MSECS"
+	// EnvMaxInflightCalls is the number of calls the agent runs or waits slots for at once, further calls
+	// queue by priority class until one finishes. Zero (default) admits every call immediately.
+	EnvMaxInflightCalls = "FN_MAX_INFLIGHT_CALLS"
+	// EnvPriorityClasses is a comma separated list of priority classes, highest first, apps select
+	// one with the fnproject.io/app/priorityClass annotation
+	EnvPriorityClasses = "FN_PRIORITY_CLASSES"
+	// EnvDefaultPriorityClass is the priority class of calls of apps without or with an unknown class
+	EnvDefaultPriorityClass = "FN_DEFAULT_PRIORITY_CLASS"
+	// EnvCheckpointIdle is the delay between a container being last used and being checkpointed to disk
+	// using CRIU, which requires docker to run in experimental mode. Zero (default) disables checkpoints.
+	EnvCheckpointIdle = "FN_EXPERIMENTAL_CHECKPOINT_IDLE_MSECS"
+	// EnvCheckpointDir is the directory for container checkpoints, empty uses the docker default
+	EnvCheckpointDir = "FN_EXPERIMENTAL_CHECKPOINT_DIR"
+	// EnvChaos is a list of faults injected into the driver of runners and the runners of lbs to test
+	// placement and retries, e.g. latency=0.1:500ms,pull=0.05,create=0.05,reject=0.2, see
+	// chaos.ParseConfig. It is refused unless EnvChaosEnable is set or fn is built with the chaos tag.
+	EnvChaos = "FN_CHAOS"
+	// EnvChaosEnable enables the faults of EnvChaos in binaries built without the chaos tag
+	EnvChaosEnable = "FN_CHAOS_ENABLE"
 	// EnvHotPoll is the interval to ping for a slot manager thread to check if a container should be
 	// launched for a given function
 	EnvHotPoll = "FN_HOT_POLL_MSECS"
@@ -55,7 +192,8 @@ const (
 	EnvHotLauncherTimeout = "FN_HOT_LAUNCHER_TIMEOUT_MSECS"
 	// EnvHotStartTimeout is the timeout for a hot container to be created including docker-pull
 	EnvHotPullTimeout = "FN_HOT_PULL_TIMEOUT_MSECS"
-	// EnvHotStartTimeout is the timeout for a hot container to become available for use for requests after EnvHotStartTimeout
+	// EnvHotStartTimeout is the timeout for a hot container to become available for use for requests after EnvHotStartTimeout,
+	// fns may set their own, see models.ResourceConfig.InitTimeout
 	EnvHotStartTimeout = "FN_HOT_START_TIMEOUT_MSECS"
 	// EnvAsyncChewPoll is the interval to poll the queue that contains async function invocations
 	EnvAsyncChewPoll = "FN_ASYNC_CHEW_POLL_MSECS"
@@ -63,6 +201,9 @@ const (
 	EnvMaxResponseSize = "FN_MAX_RESPONSE_SIZE"
 	// EnvMaxLogSize is the maximum size that a function's log may reach
 	EnvMaxLogSize = "FN_MAX_LOG_SIZE_BYTES"
+	// EnvMaxCallLogSize is the maximum number of bytes of stdout/stderr a single call may emit to
+	// the log streams, output past the limit is dropped after a truncation marker. Zero means no limit.
+	EnvMaxCallLogSize = "FN_MAX_CALL_LOG_SIZE_BYTES"
 	// EnvMaxTotalCPU is the maximum CPU that will be reserved across all containers
 	EnvMaxTotalCPU = "FN_MAX_TOTAL_CPU_MCPUS"
 	// EnvMaxTotalMemory is the maximum memory that will be reserved across all containers
@@ -79,6 +220,15 @@ const (
 	EnvPreForkUseOnce = "FN_EXPERIMENTAL_PREFORK_USE_ONCE"
 	// EnvPreForkNetworks is the equivalent of EnvDockerNetworks but for pre-fork pool containers
 	EnvPreForkNetworks = "FN_EXPERIMENTAL_PREFORK_NETWORKS"
+	// EnvPreForkPoolMaxSize is the upper bound the pre-fork pool may grow to when it runs out of free containers,
+	// if unset or smaller than EnvPreForkPoolSize the pool is fixed at EnvPreForkPoolSize
+	EnvPreForkPoolMaxSize = "FN_EXPERIMENTAL_PREFORK_POOL_MAX_SIZE"
+	// EnvPreForkLowWater is the number of free pre-fork containers below which the pool grows
+	EnvPreForkLowWater = "FN_EXPERIMENTAL_PREFORK_LOW_WATER"
+	// EnvPreForkHighWater is the number of free pre-fork containers above which the pool shrinks
+	EnvPreForkHighWater = "FN_EXPERIMENTAL_PREFORK_HIGH_WATER"
+	// EnvPreForkScaleInterval is the interval at which the pre-fork pool checks whether it should grow or shrink
+	EnvPreForkScaleInterval = "FN_EXPERIMENTAL_PREFORK_SCALE_INTERVAL_MSECS"
 	// EnvEnableNBResourceTracker makes every request to the resource tracker non-blocking, meaning the resources are either
 	// available or it will return an error immediately
 	EnvEnableNBResourceTracker = "FN_ENABLE_NB_RESOURCE_TRACKER"
@@ -108,6 +258,9 @@ const (
 
 	// DefaultHotPoll is the default value for EnvHotPoll
 	DefaultHotPoll = 200 * time.Millisecond
+	// DefaultCapAdd is the default value for EnvDockerCapAdd, the capabilities images commonly need to
+	// start as root and drop to another user
+	DefaultCapAdd = "CHOWN DAC_OVERRIDE FOWNER FSETID KILL SETGID SETUID NET_BIND_SERVICE"
 
 	// TODO(reed): none of these consts above or below should be exported yo
 
@@ -126,11 +279,37 @@ func NewConfig() (*Config, error) {
 		MaxLogSize:       1 * 1024 * 1024,
 		PreForkImage:     "busybox",
 		PreForkCmd:       "tail -f /dev/null",
+		DockerCapDrop:    "ALL",
+		DockerCapAdd:     DefaultCapAdd,
+
+		PriorityClasses:      "interactive,default,batch",
+		DefaultPriorityClass: "default",
 	}
 
 	var err error
 
 	err = setEnvMsecs(err, EnvFreezeIdle, &cfg.FreezeIdle, 50*time.Millisecond)
+	err = setEnvMsecs(err, EnvPausedTTL, &cfg.PausedTTL, 0)
+	err = setEnvStr(err, EnvEvictionPolicy, &cfg.EvictionPolicy)
+	err = setEnvUint(err, EnvEvictMemPressure, &cfg.EvictMemPressure)
+	err = setEnvUint(err, EnvEvictMemPSI, &cfg.EvictMemPSI)
+	err = setEnvUint(err, EnvMemOvercommit, &cfg.MemOvercommit)
+	err = setEnvUint(err, EnvCPUOvercommit, &cfg.CPUOvercommit)
+	err = setEnvStr(err, EnvPinnedCPUs, &cfg.PinnedCPUs)
+	err = setEnvStr(err, EnvVolumesDir, &cfg.VolumesDir)
+	err = setEnvStr(err, EnvAssetsDir, &cfg.AssetsDir)
+	err = setEnvStr(err, EnvAssetsManifestURL, &cfg.AssetsManifestURL)
+	err = setEnvMsecs(err, EnvAssetsPoll, &cfg.AssetsPoll, time.Minute)
+	err = setEnvMsecs(err, EnvFnMetricsWindow, &cfg.FnMetricsWindow, time.Minute)
+	err = setEnvUint(err, EnvMaxInflightCalls, &cfg.MaxInflightCalls)
+	err = setEnvStr(err, EnvPriorityClasses, &cfg.PriorityClasses)
+	err = setEnvStr(err, EnvDefaultPriorityClass, &cfg.DefaultPriorityClass)
+	err = setEnvStr(err, EnvInstanceID, &cfg.InstanceID)
+	err = setEnvMsecs(err, EnvDockerReapInterval, &cfg.DockerReapInterval, 0)
+	err = setEnvMsecs(err, EnvCheckpointIdle, &cfg.CheckpointIdle, 0)
+	err = setEnvStr(err, EnvCheckpointDir, &cfg.CheckpointDir)
+	err = setEnvStr(err, EnvChaos, &cfg.Chaos)
+	err = setEnvBool(err, EnvChaosEnable, &cfg.EnableChaos)
 	err = setEnvMsecs(err, EnvHotPoll, &cfg.HotPoll, DefaultHotPoll)
 	err = setEnvMsecs(err, EnvHotLauncherTimeout, &cfg.HotLauncherTimeout, time.Duration(60)*time.Minute)
 	err = setEnvMsecs(err, EnvHotPullTimeout, &cfg.HotPullTimeout, time.Duration(10)*time.Minute)
@@ -139,6 +318,7 @@ func NewConfig() (*Config, error) {
 	err = setEnvMsecs(err, EnvDetachedHeadroom, &cfg.DetachedHeadRoom, time.Duration(360)*time.Second)
 	err = setEnvUint(err, EnvMaxResponseSize, &cfg.MaxResponseSize)
 	err = setEnvUint(err, EnvMaxLogSize, &cfg.MaxLogSize)
+	err = setEnvUint(err, EnvMaxCallLogSize, &cfg.MaxCallLogSize)
 	err = setEnvUint(err, EnvMaxTotalCPU, &cfg.MaxTotalCPU)
 	err = setEnvUint(err, EnvMaxTotalMemory, &cfg.MaxTotalMemory)
 	err = setEnvUint(err, EnvMaxFsSize, &cfg.MaxFsSize)
@@ -147,8 +327,25 @@ func NewConfig() (*Config, error) {
 	err = setEnvStr(err, EnvPreForkCmd, &cfg.PreForkCmd)
 	err = setEnvUint(err, EnvPreForkUseOnce, &cfg.PreForkUseOnce)
 	err = setEnvStr(err, EnvPreForkNetworks, &cfg.PreForkNetworks)
+	err = setEnvUint(err, EnvPreForkPoolMaxSize, &cfg.PreForkPoolMaxSize)
+	err = setEnvUint(err, EnvPreForkLowWater, &cfg.PreForkLowWater)
+	err = setEnvUint(err, EnvPreForkHighWater, &cfg.PreForkHighWater)
+	err = setEnvMsecs(err, EnvPreForkScaleInterval, &cfg.PreForkScaleInterval, time.Duration(5)*time.Second)
 	err = setEnvStr(err, EnvDockerNetworks, &cfg.DockerNetworks)
 	err = setEnvStr(err, EnvDockerLoadFile, &cfg.DockerLoadFile)
+	err = setEnvStr(err, EnvBlkioDevices, &cfg.BlkioDevices)
+	err = setEnvStr(err, EnvDockerDNS, &cfg.DockerDNS)
+	err = setEnvStr(err, EnvDockerDNSSearch, &cfg.DockerDNSSearch)
+	err = setEnvStr(err, EnvDockerExtraHosts, &cfg.DockerExtraHosts)
+	err = setEnvStr(err, EnvHTTPProxy, &cfg.HTTPProxy)
+	err = setEnvStr(err, EnvHTTPSProxy, &cfg.HTTPSProxy)
+	err = setEnvStr(err, EnvNoProxy, &cfg.NoProxy)
+	err = setEnvStr(err, EnvDockerUser, &cfg.DockerUser)
+	err = setEnvBool(err, EnvDockerRequireNonRoot, &cfg.DockerRequireNonRoot)
+	err = setEnvStr(err, EnvDockerCapDrop, &cfg.DockerCapDrop)
+	err = setEnvStr(err, EnvDockerCapAdd, &cfg.DockerCapAdd)
+	err = setEnvStr(err, EnvDockerCapAllowed, &cfg.DockerCapAllowed)
+	err = setEnvBool(err, EnvDockerAllowNewPrivs, &cfg.DockerAllowNewPrivs)
 	err = setEnvUint(err, EnvMaxTmpFsInodes, &cfg.MaxTmpFsInodes)
 	err = setEnvStr(err, EnvIOFSPath, &cfg.IOFSAgentPath)
 	err = setEnvStr(err, EnvIOFSDockerPath, &cfg.IOFSMountRoot)
@@ -166,6 +363,35 @@ func NewConfig() (*Config, error) {
 		// for safety during uint64 to int conversions in Write()/Read(), etc.
 		return cfg, fmt.Errorf("error invalid %s %v > %v", EnvMaxLogSize, cfg.MaxLogSize, math.MaxInt64)
 	}
+	if cfg.MaxCallLogSize > math.MaxInt64 {
+		return cfg, fmt.Errorf("error invalid %s %v > %v", EnvMaxCallLogSize, cfg.MaxCallLogSize, math.MaxInt64)
+	}
+	if cfg.EvictMemPressure > 100 {
+		return cfg, fmt.Errorf("error invalid %s %v > 100", EnvEvictMemPressure, cfg.EvictMemPressure)
+	}
+	if cfg.EvictMemPSI > 100 {
+		return cfg, fmt.Errorf("error invalid %s %v > 100", EnvEvictMemPSI, cfg.EvictMemPSI)
+	}
+	if cfg.MemOvercommit != 0 && cfg.MemOvercommit < 100 {
+		return cfg, fmt.Errorf("error invalid %s %v < 100", EnvMemOvercommit, cfg.MemOvercommit)
+	}
+	if cfg.CPUOvercommit != 0 && cfg.CPUOvercommit < 100 {
+		return cfg, fmt.Errorf("error invalid %s %v < 100", EnvCPUOvercommit, cfg.CPUOvercommit)
+	}
+	if _, err := parseCPUList(cfg.PinnedCPUs); err != nil {
+		return cfg, fmt.Errorf("error invalid %s: %v", EnvPinnedCPUs, err)
+	}
+	if !hasClass(parsePriorityClasses(cfg.PriorityClasses), cfg.DefaultPriorityClass) {
+		return cfg, fmt.Errorf("error invalid %s %s, must be one of %s", EnvDefaultPriorityClass, cfg.DefaultPriorityClass, cfg.PriorityClasses)
+	}
+	if _, err := chaos.FromSpec(cfg.Chaos, cfg.EnableChaos); err != nil {
+		return cfg, fmt.Errorf("error invalid %s: %v", EnvChaos, err)
+	}
+	switch cfg.EvictionPolicy {
+	case "", EvictionPolicyFIFO, EvictionPolicyLRU:
+	default:
+		return cfg, fmt.Errorf("error invalid %s %s, must be one of [%s, %s]", EnvEvictionPolicy, cfg.EvictionPolicy, EvictionPolicyFIFO, EvictionPolicyLRU)
+	}
 
 	return cfg, nil
 }
@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// numaNodesDir lists the NUMA nodes of the host, with the cores of each
+const numaNodesDir = "/sys/devices/system/node"
+
+// cpuAllocator assigns dedicated cores to the hot containers of fns pinning
+// their cpus, see models.FnCPUPinningAnnotation. The cores of a container are
+// taken from a single NUMA node if one has enough free cores, the one with the
+// fewest, so that containers allocate memory local to their cores and larger
+// sets of cores stay free on other nodes.
+type cpuAllocator struct {
+	lock sync.Mutex
+	// free cores by NUMA node, -1 for cores of unknown nodes
+	free map[int][]int
+	// cores assigned to each container
+	owners map[string]*cpuSet
+}
+
+// cpuSet is the cores a container is pinned to and the NUMA nodes it
+// allocates memory from
+type cpuSet struct {
+	cpus  []int
+	mems  []int
+	nodes map[int][]int // cores by node, to release them
+}
+
+// newCPUAllocator allocates cores, whose NUMA node is in nodes, if known
+func newCPUAllocator(cores []int, nodes map[int]int) *cpuAllocator {
+	a := &cpuAllocator{
+		free:   make(map[int][]int),
+		owners: make(map[string]*cpuSet),
+	}
+	for _, core := range cores {
+		node, ok := nodes[core]
+		if !ok {
+			node = -1
+		}
+		a.free[node] = append(a.free[node], core)
+	}
+	return a
+}
+
+// allocate assigns n cores to the container owner, or returns false if
+// fewer than n cores are free
+func (a *cpuAllocator) allocate(owner string, n int) (*cpuSet, bool) {
+	if a == nil || n < 1 {
+		return nil, false
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	total := 0
+	nodes := make([]int, 0, len(a.free))
+	for node, cores := range a.free {
+		total += len(cores)
+		nodes = append(nodes, node)
+	}
+	if total < n {
+		return nil, false
+	}
+
+	// best fit within a node, or the nodes with the most free cores first
+	sort.Slice(nodes, func(i, j int) bool {
+		if len(a.free[nodes[i]]) != len(a.free[nodes[j]]) {
+			return len(a.free[nodes[i]]) > len(a.free[nodes[j]])
+		}
+		return nodes[i] < nodes[j]
+	})
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if len(a.free[nodes[i]]) >= n {
+			nodes = []int{nodes[i]}
+			break
+		}
+	}
+
+	set := &cpuSet{nodes: make(map[int][]int)}
+	for _, node := range nodes {
+		if n == 0 {
+			break
+		}
+		take := minInt(n, len(a.free[node]))
+		if take == 0 {
+			continue
+		}
+		cores := a.free[node][:take]
+		a.free[node] = a.free[node][take:]
+		n -= take
+
+		set.cpus = append(set.cpus, cores...)
+		set.nodes[node] = cores
+		if node >= 0 {
+			set.mems = append(set.mems, node)
+		}
+	}
+	sort.Ints(set.cpus)
+	sort.Ints(set.mems)
+
+	a.owners[owner] = set
+	return set, true
+}
+
+// release frees the cores of the container owner
+func (a *cpuAllocator) release(owner string) {
+	if a == nil {
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	set, ok := a.owners[owner]
+	if !ok {
+		return
+	}
+	delete(a.owners, owner)
+	for node, cores := range set.nodes {
+		a.free[node] = append(a.free[node], cores...)
+		sort.Ints(a.free[node])
+	}
+}
+
+// coresFor returns the number of cores that fit milli cpus, at least one
+func coresFor(cpus uint64) int {
+	return maxInt(1, int((cpus+999)/1000))
+}
+
+func minInt(a, b int) int {
+	if a <= b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a >= b {
+		return a
+	}
+	return b
+}
+
+// parseCPUList parses a list of cores in the cpuset format, eg. 0-3,8
+func parseCPUList(list string) ([]int, error) {
+	var cores []int
+	for _, part := range strings.Split(strings.TrimSpace(list), ",") {
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid cpu list %q", list)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpu list %q", list)
+			}
+		}
+		for core := first; core <= last; core++ {
+			cores = append(cores, core)
+		}
+	}
+	return cores, nil
+}
+
+// formatCPUList formats sorted cores in the cpuset format
+func formatCPUList(cores []int) string {
+	var parts []string
+	for i := 0; i < len(cores); {
+		j := i
+		for j+1 < len(cores) && cores[j+1] == cores[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(cores[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", cores[i], cores[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// readNUMANodes returns the NUMA node of each core of the host, none if the
+// host does not report its NUMA topology
+func readNUMANodes() map[int]int {
+	nodes := make(map[int]int)
+	dirs, _ := filepath.Glob(filepath.Join(numaNodesDir, "node[0-9]*"))
+	for _, dir := range dirs {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			continue
+		}
+		cores, err := parseCPUList(string(b))
+		if err != nil {
+			continue
+		}
+		for _, core := range cores {
+			nodes[core] = node
+		}
+	}
+	return nodes
+}
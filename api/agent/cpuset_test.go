@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCPUList(t *testing.T) {
+	cores, err := parseCPUList("0-3,8,10-11\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cores, []int{0, 1, 2, 3, 8, 10, 11}) {
+		t.Fatalf("unexpected cores %v", cores)
+	}
+	if list := formatCPUList(cores); list != "0-3,8,10-11" {
+		t.Fatalf("unexpected cpu list %s", list)
+	}
+	for _, invalid := range []string{"a", "3-1", "-1", "1-"} {
+		if _, err := parseCPUList(invalid); err == nil {
+			t.Fatalf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestCPUAllocator(t *testing.T) {
+	// two NUMA nodes of 4 cores, core 8 on an unknown node
+	nodes := map[int]int{0: 0, 1: 0, 2: 0, 3: 0, 4: 1, 5: 1, 6: 1, 7: 1}
+	a := newCPUAllocator([]int{1, 2, 3, 4, 5, 6, 7, 8}, nodes)
+
+	// best fit: the node with the fewest free cores that fits
+	set, ok := a.allocate("a", 3)
+	if !ok || formatCPUList(set.cpus) != "1-3" || formatCPUList(set.mems) != "0" {
+		t.Fatalf("expected the cores of node 0, got %+v", set)
+	}
+	set, ok = a.allocate("b", 2)
+	if !ok || formatCPUList(set.cpus) != "4-5" || formatCPUList(set.mems) != "1" {
+		t.Fatalf("expected cores of node 1, got %+v", set)
+	}
+
+	// no node fits, cores are spread over nodes
+	set, ok = a.allocate("c", 3)
+	if !ok || formatCPUList(set.cpus) != "6-8" || formatCPUList(set.mems) != "1" {
+		t.Fatalf("expected cores spread over nodes, got %+v", set)
+	}
+	if _, ok := a.allocate("d", 1); ok {
+		t.Fatal("expected no free cores")
+	}
+
+	a.release("a")
+	a.release("a")
+	set, ok = a.allocate("d", 3)
+	if !ok || formatCPUList(set.cpus) != "1-3" {
+		t.Fatalf("expected released cores to be allocated again, got %+v", set)
+	}
+
+	if coresFor(0) != 1 || coresFor(1000) != 1 || coresFor(1500) != 2 {
+		t.Fatal("unexpected number of cores for milli cpus")
+	}
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/fnproject/fn/api/common"
@@ -20,6 +21,14 @@ type ReadDataAccess interface {
 	GetFnByID(ctx context.Context, fnId string) (*models.Fn, error)
 }
 
+// FnRevisionReadAccess is implemented by a ReadDataAccess which can read the
+// revisions of fns, see models.FnRevisionStore
+type FnRevisionReadAccess interface {
+	// GetFnRevision returns a revision of a fn, or
+	// models.ErrFnRevisionsUnsupported if revisions are not kept.
+	GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error)
+}
+
 //DequeueDataAccess abstracts an underlying dequeue for async runners
 type DequeueDataAccess interface {
 	// Dequeue will query the queue for the next available Call that can be run
@@ -46,6 +55,15 @@ type CallHandler interface {
 	Finish(ctx context.Context, mCall *models.Call, stderr io.Reader, async bool) error
 }
 
+// DeadLetterHandler is implemented by call handlers that can retry failed async
+// calls and keep the ones that used up their attempts
+type DeadLetterHandler interface {
+	EnqueueDataAccess
+
+	// DeadLetter adds a call that failed all its attempts to the dead letter queue of its fn.
+	DeadLetter(ctx context.Context, mCall *models.Call) error
+}
+
 // DataAccess is currently
 type DataAccess interface {
 	ReadDataAccess
@@ -53,7 +71,20 @@ type DataAccess interface {
 	CallHandler
 }
 
-// CachedDataAccess wraps a DataAccess and caches the results of GetApp.
+// DefaultReadCacheTTL is how long NewCachedDataAccess caches apps, fns and triggers
+const DefaultReadCacheTTL = 5 * time.Second
+
+// ChangeWatcher is implemented by a ReadDataAccess caching datastore reads,
+// which can drop the objects changed in the datastore from its cache.
+type ChangeWatcher interface {
+	// WatchChanges invalidates the cache on the changes of cf until ctx is
+	// done. It returns models.ErrChangeFeedUnsupported if cf cannot report
+	// changes, cached objects then expire after their TTL only.
+	WatchChanges(ctx context.Context, cf models.ChangeFeed) error
+}
+
+// cachedDataAccess wraps a ReadDataAccess and caches the apps, fns and
+// triggers it returns, errors are not cached.
 type cachedDataAccess struct {
 	ReadDataAccess
 
@@ -61,10 +92,20 @@ type cachedDataAccess struct {
 	singleflight singleflight.SingleFlight
 }
 
+// NewCachedDataAccess caches the reads of da for DefaultReadCacheTTL
 func NewCachedDataAccess(da ReadDataAccess) ReadDataAccess {
+	return NewCachedDataAccessTTL(da, DefaultReadCacheTTL)
+}
+
+// NewCachedDataAccessTTL caches the reads of da for ttl, or for
+// DefaultReadCacheTTL if ttl is not positive.
+func NewCachedDataAccessTTL(da ReadDataAccess, ttl time.Duration) ReadDataAccess {
+	if ttl <= 0 {
+		ttl = DefaultReadCacheTTL
+	}
 	cda := &cachedDataAccess{
 		ReadDataAccess: da,
-		cache:          cache.New(5*time.Second, 1*time.Minute),
+		cache:          cache.New(ttl, 1*time.Minute),
 	}
 	return cda
 }
@@ -73,28 +114,160 @@ func appIDCacheKey(appID string) string {
 	return "a:" + appID
 }
 
+func appNameCacheKey(appName string) string {
+	return "n:" + appName
+}
+
+func fnIDCacheKey(fnID string) string {
+	return "f:" + fnID
+}
+
+func fnRevisionCacheKey(fnID, revisionID string) string {
+	return "r:" + fnID + ":" + revisionID
+}
+
+// triggerCacheKeyPrefix is the prefix of the keys of all the triggers of an app
+func triggerCacheKeyPrefix(appID string) string {
+	return "t:" + appID + ":"
+}
+
+func triggerSourceCacheKey(appID, triggerType, source string) string {
+	return triggerCacheKeyPrefix(appID) + triggerType + ":" + source
+}
+
+// get returns the cached value of key, or caches the value load returns
+func (da *cachedDataAccess) get(ctx context.Context, kind, key string, load func() (interface{}, error)) (interface{}, error) {
+	if v, ok := da.cache.Get(key); ok {
+		statsReadCacheHit(ctx, kind)
+		return v, nil
+	}
+	statsReadCacheMiss(ctx, kind)
+
+	v, err := da.singleflight.Do(key, load)
+	if err != nil {
+		return nil, err
+	}
+	da.cache.Set(key, v, cache.DefaultExpiration)
+	return v, nil
+}
+
 func (da *cachedDataAccess) GetAppID(ctx context.Context, appName string) (string, error) {
-	return da.ReadDataAccess.GetAppID(ctx, appName)
+	v, err := da.get(ctx, models.ChangeKindApp, appNameCacheKey(appName),
+		func() (interface{}, error) {
+			return da.ReadDataAccess.GetAppID(ctx, appName)
+		})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
 }
 
 func (da *cachedDataAccess) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
-	key := appIDCacheKey(appID)
-	app, ok := da.cache.Get(key)
-	if ok {
-		return app.(*models.App), nil
+	v, err := da.get(ctx, models.ChangeKindApp, appIDCacheKey(appID),
+		func() (interface{}, error) {
+			return da.ReadDataAccess.GetAppByID(ctx, appID)
+		})
+	if err != nil {
+		return nil, err
 	}
+	return v.(*models.App), nil
+}
 
-	resp, err := da.singleflight.Do(key,
+func (da *cachedDataAccess) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	v, err := da.get(ctx, models.ChangeKindFn, fnIDCacheKey(fnID),
 		func() (interface{}, error) {
-			return da.ReadDataAccess.GetAppByID(ctx, appID)
+			return da.ReadDataAccess.GetFnByID(ctx, fnID)
 		})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.Fn), nil
+}
+
+// GetFnRevision implements FnRevisionReadAccess if the wrapped
+// ReadDataAccess does
+func (da *cachedDataAccess) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	ra, ok := da.ReadDataAccess.(FnRevisionReadAccess)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	v, err := da.get(ctx, models.ChangeKindFn, fnRevisionCacheKey(fnID, revisionID),
+		func() (interface{}, error) {
+			return ra.GetFnRevision(ctx, fnID, revisionID)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.FnRevision), nil
+}
 
+func (da *cachedDataAccess) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	v, err := da.get(ctx, models.ChangeKindTrigger, triggerSourceCacheKey(appID, triggerType, source),
+		func() (interface{}, error) {
+			return da.ReadDataAccess.GetTriggerBySource(ctx, appID, triggerType, source)
+		})
 	if err != nil {
 		return nil, err
 	}
-	app = resp.(*models.App)
-	da.cache.Set(key, app, cache.DefaultExpiration)
-	return app.(*models.App), nil
+	return v.(*models.Trigger), nil
+}
+
+// WatchChanges implements ChangeWatcher. If the feed stops before ctx is done
+// changes may have been missed, so the cache is flushed and the feed
+// subscribed to again.
+func (da *cachedDataAccess) WatchChanges(ctx context.Context, cf models.ChangeFeed) error {
+	for {
+		changes, err := cf.Changes(ctx)
+		if err == models.ErrChangeFeedUnsupported {
+			return err
+		}
+		if err != nil {
+			common.Logger(ctx).WithError(err).Warn("Error subscribing to datastore changes, retrying")
+		} else {
+			for c := range changes {
+				da.invalidate(c)
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		da.cache.Flush()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (da *cachedDataAccess) invalidate(c *models.Change) {
+	switch c.Kind {
+	case models.ChangeKindApp:
+		if c.Op == models.ChangeOpDelete {
+			// the fns and triggers of the app are gone too, and its name
+			// may be taken by another app
+			da.cache.Flush()
+			return
+		}
+		da.cache.Delete(appIDCacheKey(c.ObjectID))
+	case models.ChangeKindFn:
+		da.cache.Delete(fnIDCacheKey(c.ObjectID))
+		if c.Op == models.ChangeOpDelete {
+			da.deletePrefix(triggerCacheKeyPrefix(c.AppID))
+		}
+	case models.ChangeKindTrigger:
+		// triggers are cached by source, which the change does not carry
+		da.deletePrefix(triggerCacheKeyPrefix(c.AppID))
+	}
+}
+
+func (da *cachedDataAccess) deletePrefix(prefix string) {
+	for key := range da.cache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			da.cache.Delete(key)
+		}
+	}
 }
 
 type directDataAccess struct {
@@ -146,14 +319,38 @@ func (da *directDataAccess) Enqueue(ctx context.Context, mCall *models.Call) err
 	// TODO: Insert a call in the datastore with the 'queued' state
 }
 
+func (da *directDataAccess) DeadLetter(ctx context.Context, mCall *models.Call) error {
+	dl, ok := da.ls.(models.DeadLetterStore)
+	if !ok {
+		return models.ErrDeadLettersUnsupported
+	}
+	return dl.InsertDeadLetter(ctx, mCall)
+}
+
 func (da *directDataAccess) Start(ctx context.Context, mCall *models.Call) error {
 	// TODO Access datastore and try a Compare-And-Swap to set the call to
 	// 'running'. If it fails, delete the message from the MQ and return an
 	// error. If it is successful, don't do anything - the message will be
 	// removed when the call Finish'es.
 
+	// calls cancelled while they were queued are recorded as such, they are
+	// dropped here
+	if cancelled, err := models.IsCancelledCall(ctx, da.ls, mCall); cancelled || err != nil {
+		if err == nil {
+			err = models.ErrCallCancelled
+			if derr := da.mq.Delete(ctx, mCall); derr != nil {
+				common.Logger(ctx).WithError(derr).Error("error deleting cancelled call from the queue")
+			}
+		}
+		return err
+	}
+
 	// At the moment we don't have the queued/running/finished mechanics so we
-	// remove the message here.
+	// remove the message here, unless the queue keeps the reservation alive
+	// until the call finished.
+	if models.KeepsReservations(da.mq) {
+		return nil
+	}
 	return da.mq.Delete(ctx, mCall)
 }
 
@@ -171,11 +368,12 @@ func (da *directDataAccess) Finish(ctx context.Context, mCall *models.Call, stde
 		// note: Not returning err here since the job could have already finished successfully.
 	}
 
-	if async {
-		// XXX (reed): delete MQ message, eventually
-		// YYY (hhexo): yes, once we have the queued/running/finished mechanics
-		// return cda.mq.Delete(ctx, mCall)
+	if async && models.KeepsReservations(da.mq) {
+		return da.mq.Delete(ctx, mCall)
 	}
+	// XXX (reed): delete MQ message, eventually
+	// YYY (hhexo): yes, once we have the queued/running/finished mechanics
+	// return cda.mq.Delete(ctx, mCall)
 	return nil
 }
 
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// countingReadAccess serves a single fn and trigger, counting the reads
+type countingReadAccess struct {
+	sync.Mutex
+	fn      *models.Fn
+	trigger *models.Trigger
+	reads   int
+}
+
+func (c *countingReadAccess) GetAppID(ctx context.Context, appName string) (string, error) {
+	return "", models.ErrAppsNotFound
+}
+
+func (c *countingReadAccess) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	return nil, models.ErrAppsNotFound
+}
+
+func (c *countingReadAccess) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.reads++
+	return c.trigger.Clone(), nil
+}
+
+func (c *countingReadAccess) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.reads++
+	if c.fn == nil {
+		return nil, models.ErrFnsNotFound
+	}
+	return c.fn.Clone(), nil
+}
+
+func (c *countingReadAccess) readCount() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.reads
+}
+
+type chanFeed chan *models.Change
+
+func (f chanFeed) Changes(ctx context.Context) (<-chan *models.Change, error) {
+	return f, nil
+}
+
+func TestCachedDataAccessInvalidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := &countingReadAccess{
+		fn:      &models.Fn{ID: "fn", AppID: "app", Image: "fnproject/hello:0.0.1"},
+		trigger: &models.Trigger{ID: "trigger", AppID: "app", FnID: "fn", Type: "http", Source: "/hello"},
+	}
+	da := NewCachedDataAccessTTL(src, time.Hour)
+
+	feed := make(chanFeed)
+	go da.(ChangeWatcher).WatchChanges(ctx, feed)
+
+	for i := 0; i < 3; i++ {
+		if _, err := da.GetFnByID(ctx, "fn"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := da.GetTriggerBySource(ctx, "app", "http", "/hello"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := src.readCount(); n != 2 {
+		t.Fatalf("expected the fn and trigger to be read once, got %d reads", n)
+	}
+
+	// the watcher handles a change once it received the next one
+	feed <- &models.Change{Kind: models.ChangeKindFn, Op: models.ChangeOpUpdate, ObjectID: "fn", AppID: "app"}
+	feed <- &models.Change{Kind: models.ChangeKindApp, Op: models.ChangeOpUpdate, ObjectID: "other", AppID: "other"}
+
+	if _, err := da.GetFnByID(ctx, "fn"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := da.GetTriggerBySource(ctx, "app", "http", "/hello"); err != nil {
+		t.Fatal(err)
+	}
+	if n := src.readCount(); n != 3 {
+		t.Fatalf("expected only the updated fn to be read again, got %d reads", n)
+	}
+
+	// deleting a fn deletes its triggers
+	feed <- &models.Change{Kind: models.ChangeKindFn, Op: models.ChangeOpDelete, ObjectID: "fn", AppID: "app"}
+	feed <- &models.Change{Kind: models.ChangeKindApp, Op: models.ChangeOpUpdate, ObjectID: "other", AppID: "other"}
+
+	src.Lock()
+	src.fn = nil
+	src.Unlock()
+	if _, err := da.GetTriggerBySource(ctx, "app", "http", "/hello"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := da.GetFnByID(ctx, "fn"); err != models.ErrFnsNotFound {
+		t.Fatalf("expected the deleted fn not to be found, got %v", err)
+	}
+	if _, err := da.GetFnByID(ctx, "fn"); err != models.ErrFnsNotFound {
+		t.Fatalf("expected the deleted fn not to be cached, got %v", err)
+	}
+	if n := src.readCount(); n != 6 {
+		t.Fatalf("expected the trigger and fn to be read again, got %d reads", n)
+	}
+}
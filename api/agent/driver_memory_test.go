@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/agent/drivers/memory"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestMemoryDriver(t *testing.T) {
+	app := &models.App{ID: "app_id"}
+	fn := &models.Fn{
+		ID:    "fn_id",
+		Image: "fnproject/memory",
+		ResourceConfig: models.ResourceConfig{
+			Timeout:     1,
+			IdleTimeout: 10,
+			Memory:      128,
+		},
+	}
+
+	// calls tell the container what to do with their body
+	drv := memory.New(func(_ drivers.ContainerTask, _ *http.Request, body []byte) memory.Behavior {
+		switch string(body) {
+		case "fail":
+			return memory.Behavior{Status: http.StatusBadGateway}
+		case "sleep":
+			return memory.Behavior{Sleep: time.Minute}
+		case "exit":
+			return memory.Behavior{ExitCode: 1}
+		case "oom":
+			return memory.Behavior{OOM: true}
+		}
+		return memory.Behavior{Output: append([]byte("echo "), body...)}
+	})
+
+	a := New(NewDirectCallDataAccess(logs.NewMock(), new(mqs.Mock)), WithDockerDriver(drv))
+	defer checkClose(t, a)
+
+	submit := func(body string) (*httptest.ResponseRecorder, error) {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:8080/invoke/"+fn.ID, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		call, err := a.GetCall(FromHTTPFnRequest(app, fn, req), WithWriter(rec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec, a.Submit(call)
+	}
+
+	for i := 0; i < 100; i++ {
+		rec, err := submit("yodawg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusOK || rec.Body.String() != "echo yodawg" {
+			t.Fatalf("Unexpected response %d %q", rec.Code, rec.Body.String())
+		}
+	}
+	if drv.Calls() != 100 || drv.Containers() > 10 {
+		t.Fatalf("Expected calls to be served by hot containers, got %d containers for %d calls", drv.Containers(), drv.Calls())
+	}
+
+	if _, err := submit("fail"); err != models.ErrFunctionFailed {
+		t.Fatalf("Expected the call to fail, got %v", err)
+	}
+	if _, err := submit("sleep"); err != models.ErrCallTimeout {
+		t.Fatalf("Expected the call to time out, got %v", err)
+	}
+	for _, body := range []string{"exit", "oom"} {
+		if _, err := submit(body); err != models.ErrFunctionResponse {
+			t.Fatalf("Expected the call to get no response from the container, got %v", err)
+		}
+	}
+
+	// the timed out call and exits stopped the containers they ran in
+	if rec, err := submit("yodawg"); err != nil || rec.Body.String() != "echo yodawg" {
+		t.Fatalf("Expected the call to be served after containers exited, got %v", err)
+	}
+}
+
+// lockedLogStore serializes the calls of concurrent calls to a log store
+type lockedLogStore struct {
+	mu sync.Mutex
+	models.LogStore
+}
+
+func (l *lockedLogStore) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.LogStore.InsertLog(ctx, call, callLog)
+}
+
+func (l *lockedLogStore) InsertCall(ctx context.Context, call *models.Call) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.LogStore.InsertCall(ctx, call)
+}
+
+func (l *lockedLogStore) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.LogStore.GetLog(ctx, fnID, callID)
+}
+
+func TestMemoryDriverConcurrentCallLogs(t *testing.T) {
+	app := &models.App{ID: "app_id"}
+	annotations, _ := models.EmptyAnnotations().With(models.FnConcurrencyAnnotation, 2)
+	fn := &models.Fn{
+		ID:          "fn_id",
+		Image:       "fnproject/memory",
+		Annotations: annotations,
+		ResourceConfig: models.ResourceConfig{
+			Timeout:     5,
+			IdleTimeout: 10,
+			Memory:      128,
+		},
+	}
+
+	// calls overlap, and log what they were called with
+	drv := memory.New(func(_ drivers.ContainerTask, _ *http.Request, body []byte) memory.Behavior {
+		return memory.Behavior{Sleep: 100 * time.Millisecond, Stderr: "called with " + string(body) + "\n", Output: body}
+	})
+	ls := &lockedLogStore{LogStore: logs.NewMock()}
+	a := New(NewDirectCallDataAccess(ls, new(mqs.Mock)), WithDockerDriver(drv))
+	defer checkClose(t, a)
+
+	submit := func(body string) (string, error) {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:8080/invoke/"+fn.ID, strings.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		call, err := a.GetCall(FromHTTPFnRequest(app, fn, req), WithWriter(httptest.NewRecorder()))
+		if err != nil {
+			return "", err
+		}
+		if err := a.Submit(call); err != nil {
+			return "", err
+		}
+		log, err := ls.GetLog(context.Background(), fn.ID, call.Model().ID)
+		if err != nil {
+			return "", err
+		}
+		b, err := ioutil.ReadAll(log)
+		return string(b), err
+	}
+
+	// the container is up before the calls, for them to run in it at once
+	if _, err := submit("warmup"); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	callLogs := make([]string, 4)
+	errs := make([]error, len(callLogs))
+	for i := range callLogs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			callLogs[i], errs[i] = submit(fmt.Sprintf("call%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, log := range callLogs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if !strings.Contains(log, fmt.Sprintf("called with call%d\n", i)) {
+			t.Errorf("Expected call %d to have its log, got %q", i, log)
+		}
+	}
+}
@@ -11,4 +11,11 @@
 //
 // The mock driver pretends to run functions but doesn't actually run them. This
 // is for testing only.
+//
+// Memory Driver
+//
+// The memory driver runs functions in memory, serving their calls over the
+// same unix socket as an FDK would with scripted responses, delays, exits and
+// out of memory kills. This is for testing the agent and servers without
+// Docker only.
 package drivers
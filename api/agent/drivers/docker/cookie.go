@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
 
 	"github.com/fsouza/go-dockerclient"
@@ -38,34 +40,82 @@ type cookie struct {
 	imgRepo     string
 	imgTag      string
 	imgAuthConf *docker.AuthConfiguration
+	// digest and platform the image resolved to, see resolveImage
+	imgDigest   string
+	imgPlatform string
+
+	// checkpoint state, if container is checkpointed
+	cp checkpointState
+
+	// init and sidecar containers of the task, if any, and the names of
+	// those created, to remove them with the container
+	init, sidecar *drivers.Companion
+	sidecarName   string
+	companions    []string
+}
+
+type checkpointState struct {
+	lock sync.Mutex
+	// id of the current checkpoint, empty if not checkpointed
+	id string
+	// closed when a checkpoint is restored or abandoned
+	restored chan struct{}
+	// streams re-attached on restore, nil if restore failed
+	waiter docker.CloseWaiter
 }
 
 func (c *cookie) configureLogger(log logrus.FieldLogger) {
 
 	conf := c.task.LoggerConfig()
-	if conf.URL == "" {
-		c.opts.HostConfig.LogConfig = docker.LogConfig{
-			Type: "none",
+
+	driver := conf.Driver
+	if driver == "" {
+		driver = "syslog"
+		if conf.URL == "" {
+			driver = "none"
 		}
-		return
 	}
 
 	c.opts.HostConfig.LogConfig = docker.LogConfig{
-		Type: "syslog",
-		Config: map[string]string{
-			"syslog-address":  conf.URL,
-			"syslog-facility": "user",
-			"syslog-format":   "rfc5424",
-		},
+		Type:   driver,
+		Config: map[string]string{},
 	}
 
 	tags := make([]string, 0, len(conf.Tags))
 	for _, pair := range conf.Tags {
 		tags = append(tags, fmt.Sprintf("%s=%s", pair.Name, pair.Value))
 	}
+
+	switch driver {
+	case "none":
+		c.opts.HostConfig.LogConfig.Config = nil
+		return
+	case "syslog":
+		if conf.URL == "" {
+			log.Warn("syslog log driver selected without syslog url, disabling logs")
+			c.opts.HostConfig.LogConfig = docker.LogConfig{Type: "none"}
+			return
+		}
+		c.opts.HostConfig.LogConfig.Config["syslog-address"] = conf.URL
+		c.opts.HostConfig.LogConfig.Config["syslog-facility"] = "user"
+		c.opts.HostConfig.LogConfig.Config["syslog-format"] = "rfc5424"
+	case "fluentd":
+		if conf.URL != "" {
+			c.opts.HostConfig.LogConfig.Config["fluentd-address"] = conf.URL
+		}
+	case "json-file":
+		// json-file has no tag option
+		tags = nil
+	}
+
 	if len(tags) > 0 {
 		c.opts.HostConfig.LogConfig.Config["tag"] = strings.Join(tags, ",")
 	}
+
+	// user supplied options go last so they may override the defaults above, eg. tag templates
+	for k, v := range conf.Options {
+		c.opts.HostConfig.LogConfig.Config[k] = v
+	}
 }
 
 func (c *cookie) configureMem(log logrus.FieldLogger) {
@@ -126,6 +176,13 @@ func (c *cookie) configureIOFS(log logrus.FieldLogger) {
 		return
 	}
 
+	if c.drv.windows() {
+		// windows containers listen on a named pipe forwarded to the host
+		c.opts.HostConfig.Mounts = append(c.opts.HostConfig.Mounts, docker.HostMount{
+			Type: "npipe", Source: path, Target: c.task.UDSDockerDest()})
+		return
+	}
+
 	bind := fmt.Sprintf("%s:%s", path, c.task.UDSDockerDest())
 	c.opts.HostConfig.Binds = append(c.opts.HostConfig.Binds, bind)
 }
@@ -142,7 +199,8 @@ func (c *cookie) configureVolumes(log logrus.FieldLogger) {
 	for _, mapping := range c.task.Volumes() {
 		hostDir := mapping[0]
 		containerDir := mapping[1]
-		c.opts.Config.Volumes[containerDir] = struct{}{}
+		// without mount options, eg. :ro
+		c.opts.Config.Volumes[strings.SplitN(containerDir, ":", 2)[0]] = struct{}{}
 		mapn := fmt.Sprintf("%s:%s", hostDir, containerDir)
 		c.opts.HostConfig.Binds = append(c.opts.HostConfig.Binds, mapn)
 		log.WithFields(logrus.Fields{"volumes": mapn, "call_id": c.task.Id()}).Debug("setting volumes")
@@ -166,6 +224,50 @@ func (c *cookie) configureCPU(log logrus.FieldLogger) {
 	c.opts.HostConfig.CPUPeriod = period
 }
 
+func (c *cookie) configureCPUSet(log logrus.FieldLogger) {
+	pinner, ok := c.task.(drivers.CPUPinner)
+	if !ok {
+		return
+	}
+	cpus, mems := pinner.CPUSet()
+	if cpus == "" {
+		return
+	}
+
+	log.WithFields(logrus.Fields{"cpus": cpus, "mems": mems, "call_id": c.task.Id()}).Debug("setting CPU set")
+	c.opts.HostConfig.CPUSetCPUs = cpus
+	c.opts.HostConfig.CPUSetMEMs = mems
+}
+
+func (c *cookie) configureBlkio(log logrus.FieldLogger) {
+	limiter, ok := c.task.(drivers.IOLimiter)
+	if !ok {
+		return
+	}
+	readBps, writeBps, readIOPS, writeIOPS := limiter.IOLimits()
+	if readBps == 0 && writeBps == 0 && readIOPS == 0 && writeIOPS == 0 {
+		return
+	}
+
+	limits := func(rate uint64) []docker.BlockLimit {
+		if rate == 0 {
+			return nil
+		}
+		var res []docker.BlockLimit
+		for _, dev := range strings.Fields(c.drv.conf.BlkioDevices) {
+			res = append(res, docker.BlockLimit{Path: dev, Rate: int64(rate)})
+		}
+		return res
+	}
+
+	log.WithFields(logrus.Fields{"read_bps": readBps, "write_bps": writeBps, "read_iops": readIOPS, "write_iops": writeIOPS,
+		"devices": c.drv.conf.BlkioDevices, "call_id": c.task.Id()}).Debug("setting blkio")
+	c.opts.HostConfig.BlkioDeviceReadBps = limits(readBps)
+	c.opts.HostConfig.BlkioDeviceWriteBps = limits(writeBps)
+	c.opts.HostConfig.BlkioDeviceReadIOps = limits(readIOPS)
+	c.opts.HostConfig.BlkioDeviceWriteIOps = limits(writeIOPS)
+}
+
 func (c *cookie) configureWorkDir(log logrus.FieldLogger) {
 	wd := c.task.WorkDir()
 	if wd == "" {
@@ -186,6 +288,124 @@ func (c *cookie) configureHostname(log logrus.FieldLogger) {
 	c.opts.Config.Hostname = c.drv.hostname
 }
 
+func (c *cookie) configureDNS(log logrus.FieldLogger) {
+	// containers sharing the network of another container share its resolution
+	if strings.HasPrefix(c.opts.HostConfig.NetworkMode, "container:") {
+		return
+	}
+
+	servers := strings.Fields(c.drv.conf.DNS)
+	search := strings.Fields(c.drv.conf.DNSSearch)
+	extraHosts := strings.Fields(c.drv.conf.ExtraHosts)
+	if d, ok := c.task.(drivers.DNSConfigurer); ok {
+		taskServers, taskSearch, taskHosts := d.DNS()
+		if len(taskServers) > 0 {
+			servers = taskServers
+		}
+		if len(taskSearch) > 0 {
+			search = taskSearch
+		}
+		extraHosts = append(extraHosts, taskHosts...)
+	}
+	if len(servers) == 0 && len(search) == 0 && len(extraHosts) == 0 {
+		return
+	}
+
+	log.WithFields(logrus.Fields{"dns": servers, "dns_search": search, "extra_hosts": extraHosts, "call_id": c.task.Id()}).Debug("setting dns")
+	c.opts.HostConfig.DNS = servers
+	c.opts.HostConfig.DNSSearch = search
+	c.opts.HostConfig.ExtraHosts = extraHosts
+}
+
+func (c *cookie) configureUser(log logrus.FieldLogger) {
+	user := c.drv.conf.User
+	if task, ok := c.task.(drivers.UserConfigurer); ok && task.User() != "" {
+		user = task.User()
+	}
+	if user == "" {
+		return
+	}
+
+	log.WithFields(logrus.Fields{"user": user, "call_id": c.task.Id()}).Debug("setting user")
+	c.opts.Config.User = user
+}
+
+// checkNonRoot rejects the container if it would run as root while the
+// driver requires non-root containers. Without a user configured, the USER of
+// the image is checked, which must have been pulled.
+func (c *cookie) checkNonRoot(ctx context.Context) error {
+	if !c.drv.conf.RequireNonRoot {
+		return nil
+	}
+	user := c.opts.Config.User
+	if user == "" {
+		img, err := c.drv.docker.InspectImage(ctx, c.task.Image())
+		if err != nil {
+			return err
+		}
+		if img.Config != nil {
+			user = img.Config.User
+		}
+	}
+	if models.IsRootUser(user) {
+		return models.NewAPIError(http.StatusForbidden, fmt.Errorf("image '%s' would run as root, which this server forbids, set a non-root user with the %s annotation", c.task.Image(), models.AppUserAnnotation))
+	}
+	return nil
+}
+
+// configureCapabilities drops and adds the capabilities of the driver, then
+// those of the task, which also removes the ones it drops from the ones added
+func (c *cookie) configureCapabilities(log logrus.FieldLogger) {
+	capDrop := strings.Fields(c.drv.conf.CapDrop)
+	capAdd := strings.Fields(c.drv.conf.CapAdd)
+	if task, ok := c.task.(drivers.CapabilityConfigurer); ok {
+		add, drop := task.Capabilities()
+		dropped := make(map[string]bool, len(drop))
+		for _, name := range drop {
+			dropped[name] = true
+		}
+		kept := make([]string, 0, len(capAdd)+len(add))
+		for _, name := range append(capAdd, add...) {
+			if !dropped[name] {
+				kept = append(kept, name)
+			}
+		}
+		capAdd = kept
+		capDrop = append(capDrop, drop...)
+	}
+
+	if c.drv.conf.NoNewPrivileges {
+		c.opts.HostConfig.SecurityOpt = append(c.opts.HostConfig.SecurityOpt, "no-new-privileges:true")
+	}
+	if len(capDrop) == 0 && len(capAdd) == 0 {
+		return
+	}
+
+	log.WithFields(logrus.Fields{"cap_drop": capDrop, "cap_add": capAdd, "call_id": c.task.Id()}).Debug("setting capabilities")
+	c.opts.HostConfig.CapDrop = capDrop
+	c.opts.HostConfig.CapAdd = capAdd
+}
+
+// checkCapabilities rejects the container if its task adds capabilities the
+// driver does not allow tasks to add
+func (c *cookie) checkCapabilities() error {
+	task, ok := c.task.(drivers.CapabilityConfigurer)
+	if !ok {
+		return nil
+	}
+	allowed := make(map[string]bool)
+	for _, name := range strings.Fields(c.drv.conf.CapAdd + " " + c.drv.conf.CapAllowed) {
+		allowed[name] = true
+	}
+	add, _ := task.Capabilities()
+	for _, name := range add {
+		if !allowed[name] {
+			return models.NewAPIError(http.StatusForbidden, fmt.Errorf("capability %s of the %s annotation is not allowed on this server", name, models.AppCapabilitiesAnnotation))
+		}
+	}
+	return nil
+}
+
 func (c *cookie) configureCmd(log logrus.FieldLogger) {
 	if c.task.Command() == "" {
 		return
@@ -198,16 +418,32 @@ func (c *cookie) configureCmd(log logrus.FieldLogger) {
 }
 
 func (c *cookie) configureEnv(log logrus.FieldLogger) {
-	if len(c.task.EnvVars()) == 0 {
-		return
+	env := c.task.EnvVars()
+	for name, val := range env {
+		c.opts.Config.Env = append(c.opts.Config.Env, name+"="+val)
 	}
 
-	if c.opts.Config.Env == nil {
-		c.opts.Config.Env = make([]string, 0, len(c.task.EnvVars()))
+	httpProxy, httpsProxy, noProxy := c.drv.conf.HTTPProxy, c.drv.conf.HTTPSProxy, c.drv.conf.NoProxy
+	if p, ok := c.task.(drivers.Proxier); ok {
+		if h, hs, n, ok := p.Proxy(); ok {
+			httpProxy, httpsProxy, noProxy = h, hs, n
+		}
 	}
 
-	for name, val := range c.task.EnvVars() {
-		c.opts.Config.Env = append(c.opts.Config.Env, name+"="+val)
+	// proxies configured by the fn win, tools read either case
+	for _, proxy := range [][2]string{{"HTTP_PROXY", httpProxy}, {"HTTPS_PROXY", httpsProxy}, {"NO_PROXY", noProxy}} {
+		upper, lower, val := proxy[0], strings.ToLower(proxy[0]), proxy[1]
+		if val == "" {
+			continue
+		}
+		if _, ok := env[upper]; ok {
+			continue
+		}
+		if _, ok := env[lower]; ok {
+			continue
+		}
+		log.WithFields(logrus.Fields{"name": upper, "value": val, "call_id": c.task.Id()}).Debug("setting proxy")
+		c.opts.Config.Env = append(c.opts.Config.Env, upper+"="+val, lower+"="+val)
 	}
 }
 
@@ -217,6 +453,18 @@ func (c *cookie) Close(ctx context.Context) error {
 	if c.isCreated {
 		err = c.drv.removeContainer(ctx, c.task.Id())
 	}
+	c.removeCompanions(ctx)
+
+	c.cp.lock.Lock()
+	if c.cp.id != "" {
+		if c.drv.conf.CheckpointDir != "" {
+			c.drv.docker.RemoveCheckpoint(ctx, c.task.Id(), c.cp.id, c.drv.conf.CheckpointDir)
+		}
+		close(c.cp.restored)
+		c.cp.id = ""
+	}
+	c.cp.lock.Unlock()
+
 	c.drv.unpickPool(c)
 	c.drv.unpickNetwork(c)
 	return err
@@ -224,7 +472,15 @@ func (c *cookie) Close(ctx context.Context) error {
 
 // implements Cookie
 func (c *cookie) Run(ctx context.Context) (drivers.WaitResult, error) {
-	return c.drv.run(ctx, c.task.Id(), c.task)
+	res, err := c.drv.run(ctx, c.task.Id(), c.task)
+	if w, ok := res.(*waitResult); ok {
+		w.cookie = c
+	}
+	if err == nil {
+		// the container is shaped once started, it may send unshaped until then
+		err = c.limitEgress(ctx)
+	}
+	return res, err
 }
 
 // implements Cookie
@@ -234,10 +490,13 @@ func (c *cookie) ContainerOptions() interface{} {
 
 // implements Cookie
 func (c *cookie) Freeze(ctx context.Context) error {
-	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "Freeze"})
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "Freeze"})
 	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker pause")
 
 	err := c.drv.docker.PauseContainer(c.task.Id(), ctx)
+	if err == nil && c.sidecarName != "" {
+		err = c.drv.docker.PauseContainer(c.sidecarName, ctx)
+	}
 	if err != nil {
 		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error pausing container")
 	}
@@ -246,18 +505,134 @@ func (c *cookie) Freeze(ctx context.Context) error {
 
 // implements Cookie
 func (c *cookie) Unfreeze(ctx context.Context) error {
-	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "Unfreeze"})
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "Unfreeze"})
 	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker unpause")
 
-	err := c.drv.docker.UnpauseContainer(c.task.Id(), ctx)
+	var err error
+	if c.sidecarName != "" {
+		err = c.drv.docker.UnpauseContainer(c.sidecarName, ctx)
+	}
+	if err == nil {
+		err = c.drv.docker.UnpauseContainer(c.task.Id(), ctx)
+	}
 	if err != nil {
 		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error unpausing container")
 	}
 	return err
 }
 
+// implements drivers.Checkpointer
+func (c *cookie) Checkpoint(ctx context.Context) error {
+	// CRIU cannot dump a container in the network namespace of another one,
+	// containers with a sidecar are frozen instead
+	if c.sidecarName != "" {
+		return c.Freeze(ctx)
+	}
+
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "Checkpoint"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker checkpoint")
+
+	c.cp.lock.Lock()
+	defer c.cp.lock.Unlock()
+
+	if c.cp.id != "" {
+		return nil
+	}
+
+	// mark the checkpoint before stopping the container, so that the waiter
+	// does not mistake the stop for the container exiting.
+	c.cp.id = id.New().String()
+	c.cp.restored = make(chan struct{})
+
+	err := c.drv.docker.CheckpointContainer(ctx, c.task.Id(), c.cp.id, c.drv.conf.CheckpointDir)
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error checkpointing container")
+		close(c.cp.restored)
+		c.cp.id = ""
+	}
+	return err
+}
+
+// implements drivers.Checkpointer
+func (c *cookie) Restore(ctx context.Context) error {
+	if c.sidecarName != "" {
+		return c.Unfreeze(ctx)
+	}
+
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "Restore"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker restore")
+
+	c.cp.lock.Lock()
+	defer c.cp.lock.Unlock()
+
+	if c.cp.id == "" {
+		return nil
+	}
+
+	// closing restored with a nil waiter on failure lets the waiter see
+	// the container as exited.
+	defer func() {
+		close(c.cp.restored)
+		c.cp.id = ""
+	}()
+
+	// the original attach ended when the container stopped
+	waiter, err := c.drv.attach(ctx, c.task.Id(), c.task)
+	if err == nil {
+		err = c.drv.docker.RestoreContainer(ctx, c.task.Id(), c.cp.id, c.drv.conf.CheckpointDir)
+		if err != nil {
+			waiter.Close()
+		}
+	}
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error restoring container")
+		return err
+	}
+
+	c.cp.waiter = waiter
+	if c.drv.conf.CheckpointDir != "" {
+		c.drv.docker.RemoveCheckpoint(ctx, c.task.Id(), c.cp.id, c.drv.conf.CheckpointDir)
+	}
+	return nil
+}
+
+// awaitRestore is called by the waiter once the container stops. Returns true
+// if the container stopped due to a checkpoint and was restored, in which case
+// the waiter has been swapped to the re-attached streams.
+func (c *cookie) awaitRestore(ctx context.Context, w *waitResult) bool {
+	c.cp.lock.Lock()
+	restored := c.cp.restored
+	// a restore may complete before we get here, its waiter is left for us
+	pending := c.cp.id != "" || c.cp.waiter != nil
+	c.cp.lock.Unlock()
+
+	if !pending {
+		return false
+	}
+
+	select {
+	case <-restored:
+	case <-ctx.Done():
+		return false
+	}
+
+	c.cp.lock.Lock()
+	waiter := c.cp.waiter
+	c.cp.waiter = nil
+	c.cp.lock.Unlock()
+
+	if waiter == nil {
+		return false
+	}
+
+	w.waiter.Close()
+	w.waiter.Wait()
+	w.waiter = waiter
+	return true
+}
+
 func (c *cookie) ValidateImage(ctx context.Context) (bool, error) {
-	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "ValidateImage"})
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "ValidateImage"})
 	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker auth and inspect image")
 
 	// ask for docker creds before looking for image, as the tasker may need to
@@ -279,15 +654,18 @@ func (c *cookie) ValidateImage(ctx context.Context) (bool, error) {
 	c.imgAuthConf = config
 
 	// see if we already have it
-	_, err := c.drv.docker.InspectImage(ctx, c.task.Image())
+	img, err := c.drv.docker.InspectImage(ctx, c.task.Image())
 	if err == docker.ErrNoSuchImage {
 		return true, nil
 	}
-	return false, err
+	if err != nil {
+		return false, err
+	}
+	return false, c.resolveImage(img)
 }
 
 func (c *cookie) PullImage(ctx context.Context) error {
-	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "PullImage"})
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "PullImage"})
 
 	cfg := c.imgAuthConf
 	if cfg == nil {
@@ -296,7 +674,7 @@ func (c *cookie) PullImage(ctx context.Context) error {
 
 	repo := path.Join(c.imgReg, c.imgRepo)
 
-	log = common.Logger(ctx).WithFields(logrus.Fields{"registry": cfg.ServerAddress, "username": cfg.Username, "image": c.task.Image()})
+	log = common.ComponentLogger(ctx, common.LogComponentDriver).WithFields(logrus.Fields{"registry": cfg.ServerAddress, "username": cfg.Username, "image": c.task.Image()})
 	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker pull")
 
 	err := c.drv.docker.PullImage(docker.PullImageOptions{Repository: repo, Tag: c.imgTag, Context: ctx}, *cfg)
@@ -311,21 +689,40 @@ func (c *cookie) PullImage(ctx context.Context) error {
 			msg = dockerMsg(dErr)
 			code = dErr.Status // 401/404
 		}
+		// multi-arch images without a manifest for the platform of the daemon
+		if strings.Contains(msg, "no matching manifest") {
+			code = http.StatusBadRequest
+		}
 
 		return models.NewAPIError(code, fmt.Errorf("Failed to pull image '%s': %s", c.task.Image(), msg))
 	}
 
-	return nil
+	img, err := c.drv.docker.InspectImage(ctx, c.task.Image())
+	if err != nil {
+		return err
+	}
+	return c.resolveImage(img)
 }
 
 func (c *cookie) CreateContainer(ctx context.Context) error {
-	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "CreateContainer"})
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "CreateContainer"})
 	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker create container")
 
 	// here let's assume we have created container, logically this should be after 'CreateContainer', but we
 	// are not 100% sure that *any* failure to CreateContainer does not ever leave a container around especially
 	// going through fsouza+docker-api.
 	c.isCreated = true
+	c.drv.tracker.track(c.task.Id())
+
+	if err := c.checkNonRoot(ctx); err != nil {
+		log.WithError(err).Error("Refusing to run container as root")
+		return err
+	}
+
+	if err := c.startCompanions(ctx); err != nil {
+		log.WithError(err).Error("Could not start companion containers")
+		return err
+	}
 
 	c.opts.Context = ctx
 	_, err := c.drv.docker.CreateContainer(c.opts)
@@ -0,0 +1,282 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/sirupsen/logrus"
+)
+
+type taskEnvTest struct {
+	taskDockerTest
+	env   map[string]string
+	proxy []string
+}
+
+func (f *taskEnvTest) EnvVars() map[string]string { return f.env }
+func (f *taskEnvTest) Proxy() (httpProxy, httpsProxy, noProxy string, ok bool) {
+	if f.proxy == nil {
+		return "", "", "", false
+	}
+	return f.proxy[0], f.proxy[1], f.proxy[2], true
+}
+
+func configuredEnv(conf drivers.Config, task drivers.ContainerTask) []string {
+	c := &cookie{
+		opts: docker.CreateContainerOptions{Config: &docker.Config{}, HostConfig: &docker.HostConfig{}},
+		task: task,
+		drv:  &DockerDriver{conf: conf},
+	}
+	c.configureEnv(logrus.New())
+	sort.Strings(c.opts.Config.Env)
+	return c.opts.Config.Env
+}
+
+func TestCookieProxyEnv(t *testing.T) {
+	conf := drivers.Config{HTTPProxy: "http://proxy:3128", NoProxy: "localhost"}
+
+	env := configuredEnv(conf, &taskEnvTest{env: map[string]string{"A": "1", "no_proxy": "fn"}})
+	expected := []string{"A=1", "HTTP_PROXY=http://proxy:3128", "http_proxy=http://proxy:3128", "no_proxy=fn"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Fatalf("expected the proxies of the agent unless configured by the fn, got %v", env)
+	}
+
+	env = configuredEnv(conf, &taskEnvTest{proxy: []string{"", "http://app:3128", ""}})
+	expected = []string{"HTTPS_PROXY=http://app:3128", "https_proxy=http://app:3128"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Fatalf("expected the proxies of the app, got %v", env)
+	}
+}
+
+func TestCookieCompanionOptions(t *testing.T) {
+	c := &cookie{
+		opts: docker.CreateContainerOptions{
+			Config:     &docker.Config{Labels: map[string]string{AgentLabel: "agent"}},
+			HostConfig: &docker.HostConfig{NetworkMode: "fn-net", DNS: []string{"10.0.0.2"}},
+		},
+		task: &taskDockerTest{id: "task"},
+		drv:  &DockerDriver{},
+	}
+
+	opts := c.companionOptions(context.Background(), "task-sidecar", &drivers.Companion{
+		Image: "redis:alpine", Cmd: "redis-server --port 6380", Env: map[string]string{"A": "1"}, Memory: 64 << 20})
+	if opts.Name != "task-sidecar" || opts.Config.Image != "redis:alpine" || len(opts.Config.Cmd) != 3 || !reflect.DeepEqual(opts.Config.Env, []string{"A=1"}) {
+		t.Fatalf("unexpected companion container %+v", opts.Config)
+	}
+	if opts.Config.Labels[AgentLabel] != "agent" || opts.HostConfig.NetworkMode != "fn-net" || opts.HostConfig.DNS[0] != "10.0.0.2" {
+		t.Fatalf("expected the companion in the network and with the labels of the task, got %+v", opts.HostConfig)
+	}
+	if opts.Config.Memory != 64<<20 || opts.Config.MemorySwap != 64<<20 {
+		t.Fatalf("expected the memory of the companion to be limited, got %d", opts.Config.Memory)
+	}
+}
+
+type taskUserTest struct {
+	taskDockerTest
+	user string
+}
+
+func (f *taskUserTest) User() string { return f.user }
+
+func TestCookieUser(t *testing.T) {
+	for _, test := range []struct {
+		conf      drivers.Config
+		task      string
+		user      string
+		forbidden bool
+	}{
+		{drivers.Config{User: "1000:1000"}, "", "1000:1000", false},
+		{drivers.Config{User: "1000:1000"}, "nobody", "nobody", false},
+		{drivers.Config{User: "1000", RequireNonRoot: true}, "0:0", "0:0", true},
+		{drivers.Config{RequireNonRoot: true}, "root", "root", true},
+		{drivers.Config{User: "root"}, "", "root", false},
+	} {
+		c := &cookie{
+			opts: docker.CreateContainerOptions{Config: &docker.Config{}, HostConfig: &docker.HostConfig{}},
+			task: &taskUserTest{user: test.task},
+			drv:  &DockerDriver{conf: test.conf},
+		}
+		c.configureUser(logrus.New())
+		if c.opts.Config.User != test.user {
+			t.Errorf("expected user %q, got %q", test.user, c.opts.Config.User)
+		}
+		if err := c.checkNonRoot(context.Background()); (err != nil) != test.forbidden {
+			t.Errorf("expected user %q to be forbidden %v, got %v", test.user, test.forbidden, err)
+		}
+	}
+}
+
+type taskCapsTest struct {
+	taskDockerTest
+	add, drop []string
+}
+
+func (f *taskCapsTest) Capabilities() (add, drop []string) { return f.add, f.drop }
+
+func TestCookieCapabilities(t *testing.T) {
+	conf := drivers.Config{CapDrop: "ALL", CapAdd: "CHOWN SETUID", CapAllowed: "NET_RAW", NoNewPrivileges: true}
+	c := &cookie{
+		opts: docker.CreateContainerOptions{Config: &docker.Config{}, HostConfig: &docker.HostConfig{}},
+		task: &taskCapsTest{add: []string{"NET_RAW"}, drop: []string{"SETUID"}},
+		drv:  &DockerDriver{conf: conf},
+	}
+	c.configureCapabilities(logrus.New())
+	if !reflect.DeepEqual(c.opts.HostConfig.CapDrop, []string{"ALL", "SETUID"}) || !reflect.DeepEqual(c.opts.HostConfig.CapAdd, []string{"CHOWN", "NET_RAW"}) {
+		t.Fatalf("unexpected capabilities drop %v add %v", c.opts.HostConfig.CapDrop, c.opts.HostConfig.CapAdd)
+	}
+	if !reflect.DeepEqual(c.opts.HostConfig.SecurityOpt, []string{"no-new-privileges:true"}) {
+		t.Fatalf("expected no new privileges, got %v", c.opts.HostConfig.SecurityOpt)
+	}
+	if err := c.checkCapabilities(); err != nil {
+		t.Fatalf("expected the allowed capability to be added, got %v", err)
+	}
+
+	c.task = &taskCapsTest{add: []string{"SYS_ADMIN"}}
+	if err := c.checkCapabilities(); err == nil {
+		t.Fatal("expected a capability not allowed to be rejected")
+	}
+}
+
+type taskWindowsTest struct {
+	taskDockerTest
+}
+
+func (f *taskWindowsTest) CPUs() uint64          { return 1500 }
+func (f *taskWindowsTest) FsSize() uint64        { return 1024 }
+func (f *taskWindowsTest) TmpFsSize() uint64     { return 64 }
+func (f *taskWindowsTest) UDSDockerPath() string { return `\\.\pipe\fn-1` }
+func (f *taskWindowsTest) UDSDockerDest() string { return `\\.\pipe\fn-lsnr` }
+
+func TestCookieWindows(t *testing.T) {
+	c := &cookie{
+		opts: docker.CreateContainerOptions{
+			Config:     &docker.Config{},
+			HostConfig: &docker.HostConfig{ReadonlyRootfs: true, Init: true},
+		},
+		task: &taskWindowsTest{},
+		drv:  &DockerDriver{conf: drivers.Config{CapDrop: "ALL", NoNewPrivileges: true}, osType: drivers.OSWindows},
+	}
+	log := logrus.New()
+	c.configureMem(log)
+	c.configureCPU(log)
+	c.configureFsSize(log)
+	c.configureTmpFs(log)
+	c.configureCapabilities(log)
+	c.configureIOFS(log)
+	c.configureWindows(log)
+
+	hc := c.opts.HostConfig
+	if hc.ReadonlyRootfs || hc.Init || hc.Tmpfs != nil || hc.CapDrop != nil || hc.SecurityOpt != nil {
+		t.Fatalf("expected linux only options to be removed, got %+v", hc)
+	}
+	if hc.CPUCount != 2 || hc.CPUQuota != 0 || hc.StorageOpt["size"] != "" || c.opts.Config.MemorySwap != 0 {
+		t.Fatalf("unexpected windows resources %+v", hc)
+	}
+	if len(hc.Binds) != 0 || len(hc.Mounts) != 1 || hc.Mounts[0] != (docker.HostMount{Type: "npipe", Source: `\\.\pipe\fn-1`, Target: `\\.\pipe\fn-lsnr`}) {
+		t.Fatalf("expected the listener to be a named pipe mount, got %v %v", hc.Binds, hc.Mounts)
+	}
+}
+
+func newCheckpointTestCookie() (*cookie, *fakeDocker) {
+	fake := newFakeDocker()
+	drv := &DockerDriver{docker: fake, conf: drivers.Config{CheckpointDir: "/checkpoints"}}
+	task := &taskDockerTest{id: "test-checkpoint", input: strings.NewReader(""), output: new(bytes.Buffer), errors: new(bytes.Buffer)}
+	return &cookie{drv: drv, task: task}, fake
+}
+
+func TestCookieCheckpointFailure(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newCheckpointTestCookie()
+	fake.checkpointErr = errors.New("criu failed")
+
+	if err := c.Checkpoint(ctx); err != fake.checkpointErr {
+		t.Fatalf("Expected the checkpoint to fail, got %v", err)
+	}
+	if c.cp.id != "" {
+		t.Fatal("Expected the failed checkpoint to be abandoned")
+	}
+	// the stop of a container whose checkpoint failed is an exit
+	if c.awaitRestore(ctx, &waitResult{waiter: &fakeWaiter{}}) {
+		t.Fatal("Expected the waiter not to wait for a restore")
+	}
+	if err := c.Restore(ctx); err != nil || !reflect.DeepEqual(fake.called(), []string{"checkpoint"}) {
+		t.Fatalf("Expected nothing to be restored, got %v %v", fake.called(), err)
+	}
+}
+
+func TestCookieRestoreFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, fake := newCheckpointTestCookie()
+
+	if err := c.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	orig := &fakeWaiter{}
+	w := &waitResult{container: "test-checkpoint", waiter: orig, drv: c.drv, cookie: c}
+	// the checkpoint stops the container
+	fake.exits <- 1
+	statuses := make(chan string)
+	go func() {
+		status, _ := w.wait(ctx)
+		statuses <- status
+	}()
+
+	fake.restoreErr = errors.New("criu failed")
+	if err := c.Restore(ctx); err != fake.restoreErr {
+		t.Fatalf("Expected the restore to fail, got %v", err)
+	}
+	if status := <-statuses; status != drivers.StatusError {
+		t.Fatalf("Expected the container that failed to restore to have exited, got %v", status)
+	}
+	if w.waiter != orig || !orig.isClosed() || len(fake.waiters) != 1 || !fake.waiters[0].isClosed() {
+		t.Fatal("Expected the streams to be closed and not swapped")
+	}
+	if !reflect.DeepEqual(fake.called(), []string{"checkpoint", "attach", "restore"}) {
+		t.Fatalf("Unexpected docker calls %v", fake.called())
+	}
+}
+
+func TestCookieRestore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, fake := newCheckpointTestCookie()
+
+	if err := c.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	orig := &fakeWaiter{}
+	w := &waitResult{container: "test-checkpoint", waiter: orig, drv: c.drv, cookie: c}
+	// the checkpoint stops the container, which then runs until it succeeds
+	fake.exits <- 1
+	fake.exits <- 0
+	statuses := make(chan string)
+	go func() {
+		status, _ := w.wait(ctx)
+		statuses <- status
+	}()
+
+	if err := c.Restore(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status := <-statuses; status != drivers.StatusSuccess {
+		t.Fatalf("Expected the restored container to be waited for, got %v", status)
+	}
+	if !orig.isClosed() || len(fake.waiters) != 1 || w.waiter != fake.waiters[0] {
+		t.Fatal("Expected the streams to be swapped to those attached on restore")
+	}
+	if c.cp.id != "" || c.cp.waiter != nil {
+		t.Fatal("Expected the checkpoint to be forgotten once restored")
+	}
+	if !reflect.DeepEqual(fake.called(), []string{"checkpoint", "attach", "restore", "remove checkpoint"}) {
+		t.Fatalf("Unexpected docker calls %v", fake.called())
+	}
+}
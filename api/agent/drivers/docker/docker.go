@@ -57,6 +57,10 @@ type DockerDriver struct {
 	hostname string
 	auths    map[string]driverAuthConfig
 	pool     DockerPool
+	tracker  *containerTracker
+	// operating system and architecture of the containers, see detectPlatform
+	osType string
+	arch   string
 	// protects networks map
 	networksLock sync.Mutex
 	networks     map[string]uint64
@@ -81,6 +85,11 @@ func NewDocker(conf drivers.Config) *DockerDriver {
 		docker:   newClient(ctx, conf.MaxRetries),
 		hostname: hostname,
 		auths:    auths,
+		tracker:  newContainerTracker(),
+	}
+
+	if driver.conf.InstanceID == "" {
+		driver.conf.InstanceID = hostname
 	}
 
 	if conf.ServerVersion != "" {
@@ -90,7 +99,11 @@ func NewDocker(conf drivers.Config) *DockerDriver {
 		}
 	}
 
-	if conf.PreForkPoolSize != 0 {
+	driver.osType, driver.arch = detectPlatform(driver)
+
+	if conf.PreForkPoolSize != 0 && driver.windows() {
+		logrus.Warn("pre-fork pool is not supported on windows, disabling it")
+	} else if conf.PreForkPoolSize != 0 {
 		driver.pool = NewDockerPool(conf, driver)
 	}
 
@@ -109,6 +122,10 @@ func NewDocker(conf drivers.Config) *DockerDriver {
 		}
 	}
 
+	if conf.ReapInterval > 0 {
+		go driver.reaper(ctx, conf.ReapInterval)
+	}
+
 	return driver
 }
 
@@ -136,7 +153,7 @@ func checkDockerVersion(driver *DockerDriver, expected string) error {
 }
 
 func loadDockerImages(driver *DockerDriver, filePath string) error {
-	ctx, log := common.LoggerWithFields(context.Background(), logrus.Fields{"stack": "loadDockerImages"})
+	ctx, log := common.LoggerWithFields(common.WithComponent(context.Background(), common.LogComponentDriver), logrus.Fields{"stack": "loadDockerImages"})
 	log.Infof("Loading docker images from %v", filePath)
 	return driver.docker.LoadImages(ctx, filePath)
 }
@@ -152,13 +169,22 @@ func (drv *DockerDriver) Close() error {
 	return err
 }
 
+// SetPreForkWaterMarks implements drivers.PreForkTuner
+func (drv *DockerDriver) SetPreForkWaterMarks(low, high uint64) error {
+	if drv.pool == nil {
+		return errors.New("the pre-fork pool is disabled")
+	}
+	drv.pool.SetWaterMarks(low, high)
+	return nil
+}
+
 // Obsoleted.
 func (drv *DockerDriver) PrepareCookie(ctx context.Context, cookie drivers.Cookie) error {
 	return nil
 }
 
 func (drv *DockerDriver) pickPool(ctx context.Context, c *cookie) {
-	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "tryUsePool"})
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "tryUsePool"})
 
 	if drv.pool == nil || c.opts.HostConfig.NetworkMode != "" {
 		return
@@ -217,7 +243,7 @@ func (drv *DockerDriver) unpickNetwork(c *cookie) {
 
 func (drv *DockerDriver) CreateCookie(ctx context.Context, task drivers.ContainerTask) (drivers.Cookie, error) {
 
-	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "CreateCookie"})
+	ctx, log := common.LoggerWithFields(common.WithComponent(ctx, common.LogComponentDriver), logrus.Fields{"stack": "CreateCookie"})
 
 	_, stdinOff := task.Input().(common.NoopReadWriteCloser)
 	stdout, stderr := task.Logger()
@@ -246,16 +272,22 @@ func (drv *DockerDriver) CreateCookie(ctx context.Context, task drivers.Containe
 		drv:  drv,
 	}
 
+	cookie.configureLabels()
 	cookie.configureLogger(log)
 	cookie.configureMem(log)
 	cookie.configureCmd(log)
 	cookie.configureEnv(log)
+	cookie.configureUser(log)
+	cookie.configureCapabilities(log)
 	cookie.configureCPU(log)
+	cookie.configureCPUSet(log)
+	cookie.configureBlkio(log)
 	cookie.configureFsSize(log)
 	cookie.configureTmpFs(log)
 	cookie.configureVolumes(log)
 	cookie.configureWorkDir(log)
 	cookie.configureIOFS(log)
+	cookie.configureWindows(log)
 
 	// Order is important, if pool is enabled, it overrides pick network
 	drv.pickPool(ctx, cookie)
@@ -263,15 +295,30 @@ func (drv *DockerDriver) CreateCookie(ctx context.Context, task drivers.Containe
 
 	// Order is important, Hostname doesn't play well with Network config
 	cookie.configureHostname(log)
+	cookie.configureDNS(log)
+	cookie.configureCompanions(log)
 
 	cookie.imgReg, cookie.imgRepo, cookie.imgTag = drivers.ParseImage(task.Image())
 
+	if err := cookie.checkCapabilities(); err != nil {
+		return nil, err
+	}
+
+	// the user of the image is checked once it is pulled, see CreateContainer
+	if cookie.opts.Config.User != "" {
+		if err := cookie.checkNonRoot(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	return cookie, nil
 }
 
 func (drv *DockerDriver) removeContainer(ctx context.Context, container string) error {
 	err := drv.docker.RemoveContainer(docker.RemoveContainerOptions{
 		ID: container, Force: true, RemoveVolumes: true, Context: ctx})
+	// if removal failed, the container is now an orphan for the reaper
+	drv.tracker.untrack(container)
 
 	if err != nil {
 		logrus.WithError(err).WithFields(logrus.Fields{"container": container}).Error("error removing container")
@@ -279,9 +326,9 @@ func (drv *DockerDriver) removeContainer(ctx context.Context, container string)
 	return nil
 }
 
-// Run executes the docker container. If task runs, drivers.RunResult will be returned. If something fails outside the task (ie: Docker), it will return error.
-// The docker driver will attempt to cast the task to a Auther. If that succeeds, private image support is available. See the Auther interface for how to implement this.
-func (drv *DockerDriver) run(ctx context.Context, container string, task drivers.ContainerTask) (drivers.WaitResult, error) {
+// attach connects the task's stdin/stdout/stderr to the container and blocks
+// until the streams are attached (or ctx is done).
+func (drv *DockerDriver) attach(ctx context.Context, container string, task drivers.ContainerTask) (docker.CloseWaiter, error) {
 
 	stdout, stderr := task.Logger()
 	successChan := make(chan struct{})
@@ -326,6 +373,14 @@ func (drv *DockerDriver) run(ctx context.Context, container string, task drivers
 		}
 	}
 
+	return waiter, err
+}
+
+// Run executes the docker container. If task runs, drivers.RunResult will be returned. If something fails outside the task (ie: Docker), it will return error.
+// The docker driver will attempt to cast the task to a Auther. If that succeeds, private image support is available. See the Auther interface for how to implement this.
+func (drv *DockerDriver) run(ctx context.Context, container string, task drivers.ContainerTask) (drivers.WaitResult, error) {
+
+	waiter, err := drv.attach(ctx, container, task)
 	if err != nil && ctx.Err() == nil {
 		// ignore if ctx has errored, rewrite status lay below
 		return nil, err
@@ -356,6 +411,8 @@ type waitResult struct {
 	waiter    docker.CloseWaiter
 	drv       *DockerDriver
 	done      chan struct{}
+	// cookie is set if the container may be checkpointed while running
+	cookie *cookie
 }
 
 // waitResult implements drivers.WaitResult
@@ -375,7 +432,7 @@ func (drv *DockerDriver) collectStats(ctx context.Context, stopSignal <-chan str
 	ctx, span := trace.StartSpan(ctx, "docker_collect_stats")
 	defer span.End()
 
-	log := common.Logger(ctx)
+	log := common.ComponentLogger(ctx, common.LogComponentDriver)
 
 	// dockerCallDone is used to cancel the call to drv.docker.Stats when this method exits
 	dockerCallDone := make(chan bool)
@@ -473,7 +530,7 @@ func cherryPick(ds *docker.Stats) drivers.Stat {
 }
 
 func (drv *DockerDriver) startTask(ctx context.Context, container string) error {
-	log := common.Logger(ctx)
+	log := common.ComponentLogger(ctx, common.LogComponentDriver)
 	log.WithFields(logrus.Fields{"container": container}).Debug("Starting container execution")
 	err := drv.docker.StartContainerWithContext(container, nil, ctx)
 	if err != nil {
@@ -493,6 +550,12 @@ func (w *waitResult) wait(ctx context.Context) (status string, err error) {
 	// just say it was a timeout if we have [fatal] errors talking to docker, etc.
 	// a more prevalent case is calling wait & container already finished, so again ignore err.
 	exitCode, _ := w.drv.docker.WaitContainerWithContext(w.container, ctx)
+
+	// a checkpoint stops the container, which is not an exit as far as
+	// the caller is concerned. Wait for the restore and keep waiting.
+	for w.cookie != nil && w.cookie.awaitRestore(ctx, w) {
+		exitCode, _ = w.drv.docker.WaitContainerWithContext(w.container, ctx)
+	}
 	defer RecordWaitContainerResult(ctx, exitCode)
 
 	w.waiter.Close()
@@ -524,7 +587,7 @@ func (w *waitResult) wait(ctx context.Context) (status string, err error) {
 	case 0:
 		return drivers.StatusSuccess, nil
 	case 137: // OOM
-		common.Logger(ctx).Error("docker oom")
+		common.ComponentLogger(ctx, common.LogComponentDriver).Error("docker oom")
 		err := errors.New("container out of memory, you may want to raise fn.memory for this function (default: 128MB)")
 		return drivers.StatusKilled, models.NewAPIError(http.StatusBadGateway, err)
 	}
@@ -3,9 +3,15 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -21,6 +27,9 @@ import (
 
 const (
 	eventRetryDelay = 1 * time.Second
+
+	// checkpoint API needs at least 1.25 and docker daemon running in experimental mode
+	checkpointAPIVersion = "v1.25"
 )
 
 // wrap docker client calls so we can retry 500s, kind of sucks but fsouza doesn't
@@ -47,6 +56,15 @@ type dockerClient interface {
 	Info(ctx context.Context) (*docker.DockerInfo, error)
 	DiskUsage(opts docker.DiskUsageOptions) (*docker.DiskUsage, error)
 	LoadImages(ctx context.Context, filePath string) error
+	ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error)
+	InspectContainerWithContext(container string, ctx context.Context) (*docker.Container, error)
+	AddEventListener(listener chan<- *docker.APIEvents) error
+	RemoveEventListener(listener chan *docker.APIEvents) error
+
+	// Checkpoint/restore are not supported by go-dockerclient, these talk to the docker API directly
+	CheckpointContainer(ctx context.Context, id, checkpointID, checkpointDir string) error
+	RestoreContainer(ctx context.Context, id, checkpointID, checkpointDir string) error
+	RemoveCheckpoint(ctx context.Context, id, checkpointID, checkpointDir string) error
 }
 
 // TODO: switch to github.com/docker/engine-api
@@ -91,6 +109,7 @@ var (
 	dockerLatencyMeasure = common.MakeMeasure("docker_api_latency", "Docker wrapper latency", "msecs")
 
 	dockerEventsMeasure = common.MakeMeasure("docker_events", "docker events", "")
+	dockerReapedMeasure = common.MakeMeasure("docker_reaped_containers", "orphaned containers removed", "")
 )
 
 // listenEventLoop listens for docker events and reconnects if necessary
@@ -212,6 +231,13 @@ func RegisterViews(tagKeys []string, latencyDist []float64) {
 		common.CreateViewWithTags(dockerExitMeasure, view.Count(), exitTags),
 		common.CreateViewWithTags(dockerLatencyMeasure, view.Distribution(latencyDist...), defaultTags),
 		common.CreateViewWithTags(dockerEventsMeasure, view.Count(), eventTags),
+		common.CreateViewWithTags(dockerReapedMeasure, view.Count(), nil),
+		common.CreateViewWithTags(poolSizeMeasure, view.LastValue(), nil),
+		common.CreateViewWithTags(poolFreeMeasure, view.LastValue(), nil),
+		common.CreateViewWithTags(poolExhaustedMeasure, view.Count(), nil),
+		common.CreateViewWithTags(egressLimitedMeasure, view.Count(), nil),
+		common.CreateViewWithTags(egressRateMeasure, view.LastValue(), nil),
+		common.CreateViewWithTags(egressErrorMeasure, view.Count(), nil),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
@@ -266,7 +292,7 @@ func temp(err error) error {
 
 // some 500s are totally cool
 func filter(ctx context.Context, err error) error {
-	log := common.Logger(ctx)
+	log := common.ComponentLogger(ctx, common.LogComponentDriver)
 	// "API error (500): {\"message\":\"service endpoint with name task-57d722ecdecb9e7be16aff17 already exists\"}\n" -> ok since container exists
 	switch {
 	default:
@@ -280,7 +306,7 @@ func filter(ctx context.Context, err error) error {
 }
 
 func filterNoSuchContainer(ctx context.Context, err error) error {
-	log := common.Logger(ctx)
+	log := common.ComponentLogger(ctx, common.LogComponentDriver)
 	if err == nil {
 		return nil
 	}
@@ -315,7 +341,7 @@ func (d *dockerWrap) ListImages(opts docker.ListImagesOptions) (imgs []docker.AP
 	ctx, closer := makeTracker(opts.Context, "docker_list_images")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "ListImages")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "ListImages")
 	err = d.retry(ctx, logger, func() error {
 		imgs, err = d.docker.ListImages(opts)
 		return err
@@ -344,7 +370,7 @@ func (d *dockerWrap) WaitContainerWithContext(id string, ctx context.Context) (c
 	ctx, closer := makeTracker(ctx, "docker_wait_container")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "WaitContainer")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "WaitContainer")
 	err = d.retry(ctx, logger, func() error {
 		code, err = d.docker.WaitContainerWithContext(id, ctx)
 		return err
@@ -356,7 +382,7 @@ func (d *dockerWrap) StartContainerWithContext(id string, hostConfig *docker.Hos
 	ctx, closer := makeTracker(ctx, "docker_start_container")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "StartContainer")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "StartContainer")
 	err = d.retry(ctx, logger, func() error {
 		err = d.docker.StartContainerWithContext(id, hostConfig, ctx)
 		if _, ok := err.(*docker.NoSuchContainer); ok {
@@ -372,7 +398,7 @@ func (d *dockerWrap) CreateContainer(opts docker.CreateContainerOptions) (c *doc
 	ctx, closer := makeTracker(opts.Context, "docker_create_container")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "CreateContainer")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "CreateContainer")
 	err = d.retry(ctx, logger, func() error {
 		c, err = d.docker.CreateContainer(opts)
 		return err
@@ -384,7 +410,7 @@ func (d *dockerWrap) KillContainer(opts docker.KillContainerOptions) (err error)
 	ctx, closer := makeTracker(opts.Context, "docker_kill_container")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "KillContainer")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "KillContainer")
 	err = d.retry(ctx, logger, func() error {
 		err = d.docker.KillContainer(opts)
 		return err
@@ -396,7 +422,7 @@ func (d *dockerWrap) PullImage(opts docker.PullImageOptions, auth docker.AuthCon
 	ctx, closer := makeTracker(opts.Context, "docker_pull_image")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "PullImage")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "PullImage")
 	err = d.retry(ctx, logger, func() error {
 		err = d.docker.PullImage(opts, auth)
 		return err
@@ -408,7 +434,7 @@ func (d *dockerWrap) RemoveImage(image string, opts docker.RemoveImageOptions) (
 	ctx, closer := makeTracker(opts.Context, "docker_remove_image")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "RemoveImage")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "RemoveImage")
 	err = d.retry(ctx, logger, func() error {
 		err = d.RemoveImage(image, opts)
 		return err
@@ -421,7 +447,7 @@ func (d *dockerWrap) RemoveContainer(opts docker.RemoveContainerOptions) (err er
 	ctx, closer := makeTracker(opts.Context, "docker_remove_container")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "RemoveContainer")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "RemoveContainer")
 	err = d.retry(ctx, logger, func() error {
 		err = d.docker.RemoveContainer(opts)
 		return err
@@ -433,7 +459,7 @@ func (d *dockerWrap) PauseContainer(id string, ctx context.Context) (err error)
 	ctx, closer := makeTracker(ctx, "docker_pause_container")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "PauseContainer")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "PauseContainer")
 	err = d.retry(ctx, logger, func() error {
 		err = d.docker.PauseContainer(id)
 		return err
@@ -445,7 +471,7 @@ func (d *dockerWrap) UnpauseContainer(id string, ctx context.Context) (err error
 	ctx, closer := makeTracker(ctx, "docker_unpause_container")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "UnpauseContainer")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "UnpauseContainer")
 	err = d.retry(ctx, logger, func() error {
 		err = d.docker.UnpauseContainer(id)
 		return err
@@ -457,7 +483,7 @@ func (d *dockerWrap) InspectImage(ctx context.Context, name string) (i *docker.I
 	ctx, closer := makeTracker(ctx, "docker_inspect_image")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "InspectImage")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "InspectImage")
 	err = d.retry(ctx, logger, func() error {
 		i, err = d.docker.InspectImage(name)
 		return err
@@ -478,14 +504,142 @@ func (d *dockerWrap) Stats(opts docker.StatsOptions) (err error) {
 	//return err
 }
 
+func (d *dockerWrap) ListContainers(opts docker.ListContainersOptions) (containers []docker.APIContainers, err error) {
+	ctx, closer := makeTracker(opts.Context, "docker_list_containers")
+	defer closer()
+
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "ListContainers")
+	err = d.retry(ctx, logger, func() error {
+		containers, err = d.docker.ListContainers(opts)
+		return err
+	})
+	return containers, err
+}
+
+func (d *dockerWrap) InspectContainerWithContext(container string, ctx context.Context) (c *docker.Container, err error) {
+	ctx, closer := makeTracker(ctx, "docker_inspect_container")
+	defer closer()
+
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "InspectContainer")
+	err = d.retry(ctx, logger, func() error {
+		c, err = d.docker.InspectContainerWithContext(container, ctx)
+		return err
+	})
+	return c, err
+}
+
+func (d *dockerWrap) AddEventListener(listener chan<- *docker.APIEvents) error {
+	return d.docker.AddEventListener(listener)
+}
+
+func (d *dockerWrap) RemoveEventListener(listener chan *docker.APIEvents) error {
+	return d.docker.RemoveEventListener(listener)
+}
+
 func (d *dockerWrap) DiskUsage(opts docker.DiskUsageOptions) (du *docker.DiskUsage, err error) {
 	ctx, closer := makeTracker(opts.Context, "docker_disk_usage")
 	defer closer()
 
-	logger := common.Logger(ctx).WithField("docker_cmd", "DiskUsage")
+	logger := common.ComponentLogger(ctx, common.LogComponentDriver).WithField("docker_cmd", "DiskUsage")
 	err = d.retry(ctx, logger, func() error {
 		du, err = d.docker.DiskUsage(opts)
 		return err
 	})
 	return du, err
 }
+
+// CheckpointContainer checkpoints the container using CRIU and stops it.
+func (d *dockerWrap) CheckpointContainer(ctx context.Context, id, checkpointID, checkpointDir string) error {
+	ctx, closer := makeTracker(ctx, "docker_checkpoint_container")
+	defer closer()
+
+	body, err := json.Marshal(struct {
+		CheckpointID  string
+		CheckpointDir string `json:",omitempty"`
+		Exit          bool
+	}{checkpointID, checkpointDir, true})
+	if err != nil {
+		return err
+	}
+
+	err = d.rawAPI(ctx, http.MethodPost, "/containers/"+id+"/checkpoints", nil, bytes.NewReader(body))
+	if err != nil {
+		stats.Record(ctx, dockerErrorMeasure.M(0))
+	}
+	return err
+}
+
+// RestoreContainer starts a checkpointed container from the given checkpoint.
+func (d *dockerWrap) RestoreContainer(ctx context.Context, id, checkpointID, checkpointDir string) error {
+	ctx, closer := makeTracker(ctx, "docker_restore_container")
+	defer closer()
+
+	query := url.Values{"checkpoint": {checkpointID}}
+	if checkpointDir != "" {
+		query.Set("checkpoint-dir", checkpointDir)
+	}
+
+	err := d.rawAPI(ctx, http.MethodPost, "/containers/"+id+"/start", query, nil)
+	if err != nil {
+		stats.Record(ctx, dockerErrorMeasure.M(0))
+	}
+	return err
+}
+
+// RemoveCheckpoint removes the checkpoint files of a container.
+func (d *dockerWrap) RemoveCheckpoint(ctx context.Context, id, checkpointID, checkpointDir string) error {
+	ctx, closer := makeTracker(ctx, "docker_remove_checkpoint")
+	defer closer()
+
+	var query url.Values
+	if checkpointDir != "" {
+		query = url.Values{"dir": {checkpointDir}}
+	}
+
+	err := d.rawAPI(ctx, http.MethodDelete, "/containers/"+id+"/checkpoints/"+checkpointID, query, nil)
+	return filterNoSuchContainer(ctx, err)
+}
+
+// rawAPI performs a docker API request using the http client and endpoint of
+// the underlying go-dockerclient, for endpoints the client does not cover.
+func (d *dockerWrap) rawAPI(ctx context.Context, method, path string, query url.Values, body io.Reader) error {
+	endpoint, err := url.Parse(d.docker.Endpoint())
+	if err != nil {
+		return err
+	}
+
+	u := url.URL{Scheme: "http", Host: endpoint.Host}
+	switch endpoint.Scheme {
+	case "unix":
+		// the http client dials the socket, host is not used
+		u.Host = "unix.sock"
+	case "https":
+		u.Scheme = "https"
+	default:
+		if d.docker.TLSConfig != nil {
+			u.Scheme = "https"
+		}
+	}
+	u.Path = "/" + checkpointAPIVersion + path
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.docker.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return &docker.Error{Status: resp.StatusCode, Message: string(msg)}
+	}
+	return nil
+}
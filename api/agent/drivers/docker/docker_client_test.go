@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// fakeDocker is a dockerClient recording the calls made to it, methods it
+// does not implement panic
+type fakeDocker struct {
+	dockerClient
+
+	lock  sync.Mutex
+	calls []string
+
+	checkpointErr, restoreErr error
+	// exit codes returned by WaitContainerWithContext, one per wait
+	exits chan int
+	// options of the last ListContainers
+	listOpts   docker.ListContainersOptions
+	containers []docker.APIContainers
+	inspected  map[string]*docker.Container
+	removed    []string
+	// waiters returned by AttachToContainerNonBlocking
+	waiters []*fakeWaiter
+	// events sent to the event listener
+	events chan *docker.APIEvents
+}
+
+func newFakeDocker() *fakeDocker {
+	return &fakeDocker{
+		exits:     make(chan int, 4),
+		inspected: make(map[string]*docker.Container),
+		events:    make(chan *docker.APIEvents),
+	}
+}
+
+func (f *fakeDocker) record(call string) {
+	f.lock.Lock()
+	f.calls = append(f.calls, call)
+	f.lock.Unlock()
+}
+
+func (f *fakeDocker) called() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fakeDocker) AttachToContainerNonBlocking(ctx context.Context, opts docker.AttachToContainerOptions) (docker.CloseWaiter, error) {
+	f.record("attach")
+	go func() {
+		opts.Success <- struct{}{}
+		<-opts.Success
+	}()
+	w := &fakeWaiter{}
+	f.lock.Lock()
+	f.waiters = append(f.waiters, w)
+	f.lock.Unlock()
+	return w, nil
+}
+
+func (f *fakeDocker) WaitContainerWithContext(id string, ctx context.Context) (int, error) {
+	select {
+	case code := <-f.exits:
+		return code, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (f *fakeDocker) CheckpointContainer(ctx context.Context, id, checkpointID, checkpointDir string) error {
+	f.record("checkpoint")
+	return f.checkpointErr
+}
+
+func (f *fakeDocker) RestoreContainer(ctx context.Context, id, checkpointID, checkpointDir string) error {
+	f.record("restore")
+	return f.restoreErr
+}
+
+func (f *fakeDocker) RemoveCheckpoint(ctx context.Context, id, checkpointID, checkpointDir string) error {
+	f.record("remove checkpoint")
+	return nil
+}
+
+func (f *fakeDocker) ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.listOpts = opts
+	return f.containers, nil
+}
+
+func (f *fakeDocker) InspectContainerWithContext(container string, ctx context.Context) (*docker.Container, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	c, ok := f.inspected[container]
+	if !ok {
+		return nil, &docker.NoSuchContainer{ID: container}
+	}
+	return c, nil
+}
+
+func (f *fakeDocker) RemoveContainer(opts docker.RemoveContainerOptions) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.removed = append(f.removed, opts.ID)
+	return nil
+}
+
+func (f *fakeDocker) removedContainers() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.removed...)
+}
+
+func (f *fakeDocker) AddEventListener(listener chan<- *docker.APIEvents) error {
+	go func() {
+		for ev := range f.events {
+			listener <- ev
+		}
+	}()
+	return nil
+}
+
+func (f *fakeDocker) RemoveEventListener(listener chan *docker.APIEvents) error { return nil }
+
+type fakeWaiter struct {
+	lock   sync.Mutex
+	closed bool
+}
+
+func (w *fakeWaiter) Close() error {
+	w.lock.Lock()
+	w.closed = true
+	w.lock.Unlock()
+	return nil
+}
+
+func (w *fakeWaiter) Wait() error { return nil }
+
+func (w *fakeWaiter) isClosed() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.closed
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/fsouza/go-dockerclient"
 	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
 	"golang.org/x/time/rate"
 )
 
@@ -29,7 +30,10 @@ import (
 // where pool buddy provides already creates namespaces. These are currently
 // network and user namespaces, but perhaps can be extended to also use pid and ipc.
 // (see docker.go Prepare() on how this is currently being used.)
-// Currently the pool is a set size and it does not grow on demand.
+// The pool starts at PreForkPoolSize and if PreForkPoolMaxSize is set, it grows
+// when free containers drop below the low water mark or when allocations find the
+// pool empty, and shrinks back towards PreForkPoolSize when free containers stay
+// above the high water mark.
 
 var (
 	ErrorPoolEmpty = errors.New("docker pre fork pool empty")
@@ -45,6 +49,15 @@ const (
 const (
 	LimitPerSec = 10
 	LimitBurst  = 20
+
+	// DefaultScaleInterval is how often the pool re-evaluates its size if not configured
+	DefaultScaleInterval = 5 * time.Second
+)
+
+var (
+	poolSizeMeasure      = common.MakeMeasure("docker_prefork_pool_size", "docker prefork pool size", "")
+	poolFreeMeasure      = common.MakeMeasure("docker_prefork_pool_free", "docker prefork pool free containers", "")
+	poolExhaustedMeasure = common.MakeMeasure("docker_prefork_pool_exhausted", "docker prefork pool allocations that found the pool empty", "")
 )
 
 type poolTask struct {
@@ -85,15 +98,29 @@ type dockerPool struct {
 	lock      sync.Mutex
 	inuse     map[string]dockerPoolItem
 	free      []dockerPoolItem
+	nannies   map[string]func() // task id -> cancel of its nanny go-routine
+	seq       uint64
+	misses    uint64 // allocations that found the pool empty since last scale check
+	lowWater  uint64
+	highWater uint64
+	minSize   uint64
+	maxSize   uint64
 	limiter   *rate.Limiter
 	cancel    func()
 	wg        sync.WaitGroup
 	isRecycle bool
+
+	driver   *DockerDriver
+	image    string
+	cmd      string
+	networks []string
+	pullGate chan struct{}
 }
 
 type DockerPoolStats struct {
 	inuse int
 	free  int
+	size  int
 }
 
 type DockerPool interface {
@@ -109,6 +136,11 @@ type DockerPool interface {
 
 	// returns inuse versus free
 	Usage() DockerPoolStats
+
+	// SetWaterMarks changes the number of free containers below which the
+	// pool grows (low) and above which it shrinks (high). Pool size always
+	// stays within the configured min/max sizes.
+	SetWaterMarks(low, high uint64)
 }
 
 func NewDockerPool(conf drivers.Config, driver *DockerDriver) DockerPool {
@@ -120,44 +152,182 @@ func NewDockerPool(conf drivers.Config, driver *DockerDriver) DockerPool {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	log := common.Logger(ctx)
+	log := common.ComponentLogger(ctx, common.LogComponentDriver)
 	log.Error("WARNING: Experimental Prefork Docker Pool Enabled")
 
+	maxSize := conf.PreForkPoolMaxSize
+	if maxSize < conf.PreForkPoolSize {
+		maxSize = conf.PreForkPoolSize
+	}
+
 	pool := &dockerPool{
-		inuse:   make(map[string]dockerPoolItem, conf.PreForkPoolSize),
-		free:    make([]dockerPoolItem, 0, conf.PreForkPoolSize),
-		limiter: rate.NewLimiter(LimitPerSec, LimitBurst),
-		cancel:  cancel,
+		inuse:    make(map[string]dockerPoolItem, maxSize),
+		free:     make([]dockerPoolItem, 0, maxSize),
+		nannies:  make(map[string]func(), maxSize),
+		minSize:  conf.PreForkPoolSize,
+		maxSize:  maxSize,
+		limiter:  rate.NewLimiter(LimitPerSec, LimitBurst),
+		cancel:   cancel,
+		driver:   driver,
+		image:    conf.PreForkImage,
+		cmd:      conf.PreForkCmd,
+		networks: strings.Fields(conf.PreForkNetworks),
+		pullGate: make(chan struct{}, 1),
 	}
 
 	if conf.PreForkUseOnce != 0 {
 		pool.isRecycle = true
 	}
 
-	networks := strings.Fields(conf.PreForkNetworks)
-	if len(networks) == 0 {
-		networks = append(networks, "")
+	if len(pool.networks) == 0 {
+		pool.networks = append(pool.networks, "")
 	}
 
-	pool.wg.Add(1 + int(conf.PreForkPoolSize))
-	pullGate := make(chan struct{}, 1)
+	pool.SetWaterMarks(conf.PreForkLowWater, conf.PreForkHighWater)
+
+	pool.wg.Add(1)
+	go pool.prepareImage(ctx, driver, conf.PreForkImage, pool.pullGate)
 
 	for i := 0; i < int(conf.PreForkPoolSize); i++ {
+		pool.spawn(ctx)
+	}
 
-		task := &poolTask{
-			id:      fmt.Sprintf("%d_prefork_%s", i, id.New().String()),
-			image:   conf.PreForkImage,
-			cmd:     conf.PreForkCmd,
-			netMode: networks[i%len(networks)],
+	if pool.maxSize > pool.minSize {
+		interval := conf.PreForkScaleInterval
+		if interval <= 0 {
+			interval = DefaultScaleInterval
 		}
-
-		go pool.nannyContainer(ctx, driver, task, pullGate)
+		pool.wg.Add(1)
+		go pool.scaler(ctx, interval)
 	}
 
-	go pool.prepareImage(ctx, driver, conf.PreForkImage, pullGate)
 	return pool
 }
 
+// spawn adds a new container to the pool and starts its nanny go-routine
+func (pool *dockerPool) spawn(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	pool.lock.Lock()
+	seq := pool.seq
+	pool.seq++
+	task := &poolTask{
+		id:      fmt.Sprintf("%d_prefork_%s", seq, id.New().String()),
+		image:   pool.image,
+		cmd:     pool.cmd,
+		netMode: pool.networks[seq%uint64(len(pool.networks))],
+	}
+	pool.nannies[task.id] = cancel
+	pool.lock.Unlock()
+
+	pool.wg.Add(1)
+	go pool.nannyContainer(ctx, pool.driver, task, pool.pullGate)
+}
+
+// retire stops a free container in the pool, returns false if there is
+// no free container to stop.
+func (pool *dockerPool) retire() bool {
+	pool.lock.Lock()
+	if len(pool.free) == 0 {
+		pool.lock.Unlock()
+		return false
+	}
+
+	// oldest free container goes first
+	item := pool.free[0]
+	pool.free = pool.free[1:]
+	cancel := pool.nannies[item.id]
+	pool.lock.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	return true
+}
+
+// scaler periodically checks pool exhaustion and free container counts
+// against the water marks and grows or shrinks the pool one step at a time.
+func (pool *dockerPool) scaler(ctx context.Context, interval time.Duration) {
+	defer pool.wg.Done()
+
+	log := common.ComponentLogger(ctx, common.LogComponentDriver)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pool.lock.Lock()
+		size := uint64(len(pool.nannies))
+		free := uint64(len(pool.free))
+		misses := pool.misses
+		pool.misses = 0
+		low, high := pool.lowWater, pool.highWater
+		pool.lock.Unlock()
+
+		stats.Record(ctx, poolSizeMeasure.M(int64(size)), poolFreeMeasure.M(int64(free)))
+
+		grow, shrink := scaleStep(size, free, misses, low, high, pool.minSize, pool.maxSize)
+		if grow > 0 {
+			log.WithFields(logrus.Fields{"size": size, "free": free, "misses": misses, "grow": grow}).Info("prefork pool growing")
+		}
+		for i := uint64(0); i < grow; i++ {
+			pool.spawn(ctx)
+		}
+		if shrink && pool.retire() {
+			log.WithFields(logrus.Fields{"size": size, "free": free}).Info("prefork pool shrinking")
+		}
+	}
+}
+
+// scaleStep returns how many containers a pool of size containers, free of
+// them free, spawns or whether it retires one, after misses allocations found
+// no free container since the last step. Pools grow to have low free
+// containers, or as many as they missed, up to maxSize. Pools which need not
+// grow shrink by a container while more than high are free, down to minSize.
+func scaleStep(size, free, misses, low, high, minSize, maxSize uint64) (grow uint64, shrink bool) {
+	if free < low {
+		grow = low - free
+	}
+	if misses > grow {
+		grow = misses
+	}
+	if grow > 0 {
+		if size >= maxSize {
+			return 0, false
+		}
+		if size+grow > maxSize {
+			grow = maxSize - size
+		}
+		return grow, false
+	}
+	return 0, free > high && size > minSize
+}
+
+// SetWaterMarks implements DockerPool
+func (pool *dockerPool) SetWaterMarks(low, high uint64) {
+	// by default, grow as soon as we run out and never hold on to more
+	// idle containers than the initial pool size
+	if low == 0 {
+		low = 1
+	}
+	if high == 0 {
+		high = pool.minSize
+	}
+	if high < low {
+		high = low
+	}
+
+	pool.lock.Lock()
+	pool.lowWater = low
+	pool.highWater = high
+	pool.lock.Unlock()
+}
+
 func (pool *dockerPool) Close() error {
 	pool.cancel()
 	pool.wg.Wait()
@@ -166,7 +336,7 @@ func (pool *dockerPool) Close() error {
 
 func (pool *dockerPool) performInitState(ctx context.Context, driver *DockerDriver, task *poolTask) {
 
-	log := common.Logger(ctx).WithFields(logrus.Fields{"id": task.Id(), "net": task.netMode})
+	log := common.ComponentLogger(ctx, common.LogComponentDriver).WithFields(logrus.Fields{"id": task.Id(), "net": task.netMode})
 
 	containerOpts := docker.CreateContainerOptions{
 		Name: task.Id(),
@@ -180,6 +350,7 @@ func (pool *dockerPool) performInitState(ctx context.Context, driver *DockerDriv
 			AttachStderr: false,
 			AttachStdin:  false,
 			StdinOnce:    false,
+			Labels:       map[string]string{AgentLabel: driver.conf.InstanceID},
 		},
 		HostConfig: &docker.HostConfig{
 			LogConfig: docker.LogConfig{
@@ -200,6 +371,7 @@ func (pool *dockerPool) performInitState(ctx context.Context, driver *DockerDriv
 	// ignore failure here
 	driver.docker.RemoveContainer(removeOpts)
 
+	driver.tracker.track(task.Id())
 	_, err := driver.docker.CreateContainer(containerOpts)
 	if err != nil {
 		log.WithError(err).Info("prefork pool container create failed")
@@ -211,7 +383,7 @@ func (pool *dockerPool) performInitState(ctx context.Context, driver *DockerDriv
 
 func (pool *dockerPool) performReadyState(ctx context.Context, driver *DockerDriver, task *poolTask) {
 
-	log := common.Logger(ctx).WithFields(logrus.Fields{"id": task.Id(), "net": task.netMode})
+	log := common.ComponentLogger(ctx, common.LogComponentDriver).WithFields(logrus.Fields{"id": task.Id(), "net": task.netMode})
 
 	killOpts := docker.KillContainerOptions{
 		ID:      task.Id(),
@@ -258,13 +430,14 @@ func (pool *dockerPool) performTeardown(ctx context.Context, driver *DockerDrive
 	}
 
 	driver.docker.RemoveContainer(removeOpts)
+	driver.tracker.untrack(task.Id())
 }
 
 func (pool *dockerPool) prepareImage(ctx context.Context, driver *DockerDriver, img string, pullGate chan struct{}) {
 	defer pool.wg.Done()
 	defer close(pullGate)
 
-	log := common.Logger(ctx)
+	log := common.ComponentLogger(ctx, common.LogComponentDriver)
 
 	imgReg, imgRepo, imgTag := drivers.ParseImage(img)
 	opts := docker.PullImageOptions{Repository: path.Join(imgReg, imgRepo), Tag: imgTag, Context: ctx}
@@ -295,8 +468,9 @@ func (pool *dockerPool) prepareImage(ctx context.Context, driver *DockerDriver,
 }
 
 func (pool *dockerPool) nannyContainer(ctx context.Context, driver *DockerDriver, task *poolTask, pullGate chan struct{}) {
-	defer pool.performTeardown(ctx, driver, task)
 	defer pool.wg.Done()
+	defer pool.forget(task.Id())
+	defer pool.performTeardown(ctx, driver, task)
 
 	// wait for image pull
 	select {
@@ -304,7 +478,7 @@ func (pool *dockerPool) nannyContainer(ctx context.Context, driver *DockerDriver
 	case <-pullGate:
 	}
 
-	log := common.Logger(ctx).WithFields(logrus.Fields{"id": task.Id(), "net": task.netMode})
+	log := common.ComponentLogger(ctx, common.LogComponentDriver).WithFields(logrus.Fields{"id": task.Id(), "net": task.netMode})
 
 	// We spin forever, keeping the pool resident and running at all times.
 	for ctx.Err() == nil {
@@ -328,6 +502,15 @@ func (pool *dockerPool) nannyContainer(ctx context.Context, driver *DockerDriver
 	}
 }
 
+func (pool *dockerPool) forget(id string) {
+	pool.lock.Lock()
+	if cancel, ok := pool.nannies[id]; ok {
+		cancel()
+		delete(pool.nannies, id)
+	}
+	pool.lock.Unlock()
+}
+
 func (pool *dockerPool) register(id string, cancel func()) {
 	item := dockerPoolItem{
 		id:     id,
@@ -361,8 +544,11 @@ func (pool *dockerPool) AllocPoolId() (string, error) {
 	pool.lock.Lock()
 	defer pool.lock.Unlock()
 
-	// We currently do not grow the pool if we run out of pre-forked containers
+	// If we run out of pre-forked containers, the scaler picks up the
+	// misses and grows the pool (if permitted) on its next pass.
 	if len(pool.free) == 0 {
+		pool.misses++
+		stats.Record(context.Background(), poolExhaustedMeasure.M(0))
 		return "", ErrorPoolEmpty
 	}
 
@@ -399,6 +585,7 @@ func (pool *dockerPool) Usage() DockerPoolStats {
 
 	stats.inuse = len(pool.inuse)
 	stats.free = len(pool.free)
+	stats.size = len(pool.nannies)
 
 	pool.lock.Unlock()
 	return stats
@@ -146,3 +146,68 @@ func TestRunnerDockerPoolFaulty(t *testing.T) {
 		t.Fatalf("pool shutdown timeout stats=%+v", stats)
 	}
 }
+
+func TestDockerPoolScaleStep(t *testing.T) {
+	for i, tc := range []struct {
+		size, free, misses, low, high uint64
+		grow                          uint64
+		shrink                        bool
+	}{
+		// enough free containers
+		{size: 4, free: 2, low: 1, high: 4},
+		// too few free containers
+		{size: 4, free: 0, low: 2, high: 4, grow: 2},
+		// missed allocations
+		{size: 4, free: 1, misses: 3, low: 1, high: 4, grow: 3},
+		// up to the max size
+		{size: 7, free: 0, misses: 5, low: 1, high: 4, grow: 1},
+		{size: 8, free: 0, misses: 5, low: 1, high: 4},
+		// too many free containers
+		{size: 6, free: 5, low: 1, high: 4, shrink: true},
+		// down to the min size
+		{size: 2, free: 2, low: 1, high: 1},
+		// growing pools do not shrink
+		{size: 6, free: 5, misses: 1, low: 1, high: 4, grow: 1},
+	} {
+		grow, shrink := scaleStep(tc.size, tc.free, tc.misses, tc.low, tc.high, 2, 8)
+		if grow != tc.grow || shrink != tc.shrink {
+			t.Errorf("Test %d: expected grow %d shrink %v, got grow %d shrink %v", i, tc.grow, tc.shrink, grow, shrink)
+		}
+	}
+}
+
+func TestDockerPoolWaterMarks(t *testing.T) {
+	pool := &dockerPool{minSize: 3}
+	for _, tc := range []struct{ low, high, expectedLow, expectedHigh uint64 }{
+		{0, 0, 1, 3},
+		{2, 6, 2, 6},
+		{5, 2, 5, 5},
+	} {
+		pool.SetWaterMarks(tc.low, tc.high)
+		if pool.lowWater != tc.expectedLow || pool.highWater != tc.expectedHigh {
+			t.Errorf("Water marks %d/%d: expected %d/%d, got %d/%d", tc.low, tc.high, tc.expectedLow, tc.expectedHigh, pool.lowWater, pool.highWater)
+		}
+	}
+}
+
+func TestDockerPoolRetire(t *testing.T) {
+	var retired []string
+	pool := &dockerPool{nannies: make(map[string]func())}
+	for _, id := range []string{"old", "new"} {
+		id := id
+		pool.free = append(pool.free, dockerPoolItem{id: id})
+		pool.nannies[id] = func() { retired = append(retired, id) }
+	}
+
+	for range pool.nannies {
+		if !pool.retire() {
+			t.Fatal("Expected a free container to be retired")
+		}
+	}
+	if pool.retire() {
+		t.Fatal("Expected no container to be retired without free containers")
+	}
+	if len(retired) != 2 || retired[0] != "old" || retired[1] != "new" {
+		t.Fatalf("Expected the oldest free container to be retired first, got %v", retired)
+	}
+}
@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+)
+
+// reapGracePeriod is the age below which untracked containers are not
+// reaped by sweeps. Agents restarting with the same instance id may overlap
+// for a while, the containers the other process has just created are left
+// to it. Containers that exited are reaped on their die event regardless.
+const reapGracePeriod = time.Minute
+
+// containerTracker keeps the names of the containers that this driver owns.
+// Containers labeled with our instance id that are not tracked have been
+// left behind, eg. by a crash between create and remove, and are reaped.
+type containerTracker struct {
+	lock       sync.Mutex
+	containers map[string]struct{}
+}
+
+func newContainerTracker() *containerTracker {
+	return &containerTracker{containers: make(map[string]struct{})}
+}
+
+// track must be called before the container is created, so that a
+// concurrent reap never sees an untracked container of ours.
+func (t *containerTracker) track(name string) {
+	t.lock.Lock()
+	t.containers[name] = struct{}{}
+	t.lock.Unlock()
+}
+
+func (t *containerTracker) untrack(name string) {
+	t.lock.Lock()
+	delete(t.containers, name)
+	t.lock.Unlock()
+}
+
+func (t *containerTracker) isTracked(name string) bool {
+	t.lock.Lock()
+	_, ok := t.containers[name]
+	t.lock.Unlock()
+	return ok
+}
+
+// reaper removes orphaned containers as soon as docker reports them exiting,
+// and periodically lists all containers with our label to catch the rest.
+func (drv *DockerDriver) reaper(ctx context.Context, interval time.Duration) {
+	log := common.ComponentLogger(ctx, common.LogComponentDriver).WithFields(logrus.Fields{"stack": "reaper", "instance": drv.conf.InstanceID})
+
+	// sweep once on startup, this is where leftovers of a crash are found
+	drv.reap(ctx, log)
+
+	events := make(chan *docker.APIEvents, 16)
+	if err := drv.docker.AddEventListener(events); err != nil {
+		log.WithError(err).Warn("cannot listen to docker events, reaping on interval only")
+		events = nil
+	} else {
+		defer drv.docker.RemoveEventListener(events)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			drv.reap(ctx, log)
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if ev.Type != "container" || ev.Action != "die" {
+				continue
+			}
+			attrs := ev.Actor.Attributes
+			if attrs[AgentLabel] == drv.conf.InstanceID && !drv.tracker.isTracked(attrs["name"]) {
+				drv.reapContainer(ctx, log, attrs["name"])
+			}
+		}
+	}
+}
+
+func (drv *DockerDriver) reap(ctx context.Context, log logrus.FieldLogger) {
+	containers, err := drv.ListContainers(ctx, map[string]string{AgentLabel: drv.conf.InstanceID})
+	if err != nil {
+		log.WithError(err).Error("cannot list containers")
+		return
+	}
+
+	now := time.Now()
+	for _, c := range containers {
+		if now.Sub(time.Unix(c.Created, 0)) < reapGracePeriod {
+			continue
+		}
+		for _, name := range c.Names {
+			name = strings.TrimPrefix(name, "/")
+			if !drv.tracker.isTracked(name) {
+				drv.reapContainer(ctx, log, name)
+			}
+		}
+	}
+}
+
+func (drv *DockerDriver) reapContainer(ctx context.Context, log logrus.FieldLogger, name string) {
+	log.WithFields(logrus.Fields{"container": name}).Info("removing orphaned container")
+
+	err := drv.docker.RemoveContainer(docker.RemoveContainerOptions{
+		ID: name, Force: true, RemoveVolumes: true, Context: ctx})
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"container": name}).Error("error removing orphaned container")
+		return
+	}
+
+	stats.Record(ctx, dockerReapedMeasure.M(0))
+}
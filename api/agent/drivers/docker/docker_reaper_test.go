@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/sirupsen/logrus"
+)
+
+func newReaperTestDriver() (*DockerDriver, *fakeDocker) {
+	fake := newFakeDocker()
+	drv := &DockerDriver{docker: fake, conf: drivers.Config{InstanceID: "agent-1"}, tracker: newContainerTracker()}
+	return drv, fake
+}
+
+func TestReapOrphans(t *testing.T) {
+	drv, fake := newReaperTestDriver()
+	old := time.Now().Add(-time.Hour).Unix()
+	fake.containers = []docker.APIContainers{
+		{ID: "1", Names: []string{"/tracked"}, Created: old},
+		{ID: "2", Names: []string{"/orphan"}, Created: old},
+		{ID: "3", Names: []string{"/fresh"}, Created: time.Now().Unix()},
+	}
+	drv.tracker.track("tracked")
+
+	drv.reap(context.Background(), logrus.New())
+
+	if removed := fake.removedContainers(); !reflect.DeepEqual(removed, []string{"orphan"}) {
+		t.Fatalf("Expected only the untracked container past the grace period to be reaped, got %v", removed)
+	}
+	if filters := fake.listOpts.Filters["label"]; !fake.listOpts.All || !reflect.DeepEqual(filters, []string{AgentLabel + "=agent-1"}) {
+		t.Fatalf("Expected all containers of the instance to be listed, got %+v", fake.listOpts)
+	}
+
+	// containers are reaped once untracked
+	drv.tracker.untrack("tracked")
+	drv.reap(context.Background(), logrus.New())
+	if removed := fake.removedContainers(); !reflect.DeepEqual(removed, []string{"orphan", "tracked", "orphan"}) {
+		t.Fatalf("Expected the untracked container to be reaped, got %v", removed)
+	}
+}
+
+func TestReapOnDieEvents(t *testing.T) {
+	drv, fake := newReaperTestDriver()
+	drv.tracker.track("tracked")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go drv.reaper(ctx, time.Hour)
+
+	die := func(name, instance string) *docker.APIEvents {
+		return &docker.APIEvents{Type: "container", Action: "die",
+			Actor: docker.APIActor{Attributes: map[string]string{"name": name, AgentLabel: instance}}}
+	}
+	for _, ev := range []*docker.APIEvents{
+		die("tracked", "agent-1"),
+		die("other", "agent-2"),
+		{Type: "container", Action: "start", Actor: docker.APIActor{Attributes: map[string]string{"name": "started", AgentLabel: "agent-1"}}},
+		die("orphan", "agent-1"),
+	} {
+		fake.events <- ev
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fake.removedContainers()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if removed := fake.removedContainers(); !reflect.DeepEqual(removed, []string{"orphan"}) {
+		t.Fatalf("Expected only the untracked container of the instance to be reaped once it died, got %v", removed)
+	}
+}
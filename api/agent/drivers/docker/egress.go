@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+)
+
+const (
+	// egressDevice is the interface of containers that egress is shaped on
+	egressDevice = "eth0"
+	// egressMinBurst is the smallest token bucket, enough for a few packets
+	egressMinBurst = 16 * 1024
+)
+
+var (
+	egressLimitedMeasure = common.MakeMeasure("docker_egress_limited", "containers whose egress bandwidth is limited", "")
+	egressRateMeasure    = common.MakeMeasure("docker_egress_limit_rate", "egress bandwidth limit of containers", "bytes")
+	egressErrorMeasure   = common.MakeMeasure("docker_egress_limit_error", "containers whose egress bandwidth failed to be limited", "")
+)
+
+// shapeEgress limits the egress bandwidth of the network namespace of the
+// process pid to rate bytes per second
+func shapeEgress(ctx context.Context, pid int, rate uint64) error {
+	args := tcArgs(pid, rate)
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error limiting egress bandwidth: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// tcArgs shapes the egress of the network namespace of pid with a token
+// bucket filter, whose bucket holds a tenth of a second of traffic
+func tcArgs(pid int, rate uint64) []string {
+	burst := rate / 10
+	if burst < egressMinBurst {
+		burst = egressMinBurst
+	}
+	return []string{
+		"nsenter", "-t", strconv.Itoa(pid), "-n",
+		"tc", "qdisc", "replace", "dev", egressDevice, "root", "tbf",
+		"rate", strconv.FormatUint(rate*8, 10) + "bit",
+		"burst", strconv.FormatUint(burst, 10),
+		"latency", "50ms",
+	}
+}
+
+// limitEgress shapes the egress of the running container of the task, if the
+// task limits it. Containers sharing the network namespace of a pre-fork pool
+// container are not shaped, the limit would outlive them.
+func (c *cookie) limitEgress(ctx context.Context) error {
+	limiter, ok := c.task.(drivers.EgressLimiter)
+	if !ok || limiter.EgressLimit() == 0 {
+		return nil
+	}
+	rate := limiter.EgressLimit()
+	log := common.ComponentLogger(ctx, common.LogComponentDriver).WithFields(logrus.Fields{"egress_limit": rate, "call_id": c.task.Id()})
+
+	if c.drv.windows() {
+		log.Warn("not limiting the egress bandwidth of a windows container")
+		return nil
+	}
+	if c.poolId != "" {
+		log.Warn("not limiting the egress bandwidth of a container in a pre-fork pool network")
+		return nil
+	}
+
+	container, err := c.drv.docker.InspectContainerWithContext(c.task.Id(), ctx)
+	if err == nil {
+		err = shapeEgress(ctx, container.State.Pid, rate)
+	}
+	if err != nil {
+		stats.Record(ctx, egressErrorMeasure.M(0))
+		return err
+	}
+
+	log.Debug("limited egress bandwidth")
+	stats.Record(ctx, egressLimitedMeasure.M(0), egressRateMeasure.M(int64(rate)))
+	return nil
+}
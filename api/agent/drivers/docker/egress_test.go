@@ -0,0 +1,19 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEgressTCArgs(t *testing.T) {
+	args := strings.Join(tcArgs(42, 1000000), " ")
+	expected := "nsenter -t 42 -n tc qdisc replace dev eth0 root tbf rate 8000000bit burst 100000 latency 50ms"
+	if args != expected {
+		t.Fatalf("expected %q, got %q", expected, args)
+	}
+
+	// the bucket holds at least a few packets
+	if args := tcArgs(42, 1000); args[len(args)-3] != "16384" {
+		t.Fatalf("expected the minimum burst, got %v", args)
+	}
+}
@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// Labels set on containers created by the driver, so that containers can be
+// attributed to the agent and fn that owns them.
+const (
+	labelPrefix = "io.fnproject."
+
+	// AgentLabel is set on every container to the agent instance id
+	AgentLabel = labelPrefix + "agent"
+	// AppIDLabel is the app id of the fn running in the container
+	AppIDLabel = labelPrefix + drivers.LabelAppID
+	// FnIDLabel is the id of the fn running in the container
+	FnIDLabel = labelPrefix + drivers.LabelFnID
+	// CallIDLabel is the id of the call that launched the container
+	CallIDLabel = labelPrefix + drivers.LabelCallID
+	// LBGroupLabel is the lb group the launching call was placed from, if any
+	LBGroupLabel = labelPrefix + drivers.LabelLBGroup
+)
+
+func (c *cookie) configureLabels() {
+	labels := map[string]string{AgentLabel: c.drv.conf.InstanceID}
+
+	if l, ok := c.task.(drivers.Labeler); ok {
+		for k, v := range l.Labels() {
+			if v != "" {
+				labels[labelPrefix+k] = v
+			}
+		}
+	}
+
+	c.opts.Config.Labels = labels
+}
+
+// ListContainers returns all containers, running or not, that have every
+// one of the given labels, eg. {FnIDLabel: fnID}.
+func (drv *DockerDriver) ListContainers(ctx context.Context, labels map[string]string) ([]docker.APIContainers, error) {
+	filters := make([]string, 0, len(labels))
+	for k, v := range labels {
+		filters = append(filters, k+"="+v)
+	}
+
+	return drv.docker.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": filters},
+		Context: ctx,
+	})
+}
+
+// InspectContainers returns the details of all containers that have every
+// one of the given labels.
+func (drv *DockerDriver) InspectContainers(ctx context.Context, labels map[string]string) ([]*docker.Container, error) {
+	containers, err := drv.ListContainers(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*docker.Container, 0, len(containers))
+	for _, c := range containers {
+		container, err := drv.docker.InspectContainerWithContext(c.ID, ctx)
+		if _, ok := err.(*docker.NoSuchContainer); ok {
+			// removed since listing
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		res = append(res, container)
+	}
+	return res, nil
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+)
+
+type taskLabelsTest struct {
+	taskDockerTest
+	labels map[string]string
+}
+
+func (f *taskLabelsTest) Labels() map[string]string { return f.labels }
+
+func TestCookieLabels(t *testing.T) {
+	c := &cookie{
+		opts: docker.CreateContainerOptions{Config: &docker.Config{}, HostConfig: &docker.HostConfig{}},
+		task: &taskLabelsTest{labels: map[string]string{
+			drivers.LabelAppID:   "app",
+			drivers.LabelFnID:    "fn",
+			drivers.LabelCallID:  "call",
+			drivers.LabelLBGroup: "",
+		}},
+		drv: &DockerDriver{conf: drivers.Config{InstanceID: "agent-1"}},
+	}
+	c.configureLabels()
+
+	expected := map[string]string{AgentLabel: "agent-1", AppIDLabel: "app", FnIDLabel: "fn", CallIDLabel: "call"}
+	if !reflect.DeepEqual(c.opts.Config.Labels, expected) {
+		t.Fatalf("Expected labels %v, got %v", expected, c.opts.Config.Labels)
+	}
+
+	// tasks without labels are still attributed to the agent
+	c.task = &taskDockerTest{}
+	c.configureLabels()
+	if !reflect.DeepEqual(c.opts.Config.Labels, map[string]string{AgentLabel: "agent-1"}) {
+		t.Fatalf("Expected the agent label only, got %v", c.opts.Config.Labels)
+	}
+}
+
+func TestListContainersByLabels(t *testing.T) {
+	fake := newFakeDocker()
+	drv := &DockerDriver{docker: fake}
+	fake.containers = []docker.APIContainers{{ID: "1"}, {ID: "2"}}
+	fake.inspected["2"] = &docker.Container{ID: "2", Name: "/fn"}
+
+	containers, err := drv.ListContainers(context.Background(), map[string]string{AppIDLabel: "app", FnIDLabel: "fn"})
+	if err != nil || len(containers) != 2 {
+		t.Fatalf("Expected the listed containers, got %v %v", containers, err)
+	}
+	filters := fake.listOpts.Filters["label"]
+	sort.Strings(filters)
+	if !fake.listOpts.All || len(fake.listOpts.Filters) != 1 || !reflect.DeepEqual(filters, []string{AppIDLabel + "=app", FnIDLabel + "=fn"}) {
+		t.Fatalf("Expected all containers with every label to be listed, got %+v", fake.listOpts)
+	}
+
+	// containers removed since they were listed are left out
+	inspected, err := drv.InspectContainers(context.Background(), map[string]string{FnIDLabel: "fn"})
+	if err != nil || len(inspected) != 1 || inspected[0].ID != "2" {
+		t.Fatalf("Expected the containers still there to be inspected, got %v %v", inspected, err)
+	}
+	if filters := fake.listOpts.Filters["label"]; !reflect.DeepEqual(filters, []string{FnIDLabel + "=fn"}) {
+		t.Fatalf("Expected the containers with the label to be inspected, got %v", filters)
+	}
+}
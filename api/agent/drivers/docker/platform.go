@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/sirupsen/logrus"
+)
+
+// archAliases maps the uname machine names docker daemons report to the
+// architecture names of images
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"armv7l":  "arm",
+	"armhf":   "arm",
+	"i386":    "386",
+	"i686":    "386",
+}
+
+func normalizeArch(arch string) string {
+	if a, ok := archAliases[arch]; ok {
+		return a
+	}
+	return arch
+}
+
+// detectPlatform returns the operating system and architecture of the
+// containers of the docker daemon, linux and no architecture if they can not
+// be told
+func detectPlatform(driver *DockerDriver) (string, string) {
+	info, err := driver.docker.Info(context.Background())
+	if err != nil {
+		logrus.WithError(err).Warn("could not detect docker daemon platform, assuming linux")
+		return drivers.OSLinux, ""
+	}
+	osType := drivers.OSLinux
+	if info.OSType == drivers.OSWindows {
+		osType = drivers.OSWindows
+	}
+	return osType, normalizeArch(info.Architecture)
+}
+
+// resolveImage records the digest and platform of the image of the task,
+// which docker resolved from a multi-arch manifest to the platform of the
+// daemon when it was pulled. Images of another platform, e.g. single
+// architecture images built for another architecture, are rejected.
+func (c *cookie) resolveImage(img *docker.Image) error {
+	if img == nil {
+		return nil
+	}
+	// docker names official images without their library/ namespace
+	repo := strings.TrimPrefix(c.imgRepo, "library/")
+	if c.imgReg != "" {
+		repo = c.imgReg + "/" + c.imgRepo
+	}
+	c.imgDigest = img.ID
+	if len(img.RepoDigests) > 0 {
+		c.imgDigest = img.RepoDigests[0]
+	}
+	for _, d := range img.RepoDigests {
+		if strings.SplitN(d, "@", 2)[0] == repo {
+			c.imgDigest = d
+			break
+		}
+	}
+
+	osType, arch := img.OS, normalizeArch(img.Architecture)
+	if osType == "" {
+		osType = c.drv.OSType()
+	}
+	c.imgPlatform = osType
+	if arch != "" {
+		c.imgPlatform += "/" + arch
+	}
+
+	if osType != c.drv.OSType() || (c.drv.arch != "" && arch != "" && arch != c.drv.arch) {
+		platform := c.drv.OSType()
+		if c.drv.arch != "" {
+			platform += "/" + c.drv.arch
+		}
+		return models.NewAPIError(http.StatusBadRequest, fmt.Errorf("image '%s' is built for %s, which this runner of platform %s can not run, push the image for %s too", c.task.Image(), c.imgPlatform, platform, platform))
+	}
+	return nil
+}
+
+// ResolvedImage returns the digest and platform the image of the task resolved
+// to, once it was validated or pulled
+func (c *cookie) ResolvedImage() (digest, platform string) {
+	return c.imgDigest, c.imgPlatform
+}
+
+var _ drivers.ImageResolver = &cookie{}
@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestCookieResolveImage(t *testing.T) {
+	c := &cookie{
+		task: &taskDockerTest{id: "task"},
+		drv:  &DockerDriver{osType: drivers.OSLinux, arch: "arm64"},
+	}
+	c.imgReg, c.imgRepo, c.imgTag = drivers.ParseImage("hello-world")
+
+	digest := "hello-world@sha256:4df8ca8a7e309c256d60d7971ea14c27672fc0d10c5f303856d7bc48f8cc17ff"
+	err := c.resolveImage(&docker.Image{ID: "sha256:abc", OS: "linux", Architecture: "arm64",
+		RepoDigests: []string{"mirror.local/hello-world@sha256:0000", digest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, p := c.ResolvedImage(); d != digest || p != "linux/arm64" {
+		t.Fatalf("unexpected resolved image %s %s", d, p)
+	}
+
+	err = c.resolveImage(&docker.Image{ID: "sha256:abc", OS: "linux", Architecture: "amd64"})
+	if err == nil || !strings.Contains(err.Error(), "linux/amd64") {
+		t.Fatalf("expected an amd64 image to be rejected on an arm64 runner, got %v", err)
+	}
+	if d, _ := c.ResolvedImage(); d != "sha256:abc" {
+		t.Fatalf("expected the image id without repo digests, got %s", d)
+	}
+
+	if normalizeArch("x86_64") != "amd64" || normalizeArch("aarch64") != "arm64" || normalizeArch("s390x") != "s390x" {
+		t.Fatal("unexpected architecture names")
+	}
+}
@@ -0,0 +1,162 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/sirupsen/logrus"
+)
+
+// companion containers are named after the container of their task
+const (
+	initSuffix    = "-init"
+	sidecarSuffix = "-sidecar"
+)
+
+func (c *cookie) configureCompanions(log logrus.FieldLogger) {
+	task, ok := c.task.(drivers.CompanionTask)
+	if !ok {
+		return
+	}
+	c.init, c.sidecar = task.Companions()
+	if c.init != nil || c.sidecar != nil {
+		log.WithFields(logrus.Fields{"init": c.init != nil, "sidecar": c.sidecar != nil, "call_id": c.task.Id()}).Debug("setting companions")
+	}
+}
+
+// startCompanions starts the sidecar and runs the init container of the
+// task, before its container is created. The sidecar takes over the network
+// of the container of the task, which joins the network namespace of the
+// sidecar, unless it already joins the one of a pre-fork pool container.
+func (c *cookie) startCompanions(ctx context.Context) error {
+	if c.sidecar != nil {
+		name := c.task.Id() + sidecarSuffix
+		opts := c.companionOptions(ctx, name, c.sidecar)
+		if !strings.HasPrefix(c.opts.HostConfig.NetworkMode, "container:") {
+			opts.Config.Hostname = c.opts.Config.Hostname
+			opts.HostConfig.DNS = c.opts.HostConfig.DNS
+			opts.HostConfig.DNSSearch = c.opts.HostConfig.DNSSearch
+			opts.HostConfig.ExtraHosts = c.opts.HostConfig.ExtraHosts
+
+			// network settings are those of the namespace joined
+			c.opts.HostConfig.NetworkMode = "container:" + name
+			c.opts.Config.Hostname = ""
+			c.opts.HostConfig.DNS = nil
+			c.opts.HostConfig.DNSSearch = nil
+			c.opts.HostConfig.ExtraHosts = nil
+		}
+		if err := c.startCompanion(ctx, opts); err != nil {
+			return err
+		}
+		c.sidecarName = name
+	}
+
+	if c.init != nil {
+		name := c.task.Id() + initSuffix
+		opts := c.companionOptions(ctx, name, c.init)
+		if err := c.startCompanion(ctx, opts); err != nil {
+			return err
+		}
+
+		exitCode, err := c.drv.docker.WaitContainerWithContext(name, ctx)
+		if err != nil {
+			return err
+		}
+		if exitCode != 0 {
+			return models.NewAPIError(http.StatusBadGateway, fmt.Errorf("init container of image '%s' exited with status %d", c.init.Image, exitCode))
+		}
+	}
+	return nil
+}
+
+// companionOptions creates a container in the network, and with the labels
+// and logging, of the container of the task
+func (c *cookie) companionOptions(ctx context.Context, name string, companion *drivers.Companion) docker.CreateContainerOptions {
+	env := make([]string, 0, len(companion.Env))
+	for k, v := range companion.Env {
+		env = append(env, k+"="+v)
+	}
+
+	opts := docker.CreateContainerOptions{
+		Name: name,
+		Config: &docker.Config{
+			Image:  companion.Image,
+			Cmd:    strings.Fields(companion.Cmd),
+			Env:    env,
+			Labels: c.opts.Config.Labels,
+		},
+		HostConfig: &docker.HostConfig{
+			Init:        true,
+			NetworkMode: c.opts.HostConfig.NetworkMode,
+			LogConfig:   c.opts.HostConfig.LogConfig,
+			DNS:         c.opts.HostConfig.DNS,
+			DNSSearch:   c.opts.HostConfig.DNSSearch,
+			ExtraHosts:  c.opts.HostConfig.ExtraHosts,
+			CapDrop:     c.opts.HostConfig.CapDrop,
+			CapAdd:      c.opts.HostConfig.CapAdd,
+			SecurityOpt: c.opts.HostConfig.SecurityOpt,
+		},
+		Context: ctx,
+	}
+	if companion.Memory > 0 {
+		opts.Config.Memory = int64(companion.Memory)
+		opts.Config.MemorySwap = int64(companion.Memory) // disables swap
+	}
+	return opts
+}
+
+// startCompanion pulls the image of a companion if missing, then creates and
+// starts it. Companions are removed with the cookie.
+func (c *cookie) startCompanion(ctx context.Context, opts docker.CreateContainerOptions) error {
+	log := common.ComponentLogger(ctx, common.LogComponentDriver).WithFields(logrus.Fields{"companion": opts.Name, "image": opts.Config.Image, "call_id": c.task.Id()})
+	log.Debug("docker start companion")
+
+	if err := c.pullCompanionImage(ctx, opts.Config.Image); err != nil {
+		return err
+	}
+
+	c.drv.tracker.track(opts.Name)
+	c.companions = append(c.companions, opts.Name)
+
+	_, err := c.drv.docker.CreateContainer(opts)
+	if err != nil && err != docker.ErrContainerAlreadyExists {
+		log.WithError(err).Error("Could not create companion container")
+		return err
+	}
+	return c.drv.startTask(ctx, opts.Name)
+}
+
+func (c *cookie) pullCompanionImage(ctx context.Context, image string) error {
+	_, err := c.drv.docker.InspectImage(ctx, image)
+	if err != docker.ErrNoSuchImage {
+		return err
+	}
+
+	reg, repo, tag := drivers.ParseImage(image)
+	config := findRegistryConfig(reg, c.drv.auths)
+	err = c.drv.docker.PullImage(docker.PullImageOptions{Repository: path.Join(reg, repo), Tag: tag, Context: ctx}, *config)
+	if err != nil {
+		msg := err.Error()
+		if dErr, ok := err.(*docker.Error); ok {
+			msg = dockerMsg(dErr)
+		}
+		return models.NewAPIError(http.StatusBadGateway, fmt.Errorf("Failed to pull companion image '%s': %s", image, msg))
+	}
+	return nil
+}
+
+// removeCompanions removes the companions of the cookie, after its container
+func (c *cookie) removeCompanions(ctx context.Context) {
+	for _, name := range c.companions {
+		c.drv.removeContainer(ctx, name)
+	}
+	c.companions = nil
+	c.sidecarName = ""
+}
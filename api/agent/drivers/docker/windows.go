@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/sirupsen/logrus"
+)
+
+// windowsMinSandboxMB is the default size of the sandbox of windows
+// containers, the windows daemon only grows it with the size storage option
+const windowsMinSandboxMB = 20 * 1024
+
+// OSType returns the operating system of the containers of the driver
+func (drv *DockerDriver) OSType() string {
+	if drv.osType == "" {
+		return drivers.OSLinux
+	}
+	return drv.osType
+}
+
+func (drv *DockerDriver) windows() bool {
+	return drv.osType == drivers.OSWindows
+}
+
+// configureWindows replaces the options windows containers do not support:
+// there is no tmpfs, read-only root, init process, swap or kernel memory
+// limit, capability, cpuset, blkio or security option. CPUs are a count of
+// cores rather than a CFS quota, and the size storage option can only grow
+// the sandbox of a container, so smaller sizes are dropped.
+func (c *cookie) configureWindows(log logrus.FieldLogger) {
+	if !c.drv.windows() {
+		return
+	}
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("setting windows options")
+
+	hc := c.opts.HostConfig
+	hc.ReadonlyRootfs = false
+	hc.Init = false
+	hc.Tmpfs = nil
+	hc.CapAdd, hc.CapDrop, hc.SecurityOpt = nil, nil, nil
+	hc.CPUSetCPUs, hc.CPUSetMEMs = "", ""
+	hc.BlkioDeviceReadBps, hc.BlkioDeviceWriteBps = nil, nil
+	hc.BlkioDeviceReadIOps, hc.BlkioDeviceWriteIOps = nil, nil
+	c.opts.Config.MemorySwap = 0
+	c.opts.Config.KernelMemory = 0
+
+	if hc.CPUQuota > 0 {
+		// rounded up to whole cores
+		hc.CPUCount = (int64(c.task.CPUs()) + 999) / 1000
+		hc.CPUQuota, hc.CPUPeriod = 0, 0
+	}
+
+	if c.task.FsSize() > 0 && c.task.FsSize() < windowsMinSandboxMB {
+		log.WithFields(logrus.Fields{"size": c.task.FsSize(), "call_id": c.task.Id()}).Debug("not setting storage option smaller than the windows sandbox")
+		delete(hc.StorageOpt, "size")
+	}
+}
+
+var _ drivers.OSReporter = &DockerDriver{}
@@ -58,6 +58,24 @@ type Cookie interface {
 	ContainerOptions() interface{}
 }
 
+// ImageResolver is implemented by cookies that record what the image of their
+// task resolved to once it was validated or pulled, e.g. the digest of a
+// multi-arch manifest and the platform, as os/architecture, picked from it
+type ImageResolver interface {
+	ResolvedImage() (digest, platform string)
+}
+
+// Checkpointer is implemented by cookies that can checkpoint a running
+// container to disk, releasing its memory, and restore it later. This is
+// an optional extension to Freeze/Unfreeze for long idle containers.
+type Checkpointer interface {
+	// Checkpoint saves the state of the container to disk and stops it.
+	Checkpoint(ctx context.Context) error
+
+	// Restore starts a checkpointed container from its saved state.
+	Restore(ctx context.Context) error
+}
+
 type WaitResult interface {
 	// Wait may be called to await the result of a container's execution. If the
 	// provided context is canceled and the container does not return first, the
@@ -78,6 +96,25 @@ type Driver interface {
 	Close() error
 }
 
+// operating systems an OSReporter may return
+const (
+	OSLinux   = "linux"
+	OSWindows = "windows"
+)
+
+// OSReporter may be implemented by a Driver to report the operating system
+// of its containers, OSLinux if not implemented
+type OSReporter interface {
+	OSType() string
+}
+
+// PreForkTuner may be implemented by a Driver with a pool of pre-forked
+// containers, to change the number of free containers below which the pool
+// grows and above which it shrinks while it runs
+type PreForkTuner interface {
+	SetPreForkWaterMarks(low, high uint64) error
+}
+
 // RunResult indicates only the final state of the task.
 type RunResult interface {
 	// Error is an actionable/checkable error from the container, nil if
@@ -102,6 +139,90 @@ type LoggerConfig struct {
 
 	// Log Tag Pairs
 	Tags []LoggerTag
+
+	// Log driver to use, empty means syslog if URL is set, none otherwise
+	Driver string
+
+	// Driver specific options, these override any defaults set by the driver
+	Options map[string]string
+}
+
+// Label keys a Labeler may return
+const (
+	LabelAppID   = "app_id"
+	LabelFnID    = "fn_id"
+	LabelCallID  = "call_id"
+	LabelLBGroup = "lb_group"
+)
+
+// Labeler may be implemented by a ContainerTask to attach metadata to its
+// container, drivers may namespace the keys. Empty values are ignored.
+type Labeler interface {
+	Labels() map[string]string
+}
+
+// CPUPinner may be implemented by a ContainerTask to pin its container to
+// cores and the memory of NUMA nodes, both in the cpuset list format, e.g.
+// 0-3,8. Empty values leave the container unpinned.
+type CPUPinner interface {
+	CPUSet() (cpus, mems string)
+}
+
+// IOLimiter may be implemented by a ContainerTask to throttle the disk I/O of
+// its container on each of the block devices the driver is configured with,
+// in bytes and operations per second. Zero values are unlimited.
+type IOLimiter interface {
+	IOLimits() (readBps, writeBps, readIOPS, writeIOPS uint64)
+}
+
+// EgressLimiter may be implemented by a ContainerTask to limit the egress
+// bandwidth of its container, in bytes per second. Zero is unlimited.
+type EgressLimiter interface {
+	EgressLimit() uint64
+}
+
+// DNSConfigurer may be implemented by a ContainerTask to configure the name
+// resolution of its container. Servers and search domains replace the ones
+// of the driver if not empty, extra hosts, of the form host:ip, are added.
+type DNSConfigurer interface {
+	DNS() (servers, search, extraHosts []string)
+}
+
+// Proxier may be implemented by a ContainerTask to replace the HTTP(S)
+// proxies the driver injects into its container, if ok. Empty values are not
+// injected.
+type Proxier interface {
+	Proxy() (httpProxy, httpsProxy, noProxy string, ok bool)
+}
+
+// UserConfigurer may be implemented by a ContainerTask to replace the user,
+// and optionally group, of the driver its container runs as, if not empty
+type UserConfigurer interface {
+	User() string
+}
+
+// CapabilityConfigurer may be implemented by a ContainerTask to add Linux
+// capabilities to, or drop them from, the ones of the driver its container
+// runs with
+type CapabilityConfigurer interface {
+	Capabilities() (add, drop []string)
+}
+
+// Companion is a container a driver runs with the container of a task
+type Companion struct {
+	Image  string
+	Cmd    string
+	Env    map[string]string
+	Memory uint64 // bytes, unlimited if 0
+}
+
+// CompanionTask may be implemented by a ContainerTask to run containers with
+// its container, sharing its network namespace. The init container, if not
+// nil, runs to completion before the container of the task is created. The
+// sidecar container, if not nil, runs from before the container of the task
+// is created until it is removed.
+type CompanionTask interface {
+	Companions() (init, sidecar *Companion)
 }
 
 // The ContainerTask interface guides container execution across a wide variety of
@@ -135,6 +256,8 @@ type ContainerTask interface {
 	// Volumes returns an array of 2-element tuples indicating storage volume mounts.
 	// The first element is the path on the host, and the second element is the
 	// path in the container.
+	// The host path may be a docker volume name, the container path may end with
+	// mount options, eg. /data:ro.
 	Volumes() [][2]string
 
 	// Memory determines the max amount of RAM given to the container to use.
@@ -243,18 +366,38 @@ const (
 type Config struct {
 	// TODO this should all be driver-specific config and not in the
 	// driver package itself. fix if we ever one day try something else
-	Docker               string `json:"docker"`
-	DockerNetworks       string `json:"docker_networks"`
-	DockerLoadFile       string `json:"docker_load_file"`
-	ServerVersion        string `json:"server_version"`
-	PreForkPoolSize      uint64 `json:"pre_fork_pool_size"`
-	PreForkImage         string `json:"pre_fork_image"`
-	PreForkCmd           string `json:"pre_fork_cmd"`
-	PreForkUseOnce       uint64 `json:"pre_fork_use_once"`
-	PreForkNetworks      string `json:"pre_fork_networks"`
-	MaxTmpFsInodes       uint64 `json:"max_tmpfs_inodes"`
-	EnableReadOnlyRootFs bool   `json:"enable_readonly_rootfs"`
-	MaxRetries           uint64 `json:"max_retries"`
+	Docker               string        `json:"docker"`
+	DockerNetworks       string        `json:"docker_networks"`
+	DockerLoadFile       string        `json:"docker_load_file"`
+	ServerVersion        string        `json:"server_version"`
+	PreForkPoolSize      uint64        `json:"pre_fork_pool_size"`
+	PreForkImage         string        `json:"pre_fork_image"`
+	PreForkCmd           string        `json:"pre_fork_cmd"`
+	PreForkUseOnce       uint64        `json:"pre_fork_use_once"`
+	PreForkNetworks      string        `json:"pre_fork_networks"`
+	PreForkPoolMaxSize   uint64        `json:"pre_fork_pool_max_size"`
+	PreForkLowWater      uint64        `json:"pre_fork_low_water"`
+	PreForkHighWater     uint64        `json:"pre_fork_high_water"`
+	PreForkScaleInterval time.Duration `json:"pre_fork_scale_interval"`
+	MaxTmpFsInodes       uint64        `json:"max_tmpfs_inodes"`
+	EnableReadOnlyRootFs bool          `json:"enable_readonly_rootfs"`
+	MaxRetries           uint64        `json:"max_retries"`
+	CheckpointDir        string        `json:"checkpoint_dir"`
+	InstanceID           string        `json:"instance_id"`
+	ReapInterval         time.Duration `json:"reap_interval"`
+	BlkioDevices         string        `json:"blkio_devices"`
+	DNS                  string        `json:"dns"`
+	DNSSearch            string        `json:"dns_search"`
+	ExtraHosts           string        `json:"extra_hosts"`
+	HTTPProxy            string        `json:"http_proxy"`
+	HTTPSProxy           string        `json:"https_proxy"`
+	NoProxy              string        `json:"no_proxy"`
+	User                 string        `json:"user"`
+	RequireNonRoot       bool          `json:"require_non_root"`
+	CapDrop              string        `json:"cap_drop"`
+	CapAdd               string        `json:"cap_add"`
+	CapAllowed           string        `json:"cap_allowed"`
+	NoNewPrivileges      bool          `json:"no_new_privileges"`
 }
 
 func average(samples []Stat) (Stat, bool) {
@@ -0,0 +1,307 @@
+// Package memory provides a Driver that runs containers in memory, without
+// docker, for tests. Its containers serve calls over the unix socket of the
+// agent like an FDK would, doing what a Script says for each call, so that
+// agent, placer and server tests can run many calls quickly and
+// deterministically.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// Behavior is what a container does with a call
+type Behavior struct {
+	// Sleep is how long the call takes before the container does anything
+	// else, calls are cut off by their deadline
+	Sleep time.Duration
+	// Output is the body of the response
+	Output []byte
+	// Status is the status code of the response, http.StatusOK if 0. FDKs
+	// respond with http.StatusBadGateway to calls that failed.
+	Status int
+	// Stderr is written to the stderr of the container
+	Stderr string
+	// ExitCode, if not 0, exits the container with it instead of responding
+	ExitCode int
+	// OOM kills the container for running out of memory instead of responding
+	OOM bool
+}
+
+// exitCodeOOM is the code of containers killed for running out of memory
+const exitCodeOOM = 137
+
+// Script returns the behavior of the container of task for a call. Scripts of
+// a driver are called concurrently for the calls of all of its containers.
+type Script func(task drivers.ContainerTask, req *http.Request, body []byte) Behavior
+
+// Echo is a Script responding to calls with their body
+func Echo(_ drivers.ContainerTask, _ *http.Request, body []byte) Behavior {
+	return Behavior{Output: body}
+}
+
+// Respond returns a Script behaving as b for every call
+func Respond(b Behavior) Script {
+	return func(drivers.ContainerTask, *http.Request, []byte) Behavior {
+		return b
+	}
+}
+
+// Sequence returns a Script behaving as each of bs in turn, across the calls
+// of all containers, and as the last of bs once they run out
+func Sequence(bs ...Behavior) Script {
+	var mu sync.Mutex
+	var i int
+	return func(drivers.ContainerTask, *http.Request, []byte) Behavior {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(bs) == 0 {
+			return Behavior{}
+		}
+		b := bs[i]
+		if i < len(bs)-1 {
+			i++
+		}
+		return b
+	}
+}
+
+// Driver runs containers in memory
+type Driver struct {
+	script Script
+
+	containers uint64
+	calls      uint64
+}
+
+var _ drivers.Driver = new(Driver)
+
+// New returns a driver whose containers behave as script says, Echo if nil
+func New(script Script) *Driver {
+	if script == nil {
+		script = Echo
+	}
+	return &Driver{script: script}
+}
+
+// Containers returns the number of containers the driver has run
+func (d *Driver) Containers() uint64 {
+	return atomic.LoadUint64(&d.containers)
+}
+
+// Calls returns the number of calls the containers of the driver were sent
+func (d *Driver) Calls() uint64 {
+	return atomic.LoadUint64(&d.calls)
+}
+
+func (d *Driver) CreateCookie(ctx context.Context, task drivers.ContainerTask) (drivers.Cookie, error) {
+	return &cookie{drv: d, task: task}, nil
+}
+
+// Obsoleted.
+func (d *Driver) PrepareCookie(ctx context.Context, cookie drivers.Cookie) error {
+	return nil
+}
+
+func (d *Driver) Close() error {
+	return nil
+}
+
+type cookie struct {
+	drv  *Driver
+	task drivers.ContainerTask
+
+	once sync.Once
+	// exited is closed once the container exits, with exitCode
+	exited   chan struct{}
+	exitCode int
+	server   *http.Server
+	sockets  []string
+}
+
+var _ drivers.Cookie = new(cookie)
+
+func (c *cookie) Freeze(context.Context) error {
+	return nil
+}
+
+func (c *cookie) Unfreeze(context.Context) error {
+	return nil
+}
+
+func (c *cookie) ValidateImage(context.Context) (bool, error) {
+	return false, nil
+}
+
+func (c *cookie) PullImage(context.Context) error {
+	return nil
+}
+
+func (c *cookie) CreateContainer(context.Context) error {
+	return nil
+}
+
+func (c *cookie) ContainerOptions() interface{} {
+	return nil
+}
+
+// Run listens on the socket the agent expects the FDK of the container to
+// create, linking it to a socket it listens on first as FDKs do, so that the
+// agent never sees the socket before it is listened on
+func (c *cookie) Run(ctx context.Context) (drivers.WaitResult, error) {
+	listener := strings.TrimPrefix(c.task.EnvVars()["FN_LISTENER"], "unix:")
+	if listener == "" {
+		return nil, errors.New("memory: task has no FN_LISTENER unix socket to listen on")
+	}
+	dir, name := c.task.UDSAgentPath(), filepath.Base(listener)
+	phony := "phony" + name
+
+	l, err := net.Listen("unix", filepath.Join(dir, phony))
+	if err != nil {
+		return nil, fmt.Errorf("memory: cannot listen on socket: %v", err)
+	}
+	c.sockets = []string{filepath.Join(dir, phony), filepath.Join(dir, name)}
+
+	c.exited = make(chan struct{})
+	c.server = &http.Server{Handler: http.HandlerFunc(c.serve)}
+	go c.server.Serve(l)
+
+	if err := os.Symlink(phony, filepath.Join(dir, name)); err != nil {
+		c.exit(1)
+		return nil, fmt.Errorf("memory: cannot link socket: %v", err)
+	}
+	atomic.AddUint64(&c.drv.containers, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.exit(0)
+		case <-c.exited:
+		}
+	}()
+	return c, nil
+}
+
+func (c *cookie) serve(w http.ResponseWriter, r *http.Request) {
+	atomic.AddUint64(&c.drv.calls, 1)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	b := c.drv.script(c.task, r, body)
+
+	if b.Sleep > 0 {
+		select {
+		case <-time.After(b.Sleep):
+		case <-r.Context().Done():
+			return
+		case <-c.exited:
+			return
+		}
+	}
+	if b.Stderr != "" {
+		_, stderr := c.task.Logger()
+		io.WriteString(stderr, b.Stderr)
+	}
+
+	if b.OOM || b.ExitCode != 0 {
+		code := b.ExitCode
+		if b.OOM {
+			code = exitCodeOOM
+		}
+		// the container is gone before it responds, as far as the agent can tell
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+			}
+		}
+		c.exit(code)
+		return
+	}
+
+	status := b.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(status)
+	io.Copy(w, bytes.NewReader(b.Output))
+}
+
+// exit stops the container with code, it is only stopped once
+func (c *cookie) exit(code int) {
+	c.once.Do(func() {
+		c.exitCode = code
+		close(c.exited)
+		go c.server.Close()
+	})
+}
+
+// Wait waits for the container to exit, it is killed if ctx is done first
+func (c *cookie) Wait(ctx context.Context) drivers.RunResult {
+	select {
+	case <-c.exited:
+	case <-ctx.Done():
+		c.exit(0)
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return &runResult{drivers.StatusTimeout, context.DeadlineExceeded}
+		default:
+			return &runResult{drivers.StatusCancelled, context.Canceled}
+		}
+	}
+
+	switch c.exitCode {
+	case 0:
+		return &runResult{drivers.StatusSuccess, nil}
+	case exitCodeOOM:
+		common.ComponentLogger(ctx, common.LogComponentDriver).Error("memory oom")
+		err := errors.New("container out of memory, you may want to raise fn.memory for this function (default: 128MB)")
+		return &runResult{drivers.StatusKilled, models.NewAPIError(http.StatusBadGateway, err)}
+	default:
+		return &runResult{drivers.StatusError, models.NewAPIError(http.StatusBadGateway, fmt.Errorf("container exit code %d", c.exitCode))}
+	}
+}
+
+// Close stops the container, if it was run, and removes its sockets
+func (c *cookie) Close(ctx context.Context) error {
+	if c.exited != nil {
+		c.exit(0)
+	}
+	for _, s := range c.sockets {
+		os.Remove(s)
+	}
+	return nil
+}
+
+type runResult struct {
+	status string
+	err    error
+}
+
+func (r *runResult) Status() string { return r.status }
+func (r *runResult) Error() error   { return r.err }
+
+func init() {
+	drivers.Register("memory", func(config drivers.Config) (drivers.Driver, error) {
+		return New(nil), nil
+	})
+}
@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	pool "github.com/fnproject/fn/api/runnerpool"
+	"github.com/sirupsen/logrus"
+)
+
+// manages a set of runners discovered by a node provider, the set is
+// refreshed periodically and runners that went away are closed
+type dynamicRunnerPool struct {
+	provider  pool.NodeProvider
+	generator pool.MTLSRunnerFactory
+	tlsConf   *tls.Config // can be nil when running in insecure mode
+
+	lock    sync.RWMutex
+	runners []pool.Runner // sorted by address, placers rely on a stable order
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	closes sync.WaitGroup
+}
+
+func DefaultDynamicRunnerPool(provider pool.NodeProvider, interval time.Duration) pool.RunnerPool {
+	return NewDynamicRunnerPool(provider, interval, nil, SecureGRPCRunnerFactory)
+}
+
+// NewDynamicRunnerPool returns a runner pool using the runners listed by provider,
+// which is queried every interval.
+func NewDynamicRunnerPool(provider pool.NodeProvider, interval time.Duration, tlsConf *tls.Config, runnerFactory pool.MTLSRunnerFactory) pool.RunnerPool {
+	logrus.WithField("interval", interval).Info("Starting dynamic runner pool")
+	ctx, cancel := context.WithCancel(context.Background())
+	rp := &dynamicRunnerPool{
+		provider:  provider,
+		generator: runnerFactory,
+		tlsConf:   tlsConf,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	rp.refresh(ctx)
+	go rp.refreshLoop(ctx, interval)
+	return rp
+}
+
+func (rp *dynamicRunnerPool) refreshLoop(ctx context.Context, interval time.Duration) {
+	defer close(rp.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rp.refresh(ctx)
+		}
+	}
+}
+
+// refresh syncs the runners with the addresses listed by the provider,
+// keeping the runners whose address is still listed.
+func (rp *dynamicRunnerPool) refresh(ctx context.Context) {
+	addresses, err := rp.provider.Nodes(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logrus.WithError(err).Warn("Failed to discover runners, keeping current runners")
+		}
+		return
+	}
+	sort.Strings(addresses)
+
+	// only refresh replaces the runners, the current ones can be read unlocked
+	rp.lock.RLock()
+	current := rp.runners
+	rp.lock.RUnlock()
+	runners, removed := syncRunners(current, addresses, rp.generator, rp.tlsConf)
+
+	rp.lock.Lock()
+	rp.runners = runners
+	rp.lock.Unlock()
+
+	closeRemoved(ctx, runners, removed, &rp.closes)
+}
+
+// syncRunners returns the runners of addresses in order, reusing the current
+// runners whose address is still listed, and the current runners that are not
+func syncRunners(current []pool.Runner, addresses []string, generator pool.MTLSRunnerFactory, tlsConf *tls.Config) (runners, removed []pool.Runner) {
+	byAddr := make(map[string]pool.Runner, len(current))
+	for _, r := range current {
+		byAddr[r.Address()] = r
+	}
+
+	seen := make(map[string]bool, len(addresses))
+	runners = make([]pool.Runner, 0, len(addresses))
+	for _, entry := range addresses {
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		addr, zone := pool.ParseRunnerAddress(entry)
+		if r, ok := byAddr[addr]; ok && pool.RunnerZone(r) == zone {
+			runners = append(runners, r)
+			delete(byAddr, addr)
+			continue
+		}
+		r, err := generator(addr, tlsConf)
+		if err != nil {
+			logrus.WithError(err).WithField("runner_addr", addr).Warn("Invalid runner")
+			continue
+		}
+		logrus.WithFields(logrus.Fields{"runner_addr": addr, "zone": zone}).Info("Adding runner to pool")
+		runners = append(runners, pool.WithZone(r, zone))
+	}
+
+	removed = make([]pool.Runner, 0, len(byAddr))
+	for _, r := range byAddr {
+		removed = append(removed, r)
+	}
+	return runners, removed
+}
+
+// closeRemoved records the membership of a pool whose runners changed and
+// closes the runners removed from it in the background, tracked by closes
+func closeRemoved(ctx context.Context, runners, removed []pool.Runner, closes *sync.WaitGroup) {
+	addrs := make([]string, 0, len(removed))
+	for _, r := range removed {
+		addrs = append(addrs, r.Address())
+	}
+	pool.StatsRunnerMembers(ctx, runners, addrs)
+
+	// runners drain their in flight calls on close, do not hold up the caller
+	for _, r := range removed {
+		logrus.WithField("runner_addr", r.Address()).Info("Removing runner from pool")
+		closes.Add(1)
+		go func(r pool.Runner) {
+			defer closes.Done()
+			if err := r.Close(context.Background()); err != nil {
+				logrus.WithError(err).WithField("runner_addr", r.Address()).Error("Error closing runner")
+			}
+		}(r)
+	}
+}
+
+func (rp *dynamicRunnerPool) Runners(ctx context.Context, call pool.RunnerCall) ([]pool.Runner, error) {
+	rp.lock.RLock()
+	defer rp.lock.RUnlock()
+
+	r := make([]pool.Runner, len(rp.runners))
+	copy(r, rp.runners)
+	return r, nil
+}
+
+func (rp *dynamicRunnerPool) Shutdown(ctx context.Context) error {
+	rp.cancel()
+	<-rp.done
+
+	rp.lock.Lock()
+	runners := rp.runners
+	rp.runners = nil
+	rp.lock.Unlock()
+
+	var retErr error
+	for _, r := range runners {
+		err := r.Close(ctx)
+		if err != nil {
+			common.Logger(ctx).WithError(err).WithField("runner_addr", r.Address()).Error("Error closing runner")
+			// grab the first error only for now.
+			if retErr == nil {
+				retErr = err
+			}
+		}
+	}
+	rp.closes.Wait()
+	return retErr
+}
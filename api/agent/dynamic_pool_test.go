@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pool "github.com/fnproject/fn/api/runnerpool"
+	"go.opencensus.io/stats/view"
+)
+
+type mockNodeProvider struct {
+	lock  sync.Mutex
+	nodes []string
+	err   error
+}
+
+func (p *mockNodeProvider) Nodes(ctx context.Context) ([]string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.nodes, p.err
+}
+
+func runnerAddresses(t *testing.T, rp pool.RunnerPool) []string {
+	runners, err := rp.Runners(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Failed to list runners %v", err)
+	}
+	var res []string
+	for _, r := range runners {
+		res = append(res, r.Address())
+	}
+	return res
+}
+
+func TestDynamicPoolRefresh(t *testing.T) {
+	provider := &mockNodeProvider{nodes: []string{"127.0.0.1:8081", "127.0.0.1:8080", "127.0.0.1:8080"}}
+	np := NewDynamicRunnerPool(provider, time.Hour, nil, mockRunnerFactory)
+	rp := np.(*dynamicRunnerPool)
+
+	if addrs := runnerAddresses(t, np); !reflect.DeepEqual(addrs, []string{"127.0.0.1:8080", "127.0.0.1:8081"}) {
+		t.Fatalf("Unexpected runners %v", addrs)
+	}
+	before, _ := np.Runners(context.Background(), nil)
+
+	provider.nodes = []string{"127.0.0.1:8081", "127.0.0.1:8082"}
+	rp.refresh(context.Background())
+	after, _ := np.Runners(context.Background(), nil)
+	if len(after) != 2 || after[0] != before[1] || after[1].Address() != "127.0.0.1:8082" {
+		t.Fatalf("Unexpected runners after refresh %v", runnerAddresses(t, np))
+	}
+
+	// discovery failures keep the current runners
+	provider.err = errors.New("discovery failed")
+	rp.refresh(context.Background())
+	if addrs := runnerAddresses(t, np); len(addrs) != 2 {
+		t.Fatalf("Unexpected runners after failed refresh %v", addrs)
+	}
+
+	err := np.Shutdown(context.Background())
+	if err != ErrorGarbanzoBeans {
+		t.Fatalf("Expected garbanzo beans error from shutdown %v", err)
+	}
+}
+
+func TestKubernetesEndpointAddresses(t *testing.T) {
+	var endpoints k8sEndpoints
+	err := json.Unmarshal([]byte(`{"subsets": [{
+		"addresses": [{"ip": "10.0.0.1"}],
+		"notReadyAddresses": [{"ip": "10.0.0.2"}],
+		"ports": [{"name": "http", "port": 8080}, {"name": "grpc", "port": 9190}]
+	}]}`), &endpoints)
+	if err != nil {
+		t.Fatalf("Failed to decode endpoints %v", err)
+	}
+
+	for port, expected := range map[string]string{"": "10.0.0.1:8080", "grpc": "10.0.0.1:9190", "9190": "10.0.0.1:9190"} {
+		p := &kubernetesNodeProvider{port: port}
+		if addrs := p.addresses(&endpoints); len(addrs) != 1 || addrs[0] != expected {
+			t.Fatalf("Expected %v for port %q got %v", expected, port, addrs)
+		}
+	}
+	p := &kubernetesNodeProvider{port: "metrics"}
+	if addrs := p.addresses(&endpoints); len(addrs) != 0 {
+		t.Fatalf("Expected no address for unknown port got %v", addrs)
+	}
+}
+
+func TestDynamicPoolMemberStats(t *testing.T) {
+	pool.RegisterRunnerViews(nil, []float64{1, 10, 100})
+
+	provider := &mockNodeProvider{nodes: []string{"127.0.0.1:9080", "127.0.0.1:9081"}}
+	np := NewDynamicRunnerPool(provider, time.Hour, nil, mockRunnerFactory)
+	defer np.Shutdown(context.Background())
+
+	provider.nodes = []string{"127.0.0.1:9081"}
+	np.(*dynamicRunnerPool).refresh(context.Background())
+
+	rows, err := view.RetrieveData("lb_runner_member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := make(map[string]float64)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key.Name() == "runner_addr" {
+				members[tag.Value] = row.Data.(*view.LastValueData).Value
+			}
+		}
+	}
+	if v, ok := members["127.0.0.1:9080"]; !ok || v != 0 || members["127.0.0.1:9081"] != 1 {
+		t.Fatalf("Unexpected runner members %v", members)
+	}
+
+	rows, err = view.RetrieveData("lb_runner_pool_size")
+	if err != nil || len(rows) != 1 || rows[0].Data.(*view.LastValueData).Value != 1 {
+		t.Fatalf("Unexpected runner pool size %v %v", rows, err)
+	}
+}
@@ -1,8 +1,10 @@
 package agent
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/fnproject/fn/api/id"
 
@@ -14,34 +16,57 @@ import (
 // A starved request can call PerformEviction() to scan the evictable
 // hot containers and if a number of these can be evicted to satisfy
 // memory+cpu needs of the starved request, then those hot-containers
-// are evicted.
+// are evicted. Containers are evicted only for requests of the same or a
+// higher priority, lowest priority first, see models.FnPriorityAnnotation.
 
 type tokenKey struct {
-	id     string
-	slotId string
-	memory uint64
-	cpu    uint64
+	id       string
+	slotId   string
+	priority int32
+	memory   uint64
+	cpu      uint64
 }
 
 type EvictToken struct {
 	key       tokenKey
 	evictable uint32
+	frozen    uint32
+	lastUsed  int64 // unix nanos of the last time the container became evictable
 	C         chan struct{}
 	DoneChan  chan struct{}
 }
 
+const (
+	// EvictionPolicyFIFO evicts the oldest containers first
+	EvictionPolicyFIFO = "fifo"
+	// EvictionPolicyLRU evicts the least recently used containers first
+	EvictionPolicyLRU = "lru"
+)
+
 type Evictor interface {
 	// CreateEvictToken creates an eviction token to be used in evictor tracking. Returns
 	// an eviction token.
-	CreateEvictToken(slotId string, mem, cpu uint64) *EvictToken
+	CreateEvictToken(slotId string, priority int32, mem, cpu uint64) *EvictToken
 
 	// DeleteEvictToken deletes an eviction token from evictor system
 	DeleteEvictToken(token *EvictToken)
 
 	// PerformEviction performs evictions to satisfy cpu & mem arguments
 	// and returns a slice of channels for evictions performed. The callers
-	// can wait on these channel to ensure evictions are completed.
-	PerformEviction(slotId string, mem, cpu uint64) []chan struct{}
+	// can wait on these channel to ensure evictions are completed. Only
+	// containers of priority at most priority are evicted, frozen containers
+	// of the lowest priority first.
+	PerformEviction(slotId string, priority int32, mem, cpu uint64) []chan struct{}
+
+	// EvictIdle evicts least recently used evictable containers of any slot until
+	// at least mem is freed or no evictable containers remain, eg. under memory
+	// pressure. Returns a slice of channels for evictions performed as
+	// PerformEviction does.
+	EvictIdle(mem uint64) []chan struct{}
+
+	// EvictFrozen evicts least recently used evictable containers which are
+	// frozen as EvictIdle does, eg. under actual memory pressure.
+	EvictFrozen(mem uint64) []chan struct{}
 }
 
 type evictor struct {
@@ -49,12 +74,20 @@ type evictor struct {
 	id     uint64
 	tokens map[string]*EvictToken
 	slots  []tokenKey
+	lru    bool
 }
 
 func NewEvictor() Evictor {
+	return NewEvictorWithPolicy(EvictionPolicyFIFO)
+}
+
+// NewEvictorWithPolicy returns an evictor that picks candidates by policy, one
+// of EvictionPolicyFIFO or EvictionPolicyLRU
+func NewEvictorWithPolicy(policy string) Evictor {
 	return &evictor{
 		tokens: make(map[string]*EvictToken),
 		slots:  make([]tokenKey, 0),
+		lru:    policy == EvictionPolicyLRU,
 	}
 }
 
@@ -73,9 +106,21 @@ func (token *EvictToken) SetEvictable(isEvictable bool) {
 		val = 1
 	}
 
+	if isEvictable {
+		atomic.StoreInt64(&token.lastUsed, time.Now().UnixNano())
+	}
 	atomic.StoreUint32(&token.evictable, val)
 }
 
+// SetFrozen marks the container of the token as frozen, or thawed
+func (token *EvictToken) SetFrozen(isFrozen bool) {
+	val := uint32(0)
+	if isFrozen {
+		val = 1
+	}
+	atomic.StoreUint32(&token.frozen, val)
+}
+
 func (tok *EvictToken) isEligible() bool {
 	// if no resource limits are in place, then this
 	// function is not eligible.
@@ -85,13 +130,14 @@ func (tok *EvictToken) isEligible() bool {
 	return true
 }
 
-func (e *evictor) CreateEvictToken(slotId string, mem, cpu uint64) *EvictToken {
+func (e *evictor) CreateEvictToken(slotId string, priority int32, mem, cpu uint64) *EvictToken {
 
 	key := tokenKey{
-		id:     id.New().String(),
-		slotId: slotId,
-		memory: mem,
-		cpu:    cpu,
+		id:       id.New().String(),
+		slotId:   slotId,
+		priority: priority,
+		memory:   mem,
+		cpu:      cpu,
 	}
 
 	token := &EvictToken{
@@ -139,27 +185,118 @@ func (e *evictor) DeleteEvictToken(token *EvictToken) {
 	close(token.DoneChan)
 }
 
-func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{} {
-	var notifyChans []chan struct{}
-
+func (e *evictor) PerformEviction(slotId string, priority int32, mem, cpu uint64) []chan struct{} {
 	// if no resources are defined for this function, then
 	// we don't know what to do here. We cannot evict anyone
 	// in this case.
 	if mem == 0 && cpu == 0 {
-		return notifyChans
+		return nil
 	}
 
+	e.lock.Lock()
+	var candidates []tokenKey
+	for _, val := range e.byPriority(e.candidates(e.lru), true) {
+		if val.priority <= priority {
+			candidates = append(candidates, val)
+		}
+	}
+	notifyChans, completionChans := e.evict(candidates, slotId, mem, cpu, false)
+	e.lock.Unlock()
+
+	for _, ch := range notifyChans {
+		close(ch)
+	}
+
+	return completionChans
+}
+
+func (e *evictor) EvictIdle(mem uint64) []chan struct{} {
+	if mem == 0 {
+		return nil
+	}
+
+	e.lock.Lock()
+	candidates := e.byPriority(e.candidates(true), false)
+	notifyChans, completionChans := e.evict(candidates, "", mem, 0, true)
+	e.lock.Unlock()
+
+	for _, ch := range notifyChans {
+		close(ch)
+	}
+
+	return completionChans
+}
+
+func (e *evictor) EvictFrozen(mem uint64) []chan struct{} {
+	if mem == 0 {
+		return nil
+	}
+
+	e.lock.Lock()
+	var frozen []tokenKey
+	for _, val := range e.byPriority(e.candidates(true), false) {
+		if atomic.LoadUint32(&e.tokens[val.id].frozen) == 1 {
+			frozen = append(frozen, val)
+		}
+	}
+	notifyChans, completionChans := e.evict(frozen, "", mem, 0, true)
+	e.lock.Unlock()
+
+	for _, ch := range notifyChans {
+		close(ch)
+	}
+
+	return completionChans
+}
+
+// candidates returns the slots in eviction order, oldest first or least recently
+// used first if lru is set. Must be called with lock held.
+func (e *evictor) candidates(lru bool) []tokenKey {
+	if !lru {
+		return e.slots
+	}
+	candidates := make([]tokenKey, len(e.slots))
+	copy(candidates, e.slots)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return atomic.LoadInt64(&e.tokens[candidates[i].id].lastUsed) < atomic.LoadInt64(&e.tokens[candidates[j].id].lastUsed)
+	})
+	return candidates
+}
+
+// byPriority returns candidates ordered by priority, lowest first, and frozen
+// containers first within a priority if frozenFirst is set, keeping the order
+// of candidates otherwise. Must be called with lock held.
+func (e *evictor) byPriority(candidates []tokenKey, frozenFirst bool) []tokenKey {
+	sorted := make([]tokenKey, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].priority != sorted[j].priority {
+			return sorted[i].priority < sorted[j].priority
+		}
+		if !frozenFirst {
+			return false
+		}
+		return atomic.LoadUint32(&e.tokens[sorted[i].id].frozen) > atomic.LoadUint32(&e.tokens[sorted[j].id].frozen)
+	})
+	return sorted
+}
+
+// evict picks evictable candidates outside of slotId until mem and cpu are satisfied,
+// removes them from tracking and returns their notify and completion channels. Unless
+// partial is set, nothing is evicted if the need cannot be satisfied. Must be called
+// with lock held.
+func (e *evictor) evict(candidates []tokenKey, slotId string, mem, cpu uint64, partial bool) ([]chan struct{}, []chan struct{}) {
+	var notifyChans []chan struct{}
+	var completionChans []chan struct{}
+
 	// Our eviction sum so far
 	totalMemory := uint64(0)
 	totalCpu := uint64(0)
 	isSatisfied := false
 
 	var keys []string
-	var completionChans []chan struct{}
-
-	e.lock.Lock()
 
-	for _, val := range e.slots {
+	for _, val := range candidates {
 		// lets not evict from our own slot queue
 		if slotId == val.slotId {
 			continue
@@ -181,35 +318,26 @@ func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{
 	}
 
 	// If we can satisfy the need, then let's commit/perform eviction
-	if isSatisfied {
-
-		notifyChans = make([]chan struct{}, 0, len(keys))
-		completionChans = make([]chan struct{}, 0, len(keys))
+	if !isSatisfied && (!partial || len(keys) == 0) {
+		return nil, nil
+	}
 
-		idx := 0
-		for _, id := range keys {
+	notifyChans = make([]chan struct{}, 0, len(keys))
+	completionChans = make([]chan struct{}, 0, len(keys))
 
-			// do not initialize idx, we continue where we left off
-			// since keys are in order from above.
-			for ; idx < len(e.slots); idx++ {
-				if id == e.slots[idx].id {
-					e.slots = append(e.slots[:idx], e.slots[idx+1:]...)
-					break
-				}
+	for _, id := range keys {
+		for idx := range e.slots {
+			if id == e.slots[idx].id {
+				e.slots = append(e.slots[:idx], e.slots[idx+1:]...)
+				break
 			}
-
-			notifyChans = append(notifyChans, e.tokens[id].C)
-			completionChans = append(completionChans, e.tokens[id].DoneChan)
-
-			delete(e.tokens, id)
 		}
-	}
 
-	e.lock.Unlock()
+		notifyChans = append(notifyChans, e.tokens[id].C)
+		completionChans = append(completionChans, e.tokens[id].DoneChan)
 
-	for _, ch := range notifyChans {
-		close(ch)
+		delete(e.tokens, id)
 	}
 
-	return completionChans
+	return notifyChans, completionChans
 }
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"testing"
+	"time"
 )
 
 func getACall(slot string, mem, cpu int) (string, uint64, uint64) {
@@ -15,19 +16,19 @@ func TestEvictorSimple01(t *testing.T) {
 	_, mem1, cpu1 := getACall(slotId, 1, 100)
 	_, mem2, cpu2 := getACall(slotId, 1, 100)
 
-	token1 := evictor.CreateEvictToken(slotId, mem1, cpu1)
-	token2 := evictor.CreateEvictToken(slotId, mem2, cpu2)
+	token1 := evictor.CreateEvictToken(slotId, 0, mem1, cpu1)
+	token2 := evictor.CreateEvictToken(slotId, 0, mem2, cpu2)
 
 	token1.SetEvictable(true)
 	token2.SetEvictable(true)
 
-	if len(evictor.PerformEviction(slotId, mem1, cpu1)) > 0 {
+	if len(evictor.PerformEviction(slotId, 0, mem1, cpu1)) > 0 {
 		t.Fatalf("We should not be able to self evict")
 	}
-	if len(evictor.PerformEviction("foo", 0, 0)) > 0 {
+	if len(evictor.PerformEviction("foo", 0, 0, 0)) > 0 {
 		t.Fatalf("We should not be able to evict: zero cpu/mem")
 	}
-	if len(evictor.PerformEviction("foo", 1, 300)) > 0 {
+	if len(evictor.PerformEviction("foo", 0, 1, 300)) > 0 {
 		t.Fatalf("We should not be able to evict (resource not enough)")
 	}
 
@@ -38,7 +39,7 @@ func TestEvictorSimple01(t *testing.T) {
 		t.Fatalf("should not be evicted")
 	}
 
-	if len(evictor.PerformEviction("foo", 1, 100)) != 1 {
+	if len(evictor.PerformEviction("foo", 0, 1, 100)) != 1 {
 		t.Fatalf("We should be able to evict")
 	}
 
@@ -59,8 +60,8 @@ func TestEvictorSimple02(t *testing.T) {
 	slotId1, mem1, cpu1 := getACall("slot1", 1, 100)
 	slotId2, mem2, cpu2 := getACall("slot1", 1, 100)
 
-	token1 := evictor.CreateEvictToken(slotId1, mem1, cpu1)
-	token2 := evictor.CreateEvictToken(slotId2, mem2, cpu2)
+	token1 := evictor.CreateEvictToken(slotId1, 0, mem1, cpu1)
+	token2 := evictor.CreateEvictToken(slotId2, 0, mem2, cpu2)
 
 	// add/rm/add
 	token1.SetEvictable(true)
@@ -71,10 +72,10 @@ func TestEvictorSimple02(t *testing.T) {
 	token2.SetEvictable(true)
 	token2.SetEvictable(false)
 
-	if len(evictor.PerformEviction(slotId1, mem1, cpu1)) > 0 {
+	if len(evictor.PerformEviction(slotId1, 0, mem1, cpu1)) > 0 {
 		t.Fatalf("We should not be able to self evict")
 	}
-	if len(evictor.PerformEviction("foo", 0, 0)) > 0 {
+	if len(evictor.PerformEviction("foo", 0, 0, 0)) > 0 {
 		t.Fatalf("We should not be able to evict: zero cpu/mem")
 	}
 	if token1.isEvicted() {
@@ -86,7 +87,7 @@ func TestEvictorSimple02(t *testing.T) {
 	// not registered... but should be OK
 	token2.SetEvictable(false)
 
-	if len(evictor.PerformEviction("foo", mem1, cpu1)) > 0 {
+	if len(evictor.PerformEviction("foo", 0, mem1, cpu1)) > 0 {
 		t.Fatalf("We should not be able to evict (unregistered)")
 	}
 	if token1.isEvicted() {
@@ -107,17 +108,17 @@ func TestEvictorSimple03(t *testing.T) {
 	_, mem2, cpu2 := getACall(slotId, 1, 100)
 	_, mem3, cpu3 := getACall(slotId, 1, 100)
 
-	token0 := evictor.CreateEvictToken(slotId0, mem0, cpu0)
-	token1 := evictor.CreateEvictToken(slotId, mem1, cpu1)
-	token2 := evictor.CreateEvictToken(slotId, mem2, cpu2)
-	token3 := evictor.CreateEvictToken(slotId, mem3, cpu3)
+	token0 := evictor.CreateEvictToken(slotId0, 0, mem0, cpu0)
+	token1 := evictor.CreateEvictToken(slotId, 0, mem1, cpu1)
+	token2 := evictor.CreateEvictToken(slotId, 0, mem2, cpu2)
+	token3 := evictor.CreateEvictToken(slotId, 0, mem3, cpu3)
 
 	token0.SetEvictable(true)
 	token1.SetEvictable(true)
 	token2.SetEvictable(true)
 	token3.SetEvictable(true)
 
-	if len(evictor.PerformEviction(taboo, 1, 200)) == 0 {
+	if len(evictor.PerformEviction(taboo, 0, 1, 200)) == 0 {
 		t.Fatalf("We should be able to evict")
 	}
 
@@ -141,3 +142,116 @@ func TestEvictorSimple03(t *testing.T) {
 	evictor.DeleteEvictToken(token2)
 	evictor.DeleteEvictToken(token3)
 }
+
+func TestEvictorLRU(t *testing.T) {
+	evictor := NewEvictorWithPolicy(EvictionPolicyLRU)
+
+	token1 := evictor.CreateEvictToken("slot1", 0, 1, 100)
+	token2 := evictor.CreateEvictToken("slot2", 0, 1, 100)
+
+	// token1 is the oldest container but the most recently used one
+	token2.SetEvictable(true)
+	time.Sleep(time.Millisecond)
+	token1.SetEvictable(true)
+
+	if len(evictor.PerformEviction("foo", 0, 1, 100)) != 1 {
+		t.Fatalf("We should be able to evict")
+	}
+	if token1.isEvicted() {
+		t.Fatalf("should not be evicted")
+	}
+	if !token2.isEvicted() {
+		t.Fatalf("should be evicted")
+	}
+
+	evictor.DeleteEvictToken(token1)
+	evictor.DeleteEvictToken(token2)
+}
+
+func TestEvictorEvictIdle(t *testing.T) {
+	evictor := NewEvictor()
+
+	token1 := evictor.CreateEvictToken("slot1", 0, 1, 100)
+	token2 := evictor.CreateEvictToken("slot1", 0, 1, 100)
+	token3 := evictor.CreateEvictToken("slot2", 0, 1, 100)
+
+	token1.SetEvictable(true)
+	token3.SetEvictable(true)
+
+	// not enough idle memory, but evict what we can
+	if len(evictor.EvictIdle(5)) != 2 {
+		t.Fatalf("We should be able to evict all idle containers")
+	}
+	if !token1.isEvicted() || !token3.isEvicted() {
+		t.Fatalf("should be evicted")
+	}
+	if token2.isEvicted() {
+		t.Fatalf("should not be evicted")
+	}
+
+	evictor.DeleteEvictToken(token1)
+	evictor.DeleteEvictToken(token2)
+	evictor.DeleteEvictToken(token3)
+}
+
+func TestEvictorEvictFrozen(t *testing.T) {
+	evictor := NewEvictorWithPolicy(EvictionPolicyLRU)
+
+	token1 := evictor.CreateEvictToken("slot1", 0, 1, 100)
+	token2 := evictor.CreateEvictToken("slot1", 0, 1, 100)
+	token3 := evictor.CreateEvictToken("slot2", 0, 1, 100)
+
+	token1.SetEvictable(true)
+	token2.SetEvictable(true)
+	token3.SetEvictable(true)
+	token2.SetFrozen(true)
+	token3.SetFrozen(true)
+
+	// the least recently used frozen container is evicted first
+	if len(evictor.EvictFrozen(1)) != 1 {
+		t.Fatalf("We should be able to evict a frozen container")
+	}
+	if !token2.isEvicted() {
+		t.Fatalf("should be evicted")
+	}
+	if token1.isEvicted() || token3.isEvicted() {
+		t.Fatalf("should not be evicted")
+	}
+
+	evictor.DeleteEvictToken(token1)
+	evictor.DeleteEvictToken(token2)
+	evictor.DeleteEvictToken(token3)
+}
+
+func TestEvictorPriority(t *testing.T) {
+	evictor := NewEvictor()
+
+	high := evictor.CreateEvictToken("high", 2, 100, 0)
+	low := evictor.CreateEvictToken("low", 0, 100, 0)
+	lowFrozen := evictor.CreateEvictToken("low-frozen", 0, 100, 0)
+	for _, tok := range []*EvictToken{high, low, lowFrozen} {
+		tok.SetEvictable(true)
+	}
+	lowFrozen.SetFrozen(true)
+
+	// low priority calls cannot evict high priority containers
+	if len(evictor.PerformEviction("foo", 0, 300, 0)) > 0 {
+		t.Fatalf("We should not be able to evict high priority containers")
+	}
+
+	// high priority calls preempt frozen low priority containers first
+	if len(evictor.PerformEviction("foo", 1, 100, 0)) != 1 {
+		t.Fatalf("We should be able to evict a low priority container")
+	}
+	if !lowFrozen.isEvicted() || low.isEvicted() || high.isEvicted() {
+		t.Fatalf("the frozen low priority container should be evicted")
+	}
+
+	if len(evictor.PerformEviction("foo", 2, 200, 0)) != 2 {
+		t.Fatalf("We should be able to evict the remaining containers")
+	}
+
+	evictor.DeleteEvictToken(high)
+	evictor.DeleteEvictToken(low)
+	evictor.DeleteEvictToken(lowFrozen)
+}
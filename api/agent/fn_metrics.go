@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// FnMetrics is the demand for a fn over the sliding window of an agent, the
+// input of autoscalers sizing the runners and warm containers of fns
+type FnMetrics struct {
+	FnID string `json:"fn_id"`
+	// InFlight is the number of calls of the fn running now
+	InFlight int64 `json:"in_flight"`
+	// Concurrency is the average number of calls of the fn running over the window
+	Concurrency float64 `json:"concurrency"`
+	// RPS is the number of calls of the fn finished per second over the window
+	RPS float64 `json:"rps"`
+	// P95Millis is the 95th percentile latency of the calls of the fn finished
+	// over the window, rounded up to a histogram bound
+	P95Millis int64 `json:"p95_msecs"`
+}
+
+// FnMetricsReporter is implemented by agents aggregating FnMetrics
+type FnMetricsReporter interface {
+	// FnMetrics returns the metrics of the fns with calls over the window,
+	// sorted by fn id
+	FnMetrics() ([]FnMetrics, error)
+}
+
+// number of buckets the window of fnMetrics slides by
+const fnMetricsBuckets = 6
+
+// latency histogram bounds p95s are rounded up to, in msecs
+var fnLatencyBounds = [...]int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 120000, 300000}
+
+// fnMetrics aggregates the calls of each fn over a sliding window, split in
+// fnMetricsBuckets buckets that expire one at a time
+type fnMetrics struct {
+	window time.Duration
+	width  time.Duration
+
+	lock sync.Mutex
+	fns  map[string]*fnWindow
+}
+
+type fnWindow struct {
+	inFlight   int64
+	lastChange time.Time
+	buckets    [fnMetricsBuckets]fnBucket
+}
+
+type fnBucket struct {
+	idx   int64
+	calls int64
+	// sum over time of the calls in flight, in call-nanoseconds
+	busy    float64
+	latency [len(fnLatencyBounds) + 1]int64
+}
+
+func newFnMetrics(window time.Duration) *fnMetrics {
+	if window <= 0 {
+		return nil
+	}
+	return &fnMetrics{
+		window: window,
+		width:  window / fnMetricsBuckets,
+		fns:    make(map[string]*fnWindow),
+	}
+}
+
+// bucket returns the bucket of w at now, reset if it expired. Must be called
+// with lock held.
+func (m *fnMetrics) bucket(w *fnWindow, now time.Time) *fnBucket {
+	idx := now.UnixNano() / int64(m.width)
+	b := &w.buckets[idx%fnMetricsBuckets]
+	if b.idx != idx {
+		*b = fnBucket{idx: idx}
+	}
+	return b
+}
+
+// accrue adds the time the calls in flight ran since the last change, within
+// the window, to the current bucket. Must be called with lock held.
+func (m *fnMetrics) accrue(w *fnWindow, now time.Time) {
+	if w.inFlight > 0 {
+		d := now.Sub(w.lastChange)
+		if d > m.window {
+			d = m.window
+		}
+		m.bucket(w, now).busy += float64(w.inFlight) * float64(d)
+	}
+	w.lastChange = now
+}
+
+// start records a call of fnID starting, the returned func records it ending
+func (m *fnMetrics) start(fnID string) func() {
+	if m == nil || fnID == "" {
+		return func() {}
+	}
+
+	begin := time.Now()
+	m.lock.Lock()
+	w, ok := m.fns[fnID]
+	if !ok {
+		w = &fnWindow{}
+		m.fns[fnID] = w
+	}
+	m.accrue(w, begin)
+	w.inFlight++
+	m.lock.Unlock()
+
+	return func() {
+		now := time.Now()
+		ms := int64(now.Sub(begin) / time.Millisecond)
+		bound := sort.Search(len(fnLatencyBounds), func(i int) bool { return fnLatencyBounds[i] >= ms })
+
+		m.lock.Lock()
+		m.accrue(w, now)
+		w.inFlight--
+		b := m.bucket(w, now)
+		b.calls++
+		b.latency[bound]++
+		m.lock.Unlock()
+	}
+}
+
+// metrics returns the metrics of the fns with calls in the window at now,
+// forgetting the others
+func (m *fnMetrics) metrics(now time.Time) []FnMetrics {
+	if m == nil {
+		return nil
+	}
+
+	oldest := now.UnixNano()/int64(m.width) - fnMetricsBuckets + 1
+
+	m.lock.Lock()
+	res := make([]FnMetrics, 0, len(m.fns))
+	for fnID, w := range m.fns {
+		m.accrue(w, now)
+
+		var calls int64
+		var busy float64
+		var latency [len(fnLatencyBounds) + 1]int64
+		for _, b := range w.buckets {
+			if b.idx < oldest {
+				continue
+			}
+			calls += b.calls
+			busy += b.busy
+			for i, n := range b.latency {
+				latency[i] += n
+			}
+		}
+		if calls == 0 && busy == 0 && w.inFlight == 0 {
+			delete(m.fns, fnID)
+			continue
+		}
+
+		res = append(res, FnMetrics{
+			FnID:        fnID,
+			InFlight:    w.inFlight,
+			Concurrency: busy / float64(m.window),
+			RPS:         float64(calls) / m.window.Seconds(),
+			P95Millis:   percentile(latency[:], calls, 0.95),
+		})
+	}
+	m.lock.Unlock()
+
+	sort.Slice(res, func(i, j int) bool { return res[i].FnID < res[j].FnID })
+	return res
+}
+
+// percentile returns the histogram bound below which the p share of calls
+// fall, the calls over the last bound count as the last bound
+func percentile(latency []int64, calls int64, p float64) int64 {
+	if calls == 0 {
+		return 0
+	}
+	var n int64
+	for i, c := range latency {
+		n += c
+		if float64(n) >= p*float64(calls) && i < len(fnLatencyBounds) {
+			return fnLatencyBounds[i]
+		}
+	}
+	return fnLatencyBounds[len(fnLatencyBounds)-1]
+}
+
+// FnMetrics implements FnMetricsReporter
+func (a *agent) FnMetrics() ([]FnMetrics, error) {
+	if a.fnMetrics == nil {
+		return nil, models.ErrFnMetricsUnsupported
+	}
+	return a.fnMetrics.metrics(time.Now()), nil
+}
+
+// FnMetrics implements FnMetricsReporter
+func (pr *pureRunner) FnMetrics() ([]FnMetrics, error) {
+	if r, ok := pr.a.(FnMetricsReporter); ok {
+		return r.FnMetrics()
+	}
+	return nil, models.ErrFnMetricsUnsupported
+}
+
+var _ FnMetricsReporter = new(agent)
+var _ FnMetricsReporter = new(pureRunner)
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFnMetrics(t *testing.T) {
+	if m := newFnMetrics(0); m != nil || m.metrics(time.Now()) != nil {
+		t.Fatal("Expected fn metrics to be disabled without a window")
+	}
+	newFnMetrics(0).start("fn")()
+
+	m := newFnMetrics(600 * time.Millisecond)
+	for i := 0; i < 19; i++ {
+		m.start("fast")()
+	}
+	slow := m.start("fast")
+	running := m.start("slow")
+	time.Sleep(120 * time.Millisecond)
+	slow()
+
+	metrics := m.metrics(time.Now())
+	if len(metrics) != 2 || metrics[0].FnID != "fast" || metrics[1].FnID != "slow" {
+		t.Fatalf("Unexpected fn metrics %+v", metrics)
+	}
+	fast := metrics[0]
+	if fast.InFlight != 0 || fast.RPS < 33 || fast.RPS > 34 || fast.P95Millis != 5 {
+		t.Fatalf("Unexpected metrics of fn fast %+v", fast)
+	}
+	if s := metrics[1]; s.InFlight != 1 || s.RPS != 0 || s.Concurrency < 0.1 || s.Concurrency > 1 {
+		t.Fatalf("Unexpected metrics of fn slow %+v", s)
+	}
+	running()
+
+	// fns without calls over the window are forgotten
+	if metrics := m.metrics(time.Now().Add(2 * time.Second)); len(metrics) != 0 {
+		t.Fatalf("Expected fns to expire, got %+v", metrics)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latency := make([]int64, len(fnLatencyBounds)+1)
+	latency[0] = 94
+	latency[3] = 5
+	latency[len(fnLatencyBounds)] = 1
+	if p := percentile(latency, 100, 0.95); p != 50 {
+		t.Fatalf("Expected p95 of 50ms, got %d", p)
+	}
+	if p := percentile(latency, 100, 1); p != fnLatencyBounds[len(fnLatencyBounds)-1] {
+		t.Fatalf("Expected calls over the last bound to count as the last bound, got %d", p)
+	}
+}
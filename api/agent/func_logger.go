@@ -5,8 +5,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"sync"
 
+	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 	"github.com/sirupsen/logrus"
@@ -46,7 +48,7 @@ func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call
 
 	if debug {
 		// accumulate all line writers, wrap in same line writer (to re-use buffer)
-		stderrLogger := common.Logger(ctx).WithFields(logrus.Fields{"user_log": true, "app_id": c.AppID, "fn_id": c.FnID, "image": c.Image, "call_id": c.ID})
+		stderrLogger := common.Logger(ctx).WithFields(logrus.Fields{"user_log": true, "app_id": c.AppID, "fn_id": c.FnID, "image": c.Image, "call_id": c.ID, "namespace_id": c.NamespaceID})
 		loggo := &nopCloser{&logWriter{stderrLogger}}
 
 		// we don't need to limit the log writer(s), but we do need it to dispense lines
@@ -180,12 +182,28 @@ func (li *lineWriter) Close() error {
 type limitDiscardWriter struct {
 	n, max int
 	io.Writer
+
+	// optional, called once when the limit is reached
+	onTruncate func()
 }
 
 func newLimitWriter(max int, w io.Writer) io.Writer {
 	return &limitDiscardWriter{max: max, Writer: w}
 }
 
+// newCallLogWriter limits the output of a single call to max bytes, writing a
+// truncation marker into the stream and recording the truncation in stats.
+func newCallLogWriter(ctx context.Context, max uint64, w io.Writer) io.Writer {
+	if max == 0 {
+		return w
+	}
+	return &limitDiscardWriter{
+		max:        int(max),
+		Writer:     w,
+		onTruncate: func() { statsCallLogTruncated(ctx) },
+	}
+}
+
 func (l *limitDiscardWriter) Write(b []byte) (int, error) {
 	inpLen := len(b)
 	if l.n >= l.max {
@@ -203,6 +221,9 @@ func (l *limitDiscardWriter) Write(b []byte) (int, error) {
 	if l.n >= l.max {
 		// write in truncation message to log once
 		l.Writer.Write([]byte(fmt.Sprintf("\n-----max log size %d bytes exceeded, truncating log-----\n", l.max)))
+		if l.onTruncate != nil {
+			l.onTruncate()
+		}
 	} else if n != len(b) {
 		// Is this truly a partial write? We'll be honest if that's the case.
 		return n, err
@@ -212,3 +233,58 @@ func (l *limitDiscardWriter) Write(b []byte) (int, error) {
 	// that we were able to write the entire buffer.
 	return inpLen, err
 }
+
+// callsWriter writes the output of a container serving several calls at once
+// to the logs of its calls in flight, and its stats to their stats. The output
+// of such a container cannot be told apart by call, what it writes while
+// several calls are in flight is recorded in the log of each of them. Output
+// written between calls goes to idle.
+type callsWriter struct {
+	mu    sync.Mutex
+	idle  io.Writer
+	calls map[*attachedCall]struct{}
+}
+
+type attachedCall struct {
+	stderr io.Writer
+	stats  *drivers.Stats
+}
+
+func newCallsWriter() *callsWriter {
+	return &callsWriter{idle: ioutil.Discard, calls: make(map[*attachedCall]struct{})}
+}
+
+// attach records the output and stats of the container in stderr and stats
+// until the returned func is called
+func (w *callsWriter) attach(stderr io.Writer, stats *drivers.Stats) func() {
+	c := &attachedCall{stderr: stderr, stats: stats}
+	w.mu.Lock()
+	w.calls[c] = struct{}{}
+	w.mu.Unlock()
+	return func() {
+		w.mu.Lock()
+		delete(w.calls, c)
+		w.mu.Unlock()
+	}
+}
+
+func (w *callsWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.calls) == 0 {
+		return w.idle.Write(b)
+	}
+	// a call whose log fails does not fail the logs of the others
+	for c := range w.calls {
+		c.stderr.Write(b)
+	}
+	return len(b), nil
+}
+
+func (w *callsWriter) writeStat(stat drivers.Stat) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for c := range w.calls {
+		*c.stats = append(*c.stats, stat)
+	}
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.opencensus.io/stats/view"
+)
+
+func callLogsTruncated(t *testing.T) int64 {
+	rows, err := view.RetrieveData(callLogTruncatedMetricName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	for _, row := range rows {
+		n += row.Data.(*view.CountData).Value
+	}
+	return n
+}
+
+func TestCallLogWriterTruncates(t *testing.T) {
+	RegisterContainerViews(nil, []float64{1, 10, 100})
+	before := callLogsTruncated(t)
+
+	var buf bytes.Buffer
+	w := newCallLogWriter(context.Background(), 10, &buf)
+	for _, s := range []string{"12345", "67890abc", "def"} {
+		if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Expected writes past the limit to be dropped quietly, got %d %v", n, err)
+		}
+	}
+
+	expected := "1234567890\n-----max log size 10 bytes exceeded, truncating log-----\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected the log to be truncated after a marker, got %q", buf.String())
+	}
+	if n := callLogsTruncated(t) - before; n != 1 {
+		t.Fatalf("Expected the truncation to be recorded once, got %d", n)
+	}
+
+	// no limit
+	if w := newCallLogWriter(context.Background(), 0, &buf); w != &buf {
+		t.Fatal("Expected the log of a call not to be limited without a max size")
+	}
+}
@@ -341,16 +341,19 @@ func _CallResultStart_OneofSizer(msg proto.Message) (n int) {
 
 // Call has really finished, it might have completed or crashed
 type CallFinished struct {
-	Success              bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Details              string   `protobuf:"bytes,2,opt,name=details,proto3" json:"details,omitempty"`
-	ErrorCode            int32    `protobuf:"varint,3,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
-	ErrorStr             string   `protobuf:"bytes,4,opt,name=errorStr,proto3" json:"errorStr,omitempty"`
-	CreatedAt            string   `protobuf:"bytes,5,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
-	StartedAt            string   `protobuf:"bytes,6,opt,name=startedAt,proto3" json:"startedAt,omitempty"`
-	CompletedAt          string   `protobuf:"bytes,7,opt,name=completedAt,proto3" json:"completedAt,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Success              bool             `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Details              string           `protobuf:"bytes,2,opt,name=details,proto3" json:"details,omitempty"`
+	ErrorCode            int32            `protobuf:"varint,3,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
+	ErrorStr             string           `protobuf:"bytes,4,opt,name=errorStr,proto3" json:"errorStr,omitempty"`
+	CreatedAt            string           `protobuf:"bytes,5,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	StartedAt            string           `protobuf:"bytes,6,opt,name=startedAt,proto3" json:"startedAt,omitempty"`
+	CompletedAt          string           `protobuf:"bytes,7,opt,name=completedAt,proto3" json:"completedAt,omitempty"`
+	Timings              map[string]int64 `protobuf:"bytes,8,rep,name=timings,proto3" json:"timings,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	ImageDigest          string           `protobuf:"bytes,9,opt,name=imageDigest,proto3" json:"imageDigest,omitempty"`
+	Platform             string           `protobuf:"bytes,10,opt,name=platform,proto3" json:"platform,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *CallFinished) Reset()         { *m = CallFinished{} }
@@ -427,6 +430,27 @@ func (m *CallFinished) GetCompletedAt() string {
 	return ""
 }
 
+func (m *CallFinished) GetTimings() map[string]int64 {
+	if m != nil {
+		return m.Timings
+	}
+	return nil
+}
+
+func (m *CallFinished) GetImageDigest() string {
+	if m != nil {
+		return m.ImageDigest
+	}
+	return ""
+}
+
+func (m *CallFinished) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
 type ClientMsg struct {
 	// Types that are valid to be assigned to Body:
 	//	*ClientMsg_Try
@@ -890,6 +914,7 @@ func (m *RunnerStatus) GetKdumpsOnDisk() uint64 {
 func init() {
 	proto.RegisterType((*TryCall)(nil), "TryCall")
 	proto.RegisterMapType((map[string]string)(nil), "TryCall.ExtensionsEntry")
+	proto.RegisterMapType((map[string]int64)(nil), "CallFinished.TimingsEntry")
 	proto.RegisterType((*DataFrame)(nil), "DataFrame")
 	proto.RegisterType((*HttpHeader)(nil), "HttpHeader")
 	proto.RegisterType((*HttpRespMeta)(nil), "HttpRespMeta")
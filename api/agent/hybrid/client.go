@@ -23,10 +23,14 @@ import (
 
 // client implements agent.DataAccess
 type client struct {
-	base string
-	http *http.Client
+	base  string
+	token string
+	http  *http.Client
 }
 
+// NewClient returns a client of the hybrid API of the fn server at u, the
+// password of u, if any, is sent as bearer token to authenticate, eg.
+// http://:token@fn-api:8080
 func NewClient(u string) (agent.DataAccess, error) {
 	uri, err := url.Parse(u)
 	if err != nil {
@@ -60,9 +64,15 @@ func NewClient(u string) (agent.DataAccess, error) {
 		},
 	}
 
+	var token string
+	if uri.User != nil {
+		token, _ = uri.User.Password()
+	}
+
 	return &client{
-		base: host,
-		http: httpClient,
+		base:  host,
+		token: token,
+		http:  httpClient,
 	}, nil
 }
 
@@ -167,6 +177,27 @@ func (cl *client) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error
 	return &fn, nil
 }
 
+// GetFnRevision implements agent.FnRevisionReadAccess
+func (cl *client) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	ctx, span := trace.StartSpan(ctx, "hybrid_client_get_fn_revision")
+	defer span.End()
+
+	var rev models.FnRevision
+	err := cl.do(ctx, nil, &rev, "GET", noQuery, "fns", fnID, "revisions", revisionID)
+	if err, ok := err.(*httpErr); ok {
+		switch err.code {
+		case http.StatusNotFound:
+			return nil, models.ErrFnRevisionsNotFound
+		case http.StatusNotImplemented:
+			return nil, models.ErrFnRevisionsUnsupported
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
 type httpErr struct {
 	code int
 	error
@@ -190,7 +221,7 @@ func (cl *client) do(ctx context.Context, request, result interface{}, method st
 		case nil:
 			return nil
 		case *httpErr:
-			if err.code < 500 {
+			if err.code < 500 || err.code == http.StatusNotImplemented {
 				return err
 			}
 			// retry 500s...
@@ -223,6 +254,12 @@ func (cl *client) once(ctx context.Context, request, result interface{}, method
 	if err != nil {
 		return err
 	}
+	if cl.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.token)
+	}
+	if rid := common.RequestIDFromContext(ctx); rid != "" {
+		req.Header.Set(common.RequestIDHeader, rid)
+	}
 	// shove the span headers in so that the server will continue this span
 	var xxx b3.HTTPFormat
 	xxx.SpanContextToRequest(span.SpanContext(), req)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 
@@ -80,3 +81,9 @@ func newDirectoryIOFS(ctx context.Context, cfg *Config) (*directoryIOFS, error)
 }
 
 var _ iofs = &directoryIOFS{}
+
+// dialUDS connects to the unix socket containers listen on in the iofs dir
+func dialUDS(ctx context.Context, dir string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", filepath.Join(dir, udsFilename))
+}
@@ -26,6 +26,7 @@ type lbAgent struct {
 	placer        pool.Placer
 	callOverrider CallOverrider
 	shutWg        *common.WaitGroup
+	cancels       *callCancels
 }
 
 type DetachedResponseWriter struct {
@@ -92,11 +93,12 @@ func NewLBAgent(da CallHandler, rp pool.RunnerPool, p pool.Placer, options ...LB
 	}
 
 	a := &lbAgent{
-		cfg:    *cfg,
-		cda:    da,
-		rp:     rp,
-		placer: p,
-		shutWg: common.NewWaitGroup(),
+		cfg:     *cfg,
+		cda:     da,
+		rp:      rp,
+		placer:  p,
+		shutWg:  common.NewWaitGroup(),
+		cancels: newCallCancels(),
 	}
 
 	// Allow overriding config
@@ -152,7 +154,29 @@ func (a *lbAgent) GetCall(opts ...CallOpt) (Call, error) {
 		c.extensions = ext
 	}
 
-	setupCtx(&c)
+	// runners label the containers of calls with the lb group they were placed in
+	if group, err := models.LBGroupFromAnnotations(c.Annotations); err != nil {
+		return nil, err
+	} else if group != "" {
+		ext := make(map[string]string, len(c.extensions)+1)
+		for k, v := range c.extensions {
+			ext[k] = v
+		}
+		ext[LBGroupID] = group
+		c.extensions = ext
+	}
+
+	// runners are called over grpc, which cannot carry upgraded connections
+	if c.webSocket {
+		return nil, models.ErrWebSocketUnsupported
+	}
+
+	// cut bodies over the limit of the fn off before buffering them for placement
+	if err := limitRequestBody(&c); err != nil {
+		return nil, err
+	}
+
+	setupCtx(&c, common.LogComponentLB)
 
 	c.handler = a.cda
 	c.ct = a
@@ -192,6 +216,9 @@ func (a *lbAgent) Submit(callI Call) error {
 	}
 	defer a.shutWg.DoneSession()
 
+	ctx, untrack := a.cancels.track(ctx, call)
+	defer untrack()
+
 	statsEnqueue(ctx)
 
 	// pre-read and buffer request body if already not done based
@@ -208,6 +235,7 @@ func (a *lbAgent) Submit(callI Call) error {
 	if err != nil {
 		return a.handleCallEnd(ctx, call, err, false)
 	}
+	call.addTiming(ctx, models.PhaseQueueWait, time.Since(time.Time(call.CreatedAt)))
 
 	statsDequeue(ctx)
 	statsStartRun(ctx)
@@ -231,10 +259,20 @@ func (a *lbAgent) placeDetachCall(ctx context.Context, call *call) error {
 }
 
 func (a *lbAgent) placeCall(ctx context.Context, call *call) error {
-	err := a.placer.PlaceCall(ctx, a.rp, call)
+	err := a.place(ctx, call)
 	return a.handleCallEnd(ctx, call, err, true)
 }
 
+// place places the call on a runner. The time placing it took, less the time
+// the runners report the call spent with them, is the time it waited for a
+// runner.
+func (a *lbAgent) place(ctx context.Context, call *call) error {
+	start := time.Now()
+	err := a.placer.PlaceCall(ctx, a.rp, call)
+	call.addTiming(ctx, models.PhasePlacementWait, time.Since(start)-call.getRunnerTime())
+	return err
+}
+
 func (a *lbAgent) spawnPlaceCall(ctx context.Context, call *call, errCh chan error) {
 	var cancel func()
 	ctx = common.BackgroundContext(ctx)
@@ -244,8 +282,10 @@ func (a *lbAgent) spawnPlaceCall(ctx context.Context, call *call, errCh chan err
 	newCtxTimeout := cfg.DetachedPlacerTimeout + time.Duration(call.Timeout)*time.Second + a.cfg.DetachedHeadRoom
 	ctx, cancel = context.WithTimeout(ctx, newCtxTimeout)
 	defer cancel()
+	ctx, untrack := a.cancels.track(ctx, call)
+	defer untrack()
 
-	err := a.placer.PlaceCall(ctx, a.rp, call)
+	err := a.place(ctx, call)
 	errCh <- a.handleCallEnd(ctx, call, err, true)
 }
 
@@ -298,6 +338,7 @@ func (a *lbAgent) Enqueue(context.Context, *models.Call) error {
 }
 
 func (a *lbAgent) handleCallEnd(ctx context.Context, call *call, err error, isForwarded bool) error {
+	ctx, err = cancelledError(ctx, call, err)
 	if isForwarded {
 		call.End(ctx, err)
 		statsStopRun(ctx)
@@ -330,7 +371,7 @@ func (a *lbAgent) handleCallEnd(ctx context.Context, call *call, err error, isFo
 		statsTooBusy(ctx)
 		recordCallLatency(ctx, call, serverBusyMetricName)
 		return models.ErrCallTimeoutServerBusy
-	} else if err == context.Canceled {
+	} else if err == context.Canceled || err == models.ErrCallCancelled {
 		statsCanceled(ctx)
 		recordCallLatency(ctx, call, canceledMetricName)
 	} else if err != nil {
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/fnproject/fn/api/chaos"
+	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
 	pool "github.com/fnproject/fn/api/runnerpool"
 )
@@ -23,6 +26,7 @@ type mockRunner struct {
 	curCalls  int32 // Current calls
 	procCalls int32 // Processed calls
 	addr      string
+	reject    *pool.RunnerRejection
 }
 
 type mockRunnerPool struct {
@@ -93,6 +97,9 @@ func (r *mockRunner) Status(ctx context.Context) (*pool.RunnerStatus, error) {
 }
 
 func (r *mockRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, error) {
+	if r.reject != nil {
+		return false, r.reject
+	}
 	err := r.checkAndIncrCalls()
 	if err != nil {
 		return false, err
@@ -104,10 +111,19 @@ func (r *mockRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 
 	time.Sleep(r.sleep)
 
+	r.mtx.Lock()
 	r.procCalls++
+	r.mtx.Unlock()
 	return true, nil
 }
 
+// processed returns the number of calls the runner processed
+func (r *mockRunner) processed() int32 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.procCalls
+}
+
 func (r *mockRunner) Close(context.Context) error {
 	go func() {
 		r.wg.Wait()
@@ -165,6 +181,16 @@ func (c *mockRunnerCall) GetUserExecutionTime() *time.Duration {
 	return c.userExecTime
 }
 
+func (c *mockRunnerCall) SetResolvedImage(digest, platform string) {
+	c.model.ImageDigest, c.model.Platform = digest, platform
+}
+
+func (c *mockRunnerCall) AddTimings(timings models.CallTimings) {
+	for phase, ms := range timings {
+		c.model.Timings.Add(phase, time.Duration(ms)*time.Millisecond)
+	}
+}
+
 func setupMockRunnerPool(expectedRunners []string, execSleep time.Duration, maxCalls int32) *mockRunnerPool {
 	rf := NewMockRunnerFactory(execSleep, maxCalls)
 	return newMockRunnerPool(rf, expectedRunners)
@@ -184,6 +210,485 @@ func TestOneRunner(t *testing.T) {
 	}
 }
 
+func TestPlacementAudit(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	cfg.AuditLog = pool.NewPlacementAuditLog(1)
+	placer := pool.NewNaivePlacer(&cfg)
+	rp := setupMockRunnerPool([]string{"171.19.0.1"}, 10*time.Millisecond, 5)
+
+	modelCall := &models.Call{ID: "call1", Type: models.TypeSync}
+	call := &mockRunnerCall{model: modelCall}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(1*time.Second))
+	defer cancel()
+	if err := placer.PlaceCall(ctx, rp, call); err != nil {
+		t.Fatalf("Failed to place call on runner %v", err)
+	}
+
+	audit, ok := cfg.AuditLog.Get("call1")
+	if !ok {
+		t.Fatal("Expected a placement audit for the call")
+	}
+	if !audit.Placed || len(audit.Attempts) != 1 || audit.Attempts[0].Runner != "171.19.0.1" {
+		t.Fatalf("Unexpected placement audit %+v", audit)
+	}
+
+	// the audit log only holds the most recent call
+	modelCall = &models.Call{ID: "call2", Type: models.TypeSync}
+	call = &mockRunnerCall{model: modelCall}
+	if err := placer.PlaceCall(ctx, rp, call); err != nil {
+		t.Fatalf("Failed to place call on runner %v", err)
+	}
+	if _, ok := cfg.AuditLog.Get("call1"); ok {
+		t.Fatal("Expected the oldest placement audit to be evicted")
+	}
+}
+
+func TestPoolStatus(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	placer := pool.NewNaivePlacer(&cfg)
+	breakerCfg := pool.NewCircuitBreakerConfig()
+	rp := pool.NewCircuitBreakerPool(setupMockRunnerPool([]string{"171.19.3.1", "171.19.3.2"}, 10*time.Millisecond, 5), &breakerCfg)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(1*time.Second))
+	defer cancel()
+	for i := 0; i < 4; i++ {
+		call := &mockRunnerCall{model: &models.Call{Type: models.TypeSync}}
+		if err := placer.PlaceCall(ctx, rp, call); err != nil {
+			t.Fatalf("Failed to place call on runner %v", err)
+		}
+	}
+
+	status, err := pool.Status(ctx, rp)
+	if err != nil {
+		t.Fatalf("Failed to get pool status %v", err)
+	}
+	if len(status.Runners) != 2 || status.Requests != 4 {
+		t.Fatalf("Unexpected pool status %+v", status)
+	}
+	for _, r := range status.Runners {
+		if r.Health != pool.RunnerHealthy || r.Requests[pool.RunnerResultPlaced] != 2 || r.Share != 0.5 {
+			t.Fatalf("Unexpected runner state %+v", r)
+		}
+	}
+}
+
+func TestChaosPool(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	placer := pool.NewNaivePlacer(&cfg)
+	faults := chaos.New(&chaos.Config{RunnerRejectRate: 0.5, Seed: 1})
+	rp := pool.NewChaosPool(setupMockRunnerPool([]string{"171.19.5.1", "171.19.5.2"}, 10*time.Millisecond, 5), faults)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		call := &mockRunnerCall{model: &models.Call{Type: models.TypeSync}}
+		if err := placer.PlaceCall(ctx, rp, call); err != nil {
+			t.Fatalf("Expected calls rejected by a runner to be placed on another, got %v", err)
+		}
+	}
+	if faults.Injected()[chaos.FaultReject] == 0 {
+		t.Fatal("Expected runners to reject calls")
+	}
+	if pool.BasePool(rp) == rp {
+		t.Fatal("Expected the chaos pool to wrap the base pool")
+	}
+
+	// zone placers still see the zones of the runners
+	zoned := pool.NewChaosPool(&mockRunnerPool{runners: []pool.Runner{pool.WithZone(&mockRunner{addr: "171.19.5.3"}, "zone1")}}, faults)
+	runners, err := zoned.Runners(ctx, &mockRunnerCall{model: &models.Call{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runners) != 1 || pool.RunnerZone(runners[0]) != "zone1" {
+		t.Fatalf("Expected the zone of the runner to be kept, got %v", runners)
+	}
+}
+
+func TestLBGroupPool(t *testing.T) {
+	labels := map[string]map[string]string{
+		"171.19.4.1": {"tier": "gpu"},
+		"171.19.4.2": {"tier": "gpu"},
+		"171.19.4.3": {"tier": "batch"},
+	}
+	groups := pool.NewLBGroups()
+	rp := pool.NewLBGroupPool(setupMockRunnerPool([]string{"171.19.4.1", "171.19.4.2", "171.19.4.3"}, 10*time.Millisecond, 5), groups,
+		func(addr string) map[string]string { return labels[addr] })
+
+	addresses := func(group string) []string {
+		call := &mockRunnerCall{model: &models.Call{Annotations: models.Annotations{}}}
+		if group != "" {
+			call.model.Annotations, _ = call.model.Annotations.With(models.AppLBGroupAnnotation, group)
+		}
+		runners, err := rp.Runners(context.Background(), call)
+		if err != nil {
+			t.Fatalf("Failed to list runners of group %q %v", group, err)
+		}
+		var res []string
+		for _, r := range runners {
+			res = append(res, r.Address())
+		}
+		return res
+	}
+
+	if addrs := addresses(""); len(addrs) != 3 {
+		t.Fatalf("Expected calls without group on every runner, got %v", addrs)
+	}
+
+	if err := groups.Put(pool.LBGroup{ID: "gpu", Selector: map[string]string{"tier": "gpu"}, MaxRunners: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if addrs := addresses("gpu"); len(addrs) != 1 || addrs[0] != "171.19.4.1" {
+		t.Fatalf("Unexpected runners of group gpu %v", addrs)
+	}
+
+	// too few runners spill over to the whole pool
+	groups.Put(pool.LBGroup{ID: "gpu", Selector: map[string]string{"tier": "gpu"}, MinRunners: 3})
+	if addrs := addresses("gpu"); len(addrs) != 3 {
+		t.Fatalf("Expected group gpu to spill over, got %v", addrs)
+	}
+
+	members, _, err := pool.LBGroupMembers(context.Background(), rp, "gpu")
+	if err != nil || len(members) != 2 {
+		t.Fatalf("Unexpected members of group gpu %v %v", members, err)
+	}
+
+	call := &mockRunnerCall{model: &models.Call{}}
+	call.model.Annotations, _ = models.Annotations{}.With(models.AppLBGroupAnnotation, "missing")
+	if _, err := rp.Runners(context.Background(), call); err != pool.ErrLBGroupNotFound {
+		t.Fatalf("Expected an unknown group to fail, got %v", err)
+	}
+	if err := groups.Put(pool.LBGroup{ID: "bad", MinRunners: 2, MaxRunners: 1}); err != pool.ErrInvalidLBGroup {
+		t.Fatalf("Expected invalid capacity bounds to fail, got %v", err)
+	}
+}
+
+func TestLBGroupCanary(t *testing.T) {
+	labels := map[string]map[string]string{
+		"171.19.5.1": {"image": "blue"},
+		"171.19.5.2": {"image": "green"},
+	}
+	groups := pool.NewLBGroups()
+	rp := pool.NewLBGroupPool(setupMockRunnerPool([]string{"171.19.5.1", "171.19.5.2"}, 10*time.Millisecond, 5), groups,
+		func(addr string) map[string]string { return labels[addr] })
+	groups.Put(pool.LBGroup{ID: "web"})
+
+	placed := func() map[string]int {
+		res := make(map[string]int)
+		for i := 0; i < 1000; i++ {
+			call := &mockRunnerCall{model: &models.Call{ID: id.New().String()}}
+			call.model.Annotations, _ = models.Annotations{}.With(models.AppLBGroupAnnotation, "web")
+			runners, err := rp.Runners(context.Background(), call)
+			if err != nil || len(runners) != 1 {
+				t.Fatalf("Unexpected runners %v %v", runners, err)
+			}
+			res[runners[0].Address()]++
+		}
+		return res
+	}
+
+	if _, err := groups.SetCanary("web", &pool.LBCanary{Selector: map[string]string{"image": "green"}, Weight: 101}); err != pool.ErrInvalidLBGroup {
+		t.Fatalf("Expected an invalid weight to fail, got %v", err)
+	}
+	if _, err := groups.SetCanary("web", &pool.LBCanary{Selector: map[string]string{"image": "green"}, Weight: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if n := placed()["171.19.5.2"]; n == 0 || n > 100 {
+		t.Fatalf("Expected about 5%% of calls on the canary, got %d of 1000", n)
+	}
+
+	groups.SetCanary("web", &pool.LBCanary{Selector: map[string]string{"image": "green"}, Weight: 100})
+	if n := placed()["171.19.5.2"]; n != 1000 {
+		t.Fatalf("Expected every call on the canary, got %d of 1000", n)
+	}
+}
+
+func TestNodeSelectorPool(t *testing.T) {
+	labels := map[string]map[string]string{
+		"171.19.6.1": {"arch": "amd64"},
+		"171.19.6.2": {"arch": "arm64", "gpu": "true"},
+	}
+	rp := pool.NewNodeSelectorPool(setupMockRunnerPool([]string{"171.19.6.1", "171.19.6.2"}, 10*time.Millisecond, 5),
+		func(addr string) map[string]string { return labels[addr] })
+
+	runners := func(selector map[string]string) ([]pool.Runner, error) {
+		call := &mockRunnerCall{model: &models.Call{Annotations: models.Annotations{}}}
+		if selector != nil {
+			call.model.Annotations, _ = call.model.Annotations.With(models.FnNodeSelectorAnnotation, selector)
+		}
+		return rp.Runners(context.Background(), call)
+	}
+
+	if rs, err := runners(nil); err != nil || len(rs) != 2 {
+		t.Fatalf("Expected calls without node selector on every runner, got %v %v", rs, err)
+	}
+	if rs, err := runners(map[string]string{"arch": "arm64", "gpu": "true"}); err != nil || len(rs) != 1 || rs[0].Address() != "171.19.6.2" {
+		t.Fatalf("Unexpected runners of arm64 gpu calls %v %v", rs, err)
+	}
+	if _, err := runners(map[string]string{"arch": "s390x"}); err != pool.ErrNoCompatibleRunners {
+		t.Fatalf("Expected calls no runner is compatible with to fail, got %v", err)
+	}
+}
+
+func TestAntiAffinityPool(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	placer := pool.NewCHPlacer(&cfg)
+	mp := setupMockRunnerPool([]string{"171.19.7.1", "171.19.7.2", "171.19.7.3"}, 200*time.Millisecond, 5)
+	rp := pool.NewAntiAffinityPool(mp)
+
+	annotations, _ := models.Annotations{}.With(models.FnAntiAffinityAnnotation, models.AntiAffinity{MaxPerRunner: 1})
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer cancel()
+
+	// calls of a fn all hash to the same runner, the anti-affinity spreads
+	// them over the three runners
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			call := &mockRunnerCall{slotHashId: "fn", model: &models.Call{Type: models.TypeSync, FnID: "noisy", Annotations: annotations}}
+			if err := placer.PlaceCall(ctx, rp, call); err != nil {
+				t.Errorf("Failed to place call %v", err)
+			}
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+	wg.Wait()
+
+	for _, r := range mp.runners {
+		if n := r.(*mockRunner).processed(); n != 1 {
+			t.Fatalf("Expected one call on runner %s, got %d", r.Address(), n)
+		}
+	}
+}
+
+// scaledPool is a runner pool whose runners are started during a test
+type scaledPool struct {
+	lock    sync.Mutex
+	runners []pool.Runner
+}
+
+func (p *scaledPool) Runners(ctx context.Context, call pool.RunnerCall) ([]pool.Runner, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.runners, nil
+}
+
+func (p *scaledPool) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestActivatorPool(t *testing.T) {
+	sp := &scaledPool{}
+	cfg := pool.NewActivatorConfig()
+	cfg.MaxQueued = 1
+	cfg.Poll = 10 * time.Millisecond
+	rp := pool.NewActivatorPool(sp, &cfg)
+
+	call := &mockRunnerCall{model: &models.Call{Type: models.TypeSync}}
+	queued := make(chan []pool.Runner, 1)
+	go func() {
+		runners, _ := rp.Runners(context.Background(), call)
+		queued <- runners
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := rp.Runners(context.Background(), call); err != pool.ErrActivatorQueueFull {
+		t.Fatalf("Expected calls over the queue size to fail, got %v", err)
+	}
+
+	runner, _ := NewMockRunnerFactory(0, 1)("171.19.8.1", nil)
+	sp.lock.Lock()
+	sp.runners = []pool.Runner{runner}
+	sp.lock.Unlock()
+
+	select {
+	case runners := <-queued:
+		if len(runners) != 1 {
+			t.Fatalf("Expected the queued call to get the started runner, got %v", runners)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Queued call was not released once a runner started")
+	}
+}
+
+func TestRejectedCallFailsFast(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	placer := pool.NewNaivePlacer(&cfg)
+	rp := setupMockRunnerPool([]string{"171.19.0.1", "171.19.0.2"}, 10*time.Millisecond, 5)
+	for _, r := range rp.runners {
+		r.(*mockRunner).reject = &pool.RunnerRejection{Reason: pool.RejectOverMemory, Err: models.ErrCallResourceTooBig}
+	}
+
+	modelCall := &models.Call{Type: models.TypeSync}
+	call := &mockRunnerCall{model: modelCall}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer cancel()
+
+	start := time.Now()
+	err := placer.PlaceCall(ctx, rp, call)
+	if err != models.ErrCallResourceTooBig {
+		t.Fatalf("Expected %v got %v", models.ErrCallResourceTooBig, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("Placer should not retry runners that rejected the call for good")
+	}
+
+	// a draining runner is skipped in favor of the other one
+	rp.runners[0].(*mockRunner).reject = &pool.RunnerRejection{Reason: pool.RejectDraining, Err: models.ErrCallTimeoutServerBusy}
+	rp.runners[1].(*mockRunner).reject = nil
+	if err := placer.PlaceCall(ctx, rp, call); err != nil {
+		t.Fatalf("Failed to place call on runner %v", err)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	cfg.MaxFailovers = 1
+	placer := pool.NewNaivePlacer(&cfg)
+	rp := setupMockRunnerPool([]string{"171.19.0.1", "171.19.0.2"}, 10*time.Millisecond, 5)
+	runnerErr := errors.New("runner went away")
+	rp.runners[0].(*mockRunner).reject = &pool.RunnerRejection{Reason: pool.RejectFailed, Err: runnerErr}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer cancel()
+
+	// the placer starts on either runner, two calls hit the failing one first at least once
+	failovers := 0
+	for i := 0; i < 2; i++ {
+		rw := httptest.NewRecorder()
+		call := &mockRunnerCall{model: &models.Call{Type: models.TypeSync, Method: "GET"}, rw: rw}
+		if err := placer.PlaceCall(ctx, rp, call); err != nil {
+			t.Fatalf("Failed to fail over call %v", err)
+		}
+		if rw.Header().Get(pool.FailoverHeader) == "1" {
+			failovers++
+		}
+	}
+	if failovers == 0 || rp.runners[1].(*mockRunner).processed() != 2 {
+		t.Fatalf("Expected a call to fail over to the second runner, got %d failovers", failovers)
+	}
+
+	// out of failovers, the failure of the runner is the outcome of the call
+	rp.runners[1].(*mockRunner).reject = rp.runners[0].(*mockRunner).reject
+	call := &mockRunnerCall{model: &models.Call{Type: models.TypeSync, Method: "GET"}, rw: httptest.NewRecorder()}
+	if err := placer.PlaceCall(ctx, rp, call); err != runnerErr {
+		t.Fatalf("Expected %v got %v", runnerErr, err)
+	}
+
+	if !pool.IsIdempotent(&models.Call{Method: "HEAD"}) || !pool.IsIdempotent(&models.Call{Method: "POST", IdempotencyKey: "k"}) {
+		t.Fatal("Expected HEAD calls and calls with an idempotency key to be idempotent")
+	}
+	if pool.IsIdempotent(&models.Call{Method: "POST"}) {
+		t.Fatal("Expected POST calls not to be idempotent")
+	}
+}
+
+func TestZonePlacerSpill(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	placer := pool.NewZonePlacer(&cfg, "zone1", 50*time.Millisecond)
+	local := &mockRunner{addr: "171.19.0.1", maxCalls: 0}
+	remote := &mockRunner{addr: "171.19.0.2", maxCalls: 5}
+	rp := &mockRunnerPool{runners: []pool.Runner{pool.WithZone(local, "zone1"), pool.WithZone(remote, "zone2")}}
+
+	modelCall := &models.Call{Type: models.TypeSync}
+	call := &mockRunnerCall{model: modelCall}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(1*time.Second))
+	defer cancel()
+
+	start := time.Now()
+	if err := placer.PlaceCall(ctx, rp, call); err != nil {
+		t.Fatalf("Failed to place call on runner %v", err)
+	}
+	if remote.processed() != 1 {
+		t.Fatal("Expected the call to spill to the other zone")
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Fatal("Expected the call to wait for a runner of its zone first")
+	}
+
+	// runners of the zone are preferred when they have capacity
+	local.maxCalls = 5
+	if err := placer.PlaceCall(ctx, rp, call); err != nil {
+		t.Fatalf("Failed to place call on runner %v", err)
+	}
+	if local.processed() != 1 || remote.processed() != 1 {
+		t.Fatal("Expected the call to be placed in its zone")
+	}
+}
+
+// placeInFlight places calls on rp one after the other, each once the
+// previous one is in flight, and returns the calls in flight on each runner
+// once all are placed
+func placeInFlight(t *testing.T, placer pool.Placer, rp *mockRunnerPool, calls int) []int32 {
+	inflight := func() []int32 {
+		res := make([]int32, len(rp.runners))
+		for i, r := range rp.runners {
+			m := r.(*mockRunner)
+			m.mtx.Lock()
+			res[i] = m.curCalls
+			m.mtx.Unlock()
+		}
+		return res
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+			defer cancel()
+			if err := placer.PlaceCall(ctx, rp, &mockRunnerCall{model: &models.Call{Type: models.TypeSync}}); err != nil {
+				t.Errorf("Failed to place call on runner %v", err)
+			}
+		}()
+
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			var n int32
+			for _, c := range inflight() {
+				n += c
+			}
+			if n == int32(i+1) {
+				break
+			}
+		}
+	}
+	return inflight()
+}
+
+func TestSpreadPlacer(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	placer := pool.NewSpreadPlacer(&cfg)
+	rp := setupMockRunnerPool([]string{"171.19.6.1", "171.19.6.2", "171.19.6.3"}, 200*time.Millisecond, 5)
+
+	if inflight := placeInFlight(t, placer, rp, 5); fmt.Sprint(inflight) != "[2 2 1]" {
+		t.Fatalf("Expected calls to be spread over the runners, got %v in flight", inflight)
+	}
+}
+
+func TestBinPackPlacer(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	placer := pool.NewBinPackPlacer(&cfg, 2)
+	rp := setupMockRunnerPool([]string{"171.19.7.1", "171.19.7.2", "171.19.7.3"}, 200*time.Millisecond, 5)
+
+	if inflight := placeInFlight(t, placer, rp, 3); fmt.Sprint(inflight) != "[2 1 0]" {
+		t.Fatalf("Expected calls to be packed up to the target, got %v in flight", inflight)
+	}
+}
+
+func TestBinPackPlacerFull(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	placer := pool.NewBinPackPlacer(&cfg, 1)
+	rp := setupMockRunnerPool([]string{"171.19.8.1", "171.19.8.2"}, 200*time.Millisecond, 5)
+
+	// once all runners reached the target, calls are spread
+	if inflight := placeInFlight(t, placer, rp, 4); fmt.Sprint(inflight) != "[2 2]" {
+		t.Fatalf("Expected calls past the target to be spread, got %v in flight", inflight)
+	}
+}
+
 func TestEnforceTimeoutFromContext(t *testing.T) {
 	cfg := pool.NewPlacerConfig()
 	placer := pool.NewNaivePlacer(&cfg)
@@ -250,7 +755,7 @@ func TestRRRunner(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Expected no error %s", err.Error())
 	}
-	if rp.runners[1].(*mockRunner).procCalls != 1 && rp.runners[0].(*mockRunner).procCalls != 1 {
+	if rp.runners[1].(*mockRunner).processed() != 1 && rp.runners[0].(*mockRunner).processed() != 1 {
 		t.Fatal("Expected rr runner")
 	}
 }
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// leasedDataAccess completes the async calls of a queue leasing its calls
+// exactly once. Calls are deleted from the queue only after they were stored,
+// and their lease is renewed while they run. The calls of a server that dies
+// go back to the queue once their lease expires, instead of being lost, and
+// calls found stored when they are started again are deleted instead of
+// being run again.
+type leasedDataAccess struct {
+	*directDataAccess
+	ttl time.Duration
+
+	lock   sync.Mutex
+	leases map[string]context.CancelFunc
+}
+
+// NewLeasedCallDataAccess returns a CallHandler completing the async calls of
+// mq exactly once, which must lease its calls, see models.CallLeaser. Calls
+// are only completed exactly once across restarts with a durable queue such
+// as bolt, the memory queue loses its calls and leases. The leases of running
+// calls are renewed to ttl every third of ttl. Log stores implementing
+// models.CallCompleter store calls with their log in one transaction.
+func NewLeasedCallDataAccess(ls models.LogStore, mq models.MessageQueue, ttl time.Duration) (CallHandler, error) {
+	if !models.LeasesCalls(mq) {
+		return nil, errors.New("exactly-once async calls require a message queue leasing its calls")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("the lease of async calls must be positive")
+	}
+	return &leasedDataAccess{
+		directDataAccess: &directDataAccess{mq: mq, ls: ls},
+		ttl:              ttl,
+		leases:           make(map[string]context.CancelFunc),
+	}, nil
+}
+
+func (da *leasedDataAccess) Start(ctx context.Context, mCall *models.Call) error {
+	if mCall.LeaseID == "" {
+		return da.directDataAccess.Start(ctx, mCall)
+	}
+
+	// calls are stored once they completed, or if they were cancelled while
+	// they were queued. Calls completed by a server which died before it
+	// deleted them are queued again, they are deleted now.
+	stored, err := da.ls.GetCall(ctx, mCall.FnID, mCall.ID)
+	if err != nil && err != models.ErrCallNotFound {
+		return err
+	}
+	if err == nil {
+		if derr := da.mq.Delete(ctx, mCall); derr != nil {
+			common.Logger(ctx).WithError(derr).Error("error deleting stored call from the queue")
+		}
+		if stored.Status == "cancelled" {
+			return models.ErrCallCancelled
+		}
+		return models.ErrCallAlreadyCompleted
+	}
+
+	leaser := da.mq.(models.CallLeaser)
+	if err := leaser.RenewLease(ctx, mCall, da.ttl); err != nil {
+		return err
+	}
+
+	leaseCtx, cancel := context.WithCancel(common.BackgroundContext(ctx))
+	da.lock.Lock()
+	da.leases[mCall.ID] = cancel
+	da.lock.Unlock()
+
+	go da.keepLease(leaseCtx, leaser, mCall)
+	return nil
+}
+
+// keepLease renews the lease of mCall until ctx is done
+func (da *leasedDataAccess) keepLease(ctx context.Context, leaser models.CallLeaser, mCall *models.Call) {
+	ticker := time.NewTicker(da.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := leaser.RenewLease(ctx, mCall, da.ttl); err != nil {
+			if ctx.Err() == nil {
+				common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"call_id": mCall.ID}).Warn(
+					"lost the lease of a running async call, it may run again")
+			}
+			return
+		}
+	}
+}
+
+// release stops renewing the lease of mCall
+func (da *leasedDataAccess) release(mCall *models.Call) {
+	da.lock.Lock()
+	cancel, ok := da.leases[mCall.ID]
+	delete(da.leases, mCall.ID)
+	da.lock.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (da *leasedDataAccess) Finish(ctx context.Context, mCall *models.Call, stderr io.Reader, async bool) error {
+	if !async || mCall.LeaseID == "" {
+		return da.directDataAccess.Finish(ctx, mCall, stderr, async)
+	}
+	defer da.release(mCall)
+
+	// calls which fail to be stored are not deleted, they run again once
+	// their lease expired
+	if err := da.complete(ctx, mCall, stderr); err != nil {
+		return err
+	}
+	return da.mq.Delete(ctx, mCall)
+}
+
+// complete stores mCall with its log, or returns models.ErrCallAlreadyCompleted
+func (da *leasedDataAccess) complete(ctx context.Context, mCall *models.Call, stderr io.Reader) error {
+	if cc, ok := da.ls.(models.CallCompleter); ok {
+		err := cc.CompleteCall(ctx, mCall, stderr)
+		if err != models.ErrCallCompletionUnsupported {
+			return err
+		}
+	}
+
+	// the call is stored before its log, once it is stored it is not run again
+	if err := da.ls.InsertCall(ctx, mCall); err != nil {
+		return err
+	}
+	if err := da.ls.InsertLog(ctx, mCall, stderr); err != nil {
+		common.Logger(ctx).WithError(err).Error("error uploading log")
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	_ "github.com/fnproject/fn/api/mqs/memory"
+)
+
+func TestLeasedCallDataAccess(t *testing.T) {
+	ctx := context.Background()
+	mq, err := mqs.New("memory://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mq.Close()
+	ls := logs.NewMock()
+
+	if _, err := NewLeasedCallDataAccess(ls, new(mqs.Mock), time.Second); err == nil {
+		t.Fatal("expected queues which do not lease calls to be refused")
+	}
+	da, err := NewLeasedCallDataAccess(ls, mq, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	priority := int32(0)
+	queued := &models.Call{ID: id.New().String(), FnID: "fn", AppID: "app", Type: models.TypeAsync, Priority: &priority}
+	if _, err := mq.Push(ctx, queued); err != nil {
+		t.Fatal(err)
+	}
+	mCall, err := mq.Reserve(ctx)
+	if err != nil || mCall == nil || mCall.LeaseID == "" {
+		t.Fatalf("expected a leased call, got %+v %v", mCall, err)
+	}
+	if err := da.Start(ctx, mCall); err != nil {
+		t.Fatal(err)
+	}
+	mCall.Status = "success"
+	if err := da.Finish(ctx, mCall, strings.NewReader("log"), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ls.GetCall(ctx, mCall.FnID, mCall.ID); err != nil {
+		t.Fatalf("expected the call to be stored, got %v", err)
+	}
+	if err := mq.Delete(ctx, mCall); err != models.ErrCallLeaseExpired {
+		t.Fatalf("expected the call to be deleted from the queue, got %v", err)
+	}
+
+	// a call completed by a server which died before deleting it is delivered again
+	if _, err := mq.Push(ctx, queued); err != nil {
+		t.Fatal(err)
+	}
+	again, err := mq.Reserve(ctx)
+	if err != nil || again == nil {
+		t.Fatalf("expected the call to be queued again, got %v", err)
+	}
+	if err := da.Start(ctx, again); err != models.ErrCallAlreadyCompleted {
+		t.Fatalf("expected the completed call not to run again, got %v", err)
+	}
+	if err := mq.Delete(ctx, again); err != models.ErrCallLeaseExpired {
+		t.Fatalf("expected the completed call to be deleted from the queue, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"io"
+	"sync/atomic"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// limitedBody fails reads of a request body with models.ErrRequestContentTooBig
+// once it is over a limit, as the body streams rather than after buffering it
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  uint32
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// tell bodies of exactly the limit from larger ones
+		var one [1]byte
+		n, err := b.ReadCloser.Read(one[:])
+		if n > 0 {
+			atomic.StoreUint32(&b.exceeded, 1)
+			return 0, models.ErrRequestContentTooBig
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// tooBig returns whether the body was cut off for being over its limit
+func (b *limitedBody) tooBig() bool {
+	return b != nil && atomic.LoadUint32(&b.exceeded) == 1
+}
+
+// limitRequestBody applies the request size limit of the fn of c to its body,
+// failing right away for requests declaring a larger content length
+func limitRequestBody(c *call) error {
+	maxRequest, _, _ := models.SizeLimitsFromAnnotations(c.Annotations)
+	if maxRequest == 0 || c.req.Body == nil || c.reqBody != nil {
+		return nil
+	}
+	if c.req.ContentLength > int64(maxRequest) {
+		return models.ErrRequestContentTooBig
+	}
+	c.reqBody = &limitedBody{ReadCloser: c.req.Body, remaining: int64(maxRequest)}
+	c.req.Body = c.reqBody
+	return nil
+}
+
+// maxResponseSize returns the smaller of the response size limit of the
+// server and of the fn of c, 0 if neither is limited
+func maxResponseSize(cfg *Config, c *call) uint64 {
+	_, max, _ := models.SizeLimitsFromAnnotations(c.Annotations)
+	if max == 0 || (cfg.MaxResponseSize != 0 && cfg.MaxResponseSize < max) {
+		return cfg.MaxResponseSize
+	}
+	return max
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestLimitRequestBody(t *testing.T) {
+	annotations, _ := models.EmptyAnnotations().With(models.FnMaxRequestSizeAnnotation, 5)
+
+	for _, tc := range []struct {
+		body   string
+		length int64
+		err    error
+	}{
+		{"hello", -1, nil},
+		{"hi", -1, nil},
+		{"hello!", -1, models.ErrRequestContentTooBig},
+		{"hello!", 6, models.ErrRequestContentTooBig},
+	} {
+		req := httptest.NewRequest("POST", "/invoke/fn", strings.NewReader(tc.body))
+		req.ContentLength = tc.length
+		c := &call{Call: &models.Call{Annotations: annotations}, req: req}
+
+		err := limitRequestBody(c)
+		if err == nil {
+			_, err = ioutil.ReadAll(c.req.Body)
+		}
+		if err != tc.err {
+			t.Errorf("Expected body %q to return %v, got %v", tc.body, tc.err, err)
+		}
+		if (tc.err != nil && tc.length < 0) != c.reqBody.tooBig() {
+			t.Errorf("Expected body %q to be cut off: %v", tc.body, tc.err != nil)
+		}
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	annotations, _ := models.EmptyAnnotations().With(models.FnMaxResponseSizeAnnotation, 100)
+	limited := &call{Call: &models.Call{Annotations: annotations}}
+	unlimited := &call{Call: &models.Call{}}
+
+	for _, tc := range []struct {
+		server uint64
+		c      *call
+		max    uint64
+	}{
+		{0, unlimited, 0},
+		{0, limited, 100},
+		{50, limited, 50},
+		{200, limited, 100},
+		{200, unlimited, 200},
+	} {
+		if max := maxResponseSize(&Config{MaxResponseSize: tc.server}, tc.c); max != tc.max {
+			t.Errorf("Expected a limit of %d with a server limit of %d, got %d", tc.max, tc.server, max)
+		}
+	}
+}
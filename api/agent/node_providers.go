@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pool "github.com/fnproject/fn/api/runnerpool"
+)
+
+// staticNodeProvider always returns the same list of runners
+type staticNodeProvider struct {
+	addresses []string
+}
+
+// NewStaticNodeProvider returns a node provider for a fixed list of runner addresses
+func NewStaticNodeProvider(addresses []string) pool.NodeProvider {
+	return &staticNodeProvider{addresses: addresses}
+}
+
+func (p *staticNodeProvider) Nodes(ctx context.Context) ([]string, error) {
+	res := make([]string, len(p.addresses))
+	copy(res, p.addresses)
+	return res, nil
+}
+
+// dnsSRVNodeProvider discovers runners through the SRV records of a name
+type dnsSRVNodeProvider struct {
+	name     string
+	resolver *net.Resolver
+}
+
+// NewDNSSRVNodeProvider returns a node provider looking up the SRV records of name,
+// eg. _grpc._tcp.runners.example.com, each record being a runner.
+func NewDNSSRVNodeProvider(name string) pool.NodeProvider {
+	return &dnsSRVNodeProvider{name: name, resolver: net.DefaultResolver}
+}
+
+func (p *dnsSRVNodeProvider) Nodes(ctx context.Context) ([]string, error) {
+	_, records, err := p.resolver.LookupSRV(ctx, "", "", p.name)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(records))
+	for _, r := range records {
+		host := strings.TrimSuffix(r.Target, ".")
+		res = append(res, net.JoinHostPort(host, strconv.Itoa(int(r.Port))))
+	}
+	return res, nil
+}
+
+const (
+	k8sServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	k8sRequestTimeout    = 10 * time.Second
+)
+
+// kubernetesNodeProvider discovers runners from the endpoints of a kubernetes
+// service, using the service account of the pod the lb runs in.
+type kubernetesNodeProvider struct {
+	apiURL    string
+	token     string
+	namespace string
+	service   string
+	port      string
+	client    *http.Client
+}
+
+// NewKubernetesNodeProvider returns a node provider listing the ready endpoints of a
+// kubernetes service. target is namespace/service[:port], where port is the name or
+// number of the service port runners listen on, the first port is used if unset.
+// The lb must run in the cluster with a service account allowed to get endpoints.
+func NewKubernetesNodeProvider(target string) (pool.NodeProvider, error) {
+	p := &kubernetesNodeProvider{}
+
+	nsSvc := target
+	if i := strings.LastIndex(target, ":"); i >= 0 {
+		nsSvc, p.port = target[:i], target[i+1:]
+	}
+	parts := strings.Split(nsSvc, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid kubernetes service %q, expected namespace/service[:port]", target)
+	}
+	p.namespace, p.service = parts[0], parts[1]
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("kubernetes runner discovery requires running in a kubernetes cluster")
+	}
+	p.apiURL = "https://" + net.JoinHostPort(host, port)
+
+	token, err := ioutil.ReadFile(k8sServiceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+	p.token = strings.TrimSpace(string(token))
+
+	ca, err := ioutil.ReadFile(k8sServiceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid kubernetes service account CA certificate")
+	}
+	p.client = &http.Client{
+		Timeout:   k8sRequestTimeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+	}
+	return p, nil
+}
+
+// k8sEndpoints is the subset of the kubernetes v1 Endpoints object we need
+type k8sEndpoints struct {
+	Subsets []struct {
+		Addresses []struct {
+			IP string `json:"ip"`
+		} `json:"addresses"`
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"subsets"`
+}
+
+func (p *kubernetesNodeProvider) Nodes(ctx context.Context) ([]string, error) {
+	url := fmt.Sprintf("%s/api/v1/namespaces/%s/endpoints/%s", p.apiURL, p.namespace, p.service)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kubernetes endpoints request failed with status %d", resp.StatusCode)
+	}
+
+	var endpoints k8sEndpoints
+	if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
+		return nil, err
+	}
+	return p.addresses(&endpoints), nil
+}
+
+// addresses returns host:port for every ready address of the endpoints. Not ready
+// addresses are listed separately by kubernetes and therefore left out.
+func (p *kubernetesNodeProvider) addresses(endpoints *k8sEndpoints) []string {
+	var res []string
+	for _, subset := range endpoints.Subsets {
+		port := 0
+		for i, sp := range subset.Ports {
+			if (p.port == "" && i == 0) || sp.Name == p.port || strconv.Itoa(sp.Port) == p.port {
+				port = sp.Port
+				break
+			}
+		}
+		if port == 0 {
+			continue
+		}
+		for _, addr := range subset.Addresses {
+			res = append(res, net.JoinHostPort(addr.IP, strconv.Itoa(port)))
+		}
+	}
+	return res
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"go.opencensus.io/trace"
+)
+
+// containers on windows listen on a named pipe, which the driver forwards to
+// a named pipe of the host, rather than on a unix socket
+const (
+	// pipeDockerDest is the named pipe containers listen on
+	pipeDockerDest = `\\.\pipe\fn-lsnr`
+	// pipePrefix prefixes the named pipes of the host
+	pipePrefix = `\\.\pipe\fn-`
+	// pipePoll is the interval at which the named pipe of a container is
+	// checked for until the container listens on it
+	pipePoll = 10 * time.Millisecond
+)
+
+// pipeIOFS is the named pipe of the host a container listens on
+type pipeIOFS struct {
+	pipe string
+}
+
+func newPipeIOFS() *pipeIOFS {
+	return &pipeIOFS{pipe: pipePrefix + id.New().String()}
+}
+
+func (p *pipeIOFS) AgentPath() string  { return p.pipe }
+func (p *pipeIOFS) DockerPath() string { return p.pipe }
+
+// Close is a no-op, the named pipe goes away with its container
+func (p *pipeIOFS) Close() error { return nil }
+
+var _ iofs = &pipeIOFS{}
+
+// pipeAwait closes udsWait once the container listens on the named pipe,
+// named pipes can not be watched like the unix socket directory
+func pipeAwait(ctx context.Context, pipe string, udsWait chan error) {
+	go func() {
+		ctx, span := trace.StartSpan(ctx, "pipe_await_poller")
+		defer span.End()
+
+		ticker := time.NewTicker(pipePoll)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := os.Stat(pipe); err == nil {
+					common.Logger(ctx).WithField("pipe", pipe).Debug("container listening on named pipe")
+					close(udsWait)
+					return
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,13 @@
+// +build !windows
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+func dialPipe(ctx context.Context, pipe string) (net.Conn, error) {
+	return nil, errors.New("named pipes are only supported on windows")
+}
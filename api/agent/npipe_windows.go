@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+)
+
+// pipeConn is a connection to a named pipe opened as a file
+type pipeConn struct {
+	*os.File
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "npipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+func (c *pipeConn) LocalAddr() net.Addr                { return pipeAddr(c.Name()) }
+func (c *pipeConn) RemoteAddr() net.Addr               { return pipeAddr(c.Name()) }
+func (c *pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// dialPipe connects to a named pipe, deadlines are not supported, calls are
+// bounded by the timeouts of the agent instead
+func dialPipe(ctx context.Context, pipe string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(pipe, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &pipeConn{f}, nil
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// memPressureFiles are the pressure stall information of memory, of the
+// cgroup of the agent on cgroup v2 hosts first, or of the whole system
+var memPressureFiles = []string{"/sys/fs/cgroup/memory.pressure", "/proc/pressure/memory"}
+
+// psiPoll is the interval at which memory pressure is sampled
+const psiPoll = time.Second
+
+var errNoPSI = errors.New("no memory pressure stall information, it requires linux 4.20 or later")
+
+// memPressure samples the share of time some tasks stalled on memory from a
+// PSI file, whose cumulated stall time total is in microseconds
+type memPressure struct {
+	path      string
+	lastTotal uint64
+	lastAt    time.Time
+}
+
+func newMemPressure() (*memPressure, error) {
+	for _, path := range memPressureFiles {
+		total, err := readPSITotal(path)
+		if err == nil {
+			return &memPressure{path: path, lastTotal: total, lastAt: time.Now()}, nil
+		}
+	}
+	return nil, errNoPSI
+}
+
+// sample returns the percentage of time tasks stalled on memory since the
+// last sample
+func (p *memPressure) sample() (uint64, error) {
+	total, err := readPSITotal(p.path)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	elapsed := uint64(now.Sub(p.lastAt) / time.Microsecond)
+	lastTotal := p.lastTotal
+	p.lastTotal, p.lastAt = total, now
+
+	if elapsed == 0 || total < lastTotal {
+		return 0, nil
+	}
+	return minUint64((total-lastTotal)*100/elapsed, 100), nil
+}
+
+// readPSITotal returns the total of the some line of a PSI file, eg.
+// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readPSITotal(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "total=") {
+				return strconv.ParseUint(strings.TrimPrefix(field, "total="), 10, 64)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no some total in %s", path)
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemPressure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "psi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "memory.pressure")
+	write := func(total string) {
+		content := "some avg10=0.00 avg60=0.00 avg300=0.00 total=" + total + "\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("1000")
+	total, err := readPSITotal(path)
+	if err != nil || total != 1000 {
+		t.Fatalf("expected a total of 1000, got %v %v", total, err)
+	}
+
+	// stalled for all of the last 100ms
+	p := &memPressure{path: path, lastTotal: 1000, lastAt: time.Now().Add(-100 * time.Millisecond)}
+	write("101000")
+	if stalled, err := p.sample(); err != nil || stalled < 90 {
+		t.Fatalf("expected tasks to stall, got %v %v", stalled, err)
+	}
+
+	p.lastAt = time.Now().Add(-100 * time.Millisecond)
+	if stalled, err := p.sample(); err != nil || stalled != 0 {
+		t.Fatalf("expected tasks not to stall, got %v %v", stalled, err)
+	}
+}
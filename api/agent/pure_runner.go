@@ -22,13 +22,18 @@ import (
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
 	"github.com/fnproject/fn/fnext"
 	"github.com/fnproject/fn/grpcutil"
+	"github.com/fnproject/fn/grpcutil/health"
+	"github.com/fnproject/fn/grpcutil/reflection"
 	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
@@ -237,6 +242,8 @@ func (ch *callHandle) enqueueCallResponse(err error) {
 	var details string
 	var errCode int
 	var errStr string
+	var timings models.CallTimings
+	var imageDigest, platform string
 
 	log := common.Logger(ch.ctx)
 
@@ -267,6 +274,8 @@ func (ch *callHandle) enqueueCallResponse(err error) {
 		}
 
 		details = mcall.ID
+		timings = mcall.Timings
+		imageDigest, platform = mcall.ImageDigest, mcall.Platform
 
 	}
 	log.Debugf("Sending Call Finish details=%v", details)
@@ -280,6 +289,9 @@ func (ch *callHandle) enqueueCallResponse(err error) {
 			CreatedAt:   createdAt,
 			StartedAt:   startedAt,
 			CompletedAt: completedAt,
+			Timings:     timings,
+			ImageDigest: imageDigest,
+			Platform:    platform,
 		}}})
 
 	if errTmp != nil {
@@ -559,12 +571,14 @@ type statusTracker struct {
 // and provides the gRPC server that implements the LB <-> Runner protocol.
 type pureRunner struct {
 	gRPCServer     *grpc.Server
+	health         *health.Server
 	creds          credentials.TransportCredentials
 	a              Agent
 	status         statusTracker
 	callHandleMap  map[string]*callHandle
 	callHandleLock sync.Mutex
 	enableDetach   bool
+	draining       int32
 }
 
 // implements Agent
@@ -580,6 +594,8 @@ func (pr *pureRunner) Submit(Call) error {
 // implements Agent
 func (pr *pureRunner) Close() error {
 	// First stop accepting requests
+	atomic.StoreInt32(&pr.draining, 1)
+	pr.health.Shutdown()
 	pr.gRPCServer.GracefulStop()
 	// Then let the agent finish
 	err := pr.a.Close()
@@ -607,9 +623,43 @@ func (pr *pureRunner) removeCallHandle(cID string) {
 
 }
 
+// rejectReason classifies errors of calls that were declined without running,
+// an empty reason means the call was not rejected
+func (pr *pureRunner) rejectReason(err error) pool.RejectReason {
+	switch err {
+	case models.ErrCallTimeoutServerBusy:
+		if atomic.LoadInt32(&pr.draining) != 0 {
+			return pool.RejectDraining
+		}
+		return pool.RejectTooBusy
+	case models.ErrCallResourceTooBig:
+		return pool.RejectOverMemory
+	case models.ErrFnsInvalidImage, models.ErrDetachUnsupported:
+		return pool.RejectUnsupported
+	}
+	return ""
+}
+
+// enqueueRejection tells the LB why the call was declined before sending the
+// response. The reason goes in the gRPC headers, which can only be set while
+// nothing was sent to the LB yet, that is when the call did not start.
+func (pr *pureRunner) enqueueRejection(state *callHandle, err error) {
+	if reason := pr.rejectReason(err); reason != "" {
+		err := state.engagement.SetHeader(metadata.Pairs(pool.RejectionMetadataKey, string(reason)))
+		if err != nil {
+			common.Logger(state.ctx).WithError(err).Debug("Failed to set rejection header")
+		}
+	}
+	state.enqueueCallResponse(err)
+}
+
 func (pr *pureRunner) spawnSubmit(state *callHandle) {
 	go func() {
 		err := pr.a.Submit(state.c)
+		if err == models.ErrCallTimeoutServerBusy {
+			pr.enqueueRejection(state, err)
+			return
+		}
 		state.enqueueCallResponse(err)
 	}()
 }
@@ -639,7 +689,7 @@ func (pr *pureRunner) handleTryCall(tc *runner.TryCall, state *callHandle) error
 	// We need to make sure normal functions calls cannot call it.
 	if pr.status.imageName != "" && c.Image == pr.status.imageName {
 		err = models.ErrFnsInvalidImage
-		state.enqueueCallResponse(err)
+		pr.enqueueRejection(state, err)
 		return err
 	}
 
@@ -649,6 +699,7 @@ func (pr *pureRunner) handleTryCall(tc *runner.TryCall, state *callHandle) error
 	c.CreatedAt = common.DateTime(start)
 	c.StartedAt = common.DateTime(time.Time{})
 	c.CompletedAt = common.DateTime(time.Time{})
+	c.Timings = nil
 
 	agentCall, err := pr.a.GetCall(FromModelAndInput(&c, state.pipeToFnR),
 		WithLogger(common.NoopReadWriteCloser{}),
@@ -657,7 +708,7 @@ func (pr *pureRunner) handleTryCall(tc *runner.TryCall, state *callHandle) error
 		WithExtensions(tc.GetExtensions()),
 	)
 	if err != nil {
-		state.enqueueCallResponse(err)
+		pr.enqueueRejection(state, err)
 		return err
 	}
 
@@ -674,7 +725,7 @@ func (pr *pureRunner) handleTryCall(tc *runner.TryCall, state *callHandle) error
 	if state.c.Type == models.TypeDetached {
 		if !pr.enableDetach {
 			err = models.ErrDetachUnsupported
-			state.enqueueCallResponse(err)
+			pr.enqueueRejection(state, err)
 			return err
 		}
 		pr.spawnDetachSubmit(state)
@@ -702,6 +753,10 @@ func (pr *pureRunner) Engage(engagement runner.RunnerProtocol_EngageServer) erro
 	md, ok := metadata.FromIncomingContext(ctx)
 	if ok {
 		log.Debug("MD is ", md)
+		// the logs of the call get the request id from its model, see setupCtx
+		if rids := md.Get(common.RequestIDContextKey); len(rids) > 0 && rids[0] != "" {
+			log = log.WithField(common.RequestIDContextKey, rids[0])
+		}
 	}
 	state := NewCallHandle(engagement)
 
@@ -1038,8 +1093,15 @@ func NewPureRunner(cancel context.CancelFunc, addr string, options ...PureRunner
 
 	var opts []grpc.ServerOption
 
-	opts = append(opts, grpc.StreamInterceptor(grpcutil.RIDStreamServerInterceptor))
-	opts = append(opts, grpc.UnaryInterceptor(grpcutil.RIDUnaryServerInterceptor))
+	opts = append(opts, grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
+		grpcutil.TraceStreamServerInterceptor, grpcutil.RIDStreamServerInterceptor)))
+	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
+		grpcutil.TraceUnaryServerInterceptor, grpcutil.RIDUnaryServerInterceptor)))
+	// lbs may ping idle connections to keep them alive, see RunnerConnConfig
+	opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             minKeepaliveTime,
+		PermitWithoutStream: true,
+	}))
 
 	if pr.creds != nil {
 		opts = append(opts, grpc.Creds(pr.creds))
@@ -1050,6 +1112,11 @@ func NewPureRunner(cancel context.CancelFunc, addr string, options ...PureRunner
 	pr.callHandleMap = make(map[string]*callHandle)
 	pr.gRPCServer = grpc.NewServer(opts...)
 	runner.RegisterRunnerProtocolServer(pr.gRPCServer, pr)
+	// standard probes for load balancers and tools, see grpc_health_probe and grpcurl
+	pr.health = health.NewServer()
+	pr.health.SetServingStatus("RunnerProtocol", health.Serving)
+	health.RegisterHealthServer(pr.gRPCServer, pr.health)
+	reflection.Register(pr.gRPCServer)
 
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -52,8 +52,10 @@ type ResourceTracker interface {
 	// will never receive anything (use IsResourcePossible). If a resource token is available for the provided
 	// resource parameters, it will otherwise be sent once on the returned channel. The channel is never closed.
 	// if isNB is set, resource check is done and error token is returned without blocking.
+	// Unless overcommit is set, the token is only available while the resources reserved by all tokens fit
+	// the machine, even if the tracker overcommits it.
 	// Memory is expected to be provided in MB units.
-	GetResourceToken(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, isNB bool) <-chan ResourceToken
+	GetResourceToken(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, overcommit, isNB bool) <-chan ResourceToken
 
 	// IsResourcePossible returns whether it's possible to fulfill the requested resources on this
 	// machine. It must be called before GetResourceToken or GetResourceToken may hang.
@@ -79,6 +81,10 @@ type resourceTracker struct {
 	cpuUsed uint64
 	// cpu in use in which agent stops dequeuing async jobs
 	cpuAsyncHWMark uint64
+	// memOvercommit and cpuOvercommit are the percentage of ramTotal and
+	// cpuTotal tokens may reserve, no overcommit below 100
+	memOvercommit uint64
+	cpuOvercommit uint64
 }
 
 func NewResourceTracker(cfg *Config) ResourceTracker {
@@ -86,6 +92,10 @@ func NewResourceTracker(cfg *Config) ResourceTracker {
 	obj := &resourceTracker{
 		cond: sync.NewCond(new(sync.Mutex)),
 	}
+	if cfg != nil {
+		obj.memOvercommit = cfg.MemOvercommit
+		obj.cpuOvercommit = cfg.CPUOvercommit
+	}
 
 	obj.initializeMemory(cfg)
 	obj.initializeCPU(cfg)
@@ -124,10 +134,34 @@ func (t *resourceToken) Close() error {
 	return nil
 }
 
-func (a *resourceTracker) isResourceAvailableLocked(memory uint64, cpuQuota models.MilliCPUs) bool {
+// overcommitted returns the part of total which may be reserved, pct percent of
+// it if overcommit is set
+func overcommitted(total, pct uint64, overcommit bool) uint64 {
+	if !overcommit || pct <= 100 {
+		return total
+	}
+	return total * pct / 100
+}
+
+// availLocked returns the memory and cpu which are not reserved, out of what
+// tokens may reserve
+func (a *resourceTracker) availLocked(overcommit bool) (uint64, uint64) {
+	ramLimit := overcommitted(a.ramTotal, a.memOvercommit, overcommit)
+	cpuLimit := overcommitted(a.cpuTotal, a.cpuOvercommit, overcommit)
+
+	var availMem, availCPU uint64
+	if ramLimit > a.ramUsed {
+		availMem = ramLimit - a.ramUsed
+	}
+	if cpuLimit > a.cpuUsed {
+		availCPU = cpuLimit - a.cpuUsed
+	}
+	return availMem, availCPU
+}
+
+func (a *resourceTracker) isResourceAvailableLocked(memory uint64, cpuQuota models.MilliCPUs, overcommit bool) bool {
 
-	availMem := a.ramTotal - a.ramUsed
-	availCPU := a.cpuTotal - a.cpuUsed
+	availMem, availCPU := a.availLocked(overcommit)
 
 	return availMem >= memory && availCPU >= uint64(cpuQuota)
 }
@@ -139,11 +173,12 @@ func (a *resourceTracker) GetUtilization() ResourceUtilization {
 
 	util.CpuUsed = models.MilliCPUs(a.cpuUsed)
 	util.MemUsed = a.ramUsed
+	availMem, availCPU := a.availLocked(true)
 
 	a.cond.L.Unlock()
 
-	util.CpuAvail = models.MilliCPUs(a.cpuTotal) - util.CpuUsed
-	util.MemAvail = a.ramTotal - util.MemUsed
+	util.CpuAvail = models.MilliCPUs(availCPU)
+	util.MemAvail = availMem
 
 	return util
 }
@@ -173,7 +208,7 @@ func (a *resourceTracker) allocResourcesLocked(memory uint64, cpuQuota models.Mi
 	}}
 }
 
-func (a *resourceTracker) getResourceTokenNB(memory uint64, cpuQuota models.MilliCPUs) ResourceToken {
+func (a *resourceTracker) getResourceTokenNB(memory uint64, cpuQuota models.MilliCPUs, overcommit bool) ResourceToken {
 	if !a.IsResourcePossible(memory, cpuQuota) {
 		return &resourceToken{err: CapacityFull, needCpu: cpuQuota, needMem: memory}
 	}
@@ -185,8 +220,7 @@ func (a *resourceTracker) getResourceTokenNB(memory uint64, cpuQuota models.Mill
 
 	a.cond.L.Lock()
 
-	availMem := a.ramTotal - a.ramUsed
-	availCPU := a.cpuTotal - a.cpuUsed
+	availMem, availCPU := a.availLocked(overcommit)
 
 	if availMem >= memory && availCPU >= uint64(cpuQuota) {
 		t = a.allocResourcesLocked(memory, cpuQuota)
@@ -204,13 +238,13 @@ func (a *resourceTracker) getResourceTokenNB(memory uint64, cpuQuota models.Mill
 	return t
 }
 
-func (a *resourceTracker) getResourceTokenNBChan(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs) <-chan ResourceToken {
+func (a *resourceTracker) getResourceTokenNBChan(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, overcommit bool) <-chan ResourceToken {
 	ctx, span := trace.StartSpan(ctx, "agent_get_resource_token_nbio_chan")
 
 	ch := make(chan ResourceToken)
 	go func() {
 		defer span.End()
-		t := a.getResourceTokenNB(memory, cpuQuota)
+		t := a.getResourceTokenNB(memory, cpuQuota, overcommit)
 
 		select {
 		case ch <- t:
@@ -225,9 +259,9 @@ func (a *resourceTracker) getResourceTokenNBChan(ctx context.Context, memory uin
 
 // the received token should be passed directly to launch (unconditionally), launch
 // will close this token (i.e. the receiver should not call Close)
-func (a *resourceTracker) GetResourceToken(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, isNB bool) <-chan ResourceToken {
+func (a *resourceTracker) GetResourceToken(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, overcommit, isNB bool) <-chan ResourceToken {
 	if isNB {
-		return a.getResourceTokenNBChan(ctx, memory, cpuQuota)
+		return a.getResourceTokenNBChan(ctx, memory, cpuQuota, overcommit)
 	}
 
 	ch := make(chan ResourceToken)
@@ -262,7 +296,7 @@ func (a *resourceTracker) GetResourceToken(ctx context.Context, memory uint64, c
 		c.L.Lock()
 
 		isWaiting = true
-		for !a.isResourceAvailableLocked(memory, cpuQuota) && ctx.Err() == nil {
+		for !a.isResourceAvailableLocked(memory, cpuQuota, overcommit) && ctx.Err() == nil {
 			c.Wait()
 		}
 		isWaiting = false
@@ -391,10 +425,11 @@ func (a *resourceTracker) initializeCPU(cfg *Config) {
 	}).Info("available cpu")
 
 	a.cpuTotal = availCPU
-	a.cpuAsyncHWMark = availCPU * 8 / 10
+	a.cpuAsyncHWMark = overcommitted(availCPU, a.cpuOvercommit, true) * 8 / 10
 
 	logrus.WithFields(logrus.Fields{
 		"cpu":            a.cpuTotal,
+		"cpuOvercommit":  a.cpuOvercommit,
 		"cpuAsyncHWMark": a.cpuAsyncHWMark,
 	}).Info("cpu reservations")
 
@@ -449,11 +484,12 @@ func (a *resourceTracker) initializeMemory(cfg *Config) {
 	}
 
 	a.ramTotal = availMemory
-	a.ramAsyncHWMark = availMemory * 8 / 10
+	a.ramAsyncHWMark = overcommitted(availMemory, a.memOvercommit, true) * 8 / 10
 
 	// For non-linux OS, we expect these (or their defaults) properly configured from command-line/env
 	logrus.WithFields(logrus.Fields{
 		"availMemory":    a.ramTotal,
+		"memOvercommit":  a.memOvercommit,
 		"ramAsyncHWMark": a.ramAsyncHWMark,
 	}).Info("ram reservations")
 
@@ -474,9 +510,13 @@ func getMemoryHeadRoom(usableMemory uint64, cfg *Config) (uint64, error) {
 
 	// TODO: improve this pre-fork calculation, we should fetch/query this
 	// instead of estimate below.
-	// if pre-fork pool is enabled, add 1 MB per pool-item
+	// if pre-fork pool is enabled, add 1 MB per pool-item (up to the size the pool may grow to)
 	if cfg != nil && cfg.PreForkPoolSize != 0 {
-		headRoom += Mem1MB * cfg.PreForkPoolSize
+		poolSize := cfg.PreForkPoolSize
+		if cfg.PreForkPoolMaxSize > poolSize {
+			poolSize = cfg.PreForkPoolMaxSize
+		}
+		headRoom += Mem1MB * poolSize
 	}
 
 	// TODO: improve these calculations.
@@ -163,7 +163,7 @@ func TestResourceGetSimple(t *testing.T) {
 
 	// ask for 4GB and 10 CPU
 	ctx, cancel := context.WithCancel(context.Background())
-	ch := trI.GetResourceToken(ctx, 4*1024, 1000, false)
+	ch := trI.GetResourceToken(ctx, 4*1024, 1000, true, false)
 	defer cancel()
 
 	_, err := fetchToken(ch)
@@ -182,7 +182,7 @@ func TestResourceGetSimple(t *testing.T) {
 
 	// ask for another 4GB and 10 CPU
 	ctx, cancel = context.WithCancel(context.Background())
-	ch = trI.GetResourceToken(ctx, 4*1024, 1000, false)
+	ch = trI.GetResourceToken(ctx, 4*1024, 1000, true, false)
 	defer cancel()
 
 	_, err = fetchToken(ch)
@@ -226,7 +226,7 @@ func TestResourceGetSimpleNB(t *testing.T) {
 
 	// ask for 4GB and 10 CPU
 	ctx, cancel := context.WithCancel(context.Background())
-	ch := trI.GetResourceToken(ctx, 4*1024, 1000, true)
+	ch := trI.GetResourceToken(ctx, 4*1024, 1000, true, true)
 	defer cancel()
 
 	tok := <-ch
@@ -238,14 +238,14 @@ func TestResourceGetSimpleNB(t *testing.T) {
 	vals.setDefaults()
 	setTrackerTestVals(tr, &vals)
 
-	tok1 := <-trI.GetResourceToken(ctx, 4*1024, 1000, true)
+	tok1 := <-trI.GetResourceToken(ctx, 4*1024, 1000, true, true)
 	if tok1.Error() != nil {
 		t.Fatalf("empty system should hand out token")
 	}
 
 	// ask for another 4GB and 10 CPU
 	ctx, cancel = context.WithCancel(context.Background())
-	ch = trI.GetResourceToken(ctx, 4*1024, 1000, true)
+	ch = trI.GetResourceToken(ctx, 4*1024, 1000, true, true)
 	defer cancel()
 
 	tok = <-ch
@@ -256,7 +256,7 @@ func TestResourceGetSimpleNB(t *testing.T) {
 	// close means, giant token resources released
 	tok1.Close()
 
-	tok = <-trI.GetResourceToken(ctx, 4*1024, 1000, true)
+	tok = <-trI.GetResourceToken(ctx, 4*1024, 1000, true, true)
 	if tok.Error() != nil {
 		t.Fatalf("empty system should hand out token")
 	}
@@ -272,3 +272,45 @@ func TestResourceGetSimpleNB(t *testing.T) {
 		t.Fatalf("faulty state CPU %#v", vals)
 	}
 }
+
+func TestResourceGetOvercommit(t *testing.T) {
+
+	var vals trackerVals
+	trI := NewResourceTracker(&Config{MemOvercommit: 150})
+	tr := trI.(*resourceTracker)
+
+	vals.setDefaults()
+	setTrackerTestVals(tr, &vals)
+
+	// ask for 4GB, all of the memory
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tok1 := <-trI.GetResourceToken(ctx, 4*1024, 1000, true, true)
+	if tok1.Error() != nil {
+		t.Fatalf("empty system should hand out token")
+	}
+	defer tok1.Close()
+
+	// fns opting out of overcommit only get memory which is not reserved
+	tok := <-trI.GetResourceToken(ctx, 1024, 1000, false, true)
+	if tok.Error() == nil {
+		t.Fatalf("full system should not hand out token without overcommit")
+	}
+
+	// 1.5x of 4GB leaves 2GB to overcommit
+	tok2 := <-trI.GetResourceToken(ctx, 2*1024, 1000, true, true)
+	if tok2.Error() != nil {
+		t.Fatalf("overcommitted system should hand out token")
+	}
+	defer tok2.Close()
+
+	tok = <-trI.GetResourceToken(ctx, 1, 1000, true, true)
+	if tok.Error() == nil {
+		t.Fatalf("fully overcommitted system should not hand out token")
+	}
+
+	if util := trI.GetUtilization(); util.MemUsed != 6*Mem1GB || util.MemAvail != 0 {
+		t.Fatalf("faulty utilization %#v", util)
+	}
+}
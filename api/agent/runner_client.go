@@ -8,10 +8,13 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
@@ -36,24 +39,102 @@ const (
 )
 
 type gRPCRunner struct {
-	shutWg  *common.WaitGroup
-	address string
-	conn    *grpc.ClientConn
-	client  pb.RunnerProtocolClient
+	shutWg    *common.WaitGroup
+	address   string
+	conn      *grpc.ClientConn
+	client    pb.RunnerProtocolClient
+	heartbeat *runnerHeartbeat
+}
+
+// RunnerConnConfig tunes the gRPC connections of an lb to its runners. All
+// calls to a runner are multiplexed as streams on a single connection.
+type RunnerConnConfig struct {
+	// DialTimeout bounds connecting to a runner, short to fail fast
+	DialTimeout time.Duration
+	// KeepaliveTime is how long a connection may be idle before the lb pings
+	// the runner, zero disables keepalive pings. Runners do not accept pings
+	// more often than every minKeepaliveTime.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is how long the lb waits for a ping to be acknowledged
+	// before it closes the connection
+	KeepaliveTimeout time.Duration
+	// InitialWindowSize and InitialConnWindowSize are the flow control windows
+	// of streams and of connections in bytes, zero keeps the gRPC defaults.
+	// Larger windows help bodies through high latency links.
+	InitialWindowSize     int32
+	InitialConnWindowSize int32
+	// HeartbeatInterval is how often the lb asks a runner running its calls
+	// for its status, zero disables heartbeats. The calls of runners which
+	// miss HeartbeatMisses heartbeats in a row fail with
+	// models.ErrCallRunnerLost, or fail over to another runner if they can.
+	HeartbeatInterval time.Duration
+	HeartbeatMisses   int
+}
+
+// minKeepaliveTime is the shortest KeepaliveTime runners accept, they close
+// connections of lbs pinging more often
+const minKeepaliveTime = 5 * time.Second
+
+// NewRunnerConnConfig returns the default runner connection config
+func NewRunnerConnConfig() RunnerConnConfig {
+	return RunnerConnConfig{
+		DialTimeout:       100 * time.Millisecond,
+		KeepaliveTimeout:  20 * time.Second,
+		HeartbeatInterval: 5 * time.Second,
+		HeartbeatMisses:   3,
+	}
+}
+
+func (cfg *RunnerConnConfig) dialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if cfg.KeepaliveTime > 0 {
+		t := cfg.KeepaliveTime
+		if t < minKeepaliveTime {
+			t = minKeepaliveTime
+		}
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                t,
+			Timeout:             cfg.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+	if cfg.InitialWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialWindowSize(cfg.InitialWindowSize))
+	}
+	if cfg.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialConnWindowSize(cfg.InitialConnWindowSize))
+	}
+	return opts
 }
 
 func SecureGRPCRunnerFactory(addr string, tlsConf *tls.Config) (pool.Runner, error) {
-	conn, client, err := runnerConnection(addr, tlsConf)
+	return newGRPCRunner(addr, tlsConf, NewRunnerConnConfig())
+}
+
+// NewGRPCRunnerFactory returns a factory of runners connected with cfg
+func NewGRPCRunnerFactory(cfg RunnerConnConfig) pool.MTLSRunnerFactory {
+	return func(addr string, tlsConf *tls.Config) (pool.Runner, error) {
+		return newGRPCRunner(addr, tlsConf, cfg)
+	}
+}
+
+func newGRPCRunner(addr string, tlsConf *tls.Config, cfg RunnerConnConfig) (pool.Runner, error) {
+	conn, client, err := runnerConnection(addr, tlsConf, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &gRPCRunner{
+	r := &gRPCRunner{
 		shutWg:  common.NewWaitGroup(),
 		address: addr,
 		conn:    conn,
 		client:  client,
-	}, nil
+	}
+	r.heartbeat = newRunnerHeartbeat(cfg.HeartbeatInterval, cfg.HeartbeatMisses, func(ctx context.Context) error {
+		_, err := r.client.Status(ctx, &pb_empty.Empty{})
+		return err
+	})
+	return r, nil
 }
 
 // implements Runner
@@ -62,10 +143,10 @@ func (r *gRPCRunner) Close(context.Context) error {
 	return r.conn.Close()
 }
 
-func runnerConnection(address string, tlsConf *tls.Config) (*grpc.ClientConn, pb.RunnerProtocolClient, error) {
+func runnerConnection(address string, tlsConf *tls.Config, cfg RunnerConnConfig) (*grpc.ClientConn, pb.RunnerProtocolClient, error) {
 
 	ctx := context.Background()
-	logger := common.Logger(ctx).WithField("runner_addr", address)
+	logger := common.ComponentLogger(ctx, common.LogComponentLB).WithField("runner_addr", address)
 	ctx = common.WithLogger(ctx, logger)
 
 	var creds credentials.TransportCredentials
@@ -74,7 +155,7 @@ func runnerConnection(address string, tlsConf *tls.Config) (*grpc.ClientConn, pb
 	}
 
 	// we want to set a very short timeout to fail-fast if something goes wrong
-	conn, err := grpcutil.DialWithBackoff(ctx, address, creds, 100*time.Millisecond, grpc.DefaultBackoffConfig)
+	conn, err := grpcutil.DialWithBackoff(ctx, address, creds, cfg.DialTimeout, grpc.DefaultBackoffConfig, cfg.dialOptions()...)
 	if err != nil {
 		logger.WithError(err).Error("Unable to connect to runner node")
 	}
@@ -105,6 +186,12 @@ func isTooBusy(err error) bool {
 	return false
 }
 
+// isRunnerGone reports whether the connection to a runner failed while it ran
+// a call, eg. the runner was killed or became unreachable
+func isRunnerGone(err error) bool {
+	return err != nil && status.Code(err) == codes.Unavailable
+}
+
 // Translate runner.RunnerStatus to runnerpool.RunnerStatus
 func TranslateGRPCStatusToRunnerStatus(status *pb.RunnerStatus) *pool.RunnerStatus {
 	if status == nil {
@@ -172,6 +259,9 @@ func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 		mp := metadata.Pairs(common.RequestIDContextKey, rid)
 		ctx = metadata.NewOutgoingContext(ctx, mp)
 	}
+	ctx = grpcutil.TraceToOutgoingContext(ctx)
+	ctx, inflight, untrack := r.heartbeat.track(ctx)
+	defer untrack()
 	runnerConnection, err := r.client.Engage(ctx)
 	if err != nil {
 		log.WithError(err).Error("Unable to create client to runner node")
@@ -195,22 +285,69 @@ func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 
 	recvDone := make(chan error, 1)
 
-	go receiveFromRunner(ctx, runnerConnection, r.address, call, recvDone)
+	var responded int32
+	go receiveFromRunner(ctx, runnerConnection, r.address, call, recvDone, &responded)
 	go sendToRunner(ctx, runnerConnection, r.address, call)
 
 	select {
 	case <-ctx.Done():
+		if inflight.isLost() {
+			return runnerLost(log, call, &responded)
+		}
 		log.Infof("Engagement Context ended ctxErr=%v", ctx.Err())
 		return true, ctx.Err()
 	case recvErr := <-recvDone:
+		if inflight.isLost() {
+			return runnerLost(log, call, &responded)
+		}
+		if rej := getRejection(runnerConnection, recvErr); rej != nil {
+			if rej.Reason == pool.RejectTooBusy {
+				// Try on next runner
+				return false, models.ErrCallTimeoutServerBusy
+			}
+			return false, rej
+		}
 		if isTooBusy(recvErr) {
 			// Try on next runner
 			return false, models.ErrCallTimeoutServerBusy
 		}
+		if isRunnerGone(recvErr) && atomic.LoadInt32(&responded) == 0 && pool.CanFailover(call.Model()) {
+			// nothing reached the client yet, the placer may fail over to
+			// another runner
+			return false, &pool.RunnerRejection{Reason: pool.RejectFailed, Err: recvErr}
+		}
 		return true, recvErr
 	}
 }
 
+// runnerLost fails a call whose runner missed its heartbeats, or lets the
+// placer fail it over to another runner if nothing reached the client yet
+func runnerLost(log logrus.FieldLogger, call pool.RunnerCall, responded *int32) (bool, error) {
+	log.Error("Runner lost while running the call, it missed its heartbeats")
+	if atomic.LoadInt32(responded) == 0 && pool.CanFailover(call.Model()) {
+		return false, &pool.RunnerRejection{Reason: pool.RejectFailed, Err: models.ErrCallRunnerLost}
+	}
+	return true, models.ErrCallRunnerLost
+}
+
+// getRejection returns the rejection a runner declined the call with, if any.
+// Runners that predate typed rejections only report too busy errors, which
+// are still handled by isTooBusy.
+func getRejection(protocolClient pb.RunnerProtocol_EngageClient, err error) *pool.RunnerRejection {
+	if err == nil {
+		return nil
+	}
+	md, mdErr := protocolClient.Header()
+	if mdErr != nil {
+		return nil
+	}
+	reasons := md[pool.RejectionMetadataKey]
+	if len(reasons) == 0 {
+		return nil
+	}
+	return &pool.RunnerRejection{Reason: pool.RejectReason(reasons[0]), Err: err}
+}
+
 func sendToRunner(ctx context.Context, protocolClient pb.RunnerProtocol_EngageClient, runnerAddress string, call pool.RunnerCall) {
 	bodyReader := call.RequestBody()
 	writeBuffer := make([]byte, MaxDataChunk)
@@ -289,6 +426,9 @@ func translateDate(dt string) time.Time {
 
 func recordFinishStats(ctx context.Context, msg *pb.CallFinished, c pool.RunnerCall) {
 
+	c.AddTimings(msg.GetTimings())
+	c.SetResolvedImage(msg.GetImageDigest(), msg.GetPlatform())
+
 	creatTs := translateDate(msg.GetCreatedAt())
 	startTs := translateDate(msg.GetStartedAt())
 	complTs := translateDate(msg.GetCompletedAt())
@@ -306,7 +446,8 @@ func recordFinishStats(ctx context.Context, msg *pb.CallFinished, c pool.RunnerC
 	}
 }
 
-func receiveFromRunner(ctx context.Context, protocolClient pb.RunnerProtocol_EngageClient, runnerAddress string, c pool.RunnerCall, done chan error) {
+// receiveFromRunner sets responded once the runner sent anything for the call
+func receiveFromRunner(ctx context.Context, protocolClient pb.RunnerProtocol_EngageClient, runnerAddress string, c pool.RunnerCall, done chan error, responded *int32) {
 	w := c.ResponseWriter()
 	defer close(done)
 
@@ -322,6 +463,7 @@ DataLoop:
 			tryQueueError(err, done)
 			return
 		}
+		atomic.StoreInt32(responded, 1)
 
 		switch body := msg.Body.(type) {
 
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runnerHeartbeat checks that a runner is alive while it runs calls of the
+// lb, by asking the runner for its status every interval. Runners which fail
+// to answer misses heartbeats in a row are lost, the calls in flight on them
+// are cancelled. Runners that crash close their connections, which fails
+// their calls right away, but runners that hang or become unreachable would
+// otherwise keep their calls until they time out.
+type runnerHeartbeat struct {
+	status   func(ctx context.Context) error
+	interval time.Duration
+	misses   int
+
+	lock    sync.Mutex
+	calls   map[*inflightCall]struct{}
+	beating bool
+}
+
+// inflightCall is a call running on a runner, see runnerHeartbeat
+type inflightCall struct {
+	cancel context.CancelFunc
+	lost   int32
+}
+
+// isLost returns whether the call was cancelled because its runner was lost
+func (c *inflightCall) isLost() bool {
+	return c != nil && atomic.LoadInt32(&c.lost) == 1
+}
+
+func newRunnerHeartbeat(interval time.Duration, misses int, status func(ctx context.Context) error) *runnerHeartbeat {
+	if misses < 1 {
+		misses = 1
+	}
+	return &runnerHeartbeat{
+		status:   status,
+		interval: interval,
+		misses:   misses,
+		calls:    make(map[*inflightCall]struct{}),
+	}
+}
+
+// track returns a context of ctx which is cancelled if the runner is lost,
+// and the call to check for it, until untrack is called. Calls are not
+// tracked if heartbeats are disabled.
+func (h *runnerHeartbeat) track(ctx context.Context) (_ context.Context, c *inflightCall, untrack func()) {
+	if h == nil || h.interval <= 0 {
+		return ctx, nil, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c = &inflightCall{cancel: cancel}
+
+	h.lock.Lock()
+	h.calls[c] = struct{}{}
+	if !h.beating {
+		h.beating = true
+		go h.beat()
+	}
+	h.lock.Unlock()
+
+	return ctx, c, func() {
+		h.lock.Lock()
+		delete(h.calls, c)
+		h.lock.Unlock()
+		cancel()
+	}
+}
+
+// beat checks the runner every interval, until no calls are in flight on it
+func (h *runnerHeartbeat) beat() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for range ticker.C {
+		h.lock.Lock()
+		if len(h.calls) == 0 {
+			h.beating = false
+			h.lock.Unlock()
+			return
+		}
+		h.lock.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), h.interval)
+		err := h.status(ctx)
+		cancel()
+		if err == nil {
+			missed = 0
+			continue
+		}
+		missed++
+		if missed < h.misses {
+			continue
+		}
+		missed = 0
+		h.lose()
+	}
+}
+
+// lose cancels the calls in flight on the runner, which is lost
+func (h *runnerHeartbeat) lose() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for c := range h.calls {
+		atomic.StoreInt32(&c.lost, 1)
+		c.cancel()
+		delete(h.calls, c)
+	}
+}
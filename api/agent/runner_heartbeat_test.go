@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunnerHeartbeat(t *testing.T) {
+	var alive int32 = 1
+	var beats int32
+	h := newRunnerHeartbeat(10*time.Millisecond, 2, func(ctx context.Context) error {
+		atomic.AddInt32(&beats, 1)
+		if atomic.LoadInt32(&alive) == 1 {
+			return nil
+		}
+		return errors.New("unreachable")
+	})
+
+	ctx, c, untrack := h.track(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	if ctx.Err() != nil || c.isLost() || atomic.LoadInt32(&beats) == 0 {
+		t.Fatal("expected a runner answering its heartbeats to keep its calls")
+	}
+	untrack()
+
+	// the heartbeat stops with the last call in flight
+	time.Sleep(50 * time.Millisecond)
+	stopped := atomic.LoadInt32(&beats)
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&beats) != stopped {
+		t.Fatal("expected no heartbeats without calls in flight")
+	}
+
+	atomic.StoreInt32(&alive, 0)
+	ctx, c, untrack = h.track(context.Background())
+	defer untrack()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the calls of a lost runner to be cancelled")
+	}
+	if !c.isLost() {
+		t.Fatal("expected the call to be lost with its runner")
+	}
+}
+
+func TestRunnerHeartbeatDisabled(t *testing.T) {
+	h := newRunnerHeartbeat(0, 3, func(ctx context.Context) error { return errors.New("unreachable") })
+	ctx := context.Background()
+	tracked, c, untrack := h.track(ctx)
+	defer untrack()
+	if tracked != ctx || c.isLost() {
+		t.Fatal("expected calls not to be tracked without heartbeats")
+	}
+}
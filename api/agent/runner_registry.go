@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunnerRegistration is what a runner registers with an lb, runners register
+// again every heartbeat to stay in the pool of the lb
+type RunnerRegistration struct {
+	// Address is the host:port of the gRPC server of the runner
+	Address string `json:"address"`
+	Zone    string `json:"zone,omitempty"`
+	// MemoryBytes and CPUMillis are the capacity of the runner for calls, 0
+	// if the runner does not limit them
+	MemoryBytes uint64            `json:"memory_bytes"`
+	CPUMillis   uint64            `json:"cpu_millis"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Version     string            `json:"version"`
+	// FnMetrics is the demand for each fn on the runner, see FnMetricsReporter
+	FnMetrics []FnMetrics `json:"fn_metrics,omitempty"`
+	// LastSeen is set by the registry to the time of the last heartbeat
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// RunnerRegistry is a node provider of the runners that registered with an lb
+// and sent a heartbeat within the last ttl, others are expired
+type RunnerRegistry struct {
+	ttl     time.Duration
+	lock    sync.Mutex
+	runners map[string]RunnerRegistration
+}
+
+// NewRunnerRegistry returns a registry expiring runners that do not send a
+// heartbeat for ttl
+func NewRunnerRegistry(ttl time.Duration) *RunnerRegistry {
+	return &RunnerRegistry{ttl: ttl, runners: make(map[string]RunnerRegistration)}
+}
+
+// TTL returns the time after which runners without a heartbeat are expired
+func (r *RunnerRegistry) TTL() time.Duration {
+	return r.ttl
+}
+
+// Register adds reg to the registry or, for a runner that registered before,
+// records its heartbeat and updates its registration
+func (r *RunnerRegistry) Register(reg RunnerRegistration) error {
+	if reg.Address == "" {
+		return errors.New("a runner registration needs an address")
+	}
+	reg.LastSeen = time.Now()
+
+	r.lock.Lock()
+	r.runners[reg.Address] = reg
+	r.lock.Unlock()
+	return nil
+}
+
+// Deregister removes the runner at addr, which is no longer placed calls on
+// once the pool refreshes its runners
+func (r *RunnerRegistry) Deregister(addr string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, ok := r.runners[addr]
+	delete(r.runners, addr)
+	return ok
+}
+
+// Runners returns the live registrations sorted by address, expiring those
+// whose last heartbeat is older than the ttl
+func (r *RunnerRegistry) Runners() []RunnerRegistration {
+	expiry := time.Now().Add(-r.ttl)
+
+	r.lock.Lock()
+	res := make([]RunnerRegistration, 0, len(r.runners))
+	for addr, reg := range r.runners {
+		if reg.LastSeen.Before(expiry) {
+			logrus.WithFields(logrus.Fields{"runner_addr": addr, "last_seen": reg.LastSeen}).Info("Expiring runner without heartbeat")
+			delete(r.runners, addr)
+			continue
+		}
+		res = append(res, reg)
+	}
+	r.lock.Unlock()
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Address < res[j].Address })
+	return res
+}
+
+// FnMetrics returns the demand for each fn summed over the live runners, the
+// p95 latency of a fn being the highest of its runners
+func (r *RunnerRegistry) FnMetrics() []FnMetrics {
+	byFn := make(map[string]*FnMetrics)
+	for _, reg := range r.Runners() {
+		for _, m := range reg.FnMetrics {
+			sum, ok := byFn[m.FnID]
+			if !ok {
+				sum = &FnMetrics{FnID: m.FnID}
+				byFn[m.FnID] = sum
+			}
+			sum.InFlight += m.InFlight
+			sum.Concurrency += m.Concurrency
+			sum.RPS += m.RPS
+			if m.P95Millis > sum.P95Millis {
+				sum.P95Millis = m.P95Millis
+			}
+		}
+	}
+
+	res := make([]FnMetrics, 0, len(byFn))
+	for _, m := range byFn {
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].FnID < res[j].FnID })
+	return res
+}
+
+// Labels returns the labels the runner at addr registered with, it
+// implements pool.RunnerLabels
+func (r *RunnerRegistry) Labels(addr string) map[string]string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.runners[addr].Labels
+}
+
+// Nodes implements pool.NodeProvider
+func (r *RunnerRegistry) Nodes(ctx context.Context) ([]string, error) {
+	runners := r.Runners()
+	res := make([]string, 0, len(runners))
+	for _, reg := range runners {
+		if reg.Zone != "" {
+			res = append(res, reg.Address+"@"+reg.Zone)
+		} else {
+			res = append(res, reg.Address)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunnerRegistry(t *testing.T) {
+	r := NewRunnerRegistry(50 * time.Millisecond)
+	if err := r.Register(RunnerRegistration{}); err == nil {
+		t.Fatal("Expected a registration without an address to fail")
+	}
+	r.Register(RunnerRegistration{Address: "10.0.0.2:9190", Zone: "az1"})
+	r.Register(RunnerRegistration{Address: "10.0.0.1:9190"})
+
+	nodes, _ := r.Nodes(context.Background())
+	if len(nodes) != 2 || nodes[0] != "10.0.0.1:9190" || nodes[1] != "10.0.0.2:9190@az1" {
+		t.Fatalf("Unexpected nodes %v", nodes)
+	}
+
+	// a runner that keeps sending heartbeats outlives one that stopped
+	time.Sleep(30 * time.Millisecond)
+	r.Register(RunnerRegistration{Address: "10.0.0.1:9190"})
+	time.Sleep(30 * time.Millisecond)
+	nodes, _ = r.Nodes(context.Background())
+	if len(nodes) != 1 || nodes[0] != "10.0.0.1:9190" {
+		t.Fatalf("Expected the runner without heartbeat to expire, got %v", nodes)
+	}
+
+	if !r.Deregister("10.0.0.1:9190") || r.Deregister("10.0.0.1:9190") {
+		t.Fatal("Expected a runner to be deregistered once")
+	}
+	if runners := r.Runners(); len(runners) != 0 {
+		t.Fatalf("Unexpected runners %v", runners)
+	}
+}
+
+func TestRunnerRegistryFnMetrics(t *testing.T) {
+	r := NewRunnerRegistry(time.Minute)
+	r.Register(RunnerRegistration{Address: "10.0.0.1:9190", FnMetrics: []FnMetrics{
+		{FnID: "a", InFlight: 1, Concurrency: 1.5, RPS: 10, P95Millis: 100},
+		{FnID: "b", RPS: 1, P95Millis: 10},
+	}})
+	r.Register(RunnerRegistration{Address: "10.0.0.2:9190", FnMetrics: []FnMetrics{
+		{FnID: "a", InFlight: 2, Concurrency: 0.5, RPS: 5, P95Millis: 250},
+	}})
+
+	metrics := r.FnMetrics()
+	if len(metrics) != 2 {
+		t.Fatalf("Unexpected fn metrics %+v", metrics)
+	}
+	if a := metrics[0]; a.FnID != "a" || a.InFlight != 3 || a.Concurrency != 2 || a.RPS != 15 || a.P95Millis != 250 {
+		t.Fatalf("Unexpected metrics of fn a %+v", a)
+	}
+}
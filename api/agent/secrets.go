@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/secrets"
+)
+
+// secretsDir is the directory of the iofs of a container file secrets are
+// written to, bound to models.SecretsMountPath in the container
+const secretsDir = "secrets"
+
+// SecretSource returns the decrypted secrets of apps, see secrets.NewSource
+type SecretSource interface {
+	GetAppSecrets(ctx context.Context, appID string) ([]secrets.Value, error)
+}
+
+// WithSecretSource injects the secrets of the app of a call into the hot
+// containers started for it. Secrets are read as containers start, so
+// changed secrets apply to new containers.
+func WithSecretSource(src SecretSource) Option {
+	return func(a *agent) error {
+		a.secrets = src
+		return nil
+	}
+}
+
+// injectSecrets resolves the secret references of the config of call, see
+// models.ResolveSecretRefs, adds the env secrets of its app to the environment
+// of c, on top of that config, and writes its file secrets into the iofs of c,
+// which is a tmpfs if enabled and is removed with the container. The call
+// itself never holds secret values.
+func (a *agent) injectSecrets(ctx context.Context, call *call, c *container) error {
+	var values []secrets.Value
+	if a.secrets != nil {
+		var err error
+		values, err = a.secrets.GetAppSecrets(ctx, call.AppID)
+		if err != nil {
+			return err
+		}
+	} else if models.HasSecretRefs(models.Config(c.env)) {
+		return models.ErrSecretsNoKMS
+	}
+
+	byName := make(map[string][]byte, len(values))
+	for _, v := range values {
+		byName[v.Name] = v.Value
+	}
+	env, err := models.ResolveSecretRefs(models.Config(c.env), func(name string) (string, bool) {
+		v, ok := byName[name]
+		return string(v), ok
+	})
+	if err != nil {
+		return err
+	}
+
+	var dir string
+	for _, v := range values {
+		if v.Mount != models.SecretMountFile {
+			env[v.Name] = string(v.Value)
+			continue
+		}
+		if dir == "" {
+			if c.iofs.AgentPath() == "" {
+				return fmt.Errorf("cannot mount secret %s without an iofs directory", v.Name)
+			}
+			dir = filepath.Join(c.iofs.AgentPath(), secretsDir)
+			if err := os.Mkdir(dir, 0755); err != nil {
+				return err
+			}
+			c.volumes = append(c.volumes, [2]string{filepath.Join(c.iofs.DockerPath(), secretsDir), models.SecretsMountPath})
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, v.Name), v.Value, 0444); err != nil {
+			return err
+		}
+	}
+	c.env = env
+	return nil
+}
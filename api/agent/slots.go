@@ -231,18 +231,18 @@ func (a *slotQueue) exitRequestState(reqType RequestStateType) {
 	}
 }
 
-func (a *slotQueue) enterContainerState(conType ContainerStateType) {
+func (a *slotQueue) enterContainerState(conType ContainerStateType, weight uint64) {
 	if conType > ContainerStateNone && conType < ContainerStateMax {
 		a.statsLock.Lock()
-		a.stats.containerStates[conType] += 1
+		a.stats.containerStates[conType] += weight
 		a.statsLock.Unlock()
 	}
 }
 
-func (a *slotQueue) exitContainerState(conType ContainerStateType) {
+func (a *slotQueue) exitContainerState(conType ContainerStateType, weight uint64) {
 	if conType > ContainerStateNone && conType < ContainerStateMax {
 		a.statsLock.Lock()
-		a.stats.containerStates[conType] -= 1
+		a.stats.containerStates[conType] -= weight
 		a.statsLock.Unlock()
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -307,3 +308,69 @@ func BenchmarkSlotKey(b *testing.B) {
 		_ = getSlotQueueKey(call)
 	}
 }
+
+func TestSlotQueueWeightedContainer(t *testing.T) {
+	ctx := context.Background()
+	obj := NewSlotQueue("weighted")
+	evictor := NewEvictor().CreateEvictToken("weighted", 0, 0, 0)
+
+	state := NewWeightedContainerState(3)
+	state.UpdateState(ctx, ContainerStateWait, obj)
+	state.UpdateState(ctx, ContainerStateStart, obj)
+
+	cur := obj.getStats()
+	if cur.containerStates[ContainerStateStart] != 3 {
+		t.Fatalf("Starting container should count as 3 slots cur: %#v", cur)
+	}
+
+	workers := newContainerWorkers(state, evictor)
+	w1, w2, w3 := workers.newWorker(), workers.newWorker(), workers.newWorker()
+	for _, w := range []*workerState{w1, w2, w3} {
+		w.SetEvictable(true)
+		w.UpdateState(ctx, ContainerStateIdle, obj)
+	}
+
+	cur = obj.getStats()
+	if cur.containerStates[ContainerStateStart] != 0 || cur.containerStates[ContainerStateIdle] != 3 {
+		t.Fatalf("Started container should count as 3 idle slots cur: %#v", cur)
+	}
+	if state.GetState() != "idle" || atomic.LoadUint32(&evictor.evictable) != 1 {
+		t.Fatalf("Container should be idle and evictable state: %s", state.GetState())
+	}
+
+	w1.SetEvictable(false)
+	w1.UpdateState(ctx, ContainerStateBusy, obj)
+
+	cur = obj.getStats()
+	if cur.containerStates[ContainerStateIdle] != 2 || cur.containerStates[ContainerStateBusy] != 1 {
+		t.Fatalf("Container should have 2 idle and 1 busy slots cur: %#v", cur)
+	}
+	if state.GetState() != "busy" || atomic.LoadUint32(&evictor.evictable) != 0 {
+		t.Fatalf("Container should be busy and not evictable state: %s", state.GetState())
+	}
+
+	// 2 idle slots cover 2 queued requests, a third one needs a new container
+	cur.requestStates[RequestStateWait] = 2
+	if isNewContainerNeeded(&cur) {
+		t.Fatalf("Should not need a new container cur: %#v", cur)
+	}
+	cur.requestStates[RequestStateWait] = 3
+	if !isNewContainerNeeded(&cur) {
+		t.Fatalf("Should need a new container cur: %#v", cur)
+	}
+
+	w1.UpdateState(ctx, ContainerStateDone, obj)
+	w1.SetEvictable(true)
+	if state.GetState() != "idle" || atomic.LoadUint32(&evictor.evictable) != 1 {
+		t.Fatalf("Container should be idle and evictable state: %s", state.GetState())
+	}
+
+	w2.UpdateState(ctx, ContainerStateDone, obj)
+	w3.UpdateState(ctx, ContainerStateDone, obj)
+	state.UpdateState(ctx, ContainerStateDone, obj)
+
+	cur = obj.getStats()
+	if cur.containerStates[ContainerStateIdle] != 0 || cur.containerStates[ContainerStateBusy] != 0 {
+		t.Fatalf("All slots should be gone cur: %#v", cur)
+	}
+}
@@ -12,9 +12,10 @@ type RequestStateType int
 type ContainerStateType int
 
 type containerState struct {
-	lock  sync.Mutex
-	state ContainerStateType
-	start time.Time
+	lock   sync.Mutex
+	state  ContainerStateType
+	start  time.Time
+	weight uint64
 }
 
 type requestState struct {
@@ -36,7 +37,15 @@ func NewRequestState() RequestState {
 }
 
 func NewContainerState() ContainerState {
-	return &containerState{}
+	return &containerState{weight: 1}
+}
+
+// NewWeightedContainerState returns a container state for a container serving
+// weight calls concurrently. While waiting or starting, such a container counts
+// as weight slots in the slot queue, once running its slots are tracked by
+// containerWorkers.
+func NewWeightedContainerState(weight uint64) ContainerState {
+	return &containerState{weight: weight}
 }
 
 const (
@@ -119,6 +128,28 @@ func isIdleState(state ContainerStateType) bool {
 	return state == ContainerStateIdle || state == ContainerStatePaused
 }
 
+// isValidTransition reports whether a container may move from oldState to newState.
+// Only the following state transitions are allowed:
+// 1) any move forward in states as per ContainerStateType order
+// 2) move back: from paused to idle
+// 3) move back: from busy to idle/paused
+func isValidTransition(oldState, newState ContainerStateType) bool {
+	return oldState < newState ||
+		(oldState == ContainerStatePaused && newState == ContainerStateIdle) ||
+		(oldState == ContainerStateBusy && isIdleState(newState))
+}
+
+// slotWeight returns the number of slots this container accounts for in state
+func (c *containerState) slotWeight(state ContainerStateType) uint64 {
+	if c.weight <= 1 {
+		return 1
+	}
+	if state == ContainerStateWait || state == ContainerStateStart {
+		return c.weight
+	}
+	return 0
+}
+
 func (c *containerState) GetState() string {
 	var res ContainerStateType
 
@@ -137,13 +168,7 @@ func (c *containerState) UpdateState(ctx context.Context, newState ContainerStat
 
 	c.lock.Lock()
 
-	// Only the following state transitions are allowed:
-	// 1) any move forward in states as per ContainerStateType order
-	// 2) move back: from paused to idle
-	// 3) move back: from busy to idle/paused
-	if c.state < newState ||
-		(c.state == ContainerStatePaused && newState == ContainerStateIdle) ||
-		(c.state == ContainerStateBusy && isIdleState(newState)) {
+	if isValidTransition(c.state, newState) {
 
 		now = time.Now()
 		oldState = c.state
@@ -160,8 +185,8 @@ func (c *containerState) UpdateState(ctx context.Context, newState ContainerStat
 
 	// reflect this change to slot mgr if defined (AKA hot)
 	if slots != nil {
-		slots.enterContainerState(newState)
-		slots.exitContainerState(oldState)
+		slots.enterContainerState(newState, c.slotWeight(newState))
+		slots.exitContainerState(oldState, c.slotWeight(oldState))
 	}
 
 	// update old state stats
@@ -181,3 +206,115 @@ func (c *containerState) UpdateState(ctx context.Context, newState ContainerStat
 		stats.Record(ctx, containerGaugeMeasures[newState].M(1))
 	}
 }
+
+// containerWorkers tracks the workers of a container serving multiple calls
+// concurrently. Each worker reports its own state (and a slot) to the slot
+// queue, while the container state reflects the busiest of its workers.
+// The container is evictable only if all of its workers are.
+type containerWorkers struct {
+	lock      sync.Mutex
+	container ContainerState
+	evictor   *EvictToken
+	states    [ContainerStateMax]uint64
+	live      uint64
+	evictable uint64
+}
+
+type workerState struct {
+	workers   *containerWorkers
+	state     ContainerStateType
+	evictable bool
+}
+
+func newContainerWorkers(container ContainerState, evictor *EvictToken) *containerWorkers {
+	return &containerWorkers{container: container, evictor: evictor}
+}
+
+func (w *containerWorkers) newWorker() *workerState {
+	w.lock.Lock()
+	w.live++
+	w.lock.Unlock()
+	return &workerState{workers: w}
+}
+
+// containerState returns the container state implied by its workers
+func (w *containerWorkers) containerState() ContainerStateType {
+	switch {
+	case w.states[ContainerStateBusy] > 0:
+		return ContainerStateBusy
+	case w.states[ContainerStateIdle] > 0:
+		return ContainerStateIdle
+	case w.states[ContainerStatePaused] > 0:
+		return ContainerStatePaused
+	}
+	return ContainerStateNone
+}
+
+func (s *workerState) GetState() string {
+	s.workers.lock.Lock()
+	res := s.state
+	s.workers.lock.Unlock()
+
+	return containerStateKeys[res]
+}
+
+func (s *workerState) UpdateState(ctx context.Context, newState ContainerStateType, slots *slotQueue) {
+	w := s.workers
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if !isValidTransition(s.state, newState) {
+		return
+	}
+
+	oldState := s.state
+	s.state = newState
+	if oldState != ContainerStateNone {
+		w.states[oldState]--
+	}
+	w.states[newState]++
+
+	if newState == ContainerStateDone {
+		w.live--
+		if s.evictable {
+			s.evictable = false
+			w.evictable--
+		}
+		w.updateEvictable()
+	}
+
+	if slots != nil {
+		slots.enterContainerState(newState, 1)
+		slots.exitContainerState(oldState, 1)
+	}
+
+	// under lock, so that container state changes are applied in order
+	if cs := w.containerState(); cs != ContainerStateNone {
+		w.container.UpdateState(ctx, cs, slots)
+	}
+}
+
+// SetEvictable marks this worker evictable, the container is evictable
+// once all of its live workers are.
+func (s *workerState) SetEvictable(isEvictable bool) {
+	w := s.workers
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if s.state == ContainerStateDone || s.evictable == isEvictable {
+		return
+	}
+	s.evictable = isEvictable
+	if isEvictable {
+		w.evictable++
+	} else {
+		w.evictable--
+	}
+	w.updateEvictable()
+}
+
+func (w *containerWorkers) updateEvictable() {
+	w.evictor.SetEvictable(w.live > 0 && w.evictable == w.live)
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"crypto/tls"
+	"sync"
 
 	pool "github.com/fnproject/fn/api/runnerpool"
 	"github.com/sirupsen/logrus"
@@ -13,7 +14,10 @@ type staticRunnerPool struct {
 	generator pool.MTLSRunnerFactory
 	tlsConf   *tls.Config // can be nil when running in insecure mode
 	runnerCN  string
-	runners   []pool.Runner
+
+	lock    sync.RWMutex
+	runners []pool.Runner
+	closes  sync.WaitGroup
 }
 
 func DefaultStaticRunnerPool(runnerAddresses []string) pool.RunnerPool {
@@ -23,15 +27,17 @@ func DefaultStaticRunnerPool(runnerAddresses []string) pool.RunnerPool {
 func NewStaticRunnerPool(runnerAddresses []string, tlsConf *tls.Config, runnerFactory pool.MTLSRunnerFactory) pool.RunnerPool {
 	logrus.WithField("runners", runnerAddresses).Info("Starting static runner pool")
 	var runners []pool.Runner
-	for _, addr := range runnerAddresses {
+	for _, entry := range runnerAddresses {
+		addr, zone := pool.ParseRunnerAddress(entry)
 		r, err := runnerFactory(addr, tlsConf)
 		if err != nil {
 			logrus.WithError(err).WithField("runner_addr", addr).Warn("Invalid runner")
 			continue
 		}
-		logrus.WithField("runner_addr", addr).Debug("Adding runner to pool")
-		runners = append(runners, r)
+		logrus.WithFields(logrus.Fields{"runner_addr": addr, "zone": zone}).Debug("Adding runner to pool")
+		runners = append(runners, pool.WithZone(r, zone))
 	}
+	pool.StatsRunnerMembers(context.Background(), runners, nil)
 	return &staticRunnerPool{
 		runners:   runners,
 		tlsConf:   tlsConf,
@@ -40,14 +46,33 @@ func NewStaticRunnerPool(runnerAddresses []string, tlsConf *tls.Config, runnerFa
 }
 
 func (rp *staticRunnerPool) Runners(ctx context.Context, call pool.RunnerCall) ([]pool.Runner, error) {
+	rp.lock.RLock()
+	defer rp.lock.RUnlock()
+
 	r := make([]pool.Runner, len(rp.runners))
 	copy(r, rp.runners)
 	return r, nil
 }
 
+// SetRunners implements pool.RunnerSetter, runners keep the order of
+// runnerAddresses as they do when the pool is created
+func (rp *staticRunnerPool) SetRunners(ctx context.Context, runnerAddresses []string) {
+	rp.lock.Lock()
+	runners, removed := syncRunners(rp.runners, runnerAddresses, rp.generator, rp.tlsConf)
+	rp.runners = runners
+	rp.lock.Unlock()
+
+	closeRemoved(ctx, runners, removed, &rp.closes)
+}
+
 func (rp *staticRunnerPool) Shutdown(ctx context.Context) error {
+	rp.lock.Lock()
+	runners := rp.runners
+	rp.runners = nil
+	rp.lock.Unlock()
+
 	var retErr error
-	for _, r := range rp.runners {
+	for _, r := range runners {
 		err := r.Close(ctx)
 		if err != nil {
 			logrus.WithError(err).WithField("runner_addr", r.Address()).Error("Error closing runner")
@@ -57,5 +82,6 @@ func (rp *staticRunnerPool) Shutdown(ctx context.Context) error {
 			}
 		}
 	}
+	rp.closes.Wait()
 	return retErr
 }
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"testing"
+	"time"
 
 	pool "github.com/fnproject/fn/api/runnerpool"
 )
@@ -75,3 +76,46 @@ func TestEmptyPool(t *testing.T) {
 		t.Fatalf("Unexpected error from shutdown %v", err)
 	}
 }
+
+func TestGRPCRunnerFactoryConfig(t *testing.T) {
+	cfg := NewRunnerConnConfig()
+	if opts := cfg.dialOptions(); len(opts) != 0 {
+		t.Fatalf("Expected the gRPC defaults, got %d dial options", len(opts))
+	}
+
+	cfg.KeepaliveTime = time.Second
+	cfg.InitialWindowSize = 1 << 20
+	cfg.InitialConnWindowSize = 1 << 22
+	if opts := cfg.dialOptions(); len(opts) != 3 {
+		t.Fatalf("Expected keepalive and window dial options, got %d", len(opts))
+	}
+
+	// connections are made in the background, runners are created right away
+	np := NewStaticRunnerPool([]string{"127.0.0.1:9190"}, nil, NewGRPCRunnerFactory(cfg))
+	if addrs := runnerAddresses(t, np); len(addrs) != 1 || addrs[0] != "127.0.0.1:9190" {
+		t.Fatalf("Unexpected runners %v", addrs)
+	}
+	if err := np.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error from shutdown %v", err)
+	}
+}
+
+func TestStaticPoolSetRunners(t *testing.T) {
+	np := setupStaticPool([]string{"127.0.0.1:8080", "127.0.0.1:8081"})
+	runners, _ := np.Runners(context.Background(), nil)
+	kept := runners[1]
+
+	np.(pool.RunnerSetter).SetRunners(context.Background(), []string{"127.0.0.1:8082", "127.0.0.1:8081"})
+	if addrs := runnerAddresses(t, np); len(addrs) != 2 || addrs[0] != "127.0.0.1:8082" || addrs[1] != "127.0.0.1:8081" {
+		t.Fatalf("Unexpected runners %v", addrs)
+	}
+	runners, _ = np.Runners(context.Background(), nil)
+	if runners[1] != kept {
+		t.Fatal("Expected the runner still listed to be kept")
+	}
+
+	err := np.Shutdown(context.Background())
+	if err != ErrorGarbanzoBeans {
+		t.Fatalf("Expected garbanzo beans error from shutdown %v", err)
+	}
+}
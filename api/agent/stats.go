@@ -17,8 +17,25 @@ var (
 	containerStateKey    = common.MakeKey("container_state")
 	callStatusKey        = common.MakeKey("call_status")
 	containerUDSStateKey = common.MakeKey("container_uds_state")
+	priorityClassKey     = common.MakeKey("priority_class")
+	cacheKindKey         = common.MakeKey("cache_kind")
+	namespaceIDKey       = common.MakeKey("namespace_id")
+	callPhaseKey         = common.MakeKey("call_phase")
 )
 
+// withNamespaceTag tags the stats recorded with ctx with the namespace of the
+// app a call belongs to, views may then break calls down by tenant
+func withNamespaceTag(ctx context.Context, nsID string) context.Context {
+	if nsID == "" {
+		return ctx
+	}
+	ctx, err := tag.New(ctx, tag.Upsert(namespaceIDKey, nsID))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return ctx
+}
+
 func statsCalls(ctx context.Context) {
 	stats.Record(ctx, callsMeasure.M(1))
 }
@@ -94,6 +111,40 @@ func statsContainerEvicted(ctx context.Context, containerState string) {
 	stats.Record(ctx, containerEvictedMeasure.M(0))
 }
 
+func statsCallLogTruncated(ctx context.Context) {
+	stats.Record(ctx, callLogTruncatedMeasure.M(0))
+}
+
+func statsAdmissionWait(ctx context.Context, dur time.Duration, class string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(priorityClassKey, class),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, admissionWaitMeasure.M(int64(dur/time.Millisecond)))
+}
+
+func statsReadCacheHit(ctx context.Context, kind string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(cacheKindKey, kind),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, readCacheHitsMeasure.M(1))
+}
+
+func statsReadCacheMiss(ctx context.Context, kind string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(cacheKindKey, kind),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, readCacheMissesMeasure.M(1))
+}
+
 func statsUtilization(ctx context.Context, util ResourceUtilization) {
 	stats.Record(ctx, utilCpuUsedMeasure.M(int64(util.CpuUsed)))
 	stats.Record(ctx, utilCpuAvailMeasure.M(int64(util.CpuAvail)))
@@ -101,6 +152,16 @@ func statsUtilization(ctx context.Context, util ResourceUtilization) {
 	stats.Record(ctx, utilMemAvailMeasure.M(int64(util.MemAvail)))
 }
 
+func statsCallPhaseLatency(ctx context.Context, phase string, dur time.Duration) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(callPhaseKey, phase),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, callPhaseLatencyMeasure.M(int64(dur/time.Millisecond)))
+}
+
 func statsCallLatency(ctx context.Context, dur time.Duration, callStatus string) {
 	ctx, err := tag.New(ctx,
 		tag.Upsert(callStatusKey, callStatus),
@@ -146,8 +207,19 @@ const (
 	errorsMetricName     = "errors"
 	serverBusyMetricName = "server_busy"
 
+	admissionWaitMetricName = "admission_wait_latency"
+
+	// time calls spent in each phase, see models.CallTimings. The phases
+	// a node records depend on its role, lb nodes record queue and placement
+	// waits only, runners the phases they observe themselves.
+	callPhaseLatencyMetricName = "call_phase_latency"
+
+	readCacheHitsMetricName   = "read_cache_hits"
+	readCacheMissesMetricName = "read_cache_misses"
+
 	containerEvictedMetricName        = "container_evictions"
 	containerUDSInitLatencyMetricName = "container_uds_init_latency"
+	callLogTruncatedMetricName        = "call_log_truncated"
 
 	utilCpuUsedMetricName  = "util_cpu_used"
 	utilCpuAvailMetricName = "util_cpu_avail"
@@ -161,18 +233,24 @@ const (
 )
 
 var (
-	queuedMeasure          = common.MakeMeasure(queuedMetricName, "calls currently queued against agent", "")
-	callsMeasure           = common.MakeMeasure(callsMetricName, "calls created in agent", "")
-	runningMeasure         = common.MakeMeasure(runningMetricName, "calls currently running in agent", "")
-	completedMeasure       = common.MakeMeasure(completedMetricName, "calls completed in agent", "")
-	canceledMeasure        = common.MakeMeasure(canceledMetricName, "calls canceled in agent", "")
-	timedoutMeasure        = common.MakeMeasure(timedoutMetricName, "calls timed out in agent", "")
-	errorsMeasure          = common.MakeMeasure(errorsMetricName, "calls errored in agent", "")
-	serverBusyMeasure      = common.MakeMeasure(serverBusyMetricName, "calls where server was too busy in agent", "")
+	queuedMeasure     = common.MakeMeasure(queuedMetricName, "calls currently queued against agent", "")
+	callsMeasure      = common.MakeMeasure(callsMetricName, "calls created in agent", "")
+	runningMeasure    = common.MakeMeasure(runningMetricName, "calls currently running in agent", "")
+	completedMeasure  = common.MakeMeasure(completedMetricName, "calls completed in agent", "")
+	canceledMeasure   = common.MakeMeasure(canceledMetricName, "calls canceled in agent", "")
+	timedoutMeasure   = common.MakeMeasure(timedoutMetricName, "calls timed out in agent", "")
+	errorsMeasure     = common.MakeMeasure(errorsMetricName, "calls errored in agent", "")
+	serverBusyMeasure = common.MakeMeasure(serverBusyMetricName, "calls where server was too busy in agent", "")
+
+	admissionWaitMeasure   = common.MakeMeasure(admissionWaitMetricName, "time calls waited for admission in agent", "msecs")
+	readCacheHitsMeasure   = common.MakeMeasure(readCacheHitsMetricName, "app, fn and trigger reads served from cache", "")
+	readCacheMissesMeasure = common.MakeMeasure(readCacheMissesMetricName, "app, fn and trigger reads from the datastore", "")
 	dockerMeasures         = initDockerMeasures()
 	containerGaugeMeasures = initContainerGaugeMeasures()
 	containerTimeMeasures  = initContainerTimeMeasures()
 
+	callPhaseLatencyMeasure = common.MakeMeasure(callPhaseLatencyMetricName, "time calls spent in each phase in agent", "msecs")
+
 	utilCpuUsedMeasure  = common.MakeMeasure(utilCpuUsedMetricName, "agent cpu in use", "")
 	utilCpuAvailMeasure = common.MakeMeasure(utilCpuAvailMetricName, "agent cpu available", "")
 	utilMemUsedMeasure  = common.MakeMeasure(utilMemUsedMetricName, "agent memory in use", "By")
@@ -180,6 +258,7 @@ var (
 
 	containerEvictedMeasure        = common.MakeMeasure(containerEvictedMetricName, "containers evicted", "")
 	containerUDSInitLatencyMeasure = common.MakeMeasure(containerUDSInitLatencyMetricName, "container UDS Init-Wait Latency", "msecs")
+	callLogTruncatedMeasure        = common.MakeMeasure(callLogTruncatedMetricName, "calls with truncated logs", "")
 
 	// Reported By LB: How long does a runner scheduler wait for a committed call? eg. wait/launch/pull containers
 	runnerSchedLatencyMeasure = common.MakeMeasure(runnerSchedLatencyMetricName, "Runner Scheduler Latency Reported By LBAgent", "msecs")
@@ -203,14 +282,47 @@ func RegisterLBAgentViews(tagKeys []string, latencyDist []float64) {
 		common.CreateView(runnerSchedLatencyMeasure, view.Distribution(latencyDist...), tagKeys),
 		common.CreateView(runnerExecLatencyMeasure, view.Distribution(latencyDist...), tagKeys),
 		common.CreateView(callLatencyMeasure, view.Distribution(latencyDist...), callLatencyTags),
+		callPhaseLatencyView(tagKeys, latencyDist),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
 	}
 }
 
+// callPhaseLatencyView is the histogram of call phases, registered by both
+// agents so nodes of either role report it
+func callPhaseLatencyView(tagKeys []string, latencyDist []float64) *view.View {
+	// add call_phase tag for phase latency
+	phaseTags := make([]string, 0, len(tagKeys)+1)
+	phaseTags = append(phaseTags, "call_phase")
+	for _, key := range tagKeys {
+		if key != "call_phase" {
+			phaseTags = append(phaseTags, key)
+		}
+	}
+	return common.CreateView(callPhaseLatencyMeasure, view.Distribution(latencyDist...), phaseTags)
+}
+
 // RegisterAgentViews creates and registers all agent views
 func RegisterAgentViews(tagKeys []string, latencyDist []float64) {
+	// add priority_class tag for admission wait
+	admissionTags := make([]string, 0, len(tagKeys)+1)
+	admissionTags = append(admissionTags, "priority_class")
+	for _, key := range tagKeys {
+		if key != "priority_class" {
+			admissionTags = append(admissionTags, key)
+		}
+	}
+
+	// add cache_kind tag for read cache hits and misses
+	cacheTags := make([]string, 0, len(tagKeys)+1)
+	cacheTags = append(cacheTags, "cache_kind")
+	for _, key := range tagKeys {
+		if key != "cache_kind" {
+			cacheTags = append(cacheTags, key)
+		}
+	}
+
 	err := view.Register(
 		common.CreateView(queuedMeasure, view.Sum(), tagKeys),
 		common.CreateView(callsMeasure, view.Sum(), tagKeys),
@@ -220,6 +332,10 @@ func RegisterAgentViews(tagKeys []string, latencyDist []float64) {
 		common.CreateView(timedoutMeasure, view.Sum(), tagKeys),
 		common.CreateView(errorsMeasure, view.Sum(), tagKeys),
 		common.CreateView(serverBusyMeasure, view.Sum(), tagKeys),
+		common.CreateView(admissionWaitMeasure, view.Distribution(latencyDist...), admissionTags),
+		callPhaseLatencyView(tagKeys, latencyDist),
+		common.CreateView(readCacheHitsMeasure, view.Count(), cacheTags),
+		common.CreateView(readCacheMissesMeasure, view.Count(), cacheTags),
 		common.CreateView(utilCpuUsedMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilCpuAvailMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilMemUsedMeasure, view.LastValue(), tagKeys),
@@ -302,6 +418,7 @@ func RegisterContainerViews(tagKeys []string, latencyDist []float64) {
 	err := view.Register(
 		common.CreateView(containerEvictedMeasure, view.Count(), evictTags),
 		common.CreateView(containerUDSInitLatencyMeasure, view.Distribution(latencyDist...), udsInitTags),
+		common.CreateView(callLogTruncatedMeasure, view.Count(), tagKeys),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// volumesPoll is the interval at which the usage of volumes is measured and
+// idle volumes are removed
+const volumesPoll = time.Minute
+
+// volumeManager mounts the persistent volumes of apps, see
+// models.AppVolumesAnnotation, as the directories dir/<app id>/<name>. It
+// measures their usage against their quota and removes the ones no container
+// used for longer than their ttl. Without dir, volumes are the docker volumes
+// fn_<app id>_<name>, which docker creates when they are first mounted.
+type volumeManager struct {
+	dir string
+
+	lock sync.Mutex
+	// volumes by host path
+	volumes map[string]*volumeState
+	// host paths of the volumes mounted into each container
+	mounts map[string][]string
+}
+
+type volumeState struct {
+	spec      models.Volume
+	refs      int
+	lastUsed  time.Time
+	overQuota bool
+}
+
+func newVolumeManager(dir string) *volumeManager {
+	return &volumeManager{
+		dir:     dir,
+		volumes: make(map[string]*volumeState),
+		mounts:  make(map[string][]string),
+	}
+}
+
+// mount returns the mounts of volumes into the container id of the app appID,
+// as host path and container path pairs. Volumes over their quota are mounted
+// read-only.
+func (m *volumeManager) mount(id, appID string, volumes []models.Volume) ([][2]string, error) {
+	mounts := make([][2]string, 0, len(volumes))
+	if m.dir == "" {
+		for _, v := range volumes {
+			mounts = append(mounts, [2]string{fmt.Sprintf("fn_%s_%s", appID, v.Name), path.Clean(v.Path)})
+		}
+		return mounts, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, v := range volumes {
+		host := filepath.Join(m.dir, appID, v.Name)
+		if err := os.MkdirAll(host, 0755); err != nil {
+			m.releaseLocked(id)
+			return nil, err
+		}
+
+		state, ok := m.volumes[host]
+		if !ok {
+			state = &volumeState{}
+			m.volumes[host] = state
+		}
+		state.spec = v
+		state.refs++
+		m.mounts[id] = append(m.mounts[id], host)
+
+		dest := path.Clean(v.Path)
+		if state.overQuota {
+			dest += ":ro"
+		}
+		mounts = append(mounts, [2]string{host, dest})
+	}
+	return mounts, nil
+}
+
+// release releases the volumes mounted into the container id
+func (m *volumeManager) release(id string) {
+	m.lock.Lock()
+	m.releaseLocked(id)
+	m.lock.Unlock()
+}
+
+func (m *volumeManager) releaseLocked(id string) {
+	now := time.Now()
+	for _, host := range m.mounts[id] {
+		if state, ok := m.volumes[host]; ok {
+			state.refs--
+			state.lastUsed = now
+		}
+	}
+	delete(m.mounts, id)
+}
+
+// sweep removes the volumes unused for longer than their ttl, then measures
+// the usage of the volumes with a quota
+func (m *volumeManager) sweep(now time.Time) {
+	if m.dir == "" {
+		return
+	}
+
+	quotas := make(map[string]uint64)
+
+	m.lock.Lock()
+	for host, state := range m.volumes {
+		ttl := time.Duration(state.spec.TTL) * time.Second
+		if state.refs == 0 && ttl > 0 && now.Sub(state.lastUsed) > ttl {
+			// under lock, so that the volume is not mounted again meanwhile
+			if err := os.RemoveAll(host); err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"volume": host}).Error("error removing idle volume")
+				continue
+			}
+			delete(m.volumes, host)
+			continue
+		}
+		if state.spec.Size > 0 {
+			quotas[host] = state.spec.Size * 1024 * 1024
+		}
+	}
+	m.lock.Unlock()
+
+	for host, quota := range quotas {
+		usage, err := dirSize(host)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"volume": host}).Error("error measuring volume usage")
+			continue
+		}
+		over := usage > quota
+		m.lock.Lock()
+		if state, ok := m.volumes[host]; ok {
+			if over && !state.overQuota {
+				logrus.WithFields(logrus.Fields{"volume": host, "usage": usage, "quota": quota}).Warn("volume over quota, mounting it read-only")
+			}
+			state.overQuota = over
+		}
+		m.lock.Unlock()
+	}
+}
+
+// dirSize returns the size of the files of a directory tree
+func dirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size, err
+}
+
+// sweepVolumes sweeps volumes every volumesPoll until the agent shuts down
+func (a *agent) sweepVolumes() {
+	defer a.shutWg.DoneSession()
+
+	ticker := time.NewTicker(volumesPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			a.volumes.sweep(now)
+		case <-a.shutWg.Closer(): // server shutdown
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestVolumeManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-volumes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := newVolumeManager(dir)
+	volumes := []models.Volume{{Name: "cache", Path: "/data/cache/", Size: 1, TTL: 60}, {Name: "state", Path: "/state"}}
+
+	mounts, err := m.mount("c1", "app", volumes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(dir, "app", "cache")
+	if len(mounts) != 2 || mounts[0] != [2]string{cache, "/data/cache"} {
+		t.Fatalf("unexpected mounts %v", mounts)
+	}
+
+	// over quota volumes are mounted read-only
+	if err := ioutil.WriteFile(filepath.Join(cache, "blob"), make([]byte, 2<<20), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.sweep(time.Now())
+	mounts, err = m.mount("c2", "app", volumes)
+	if err != nil || mounts[0][1] != "/data/cache:ro" || mounts[1][1] != "/state" {
+		t.Fatalf("expected the volume over quota to be read-only, got %v %v", mounts, err)
+	}
+
+	// volumes in use or without ttl are kept
+	m.release("c1")
+	m.sweep(time.Now().Add(time.Hour))
+	if _, err := os.Stat(cache); err != nil {
+		t.Fatal("expected a volume in use to be kept")
+	}
+	m.release("c2")
+	m.sweep(time.Now().Add(30 * time.Second))
+	if _, err := os.Stat(cache); err != nil {
+		t.Fatal("expected a volume idle for less than its ttl to be kept")
+	}
+	m.sweep(time.Now().Add(time.Hour))
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Fatal("expected an idle volume to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app", "state")); err != nil {
+		t.Fatal("expected a volume without ttl to be kept")
+	}
+}
+
+func TestVolumeManagerDocker(t *testing.T) {
+	m := newVolumeManager("")
+	mounts, err := m.mount("c1", "app", []models.Volume{{Name: "cache", Path: "/cache"}})
+	if err != nil || mounts[0] != [2]string{"fn_app_cache", "/cache"} {
+		t.Fatalf("expected a docker volume, got %v %v", mounts, err)
+	}
+}
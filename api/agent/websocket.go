@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// InvokeWebSocket makes a call proxy the WebSocket connection its request
+// upgrades to to a hot container. The container gets the upgrade request on
+// /call and completes the handshake itself, frames are then copied both ways
+// until either side closes the connection or the call times out. The writer
+// of the call must be an http.Hijacker.
+func InvokeWebSocket() CallOpt {
+	return func(c *call) error {
+		c.webSocket = true
+		return nil
+	}
+}
+
+// dispatchWebSocket upgrades a connection to the container and splices it to
+// the hijacked connection of the client. The client only gets a response if
+// the container accepted the upgrade.
+func (s *hotSlot) dispatchWebSocket(ctx context.Context, call *call) error {
+	hijacker, ok := call.respWriter.(http.Hijacker)
+	if !ok {
+		return models.ErrWebSocketUnsupported
+	}
+
+	req := createUDSRequest(ctx, call)
+	req.Method = http.MethodGet
+	req.Body = nil
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	resp, err := s.container.udsClient.Do(req)
+	if err != nil {
+		// IMPORTANT: Container contract: If http-uds errors/timeout, container cannot continue
+		s.trySetError(err)
+		if ctx.Err() == context.DeadlineExceeded {
+			return context.DeadlineExceeded
+		}
+		return models.ErrFunctionResponse
+	}
+	upstream, ok := resp.Body.(io.ReadWriteCloser)
+	if resp.StatusCode != http.StatusSwitchingProtocols || !ok {
+		resp.Body.Close()
+		return models.ErrFunctionWebSocketUnsupported
+	}
+	defer upstream.Close()
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\n")
+	resp.Header.Set("Fn-Call-Id", call.ID)
+	resp.Header.Write(buf)
+	buf.WriteString("\r\n")
+	if err := buf.Flush(); err != nil {
+		return nil // the client went away, there is nobody to tell
+	}
+
+	spliceWebSocket(ctx, conn, buf.Reader, upstream)
+	common.Logger(ctx).Debug("WebSocket connection closed")
+	return nil
+}
+
+// spliceWebSocket copies frames between the client and the container until
+// either closes its connection or ctx is done, buffered holds what the client
+// sent after its handshake.
+func spliceWebSocket(ctx context.Context, client net.Conn, buffered *bufio.Reader, upstream io.ReadWriteCloser) {
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			client.Close()
+			upstream.Close()
+		})
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, buffered)
+		closeBoth()
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, upstream)
+		closeBoth()
+		done <- struct{}{}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		closeBoth()
+	}
+	<-done
+}
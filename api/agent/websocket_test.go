@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpliceWebSocket(t *testing.T) {
+	client, clientPeer := net.Pipe()
+	upstream, upstreamPeer := net.Pipe()
+
+	// bytes the client sent along with its handshake are forwarded first
+	buffered := bufio.NewReader(io.MultiReader(strings.NewReader("early"), client))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		spliceWebSocket(ctx, client, buffered, upstream)
+		close(done)
+	}()
+
+	go clientPeer.Write([]byte("hello"))
+	b := make([]byte, 10)
+	n, _ := io.ReadFull(upstreamPeer, b)
+	if string(b[:n]) != "earlyhello" {
+		t.Fatalf("expected the container to get the frames of the client, got %q", b[:n])
+	}
+
+	go upstreamPeer.Write([]byte("world"))
+	n, _ = io.ReadFull(clientPeer, b[:5])
+	if string(b[:n]) != "world" {
+		t.Fatalf("expected the client to get the frames of the container, got %q", b[:n])
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the connections to be closed once the call is done")
+	}
+	if _, err := clientPeer.Write([]byte("x")); err == nil {
+		t.Fatal("expected the client connection to be closed")
+	}
+}
@@ -0,0 +1,138 @@
+// Package audit records the creates, updates and deletes of apps, fns,
+// triggers and secrets made through the datastore of a server, with who made
+// them and the objects before and after, to a Sink.
+//
+// Removing an app also removes its fns, triggers and secrets, and removing a
+// fn its triggers, which are not recorded on their own.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// Sink receives the audit records of a server
+type Sink interface {
+	Write(ctx context.Context, record *models.AuditRecord) error
+}
+
+// New returns the sink of a url, one of:
+//
+//	logstore                        the audit table of the log store, see models.AuditStore
+//	file:///var/log/fn/audit.log    a file records are appended to as JSON lines
+//	https://example.com/audit       an endpoint each record is posted to as JSON
+//
+// Only the log store sink can be queried through the API.
+func New(ctx context.Context, sinkURL string, ls models.LogStore) (Sink, error) {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, fmt.Errorf("audit: bad sink url: %v", err)
+	}
+	switch {
+	case sinkURL == "logstore":
+		as, ok := ls.(models.AuditStore)
+		if !ok {
+			return nil, models.ErrAuditUnsupported
+		}
+		return NewStoreSink(as), nil
+	case u.Scheme == "file":
+		return NewFileSink(u.Path)
+	case u.Scheme == "http" || u.Scheme == "https":
+		return NewWebhookSink(sinkURL), nil
+	}
+	return nil, fmt.Errorf("audit: no sink available for url %q", sinkURL)
+}
+
+// NewStoreSink returns a sink inserting records into as
+func NewStoreSink(as models.AuditStore) Sink {
+	return &storeSink{as}
+}
+
+type storeSink struct {
+	as models.AuditStore
+}
+
+func (s *storeSink) Write(ctx context.Context, record *models.AuditRecord) error {
+	return s.as.InsertAuditRecord(ctx, record)
+}
+
+// NewFileSink returns a sink appending records to the file at path as JSON
+// lines, creating it if needed.
+func NewFileSink(path string) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{f: f}, nil
+}
+
+type fileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func (s *fileSink) Write(ctx context.Context, record *models.AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(b)
+	return err
+}
+
+// NewWebhookSink returns a sink posting each record as JSON to url, which
+// must reply with a 2xx status.
+func NewWebhookSink(url string) Sink {
+	return &webhookSink{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *webhookSink) Write(ctx context.Context, record *models.AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("audit webhook %s returned %d: %s", s.url, resp.StatusCode, msg)
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/fnproject/fn/api/auth"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+// Wrap returns a Datastore recording the changes made through ds to sink.
+// Changes are recorded once ds made them, failing to record them is logged
+// and does not fail the change.
+func Wrap(ds models.Datastore, sink Sink) models.Datastore {
+	return &auditds{Datastore: ds, sink: sink}
+}
+
+type auditds struct {
+	models.Datastore
+	sink Sink
+}
+
+// record writes the record of a change of an object, before or after are nil
+// for creates and deletes, or if they could not be read
+func (a *auditds) record(ctx context.Context, kind, op, objectID, appID string, before, after interface{}) {
+	r := &models.AuditRecord{
+		ID:        id.New().String(),
+		Kind:      kind,
+		Op:        op,
+		ObjectID:  objectID,
+		AppID:     appID,
+		CreatedAt: common.DateTime(time.Now()),
+	}
+	if identity := auth.IdentityFromContext(ctx); identity != nil {
+		r.Subject = identity.Subject
+	}
+
+	log := common.Logger(ctx).WithField("audit_kind", kind).WithField("audit_object_id", objectID)
+	if err := r.SetObjects(before, after); err != nil {
+		log.WithError(err).Error("Could not record change to the audit log")
+		return
+	}
+	if err := a.sink.Write(ctx, r); err != nil {
+		log.WithError(err).Error("Could not record change to the audit log")
+	}
+}
+
+func (a *auditds) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	app, err := a.Datastore.InsertApp(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ChangeKindApp, models.ChangeOpCreate, app.ID, app.ID, nil, app)
+	return app, nil
+}
+
+func (a *auditds) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	var before *models.App
+	if app != nil {
+		before, _ = a.Datastore.GetAppByID(ctx, app.ID)
+	}
+	app, err := a.Datastore.UpdateApp(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ChangeKindApp, models.ChangeOpUpdate, app.ID, app.ID, before, app)
+	return app, nil
+}
+
+func (a *auditds) RemoveApp(ctx context.Context, appID string) error {
+	before, _ := a.Datastore.GetAppByID(ctx, appID)
+	if err := a.Datastore.RemoveApp(ctx, appID); err != nil {
+		return err
+	}
+	a.record(ctx, models.ChangeKindApp, models.ChangeOpDelete, appID, appID, before, nil)
+	return nil
+}
+
+func (a *auditds) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	fn, err := a.Datastore.InsertFn(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ChangeKindFn, models.ChangeOpCreate, fn.ID, fn.AppID, nil, fn)
+	return fn, nil
+}
+
+func (a *auditds) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	var before *models.Fn
+	if fn != nil {
+		before, _ = a.Datastore.GetFnByID(ctx, fn.ID)
+	}
+	fn, err := a.Datastore.UpdateFn(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ChangeKindFn, models.ChangeOpUpdate, fn.ID, fn.AppID, before, fn)
+	return fn, nil
+}
+
+func (a *auditds) RemoveFn(ctx context.Context, fnID string) error {
+	before, err := a.Datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		return a.Datastore.RemoveFn(ctx, fnID)
+	}
+	if err := a.Datastore.RemoveFn(ctx, fnID); err != nil {
+		return err
+	}
+	a.record(ctx, models.ChangeKindFn, models.ChangeOpDelete, fnID, before.AppID, before, nil)
+	return nil
+}
+
+func (a *auditds) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	trigger, err := a.Datastore.InsertTrigger(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ChangeKindTrigger, models.ChangeOpCreate, trigger.ID, trigger.AppID, nil, trigger)
+	return trigger, nil
+}
+
+func (a *auditds) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	var before *models.Trigger
+	if trigger != nil {
+		before, _ = a.Datastore.GetTriggerByID(ctx, trigger.ID)
+	}
+	trigger, err := a.Datastore.UpdateTrigger(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ChangeKindTrigger, models.ChangeOpUpdate, trigger.ID, trigger.AppID, before, trigger)
+	return trigger, nil
+}
+
+func (a *auditds) RemoveTrigger(ctx context.Context, triggerID string) error {
+	before, err := a.Datastore.GetTriggerByID(ctx, triggerID)
+	if err != nil {
+		return a.Datastore.RemoveTrigger(ctx, triggerID)
+	}
+	if err := a.Datastore.RemoveTrigger(ctx, triggerID); err != nil {
+		return err
+	}
+	a.record(ctx, models.ChangeKindTrigger, models.ChangeOpDelete, triggerID, before.AppID, before, nil)
+	return nil
+}
+
+// secrets are recorded without their values, as returned by the API
+
+func (a *auditds) secrets() (models.SecretStore, error) {
+	ss, ok := a.Datastore.(models.SecretStore)
+	if !ok {
+		return nil, models.ErrSecretsUnsupported
+	}
+	return ss, nil
+}
+
+func (a *auditds) getSecret(ctx context.Context, ss models.SecretStore, appID, name string) *models.Secret {
+	secrets, err := ss.GetSecrets(ctx, appID)
+	if err != nil {
+		return nil
+	}
+	for _, s := range secrets {
+		if s.Name == name {
+			return secretMetadata(s)
+		}
+	}
+	return nil
+}
+
+func secretMetadata(s *models.StoredSecret) *models.Secret {
+	return &models.Secret{Name: s.Name, Mount: s.Mount, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt}
+}
+
+func (a *auditds) PutSecret(ctx context.Context, secret *models.StoredSecret) (*models.StoredSecret, error) {
+	ss, err := a.secrets()
+	if err != nil {
+		return nil, err
+	}
+	before := a.getSecret(ctx, ss, secret.AppID, secret.Name)
+	stored, err := ss.PutSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+	op := models.ChangeOpUpdate
+	if before == nil {
+		op = models.ChangeOpCreate
+	}
+	a.record(ctx, models.AuditKindSecret, op, stored.Name, stored.AppID, before, secretMetadata(stored))
+	return stored, nil
+}
+
+func (a *auditds) GetSecrets(ctx context.Context, appID string) ([]*models.StoredSecret, error) {
+	ss, err := a.secrets()
+	if err != nil {
+		return nil, err
+	}
+	return ss.GetSecrets(ctx, appID)
+}
+
+func (a *auditds) RemoveSecret(ctx context.Context, appID, name string) error {
+	ss, err := a.secrets()
+	if err != nil {
+		return err
+	}
+	before := a.getSecret(ctx, ss, appID, name)
+	if err := ss.RemoveSecret(ctx, appID, name); err != nil {
+		return err
+	}
+	a.record(ctx, models.AuditKindSecret, models.ChangeOpDelete, name, appID, before, nil)
+	return nil
+}
+
+// the other optional stores of the wrapped Datastore are passed through
+
+func (a *auditds) Changes(ctx context.Context) (<-chan *models.Change, error) {
+	cf, ok := a.Datastore.(models.ChangeFeed)
+	if !ok {
+		return nil, models.ErrChangeFeedUnsupported
+	}
+	return cf.Changes(ctx)
+}
+
+func (a *auditds) InsertNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, ok := a.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss.InsertNamespace(ctx, ns)
+}
+
+func (a *auditds) UpdateNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, ok := a.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss.UpdateNamespace(ctx, ns)
+}
+
+func (a *auditds) GetNamespaceByID(ctx context.Context, nsID string) (*models.Namespace, error) {
+	nss, ok := a.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss.GetNamespaceByID(ctx, nsID)
+}
+
+func (a *auditds) GetNamespaces(ctx context.Context, filter *models.NamespaceFilter) (*models.NamespaceList, error) {
+	nss, ok := a.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss.GetNamespaces(ctx, filter)
+}
+
+func (a *auditds) RemoveNamespace(ctx context.Context, nsID string) error {
+	nss, ok := a.Datastore.(models.NamespaceStore)
+	if !ok {
+		return models.ErrNamespacesUnsupported
+	}
+	return nss.RemoveNamespace(ctx, nsID)
+}
+
+func (a *auditds) InsertFnRevision(ctx context.Context, rev *models.FnRevision) (*models.FnRevision, error) {
+	rs, ok := a.Datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs.InsertFnRevision(ctx, rev)
+}
+
+func (a *auditds) GetFnRevisions(ctx context.Context, fnID string) ([]*models.FnRevision, error) {
+	rs, ok := a.Datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs.GetFnRevisions(ctx, fnID)
+}
+
+func (a *auditds) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	rs, ok := a.Datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs.GetFnRevision(ctx, fnID, revisionID)
+}
+
+func (a *auditds) InsertRoleBinding(ctx context.Context, rb *models.RoleBinding) (*models.RoleBinding, error) {
+	rbs, ok := a.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs.InsertRoleBinding(ctx, rb)
+}
+
+func (a *auditds) GetRoleBindingByID(ctx context.Context, rbID string) (*models.RoleBinding, error) {
+	rbs, ok := a.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs.GetRoleBindingByID(ctx, rbID)
+}
+
+func (a *auditds) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	rbs, ok := a.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs.GetRoleBindings(ctx, filter)
+}
+
+func (a *auditds) RemoveRoleBinding(ctx context.Context, rbID string) error {
+	rbs, ok := a.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return models.ErrRoleBindingsUnsupported
+	}
+	return rbs.RemoveRoleBinding(ctx, rbID)
+}
+
+func (a *auditds) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ks, ok := a.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks.InsertAPIKey(ctx, key)
+}
+
+func (a *auditds) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	ks, ok := a.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks.GetAPIKeyByID(ctx, keyID)
+}
+
+func (a *auditds) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	ks, ok := a.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks.GetAPIKeys(ctx, filter)
+}
+
+func (a *auditds) RevokeAPIKey(ctx context.Context, keyID string, at common.DateTime) (*models.APIKey, error) {
+	ks, ok := a.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks.RevokeAPIKey(ctx, keyID, at)
+}
+
+func (a *auditds) GetWorkflowState(ctx context.Context, chainID string) (*models.WorkflowState, error) {
+	ws, ok := a.Datastore.(models.WorkflowStateStore)
+	if !ok {
+		return nil, models.ErrWorkflowStatesUnsupported
+	}
+	return ws.GetWorkflowState(ctx, chainID)
+}
+
+func (a *auditds) PutWorkflowState(ctx context.Context, state *models.WorkflowState) (*models.WorkflowState, error) {
+	ws, ok := a.Datastore.(models.WorkflowStateStore)
+	if !ok {
+		return nil, models.ErrWorkflowStatesUnsupported
+	}
+	return ws.PutWorkflowState(ctx, state)
+}
+
+func (a *auditds) RemoveWorkflowState(ctx context.Context, chainID string) error {
+	ws, ok := a.Datastore.(models.WorkflowStateStore)
+	if !ok {
+		return models.ErrWorkflowStatesUnsupported
+	}
+	return ws.RemoveWorkflowState(ctx, chainID)
+}
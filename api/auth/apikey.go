@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+var (
+	errAPIKeyInvalid  = errors.New("auth: invalid API key")
+	errAPIKeyInactive = errors.New("auth: API key revoked or expired")
+)
+
+// APIKeyGetter looks up the API keys of the tokens of requests, see
+// models.APIKeyStore
+type APIKeyGetter interface {
+	GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error)
+}
+
+// NewAPIKeys returns a provider authenticating requests with the bearer token
+// of an API key of keys, see models.APIKey. Secrets are compared in constant
+// time and revoked or expired keys rejected. The subject of the identity is
+// key:<key id>, restricted to the apps and namespace of the key.
+func NewAPIKeys(keys APIKeyGetter) Provider {
+	return &apiKeys{keys: keys, now: time.Now}
+}
+
+type apiKeys struct {
+	keys APIKeyGetter
+	now  func() time.Time
+}
+
+func (p *apiKeys) Authenticate(r *http.Request) (*Identity, error) {
+	keyID, secret, ok := models.ParseAPIKeyToken(bearerToken(r))
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	key, err := p.keys.GetAPIKeyByID(r.Context(), keyID)
+	if err == models.ErrAPIKeysNotFound {
+		return nil, errAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !key.Matches(secret) {
+		return nil, errAPIKeyInvalid
+	}
+	if !key.Active(p.now()) {
+		return nil, errAPIKeyInactive
+	}
+
+	return &Identity{
+		Subject:     "key:" + key.ID,
+		Scopes:      ParseScopes(strings.Join(key.Scopes, ",")),
+		NamespaceID: key.NamespaceID,
+		AppIDs:      key.AppIDs,
+	}, nil
+}
@@ -0,0 +1,262 @@
+// Package auth authenticates the requests made to an fn server and checks
+// that their credentials grant the scope an endpoint needs.
+//
+// Credentials are checked by Providers: static API keys, API keys kept in the
+// datastore, JWTs signed with a shared secret or by an OIDC issuer, and TLS
+// client certificates. Each
+// endpoint needs one of four scopes:
+//
+//	admin   manage namespaces and role bindings, implies all other scopes
+//	write   manage apps, fns, triggers and secrets
+//	read    read apps, fns, triggers, calls and logs
+//	invoke  invoke fns through /invoke, /t and /ce
+//
+// Credentials bound to a namespace scope the requests made with them to it,
+// see common.WithNamespaceID.
+//
+// Requests whose credentials lack the scope of an endpoint may still be let
+// through by an Authorizer, such as one granting the scopes of the roles bound
+// to their subject on an app or namespace, see RoleScopes.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+	"github.com/sirupsen/logrus"
+)
+
+// Scope is a class of endpoints credentials may be granted access to
+type Scope string
+
+const (
+	// ScopeAdmin grants access to all endpoints
+	ScopeAdmin Scope = "admin"
+	// ScopeWrite grants access to the endpoints changing apps, fns, triggers
+	// and secrets
+	ScopeWrite Scope = "write"
+	// ScopeRead grants access to the endpoints reading resources
+	ScopeRead Scope = "read"
+	// ScopeInvoke grants access to the endpoints invoking fns
+	ScopeInvoke Scope = "invoke"
+)
+
+// ParseScopes parses a list of scopes separated by commas or spaces, ignoring
+// names which are not scopes.
+func ParseScopes(s string) []Scope {
+	var scopes []Scope
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		switch sc := Scope(strings.TrimSpace(name)); sc {
+		case ScopeAdmin, ScopeWrite, ScopeRead, ScopeInvoke:
+			scopes = append(scopes, sc)
+		}
+	}
+	return scopes
+}
+
+// RoleScopes returns the scopes granted by a role of a models.RoleBinding
+func RoleScopes(role string) []Scope {
+	switch role {
+	case models.RoleAdmin:
+		return []Scope{ScopeAdmin}
+	case models.RoleDeveloper:
+		return []Scope{ScopeWrite, ScopeRead, ScopeInvoke}
+	case models.RoleInvoker:
+		return []Scope{ScopeInvoke}
+	case models.RoleViewer:
+		return []Scope{ScopeRead}
+	}
+	return nil
+}
+
+// Identity is who a request was authenticated as
+type Identity struct {
+	// Subject names the holder of the credentials, for logs
+	Subject string
+	// Scopes are the scopes the credentials grant access to
+	Scopes []Scope
+	// NamespaceID, if set, scopes requests to a namespace
+	NamespaceID string
+	// AppIDs, if set, restrict requests to these apps, which only an
+	// Authorizer can tell requests are made to
+	AppIDs []string
+}
+
+// HasScope returns whether the identity was granted scope, directly or through
+// the admin scope.
+func (id *Identity) HasScope(scope Scope) bool {
+	for _, s := range id.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrNoCredentials is returned by providers for requests which do not carry
+	// credentials they know of, so that the next provider may be tried.
+	ErrNoCredentials = errors.New("no credentials")
+)
+
+// Provider authenticates requests from one kind of credentials.
+type Provider interface {
+	// Authenticate returns the identity the credentials of r belong to,
+	// ErrNoCredentials if r carries none the provider knows of, or another
+	// error if the credentials are invalid.
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+type contextKey string
+
+// WithIdentity returns a context carrying the identity a request was
+// authenticated as.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey("identity"), id)
+}
+
+// IdentityFromContext returns the identity a request was authenticated as, or
+// nil if it was not.
+func IdentityFromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(contextKey("identity")).(*Identity)
+	return id
+}
+
+// ScopeFunc returns the scope a request needs, or false if it needs no
+// credentials.
+type ScopeFunc func(r *http.Request) (Scope, bool)
+
+// DefaultScope requires the invoke scope to invoke fns, the admin scope to use
+// the hybrid runner API, to read the audit log, to manage role bindings or API
+// keys or to change namespaces, the read scope to read other resources and the write
+// scope to change them.
+// Pings and CORS preflight requests need no credentials.
+func DefaultScope(r *http.Request) (Scope, bool) {
+	path := r.URL.Path
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	switch {
+	case path == "/" || r.Method == http.MethodOptions:
+		return "", false
+	case strings.HasPrefix(path, "/invoke/"), strings.HasPrefix(path, "/t/"), strings.HasPrefix(path, "/ce/"):
+		return ScopeInvoke, true
+	case strings.HasPrefix(path, "/v2/runner/"), path == "/v2/audit", strings.HasPrefix(path, "/v2/rolebindings"), strings.HasPrefix(path, "/v2/keys"):
+		return ScopeAdmin, true
+	case strings.HasPrefix(path, "/v2/namespaces") && !read:
+		return ScopeAdmin, true
+	case read:
+		return ScopeRead, true
+	}
+	return ScopeWrite, true
+}
+
+// Authorizer may let through requests whose identity lacks the scope they
+// need, and decides on the requests of identities restricted to apps.
+type Authorizer interface {
+	// Authorize returns whether id is granted scope for r, whose context
+	// carries id.
+	Authorize(r *http.Request, id *Identity, scope Scope) (bool, error)
+}
+
+// NewMiddleware returns a middleware which authenticates requests with the
+// first provider which finds credentials it knows of, and rejects requests
+// whose credentials do not grant the scope returned by scopeOf. A nil scopeOf
+// uses DefaultScope.
+func NewMiddleware(scopeOf ScopeFunc, providers ...Provider) fnext.Middleware {
+	return NewAuthorizingMiddleware(scopeOf, nil, providers...)
+}
+
+// NewAuthorizingMiddleware returns a middleware like NewMiddleware which asks
+// authz, if not nil, whether to let through requests whose credentials do
+// not grant the scope they need or are restricted to apps.
+func NewAuthorizingMiddleware(scopeOf ScopeFunc, authz Authorizer, providers ...Provider) fnext.Middleware {
+	if scopeOf == nil {
+		scopeOf = DefaultScope
+	}
+	return &middleware{scopeOf: scopeOf, authz: authz, providers: providers}
+}
+
+type middleware struct {
+	scopeOf   ScopeFunc
+	authz     Authorizer
+	providers []Provider
+}
+
+func (m *middleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := m.scopeOf(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		id, err := m.authenticate(r)
+		if err != nil {
+			common.Logger(ctx).WithError(err).Debug("request failed authentication")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="fn"`)
+			writeError(w, models.ErrUnauthenticated)
+			return
+		}
+
+		ctx, _ = common.LoggerWithFields(ctx, logrus.Fields{"auth_subject": id.Subject})
+		ctx = WithIdentity(ctx, id)
+		if id.NamespaceID != "" {
+			ctx = common.WithNamespaceID(ctx, id.NamespaceID)
+		}
+		r = r.WithContext(ctx)
+
+		allowed := id.HasScope(scope) && len(id.AppIDs) == 0
+		if !allowed && !m.authorize(r, id, scope) {
+			common.Logger(ctx).WithFields(logrus.Fields{"subject": id.Subject, "scope": scope}).Debug("request lacks scope")
+			writeError(w, models.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize asks the authorizer, if any, to let r through, failing closed so
+// that identities restricted to apps are rejected without one
+func (m *middleware) authorize(r *http.Request, id *Identity, scope Scope) bool {
+	if m.authz == nil {
+		return false
+	}
+	ok, err := m.authz.Authorize(r, id, scope)
+	if err != nil {
+		common.Logger(r.Context()).WithError(err).Error("could not authorize request")
+		return false
+	}
+	return ok
+}
+
+func (m *middleware) authenticate(r *http.Request) (*Identity, error) {
+	for _, p := range m.providers {
+		id, err := p.Authenticate(r)
+		if err == ErrNoCredentials {
+			continue
+		}
+		return id, err
+	}
+	return nil, ErrNoCredentials
+}
+
+func writeError(w http.ResponseWriter, err models.APIError) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(err.Code())
+	json.NewEncoder(w).Encode(&models.Error{Message: err.Error()})
+}
+
+// bearerToken returns the token of the Authorization header of r, if any
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+func signHS256(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	signed := segment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := segment(t, map[string]string{"alg": "RS256", "kid": kid}) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func segment(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func bearer(method, path, token string) *http.Request {
+	r := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestMiddlewareScopes(t *testing.T) {
+	keys, err := NewStaticKeys([]StaticKey{
+		{Key: "admin-key", Subject: "admin", Scopes: []string{"admin"}},
+		{Key: "reader-key", Subject: "reader", Scopes: []string{"read"}},
+		{Key: "writer-key", Subject: "writer", Scopes: []string{"write"}},
+		{Key: "tenant-key", Subject: "tenant", Scopes: []string{"read", "invoke"}, NamespaceID: "ns"},
+		{Key: "invoker-key", Subject: "invoker", Scopes: []string{"invoke"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got context.Context
+	h := NewMiddleware(nil, keys).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Context()
+	}))
+
+	for i, test := range []struct {
+		req  *http.Request
+		code int
+	}{
+		{bearer("GET", "/", ""), http.StatusOK},
+		{bearer("GET", "/v2/apps", ""), http.StatusUnauthorized},
+		{bearer("GET", "/v2/apps", "unknown-key"), http.StatusUnauthorized},
+		{bearer("GET", "/v2/apps", "reader-key"), http.StatusOK},
+		{bearer("POST", "/v2/apps", "reader-key"), http.StatusForbidden},
+		{bearer("POST", "/invoke/fn", "reader-key"), http.StatusForbidden},
+		{bearer("GET", "/v2/runner/async", "reader-key"), http.StatusForbidden},
+		{bearer("POST", "/v2/apps", "writer-key"), http.StatusOK},
+		{bearer("POST", "/v2/namespaces", "writer-key"), http.StatusForbidden},
+		{bearer("DELETE", "/v2/rolebindings/rb", "writer-key"), http.StatusForbidden},
+		{bearer("POST", "/v2/apps", "admin-key"), http.StatusOK},
+		{bearer("POST", "/v2/namespaces", "admin-key"), http.StatusOK},
+		{bearer("POST", "/invoke/fn", "admin-key"), http.StatusOK},
+		{bearer("POST", "/ce/app", "invoker-key"), http.StatusOK},
+		{bearer("POST", "/ce/app", "writer-key"), http.StatusForbidden},
+		{bearer("POST", "/v2/apps", "invoker-key"), http.StatusForbidden},
+		{bearer("POST", "/t/app/hello", "tenant-key"), http.StatusOK},
+	} {
+		got = nil
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, test.req)
+		if rec.Code != test.code {
+			t.Errorf("Test %d: expected status %d for %s %s, got %d", i, test.code, test.req.Method, test.req.URL.Path, rec.Code)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Test %d: expected a WWW-Authenticate header", i)
+		}
+	}
+
+	if id := IdentityFromContext(got); id == nil || id.Subject != "tenant" {
+		t.Fatalf("expected the request to carry the identity of its credentials, got %+v", id)
+	}
+	if ns := common.NamespaceIDFromContext(got); ns != "ns" {
+		t.Fatalf("expected the request to be scoped to the namespace of its credentials, got %q", ns)
+	}
+}
+
+type appAuthorizer struct{ appID string }
+
+func (a appAuthorizer) Authorize(r *http.Request, id *Identity, scope Scope) (bool, error) {
+	if IdentityFromContext(r.Context()) != id {
+		return false, errors.New("expected the context to carry the identity")
+	}
+	return scope != ScopeAdmin && r.URL.Query().Get("app_id") == a.appID, nil
+}
+
+func TestMiddlewareAuthorizer(t *testing.T) {
+	keys, err := NewStaticKeys([]StaticKey{{Key: "bound-key", Subject: "bound"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewAuthorizingMiddleware(nil, appAuthorizer{"a"}, keys).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i, test := range []struct {
+		req  *http.Request
+		code int
+	}{
+		{bearer("GET", "/v2/fns?app_id=a", "bound-key"), http.StatusOK},
+		{bearer("POST", "/v2/triggers?app_id=a", "bound-key"), http.StatusOK},
+		{bearer("GET", "/v2/fns?app_id=b", "bound-key"), http.StatusForbidden},
+		{bearer("GET", "/v2/audit?app_id=a", "bound-key"), http.StatusForbidden},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, test.req)
+		if rec.Code != test.code {
+			t.Errorf("Test %d: expected status %d for %s %s, got %d", i, test.code, test.req.Method, test.req.URL, rec.Code)
+		}
+	}
+
+	if scopes := RoleScopes("developer"); len(scopes) != 3 || (&Identity{Scopes: scopes}).HasScope(ScopeAdmin) {
+		t.Fatalf("expected developers to be granted all scopes but admin, got %v", scopes)
+	}
+}
+
+type keyGetter map[string]*models.APIKey
+
+func (g keyGetter) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	if k, ok := g[keyID]; ok {
+		return k, nil
+	}
+	return nil, models.ErrAPIKeysNotFound
+}
+
+func TestAPIKeys(t *testing.T) {
+	secret, hash, err := models.NewAPIKeySecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := common.DateTime(time.Now().Add(-time.Minute))
+	p := NewAPIKeys(keyGetter{
+		"K1": {ID: "K1", Scopes: []string{"invoke"}, AppIDs: []string{"app"}, TokenHash: hash},
+		"K2": {ID: "K2", Scopes: []string{"read"}, TokenHash: hash, RevokedAt: &past},
+		"K3": {ID: "K3", Scopes: []string{"read"}, TokenHash: hash, ExpiresAt: &past},
+	})
+
+	id, err := p.Authenticate(bearer("POST", "/invoke/fn", models.APIKeyToken("K1", secret)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "key:K1" || !id.HasScope(ScopeInvoke) || id.HasScope(ScopeRead) || len(id.AppIDs) != 1 {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+
+	for i, token := range []string{
+		models.APIKeyToken("K1", secret+"x"),
+		models.APIKeyToken("K2", secret),
+		models.APIKeyToken("K3", secret),
+		models.APIKeyToken("K4", secret),
+	} {
+		if _, err := p.Authenticate(bearer("GET", "/v2/apps", token)); err == nil || err == ErrNoCredentials {
+			t.Errorf("Test %d: expected the key to be rejected, got %v", i, err)
+		}
+	}
+	if _, err := p.Authenticate(bearer("GET", "/v2/apps", "admin-key")); err != ErrNoCredentials {
+		t.Fatalf("expected other tokens to be left to other providers, got %v", err)
+	}
+
+	// identities restricted to apps need an authorizer
+	h := NewMiddleware(nil, p).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, bearer("POST", "/invoke/fn", models.APIKeyToken("K1", secret)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a key restricted to apps to be forbidden without an authorizer, got %d", rec.Code)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("secret")
+	p, err := NewJWT(JWTConfig{Secret: secret, Audience: "fn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+
+	id, err := p.Authenticate(bearer("GET", "/v2/apps", signHS256(t, secret, map[string]interface{}{
+		"sub": "ci", "aud": []string{"other", "fn"}, "exp": now + 60, "scope": "read invoke", "fn_namespace_id": "ns",
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "ci" || id.NamespaceID != "ns" || !id.HasScope(ScopeRead) || !id.HasScope(ScopeInvoke) || id.HasScope(ScopeAdmin) {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+
+	for i, token := range []string{
+		signHS256(t, []byte("other"), map[string]interface{}{"aud": "fn", "scope": "admin"}),
+		signHS256(t, secret, map[string]interface{}{"aud": "fn", "scope": "admin", "exp": now - 3600}),
+		signHS256(t, secret, map[string]interface{}{"aud": "other", "scope": "admin"}),
+		segment(t, map[string]string{"alg": "none"}) + "." + segment(t, map[string]interface{}{"aud": "fn", "scope": "admin"}) + ".",
+	} {
+		if _, err := p.Authenticate(bearer("GET", "/v2/apps", token)); err == nil || err == ErrNoCredentials {
+			t.Errorf("Test %d: expected the token to be rejected, got %v", i, err)
+		}
+	}
+
+	if _, err := p.Authenticate(bearer("GET", "/v2/apps", "not-a-jwt")); err != ErrNoCredentials {
+		t.Fatalf("expected other tokens to be left to other providers, got %v", err)
+	}
+}
+
+func TestOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	issuer = srv.URL
+
+	p, err := NewOIDC(context.Background(), issuer, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := p.Authenticate(bearer("GET", "/v2/apps", signRS256(t, key, "k1", map[string]interface{}{
+		"sub": "user", "iss": issuer, "scp": []string{"admin"},
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "user" || !id.HasScope(ScopeRead) {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+
+	if _, err := p.Authenticate(bearer("GET", "/v2/apps", signRS256(t, key, "k1", map[string]interface{}{
+		"sub": "user", "iss": "https://other.example.com", "scp": []string{"admin"},
+	}))); err != errJWTIssuer {
+		t.Fatalf("expected tokens of other issuers to be rejected, got %v", err)
+	}
+	if _, err := p.Authenticate(bearer("GET", "/v2/apps", signRS256(t, key, "k2", map[string]interface{}{
+		"sub": "user", "iss": issuer,
+	}))); err != errJWTKey {
+		t.Fatalf("expected tokens signed by unknown keys to be rejected, got %v", err)
+	}
+}
+
+func TestClientCert(t *testing.T) {
+	p := NewClientCert([]Scope{ScopeInvoke})
+
+	if _, err := p.Authenticate(httptest.NewRequest("GET", "/v2/apps", nil)); err != ErrNoCredentials {
+		t.Fatalf("expected requests without certificates to be left to other providers, got %v", err)
+	}
+
+	r := httptest.NewRequest("GET", "/v2/apps", nil)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+		{Subject: pkix.Name{CommonName: "ops", OrganizationalUnit: []string{"read", "platform"}}},
+	}}}
+	id, err := p.Authenticate(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "ops" || !id.HasScope(ScopeRead) || id.HasScope(ScopeInvoke) {
+		t.Fatalf("expected the scopes of the organizational units, got %+v", id)
+	}
+
+	r.TLS.VerifiedChains[0][0].Subject.OrganizationalUnit = nil
+	id, err = p.Authenticate(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.HasScope(ScopeInvoke) || id.HasScope(ScopeRead) {
+		t.Fatalf("expected the default scopes, got %+v", id)
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// NewClientCert returns a provider authenticating requests made over TLS with a
+// client certificate the server verified, which requires the web server to be
+// configured with client CAs, see server.WithTLS. Certificates are granted the
+// scopes named by their organizational units, or defaultScopes if they name
+// none. The subject of the identity is the common name of the certificate.
+func NewClientCert(defaultScopes []Scope) Provider {
+	return &clientCert{defaultScopes: defaultScopes}
+}
+
+type clientCert struct {
+	defaultScopes []Scope
+}
+
+func (p *clientCert) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+
+	id := &Identity{Subject: cert.Subject.CommonName}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		id.Scopes = append(id.Scopes, ParseScopes(ou)...)
+	}
+	if len(id.Scopes) == 0 {
+		id.Scopes = p.defaultScopes
+	}
+	return id, nil
+}
@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	_ "crypto/sha256" // register hashes used to verify signatures
+	_ "crypto/sha512"
+)
+
+const (
+	// clockSkew is how far exp and nbf claims may be off
+	clockSkew = time.Minute
+
+	// jwksRefreshInterval is the least time between two fetches of the keys of
+	// an OIDC issuer, which are fetched when a token is signed by an unknown key
+	jwksRefreshInterval = time.Minute
+)
+
+var (
+	errMalformedJWT = errors.New("malformed JWT")
+	errJWTSignature = errors.New("invalid JWT signature")
+	errJWTExpired   = errors.New("JWT expired or not yet valid")
+	errJWTIssuer    = errors.New("JWT has an unexpected issuer")
+	errJWTAudience  = errors.New("JWT has an unexpected audience")
+	errJWTKey       = errors.New("JWT signed with an unknown key")
+)
+
+// JWTConfig configures the verification of JWTs
+type JWTConfig struct {
+	// Secret verifies HS256, HS384 and HS512 signatures
+	Secret []byte
+	// Issuer, if set, is the only issuer accepted
+	Issuer string
+	// Audience, if set, must be one of the audiences of tokens
+	Audience string
+}
+
+// NewJWT returns a provider authenticating requests with bearer JWTs signed
+// with the secret of cfg. Tokens carry their scopes in a space separated scope
+// claim, or an scp array claim, and may be bound to a namespace with a
+// fn_namespace_id claim.
+func NewJWT(cfg JWTConfig) (Provider, error) {
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("auth: JWT secret must not be empty")
+	}
+	return &jwtProvider{cfg: cfg}, nil
+}
+
+// NewOIDC returns a provider authenticating requests with bearer JWTs issued by
+// the OpenID Connect provider at issuer, whose signing keys are discovered
+// from issuer/.well-known/openid-configuration.
+func NewOIDC(ctx context.Context, issuer, audience string) (Provider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	p := &jwtProvider{
+		cfg:    JWTConfig{Issuer: issuer, Audience: audience},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("auth: could not discover OIDC issuer %s: %v", issuer, err)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("auth: OIDC issuer %s has no jwks_uri", issuer)
+	}
+	p.jwksURI = discovery.JWKSURI
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, fmt.Errorf("auth: could not fetch the keys of OIDC issuer %s: %v", issuer, err)
+	}
+	return p, nil
+}
+
+type jwtProvider struct {
+	cfg JWTConfig
+
+	// OIDC key set, keyed by kid
+	client    *http.Client
+	jwksURI   string
+	keysMu    sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Scope     string          `json:"scope"`
+	Scp       []string        `json:"scp"`
+	Namespace string          `json:"fn_namespace_id"`
+}
+
+func (p *jwtProvider) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		// not a JWT, may be a token of another provider
+		return nil, ErrNoCredentials
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errMalformedJWT
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedJWT
+	}
+	if err := p.verify(r.Context(), &header, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errMalformedJWT
+	}
+	if err := p.validate(&claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	id := &Identity{Subject: claims.Subject, NamespaceID: claims.Namespace}
+	id.Scopes = ParseScopes(claims.Scope)
+	for _, s := range claims.Scp {
+		id.Scopes = append(id.Scopes, ParseScopes(s)...)
+	}
+	return id, nil
+}
+
+func (p *jwtProvider) verify(ctx context.Context, header *jwtHeader, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch {
+	case strings.HasSuffix(header.Alg, "256"):
+		hash = crypto.SHA256
+	case strings.HasSuffix(header.Alg, "384"):
+		hash = crypto.SHA384
+	case strings.HasSuffix(header.Alg, "512"):
+		hash = crypto.SHA512
+	default:
+		// including "none"
+		return errJWTSignature
+	}
+
+	if strings.HasPrefix(header.Alg, "HS") {
+		if len(p.cfg.Secret) == 0 {
+			return errJWTSignature
+		}
+		mac := hmac.New(hash.New, p.cfg.Secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errJWTSignature
+		}
+		return nil
+	}
+
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(header.Alg, "RS") || rsa.VerifyPKCS1v15(key, hash, digest, sig) != nil {
+			return errJWTSignature
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(header.Alg, "ES") || len(sig) != 2*size {
+			return errJWTSignature
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errJWTSignature
+		}
+	default:
+		return errJWTSignature
+	}
+	return nil
+}
+
+func (p *jwtProvider) validate(claims *jwtClaims, now time.Time) error {
+	if claims.ExpiresAt != nil && now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)) {
+		return errJWTExpired
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return errJWTExpired
+	}
+	if p.cfg.Issuer != "" && strings.TrimSuffix(claims.Issuer, "/") != p.cfg.Issuer {
+		return errJWTIssuer
+	}
+	if p.cfg.Audience != "" {
+		// aud is either a string or an array of strings
+		var auds []string
+		var aud string
+		if json.Unmarshal(claims.Audience, &aud) == nil {
+			auds = []string{aud}
+		} else if json.Unmarshal(claims.Audience, &auds) != nil {
+			return errJWTAudience
+		}
+		for _, a := range auds {
+			if a == p.cfg.Audience {
+				return nil
+			}
+		}
+		return errJWTAudience
+	}
+	return nil
+}
+
+// key returns the OIDC key kid, fetching the keys of the issuer again if kid is
+// unknown, as issuers rotate their keys
+func (p *jwtProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if p.jwksURI == "" {
+		return nil, errJWTSignature
+	}
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.fetchedAt) < jwksRefreshInterval {
+		return nil, errJWTKey
+	}
+	if err := p.refreshKeysLocked(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, errJWTKey
+}
+
+func (p *jwtProvider) refreshKeys(ctx context.Context) error {
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
+	return p.refreshKeysLocked(ctx)
+}
+
+func (p *jwtProvider) refreshKeysLocked(ctx context.Context) error {
+	p.fetchedAt = time.Now()
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.jwksURI, &set); err != nil {
+		return err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue // skip key types we do not support
+		}
+		keys[k.Kid] = key
+	}
+	p.keys = keys
+	return nil
+}
+
+func (p *jwtProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// jwk is a JSON web key, RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,214 @@
+// Package chaos injects faults into the container driver of runners and the
+// runners of lbs at configured rates, to test how placement and retries hold
+// up against slow calls, failing image pulls and container creates and busy
+// runners without breaking docker. Faults are only injected by binaries built
+// with the chaos build tag or when enabled explicitly, see FromSpec.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Faults that can be injected
+const (
+	// FaultLatency delays container runs on runners and placements on lbs
+	FaultLatency = "latency"
+	// FaultPull fails image pulls, images which are present are pulled again
+	// to be failed
+	FaultPull = "pull"
+	// FaultCreate fails container creates
+	FaultCreate = "create"
+	// FaultReject makes runners reject calls as if they were busy, so that
+	// lbs place them on other runners
+	FaultReject = "reject"
+)
+
+// ErrNotEnabled is returned by FromSpec for faults of binaries built without
+// the chaos build tag which were not enabled explicitly
+var ErrNotEnabled = errors.New("chaos: fault injection must be enabled explicitly or built in with the chaos build tag")
+
+// buildEnabled is set by the chaos build tag
+var buildEnabled bool
+
+// BuildEnabled returns whether the binary was built with the chaos build tag
+func BuildEnabled() bool {
+	return buildEnabled
+}
+
+// Config are the rates faults are injected at, between 0 and 1
+type Config struct {
+	LatencyRate float64
+	// Latency is the delay of FaultLatency
+	Latency           time.Duration
+	PullFailureRate   float64
+	CreateFailureRate float64
+	RunnerRejectRate  float64
+	// Seed makes the faults injected the same across runs, 0 seeds from the
+	// time
+	Seed int64
+}
+
+// ParseConfig parses a list of faults separated by commas, such as:
+//
+//	latency=0.1:500ms,pull=0.05,create=0.05,reject=0.2,seed=42
+//
+// which delays 10% of container runs and placements by 500ms, fails 5% of
+// image pulls and container creates and has runners reject 20% of calls.
+func ParseConfig(spec string) (*Config, error) {
+	var cfg Config
+	for _, f := range strings.Split(spec, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("chaos: invalid fault %q, must be name=rate", f)
+		}
+		name, val := kv[0], kv[1]
+
+		var err error
+		switch name {
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(val, 10, 64)
+		case FaultLatency:
+			parts := strings.SplitN(val, ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("chaos: invalid fault %q, must be latency=rate:duration", f)
+			}
+			if cfg.Latency, err = time.ParseDuration(parts[1]); err == nil && cfg.Latency <= 0 {
+				err = errors.New("latency must be positive")
+			}
+			if err == nil {
+				cfg.LatencyRate, err = parseRate(parts[0])
+			}
+		case FaultPull:
+			cfg.PullFailureRate, err = parseRate(val)
+		case FaultCreate:
+			cfg.CreateFailureRate, err = parseRate(val)
+		case FaultReject:
+			cfg.RunnerRejectRate, err = parseRate(val)
+		default:
+			return nil, fmt.Errorf("chaos: unknown fault %q, must be one of %s, %s, %s, %s or seed", name, FaultLatency, FaultPull, FaultCreate, FaultReject)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("chaos: invalid fault %q: %v", f, err)
+		}
+	}
+	return &cfg, nil
+}
+
+func parseRate(s string) (float64, error) {
+	r, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if r < 0 || r > 1 {
+		return 0, errors.New("rate must be between 0 and 1")
+	}
+	return r, nil
+}
+
+// Faults decides which faults to inject
+type Faults struct {
+	cfg Config
+
+	mu       sync.Mutex
+	rnd      *rand.Rand
+	injected map[string]uint64
+}
+
+// New returns the faults of cfg
+func New(cfg *Config) *Faults {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Faults{
+		cfg:      *cfg,
+		rnd:      rand.New(rand.NewSource(seed)),
+		injected: make(map[string]uint64),
+	}
+}
+
+// FromSpec returns the faults of a spec, see ParseConfig, nil if spec is
+// empty. Unless built with the chaos build tag, faults are only returned if
+// enable is set.
+func FromSpec(spec string, enable bool) (*Faults, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	if !enable && !buildEnabled {
+		return nil, ErrNotEnabled
+	}
+	cfg, err := ParseConfig(spec)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg), nil
+}
+
+// Inject returns whether to inject fault into what ctx is about, logging it
+// if so
+func (f *Faults) Inject(ctx context.Context, fault string) bool {
+	var rate float64
+	switch fault {
+	case FaultLatency:
+		rate = f.cfg.LatencyRate
+	case FaultPull:
+		rate = f.cfg.PullFailureRate
+	case FaultCreate:
+		rate = f.cfg.CreateFailureRate
+	case FaultReject:
+		rate = f.cfg.RunnerRejectRate
+	}
+	if rate == 0 {
+		return false
+	}
+
+	f.mu.Lock()
+	inject := f.rnd.Float64() < rate
+	if inject {
+		f.injected[fault]++
+	}
+	f.mu.Unlock()
+
+	if inject {
+		common.Logger(ctx).WithField("fault", fault).Info("chaos: injecting fault")
+	}
+	return inject
+}
+
+// Delay waits for the latency of the faults, if it is injected, or until ctx
+// is done
+func (f *Faults) Delay(ctx context.Context) error {
+	if !f.Inject(ctx, FaultLatency) {
+		return nil
+	}
+	select {
+	case <-time.After(f.cfg.Latency):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Injected returns the number of times each fault was injected
+func (f *Faults) Injected() map[string]uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := make(map[string]uint64, len(f.injected))
+	for k, v := range f.injected {
+		res[k] = v
+	}
+	return res
+}
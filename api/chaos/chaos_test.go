@@ -0,0 +1,94 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/agent/drivers/mock"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(" latency=0.1:500ms, pull=0.05,create=1,reject=0.2,seed=42,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cfg != (Config{LatencyRate: 0.1, Latency: 500 * time.Millisecond, PullFailureRate: 0.05, CreateFailureRate: 1, RunnerRejectRate: 0.2, Seed: 42}) {
+		t.Fatalf("Unexpected config %+v", cfg)
+	}
+	for _, bad := range []string{"pull", "pull=2", "latency=0.1", "latency=0.1:-1s", "crash=0.1", "seed=x"} {
+		if _, err := ParseConfig(bad); err == nil {
+			t.Errorf("Expected faults %q to be invalid", bad)
+		}
+	}
+}
+
+func TestFromSpec(t *testing.T) {
+	if f, err := FromSpec("", false); f != nil || err != nil {
+		t.Fatalf("Expected no faults without a spec, got %v %v", f, err)
+	}
+	if _, err := FromSpec("reject=0.1", false); BuildEnabled() != (err == nil) {
+		t.Fatalf("Expected faults to be refused unless enabled, got %v", err)
+	}
+	if f, err := FromSpec("reject=0.1", true); f == nil || err != nil {
+		t.Fatalf("Expected faults once enabled, got %v %v", f, err)
+	}
+}
+
+func TestInject(t *testing.T) {
+	ctx := context.Background()
+	f := New(&Config{RunnerRejectRate: 0.25, Seed: 1})
+	var rejected int
+	for i := 0; i < 1000; i++ {
+		if f.Inject(ctx, FaultReject) {
+			rejected++
+		}
+		if f.Inject(ctx, FaultPull) {
+			t.Fatal("Expected faults without a rate not to be injected")
+		}
+	}
+	if rejected < 200 || rejected > 300 || f.Injected()[FaultReject] != uint64(rejected) {
+		t.Fatalf("Expected about a quarter of calls rejected, got %d %v", rejected, f.Injected())
+	}
+
+	f = New(&Config{LatencyRate: 1, Latency: time.Hour})
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := f.Delay(cctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected delays to end with their context, got %v", err)
+	}
+}
+
+// task is a container task of which the mock driver only needs the image
+type task struct {
+	drivers.ContainerTask
+}
+
+func (task) Image() string { return "fnproject/hello" }
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+	drv := NewDriver(mock.New(), New(&Config{PullFailureRate: 1, CreateFailureRate: 1}))
+
+	cookie, err := drv.CreateCookie(ctx, task{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsPull, err := cookie.ValidateImage(ctx); !needsPull || err != nil {
+		t.Fatalf("Expected the image to be pulled again to fail, got %v %v", needsPull, err)
+	}
+	if err := cookie.PullImage(ctx); !models.IsAPIError(err) {
+		t.Fatalf("Expected the pull to fail, got %v", err)
+	}
+	if err := cookie.PullImage(ctx); err != nil {
+		t.Fatalf("Expected only the pull asked for to fail, got %v", err)
+	}
+	if err := cookie.CreateContainer(ctx); err != errCreateFailure {
+		t.Fatalf("Expected the create to fail, got %v", err)
+	}
+	if _, ok := drv.(drivers.OSReporter); !ok {
+		t.Fatal("Expected the driver to report the os of the mock driver")
+	}
+}
@@ -0,0 +1,124 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/models"
+)
+
+var errCreateFailure = errors.New("chaos: injected container create failure")
+
+// NewDriver returns a driver injecting the latency, pull and create faults of
+// faults into the cookies of drv
+func NewDriver(drv drivers.Driver, faults *Faults) drivers.Driver {
+	return &driver{Driver: drv, faults: faults}
+}
+
+type driver struct {
+	drivers.Driver
+	faults *Faults
+}
+
+var _ drivers.OSReporter = new(driver)
+
+func (d *driver) CreateCookie(ctx context.Context, task drivers.ContainerTask) (drivers.Cookie, error) {
+	c, err := d.Driver.CreateCookie(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	cookie := &cookie{Cookie: c, faults: d.faults, image: task.Image()}
+	if _, ok := c.(drivers.Checkpointer); ok {
+		return &checkpointCookie{cookie}, nil
+	}
+	return cookie, nil
+}
+
+// SetPreForkWaterMarks implements drivers.PreForkTuner with the pool of the
+// wrapped driver
+func (d *driver) SetPreForkWaterMarks(low, high uint64) error {
+	t, ok := d.Driver.(drivers.PreForkTuner)
+	if !ok {
+		return errors.New("the driver has no pre-fork pool")
+	}
+	return t.SetPreForkWaterMarks(low, high)
+}
+
+// OSType implements drivers.OSReporter with the os of the wrapped driver
+func (d *driver) OSType() string {
+	if r, ok := d.Driver.(drivers.OSReporter); ok {
+		return r.OSType()
+	}
+	return drivers.OSLinux
+}
+
+type cookie struct {
+	drivers.Cookie
+	faults *Faults
+	image  string
+	// failPull fails the pull of the image, which ValidateImage asked for
+	failPull bool
+}
+
+var _ drivers.ImageResolver = new(cookie)
+
+func (c *cookie) ValidateImage(ctx context.Context) (bool, error) {
+	needsPull, err := c.Cookie.ValidateImage(ctx)
+	if err != nil {
+		return needsPull, err
+	}
+	if c.faults.Inject(ctx, FaultPull) {
+		c.failPull = true
+		return true, nil
+	}
+	return needsPull, nil
+}
+
+func (c *cookie) PullImage(ctx context.Context) error {
+	if c.failPull {
+		c.failPull = false
+		return models.NewAPIError(http.StatusInternalServerError, fmt.Errorf("Failed to pull image '%s': chaos: injected pull failure", c.image))
+	}
+	return c.Cookie.PullImage(ctx)
+}
+
+func (c *cookie) CreateContainer(ctx context.Context) error {
+	if c.faults.Inject(ctx, FaultCreate) {
+		return errCreateFailure
+	}
+	return c.Cookie.CreateContainer(ctx)
+}
+
+func (c *cookie) Run(ctx context.Context) (drivers.WaitResult, error) {
+	if err := c.faults.Delay(ctx); err != nil {
+		return nil, err
+	}
+	return c.Cookie.Run(ctx)
+}
+
+// ResolvedImage implements drivers.ImageResolver with what the image of the
+// wrapped cookie resolved to
+func (c *cookie) ResolvedImage() (string, string) {
+	if r, ok := c.Cookie.(drivers.ImageResolver); ok {
+		return r.ResolvedImage()
+	}
+	return "", ""
+}
+
+// checkpointCookie is a cookie wrapping a drivers.Checkpointer
+type checkpointCookie struct {
+	*cookie
+}
+
+var _ drivers.Checkpointer = new(checkpointCookie)
+
+func (c *checkpointCookie) Checkpoint(ctx context.Context) error {
+	return c.Cookie.(drivers.Checkpointer).Checkpoint(ctx)
+}
+
+func (c *checkpointCookie) Restore(ctx context.Context) error {
+	return c.Cookie.(drivers.Checkpointer).Restore(ctx)
+}
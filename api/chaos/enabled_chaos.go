@@ -0,0 +1,8 @@
+//go:build chaos
+// +build chaos
+
+package chaos
+
+func init() {
+	buildEnabled = true
+}
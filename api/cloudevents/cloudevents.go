@@ -0,0 +1,287 @@
+// Package cloudevents reads and writes CloudEvents 1.0 in the structured and
+// binary modes of the http protocol binding, see
+// https://github.com/cloudevents/spec/blob/v1.0/http-protocol-binding.md
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// SpecVersion is the version of the spec events must conform to
+	SpecVersion = "1.0"
+
+	// ContentType is the content type of events in structured mode
+	ContentType = "application/cloudevents+json"
+
+	// BatchContentType is the content type of batches of events, which are not supported
+	BatchContentType = "application/cloudevents-batch+json"
+
+	// headerPrefix prefixes the attributes of events in binary mode
+	headerPrefix = "Ce-"
+)
+
+// Event is a CloudEvent, its data is kept as raw bytes of DataContentType.
+type Event struct {
+	ID              string
+	Source          string
+	SpecVersion     string
+	Type            string
+	DataContentType string
+	DataSchema      string
+	Subject         string
+	Time            string
+
+	// Extensions are the other attributes of the event
+	Extensions map[string]string
+
+	Data []byte
+}
+
+var (
+	errBatch    = errors.New("batches of CloudEvents are not supported, send one event per request")
+	errNotEvent = errors.New("request is not a CloudEvent, expected a structured " + ContentType + " body or binary mode ce- headers")
+)
+
+// Validate checks that e has the required attributes of the spec, which are
+// valid.
+func (e *Event) Validate() error {
+	if e.SpecVersion != SpecVersion {
+		return fmt.Errorf("unsupported CloudEvents specversion %q, expected %s", e.SpecVersion, SpecVersion)
+	}
+	for name, v := range map[string]string{"id": e.ID, "source": e.Source, "type": e.Type} {
+		if v == "" {
+			return fmt.Errorf("CloudEvent is missing the required attribute %s", name)
+		}
+	}
+	if e.Time != "" {
+		if _, err := time.Parse(time.RFC3339Nano, e.Time); err != nil {
+			return fmt.Errorf("CloudEvent time %q is not an RFC 3339 timestamp", e.Time)
+		}
+	}
+	for name := range e.Extensions {
+		if !validAttributeName(name) {
+			return fmt.Errorf("CloudEvent attribute name %q must be lower case letters and digits", name)
+		}
+	}
+	return nil
+}
+
+func validAttributeName(name string) bool {
+	if name == "" || len(name) > 20 {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEvent returns whether header is the header of an event in either mode
+func IsEvent(header http.Header) bool {
+	mt, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mt == ContentType || header.Get(headerPrefix+"Specversion") != ""
+}
+
+// Read reads the event in the header and body of an http message, in
+// structured or binary mode, and validates it.
+func Read(header http.Header, body io.Reader) (*Event, error) {
+	mt, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var e *Event
+	switch {
+	case mt == BatchContentType:
+		return nil, errBatch
+	case mt == ContentType:
+		e, err = unmarshalStructured(b)
+		if err != nil {
+			return nil, err
+		}
+	case header.Get(headerPrefix+"Specversion") != "":
+		e = readBinary(header, b)
+	default:
+		return nil, errNotEvent
+	}
+	return e, e.Validate()
+}
+
+func readBinary(header http.Header, body []byte) *Event {
+	e := &Event{Data: body, DataContentType: header.Get("Content-Type")}
+	for k, vs := range header {
+		if !strings.HasPrefix(k, headerPrefix) || len(vs) == 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(k, headerPrefix))
+		e.set(name, vs[0])
+	}
+	return e
+}
+
+// set sets the attribute name, for binary mode and structured mode events
+func (e *Event) set(name, v string) {
+	switch name {
+	case "id":
+		e.ID = v
+	case "source":
+		e.Source = v
+	case "specversion":
+		e.SpecVersion = v
+	case "type":
+		e.Type = v
+	case "datacontenttype":
+		e.DataContentType = v
+	case "dataschema":
+		e.DataSchema = v
+	case "subject":
+		e.Subject = v
+	case "time":
+		e.Time = v
+	default:
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]string)
+		}
+		e.Extensions[name] = v
+	}
+}
+
+// attributes returns the set attributes of e by name, without data
+func (e *Event) attributes() map[string]string {
+	attrs := make(map[string]string, 8+len(e.Extensions))
+	for k, v := range e.Extensions {
+		attrs[k] = v
+	}
+	for k, v := range map[string]string{
+		"id":              e.ID,
+		"source":          e.Source,
+		"specversion":     e.SpecVersion,
+		"type":            e.Type,
+		"datacontenttype": e.DataContentType,
+		"dataschema":      e.DataSchema,
+		"subject":         e.Subject,
+		"time":            e.Time,
+	} {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+func unmarshalStructured(b []byte) (*Event, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("invalid structured CloudEvent: %v", err)
+	}
+	e := &Event{}
+	for name, v := range raw {
+		switch name {
+		case "data":
+			e.Data = []byte(v)
+		case "data_base64":
+			var s string
+			if err := json.Unmarshal(v, &s); err != nil {
+				return nil, fmt.Errorf("invalid CloudEvent data_base64: %v", err)
+			}
+			data, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CloudEvent data_base64: %v", err)
+			}
+			e.Data = data
+		default:
+			var s string
+			if err := json.Unmarshal(v, &s); err != nil {
+				// attributes of other types are kept in their canonical string form
+				s = string(v)
+			}
+			e.set(name, s)
+		}
+	}
+	if _, ok := raw["data"]; ok {
+		// json data is kept as is, string data of other content types unquoted
+		var s string
+		if !isJSON(e.DataContentType) && json.Unmarshal(raw["data"], &s) == nil {
+			e.Data = []byte(s)
+		}
+		if e.DataContentType == "" {
+			e.DataContentType = "application/json"
+		}
+	}
+	return e, nil
+}
+
+func isJSON(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return mt == "" || mt == "application/json" || mt == "text/json" || strings.HasSuffix(mt, "+json")
+}
+
+// MarshalJSON returns e in structured mode
+func (e *Event) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, 10)
+	for k, v := range e.attributes() {
+		m[k] = v
+	}
+	if e.Data != nil {
+		switch {
+		case isJSON(e.DataContentType) && json.Valid(e.Data):
+			m["data"] = json.RawMessage(e.Data)
+		case strings.HasPrefix(e.DataContentType, "text/"):
+			m["data"] = string(e.Data)
+		default:
+			m["data_base64"] = base64.StdEncoding.EncodeToString(e.Data)
+		}
+	}
+	return json.Marshal(m)
+}
+
+// WriteBinary sets the attributes of e as the ce- headers of header, and the
+// content type of its data, the data is the body.
+func (e *Event) WriteBinary(header http.Header) {
+	for k, v := range e.attributes() {
+		if k == "datacontenttype" {
+			header.Set("Content-Type", v)
+			continue
+		}
+		header.Set(headerPrefix+k, v)
+	}
+}
+
+// Send posts e to sink in structured mode
+func Send(ctx context.Context, client *http.Client, sink string, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, sink, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", ContentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("CloudEvents sink %s returned %s", sink, resp.Status)
+	}
+	return nil
+}
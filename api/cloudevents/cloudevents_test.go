@@ -0,0 +1,105 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadStructured(t *testing.T) {
+	header := http.Header{"Content-Type": {ContentType + "; charset=utf-8"}}
+	body := `{"specversion":"1.0","id":"1","source":"/orders","type":"order.created","time":"2018-04-05T17:31:00Z","tenant":"acme","data":{"total":3}}`
+	e, err := Read(header, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "1" || e.Source != "/orders" || e.Type != "order.created" || e.Extensions["tenant"] != "acme" {
+		t.Fatalf("unexpected attributes %+v", e)
+	}
+	if string(e.Data) != `{"total":3}` || e.DataContentType != "application/json" {
+		t.Fatalf("expected json data to be kept as is, got %s %s", e.Data, e.DataContentType)
+	}
+
+	// binary data round trips through data_base64
+	e.Data, e.DataContentType = []byte{0, 1, 2}, "application/octet-stream"
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := Read(header, strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(e2.Data) != string(e.Data) || e2.Extensions["tenant"] != "acme" {
+		t.Fatalf("expected the event to round trip, got %+v", e2)
+	}
+}
+
+func TestReadBinary(t *testing.T) {
+	header := http.Header{
+		"Content-Type":   {"text/plain"},
+		"Ce-Specversion": {"1.0"},
+		"Ce-Id":          {"1"},
+		"Ce-Source":      {"/orders"},
+		"Ce-Type":        {"order.created"},
+		"Ce-Tenant":      {"acme"},
+	}
+	e, err := Read(header, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(e.Data) != "hello" || e.DataContentType != "text/plain" || e.Extensions["tenant"] != "acme" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	out := make(http.Header)
+	e.WriteBinary(out)
+	for k, v := range header {
+		if out.Get(k) != v[0] {
+			t.Errorf("expected header %s to be %s, got %q", k, v[0], out.Get(k))
+		}
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	for name, c := range map[string]struct {
+		header http.Header
+		body   string
+	}{
+		"not an event":   {http.Header{"Content-Type": {"application/json"}}, `{}`},
+		"batch":          {http.Header{"Content-Type": {BatchContentType}}, `[]`},
+		"missing id":     {http.Header{"Content-Type": {ContentType}}, `{"specversion":"1.0","source":"/s","type":"t"}`},
+		"old version":    {http.Header{"Content-Type": {ContentType}}, `{"specversion":"0.3","id":"1","source":"/s","type":"t"}`},
+		"bad time":       {http.Header{"Ce-Specversion": {"1.0"}, "Ce-Id": {"1"}, "Ce-Source": {"/s"}, "Ce-Type": {"t"}, "Ce-Time": {"yesterday"}}, ``},
+		"bad extension":  {http.Header{"Ce-Specversion": {"1.0"}, "Ce-Id": {"1"}, "Ce-Source": {"/s"}, "Ce-Type": {"t"}, "Ce-My-Ext": {"x"}}, ``},
+		"malformed json": {http.Header{"Content-Type": {ContentType}}, `{`},
+	} {
+		if _, err := Read(c.header, strings.NewReader(c.body)); err == nil {
+			t.Errorf("%s: expected the event to be rejected", name)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got *Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = Read(r.Header, r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	e := &Event{SpecVersion: SpecVersion, ID: "1", Source: "/fn", Type: "reply", DataContentType: "text/plain", Data: []byte("hi")}
+	if err := Send(context.Background(), http.DefaultClient, srv.URL, e); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.ID != "1" || string(got.Data) != "hi" {
+		t.Fatalf("expected the sink to get the event, got %+v", got)
+	}
+
+	if err := Send(context.Background(), http.DefaultClient, srv.URL+"/\x00", e); err == nil {
+		t.Fatal("expected invalid sinks to fail")
+	}
+}
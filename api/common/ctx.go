@@ -12,11 +12,28 @@ type contextKey string
 // RequestIDContextKey is the name of the key used to store the request ID into the context
 const RequestIDContextKey = "fn_request_id"
 
+// RequestIDHeader is the header carrying the request ID of a call to the
+// containers of fns, and from runners to the API servers of hybrid nodes
+const RequestIDHeader = "Fn-Request-Id"
+
 //WithRequestID stores a request ID into the context
 func WithRequestID(ctx context.Context, rid string) context.Context {
 	return context.WithValue(ctx, contextKey(RequestIDContextKey), rid)
 }
 
+// WithNamespaceID scopes a context to the namespace of a tenant, datastores
+// wrapped by the datastore package then only show the apps of the namespace
+// and everything under them to requests made with it.
+func WithNamespaceID(ctx context.Context, nsID string) context.Context {
+	return context.WithValue(ctx, contextKey("namespace_id"), nsID)
+}
+
+// NamespaceIDFromContext returns the namespace a context is scoped to, if any.
+func NamespaceIDFromContext(ctx context.Context) string {
+	nsID, _ := ctx.Value(contextKey("namespace_id")).(string)
+	return nsID
+}
+
 // WithLogger stores the logger.
 func WithLogger(ctx context.Context, l logrus.FieldLogger) context.Context {
 	return context.WithValue(ctx, contextKey("logger"), l)
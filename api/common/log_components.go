@@ -0,0 +1,217 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Components of a server whose log level can be set apart from the level of
+// the rest of the server, see SetComponentLogLevel
+const (
+	LogComponentAgent  = "agent"
+	LogComponentDriver = "driver"
+	LogComponentLB     = "lb"
+	LogComponentServer = "server"
+)
+
+// Field names of the ids logged with the logs about a call, use CallFields to
+// set them
+const (
+	FieldCallID    = "call_id"
+	FieldFnID      = "fn_id"
+	FieldAppID     = "app_id"
+	FieldComponent = "component"
+)
+
+// LogComponents are the components with a log level of their own
+var LogComponents = []string{LogComponentAgent, LogComponentDriver, LogComponentLB, LogComponentServer}
+
+// componentLogger is the logger of a component, which writes through the
+// standard logger at a level of its own. level is empty while the component
+// follows the level of the standard logger.
+type componentLogger struct {
+	logger *logrus.Logger
+	level  string
+}
+
+var (
+	// componentsMu guards the levels of componentLoggers, which has the
+	// loggers of all components from init on
+	componentsMu     sync.Mutex
+	componentLoggers = make(map[string]*componentLogger)
+)
+
+func init() {
+	for _, c := range LogComponents {
+		l := &logrus.Logger{
+			Out:       stdWriter{},
+			Formatter: stdFormatter{},
+			Hooks:     logrus.LevelHooks{},
+			Level:     logrus.GetLevel(),
+		}
+		l.AddHook(stdHooks{})
+		componentLoggers[c] = &componentLogger{logger: l}
+	}
+}
+
+// stdWriter, stdFormatter and stdHooks write the logs of component loggers
+// the way the standard logger is configured to, at the time of each log, so
+// that SetLogFormat and SetLogDest apply to components
+type stdWriter struct{}
+
+func (stdWriter) Write(b []byte) (int, error) { return logrus.StandardLogger().Out.Write(b) }
+
+type stdFormatter struct{}
+
+func (stdFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	return logrus.StandardLogger().Formatter.Format(e)
+}
+
+type stdHooks struct{}
+
+func (stdHooks) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (stdHooks) Fire(e *logrus.Entry) error { return logrus.StandardLogger().Hooks.Fire(e.Level, e) }
+
+// ComponentLogger returns the logger of ctx, with its fields, logging at the
+// level of component and with a component field. The logger of ctx is
+// returned as is if it is not a logrus logger or component is unknown.
+func ComponentLogger(ctx context.Context, component string) logrus.FieldLogger {
+	l := Logger(ctx)
+	cl, ok := componentLoggers[component]
+	if !ok {
+		return l
+	}
+
+	var data logrus.Fields
+	switch l := l.(type) {
+	case *logrus.Entry:
+		data = l.Data
+	case *logrus.Logger:
+	default:
+		return l
+	}
+	fields := make(logrus.Fields, len(data)+1)
+	for k, v := range data {
+		fields[k] = v
+	}
+	fields[FieldComponent] = component
+	return cl.logger.WithFields(fields)
+}
+
+// WithComponent returns a child context of ctx whose logger logs at the level
+// of component, see ComponentLogger. Loggers derived from it with
+// LoggerWithFields keep logging at the level of the component.
+func WithComponent(ctx context.Context, component string) context.Context {
+	return WithLogger(ctx, ComponentLogger(ctx, component))
+}
+
+// CallFields returns the fields of the ids of a call, leaving out those
+// which are empty
+func CallFields(appID, fnID, callID string) logrus.Fields {
+	fields := make(logrus.Fields, 3)
+	if appID != "" {
+		fields[FieldAppID] = appID
+	}
+	if fnID != "" {
+		fields[FieldFnID] = fnID
+	}
+	if callID != "" {
+		fields[FieldCallID] = callID
+	}
+	return fields
+}
+
+// LoggerWithCall returns a child context of ctx whose logger has the fields
+// of the ids of a call, see CallFields
+func LoggerWithCall(ctx context.Context, appID, fnID, callID string) (context.Context, logrus.FieldLogger) {
+	return LoggerWithFields(ctx, CallFields(appID, fnID, callID))
+}
+
+// SetComponentLogLevel sets the log level of a component, an empty level
+// makes the component follow the level set by SetLogLevel again
+func SetComponentLogLevel(component, level string) error {
+	var lvl logrus.Level
+	if level != "" {
+		var err error
+		if lvl, err = logrus.ParseLevel(level); err != nil {
+			return err
+		}
+		level = lvl.String()
+	}
+
+	componentsMu.Lock()
+	defer componentsMu.Unlock()
+	cl, ok := componentLoggers[component]
+	if !ok {
+		return fmt.Errorf("unknown log component %q, must be one of %s", component, strings.Join(LogComponents, ", "))
+	}
+	cl.level = level
+	if level == "" {
+		lvl = logrus.GetLevel()
+	}
+	cl.logger.SetLevel(lvl)
+	return nil
+}
+
+// SetComponentLogLevels sets the levels of components from a list of
+// component=level pairs separated by commas, such as agent=debug,lb=warn.
+// The levels of the components left out follow the level set by SetLogLevel.
+func SetComponentLogLevels(levels string) error {
+	set := make(map[string]string)
+	for _, pair := range strings.Split(levels, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid log component level %q, must be component=level", pair)
+		}
+		set[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	for c, level := range set {
+		if _, err := logrus.ParseLevel(level); err != nil {
+			return err
+		}
+		if _, ok := componentLoggers[c]; !ok {
+			return fmt.Errorf("unknown log component %q, must be one of %s", c, strings.Join(LogComponents, ", "))
+		}
+	}
+	for _, c := range LogComponents {
+		if err := SetComponentLogLevel(c, set[c]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ComponentLogLevels returns the levels set for components, components
+// following the level set by SetLogLevel are left out
+func ComponentLogLevels() map[string]string {
+	componentsMu.Lock()
+	defer componentsMu.Unlock()
+	levels := make(map[string]string)
+	for c, cl := range componentLoggers {
+		if cl.level != "" {
+			levels[c] = cl.level
+		}
+	}
+	return levels
+}
+
+// syncComponentLogLevels sets the level of components without a level of
+// their own to the level of the standard logger
+func syncComponentLogLevels() {
+	componentsMu.Lock()
+	defer componentsMu.Unlock()
+	for _, cl := range componentLoggers {
+		if cl.level == "" {
+			cl.logger.SetLevel(logrus.GetLevel())
+		}
+	}
+}
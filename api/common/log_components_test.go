@@ -0,0 +1,65 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestComponentLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	std := logrus.StandardLogger()
+	out, formatter, level := std.Out, std.Formatter, std.GetLevel()
+	defer func() {
+		std.Out, std.Formatter = out, formatter
+		SetLogLevel(level.String())
+		SetComponentLogLevels("")
+	}()
+	std.Out = &buf
+	SetLogFormat("json")
+	SetLogLevel("info")
+
+	if err := SetComponentLogLevels("agent=debug,lb=error"); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"agent", "agent=loud", "runner=debug"} {
+		if err := SetComponentLogLevels(bad); err == nil {
+			t.Errorf("Expected levels %q to be invalid", bad)
+		}
+	}
+	if levels := ComponentLogLevels(); len(levels) != 2 || levels["agent"] != "debug" || levels["lb"] != "error" {
+		t.Fatalf("Unexpected levels %v", levels)
+	}
+
+	buf.Reset()
+	ctx, _ := LoggerWithCall(context.Background(), "app1", "fn1", "call1")
+	ComponentLogger(ctx, LogComponentAgent).Debug("agent")
+	ComponentLogger(ctx, LogComponentLB).Warn("lb")
+	Logger(WithComponent(ctx, LogComponentDriver)).Debug("driver")
+	Logger(ctx).Debug("none")
+
+	var entry map[string]interface{}
+	if err := json.NewDecoder(&buf).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["msg"] != "agent" || entry[FieldComponent] != "agent" || entry[FieldCallID] != "call1" || entry[FieldFnID] != "fn1" || entry[FieldAppID] != "app1" {
+		t.Fatalf("Unexpected entry %v", entry)
+	}
+	if rest := strings.TrimSpace(buf.String()); rest != "" {
+		t.Fatalf("Expected the other components to log at their own levels, got %s", rest)
+	}
+
+	// components without a level of their own follow the standard logger
+	SetLogLevel("debug")
+	if err := SetComponentLogLevel(LogComponentLB, ""); err != nil {
+		t.Fatal(err)
+	}
+	ComponentLogger(ctx, LogComponentLB).Debug("lb")
+	if !strings.Contains(buf.String(), `"component":"lb"`) {
+		t.Fatalf("Expected lb to log at debug, got %q", buf.String())
+	}
+}
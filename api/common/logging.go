@@ -1,6 +1,8 @@
 package common
 
 import (
+	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"strings"
@@ -38,6 +40,7 @@ func SetLogLevel(ll string) {
 		logLevel = logrus.InfoLevel
 	}
 	logrus.SetLevel(logLevel)
+	syncComponentLogLevels()
 
 	// this effectively just adds more gin log goodies
 	gin.SetMode(gin.ReleaseMode)
@@ -94,6 +97,56 @@ func SetLogDest(to, prefix string) {
 	}
 }
 
+// NewLogger returns a logger of its own writing to the location to, one of
+// stdout, stderr, a file:// url or the udp:// or tcp:// url of a syslog server.
+// Unlike SetLogDest an invalid location is an error, not a fallback.
+func NewLogger(to, format, prefix string) (*logrus.Logger, error) {
+	logger := logrus.New()
+	if format == "text" {
+		logger.Formatter = &logrus.TextFormatter{FullTimestamp: true}
+	} else {
+		logger.Formatter = &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	}
+
+	switch to {
+	case "stdout":
+		logger.Out = os.Stdout
+		return logger, nil
+	case "stderr":
+		logger.Out = os.Stderr
+		return logger, nil
+	}
+
+	parsed, err := url.Parse(to)
+	if err != nil {
+		return nil, err
+	}
+	switch parsed.Scheme {
+	case "udp", "tcp":
+		if parsed.Host == "" || parsed.Path != "" {
+			return nil, fmt.Errorf("syslog location %q must contain only a host[:port]", to)
+		}
+		hook, err := newSyslogHook(parsed, prefix)
+		if err != nil {
+			return nil, err
+		}
+		logger.Hooks.Add(hook)
+		logger.Out = ioutil.Discard
+	case "file":
+		if parsed.Host != "" || parsed.Path == "" {
+			return nil, fmt.Errorf("file location %q must contain only a path", to)
+		}
+		f, err := os.OpenFile(parsed.Path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
+		if err != nil {
+			return nil, err
+		}
+		logger.Out = f
+	default:
+		return nil, fmt.Errorf("unknown logging location %q", to)
+	}
+	return logger, nil
+}
+
 // MaskPassword returns a stringified URL without its password visible
 func MaskPassword(u *url.URL) string {
 	if u.User != nil {
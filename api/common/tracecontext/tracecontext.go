@@ -0,0 +1,135 @@
+// Package tracecontext propagates spans in the W3C Trace Context format, see
+// https://www.w3.org/TR/trace-context/. Spans are carried by the traceparent
+// and tracestate headers of http requests, the metadata of gRPC calls between
+// lb nodes and runners, and the requests fns get.
+package tracecontext
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+	"go.opencensus.io/trace/tracestate"
+)
+
+const (
+	// TraceparentHeader holds the trace and parent span of a request
+	TraceparentHeader = "traceparent"
+	// TracestateHeader holds vendor specific state of the trace of a request
+	TracestateHeader = "tracestate"
+
+	supportedVersion = "00"
+	maxTracestate    = 32
+)
+
+// Parse returns the span context of the traceparent and tracestate values
+// given, an invalid tracestate is dropped
+func Parse(traceparent, tracestate string) (trace.SpanContext, bool) {
+	var sc trace.SpanContext
+
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	// versions after 00 may add fields, but start with the same ones
+	if parts[0] == supportedVersion && len(parts) != 4 {
+		return sc, false
+	}
+
+	tid, err := hex.DecodeString(parts[1])
+	if err != nil || len(tid) != len(sc.TraceID) || parts[1] != strings.ToLower(parts[1]) {
+		return sc, false
+	}
+	sid, err := hex.DecodeString(parts[2])
+	if err != nil || len(sid) != len(sc.SpanID) || parts[2] != strings.ToLower(parts[2]) {
+		return sc, false
+	}
+	opts, err := hex.DecodeString(parts[3])
+	if err != nil || len(opts) != 1 {
+		return sc, false
+	}
+	copy(sc.TraceID[:], tid)
+	copy(sc.SpanID[:], sid)
+	if sc.TraceID == (trace.TraceID{}) || sc.SpanID == (trace.SpanID{}) {
+		return trace.SpanContext{}, false
+	}
+	sc.TraceOptions = trace.TraceOptions(opts[0] & 1)
+	sc.Tracestate = parseTracestate(tracestate)
+	return sc, true
+}
+
+func parseTracestate(s string) *tracestate.Tracestate {
+	if s == "" {
+		return nil
+	}
+	var entries []tracestate.Entry
+	for _, member := range strings.Split(s, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		kv := strings.SplitN(member, "=", 2)
+		if len(kv) != 2 || len(entries) == maxTracestate {
+			return nil
+		}
+		entries = append(entries, tracestate.Entry{Key: kv[0], Value: kv[1]})
+	}
+	ts, err := tracestate.New(nil, entries...)
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+// Format returns the traceparent and tracestate values of sc
+func Format(sc trace.SpanContext) (traceparent, tracestate string) {
+	traceparent = fmt.Sprintf("%s-%s-%s-%02x", supportedVersion,
+		hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), uint8(sc.TraceOptions)&1)
+	if sc.Tracestate != nil {
+		entries := sc.Tracestate.Entries()
+		members := make([]string, 0, len(entries))
+		for _, e := range entries {
+			members = append(members, e.Key+"="+e.Value)
+		}
+		tracestate = strings.Join(members, ",")
+	}
+	return traceparent, tracestate
+}
+
+// Inject sets the traceparent and tracestate headers of h to sc
+func Inject(sc trace.SpanContext, h http.Header) {
+	traceparent, tracestate := Format(sc)
+	h.Set(TraceparentHeader, traceparent)
+	if tracestate != "" {
+		h.Set(TracestateHeader, tracestate)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
+
+// HTTPFormat implements propagation.HTTPFormat for the W3C Trace Context
+// headers. Requests without them may still carry B3 headers, which fn used
+// before, and those are understood too. Outgoing requests get both.
+type HTTPFormat struct {
+	b3 b3.HTTPFormat
+}
+
+var _ propagation.HTTPFormat = (*HTTPFormat)(nil)
+
+// SpanContextFromRequest extracts the span context of incoming requests
+func (f *HTTPFormat) SpanContextFromRequest(req *http.Request) (trace.SpanContext, bool) {
+	if h := req.Header.Get(TraceparentHeader); h != "" {
+		return Parse(h, strings.Join(req.Header[http.CanonicalHeaderKey(TracestateHeader)], ","))
+	}
+	return f.b3.SpanContextFromRequest(req)
+}
+
+// SpanContextToRequest sets the span context headers of outgoing requests
+func (f *HTTPFormat) SpanContextToRequest(sc trace.SpanContext, req *http.Request) {
+	Inject(sc, req.Header)
+	f.b3.SpanContextToRequest(sc, req)
+}
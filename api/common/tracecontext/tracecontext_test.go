@@ -0,0 +1,74 @@
+package tracecontext
+
+import (
+	"net/http"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestParseFormat(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, ok := Parse(traceparent, "congo=t61rcWkgMzE, rojo=00f067aa0ba902b7")
+	if !ok {
+		t.Fatal("expected a valid traceparent")
+	}
+	if !sc.IsSampled() {
+		t.Fatal("expected the sampled flag to be kept")
+	}
+	tp, ts := Format(sc)
+	if tp != traceparent {
+		t.Fatalf("expected %q, got %q", traceparent, tp)
+	}
+	if ts != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Fatalf("expected the tracestate to be kept, got %q", ts)
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, ok := Parse(bad, ""); ok {
+			t.Errorf("expected %q to be invalid", bad)
+		}
+	}
+
+	if _, ok := Parse("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra", ""); !ok {
+		t.Error("expected fields of later versions to be ignored")
+	}
+	if sc, ok := Parse(traceparent, "not a tracestate"); !ok || sc.Tracestate != nil {
+		t.Error("expected an invalid tracestate to be dropped")
+	}
+}
+
+func TestHTTPFormat(t *testing.T) {
+	var f HTTPFormat
+	sc := trace.SpanContext{
+		TraceID:      trace.TraceID{1, 2, 3},
+		SpanID:       trace.SpanID{4, 5, 6},
+		TraceOptions: 1,
+	}
+
+	req, _ := http.NewRequest("GET", "http://fn", nil)
+	f.SpanContextToRequest(sc, req)
+	if req.Header.Get(TraceparentHeader) == "" || req.Header.Get("X-B3-TraceId") == "" {
+		t.Fatalf("expected both trace context and b3 headers, got %v", req.Header)
+	}
+	got, ok := f.SpanContextFromRequest(req)
+	if !ok || got.TraceID != sc.TraceID || got.SpanID != sc.SpanID {
+		t.Fatalf("expected %v, got %v", sc, got)
+	}
+
+	// b3 only requests are still understood
+	req.Header.Del(TraceparentHeader)
+	got, ok = f.SpanContextFromRequest(req)
+	if !ok || got.TraceID != sc.TraceID {
+		t.Fatalf("expected the b3 span context, got %v", got)
+	}
+}
@@ -11,7 +11,7 @@ import (
 )
 
 func NewSyslogHook(url *url.URL, prefix string) error {
-	syslog, err := logrus_syslog.NewSyslogHook(url.Scheme, url.Host, 0, prefix)
+	syslog, err := newSyslogHook(url, prefix)
 	if err != nil {
 		return err
 	}
@@ -20,3 +20,7 @@ func NewSyslogHook(url *url.URL, prefix string) error {
 	logrus.SetOutput(ioutil.Discard)
 	return nil
 }
+
+func newSyslogHook(url *url.URL, prefix string) (logrus.Hook, error) {
+	return logrus_syslog.NewSyslogHook(url.Scheme, url.Host, 0, prefix)
+}
@@ -6,8 +6,12 @@ const (
 	// AppID is the app id context key
 	AppID string = "app_id"
 
+	// ParamNamespaceID is the url path parameter for namespace id
+	ParamNamespaceID string = "namespaceID"
 	// ParamAppID is the url path parameter for app id
 	ParamAppID string = "appID"
+	// ParamSecretName is the url path parameter for secret name
+	ParamSecretName string = "secretName"
 	// ParamAppName is the url path parameter for app name
 	ParamAppName string = "appName"
 	// ParamTriggerID is the url path parameter for trigger id
@@ -16,6 +20,12 @@ const (
 	ParamCallID string = "callID"
 	// ParamFnID is the url path parameter for fn id
 	ParamFnID string = "fnID"
+	// ParamRevisionID is the url path parameter for fn revision id
+	ParamRevisionID string = "revisionID"
+	// ParamRoleBindingID is the url path parameter for role binding id
+	ParamRoleBindingID string = "bindingID"
+	// ParamAPIKeyID is the url path parameter for API key id
+	ParamAPIKeyID string = "keyID"
 	// ParamTriggerSource is the triggers source parameter
 	ParamTriggerSource string = "triggerSource"
 
@@ -29,8 +29,9 @@ func New(ctx context.Context, dbURL string) (models.Datastore, error) {
 	return nil, fmt.Errorf("no data store provider found for storage url %s", u)
 }
 
+// Wrap adds tracing, validation and namespace scoping to a datastore
 func Wrap(ds models.Datastore) models.Datastore {
-	return datastoreutil.MetricDS(datastoreutil.NewValidator(ds))
+	return datastoreutil.MetricDS(datastoreutil.NewNamespaceScope(datastoreutil.NewValidator(ds)))
 }
 
 // Provider is a datastore provider
@@ -14,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -99,6 +100,7 @@ func (brp *BasicResourceProvider) ValidFn(appId string) *models.Fn {
 		ResourceConfig: models.ResourceConfig{
 			Timeout:     models.DefaultTimeout,
 			IdleTimeout: models.DefaultIdleTimeout,
+			InitTimeout: 10,
 			Memory:      models.DefaultMemory,
 		},
 	}
@@ -603,6 +605,7 @@ func RunFnsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 				ResourceConfig: models.ResourceConfig{
 					Timeout:     testFn.Timeout,
 					IdleTimeout: testFn.IdleTimeout,
+					InitTimeout: testFn.InitTimeout,
 					Memory:      testFn.Memory,
 				},
 				// updated
@@ -655,6 +658,7 @@ func RunFnsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 				ResourceConfig: models.ResourceConfig{
 					Timeout:     testFn.Timeout,
 					IdleTimeout: testFn.IdleTimeout,
+					InitTimeout: testFn.InitTimeout,
 					Memory:      testFn.Memory,
 				},
 				// updated
@@ -1221,6 +1225,624 @@ func RunTriggerBySourceTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvide
 
 }
 
+// RunChangeFeedTests checks the changes of a datastore implementing
+// models.ChangeFeed, it is skipped for other datastores
+func RunChangeFeedTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+
+	t.Run("change_feed", func(t *testing.T) {
+		ds := dsf(t)
+		cf, ok := ds.(models.ChangeFeed)
+		if !ok {
+			t.Skip("datastore does not implement models.ChangeFeed")
+		}
+
+		ctx := rp.DefaultCtx()
+		feedCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		changes, err := cf.Changes(feedCtx)
+		if err == models.ErrChangeFeedUnsupported {
+			t.Skip("datastore does not support change feeds")
+		}
+		if err != nil {
+			t.Fatalf("Expecting changes, got error %s", err)
+		}
+
+		h := NewHarness(t, ctx, ds)
+		defer h.Cleanup()
+		testApp := h.GivenAppInDb(rp.ValidApp())
+		testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+		testTrigger := h.GivenTriggerInDb(rp.ValidTrigger(testApp.ID, testFn.ID))
+
+		testFn.Timeout = testFn.Timeout + 1
+		if _, err := ds.UpdateFn(ctx, testFn); err != nil {
+			t.Fatalf("Failed to update function %s", err)
+		}
+		if err := ds.RemoveTrigger(ctx, testTrigger.ID); err != nil {
+			t.Fatalf("Failed to remove trigger %s", err)
+		}
+		if err := ds.RemoveApp(ctx, testApp.ID); err != nil {
+			t.Fatalf("Failed to remove app %s", err)
+		}
+
+		expected := []models.Change{
+			{Kind: models.ChangeKindApp, Op: models.ChangeOpCreate, ObjectID: testApp.ID, AppID: testApp.ID},
+			{Kind: models.ChangeKindFn, Op: models.ChangeOpCreate, ObjectID: testFn.ID, AppID: testApp.ID},
+			{Kind: models.ChangeKindTrigger, Op: models.ChangeOpCreate, ObjectID: testTrigger.ID, AppID: testApp.ID},
+			{Kind: models.ChangeKindFn, Op: models.ChangeOpUpdate, ObjectID: testFn.ID, AppID: testApp.ID},
+			{Kind: models.ChangeKindTrigger, Op: models.ChangeOpDelete, ObjectID: testTrigger.ID, AppID: testApp.ID},
+			{Kind: models.ChangeKindApp, Op: models.ChangeOpDelete, ObjectID: testApp.ID, AppID: testApp.ID},
+		}
+
+		seen := make(map[string]bool)
+		timeout := time.After(10 * time.Second)
+		for i := 0; i < len(expected); {
+			var c *models.Change
+			select {
+			case c = <-changes:
+			case <-timeout:
+				t.Fatalf("Timed out waiting for change %+v", expected[i])
+			}
+			if c == nil {
+				t.Fatalf("Changes closed before change %+v", expected[i])
+			}
+			if seen[c.ID] {
+				continue // changes may be delivered more than once
+			}
+			seen[c.ID] = true
+
+			exp := expected[i]
+			if c.Kind != exp.Kind || c.Op != exp.Op || c.ObjectID != exp.ObjectID || c.AppID != exp.AppID {
+				t.Fatalf("Expecting change %d to be %+v, got %+v", i, exp, c)
+			}
+			i++
+		}
+
+		cancel()
+		for range changes {
+		}
+	})
+}
+
+func RunNamespacesTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+
+	t.Run("namespaces", func(t *testing.T) {
+		ds := dsf(t)
+		nss, ok := ds.(models.NamespaceStore)
+		if !ok {
+			t.Skip("datastore does not implement models.NamespaceStore")
+		}
+		ctx := rp.DefaultCtx()
+
+		suffix := fmt.Sprintf("%09d", rand.Uint32())
+		ns, err := nss.InsertNamespace(ctx, &models.Namespace{Name: "team_a_" + suffix})
+		if err == models.ErrNamespacesUnsupported {
+			t.Skip("datastore does not support namespaces")
+		}
+		if err != nil {
+			t.Fatalf("Expecting namespace to be inserted, got error %s", err)
+		}
+		if ns.ID == "" || time.Time(ns.CreatedAt).IsZero() {
+			t.Fatalf("Expecting id and created time to be set, got %+v", ns)
+		}
+
+		_, err = nss.InsertNamespace(ctx, &models.Namespace{Name: ns.Name})
+		if err != models.ErrNamespacesAlreadyExists {
+			t.Fatalf("Expecting %s inserting a duplicate name, got %v", models.ErrNamespacesAlreadyExists, err)
+		}
+
+		other, err := nss.InsertNamespace(ctx, &models.Namespace{Name: "team_b_" + suffix})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := nss.GetNamespaceByID(ctx, ns.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equals(ns) {
+			t.Fatalf("Expecting namespace %+v, got %+v", ns, got)
+		}
+
+		list, err := nss.GetNamespaces(ctx, &models.NamespaceFilter{Name: other.Name, PerPage: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 1 || list.Items[0].ID != other.ID {
+			t.Fatalf("Expecting only namespace %s filtering by name, got %+v", other.ID, list.Items)
+		}
+
+		list, err = nss.GetNamespaces(ctx, &models.NamespaceFilter{PerPage: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 1 || list.NextCursor == "" {
+			t.Fatalf("Expecting a page of one namespace and a cursor, got %+v", list)
+		}
+
+		annotations, _ := models.EmptyAnnotations().With("team", "a")
+		updated, err := nss.UpdateNamespace(ctx, &models.Namespace{ID: ns.ID, Annotations: annotations})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated.Annotations.Equals(annotations) {
+			t.Fatalf("Expecting annotations to be updated, got %+v", updated.Annotations)
+		}
+		_, err = nss.UpdateNamespace(ctx, &models.Namespace{ID: ns.ID, Name: "renamed"})
+		if err != models.ErrNamespacesNameImmutable {
+			t.Fatalf("Expecting %s renaming a namespace, got %v", models.ErrNamespacesNameImmutable, err)
+		}
+
+		// apps are listed by namespace
+		app := rp.ValidApp()
+		app.NamespaceID = ns.ID
+		app, err = ds.InsertApp(ctx, app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ds.RemoveApp(ctx, app.ID)
+		unscopedApp, err := ds.InsertApp(ctx, rp.ValidApp())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ds.RemoveApp(ctx, unscopedApp.ID)
+
+		apps, err := ds.GetApps(ctx, &models.AppFilter{NamespaceID: ns.ID, PerPage: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(apps.Items) != 1 || apps.Items[0].ID != app.ID || apps.Items[0].NamespaceID != ns.ID {
+			t.Fatalf("Expecting only app %s in namespace %s, got %+v", app.ID, ns.ID, apps.Items)
+		}
+
+		err = nss.RemoveNamespace(ctx, ns.ID)
+		if err != models.ErrNamespacesNotEmpty {
+			t.Fatalf("Expecting %s removing a namespace with apps, got %v", models.ErrNamespacesNotEmpty, err)
+		}
+		if err := ds.RemoveApp(ctx, app.ID); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{ns.ID, other.ID} {
+			if err := nss.RemoveNamespace(ctx, id); err != nil {
+				t.Fatalf("Expecting namespace to be removed, got error %s", err)
+			}
+		}
+
+		_, err = nss.GetNamespaceByID(ctx, ns.ID)
+		if err != models.ErrNamespacesNotFound {
+			t.Fatalf("Expecting %s getting a removed namespace, got %v", models.ErrNamespacesNotFound, err)
+		}
+		err = nss.RemoveNamespace(ctx, ns.ID)
+		if err != models.ErrNamespacesNotFound {
+			t.Fatalf("Expecting %s removing a removed namespace, got %v", models.ErrNamespacesNotFound, err)
+		}
+	})
+}
+
+func RunSecretsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+
+	t.Run("secrets", func(t *testing.T) {
+		ds := dsf(t)
+		ss, ok := ds.(models.SecretStore)
+		if !ok {
+			t.Skip("datastore does not implement models.SecretStore")
+		}
+		ctx := rp.DefaultCtx()
+
+		_, err := ss.PutSecret(ctx, &models.StoredSecret{AppID: "missing", Name: "TOKEN", Mount: models.SecretMountEnv, Ciphertext: []byte{1}})
+		if err == models.ErrSecretsUnsupported {
+			t.Skip("datastore does not support secrets")
+		}
+		if err != models.ErrAppsNotFound {
+			t.Fatalf("Expecting %s putting a secret of a missing app, got %v", models.ErrAppsNotFound, err)
+		}
+
+		app, err := ds.InsertApp(ctx, rp.ValidApp())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ds.RemoveApp(ctx, app.ID)
+
+		_, err = ss.PutSecret(ctx, &models.StoredSecret{AppID: app.ID, Name: "1TOKEN", Mount: models.SecretMountEnv, Ciphertext: []byte{1}})
+		if err != models.ErrSecretsInvalidName {
+			t.Fatalf("Expecting %s putting a secret with an invalid name, got %v", models.ErrSecretsInvalidName, err)
+		}
+
+		// ciphertexts are binary
+		ciphertext := []byte{0, 1, 2, 0xfe, 0xff}
+		token, err := ss.PutSecret(ctx, &models.StoredSecret{AppID: app.ID, Name: "TOKEN", Mount: models.SecretMountEnv, Ciphertext: ciphertext})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Time(token.CreatedAt).IsZero() || !bytes.Equal(token.Ciphertext, ciphertext) {
+			t.Fatalf("Expecting the secret to be stored, got %+v", token)
+		}
+		if _, err := ss.PutSecret(ctx, &models.StoredSecret{AppID: app.ID, Name: "CERT", Mount: models.SecretMountFile, Ciphertext: []byte{3}}); err != nil {
+			t.Fatal(err)
+		}
+
+		updated, err := ss.PutSecret(ctx, &models.StoredSecret{AppID: app.ID, Name: "TOKEN", Mount: models.SecretMountFile, Ciphertext: []byte{4}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.CreatedAt.String() != token.CreatedAt.String() {
+			t.Fatalf("Expecting replacing a secret to keep its creation time, got %v want %v", updated.CreatedAt, token.CreatedAt)
+		}
+
+		secrets, err := ss.GetSecrets(ctx, app.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(secrets) != 2 || secrets[0].Name != "CERT" || secrets[1].Name != "TOKEN" {
+			t.Fatalf("Expecting secrets CERT and TOKEN, got %+v", secrets)
+		}
+		if secrets[1].Mount != models.SecretMountFile || !bytes.Equal(secrets[1].Ciphertext, []byte{4}) {
+			t.Fatalf("Expecting the secret to be replaced, got %+v", secrets[1])
+		}
+
+		if err := ss.RemoveSecret(ctx, app.ID, "CERT"); err != nil {
+			t.Fatal(err)
+		}
+		if err := ss.RemoveSecret(ctx, app.ID, "CERT"); err != models.ErrSecretsNotFound {
+			t.Fatalf("Expecting %s removing a removed secret, got %v", models.ErrSecretsNotFound, err)
+		}
+
+		// secrets are removed with their app
+		if err := ds.RemoveApp(ctx, app.ID); err != nil {
+			t.Fatal(err)
+		}
+		secrets, err = ss.GetSecrets(ctx, app.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(secrets) != 0 {
+			t.Fatalf("Expecting the secrets of a removed app to be removed, got %+v", secrets)
+		}
+	})
+}
+
+func RunFnRevisionsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+
+	t.Run("fn revisions", func(t *testing.T) {
+		ds := dsf(t)
+		rs, ok := ds.(models.FnRevisionStore)
+		if !ok {
+			t.Skip("datastore does not implement models.FnRevisionStore")
+		}
+		ctx := rp.DefaultCtx()
+
+		_, err := rs.InsertFnRevision(ctx, &models.FnRevision{FnID: "missing", Image: "fnproject/fn-test-utils"})
+		if err == models.ErrFnRevisionsUnsupported {
+			t.Skip("datastore does not support fn revisions")
+		}
+		if err != models.ErrFnsNotFound {
+			t.Fatalf("Expecting %s inserting a revision of a missing fn, got %v", models.ErrFnsNotFound, err)
+		}
+
+		app, err := ds.InsertApp(ctx, rp.ValidApp())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ds.RemoveApp(ctx, app.ID)
+		fn, err := ds.InsertFn(ctx, rp.ValidFn(app.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first, err := rs.InsertFnRevision(ctx, models.NewFnRevision(fn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.ID == "" || first.Number != 1 || first.AppID != app.ID || time.Time(first.CreatedAt).IsZero() {
+			t.Fatalf("Expecting the first revision to be numbered 1, got %+v", first)
+		}
+
+		fn.Image = "fnproject/fn-test-utils@sha256:0123"
+		fn.Config = models.Config{"LEVEL": "debug"}
+		second, err := rs.InsertFnRevision(ctx, models.NewFnRevision(fn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second.Number != 2 || second.ImageDigest != "sha256:0123" {
+			t.Fatalf("Expecting the second revision to be numbered 2 and keep the image digest, got %+v", second)
+		}
+
+		revs, err := rs.GetFnRevisions(ctx, fn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revs) != 2 || revs[0].ID != second.ID || revs[1].ID != first.ID {
+			t.Fatalf("Expecting the revisions most recent first, got %+v", revs)
+		}
+
+		rev, err := rs.GetFnRevision(ctx, fn.ID, second.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rev.Deploys(fn) {
+			t.Fatalf("Expecting the revision to deploy the fn it was taken of, got %+v", rev)
+		}
+		if _, err := rs.GetFnRevision(ctx, "other", second.ID); err != models.ErrFnRevisionsNotFound {
+			t.Fatalf("Expecting %s getting the revision of another fn, got %v", models.ErrFnRevisionsNotFound, err)
+		}
+
+		// revisions are removed with their fn
+		if err := ds.RemoveFn(ctx, fn.ID); err != nil {
+			t.Fatal(err)
+		}
+		revs, err = rs.GetFnRevisions(ctx, fn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revs) != 0 {
+			t.Fatalf("Expecting the revisions of a removed fn to be removed, got %+v", revs)
+		}
+	})
+}
+
+func RunRoleBindingsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+
+	t.Run("role bindings", func(t *testing.T) {
+		ds := dsf(t)
+		rbs, ok := ds.(models.RoleBindingStore)
+		if !ok {
+			t.Skip("datastore does not implement models.RoleBindingStore")
+		}
+		ctx := rp.DefaultCtx()
+
+		_, err := rbs.GetRoleBindingByID(ctx, "missing")
+		if err == models.ErrRoleBindingsUnsupported {
+			t.Skip("datastore does not support role bindings")
+		}
+		if err != models.ErrRoleBindingsNotFound {
+			t.Fatalf("Expecting %s getting a missing role binding, got %v", models.ErrRoleBindingsNotFound, err)
+		}
+
+		app, err := ds.InsertApp(ctx, rp.ValidApp())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ds.RemoveApp(ctx, app.ID)
+
+		subject := fmt.Sprintf("user_%09d", rand.Uint32())
+		cluster, err := rbs.InsertRoleBinding(ctx, &models.RoleBinding{Subject: subject, Role: models.RoleViewer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rbs.RemoveRoleBinding(ctx, cluster.ID)
+		if cluster.ID == "" || time.Time(cluster.CreatedAt).IsZero() {
+			t.Fatalf("Expecting the binding to get an ID and a creation time, got %+v", cluster)
+		}
+
+		onApp, err := rbs.InsertRoleBinding(ctx, &models.RoleBinding{Subject: subject, Role: models.RoleDeveloper, AppID: app.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = rbs.InsertRoleBinding(ctx, &models.RoleBinding{Subject: subject, Role: models.RoleDeveloper, AppID: app.ID})
+		if err != models.ErrRoleBindingsAlreadyExists {
+			t.Fatalf("Expecting %s inserting a duplicate binding, got %v", models.ErrRoleBindingsAlreadyExists, err)
+		}
+
+		got, err := rbs.GetRoleBindingByID(ctx, onApp.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Subject != subject || got.Role != models.RoleDeveloper || got.AppID != app.ID {
+			t.Fatalf("Expecting the inserted binding, got %+v", got)
+		}
+
+		list, err := rbs.GetRoleBindings(ctx, &models.RoleBindingFilter{Subject: subject, PerPage: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 1 || list.NextCursor == "" {
+			t.Fatalf("Expecting a page of one binding and a cursor, got %+v", list)
+		}
+		next, err := rbs.GetRoleBindings(ctx, &models.RoleBindingFilter{Subject: subject, Cursor: list.NextCursor, PerPage: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(next.Items) != 1 || next.Items[0].ID == list.Items[0].ID {
+			t.Fatalf("Expecting the next page to hold the other binding, got %+v", next)
+		}
+
+		list, err = rbs.GetRoleBindings(ctx, &models.RoleBindingFilter{AppID: app.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 1 || list.Items[0].ID != onApp.ID {
+			t.Fatalf("Expecting the bindings of the app, got %+v", list)
+		}
+
+		// bindings are removed with their app
+		if err := ds.RemoveApp(ctx, app.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rbs.GetRoleBindingByID(ctx, onApp.ID); err != models.ErrRoleBindingsNotFound {
+			t.Fatalf("Expecting the bindings of a removed app to be removed, got %v", err)
+		}
+
+		if err := rbs.RemoveRoleBinding(ctx, cluster.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := rbs.RemoveRoleBinding(ctx, cluster.ID); err != models.ErrRoleBindingsNotFound {
+			t.Fatalf("Expecting %s removing a removed binding, got %v", models.ErrRoleBindingsNotFound, err)
+		}
+	})
+}
+
+func RunAPIKeysTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+
+	t.Run("api keys", func(t *testing.T) {
+		ds := dsf(t)
+		ks, ok := ds.(models.APIKeyStore)
+		if !ok {
+			t.Skip("datastore does not implement models.APIKeyStore")
+		}
+		ctx := rp.DefaultCtx()
+
+		_, err := ks.GetAPIKeyByID(ctx, "missing")
+		if err == models.ErrAPIKeysUnsupported {
+			t.Skip("datastore does not support API keys")
+		}
+		if err != models.ErrAPIKeysNotFound {
+			t.Fatalf("Expecting %s getting a missing key, got %v", models.ErrAPIKeysNotFound, err)
+		}
+
+		name := fmt.Sprintf("ci_%09d", rand.Uint32())
+		expires := common.DateTime(time.Now().Add(time.Hour))
+		_, hash, err := models.NewAPIKeySecret()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ks.InsertAPIKey(ctx, &models.APIKey{
+			Name:      name,
+			Scopes:    []string{"write", "read"},
+			AppIDs:    []string{"app_a", "app_b"},
+			ExpiresAt: &expires,
+			TokenHash: hash,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.ID == "" || time.Time(key.CreatedAt).IsZero() || key.RevokedAt != nil {
+			t.Fatalf("Expecting the key to get an ID and a creation time, got %+v", key)
+		}
+
+		got, err := ks.GetAPIKeyByID(ctx, key.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.TokenHash != hash || len(got.Scopes) != 2 || len(got.AppIDs) != 2 || got.AppIDs[1] != "app_b" ||
+			got.ExpiresAt == nil || got.ExpiresAt.String() != expires.String() {
+			t.Fatalf("Expecting the inserted key, got %+v", got)
+		}
+
+		other, err := ks.InsertAPIKey(ctx, &models.APIKey{Name: name, Scopes: []string{"invoke"}, TokenHash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := ks.GetAPIKeys(ctx, &models.APIKeyFilter{Name: name, PerPage: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 1 || list.Items[0].ID != key.ID || list.NextCursor == "" {
+			t.Fatalf("Expecting a page holding the first key and a cursor, got %+v", list)
+		}
+		list, err = ks.GetAPIKeys(ctx, &models.APIKeyFilter{Name: name, Cursor: list.NextCursor, PerPage: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 1 || list.Items[0].ID != other.ID {
+			t.Fatalf("Expecting the next page to hold the other key, got %+v", list)
+		}
+
+		later := common.DateTime(time.Now().Add(time.Minute))
+		revoked, err := ks.RevokeAPIKey(ctx, key.ID, later)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked.RevokedAt == nil || !revoked.Active(time.Now()) || revoked.Active(time.Now().Add(2*time.Minute)) {
+			t.Fatalf("Expecting the key to be revoked in a minute, got %+v", revoked)
+		}
+		now := common.DateTime(time.Now())
+		if revoked, err = ks.RevokeAPIKey(ctx, key.ID, now); err != nil {
+			t.Fatal(err)
+		}
+		if revoked.Active(time.Now()) {
+			t.Fatalf("Expecting an earlier revocation to take over, got %+v", revoked)
+		}
+		if revoked, err = ks.RevokeAPIKey(ctx, key.ID, later); err != nil {
+			t.Fatal(err)
+		}
+		if revoked.Active(time.Now()) {
+			t.Fatalf("Expecting a later revocation to keep the earlier one, got %+v", revoked)
+		}
+
+		if _, err := ks.RevokeAPIKey(ctx, "missing", now); err != models.ErrAPIKeysNotFound {
+			t.Fatalf("Expecting %s revoking a missing key, got %v", models.ErrAPIKeysNotFound, err)
+		}
+	})
+}
+
+func RunWorkflowStatesTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+
+	t.Run("workflow states", func(t *testing.T) {
+		ds := dsf(t)
+		ws, ok := ds.(models.WorkflowStateStore)
+		if !ok {
+			t.Skip("datastore does not implement models.WorkflowStateStore")
+		}
+		ctx := rp.DefaultCtx()
+
+		_, err := ws.GetWorkflowState(ctx, "missing")
+		if err == models.ErrWorkflowStatesUnsupported {
+			t.Skip("datastore does not support workflow states")
+		}
+		if err != models.ErrWorkflowStatesNotFound {
+			t.Fatalf("Expecting %s getting a missing workflow state, got %v", models.ErrWorkflowStatesNotFound, err)
+		}
+
+		app, err := ds.InsertApp(ctx, rp.ValidApp())
+		if err != nil {
+			t.Fatal(err)
+		}
+		chainID := fmt.Sprintf("chain_%09d", rand.Uint32())
+
+		if _, err := ws.PutWorkflowState(ctx, &models.WorkflowState{ChainID: chainID, AppID: app.ID}); err != nil {
+			t.Fatal(err)
+		}
+		state, err := ws.GetWorkflowState(ctx, chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.AppID != app.ID || state.Version != 1 || time.Time(state.CreatedAt).IsZero() {
+			t.Fatalf("Expecting the first version of the state, got %+v", state)
+		}
+
+		stale := *state
+		state.State = `{"step": 2}`
+		state, err = ws.PutWorkflowState(ctx, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Version != 2 {
+			t.Fatalf("Expecting the version of the state to be incremented, got %+v", state)
+		}
+		stale.State = `{"step": 3}`
+		if _, err := ws.PutWorkflowState(ctx, &stale); err != models.ErrWorkflowStatesConflict {
+			t.Fatalf("Expecting %s putting a stale state, got %v", models.ErrWorkflowStatesConflict, err)
+		}
+		if _, err := ws.PutWorkflowState(ctx, &models.WorkflowState{ChainID: chainID, AppID: app.ID}); err != models.ErrWorkflowStatesConflict {
+			t.Fatalf("Expecting %s creating an existing state, got %v", models.ErrWorkflowStatesConflict, err)
+		}
+		got, err := ws.GetWorkflowState(ctx, chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.State != `{"step": 2}` || got.Version != 2 || got.CreatedAt.String() != state.CreatedAt.String() {
+			t.Fatalf("Expecting the state put last, got %+v", got)
+		}
+
+		if err := ws.RemoveWorkflowState(ctx, chainID); err != nil {
+			t.Fatal(err)
+		}
+		if err := ws.RemoveWorkflowState(ctx, chainID); err != models.ErrWorkflowStatesNotFound {
+			t.Fatalf("Expecting %s removing a removed state, got %v", models.ErrWorkflowStatesNotFound, err)
+		}
+
+		// states are removed with their app
+		if _, err := ws.PutWorkflowState(ctx, &models.WorkflowState{ChainID: chainID, AppID: app.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ds.RemoveApp(ctx, app.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ws.GetWorkflowState(ctx, chainID); err != models.ErrWorkflowStatesNotFound {
+			t.Fatalf("Expecting the state to be removed with its app, got %v", err)
+		}
+	})
+}
+
 func RunAllTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 	buf := setLogBuffer()
 	defer func() {
@@ -1233,5 +1855,12 @@ func RunAllTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 	RunFnsTest(t, dsf, rp)
 	RunTriggersTest(t, dsf, rp)
 	RunTriggerBySourceTests(t, dsf, rp)
+	RunChangeFeedTests(t, dsf, rp)
+	RunNamespacesTest(t, dsf, rp)
+	RunSecretsTest(t, dsf, rp)
+	RunFnRevisionsTest(t, dsf, rp)
+	RunRoleBindingsTest(t, dsf, rp)
+	RunAPIKeysTest(t, dsf, rp)
+	RunWorkflowStatesTest(t, dsf, rp)
 
 }
@@ -0,0 +1,244 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// dynamoClient makes the DynamoDB api calls the store needs. The DynamoDB
+// service client of the aws sdk is not vendored, so this sets up a client the
+// same way the generated service clients do, with handlers for the JSON 1.0
+// protocol DynamoDB speaks, and defines the shapes of the calls used.
+type dynamoClient struct {
+	*client.Client
+}
+
+const targetPrefix = "DynamoDB_20120810"
+
+func newDynamoClient(p client.ConfigProvider, cfgs ...*aws.Config) *dynamoClient {
+	c := p.ClientConfig("dynamodb", cfgs...)
+	svc := &dynamoClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "dynamodb",
+				ServiceID:     "DynamoDB",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2012-08-10",
+				JSONVersion:   "1.0",
+				TargetPrefix:  targetPrefix,
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(request.NamedHandler{Name: "fn.dynamodb.Build", Fn: buildRequest})
+	svc.Handlers.Unmarshal.PushBackNamed(request.NamedHandler{Name: "fn.dynamodb.Unmarshal", Fn: unmarshalResponse})
+	svc.Handlers.UnmarshalMeta.PushBackNamed(request.NamedHandler{Name: "fn.dynamodb.UnmarshalMeta", Fn: unmarshalMeta})
+	svc.Handlers.UnmarshalError.PushBackNamed(request.NamedHandler{Name: "fn.dynamodb.UnmarshalError", Fn: unmarshalError})
+	return svc
+}
+
+func (c *dynamoClient) call(ctx aws.Context, action string, input, output interface{}) error {
+	req := c.NewRequest(&request.Operation{Name: action, HTTPMethod: "POST", HTTPPath: "/"}, input, output)
+	req.SetContext(ctx)
+	return req.Send()
+}
+
+func buildRequest(r *request.Request) {
+	buf, err := json.Marshal(r.Params)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed encoding dynamodb request", err)
+		return
+	}
+	r.SetBufferBody(buf)
+	r.HTTPRequest.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	r.HTTPRequest.Header.Set("X-Amz-Target", targetPrefix+"."+r.Operation.Name)
+}
+
+func unmarshalResponse(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	if r.Data == nil {
+		return
+	}
+	if err := json.NewDecoder(r.HTTPResponse.Body).Decode(r.Data); err != nil {
+		r.Error = awserr.New("SerializationError", "failed decoding dynamodb response", err)
+	}
+}
+
+func unmarshalMeta(r *request.Request) {
+	r.RequestID = r.HTTPResponse.Header.Get("X-Amzn-Requestid")
+}
+
+func unmarshalError(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	body, err := ioutil.ReadAll(r.HTTPResponse.Body)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed reading dynamodb error response", err)
+		return
+	}
+
+	var e struct {
+		Type                string `json:"__type"`
+		Message             string `json:"message"`
+		MessageUpper        string `json:"Message"`
+		CancellationReasons []struct {
+			Code string `json:"Code"`
+		} `json:"CancellationReasons"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil || e.Type == "" {
+		r.Error = awserr.NewRequestFailure(awserr.New("UnknownError", string(body), nil), r.HTTPResponse.StatusCode, r.RequestID)
+		return
+	}
+
+	apiErr := &apiError{
+		code:    e.Type[strings.LastIndex(e.Type, "#")+1:],
+		message: e.Message,
+	}
+	if apiErr.message == "" {
+		apiErr.message = e.MessageUpper
+	}
+	for _, reason := range e.CancellationReasons {
+		apiErr.reasons = append(apiErr.reasons, reason.Code)
+	}
+	r.Error = apiErr
+}
+
+// apiError is an error returned by DynamoDB, reasons are the cancellation
+// reasons of the items of a canceled transaction.
+type apiError struct {
+	code    string
+	message string
+	reasons []string
+}
+
+func (e *apiError) Code() string    { return e.code }
+func (e *apiError) Message() string { return e.message }
+func (e *apiError) OrigErr() error  { return nil }
+func (e *apiError) Error() string   { return fmt.Sprintf("dynamodb: %s: %s", e.code, e.message) }
+
+// errorCode returns the DynamoDB error code of err, if any
+func errorCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return ""
+}
+
+// conditionFailed returns whether the condition of item i of a canceled
+// transaction failed
+func conditionFailed(err error, i int) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && i < len(apiErr.reasons) && apiErr.reasons[i] == "ConditionalCheckFailed"
+}
+
+// attributeValue is a string attribute, the only type the store uses
+type attributeValue struct {
+	S string `json:"S"`
+}
+
+type item map[string]attributeValue
+
+type createTableInput struct {
+	TableName            string
+	AttributeDefinitions []attributeDefinition
+	KeySchema            []keySchemaElement
+	BillingMode          string
+}
+
+type attributeDefinition struct {
+	AttributeName string
+	AttributeType string
+}
+
+type keySchemaElement struct {
+	AttributeName string
+	KeyType       string
+}
+
+type describeTableInput struct {
+	TableName string
+}
+
+type describeTableOutput struct {
+	Table struct {
+		TableStatus string
+	}
+}
+
+type getItemInput struct {
+	TableName      string
+	Key            item
+	ConsistentRead bool
+}
+
+type getItemOutput struct {
+	Item item
+}
+
+type putItemInput struct {
+	TableName           string
+	Item                item
+	ConditionExpression string `json:",omitempty"`
+}
+
+type queryInput struct {
+	TableName                 string
+	KeyConditionExpression    string
+	ExpressionAttributeValues item
+	ExclusiveStartKey         item  `json:",omitempty"`
+	ScanIndexForward          *bool `json:",omitempty"`
+	Limit                     int64 `json:",omitempty"`
+	ConsistentRead            bool
+}
+
+type queryOutput struct {
+	Items            []item
+	LastEvaluatedKey item
+}
+
+type transactWriteItemsInput struct {
+	TransactItems []transactWriteItem
+}
+
+type transactWriteItem struct {
+	ConditionCheck *keyCondition `json:",omitempty"`
+	Put            *putItemInput `json:",omitempty"`
+	Delete         *keyCondition `json:",omitempty"`
+}
+
+type keyCondition struct {
+	TableName           string
+	Key                 item
+	ConditionExpression string `json:",omitempty"`
+}
+
+type batchWriteItemInput struct {
+	RequestItems map[string][]writeRequest
+}
+
+type batchWriteItemOutput struct {
+	UnprocessedItems map[string][]writeRequest
+}
+
+type writeRequest struct {
+	DeleteRequest *deleteRequest `json:",omitempty"`
+}
+
+type deleteRequest struct {
+	Key item
+}
+
+type emptyOutput struct{}
@@ -0,0 +1,98 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// maxLogSize is how much of a log is stored, items are limited to 400KB
+const maxLogSize = 384 * 1024
+
+func (ds *DynamoStore) InsertCall(ctx context.Context, call *models.Call) error {
+	it, err := withData(callKey(call.FnID, call.ID), call)
+	if err != nil {
+		return err
+	}
+
+	return ds.client.call(ctx, "PutItem", &putItemInput{TableName: ds.table, Item: it, ConditionExpression: condNotExists}, &emptyOutput{})
+}
+
+func (ds *DynamoStore) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	var call models.Call
+	ok, err := ds.get(ctx, callKey(fnID, callID), &call)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrCallNotFound
+	}
+	return &call, nil
+}
+
+// GetCalls returns the calls of a fn newest first, listing calls across fns is not supported
+func (ds *DynamoStore) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	res := &models.CallList{Items: []*models.Call{}}
+	if filter.FnID == "" {
+		return res, nil
+	}
+
+	q := rangeQuery{pk: callsPK(filter.FnID), desc: true}
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.op, q.sk = "<", cursor
+	}
+
+	// times of calls are stored in milliseconds
+	from, to := time.Time(filter.FromTime).Truncate(time.Millisecond), time.Time(filter.ToTime).Truncate(time.Millisecond)
+	err := ds.query(ctx, q, filter.PerPage, func(it item) (bool, error) {
+		var call models.Call
+		if err := decode(it, &call); err != nil {
+			return false, err
+		}
+		createdAt := time.Time(call.CreatedAt)
+		if (!to.IsZero() && !createdAt.Before(to)) || (!from.IsZero() && !createdAt.After(from)) || !filter.MatchOutcome(&call) {
+			return false, nil
+		}
+		res.Items = append(res.Items, &call)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		res.NextCursor = encodeCursor(res.Items[len(res.Items)-1].ID)
+	}
+	return res, nil
+}
+
+// InsertLog stores the first maxLogSize bytes of a log
+func (ds *DynamoStore) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, io.LimitReader(callLog, maxLogSize)); err != nil {
+		return err
+	}
+	it := logKey(call.FnID, call.ID)
+	it["log"] = attributeValue{S: b.String()}
+
+	return ds.client.call(ctx, "PutItem", &putItemInput{TableName: ds.table, Item: it}, &emptyOutput{})
+}
+
+func (ds *DynamoStore) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	it, err := ds.getItem(ctx, logKey(fnID, callID))
+	if err != nil {
+		return nil, err
+	}
+	if it == nil {
+		return nil, models.ErrCallLogNotFound
+	}
+	return strings.NewReader(it["log"].S), nil
+}
@@ -0,0 +1,901 @@
+// Package dynamodb is a datastore and log store backed by a single DynamoDB table.
+//
+// # Key schema
+//
+// The table has a string partition key pk and a string sort key sk. Apps, fns
+// and triggers are stored once for every way they are looked up, the items of
+// an object are written together in a transaction:
+//
+//	pk                       sk                        item
+//	app#<app id>             app                       app by id
+//	apps                     <app name>                apps by name
+//	fn#<fn id>               fn                        fn by id
+//	app#<app id>#fns         <fn name>                 fns of an app by name
+//	trigger#<trigger id>     trigger                   trigger by id
+//	app#<app id>#triggers    <trigger name>#<fn id>    triggers of an app by name
+//	app#<app id>#sources     <type>#<source>           trigger by source
+//	fn#<fn id>#calls         <call id>                 calls of a fn
+//	fn#<fn id>#logs          <call id>                 log of a call
+//
+// Items hold the object as JSON in their data attribute, logs their text in
+// the log attribute. The cursors of lists are, as for the other datastores,
+// the base64 encoded name of the last app, fn or trigger, or id of the last
+// call, which map to the sort keys to continue a list query after.
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTable = "fn"
+
+	condNotExists = "attribute_not_exists(pk)"
+	condExists    = "attribute_exists(pk)"
+
+	// batchSize is the most items a BatchWriteItem call takes
+	batchSize = 25
+)
+
+var ( // compiler will yell nice things about our upbringing as a child
+	_ models.Datastore = new(DynamoStore)
+	_ models.LogStore  = new(DynamoStore)
+)
+
+type dynamoDsProvider int
+
+func (dynamoDsProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "dynamodb"
+}
+
+func (dynamoDsProvider) New(ctx context.Context, u *url.URL) (models.Datastore, error) {
+	return New(ctx, u)
+}
+
+func (dynamoDsProvider) String() string {
+	return "dynamodb"
+}
+
+type dynamoLogsProvider int
+
+func (dynamoLogsProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "dynamodb"
+}
+
+func (dynamoLogsProvider) New(ctx context.Context, u *url.URL) (models.LogStore, error) {
+	return New(ctx, u)
+}
+
+func (dynamoLogsProvider) String() string {
+	return "dynamodb"
+}
+
+// DynamoStore is a models.Datastore and models.LogStore storing everything in
+// one DynamoDB table, see the package doc for its key schema.
+type DynamoStore struct {
+	client *dynamoClient
+	table  string
+}
+
+// New returns a DynamoDB store for a URL of the form
+// dynamodb://[access_key_id:secret_access_key@]host/region/table?ssl=true
+//
+// host may be left empty to use the public endpoint of the region, and the
+// credentials to use the default aws credential chain. The table is created,
+// billed per request, if it does not exist.
+func New(ctx context.Context, u *url.URL) (*DynamoStore, error) {
+	strs := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+	region := strs[0]
+	if region == "" {
+		return nil, errors.New("must provide a region in the path of the dynamodb url, e.g. dynamodb:///us-east-1/fn")
+	}
+	table := defaultTable
+	if len(strs) == 2 && strs[1] != "" {
+		table = strs[1]
+	}
+
+	config := &aws.Config{
+		Region:     aws.String(region),
+		DisableSSL: aws.Bool(u.Host != "" && u.Query().Get("ssl") == "false"),
+	}
+	if u.Host != "" {
+		config.Endpoint = aws.String(u.Host)
+	}
+	if u.User != nil {
+		secret, _ := u.User.Password()
+		config.Credentials = credentials.NewStaticCredentials(u.User.Username(), secret, "")
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &DynamoStore{client: newDynamoClient(sess), table: table}
+
+	log := common.Logger(ctx).WithFields(logrus.Fields{"table": table, "region": region, "endpoint": u.Host})
+	if err := ds.ensureTable(ctx); err != nil {
+		log.WithError(err).Error("Error creating dynamodb table")
+		return nil, err
+	}
+	log.Info("DynamoDB store initialized")
+	return ds, nil
+}
+
+// ensureTable creates the table if it does not exist and waits for it to be active
+func (ds *DynamoStore) ensureTable(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	for {
+		var out describeTableOutput
+		err := ds.client.call(ctx, "DescribeTable", &describeTableInput{TableName: ds.table}, &out)
+		switch {
+		case errorCode(err) == "ResourceNotFoundException":
+			err = ds.client.call(ctx, "CreateTable", &createTableInput{
+				TableName: ds.table,
+				AttributeDefinitions: []attributeDefinition{
+					{AttributeName: "pk", AttributeType: "S"},
+					{AttributeName: "sk", AttributeType: "S"},
+				},
+				KeySchema: []keySchemaElement{
+					{AttributeName: "pk", KeyType: "HASH"},
+					{AttributeName: "sk", KeyType: "RANGE"},
+				},
+				BillingMode: "PAY_PER_REQUEST",
+			}, &emptyOutput{})
+			// another server may have created the table meanwhile
+			if err != nil && errorCode(err) != "ResourceInUseException" {
+				return err
+			}
+		case err != nil:
+			return err
+		case out.Table.TableStatus == "ACTIVE" || out.Table.TableStatus == "UPDATING":
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func key(pk, sk string) item {
+	return item{"pk": {S: pk}, "sk": {S: sk}}
+}
+
+func appKey(appID string) item              { return key("app#"+appID, "app") }
+func appNameKey(name string) item           { return key("apps", name) }
+func fnKey(fnID string) item                { return key("fn#"+fnID, "fn") }
+func fnsPK(appID string) string             { return "app#" + appID + "#fns" }
+func fnNameKey(appID, name string) item     { return key(fnsPK(appID), name) }
+func triggerKey(triggerID string) item      { return key("trigger#"+triggerID, "trigger") }
+func triggersPK(appID string) string        { return "app#" + appID + "#triggers" }
+func sourcesPK(appID string) string         { return "app#" + appID + "#sources" }
+func callsPK(fnID string) string            { return "fn#" + fnID + "#calls" }
+func logsPK(fnID string) string             { return "fn#" + fnID + "#logs" }
+func callKey(fnID, callID string) item      { return key(callsPK(fnID), callID) }
+func logKey(fnID, callID string) item       { return key(logsPK(fnID), callID) }
+func triggerNameKey(t *models.Trigger) item { return key(triggersPK(t.AppID), t.Name+"#"+t.FnID) }
+
+func triggerSourceKey(appID, triggerType, source string) item {
+	return key(sourcesPK(appID), triggerType+"#"+source)
+}
+
+// withData returns the item of key k holding v
+func withData(k item, v interface{}) (item, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	it := key(k["pk"].S, k["sk"].S)
+	it["data"] = attributeValue{S: string(buf)}
+	return it, nil
+}
+
+func decode(it item, v interface{}) error {
+	return json.Unmarshal([]byte(it["data"].S), v)
+}
+
+func (ds *DynamoStore) getItem(ctx context.Context, k item) (item, error) {
+	var out getItemOutput
+	err := ds.client.call(ctx, "GetItem", &getItemInput{TableName: ds.table, Key: k, ConsistentRead: true}, &out)
+	return out.Item, err
+}
+
+// get decodes the data of the item of key k into v, returning false if there is no such item
+func (ds *DynamoStore) get(ctx context.Context, k item, v interface{}) (bool, error) {
+	it, err := ds.getItem(ctx, k)
+	if err != nil || it == nil {
+		return false, err
+	}
+	return true, decode(it, v)
+}
+
+func (ds *DynamoStore) put(it item, cond string) transactWriteItem {
+	return transactWriteItem{Put: &putItemInput{TableName: ds.table, Item: it, ConditionExpression: cond}}
+}
+
+func (ds *DynamoStore) delete(k item, cond string) transactWriteItem {
+	return transactWriteItem{Delete: &keyCondition{TableName: ds.table, Key: k, ConditionExpression: cond}}
+}
+
+func (ds *DynamoStore) check(k item, cond string) transactWriteItem {
+	return transactWriteItem{ConditionCheck: &keyCondition{TableName: ds.table, Key: k, ConditionExpression: cond}}
+}
+
+func (ds *DynamoStore) transact(ctx context.Context, items ...transactWriteItem) error {
+	return ds.client.call(ctx, "TransactWriteItems", &transactWriteItemsInput{TransactItems: items}, &emptyOutput{})
+}
+
+// rangeQuery selects the items of a partition
+type rangeQuery struct {
+	pk   string
+	op   string // condition on the sort key, >, < or begins_with, empty for all items
+	sk   string
+	desc bool
+}
+
+// query calls each for the items of a range in sort key order until limit
+// items were accepted, or for all items if limit is not positive.
+func (ds *DynamoStore) query(ctx context.Context, q rangeQuery, limit int, each func(it item) (bool, error)) error {
+	in := &queryInput{
+		TableName:                 ds.table,
+		KeyConditionExpression:    "pk = :pk",
+		ExpressionAttributeValues: item{":pk": {S: q.pk}},
+		ConsistentRead:            true,
+	}
+	switch q.op {
+	case "":
+	case "begins_with":
+		in.KeyConditionExpression += " AND begins_with(sk, :sk)"
+	default:
+		in.KeyConditionExpression += " AND sk " + q.op + " :sk"
+	}
+	if q.op != "" {
+		in.ExpressionAttributeValues[":sk"] = attributeValue{S: q.sk}
+	}
+	if q.desc {
+		in.ScanIndexForward = aws.Bool(false)
+	}
+
+	n := 0
+	for {
+		if limit > 0 {
+			in.Limit = int64(limit - n)
+		}
+		var out queryOutput
+		if err := ds.client.call(ctx, "Query", in, &out); err != nil {
+			return err
+		}
+		for _, it := range out.Items {
+			ok, err := each(it)
+			if err != nil {
+				return err
+			}
+			if ok {
+				n++
+				if limit > 0 && n == limit {
+					return nil
+				}
+			}
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// batchDelete deletes the items of keys, which are not deleted atomically
+func (ds *DynamoStore) batchDelete(ctx context.Context, keys []item) error {
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > batchSize {
+			n = batchSize
+		}
+		reqs := make([]writeRequest, n)
+		for i, k := range keys[:n] {
+			reqs[i].DeleteRequest = &deleteRequest{Key: k}
+		}
+		keys = keys[n:]
+
+		pending := map[string][]writeRequest{ds.table: reqs}
+		for attempt := 0; len(pending[ds.table]) > 0; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Duration(attempt) * 50 * time.Millisecond):
+				}
+			}
+			var out batchWriteItemOutput
+			if err := ds.client.call(ctx, "BatchWriteItem", &batchWriteItemInput{RequestItems: pending}, &out); err != nil {
+				return err
+			}
+			pending = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// deletePartition deletes all the items of a partition
+func (ds *DynamoStore) deletePartition(ctx context.Context, pk string) error {
+	var keys []item
+	err := ds.query(ctx, rangeQuery{pk: pk}, 0, func(it item) (bool, error) {
+		keys = append(keys, key(it["pk"].S, it["sk"].S))
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return ds.batchDelete(ctx, keys)
+}
+
+func (ds *DynamoStore) GetAppID(ctx context.Context, appName string) (string, error) {
+	var app models.App
+	ok, err := ds.get(ctx, appNameKey(appName), &app)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", models.ErrAppsNotFound
+	}
+	return app.ID, nil
+}
+
+func (ds *DynamoStore) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	var app models.App
+	ok, err := ds.get(ctx, appKey(appID), &app)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrAppsNotFound
+	}
+	return &app, nil
+}
+
+// decodeCursor returns the sort key a cursor continues after
+func decodeCursor(cursor string) (string, error) {
+	s, err := base64.RawURLEncoding.DecodeString(cursor)
+	return string(s), err
+}
+
+func encodeCursor(sk string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sk))
+}
+
+// afterCursor returns the range of a partition after a cursor, which is the
+// whole partition for an empty cursor
+func afterCursor(pk, cursor string) (rangeQuery, error) {
+	q := rangeQuery{pk: pk}
+	if cursor != "" {
+		sk, err := decodeCursor(cursor)
+		if err != nil {
+			return q, err
+		}
+		q.op, q.sk = ">", sk
+	}
+	return q, nil
+}
+
+func (ds *DynamoStore) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
+	if filter == nil {
+		filter = new(models.AppFilter)
+	}
+	res := &models.AppList{Items: []*models.App{}}
+
+	q, err := afterCursor("apps", filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Name != "" {
+		if q.op != "" && filter.Name <= q.sk {
+			return res, nil
+		}
+		q.op, q.sk = "begins_with", filter.Name
+	}
+
+	err = ds.query(ctx, q, filter.PerPage, func(it item) (bool, error) {
+		if filter.Name != "" && it["sk"].S != filter.Name {
+			return false, nil
+		}
+		var app models.App
+		if err := decode(it, &app); err != nil {
+			return false, err
+		}
+		if filter.NamespaceID != "" && app.NamespaceID != filter.NamespaceID {
+			return false, nil
+		}
+		res.Items = append(res.Items, &app)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		res.NextCursor = encodeCursor(res.Items[len(res.Items)-1].Name)
+	}
+	return res, nil
+}
+
+func (ds *DynamoStore) InsertApp(ctx context.Context, newApp *models.App) (*models.App, error) {
+	app := newApp.Clone()
+	app.CreatedAt = common.DateTime(time.Now())
+	app.UpdatedAt = app.CreatedAt
+	app.ID = id.New().String()
+
+	if app.Config == nil {
+		// keeps the JSON from being nil
+		app.Config = map[string]string{}
+	}
+
+	byID, err := withData(appKey(app.ID), app)
+	if err != nil {
+		return nil, err
+	}
+	byName, err := withData(appNameKey(app.Name), app)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.transact(ctx, ds.put(byID, condNotExists), ds.put(byName, condNotExists))
+	if conditionFailed(err, 1) {
+		return nil, models.ErrAppsAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func (ds *DynamoStore) UpdateApp(ctx context.Context, newapp *models.App) (*models.App, error) {
+	app, err := ds.GetAppByID(ctx, newapp.ID)
+	if err != nil {
+		return nil, err
+	}
+	if newapp.Name != "" && app.Name != newapp.Name {
+		return nil, models.ErrAppsNameImmutable
+	}
+	app.Update(newapp)
+	if err := app.Validate(); err != nil {
+		return nil, err
+	}
+
+	byID, err := withData(appKey(app.ID), app)
+	if err != nil {
+		return nil, err
+	}
+	byName, err := withData(appNameKey(app.Name), app)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.transact(ctx, ds.put(byID, condExists), ds.put(byName, ""))
+	if conditionFailed(err, 0) {
+		return nil, models.ErrAppsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// RemoveApp removes an app, then its fns, triggers, calls and logs
+func (ds *DynamoStore) RemoveApp(ctx context.Context, appID string) error {
+	app, err := ds.GetAppByID(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	err = ds.transact(ctx, ds.delete(appKey(appID), condExists), ds.delete(appNameKey(app.Name), ""))
+	if conditionFailed(err, 0) {
+		return models.ErrAppsNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var keys []item
+	var fnIDs []string
+	err = ds.query(ctx, rangeQuery{pk: fnsPK(appID)}, 0, func(it item) (bool, error) {
+		var fn models.Fn
+		if err := decode(it, &fn); err != nil {
+			return false, err
+		}
+		fnIDs = append(fnIDs, fn.ID)
+		keys = append(keys, fnKey(fn.ID))
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	err = ds.query(ctx, rangeQuery{pk: triggersPK(appID)}, 0, func(it item) (bool, error) {
+		var t models.Trigger
+		if err := decode(it, &t); err != nil {
+			return false, err
+		}
+		keys = append(keys, triggerKey(t.ID))
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := ds.batchDelete(ctx, keys); err != nil {
+		return err
+	}
+
+	pks := []string{fnsPK(appID), triggersPK(appID), sourcesPK(appID)}
+	for _, fnID := range fnIDs {
+		pks = append(pks, callsPK(fnID), logsPK(fnID))
+	}
+	for _, pk := range pks {
+		if err := ds.deletePartition(ctx, pk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds *DynamoStore) InsertFn(ctx context.Context, newFn *models.Fn) (*models.Fn, error) {
+	fn := newFn.Clone()
+	fn.ID = id.New().String()
+	fn.CreatedAt = common.DateTime(time.Now())
+	fn.UpdatedAt = fn.CreatedAt
+
+	if err := newFn.Validate(); err != nil {
+		return nil, err
+	}
+
+	byID, err := withData(fnKey(fn.ID), fn)
+	if err != nil {
+		return nil, err
+	}
+	byName, err := withData(fnNameKey(fn.AppID, fn.Name), fn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.transact(ctx,
+		ds.check(appKey(fn.AppID), condExists),
+		ds.put(byID, condNotExists),
+		ds.put(byName, condNotExists))
+	switch {
+	case conditionFailed(err, 0):
+		return nil, models.ErrAppsNotFound
+	case conditionFailed(err, 2):
+		return nil, models.ErrFnsExists
+	case err != nil:
+		return nil, err
+	}
+	return fn, nil
+}
+
+func (ds *DynamoStore) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	dst, err := ds.GetFnByID(ctx, fn.ID)
+	if err != nil {
+		return nil, err
+	}
+	dst.Update(fn)
+	if err := dst.Validate(); err != nil {
+		return nil, err
+	}
+
+	byID, err := withData(fnKey(dst.ID), dst)
+	if err != nil {
+		return nil, err
+	}
+	byName, err := withData(fnNameKey(dst.AppID, dst.Name), dst)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.transact(ctx, ds.put(byID, condExists), ds.put(byName, ""))
+	if conditionFailed(err, 0) {
+		return nil, models.ErrFnsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+func (ds *DynamoStore) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnList, error) {
+	if filter == nil {
+		filter = new(models.FnFilter)
+	}
+	res := &models.FnList{Items: []*models.Fn{}}
+
+	q, err := afterCursor(fnsPK(filter.AppID), filter.Cursor)
+	if err != nil {
+		return res, err
+	}
+	if filter.Name != "" {
+		if q.op != "" && filter.Name <= q.sk {
+			return res, nil
+		}
+		q.op, q.sk = "begins_with", filter.Name
+	}
+
+	err = ds.query(ctx, q, filter.PerPage, func(it item) (bool, error) {
+		if filter.Name != "" && it["sk"].S != filter.Name {
+			return false, nil
+		}
+		var fn models.Fn
+		if err := decode(it, &fn); err != nil {
+			return false, err
+		}
+		res.Items = append(res.Items, &fn)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		res.NextCursor = encodeCursor(res.Items[len(res.Items)-1].Name)
+	}
+	return res, nil
+}
+
+func (ds *DynamoStore) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	var fn models.Fn
+	ok, err := ds.get(ctx, fnKey(fnID), &fn)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrFnsNotFound
+	}
+	return &fn, nil
+}
+
+// RemoveFn removes a fn, then its triggers
+func (ds *DynamoStore) RemoveFn(ctx context.Context, fnID string) error {
+	fn, err := ds.GetFnByID(ctx, fnID)
+	if err != nil {
+		return err
+	}
+
+	err = ds.transact(ctx, ds.delete(fnKey(fnID), condExists), ds.delete(fnNameKey(fn.AppID, fn.Name), ""))
+	if conditionFailed(err, 0) {
+		return models.ErrFnsNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var keys []item
+	err = ds.query(ctx, rangeQuery{pk: triggersPK(fn.AppID)}, 0, func(it item) (bool, error) {
+		var t models.Trigger
+		if err := decode(it, &t); err != nil {
+			return false, err
+		}
+		if t.FnID == fnID {
+			keys = append(keys, triggerKey(t.ID), triggerNameKey(&t), triggerSourceKey(t.AppID, t.Type, t.Source))
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return ds.batchDelete(ctx, keys)
+}
+
+// triggerItems returns the items of a trigger by id, name and source
+func triggerItems(t *models.Trigger) (byID, byName, bySource item, err error) {
+	if byID, err = withData(triggerKey(t.ID), t); err != nil {
+		return
+	}
+	if byName, err = withData(triggerNameKey(t), t); err != nil {
+		return
+	}
+	bySource, err = withData(triggerSourceKey(t.AppID, t.Type, t.Source), t)
+	return
+}
+
+func (ds *DynamoStore) InsertTrigger(ctx context.Context, newTrigger *models.Trigger) (*models.Trigger, error) {
+	trigger := newTrigger.Clone()
+	trigger.CreatedAt = common.DateTime(time.Now())
+	trigger.UpdatedAt = trigger.CreatedAt
+	trigger.ID = id.New().String()
+
+	if err := trigger.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := ds.GetAppByID(ctx, trigger.AppID); err != nil {
+		return nil, err
+	}
+	fn, err := ds.GetFnByID(ctx, trigger.FnID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.AppID != trigger.AppID {
+		return nil, models.ErrTriggerFnIDNotSameApp
+	}
+
+	byID, byName, bySource, err := triggerItems(trigger)
+	if err != nil {
+		return nil, err
+	}
+	err = ds.transact(ctx,
+		ds.check(fnKey(trigger.FnID), condExists),
+		ds.put(byID, condNotExists),
+		ds.put(byName, condNotExists),
+		ds.put(bySource, condNotExists))
+	switch {
+	case conditionFailed(err, 0):
+		return nil, models.ErrFnsNotFound
+	case conditionFailed(err, 3):
+		return nil, models.ErrTriggerSourceExists
+	case conditionFailed(err, 2):
+		return nil, models.ErrTriggerExists
+	case err != nil:
+		return nil, err
+	}
+	return trigger, nil
+}
+
+func (ds *DynamoStore) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	old, err := ds.GetTriggerByID(ctx, trigger.ID)
+	if err != nil {
+		return nil, err
+	}
+	dst := old.Clone()
+	dst.Update(trigger)
+	if err := dst.Validate(); err != nil {
+		return nil, err
+	}
+
+	byID, byName, bySource, err := triggerItems(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	// a renamed or moved trigger must not replace another one
+	items := []transactWriteItem{ds.put(byID, condExists)}
+	nameIdx, sourceIdx := -1, -1
+	if oldKey := triggerNameKey(old); oldKey["sk"].S != byName["sk"].S {
+		items = append(items, ds.delete(oldKey, ""), ds.put(byName, condNotExists))
+		nameIdx = len(items) - 1
+	} else {
+		items = append(items, ds.put(byName, ""))
+	}
+	if oldKey := triggerSourceKey(old.AppID, old.Type, old.Source); oldKey["sk"].S != bySource["sk"].S {
+		items = append(items, ds.delete(oldKey, ""), ds.put(bySource, condNotExists))
+		sourceIdx = len(items) - 1
+	} else {
+		items = append(items, ds.put(bySource, ""))
+	}
+
+	err = ds.transact(ctx, items...)
+	switch {
+	case conditionFailed(err, 0):
+		return nil, models.ErrTriggerNotFound
+	case conditionFailed(err, sourceIdx):
+		return nil, models.ErrTriggerSourceExists
+	case conditionFailed(err, nameIdx):
+		return nil, models.ErrTriggerExists
+	case err != nil:
+		return nil, err
+	}
+	return dst, nil
+}
+
+func (ds *DynamoStore) RemoveTrigger(ctx context.Context, triggerID string) error {
+	t, err := ds.GetTriggerByID(ctx, triggerID)
+	if err != nil {
+		return err
+	}
+
+	err = ds.transact(ctx,
+		ds.delete(triggerKey(triggerID), condExists),
+		ds.delete(triggerNameKey(t), ""),
+		ds.delete(triggerSourceKey(t.AppID, t.Type, t.Source), ""))
+	if conditionFailed(err, 0) {
+		return models.ErrTriggerNotFound
+	}
+	return err
+}
+
+func (ds *DynamoStore) GetTriggerByID(ctx context.Context, triggerID string) (*models.Trigger, error) {
+	var t models.Trigger
+	ok, err := ds.get(ctx, triggerKey(triggerID), &t)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrTriggerNotFound
+	}
+	return &t, nil
+}
+
+func (ds *DynamoStore) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*models.TriggerList, error) {
+	if filter == nil {
+		filter = new(models.TriggerFilter)
+	}
+	res := &models.TriggerList{Items: []*models.Trigger{}}
+
+	q, err := afterCursor(triggersPK(filter.AppID), filter.Cursor)
+	if err != nil {
+		return res, err
+	}
+	if q.op != "" {
+		// '$' follows the '#' separating names from fn ids and precedes all
+		// characters of names, so this skips all triggers of the cursor name
+		q.sk += "$"
+	}
+	if filter.Name != "" {
+		if q.op != "" && filter.Name+"$" <= q.sk {
+			return res, nil
+		}
+		q.op, q.sk = "begins_with", filter.Name+"#"
+	}
+
+	err = ds.query(ctx, q, filter.PerPage, func(it item) (bool, error) {
+		var t models.Trigger
+		if err := decode(it, &t); err != nil {
+			return false, err
+		}
+		if filter.FnID != "" && t.FnID != filter.FnID {
+			return false, nil
+		}
+		res.Items = append(res.Items, &t)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		res.NextCursor = encodeCursor(res.Items[len(res.Items)-1].Name)
+	}
+	return res, nil
+}
+
+func (ds *DynamoStore) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	var t models.Trigger
+	ok, err := ds.get(ctx, triggerSourceKey(appID, triggerType, source), &t)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrTriggerNotFound
+	}
+	return &t, nil
+}
+
+// Close implements io.Closer, the store holds no resources
+func (ds *DynamoStore) Close() error {
+	return nil
+}
+
+func (ds *DynamoStore) String() string {
+	return fmt.Sprintf("dynamodb table %s", ds.table)
+}
+
+func init() {
+	datastore.Register(dynamoDsProvider(0))
+	logs.Register(dynamoLogsProvider(0))
+}
@@ -0,0 +1,222 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
+	logstoretest "github.com/fnproject/fn/api/logs/testing"
+	"github.com/fnproject/fn/api/models"
+)
+
+// fakeDynamo serves the DynamoDB calls the store makes from memory
+type fakeDynamo struct {
+	sync.Mutex
+	table    bool
+	items    map[string]map[string]item
+	pageSize int // most items a query returns, to exercise paging
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: make(map[string]map[string]item), pageSize: 3}
+}
+
+func (f *fakeDynamo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), targetPrefix+".")
+	var out interface{} = struct{}{}
+	var err *fakeError
+	dec := json.NewDecoder(r.Body)
+	switch op {
+	case "DescribeTable":
+		if !f.table {
+			err = &fakeError{Type: "ResourceNotFoundException"}
+			break
+		}
+		var res describeTableOutput
+		res.Table.TableStatus = "ACTIVE"
+		out = res
+	case "CreateTable":
+		f.table = true
+	case "GetItem":
+		var in getItemInput
+		dec.Decode(&in)
+		out = getItemOutput{Item: f.get(in.Key)}
+	case "PutItem":
+		var in putItemInput
+		dec.Decode(&in)
+		if !f.check(in.Item, in.ConditionExpression) {
+			err = &fakeError{Type: "ConditionalCheckFailedException"}
+			break
+		}
+		f.put(in.Item)
+	case "Query":
+		var in queryInput
+		dec.Decode(&in)
+		out = f.query(&in)
+	case "TransactWriteItems":
+		var in transactWriteItemsInput
+		dec.Decode(&in)
+		err = f.transact(&in)
+	case "BatchWriteItem":
+		var in batchWriteItemInput
+		dec.Decode(&in)
+		for _, reqs := range in.RequestItems {
+			for _, req := range reqs {
+				f.delete(req.DeleteRequest.Key)
+			}
+		}
+	default:
+		err = &fakeError{Type: "UnknownOperationException"}
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	if err != nil {
+		err.Type = "com.amazonaws.dynamodb.v20120810#" + err.Type
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err)
+		return
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+type fakeError struct {
+	Type                string `json:"__type"`
+	Message             string
+	CancellationReasons []struct{ Code string } `json:",omitempty"`
+}
+
+func (f *fakeDynamo) get(k item) item {
+	return f.items[k["pk"].S][k["sk"].S]
+}
+
+func (f *fakeDynamo) put(it item) {
+	pk := it["pk"].S
+	if f.items[pk] == nil {
+		f.items[pk] = make(map[string]item)
+	}
+	f.items[pk][it["sk"].S] = it
+}
+
+func (f *fakeDynamo) delete(k item) {
+	delete(f.items[k["pk"].S], k["sk"].S)
+}
+
+func (f *fakeDynamo) check(k item, cond string) bool {
+	switch cond {
+	case condExists:
+		return f.get(k) != nil
+	case condNotExists:
+		return f.get(k) == nil
+	}
+	return true
+}
+
+func (f *fakeDynamo) query(in *queryInput) queryOutput {
+	values := in.ExpressionAttributeValues
+	cond := strings.TrimPrefix(in.KeyConditionExpression, "pk = :pk")
+	sk := values[":sk"].S
+
+	var sks []string
+	for s := range f.items[values[":pk"].S] {
+		ok := true
+		switch cond {
+		case " AND sk > :sk":
+			ok = s > sk
+		case " AND sk < :sk":
+			ok = s < sk
+		case " AND begins_with(sk, :sk)":
+			ok = strings.HasPrefix(s, sk)
+		}
+		if ok {
+			sks = append(sks, s)
+		}
+	}
+	sort.Strings(sks)
+	desc := in.ScanIndexForward != nil && !*in.ScanIndexForward
+	if desc {
+		sort.Sort(sort.Reverse(sort.StringSlice(sks)))
+	}
+
+	var out queryOutput
+	for _, s := range sks {
+		if start := in.ExclusiveStartKey; start != nil {
+			if (!desc && s <= start["sk"].S) || (desc && s >= start["sk"].S) {
+				continue
+			}
+		}
+		if len(out.Items) == f.pageSize || (in.Limit > 0 && int64(len(out.Items)) == in.Limit) {
+			last := out.Items[len(out.Items)-1]
+			out.LastEvaluatedKey = key(last["pk"].S, last["sk"].S)
+			break
+		}
+		out.Items = append(out.Items, f.items[values[":pk"].S][s])
+	}
+	return out
+}
+
+func (f *fakeDynamo) transact(in *transactWriteItemsInput) *fakeError {
+	err := &fakeError{Type: "TransactionCanceledException"}
+	failed := false
+	for _, it := range in.TransactItems {
+		ok := true
+		switch {
+		case it.ConditionCheck != nil:
+			ok = f.check(it.ConditionCheck.Key, it.ConditionCheck.ConditionExpression)
+		case it.Delete != nil:
+			ok = f.check(it.Delete.Key, it.Delete.ConditionExpression)
+		case it.Put != nil:
+			ok = f.check(it.Put.Item, it.Put.ConditionExpression)
+		}
+		code := "None"
+		if !ok {
+			code, failed = "ConditionalCheckFailed", true
+		}
+		err.CancellationReasons = append(err.CancellationReasons, struct{ Code string }{code})
+	}
+	if failed {
+		return err
+	}
+
+	for _, it := range in.TransactItems {
+		switch {
+		case it.Delete != nil:
+			f.delete(it.Delete.Key)
+		case it.Put != nil:
+			f.put(it.Put.Item)
+		}
+	}
+	return nil
+}
+
+func newTestStore(t *testing.T) *DynamoStore {
+	srv := httptest.NewServer(newFakeDynamo())
+	u, err := url.Parse("dynamodb://key:secret@" + strings.TrimPrefix(srv.URL, "http://") + "/us-east-1/fn?ssl=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := New(context.Background(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func TestDatastore(t *testing.T) {
+	f := func(t *testing.T) models.Datastore {
+		return datastoreutil.NewValidator(newTestStore(t))
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+
+	logstoretest.Test(t, newTestStore(t))
+}
@@ -5,6 +5,7 @@ import (
 
 	"go.opencensus.io/trace"
 
+	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 )
 
@@ -119,6 +120,234 @@ func (m *metricds) RemoveFn(ctx context.Context, fnID string) error {
 	return m.ds.RemoveFn(ctx, fnID)
 }
 
+func (m *metricds) Changes(ctx context.Context) (<-chan *models.Change, error) {
+	cf, ok := m.ds.(models.ChangeFeed)
+	if !ok {
+		return nil, models.ErrChangeFeedUnsupported
+	}
+	return cf.Changes(ctx)
+}
+
+func (m *metricds) InsertNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, ok := m.ds.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_insert_namespace")
+	defer span.End()
+	return nss.InsertNamespace(ctx, ns)
+}
+
+func (m *metricds) UpdateNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, ok := m.ds.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_update_namespace")
+	defer span.End()
+	return nss.UpdateNamespace(ctx, ns)
+}
+
+func (m *metricds) GetNamespaceByID(ctx context.Context, nsID string) (*models.Namespace, error) {
+	nss, ok := m.ds.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_namespace_by_id")
+	defer span.End()
+	return nss.GetNamespaceByID(ctx, nsID)
+}
+
+func (m *metricds) GetNamespaces(ctx context.Context, filter *models.NamespaceFilter) (*models.NamespaceList, error) {
+	nss, ok := m.ds.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_namespaces")
+	defer span.End()
+	return nss.GetNamespaces(ctx, filter)
+}
+
+func (m *metricds) RemoveNamespace(ctx context.Context, nsID string) error {
+	nss, ok := m.ds.(models.NamespaceStore)
+	if !ok {
+		return models.ErrNamespacesUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_remove_namespace")
+	defer span.End()
+	return nss.RemoveNamespace(ctx, nsID)
+}
+
+func (m *metricds) PutSecret(ctx context.Context, secret *models.StoredSecret) (*models.StoredSecret, error) {
+	ss, ok := m.ds.(models.SecretStore)
+	if !ok {
+		return nil, models.ErrSecretsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_put_secret")
+	defer span.End()
+	return ss.PutSecret(ctx, secret)
+}
+
+func (m *metricds) GetSecrets(ctx context.Context, appID string) ([]*models.StoredSecret, error) {
+	ss, ok := m.ds.(models.SecretStore)
+	if !ok {
+		return nil, models.ErrSecretsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_secrets")
+	defer span.End()
+	return ss.GetSecrets(ctx, appID)
+}
+
+func (m *metricds) RemoveSecret(ctx context.Context, appID, name string) error {
+	ss, ok := m.ds.(models.SecretStore)
+	if !ok {
+		return models.ErrSecretsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_remove_secret")
+	defer span.End()
+	return ss.RemoveSecret(ctx, appID, name)
+}
+
+func (m *metricds) InsertFnRevision(ctx context.Context, rev *models.FnRevision) (*models.FnRevision, error) {
+	rs, ok := m.ds.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_insert_fn_revision")
+	defer span.End()
+	return rs.InsertFnRevision(ctx, rev)
+}
+
+func (m *metricds) GetFnRevisions(ctx context.Context, fnID string) ([]*models.FnRevision, error) {
+	rs, ok := m.ds.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_fn_revisions")
+	defer span.End()
+	return rs.GetFnRevisions(ctx, fnID)
+}
+
+func (m *metricds) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	rs, ok := m.ds.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_fn_revision")
+	defer span.End()
+	return rs.GetFnRevision(ctx, fnID, revisionID)
+}
+
+func (m *metricds) InsertRoleBinding(ctx context.Context, rb *models.RoleBinding) (*models.RoleBinding, error) {
+	rbs, ok := m.ds.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_insert_role_binding")
+	defer span.End()
+	return rbs.InsertRoleBinding(ctx, rb)
+}
+
+func (m *metricds) GetRoleBindingByID(ctx context.Context, rbID string) (*models.RoleBinding, error) {
+	rbs, ok := m.ds.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_role_binding_by_id")
+	defer span.End()
+	return rbs.GetRoleBindingByID(ctx, rbID)
+}
+
+func (m *metricds) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	rbs, ok := m.ds.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_role_bindings")
+	defer span.End()
+	return rbs.GetRoleBindings(ctx, filter)
+}
+
+func (m *metricds) RemoveRoleBinding(ctx context.Context, rbID string) error {
+	rbs, ok := m.ds.(models.RoleBindingStore)
+	if !ok {
+		return models.ErrRoleBindingsUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_remove_role_binding")
+	defer span.End()
+	return rbs.RemoveRoleBinding(ctx, rbID)
+}
+
+func (m *metricds) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ks, ok := m.ds.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_insert_api_key")
+	defer span.End()
+	return ks.InsertAPIKey(ctx, key)
+}
+
+func (m *metricds) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	ks, ok := m.ds.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_api_key_by_id")
+	defer span.End()
+	return ks.GetAPIKeyByID(ctx, keyID)
+}
+
+func (m *metricds) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	ks, ok := m.ds.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_api_keys")
+	defer span.End()
+	return ks.GetAPIKeys(ctx, filter)
+}
+
+func (m *metricds) RevokeAPIKey(ctx context.Context, keyID string, at common.DateTime) (*models.APIKey, error) {
+	ks, ok := m.ds.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_revoke_api_key")
+	defer span.End()
+	return ks.RevokeAPIKey(ctx, keyID, at)
+}
+
+func (m *metricds) GetWorkflowState(ctx context.Context, chainID string) (*models.WorkflowState, error) {
+	ws, ok := m.ds.(models.WorkflowStateStore)
+	if !ok {
+		return nil, models.ErrWorkflowStatesUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_get_workflow_state")
+	defer span.End()
+	return ws.GetWorkflowState(ctx, chainID)
+}
+
+func (m *metricds) PutWorkflowState(ctx context.Context, state *models.WorkflowState) (*models.WorkflowState, error) {
+	ws, ok := m.ds.(models.WorkflowStateStore)
+	if !ok {
+		return nil, models.ErrWorkflowStatesUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_put_workflow_state")
+	defer span.End()
+	return ws.PutWorkflowState(ctx, state)
+}
+
+func (m *metricds) RemoveWorkflowState(ctx context.Context, chainID string) error {
+	ws, ok := m.ds.(models.WorkflowStateStore)
+	if !ok {
+		return models.ErrWorkflowStatesUnsupported
+	}
+	ctx, span := trace.StartSpan(ctx, "ds_remove_workflow_state")
+	defer span.End()
+	return ws.RemoveWorkflowState(ctx, chainID)
+}
+
 // Close calls Close on the underlying Datastore
 func (m *metricds) Close() error {
 	return m.ds.Close()
@@ -0,0 +1,591 @@
+package datastoreutil
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// NewNamespaceScope returns a models.Datastore which only shows the apps of
+// the namespace a context is scoped to, see common.WithNamespaceID, and the
+// fns, triggers and namespaces under them. Objects out of scope are reported
+// as not found. Contexts without a namespace see everything.
+func NewNamespaceScope(ds models.Datastore) models.Datastore {
+	return &namespaceScope{ds}
+}
+
+type namespaceScope struct {
+	models.Datastore
+}
+
+// checkApp returns ErrAppsNotFound if the app is not in the namespace of ctx
+func (s *namespaceScope) checkApp(ctx context.Context, appID string) error {
+	nsID := common.NamespaceIDFromContext(ctx)
+	if nsID == "" {
+		return nil
+	}
+	app, err := s.Datastore.GetAppByID(ctx, appID)
+	if err != nil {
+		return err
+	}
+	if app.NamespaceID != nsID {
+		return models.ErrAppsNotFound
+	}
+	return nil
+}
+
+func (s *namespaceScope) GetAppID(ctx context.Context, appName string) (string, error) {
+	appID, err := s.Datastore.GetAppID(ctx, appName)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkApp(ctx, appID); err != nil {
+		return "", err
+	}
+	return appID, nil
+}
+
+func (s *namespaceScope) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	app, err := s.Datastore.GetAppByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if nsID := common.NamespaceIDFromContext(ctx); nsID != "" && app.NamespaceID != nsID {
+		return nil, models.ErrAppsNotFound
+	}
+	return app, nil
+}
+
+func (s *namespaceScope) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
+	if nsID := common.NamespaceIDFromContext(ctx); nsID != "" {
+		scoped := models.AppFilter{}
+		if filter != nil {
+			scoped = *filter
+		}
+		scoped.NamespaceID = nsID
+		filter = &scoped
+	}
+	return s.Datastore.GetApps(ctx, filter)
+}
+
+// InsertApp puts apps in the namespace of ctx, if any, apps may only be put in
+// a namespace that exists.
+func (s *namespaceScope) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	if app == nil {
+		return s.Datastore.InsertApp(ctx, app)
+	}
+	if nsID := common.NamespaceIDFromContext(ctx); nsID != "" {
+		if app.NamespaceID != "" && app.NamespaceID != nsID {
+			return nil, models.ErrNamespacesNotFound
+		}
+		app = app.Clone()
+		app.NamespaceID = nsID
+	} else if app.NamespaceID != "" {
+		nss, ok := s.Datastore.(models.NamespaceStore)
+		if !ok {
+			return nil, models.ErrNamespacesUnsupported
+		}
+		if _, err := nss.GetNamespaceByID(ctx, app.NamespaceID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Datastore.InsertApp(ctx, app)
+}
+
+func (s *namespaceScope) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	if app != nil && (app.NamespaceID != "" || common.NamespaceIDFromContext(ctx) != "") {
+		existing, err := s.GetAppByID(ctx, app.ID)
+		if err != nil {
+			return nil, err
+		}
+		if app.NamespaceID != "" && app.NamespaceID != existing.NamespaceID {
+			return nil, models.ErrAppsNamespaceImmutable
+		}
+	}
+	return s.Datastore.UpdateApp(ctx, app)
+}
+
+func (s *namespaceScope) RemoveApp(ctx context.Context, appID string) error {
+	if err := s.checkApp(ctx, appID); err != nil {
+		return err
+	}
+	return s.Datastore.RemoveApp(ctx, appID)
+}
+
+func (s *namespaceScope) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	if fn != nil {
+		if err := s.checkApp(ctx, fn.AppID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Datastore.InsertFn(ctx, fn)
+}
+
+func (s *namespaceScope) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	if fn != nil && common.NamespaceIDFromContext(ctx) != "" {
+		if _, err := s.GetFnByID(ctx, fn.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Datastore.UpdateFn(ctx, fn)
+}
+
+func (s *namespaceScope) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnList, error) {
+	if filter != nil && filter.AppID != "" {
+		if err := s.checkApp(ctx, filter.AppID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Datastore.GetFns(ctx, filter)
+}
+
+func (s *namespaceScope) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	fn, err := s.Datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkApp(ctx, fn.AppID); err != nil {
+		return nil, models.ErrFnsNotFound
+	}
+	return fn, nil
+}
+
+func (s *namespaceScope) RemoveFn(ctx context.Context, fnID string) error {
+	if common.NamespaceIDFromContext(ctx) != "" {
+		if _, err := s.GetFnByID(ctx, fnID); err != nil {
+			return err
+		}
+	}
+	return s.Datastore.RemoveFn(ctx, fnID)
+}
+
+func (s *namespaceScope) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	if trigger != nil {
+		if err := s.checkApp(ctx, trigger.AppID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Datastore.InsertTrigger(ctx, trigger)
+}
+
+func (s *namespaceScope) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	if trigger != nil && common.NamespaceIDFromContext(ctx) != "" {
+		if _, err := s.GetTriggerByID(ctx, trigger.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Datastore.UpdateTrigger(ctx, trigger)
+}
+
+func (s *namespaceScope) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*models.TriggerList, error) {
+	if filter != nil && filter.AppID != "" {
+		if err := s.checkApp(ctx, filter.AppID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Datastore.GetTriggers(ctx, filter)
+}
+
+func (s *namespaceScope) GetTriggerByID(ctx context.Context, triggerID string) (*models.Trigger, error) {
+	trigger, err := s.Datastore.GetTriggerByID(ctx, triggerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkApp(ctx, trigger.AppID); err != nil {
+		return nil, models.ErrTriggerNotFound
+	}
+	return trigger, nil
+}
+
+func (s *namespaceScope) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	if err := s.checkApp(ctx, appID); err != nil {
+		return nil, models.ErrTriggerNotFound
+	}
+	return s.Datastore.GetTriggerBySource(ctx, appID, triggerType, source)
+}
+
+func (s *namespaceScope) RemoveTrigger(ctx context.Context, triggerID string) error {
+	if common.NamespaceIDFromContext(ctx) != "" {
+		if _, err := s.GetTriggerByID(ctx, triggerID); err != nil {
+			return err
+		}
+	}
+	return s.Datastore.RemoveTrigger(ctx, triggerID)
+}
+
+func (s *namespaceScope) Changes(ctx context.Context) (<-chan *models.Change, error) {
+	cf, ok := s.Datastore.(models.ChangeFeed)
+	if !ok {
+		return nil, models.ErrChangeFeedUnsupported
+	}
+	return cf.Changes(ctx)
+}
+
+func (s *namespaceScope) namespaces() (models.NamespaceStore, error) {
+	nss, ok := s.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss, nil
+}
+
+// InsertNamespace, UpdateNamespace and RemoveNamespace are only allowed to
+// contexts not scoped to a namespace.
+func (s *namespaceScope) InsertNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, err := s.namespaces()
+	if err != nil {
+		return nil, err
+	}
+	if common.NamespaceIDFromContext(ctx) != "" {
+		return nil, models.ErrNamespacesScoped
+	}
+	return nss.InsertNamespace(ctx, ns)
+}
+
+func (s *namespaceScope) UpdateNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, err := s.namespaces()
+	if err != nil {
+		return nil, err
+	}
+	if common.NamespaceIDFromContext(ctx) != "" {
+		return nil, models.ErrNamespacesScoped
+	}
+	return nss.UpdateNamespace(ctx, ns)
+}
+
+func (s *namespaceScope) GetNamespaceByID(ctx context.Context, nsID string) (*models.Namespace, error) {
+	nss, err := s.namespaces()
+	if err != nil {
+		return nil, err
+	}
+	if scope := common.NamespaceIDFromContext(ctx); scope != "" && scope != nsID {
+		return nil, models.ErrNamespacesNotFound
+	}
+	return nss.GetNamespaceByID(ctx, nsID)
+}
+
+// GetNamespaces returns at most the namespace of ctx for scoped contexts
+func (s *namespaceScope) GetNamespaces(ctx context.Context, filter *models.NamespaceFilter) (*models.NamespaceList, error) {
+	nss, err := s.namespaces()
+	if err != nil {
+		return nil, err
+	}
+	scope := common.NamespaceIDFromContext(ctx)
+	if scope == "" {
+		return nss.GetNamespaces(ctx, filter)
+	}
+
+	res := &models.NamespaceList{Items: []*models.Namespace{}}
+	if filter != nil && filter.Cursor != "" {
+		// the single namespace was returned on the first page
+		return res, nil
+	}
+	ns, err := nss.GetNamespaceByID(ctx, scope)
+	if err == models.ErrNamespacesNotFound {
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil || filter.Name == "" || filter.Name == ns.Name {
+		res.Items = append(res.Items, ns)
+	}
+	return res, nil
+}
+
+func (s *namespaceScope) RemoveNamespace(ctx context.Context, nsID string) error {
+	nss, err := s.namespaces()
+	if err != nil {
+		return err
+	}
+	if common.NamespaceIDFromContext(ctx) != "" {
+		return models.ErrNamespacesScoped
+	}
+	return nss.RemoveNamespace(ctx, nsID)
+}
+
+func (s *namespaceScope) secrets() (models.SecretStore, error) {
+	ss, ok := s.Datastore.(models.SecretStore)
+	if !ok {
+		return nil, models.ErrSecretsUnsupported
+	}
+	return ss, nil
+}
+
+func (s *namespaceScope) PutSecret(ctx context.Context, secret *models.StoredSecret) (*models.StoredSecret, error) {
+	ss, err := s.secrets()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkApp(ctx, secret.AppID); err != nil {
+		return nil, err
+	}
+	return ss.PutSecret(ctx, secret)
+}
+
+func (s *namespaceScope) GetSecrets(ctx context.Context, appID string) ([]*models.StoredSecret, error) {
+	ss, err := s.secrets()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkApp(ctx, appID); err != nil {
+		return nil, err
+	}
+	return ss.GetSecrets(ctx, appID)
+}
+
+func (s *namespaceScope) RemoveSecret(ctx context.Context, appID, name string) error {
+	ss, err := s.secrets()
+	if err != nil {
+		return err
+	}
+	if err := s.checkApp(ctx, appID); err != nil {
+		return err
+	}
+	return ss.RemoveSecret(ctx, appID, name)
+}
+
+func (s *namespaceScope) revisions() (models.FnRevisionStore, error) {
+	rs, ok := s.Datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs, nil
+}
+
+// checkFn returns ErrFnsNotFound if the fn is not in the namespace of ctx
+func (s *namespaceScope) checkFn(ctx context.Context, fnID string) error {
+	if common.NamespaceIDFromContext(ctx) == "" {
+		return nil
+	}
+	_, err := s.GetFnByID(ctx, fnID)
+	return err
+}
+
+func (s *namespaceScope) InsertFnRevision(ctx context.Context, rev *models.FnRevision) (*models.FnRevision, error) {
+	rs, err := s.revisions()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkFn(ctx, rev.FnID); err != nil {
+		return nil, err
+	}
+	return rs.InsertFnRevision(ctx, rev)
+}
+
+func (s *namespaceScope) GetFnRevisions(ctx context.Context, fnID string) ([]*models.FnRevision, error) {
+	rs, err := s.revisions()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkFn(ctx, fnID); err != nil {
+		return nil, err
+	}
+	return rs.GetFnRevisions(ctx, fnID)
+}
+
+func (s *namespaceScope) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	rs, err := s.revisions()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkFn(ctx, fnID); err != nil {
+		return nil, err
+	}
+	return rs.GetFnRevision(ctx, fnID, revisionID)
+}
+
+func (s *namespaceScope) roleBindings() (models.RoleBindingStore, error) {
+	rbs, ok := s.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs, nil
+}
+
+// InsertRoleBinding binds roles of scoped contexts to their namespace or its
+// apps only.
+func (s *namespaceScope) InsertRoleBinding(ctx context.Context, rb *models.RoleBinding) (*models.RoleBinding, error) {
+	rbs, err := s.roleBindings()
+	if err != nil {
+		return nil, err
+	}
+	if scope := common.NamespaceIDFromContext(ctx); scope != "" {
+		if rb.NamespaceID == "" && rb.AppID == "" {
+			return nil, models.ErrNamespacesScoped
+		}
+		if rb.NamespaceID != scope {
+			return nil, models.ErrNamespacesNotFound
+		}
+		if rb.AppID != "" {
+			if err := s.checkApp(ctx, rb.AppID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rbs.InsertRoleBinding(ctx, rb)
+}
+
+func (s *namespaceScope) GetRoleBindingByID(ctx context.Context, rbID string) (*models.RoleBinding, error) {
+	rbs, err := s.roleBindings()
+	if err != nil {
+		return nil, err
+	}
+	rb, err := rbs.GetRoleBindingByID(ctx, rbID)
+	if err != nil {
+		return nil, err
+	}
+	if scope := common.NamespaceIDFromContext(ctx); scope != "" && rb.NamespaceID != scope {
+		return nil, models.ErrRoleBindingsNotFound
+	}
+	return rb, nil
+}
+
+// GetRoleBindings returns the bindings of the namespace of ctx and its apps
+// for scoped contexts
+func (s *namespaceScope) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	rbs, err := s.roleBindings()
+	if err != nil {
+		return nil, err
+	}
+	if scope := common.NamespaceIDFromContext(ctx); scope != "" {
+		f := models.RoleBindingFilter{}
+		if filter != nil {
+			f = *filter
+		}
+		if f.NamespaceID != "" && f.NamespaceID != scope {
+			return &models.RoleBindingList{Items: []*models.RoleBinding{}}, nil
+		}
+		f.NamespaceID = scope
+		filter = &f
+	}
+	return rbs.GetRoleBindings(ctx, filter)
+}
+
+func (s *namespaceScope) RemoveRoleBinding(ctx context.Context, rbID string) error {
+	rbs, err := s.roleBindings()
+	if err != nil {
+		return err
+	}
+	if _, err := s.GetRoleBindingByID(ctx, rbID); err != nil {
+		return err
+	}
+	return rbs.RemoveRoleBinding(ctx, rbID)
+}
+
+func (s *namespaceScope) apiKeys() (models.APIKeyStore, error) {
+	ks, ok := s.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks, nil
+}
+
+// InsertAPIKey binds the keys made by scoped contexts to their namespace
+func (s *namespaceScope) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ks, err := s.apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	if scope := common.NamespaceIDFromContext(ctx); scope != "" {
+		if key.NamespaceID != "" && key.NamespaceID != scope {
+			return nil, models.ErrNamespacesNotFound
+		}
+		c := *key
+		c.NamespaceID = scope
+		key = &c
+	}
+	return ks.InsertAPIKey(ctx, key)
+}
+
+func (s *namespaceScope) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	ks, err := s.apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, err := ks.GetAPIKeyByID(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if scope := common.NamespaceIDFromContext(ctx); scope != "" && key.NamespaceID != scope {
+		return nil, models.ErrAPIKeysNotFound
+	}
+	return key, nil
+}
+
+// GetAPIKeys returns the keys of the namespace of ctx for scoped contexts
+func (s *namespaceScope) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	ks, err := s.apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	if scope := common.NamespaceIDFromContext(ctx); scope != "" {
+		f := models.APIKeyFilter{}
+		if filter != nil {
+			f = *filter
+		}
+		if f.NamespaceID != "" && f.NamespaceID != scope {
+			return &models.APIKeyList{Items: []*models.APIKey{}}, nil
+		}
+		f.NamespaceID = scope
+		filter = &f
+	}
+	return ks.GetAPIKeys(ctx, filter)
+}
+
+func (s *namespaceScope) RevokeAPIKey(ctx context.Context, keyID string, at common.DateTime) (*models.APIKey, error) {
+	ks, err := s.apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetAPIKeyByID(ctx, keyID); err != nil {
+		return nil, err
+	}
+	return ks.RevokeAPIKey(ctx, keyID, at)
+}
+
+func (s *namespaceScope) workflowStates() (models.WorkflowStateStore, error) {
+	ws, ok := s.Datastore.(models.WorkflowStateStore)
+	if !ok {
+		return nil, models.ErrWorkflowStatesUnsupported
+	}
+	return ws, nil
+}
+
+func (s *namespaceScope) GetWorkflowState(ctx context.Context, chainID string) (*models.WorkflowState, error) {
+	ws, err := s.workflowStates()
+	if err != nil {
+		return nil, err
+	}
+	state, err := ws.GetWorkflowState(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkApp(ctx, state.AppID); err != nil {
+		return nil, models.ErrWorkflowStatesNotFound
+	}
+	return state, nil
+}
+
+func (s *namespaceScope) PutWorkflowState(ctx context.Context, state *models.WorkflowState) (*models.WorkflowState, error) {
+	ws, err := s.workflowStates()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkApp(ctx, state.AppID); err != nil {
+		return nil, err
+	}
+	return ws.PutWorkflowState(ctx, state)
+}
+
+func (s *namespaceScope) RemoveWorkflowState(ctx context.Context, chainID string) error {
+	ws, err := s.workflowStates()
+	if err != nil {
+		return err
+	}
+	if _, err := s.GetWorkflowState(ctx, chainID); err != nil {
+		return err
+	}
+	return ws.RemoveWorkflowState(ctx, chainID)
+}
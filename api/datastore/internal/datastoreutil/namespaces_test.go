@@ -0,0 +1,93 @@
+package datastoreutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestNamespaceScope(t *testing.T) {
+	ds := datastoreutil.NewNamespaceScope(datastore.NewMockInit(
+		[]*models.Namespace{{ID: "ns-a", Name: "a"}, {ID: "ns-b", Name: "b"}},
+		[]*models.App{
+			{ID: "app-a", Name: "appa", NamespaceID: "ns-a"},
+			{ID: "app-b", Name: "appb", NamespaceID: "ns-b"},
+			{ID: "app-none", Name: "appnone"},
+		},
+		[]*models.Fn{
+			{ID: "fn-a", Name: "fna", AppID: "app-a"},
+			{ID: "fn-b", Name: "fnb", AppID: "app-b"},
+		},
+	))
+	nss := ds.(models.NamespaceStore)
+
+	ctx := context.Background()
+	scoped := common.WithNamespaceID(ctx, "ns-a")
+
+	apps, err := ds.GetApps(ctx, &models.AppFilter{PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps.Items) != 3 {
+		t.Fatalf("expected unscoped contexts to see all apps, got %d", len(apps.Items))
+	}
+
+	apps, err = ds.GetApps(scoped, &models.AppFilter{PerPage: 10, NamespaceID: "ns-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps.Items) != 1 || apps.Items[0].ID != "app-a" {
+		t.Fatalf("expected only the app of the namespace, got %+v", apps.Items)
+	}
+
+	if _, err := ds.GetAppByID(scoped, "app-b"); err != models.ErrAppsNotFound {
+		t.Fatalf("expected an app of another namespace not to be found, got %v", err)
+	}
+	if _, err := ds.GetAppID(scoped, "appnone"); err != models.ErrAppsNotFound {
+		t.Fatalf("expected an app without namespace not to be found, got %v", err)
+	}
+	if _, err := ds.GetFnByID(scoped, "fn-b"); err != models.ErrFnsNotFound {
+		t.Fatalf("expected a fn of another namespace not to be found, got %v", err)
+	}
+	if _, err := ds.GetFnByID(scoped, "fn-a"); err != nil {
+		t.Fatalf("expected the fn of the namespace to be found, got %v", err)
+	}
+	if err := ds.RemoveFn(scoped, "fn-b"); err != models.ErrFnsNotFound {
+		t.Fatalf("expected a fn of another namespace not to be removed, got %v", err)
+	}
+	if _, err := ds.InsertFn(scoped, &models.Fn{Name: "fnc", AppID: "app-b", Image: "fnproject/hello"}); err != models.ErrAppsNotFound {
+		t.Fatalf("expected no fn to be created in an app of another namespace, got %v", err)
+	}
+
+	app, err := ds.InsertApp(scoped, &models.App{Name: "appc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.NamespaceID != "ns-a" {
+		t.Fatalf("expected apps to be created in the namespace of the context, got %q", app.NamespaceID)
+	}
+	if _, err := ds.UpdateApp(ctx, &models.App{ID: app.ID, NamespaceID: "ns-b"}); err != models.ErrAppsNamespaceImmutable {
+		t.Fatalf("expected the namespace of an app not to change, got %v", err)
+	}
+	if _, err := ds.InsertApp(ctx, &models.App{Name: "appd", NamespaceID: "ns-missing"}); err != models.ErrNamespacesNotFound {
+		t.Fatalf("expected no app to be created in a missing namespace, got %v", err)
+	}
+
+	list, err := nss.GetNamespaces(scoped, &models.NamespaceFilter{PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].ID != "ns-a" {
+		t.Fatalf("expected only the namespace of the context, got %+v", list.Items)
+	}
+	if _, err := nss.GetNamespaceByID(scoped, "ns-b"); err != models.ErrNamespacesNotFound {
+		t.Fatalf("expected another namespace not to be found, got %v", err)
+	}
+	if _, err := nss.InsertNamespace(scoped, &models.Namespace{Name: "c"}); err != models.ErrNamespacesScoped {
+		t.Fatalf("expected namespaces not to be created from a namespace, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 )
 
@@ -138,3 +139,322 @@ func (v *validator) RemoveFn(ctx context.Context, fnID string) error {
 	}
 	return v.Datastore.RemoveFn(ctx, fnID)
 }
+
+func (v *validator) Changes(ctx context.Context) (<-chan *models.Change, error) {
+	cf, ok := v.Datastore.(models.ChangeFeed)
+	if !ok {
+		return nil, models.ErrChangeFeedUnsupported
+	}
+	return cf.Changes(ctx)
+}
+
+func (v *validator) namespaces() (models.NamespaceStore, error) {
+	nss, ok := v.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss, nil
+}
+
+func (v *validator) InsertNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, err := v.namespaces()
+	if err != nil {
+		return nil, err
+	}
+	if ns == nil {
+		return nil, models.ErrNamespacesMissingNew
+	}
+	if ns.ID != "" {
+		return nil, models.ErrNamespaceIDProvided
+	}
+	if err := ns.Validate(); err != nil {
+		return nil, err
+	}
+	return nss.InsertNamespace(ctx, ns)
+}
+
+func (v *validator) UpdateNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, err := v.namespaces()
+	if err != nil {
+		return nil, err
+	}
+	if ns == nil {
+		return nil, models.ErrNamespacesMissingNew
+	}
+	if ns.ID == "" {
+		return nil, models.ErrNamespacesMissingID
+	}
+	return nss.UpdateNamespace(ctx, ns)
+}
+
+func (v *validator) GetNamespaceByID(ctx context.Context, nsID string) (*models.Namespace, error) {
+	nss, err := v.namespaces()
+	if err != nil {
+		return nil, err
+	}
+	if nsID == "" {
+		return nil, models.ErrNamespacesMissingID
+	}
+	return nss.GetNamespaceByID(ctx, nsID)
+}
+
+func (v *validator) GetNamespaces(ctx context.Context, filter *models.NamespaceFilter) (*models.NamespaceList, error) {
+	nss, err := v.namespaces()
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		filter = new(models.NamespaceFilter)
+	}
+	return nss.GetNamespaces(ctx, filter)
+}
+
+func (v *validator) RemoveNamespace(ctx context.Context, nsID string) error {
+	nss, err := v.namespaces()
+	if err != nil {
+		return err
+	}
+	if nsID == "" {
+		return models.ErrNamespacesMissingID
+	}
+	return nss.RemoveNamespace(ctx, nsID)
+}
+
+func (v *validator) secrets() (models.SecretStore, error) {
+	ss, ok := v.Datastore.(models.SecretStore)
+	if !ok {
+		return nil, models.ErrSecretsUnsupported
+	}
+	return ss, nil
+}
+
+func (v *validator) PutSecret(ctx context.Context, secret *models.StoredSecret) (*models.StoredSecret, error) {
+	ss, err := v.secrets()
+	if err != nil {
+		return nil, err
+	}
+	if err := secret.Validate(); err != nil {
+		return nil, err
+	}
+	return ss.PutSecret(ctx, secret)
+}
+
+func (v *validator) GetSecrets(ctx context.Context, appID string) ([]*models.StoredSecret, error) {
+	ss, err := v.secrets()
+	if err != nil {
+		return nil, err
+	}
+	if appID == "" {
+		return nil, models.ErrAppsMissingID
+	}
+	return ss.GetSecrets(ctx, appID)
+}
+
+func (v *validator) RemoveSecret(ctx context.Context, appID, name string) error {
+	ss, err := v.secrets()
+	if err != nil {
+		return err
+	}
+	if appID == "" {
+		return models.ErrAppsMissingID
+	}
+	if name == "" {
+		return models.ErrMissingName
+	}
+	return ss.RemoveSecret(ctx, appID, name)
+}
+
+func (v *validator) revisions() (models.FnRevisionStore, error) {
+	rs, ok := v.Datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs, nil
+}
+
+func (v *validator) InsertFnRevision(ctx context.Context, rev *models.FnRevision) (*models.FnRevision, error) {
+	rs, err := v.revisions()
+	if err != nil {
+		return nil, err
+	}
+	if err := rev.Validate(); err != nil {
+		return nil, err
+	}
+	return rs.InsertFnRevision(ctx, rev)
+}
+
+func (v *validator) GetFnRevisions(ctx context.Context, fnID string) ([]*models.FnRevision, error) {
+	rs, err := v.revisions()
+	if err != nil {
+		return nil, err
+	}
+	if fnID == "" {
+		return nil, models.ErrDatastoreEmptyFnID
+	}
+	return rs.GetFnRevisions(ctx, fnID)
+}
+
+func (v *validator) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	rs, err := v.revisions()
+	if err != nil {
+		return nil, err
+	}
+	if fnID == "" {
+		return nil, models.ErrDatastoreEmptyFnID
+	}
+	if revisionID == "" {
+		return nil, models.ErrFnRevisionsNotFound
+	}
+	return rs.GetFnRevision(ctx, fnID, revisionID)
+}
+
+func (v *validator) roleBindings() (models.RoleBindingStore, error) {
+	rbs, ok := v.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs, nil
+}
+
+func (v *validator) InsertRoleBinding(ctx context.Context, rb *models.RoleBinding) (*models.RoleBinding, error) {
+	rbs, err := v.roleBindings()
+	if err != nil {
+		return nil, err
+	}
+	if rb.ID != "" {
+		return nil, models.ErrRoleBindingIDProvided
+	}
+	if err := rb.Validate(); err != nil {
+		return nil, err
+	}
+	return rbs.InsertRoleBinding(ctx, rb)
+}
+
+func (v *validator) GetRoleBindingByID(ctx context.Context, rbID string) (*models.RoleBinding, error) {
+	rbs, err := v.roleBindings()
+	if err != nil {
+		return nil, err
+	}
+	if rbID == "" {
+		return nil, models.ErrRoleBindingsNotFound
+	}
+	return rbs.GetRoleBindingByID(ctx, rbID)
+}
+
+func (v *validator) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	rbs, err := v.roleBindings()
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		filter = new(models.RoleBindingFilter)
+	}
+	return rbs.GetRoleBindings(ctx, filter)
+}
+
+func (v *validator) RemoveRoleBinding(ctx context.Context, rbID string) error {
+	rbs, err := v.roleBindings()
+	if err != nil {
+		return err
+	}
+	if rbID == "" {
+		return models.ErrRoleBindingsNotFound
+	}
+	return rbs.RemoveRoleBinding(ctx, rbID)
+}
+
+func (v *validator) apiKeys() (models.APIKeyStore, error) {
+	ks, ok := v.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks, nil
+}
+
+func (v *validator) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ks, err := v.apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	if key.ID != "" {
+		return nil, models.ErrAPIKeyIDProvided
+	}
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	return ks.InsertAPIKey(ctx, key)
+}
+
+func (v *validator) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	ks, err := v.apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	if keyID == "" {
+		return nil, models.ErrAPIKeysNotFound
+	}
+	return ks.GetAPIKeyByID(ctx, keyID)
+}
+
+func (v *validator) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	ks, err := v.apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		filter = new(models.APIKeyFilter)
+	}
+	return ks.GetAPIKeys(ctx, filter)
+}
+
+func (v *validator) RevokeAPIKey(ctx context.Context, keyID string, at common.DateTime) (*models.APIKey, error) {
+	ks, err := v.apiKeys()
+	if err != nil {
+		return nil, err
+	}
+	if keyID == "" {
+		return nil, models.ErrAPIKeysNotFound
+	}
+	return ks.RevokeAPIKey(ctx, keyID, at)
+}
+
+func (v *validator) workflowStates() (models.WorkflowStateStore, error) {
+	ws, ok := v.Datastore.(models.WorkflowStateStore)
+	if !ok {
+		return nil, models.ErrWorkflowStatesUnsupported
+	}
+	return ws, nil
+}
+
+func (v *validator) GetWorkflowState(ctx context.Context, chainID string) (*models.WorkflowState, error) {
+	ws, err := v.workflowStates()
+	if err != nil {
+		return nil, err
+	}
+	if chainID == "" {
+		return nil, models.ErrWorkflowStatesMissingChainID
+	}
+	return ws.GetWorkflowState(ctx, chainID)
+}
+
+func (v *validator) PutWorkflowState(ctx context.Context, state *models.WorkflowState) (*models.WorkflowState, error) {
+	ws, err := v.workflowStates()
+	if err != nil {
+		return nil, err
+	}
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+	return ws.PutWorkflowState(ctx, state)
+}
+
+func (v *validator) RemoveWorkflowState(ctx context.Context, chainID string) error {
+	ws, err := v.workflowStates()
+	if err != nil {
+		return err
+	}
+	if chainID == "" {
+		return models.ErrWorkflowStatesMissingChainID
+	}
+	return ws.RemoveWorkflowState(ctx, chainID)
+}
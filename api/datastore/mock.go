@@ -16,9 +16,15 @@ import (
 )
 
 type mock struct {
-	Apps     []*models.App
-	Fns      []*models.Fn
-	Triggers []*models.Trigger
+	Apps       []*models.App
+	Fns        []*models.Fn
+	Triggers   []*models.Trigger
+	Namespaces []*models.Namespace
+	Secrets    []*models.StoredSecret
+	Revisions  []*models.FnRevision
+	Bindings   []*models.RoleBinding
+	Keys       []*models.APIKey
+	States     []*models.WorkflowState
 
 	models.LogStore
 }
@@ -50,6 +56,16 @@ func NewMockInit(args ...interface{}) models.Datastore {
 			mocker.Fns = x
 		case []*models.Trigger:
 			mocker.Triggers = x
+		case []*models.Namespace:
+			mocker.Namespaces = x
+		case []*models.StoredSecret:
+			mocker.Secrets = x
+		case []*models.FnRevision:
+			mocker.Revisions = x
+		case []*models.RoleBinding:
+			mocker.Bindings = x
+		case []*models.APIKey:
+			mocker.Keys = x
 
 		default:
 			panic("not accounted for data type sent to mock init. add it")
@@ -108,6 +124,9 @@ func (m *mock) GetApps(ctx context.Context, filter *models.AppFilter) (*models.A
 			if filter.Name != "" && filter.Name != a.Name {
 				continue
 			}
+			if filter.NamespaceID != "" && filter.NamespaceID != a.NamespaceID {
+				continue
+			}
 			apps = append(apps, a.Clone())
 		}
 	}
@@ -168,6 +187,8 @@ func (m *mock) RemoveApp(ctx context.Context, appID string) error {
 		if a.ID == appID {
 			var newFns []*models.Fn
 			var newTriggers []*models.Trigger
+			var newSecrets []*models.StoredSecret
+			var newRevisions []*models.FnRevision
 			newApps := append(m.Apps[0:i], m.Apps[i+1:]...)
 
 			for _, fn := range m.Fns {
@@ -182,9 +203,31 @@ func (m *mock) RemoveApp(ctx context.Context, appID string) error {
 				}
 			}
 
+			for _, s := range m.Secrets {
+				if s.AppID != appID {
+					newSecrets = append(newSecrets, s)
+				}
+			}
+
 			m.Apps = newApps
 			m.Triggers = newTriggers
 			m.Fns = newFns
+			for _, r := range m.Revisions {
+				if r.AppID != appID {
+					newRevisions = append(newRevisions, r)
+				}
+			}
+
+			m.Secrets = newSecrets
+			m.Revisions = newRevisions
+			m.removeRoleBindings(func(rb *models.RoleBinding) bool { return rb.AppID == appID })
+			var states []*models.WorkflowState
+			for _, st := range m.States {
+				if st.AppID != appID {
+					states = append(states, st)
+				}
+			}
+			m.States = states
 			return nil
 
 		}
@@ -304,7 +347,15 @@ func (m *mock) RemoveFn(ctx context.Context, fnID string) error {
 				}
 			}
 
+			var newRevisions []*models.FnRevision
+			for _, r := range m.Revisions {
+				if r.FnID != f.ID {
+					newRevisions = append(newRevisions, r)
+				}
+			}
+
 			m.Triggers = newTriggers
+			m.Revisions = newRevisions
 			return nil
 		}
 	}
@@ -455,6 +506,391 @@ func (m *mock) RemoveTrigger(ctx context.Context, triggerID string) error {
 	return models.ErrTriggerNotFound
 }
 
+var _ models.NamespaceStore = &mock{}
+
+func (m *mock) InsertNamespace(ctx context.Context, newNs *models.Namespace) (*models.Namespace, error) {
+	for _, n := range m.Namespaces {
+		if newNs.Name == n.Name {
+			return nil, models.ErrNamespacesAlreadyExists
+		}
+	}
+
+	ns := newNs.Clone()
+	ns.CreatedAt = common.DateTime(time.Now())
+	ns.UpdatedAt = ns.CreatedAt
+	ns.ID = id.New().String()
+
+	m.Namespaces = append(m.Namespaces, ns)
+	return ns.Clone(), nil
+}
+
+func (m *mock) UpdateNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	for idx, n := range m.Namespaces {
+		if n.ID == ns.ID {
+			if ns.Name != "" && ns.Name != n.Name {
+				return nil, models.ErrNamespacesNameImmutable
+			}
+			c := n.Clone()
+			c.Update(ns)
+			if err := c.Validate(); err != nil {
+				return nil, err
+			}
+			m.Namespaces[idx] = c
+			return c.Clone(), nil
+		}
+	}
+	return nil, models.ErrNamespacesNotFound
+}
+
+func (m *mock) GetNamespaceByID(ctx context.Context, nsID string) (*models.Namespace, error) {
+	for _, n := range m.Namespaces {
+		if n.ID == nsID {
+			return n.Clone(), nil
+		}
+	}
+	return nil, models.ErrNamespacesNotFound
+}
+
+func (m *mock) GetNamespaces(ctx context.Context, filter *models.NamespaceFilter) (*models.NamespaceList, error) {
+	sort.Slice(m.Namespaces, func(i, j int) bool { return m.Namespaces[i].Name < m.Namespaces[j].Name })
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	res := &models.NamespaceList{Items: []*models.Namespace{}}
+	for _, n := range m.Namespaces {
+		if len(res.Items) == filter.PerPage {
+			break
+		}
+		if n.Name > cursor && (filter.Name == "" || filter.Name == n.Name) {
+			res.Items = append(res.Items, n.Clone())
+		}
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].Name)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (m *mock) RemoveNamespace(ctx context.Context, nsID string) error {
+	for _, a := range m.Apps {
+		if a.NamespaceID == nsID {
+			return models.ErrNamespacesNotEmpty
+		}
+	}
+	for i, n := range m.Namespaces {
+		if n.ID == nsID {
+			m.Namespaces = append(m.Namespaces[:i], m.Namespaces[i+1:]...)
+			m.removeRoleBindings(func(rb *models.RoleBinding) bool { return rb.NamespaceID == nsID })
+			var keys []*models.APIKey
+			for _, k := range m.Keys {
+				if k.NamespaceID != nsID {
+					keys = append(keys, k)
+				}
+			}
+			m.Keys = keys
+			return nil
+		}
+	}
+	return models.ErrNamespacesNotFound
+}
+
+var _ models.SecretStore = &mock{}
+
+func (m *mock) PutSecret(ctx context.Context, secret *models.StoredSecret) (*models.StoredSecret, error) {
+	if _, err := m.GetAppByID(ctx, secret.AppID); err != nil {
+		return nil, err
+	}
+
+	c := *secret
+	c.UpdatedAt = common.DateTime(time.Now())
+	c.CreatedAt = c.UpdatedAt
+	for i, s := range m.Secrets {
+		if s.AppID == secret.AppID && s.Name == secret.Name {
+			c.CreatedAt = s.CreatedAt
+			m.Secrets[i] = &c
+			cc := c
+			return &cc, nil
+		}
+	}
+	m.Secrets = append(m.Secrets, &c)
+	cc := c
+	return &cc, nil
+}
+
+func (m *mock) GetSecrets(ctx context.Context, appID string) ([]*models.StoredSecret, error) {
+	res := []*models.StoredSecret{}
+	for _, s := range m.Secrets {
+		if s.AppID == appID {
+			c := *s
+			res = append(res, &c)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+func (m *mock) RemoveSecret(ctx context.Context, appID, name string) error {
+	for i, s := range m.Secrets {
+		if s.AppID == appID && s.Name == name {
+			m.Secrets = append(m.Secrets[:i], m.Secrets[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrSecretsNotFound
+}
+
+var _ models.FnRevisionStore = &mock{}
+
+func (m *mock) InsertFnRevision(ctx context.Context, rev *models.FnRevision) (*models.FnRevision, error) {
+	fn, err := m.GetFnByID(ctx, rev.FnID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *rev
+	c.ID = id.New().String()
+	c.AppID = fn.AppID
+	c.CreatedAt = common.DateTime(time.Now())
+	c.Number = 1
+	for _, r := range m.Revisions {
+		if r.FnID == c.FnID && r.Number >= c.Number {
+			c.Number = r.Number + 1
+		}
+	}
+	m.Revisions = append(m.Revisions, &c)
+	cc := c
+	return &cc, nil
+}
+
+func (m *mock) GetFnRevisions(ctx context.Context, fnID string) ([]*models.FnRevision, error) {
+	res := []*models.FnRevision{}
+	for _, r := range m.Revisions {
+		if r.FnID == fnID {
+			c := *r
+			res = append(res, &c)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Number > res[j].Number })
+	return res, nil
+}
+
+func (m *mock) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	for _, r := range m.Revisions {
+		if r.FnID == fnID && r.ID == revisionID {
+			c := *r
+			return &c, nil
+		}
+	}
+	return nil, models.ErrFnRevisionsNotFound
+}
+
+var _ models.RoleBindingStore = &mock{}
+
+func (m *mock) InsertRoleBinding(ctx context.Context, newRb *models.RoleBinding) (*models.RoleBinding, error) {
+	for _, rb := range m.Bindings {
+		if rb.Subject == newRb.Subject && rb.Role == newRb.Role && rb.NamespaceID == newRb.NamespaceID && rb.AppID == newRb.AppID {
+			return nil, models.ErrRoleBindingsAlreadyExists
+		}
+	}
+
+	c := *newRb
+	c.ID = id.New().String()
+	c.CreatedAt = common.DateTime(time.Now())
+	m.Bindings = append(m.Bindings, &c)
+	cc := c
+	return &cc, nil
+}
+
+func (m *mock) GetRoleBindingByID(ctx context.Context, rbID string) (*models.RoleBinding, error) {
+	for _, rb := range m.Bindings {
+		if rb.ID == rbID {
+			c := *rb
+			return &c, nil
+		}
+	}
+	return nil, models.ErrRoleBindingsNotFound
+}
+
+func (m *mock) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	sort.Slice(m.Bindings, func(i, j int) bool { return m.Bindings[i].ID < m.Bindings[j].ID })
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	res := &models.RoleBindingList{Items: []*models.RoleBinding{}}
+	for _, rb := range m.Bindings {
+		if filter.PerPage > 0 && len(res.Items) == filter.PerPage {
+			break
+		}
+		if rb.ID > cursor &&
+			(filter.Subject == "" || filter.Subject == rb.Subject) &&
+			(filter.NamespaceID == "" || filter.NamespaceID == rb.NamespaceID) &&
+			(filter.AppID == "" || filter.AppID == rb.AppID) {
+			c := *rb
+			res.Items = append(res.Items, &c)
+		}
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (m *mock) RemoveRoleBinding(ctx context.Context, rbID string) error {
+	for i, rb := range m.Bindings {
+		if rb.ID == rbID {
+			m.Bindings = append(m.Bindings[:i], m.Bindings[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrRoleBindingsNotFound
+}
+
+func (m *mock) removeRoleBindings(remove func(rb *models.RoleBinding) bool) {
+	var kept []*models.RoleBinding
+	for _, rb := range m.Bindings {
+		if !remove(rb) {
+			kept = append(kept, rb)
+		}
+	}
+	m.Bindings = kept
+}
+
+var _ models.APIKeyStore = &mock{}
+
+func copyAPIKey(k *models.APIKey) *models.APIKey {
+	c := *k
+	c.Scopes = append([]string(nil), k.Scopes...)
+	c.AppIDs = append([]string(nil), k.AppIDs...)
+	return &c
+}
+
+func (m *mock) InsertAPIKey(ctx context.Context, newKey *models.APIKey) (*models.APIKey, error) {
+	c := copyAPIKey(newKey)
+	c.ID = id.New().String()
+	c.CreatedAt = common.DateTime(time.Now())
+	c.RevokedAt = nil
+	c.Token = ""
+	m.Keys = append(m.Keys, c)
+	return copyAPIKey(c), nil
+}
+
+func (m *mock) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	for _, k := range m.Keys {
+		if k.ID == keyID {
+			return copyAPIKey(k), nil
+		}
+	}
+	return nil, models.ErrAPIKeysNotFound
+}
+
+func (m *mock) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	sort.Slice(m.Keys, func(i, j int) bool { return m.Keys[i].ID < m.Keys[j].ID })
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	res := &models.APIKeyList{Items: []*models.APIKey{}}
+	for _, k := range m.Keys {
+		if filter.PerPage > 0 && len(res.Items) == filter.PerPage {
+			break
+		}
+		if k.ID > cursor &&
+			(filter.Name == "" || filter.Name == k.Name) &&
+			(filter.NamespaceID == "" || filter.NamespaceID == k.NamespaceID) {
+			res.Items = append(res.Items, copyAPIKey(k))
+		}
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (m *mock) RevokeAPIKey(ctx context.Context, keyID string, at common.DateTime) (*models.APIKey, error) {
+	for _, k := range m.Keys {
+		if k.ID == keyID {
+			if k.RevokedAt == nil || time.Time(at).Before(time.Time(*k.RevokedAt)) {
+				k.RevokedAt = &at
+			}
+			return copyAPIKey(k), nil
+		}
+	}
+	return nil, models.ErrAPIKeysNotFound
+}
+
+var _ models.WorkflowStateStore = &mock{}
+
+func (m *mock) GetWorkflowState(ctx context.Context, chainID string) (*models.WorkflowState, error) {
+	for _, st := range m.States {
+		if st.ChainID == chainID {
+			c := *st
+			return &c, nil
+		}
+	}
+	return nil, models.ErrWorkflowStatesNotFound
+}
+
+func (m *mock) PutWorkflowState(ctx context.Context, newState *models.WorkflowState) (*models.WorkflowState, error) {
+	c := *newState
+	c.UpdatedAt = common.DateTime(time.Now())
+	c.Version++
+
+	for i, st := range m.States {
+		if st.ChainID == c.ChainID {
+			if st.Version != newState.Version || st.AppID != c.AppID {
+				return nil, models.ErrWorkflowStatesConflict
+			}
+			c.CreatedAt = st.CreatedAt
+			m.States[i] = &c
+			res := c
+			return &res, nil
+		}
+	}
+	if newState.Version != 0 {
+		return nil, models.ErrWorkflowStatesConflict
+	}
+	c.CreatedAt = c.UpdatedAt
+	m.States = append(m.States, &c)
+	res := c
+	return &res, nil
+}
+
+func (m *mock) RemoveWorkflowState(ctx context.Context, chainID string) error {
+	for i, st := range m.States {
+		if st.ChainID == chainID {
+			m.States = append(m.States[:i], m.States[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrWorkflowStatesNotFound
+}
+
 func (m *mock) Close() error {
 	return nil
 }
@@ -0,0 +1,200 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const apiKeySelector = `SELECT id, name, scopes, app_ids, namespace_id, token_hash, expires_at, revoked_at, created_at FROM api_keys`
+
+var _ models.APIKeyStore = new(SQLStore)
+
+// apiKeyRow is the row of an API key, whose lists are kept comma separated
+// and times empty if unset
+type apiKeyRow struct {
+	ID          string          `db:"id"`
+	Name        string          `db:"name"`
+	Scopes      string          `db:"scopes"`
+	AppIDs      string          `db:"app_ids"`
+	NamespaceID string          `db:"namespace_id"`
+	TokenHash   string          `db:"token_hash"`
+	ExpiresAt   string          `db:"expires_at"`
+	RevokedAt   string          `db:"revoked_at"`
+	CreatedAt   common.DateTime `db:"created_at"`
+}
+
+func newAPIKeyRow(key *models.APIKey) *apiKeyRow {
+	row := &apiKeyRow{
+		ID:          key.ID,
+		Name:        key.Name,
+		Scopes:      strings.Join(key.Scopes, ","),
+		AppIDs:      strings.Join(key.AppIDs, ","),
+		NamespaceID: key.NamespaceID,
+		TokenHash:   key.TokenHash,
+		CreatedAt:   key.CreatedAt,
+	}
+	if key.ExpiresAt != nil {
+		row.ExpiresAt = key.ExpiresAt.String()
+	}
+	if key.RevokedAt != nil {
+		row.RevokedAt = key.RevokedAt.String()
+	}
+	return row
+}
+
+func (row *apiKeyRow) key() (*models.APIKey, error) {
+	key := &models.APIKey{
+		ID:          row.ID,
+		Name:        row.Name,
+		Scopes:      splitList(row.Scopes),
+		AppIDs:      splitList(row.AppIDs),
+		NamespaceID: row.NamespaceID,
+		TokenHash:   row.TokenHash,
+		CreatedAt:   row.CreatedAt,
+	}
+	for _, t := range []struct {
+		s string
+		t **common.DateTime
+	}{{row.ExpiresAt, &key.ExpiresAt}, {row.RevokedAt, &key.RevokedAt}} {
+		if t.s == "" {
+			continue
+		}
+		dt, err := common.ParseDateTime(t.s)
+		if err != nil {
+			return nil, err
+		}
+		*t.t = &dt
+	}
+	return key, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func (ds *SQLStore) InsertAPIKey(ctx context.Context, newKey *models.APIKey) (*models.APIKey, error) {
+	key := *newKey
+	key.ID = id.New().String()
+	key.CreatedAt = common.DateTime(time.Now())
+	key.RevokedAt = nil
+	key.Token = ""
+
+	query := ds.db.Rebind(`INSERT INTO api_keys (id, name, scopes, app_ids, namespace_id, token_hash, expires_at, revoked_at, created_at)
+		VALUES (:id, :name, :scopes, :app_ids, :namespace_id, :token_hash, :expires_at, :revoked_at, :created_at);`)
+	_, err := ds.db.NamedExecContext(ctx, query, newAPIKeyRow(&key))
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (ds *SQLStore) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	var row apiKeyRow
+	err := ds.db.QueryRowxContext(ctx, ds.db.Rebind(apiKeySelector+` WHERE id=?`), keyID).StructScan(&row)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrAPIKeysNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.key()
+}
+
+func (ds *SQLStore) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	res := &models.APIKeyList{Items: []*models.APIKey{}}
+
+	query, args, err := buildFilterAPIKeyQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ds.db.QueryxContext(ctx, ds.db.Rebind(fmt.Sprintf("%s %s", apiKeySelector, query)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row apiKeyRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		key, err := row.key()
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) RevokeAPIKey(ctx context.Context, keyID string, at common.DateTime) (*models.APIKey, error) {
+	var key *models.APIKey
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		var row apiKeyRow
+		err := tx.QueryRowxContext(ctx, tx.Rebind(apiKeySelector+` WHERE id=?`), keyID).StructScan(&row)
+		if err == sql.ErrNoRows {
+			return models.ErrAPIKeysNotFound
+		}
+		if err != nil {
+			return err
+		}
+		key, err = row.key()
+		if err != nil {
+			return err
+		}
+		if key.RevokedAt != nil && !time.Time(at).Before(time.Time(*key.RevokedAt)) {
+			return nil
+		}
+
+		key.RevokedAt = &at
+		_, err = tx.ExecContext(ctx, tx.Rebind(`UPDATE api_keys SET revoked_at=? WHERE id=?`), at.String(), keyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func buildFilterAPIKeyQuery(filter *models.APIKeyFilter) (string, []interface{}, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		args = where(&b, args, "id>?", string(s))
+	}
+	args = where(&b, args, "name=?", filter.Name)
+	args = where(&b, args, "namespace_id=?", filter.NamespaceID)
+
+	fmt.Fprintf(&b, ` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+	return b.String(), args, nil
+}
@@ -0,0 +1,92 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+var _ models.AuditStore = new(SQLStore)
+
+// InsertAuditRecord implements models.AuditStore, records are kept whole as
+// JSON next to the columns they are filtered by
+func (ds *SQLStore) InsertAuditRecord(ctx context.Context, record *models.AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	query := ds.db.Rebind(`INSERT INTO audit_records (id, kind, object_id, app_id, subject, created_at, record) VALUES (?, ?, ?, ?, ?, ?, ?);`)
+	_, err = ds.db.ExecContext(ctx, query, record.ID, record.Kind, record.ObjectID, record.AppID, record.Subject, record.CreatedAt.String(), string(b))
+	return err
+}
+
+// GetAuditRecords implements models.AuditStore
+func (ds *SQLStore) GetAuditRecords(ctx context.Context, filter *models.AuditFilter) (*models.AuditRecordList, error) {
+	cursor := ""
+	if filter.Cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(b)
+	}
+
+	query, args := buildFilterAuditQuery(filter, cursor)
+	query = ds.db.Rebind(fmt.Sprintf("SELECT record FROM audit_records %s", query))
+	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &models.AuditRecordList{Items: []*models.AuditRecord{}}
+	for rows.Next() {
+		var b string
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var record models.AuditRecord
+		if err := json.Unmarshal([]byte(b), &record); err != nil {
+			continue
+		}
+		res.Items = append(res.Items, &record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func buildFilterAuditQuery(filter *models.AuditFilter, cursor string) (string, []interface{}) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	args = where(&b, args, "id<?", cursor)
+	if !time.Time(filter.ToTime).IsZero() {
+		args = where(&b, args, "created_at<?", filter.ToTime.String())
+	}
+	if !time.Time(filter.FromTime).IsZero() {
+		args = where(&b, args, "created_at>?", filter.FromTime.String())
+	}
+	args = where(&b, args, "kind=?", filter.Kind)
+	args = where(&b, args, "object_id=?", filter.ObjectID)
+	args = where(&b, args, "app_id=?", filter.AppID)
+	args = where(&b, args, "subject=?", filter.Subject)
+
+	fmt.Fprintf(&b, ` ORDER BY id DESC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+	return b.String(), args
+}
@@ -0,0 +1,253 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// changesChannel is the postgres channel changes are notified on
+	changesChannel = "fn_changes"
+
+	changeSelector = `SELECT id, kind, op, object_id, app_id, created_at FROM changes`
+
+	// changeRetention is how long changes are kept in the changes table
+	changeRetention = time.Hour
+	// changeLookback is how far back subscribers read the changes table
+	// again, to get the changes of transactions that committed late or of
+	// servers with clocks slightly behind
+	changeLookback = 10 * time.Second
+)
+
+// changePollInterval is how often subscribers poll the changes table of dbs
+// without notifications, i.e. mysql and sqlite
+var changePollInterval = time.Second
+
+var _ models.ChangeFeed = new(SQLStore)
+
+func (ds *SQLStore) notifiesChanges() bool {
+	return ds.helper.String() == "postgres"
+}
+
+// insertChange records a change of an app, fn or trigger in tx. On postgres
+// subscribers are notified once tx commits.
+func (ds *SQLStore) insertChange(ctx context.Context, tx *sqlx.Tx, kind, op, objectID, appID string) error {
+	c := &models.Change{
+		ID:        id.New().String(),
+		Kind:      kind,
+		Op:        op,
+		ObjectID:  objectID,
+		AppID:     appID,
+		CreatedAt: common.DateTime(time.Now()),
+	}
+
+	query := tx.Rebind(`INSERT INTO changes (id, kind, op, object_id, app_id, created_at)
+		VALUES (:id, :kind, :op, :object_id, :app_id, :created_at);`)
+	_, err := tx.NamedExecContext(ctx, query, c)
+	if err != nil {
+		return err
+	}
+
+	// prune old changes at most once a minute per server
+	now := time.Now()
+	last := atomic.LoadInt64(&ds.changesPrunedAt)
+	if now.Sub(time.Unix(0, last)) > time.Minute && atomic.CompareAndSwapInt64(&ds.changesPrunedAt, last, now.UnixNano()) {
+		query = tx.Rebind(`DELETE FROM changes WHERE id < ?`)
+		_, err = tx.ExecContext(ctx, query, id.NewWithTime(now.Add(-changeRetention)).String())
+		if err != nil {
+			return err
+		}
+	}
+
+	if ds.notifiesChanges() {
+		buf, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(`SELECT pg_notify(?, ?)`), changesChannel, string(buf))
+		return err
+	}
+	return nil
+}
+
+// Changes implements models.ChangeFeed. On postgres the changes are received
+// through LISTEN/NOTIFY on a connection of each subscriber, on other dbs the
+// changes table is polled every changePollInterval.
+func (ds *SQLStore) Changes(ctx context.Context) (<-chan *models.Change, error) {
+	// times of changes are stored in milliseconds
+	cur := &changeCursor{
+		last: time.Now().Truncate(time.Millisecond),
+		seen: make(map[string]time.Time),
+	}
+	cur.since = cur.last
+	ch := make(chan *models.Change, 64)
+
+	if !ds.notifiesChanges() {
+		go ds.pollChanges(ctx, cur, ch)
+		return ch, nil
+	}
+
+	l := pq.NewListener(ds.uri, time.Second, time.Minute, nil)
+	if err := l.Listen(changesChannel); err != nil {
+		l.Close()
+		return nil, err
+	}
+	go ds.listenChanges(ctx, l, cur, ch)
+	return ch, nil
+}
+
+func (ds *SQLStore) pollChanges(ctx context.Context, cur *changeCursor, ch chan<- *models.Change) {
+	defer close(ch)
+
+	ticker := time.NewTicker(changePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changes, err := ds.readChanges(ctx, cur)
+		if err != nil {
+			if ctx.Err() == nil {
+				common.Logger(ctx).WithError(err).Warn("Error polling datastore changes")
+			}
+			continue
+		}
+		if !deliverChanges(ctx, changes, ch) {
+			return
+		}
+	}
+}
+
+func (ds *SQLStore) listenChanges(ctx context.Context, l *pq.Listener, cur *changeCursor, ch chan<- *models.Change) {
+	defer close(ch)
+	defer l.Close()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		var changes []*models.Change
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// notice a broken connection, and forget changes older than the lookback
+			go l.Ping()
+			cur.prune()
+			continue
+		case n := <-l.Notify:
+			if n == nil {
+				// reconnected, notifications may have been missed meanwhile
+				var err error
+				if changes, err = ds.readChanges(ctx, cur); err != nil {
+					common.Logger(ctx).WithError(err).Warn("Error reading datastore changes after reconnecting")
+					continue
+				}
+				break
+			}
+			var c models.Change
+			if err := json.Unmarshal([]byte(n.Extra), &c); err != nil {
+				common.Logger(ctx).WithError(err).Warn("Ignoring malformed datastore change notification")
+				continue
+			}
+			if cur.add(&c) {
+				changes = append(changes, &c)
+			}
+		}
+		if !deliverChanges(ctx, changes, ch) {
+			return
+		}
+	}
+}
+
+func deliverChanges(ctx context.Context, changes []*models.Change, ch chan<- *models.Change) bool {
+	for _, c := range changes {
+		select {
+		case ch <- c:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// readChanges returns the changes of the changes table the cursor did not see yet
+func (ds *SQLStore) readChanges(ctx context.Context, cur *changeCursor) ([]*models.Change, error) {
+	// ids start with their time in milliseconds, an id of the millisecond
+	// before is lower than all the ids from then on
+	query := ds.db.Rebind(changeSelector + ` WHERE id > ? ORDER BY id`)
+	rows, err := ds.db.QueryxContext(ctx, query, id.NewWithTime(cur.from().Add(-time.Millisecond)).String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*models.Change
+	for rows.Next() {
+		var c models.Change
+		if err := rows.StructScan(&c); err != nil {
+			return nil, err
+		}
+		if cur.add(&c) {
+			changes = append(changes, &c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	cur.prune()
+	return changes, nil
+}
+
+// changeCursor tracks the changes a subscriber received
+type changeCursor struct {
+	since time.Time            // time of the subscription, older changes are skipped
+	last  time.Time            // time of the newest change received
+	seen  map[string]time.Time // changes received within the lookback of last
+}
+
+// from is the time to read the changes table from
+func (cur *changeCursor) from() time.Time {
+	from := cur.last.Add(-changeLookback)
+	if from.Before(cur.since) {
+		return cur.since
+	}
+	return from
+}
+
+// add returns whether a change is new to the cursor, marking it seen
+func (cur *changeCursor) add(c *models.Change) bool {
+	at := time.Time(c.CreatedAt)
+	if at.Before(cur.since) {
+		return false
+	}
+	if _, ok := cur.seen[c.ID]; ok {
+		return false
+	}
+	cur.seen[c.ID] = at
+	if at.After(cur.last) {
+		cur.last = at
+	}
+	return true
+}
+
+func (cur *changeCursor) prune() {
+	from := cur.from().Add(-time.Second)
+	for id, at := range cur.seen {
+		if at.Before(from) {
+			delete(cur.seen, id)
+		}
+	}
+}
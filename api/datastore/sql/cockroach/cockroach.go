@@ -0,0 +1,112 @@
+package cockroach
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// asOfSystemTimeParam sets how stale list queries may be, e.g.
+	// cockroach://root@localhost:26257/fn?as_of_system_time=10s, 0 reads the latest data
+	asOfSystemTimeParam = "as_of_system_time"
+
+	// defaultAsOfSystemTime lets list queries be served by the closest replica
+	// of the data rather than its leaseholder, which may be in another region
+	defaultAsOfSystemTime = 5 * time.Second
+)
+
+type cockroachHelper int
+
+func (cockroachHelper) Supports(scheme string) bool {
+	switch scheme {
+	case "cockroach", "cockroachdb":
+		return true
+	}
+	return false
+}
+
+// PreConnect returns the postgres URL of the db, cockroach speaks the postgres wire protocol
+func (cockroachHelper) PreConnect(url *url.URL) (string, error) {
+	u := *url
+	u.Scheme = "postgres"
+	q := u.Query()
+	q.Del(asOfSystemTimeParam)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// PostCreate makes sqlx bind query args the postgres way
+func (cockroachHelper) PostCreate(db *sqlx.DB) (*sqlx.DB, error) {
+	return sqlx.NewDb(db.DB, "postgres"), nil
+}
+
+func (cockroachHelper) CheckTableExists(tx *sqlx.Tx, table string) (bool, error) {
+	query := tx.Rebind(`SELECT count(*)
+	FROM information_schema.TABLES
+	WHERE TABLE_NAME = ?
+`)
+
+	row := tx.QueryRow(query, table)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	exists := count > 0
+	return exists, nil
+}
+
+func (cockroachHelper) String() string {
+	return "cockroach"
+}
+
+func (cockroachHelper) IsDuplicateKeyError(err error) bool {
+	switch dbErr := err.(type) {
+	case *pq.Error:
+		if dbErr.Code == "23505" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryableError is true for transactions cockroach aborted because they
+// conflicted with other transactions, which clients are expected to retry
+func (cockroachHelper) IsRetryableError(err error) bool {
+	switch dbErr := err.(type) {
+	case *pq.Error:
+		if dbErr.Code == "40001" {
+			return true
+		}
+	}
+	return false
+}
+
+func (cockroachHelper) HistoricalReadClause(url *url.URL) (string, error) {
+	staleness := defaultAsOfSystemTime
+	if v := url.Query().Get(asOfSystemTimeParam); v != "" {
+		var err error
+		staleness, err = time.ParseDuration(v)
+		if err != nil || staleness < 0 {
+			return "", fmt.Errorf("invalid %s %q, must be a positive duration", asOfSystemTimeParam, v)
+		}
+	}
+	if staleness == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("AS OF SYSTEM TIME '-%dms'", staleness/time.Millisecond), nil
+}
+
+func init() {
+	sql.Register("cockroach", &pq.Driver{})
+	sql.Register("cockroachdb", &pq.Driver{})
+	dbhelper.Register(cockroachHelper(0))
+}
@@ -29,6 +29,11 @@ type Helper interface {
 	CheckTableExists(tx *sqlx.Tx, table string) (bool, error)
 	// IsDuplicateKeyError determines if an error indicates if the prior error was caused by a duplicate key insert
 	IsDuplicateKeyError(err error) bool
+	// IsRetryableError determines if an error indicates that the db aborted a transaction which should be run again
+	IsRetryableError(err error) bool
+	// HistoricalReadClause returns the clause to add after the table of list queries to read them at a past
+	// time from the canonical URL used in Fn config, or "" to read the latest data
+	HistoricalReadClause(url *url.URL) (string, error)
 }
 
 // GetHelper returns a helper for a specific driver
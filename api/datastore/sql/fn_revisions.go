@@ -0,0 +1,81 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const fnRevisionSelector = `SELECT id, fn_id, app_id, number, image, image_digest, memory, timeout, idle_timeout, init_timeout, config, created_at FROM fn_revisions`
+
+var _ models.FnRevisionStore = new(SQLStore)
+
+func (ds *SQLStore) InsertFnRevision(ctx context.Context, newRev *models.FnRevision) (*models.FnRevision, error) {
+	rev := *newRev
+	rev.ID = id.New().String()
+	rev.CreatedAt = common.DateTime(time.Now())
+
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		var appID string
+		err := tx.QueryRowContext(ctx, tx.Rebind(`SELECT app_id FROM fns WHERE id=?`), rev.FnID).Scan(&appID)
+		if err == sql.ErrNoRows {
+			return models.ErrFnsNotFound
+		}
+		if err != nil {
+			return err
+		}
+		rev.AppID = appID
+
+		var last sql.NullInt64
+		err = tx.QueryRowContext(ctx, tx.Rebind(`SELECT MAX(number) FROM fn_revisions WHERE fn_id=?`), rev.FnID).Scan(&last)
+		if err != nil {
+			return err
+		}
+		rev.Number = last.Int64 + 1
+
+		query := tx.Rebind(`INSERT INTO fn_revisions (id, fn_id, app_id, number, image, image_digest, memory, timeout, idle_timeout, init_timeout, config, created_at)
+			VALUES (:id, :fn_id, :app_id, :number, :image, :image_digest, :memory, :timeout, :idle_timeout, :init_timeout, :config, :created_at);`)
+		_, err = tx.NamedExecContext(ctx, query, &rev)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (ds *SQLStore) GetFnRevisions(ctx context.Context, fnID string) ([]*models.FnRevision, error) {
+	rows, err := ds.db.QueryxContext(ctx, ds.db.Rebind(fnRevisionSelector+` WHERE fn_id=? ORDER BY number DESC`), fnID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*models.FnRevision{}
+	for rows.Next() {
+		var rev models.FnRevision
+		if err := rows.StructScan(&rev); err != nil {
+			return nil, err
+		}
+		res = append(res, &rev)
+	}
+	return res, rows.Err()
+}
+
+func (ds *SQLStore) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	var rev models.FnRevision
+	row := ds.db.QueryRowxContext(ctx, ds.db.Rebind(fnRevisionSelector+` WHERE fn_id=? AND id=?`), fnID, revisionID)
+	err := row.StructScan(&rev)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrFnRevisionsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up25(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS changes (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	kind varchar(256) NOT NULL,
+	op varchar(256) NOT NULL,
+	object_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL
+);`)
+	return err
+}
+
+func down25(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE changes;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(25),
+		UpFunc:      up25,
+		DownFunc:    down25,
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up29(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS app_secrets (
+	app_id varchar(256) NOT NULL,
+	name varchar(256) NOT NULL,
+	mount varchar(16) NOT NULL,
+	ciphertext text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL,
+	PRIMARY KEY (app_id, name)
+);`)
+	return err
+}
+
+func down29(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE app_secrets;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(29),
+		UpFunc:      up29,
+		DownFunc:    down29,
+	})
+}
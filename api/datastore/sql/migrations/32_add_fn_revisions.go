@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up32(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS fn_revisions (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	fn_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	number int NOT NULL,
+	image varchar(256) NOT NULL,
+	image_digest varchar(256) NOT NULL,
+	memory int NOT NULL,
+	timeout int NOT NULL,
+	idle_timeout int NOT NULL,
+	config text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT fn_id_number_unique UNIQUE (fn_id, number)
+);`)
+	return err
+}
+
+func down32(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE fn_revisions;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(32),
+		UpFunc:      up32,
+		DownFunc:    down32,
+	})
+}
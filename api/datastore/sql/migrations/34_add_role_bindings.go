@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up34(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS role_bindings (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	subject varchar(256) NOT NULL,
+	role varchar(256) NOT NULL,
+	namespace_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT subject_role_namespace_id_app_id_unique UNIQUE (subject, role, namespace_id, app_id)
+);`)
+	return err
+}
+
+func down34(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE role_bindings;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(34),
+		UpFunc:      up34,
+		DownFunc:    down34,
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up35(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS api_keys (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	name varchar(256) NOT NULL,
+	scopes varchar(256) NOT NULL,
+	app_ids text NOT NULL,
+	namespace_id varchar(256) NOT NULL,
+	token_hash varchar(256) NOT NULL,
+	expires_at varchar(256) NOT NULL,
+	revoked_at varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL
+);`)
+	return err
+}
+
+func down35(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE api_keys;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(35),
+		UpFunc:      up35,
+		DownFunc:    down35,
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up40(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS usage_records (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	duration_ms bigint NOT NULL,
+	memory_mb_ms bigint NOT NULL,
+	cpu_ms bigint NOT NULL,
+	egress_bytes bigint NOT NULL
+);`)
+	return err
+}
+
+func down40(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE usage_records;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(40),
+		UpFunc:      up40,
+		DownFunc:    down40,
+	})
+}
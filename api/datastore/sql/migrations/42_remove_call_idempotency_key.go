@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+// idempotency keys are only remembered by the servers which saw them, calls do
+// not keep theirs. sqlite cannot drop columns, the column is left unused.
+func up42(ctx context.Context, tx *sqlx.Tx) error {
+	if tx.DriverName() == "sqlite3" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, "ALTER TABLE calls DROP COLUMN idempotency_key;")
+	return err
+}
+
+func down42(ctx context.Context, tx *sqlx.Tx) error {
+	if tx.DriverName() == "sqlite3" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, "ALTER TABLE calls ADD idempotency_key varchar(256) NOT NULL DEFAULT '';")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(42),
+		UpFunc:      up42,
+		DownFunc:    down42,
+	})
+}
@@ -56,6 +56,14 @@ func (mysqlHelper) IsDuplicateKeyError(err error) bool {
 	return false
 }
 
+func (mysqlHelper) IsRetryableError(err error) bool {
+	return false
+}
+
+func (mysqlHelper) HistoricalReadClause(url *url.URL) (string, error) {
+	return "", nil
+}
+
 func init() {
 	dbhelper.Register(mysqlHelper(0))
 }
@@ -0,0 +1,163 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const namespaceSelector = `SELECT id, name, annotations, created_at, updated_at FROM namespaces`
+
+var _ models.NamespaceStore = new(SQLStore)
+
+func (ds *SQLStore) InsertNamespace(ctx context.Context, newNs *models.Namespace) (*models.Namespace, error) {
+	ns := newNs.Clone()
+	ns.CreatedAt = common.DateTime(time.Now())
+	ns.UpdatedAt = ns.CreatedAt
+	ns.ID = id.New().String()
+
+	query := ds.db.Rebind(`INSERT INTO namespaces (id, name, annotations, created_at, updated_at)
+		VALUES (:id, :name, :annotations, :created_at, :updated_at);`)
+	_, err := ds.db.NamedExecContext(ctx, query, ns)
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return nil, models.ErrNamespacesAlreadyExists
+		}
+		return nil, err
+	}
+	return ns, nil
+}
+
+func (ds *SQLStore) UpdateNamespace(ctx context.Context, newNs *models.Namespace) (*models.Namespace, error) {
+	var ns models.Namespace
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		row := tx.QueryRowxContext(ctx, tx.Rebind(namespaceSelector+` WHERE id=?`), newNs.ID)
+		err := row.StructScan(&ns)
+		if err == sql.ErrNoRows {
+			return models.ErrNamespacesNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if newNs.Name != "" && ns.Name != newNs.Name {
+			return models.ErrNamespacesNameImmutable
+		}
+		ns.Update(newNs)
+		if err := ns.Validate(); err != nil {
+			return err
+		}
+
+		query := tx.Rebind(`UPDATE namespaces SET annotations=:annotations, updated_at=:updated_at WHERE id=:id`)
+		_, err = tx.NamedExecContext(ctx, query, ns)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ns, nil
+}
+
+func (ds *SQLStore) GetNamespaceByID(ctx context.Context, nsID string) (*models.Namespace, error) {
+	var ns models.Namespace
+	row := ds.db.QueryRowxContext(ctx, ds.db.Rebind(namespaceSelector+` WHERE id=?`), nsID)
+	err := row.StructScan(&ns)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrNamespacesNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ns, nil
+}
+
+func (ds *SQLStore) GetNamespaces(ctx context.Context, filter *models.NamespaceFilter) (*models.NamespaceList, error) {
+	res := &models.NamespaceList{Items: []*models.Namespace{}}
+
+	query, args, err := buildFilterNamespaceQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ds.db.QueryxContext(ctx, ds.db.Rebind(fmt.Sprintf("%s %s", namespaceSelector, query)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ns models.Namespace
+		if err := rows.StructScan(&ns); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &ns)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].Name)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) RemoveNamespace(ctx context.Context, nsID string) error {
+	return ds.Tx(func(tx *sqlx.Tx) error {
+		var apps int
+		row := tx.QueryRowContext(ctx, tx.Rebind(`SELECT count(*) FROM apps WHERE namespace_id=?`), nsID)
+		if err := row.Scan(&apps); err != nil {
+			return err
+		}
+		if apps > 0 {
+			return models.ErrNamespacesNotEmpty
+		}
+
+		res, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM namespaces WHERE id=?`), nsID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrNamespacesNotFound
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(`DELETE FROM role_bindings WHERE namespace_id=?`), nsID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(`DELETE FROM api_keys WHERE namespace_id=?`), nsID)
+		return err
+	})
+}
+
+func buildFilterNamespaceQuery(filter *models.NamespaceFilter) (string, []interface{}, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return "", args, err
+		}
+		args = where(&b, args, "name>?", string(s))
+	}
+	if filter.Name != "" {
+		args = where(&b, args, "name=?", filter.Name)
+	}
+
+	fmt.Fprintf(&b, ` ORDER BY name ASC`)
+	fmt.Fprintf(&b, ` LIMIT ?`)
+	args = append(args, filter.PerPage)
+	return b.String(), args, nil
+}
@@ -58,6 +58,21 @@ func (postgresHelper) IsDuplicateKeyError(err error) bool {
 	return false
 }
 
+func (postgresHelper) IsRetryableError(err error) bool {
+	switch dbErr := err.(type) {
+	case *pq.Error:
+		// serialization_failure
+		if dbErr.Code == "40001" {
+			return true
+		}
+	}
+	return false
+}
+
+func (postgresHelper) HistoricalReadClause(url *url.URL) (string, error) {
+	return "", nil
+}
+
 func init() {
 	dbhelper.Register(postgresHelper(0))
 }
@@ -0,0 +1,117 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+const roleBindingSelector = `SELECT id, subject, role, namespace_id, app_id, created_at FROM role_bindings`
+
+var _ models.RoleBindingStore = new(SQLStore)
+
+func (ds *SQLStore) InsertRoleBinding(ctx context.Context, newRb *models.RoleBinding) (*models.RoleBinding, error) {
+	rb := *newRb
+	rb.ID = id.New().String()
+	rb.CreatedAt = common.DateTime(time.Now())
+
+	query := ds.db.Rebind(`INSERT INTO role_bindings (id, subject, role, namespace_id, app_id, created_at)
+		VALUES (:id, :subject, :role, :namespace_id, :app_id, :created_at);`)
+	_, err := ds.db.NamedExecContext(ctx, query, &rb)
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return nil, models.ErrRoleBindingsAlreadyExists
+		}
+		return nil, err
+	}
+	return &rb, nil
+}
+
+func (ds *SQLStore) GetRoleBindingByID(ctx context.Context, rbID string) (*models.RoleBinding, error) {
+	var rb models.RoleBinding
+	row := ds.db.QueryRowxContext(ctx, ds.db.Rebind(roleBindingSelector+` WHERE id=?`), rbID)
+	err := row.StructScan(&rb)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrRoleBindingsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rb, nil
+}
+
+func (ds *SQLStore) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	res := &models.RoleBindingList{Items: []*models.RoleBinding{}}
+
+	query, args, err := buildFilterRoleBindingQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ds.db.QueryxContext(ctx, ds.db.Rebind(fmt.Sprintf("%s %s", roleBindingSelector, query)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rb models.RoleBinding
+		if err := rows.StructScan(&rb); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &rb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) RemoveRoleBinding(ctx context.Context, rbID string) error {
+	res, err := ds.db.ExecContext(ctx, ds.db.Rebind(`DELETE FROM role_bindings WHERE id=?`), rbID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrRoleBindingsNotFound
+	}
+	return nil
+}
+
+func buildFilterRoleBindingQuery(filter *models.RoleBindingFilter) (string, []interface{}, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		args = where(&b, args, "id>?", string(s))
+	}
+	args = where(&b, args, "subject=?", filter.Subject)
+	args = where(&b, args, "namespace_id=?", filter.NamespaceID)
+	args = where(&b, args, "app_id=?", filter.AppID)
+
+	fmt.Fprintf(&b, ` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+	return b.String(), args, nil
+}
@@ -0,0 +1,123 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const secretSelector = `SELECT app_id, name, mount, ciphertext, created_at, updated_at FROM app_secrets`
+
+var _ models.SecretStore = new(SQLStore)
+
+// secretRow is a secret as stored, with its ciphertext base64 encoded as not
+// every database takes binary values in text columns
+type secretRow struct {
+	AppID      string          `db:"app_id"`
+	Name       string          `db:"name"`
+	Mount      string          `db:"mount"`
+	Ciphertext string          `db:"ciphertext"`
+	CreatedAt  common.DateTime `db:"created_at"`
+	UpdatedAt  common.DateTime `db:"updated_at"`
+}
+
+func (r *secretRow) secret() (*models.StoredSecret, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(r.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return &models.StoredSecret{
+		AppID:      r.AppID,
+		Name:       r.Name,
+		Mount:      r.Mount,
+		Ciphertext: ciphertext,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}, nil
+}
+
+func (ds *SQLStore) PutSecret(ctx context.Context, secret *models.StoredSecret) (*models.StoredSecret, error) {
+	row := secretRow{
+		AppID:      secret.AppID,
+		Name:       secret.Name,
+		Mount:      secret.Mount,
+		Ciphertext: base64.StdEncoding.EncodeToString(secret.Ciphertext),
+		UpdatedAt:  common.DateTime(time.Now()),
+	}
+
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		var appID string
+		err := tx.QueryRowContext(ctx, tx.Rebind(`SELECT id FROM apps WHERE id=?`), row.AppID).Scan(&appID)
+		if err == sql.ErrNoRows {
+			return models.ErrAppsNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var existing secretRow
+		err = tx.QueryRowxContext(ctx, tx.Rebind(secretSelector+` WHERE app_id=? AND name=?`), row.AppID, row.Name).StructScan(&existing)
+		switch {
+		case err == sql.ErrNoRows:
+			row.CreatedAt = row.UpdatedAt
+			query := tx.Rebind(`INSERT INTO app_secrets (app_id, name, mount, ciphertext, created_at, updated_at)
+				VALUES (:app_id, :name, :mount, :ciphertext, :created_at, :updated_at);`)
+			_, err = tx.NamedExecContext(ctx, query, &row)
+			return err
+		case err != nil:
+			return err
+		}
+
+		row.CreatedAt = existing.CreatedAt
+		query := tx.Rebind(`UPDATE app_secrets SET mount=:mount, ciphertext=:ciphertext, updated_at=:updated_at
+			WHERE app_id=:app_id AND name=:name`)
+		_, err = tx.NamedExecContext(ctx, query, &row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return row.secret()
+}
+
+func (ds *SQLStore) GetSecrets(ctx context.Context, appID string) ([]*models.StoredSecret, error) {
+	rows, err := ds.db.QueryxContext(ctx, ds.db.Rebind(secretSelector+` WHERE app_id=? ORDER BY name ASC`), appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*models.StoredSecret{}
+	for rows.Next() {
+		var row secretRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		secret, err := row.secret()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, secret)
+	}
+	return res, rows.Err()
+}
+
+func (ds *SQLStore) RemoveSecret(ctx context.Context, appID, name string) error {
+	res, err := ds.db.ExecContext(ctx, ds.db.Rebind(`DELETE FROM app_secrets WHERE app_id=? AND name=?`), appID, name)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrSecretsNotFound
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
@@ -44,7 +45,8 @@ var tables = [...]string{
 	annotations text NOT NULL,
 	syslog_url text,
 	created_at varchar(256),
-	updated_at varchar(256)
+	updated_at varchar(256),
+	namespace_id varchar(256) NOT NULL DEFAULT ''
 );`,
 
 	`CREATE TABLE IF NOT EXISTS calls (
@@ -57,6 +59,13 @@ var tables = [...]string{
 	fn_id varchar(256),
 	stats text,
 	error text,
+	namespace_id varchar(256) NOT NULL DEFAULT '',
+	error_class varchar(256) NOT NULL DEFAULT '',
+	timings text,
+	parent_call_id varchar(256) NOT NULL DEFAULT '',
+	image_digest varchar(256) NOT NULL DEFAULT '',
+	platform varchar(256) NOT NULL DEFAULT '',
+	request_id varchar(256) NOT NULL DEFAULT '',
 	PRIMARY KEY (id)
 );`,
 
@@ -80,6 +89,23 @@ var tables = [...]string{
 	log text NOT NULL
 );`,
 
+	`CREATE TABLE IF NOT EXISTS dead_letters (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	call text NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS changes (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	kind varchar(256) NOT NULL,
+	op varchar(256) NOT NULL,
+	object_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL
+);`,
+
 	`CREATE TABLE IF NOT EXISTS fns (
 	id varchar(256) NOT NULL PRIMARY KEY,
 	name varchar(256) NOT NULL,
@@ -88,20 +114,115 @@ var tables = [...]string{
 	memory int NOT NULL,
 	timeout int NOT NULL,
 	idle_timeout int NOT NULL,
+	init_timeout int NOT NULL DEFAULT 0,
 	config text NOT NULL,
 	annotations text NOT NULL,
 	created_at varchar(256) NOT NULL,
 	updated_at varchar(256) NOT NULL,
     CONSTRAINT name_app_id_unique UNIQUE (app_id, name)
 );`,
+
+	`CREATE TABLE IF NOT EXISTS namespaces (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	name varchar(256) NOT NULL UNIQUE,
+	annotations text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS app_secrets (
+	app_id varchar(256) NOT NULL,
+	name varchar(256) NOT NULL,
+	mount varchar(16) NOT NULL,
+	ciphertext text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL,
+	PRIMARY KEY (app_id, name)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS fn_revisions (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	fn_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	number int NOT NULL,
+	image varchar(256) NOT NULL,
+	image_digest varchar(256) NOT NULL,
+	memory int NOT NULL,
+	timeout int NOT NULL,
+	idle_timeout int NOT NULL,
+	init_timeout int NOT NULL DEFAULT 0,
+	config text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT fn_id_number_unique UNIQUE (fn_id, number)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS role_bindings (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	subject varchar(256) NOT NULL,
+	role varchar(256) NOT NULL,
+	namespace_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT subject_role_namespace_id_app_id_unique UNIQUE (subject, role, namespace_id, app_id)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS api_keys (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	name varchar(256) NOT NULL,
+	scopes varchar(256) NOT NULL,
+	app_ids text NOT NULL,
+	namespace_id varchar(256) NOT NULL,
+	token_hash varchar(256) NOT NULL,
+	expires_at varchar(256) NOT NULL,
+	revoked_at varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS workflow_states (
+	chain_id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	state text NOT NULL,
+	version bigint NOT NULL,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS audit_records (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	kind varchar(256) NOT NULL,
+	object_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	subject varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	record text NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS usage_records (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	duration_ms bigint NOT NULL,
+	memory_mb_ms bigint NOT NULL,
+	cpu_ms bigint NOT NULL,
+	egress_bytes bigint NOT NULL
+);`,
+}
+
+// indexes back the filters and the cursor of listing calls and the usage of
+// apps, they are created after the tables on new and migrated dbs alike.
+var indexes = [...]struct{ name, table, columns string }{
+	{"calls_fn_id_id", "calls", "fn_id, id"},
+	{"calls_fn_id_status", "calls", "fn_id, status"},
+	{"usage_records_app_id_created_at", "usage_records", "app_id, created_at"},
 }
 
 const (
-	callSelector      = `SELECT id, created_at, started_at, completed_at, status, app_id, fn_id, stats, error FROM calls`
-	appIDSelector     = `SELECT id, name, config, annotations, syslog_url, created_at, updated_at FROM apps WHERE id=?`
+	callSelector      = `SELECT id, created_at, started_at, completed_at, status, app_id, fn_id, stats, error, namespace_id, error_class, timings, parent_call_id, image_digest, platform, request_id FROM calls`
+	appIDSelector     = `SELECT id, name, namespace_id, config, annotations, syslog_url, created_at, updated_at FROM apps WHERE id=?`
 	ensureAppSelector = `SELECT id FROM apps WHERE name=?`
 
-	fnSelector   = `SELECT id,name,app_id,image,memory,timeout,idle_timeout,config,annotations,created_at,updated_at FROM fns`
+	fnSelector   = `SELECT id,name,app_id,image,memory,timeout,idle_timeout,init_timeout,config,annotations,created_at,updated_at FROM fns`
 	fnIDSelector = fnSelector + ` WHERE id=?`
 
 	triggerSelector   = `SELECT id,name,app_id,fn_id,type,source,annotations,created_at,updated_at FROM triggers`
@@ -109,7 +230,48 @@ const (
 
 	triggerIDSourceSelector = triggerSelector + ` WHERE app_id=? AND type=? AND source=?`
 
+	insertCallQuery = `INSERT INTO calls (
+		id,
+		created_at,
+		started_at,
+		completed_at,
+		status,
+		app_id,
+		fn_id,
+		stats,
+		error,
+		namespace_id,
+		error_class,
+		timings,
+		parent_call_id,
+		image_digest,
+		platform,
+		request_id
+	)
+	VALUES (
+		:id,
+		:created_at,
+		:started_at,
+		:completed_at,
+		:status,
+		:app_id,
+		:fn_id,
+		:stats,
+		:error,
+		:namespace_id,
+		:error_class,
+		:timings,
+		:parent_call_id,
+		:image_digest,
+		:platform,
+		:request_id
+	);`
+	insertLogQuery = `INSERT INTO logs (id, app_id, fn_id, log) VALUES (?, ?, ?, ?);`
+
 	EnvDBPingMaxRetries = "FN_DS_DB_PING_MAX_RETRIES"
+
+	// maxTxAttempts bounds the runs of a transaction the db aborted, see dbhelper.Helper.IsRetryableError
+	maxTxAttempts = 10
 )
 
 var ( // compiler will yell nice things about our upbringing as a child
@@ -120,6 +282,10 @@ var ( // compiler will yell nice things about our upbringing as a child
 type SQLStore struct {
 	helper dbhelper.Helper
 	db     *sqlx.DB
+	uri    string // connect url of the db, for listening to postgres notifications
+	asOf   string // clause to read list queries at a past time, see dbhelper.Helper
+
+	changesPrunedAt int64 // unix nanos, accessed atomically
 }
 
 type sqlDsProvider int
@@ -175,6 +341,11 @@ func newDS(ctx context.Context, url *url.URL) (*SQLStore, error) {
 		return nil, fmt.Errorf("failed to initialise db helper %s : %s", driver, err)
 	}
 
+	asOf, err := helper.HistoricalReadClause(url)
+	if err != nil {
+		return nil, err
+	}
+
 	log.WithFields(logrus.Fields{"url": uri}).Info("Connecting to DB")
 
 	sqldb, err := sql.Open(driver, uri)
@@ -201,7 +372,7 @@ func newDS(ctx context.Context, url *url.URL) (*SQLStore, error) {
 		log.WithFields(logrus.Fields{"url": uri}).WithError(err).Error("couldn't initialize db")
 		return nil, err
 	}
-	sdb := &SQLStore{db: db, helper: helper}
+	sdb := &SQLStore{db: db, helper: helper, uri: uri, asOf: asOf}
 
 	// NOTE: runMigrations happens before we create all the tables, so that it
 	// can detect whether the db did not exist and insert the latest version of
@@ -228,6 +399,13 @@ func newDS(ctx context.Context, url *url.URL) (*SQLStore, error) {
 				return err
 			}
 		}
+		for _, idx := range indexes {
+			err = createIndex(ctx, tx, idx.name, idx.table, idx.columns)
+			if err != nil {
+				log.WithError(err).Error("error creating indexes")
+				return err
+			}
+		}
 		return nil
 	})
 
@@ -327,6 +505,66 @@ func (ds *SQLStore) clear() error {
 			return err
 		}
 
+		query = tx.Rebind(`DELETE FROM dead_letters`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM changes`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM namespaces`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM app_secrets`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM fn_revisions`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM role_bindings`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM api_keys`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM workflow_states`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM audit_records`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM usage_records`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
 		query = tx.Rebind(`DELETE FROM logs`)
 		_, err = tx.Exec(query)
 		return err
@@ -363,6 +601,7 @@ func (ds *SQLStore) InsertApp(ctx context.Context, newApp *models.App) (*models.
 	query := ds.db.Rebind(`INSERT INTO apps (
 		id,
 		name,
+		namespace_id,
 		config,
 		annotations,
 		syslog_url,
@@ -372,13 +611,20 @@ func (ds *SQLStore) InsertApp(ctx context.Context, newApp *models.App) (*models.
 	VALUES (
 		:id,
 		:name,
+		:namespace_id,
 		:config,
 		:annotations,
 		:syslog_url,
 		:created_at,
 		:updated_at
 	);`)
-	_, err := ds.db.NamedExecContext(ctx, query, app)
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExecContext(ctx, query, app)
+		if err != nil {
+			return err
+		}
+		return ds.insertChange(ctx, tx, models.ChangeKindApp, models.ChangeOpCreate, app.ID, app.ID)
+	})
 	if err != nil {
 		if ds.helper.IsDuplicateKeyError(err) {
 			return nil, models.ErrAppsAlreadyExists
@@ -428,7 +674,7 @@ func (ds *SQLStore) UpdateApp(ctx context.Context, newapp *models.App) (*models.
 			// inside of the transaction, we are querying for the app, so we know that it exists
 			return nil
 		}
-		return nil
+		return ds.insertChange(ctx, tx, models.ChangeKindApp, models.ChangeOpUpdate, app.ID, app.ID)
 	})
 
 	if err != nil {
@@ -455,8 +701,13 @@ func (ds *SQLStore) RemoveApp(ctx context.Context, appID string) error {
 		deletes := []string{
 			`DELETE FROM logs WHERE app_id=?`,
 			`DELETE FROM calls WHERE app_id=?`,
+			`DELETE FROM dead_letters WHERE app_id=?`,
 			`DELETE FROM fns WHERE app_id=?`,
 			`DELETE FROM triggers WHERE app_id=?`,
+			`DELETE FROM app_secrets WHERE app_id=?`,
+			`DELETE FROM fn_revisions WHERE app_id=?`,
+			`DELETE FROM role_bindings WHERE app_id=?`,
+			`DELETE FROM workflow_states WHERE app_id=?`,
 		}
 		for _, stmt := range deletes {
 			_, err := tx.ExecContext(ctx, tx.Rebind(stmt), appID)
@@ -465,7 +716,7 @@ func (ds *SQLStore) RemoveApp(ctx context.Context, appID string) error {
 			}
 		}
 
-		return nil
+		return ds.insertChange(ctx, tx, models.ChangeKindApp, models.ChangeOpDelete, appID, appID)
 	})
 }
 
@@ -492,7 +743,7 @@ func (ds *SQLStore) GetApps(ctx context.Context, filter *models.AppFilter) (*mod
 	if err != nil {
 		return nil, err
 	}
-	query = ds.db.Rebind(fmt.Sprintf("SELECT DISTINCT id, name, config, annotations, syslog_url, created_at, updated_at FROM apps %s", query))
+	query = ds.db.Rebind(fmt.Sprintf("SELECT DISTINCT id, name, namespace_id, config, annotations, syslog_url, created_at, updated_at FROM apps %s", ds.listFilter(query)))
 	rows, err := ds.db.QueryxContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -551,6 +802,7 @@ func (ds *SQLStore) InsertFn(ctx context.Context, newFn *models.Fn) (*models.Fn,
 				memory,
 				timeout,
 				idle_timeout,
+				init_timeout,
 				config,
 				annotations,
 				created_at,
@@ -564,6 +816,7 @@ func (ds *SQLStore) InsertFn(ctx context.Context, newFn *models.Fn) (*models.Fn,
 				:memory,
 				:timeout,
 				:idle_timeout,
+				:init_timeout,
 				:config,
 				:annotations,
 				:created_at,
@@ -571,7 +824,10 @@ func (ds *SQLStore) InsertFn(ctx context.Context, newFn *models.Fn) (*models.Fn,
 			);`)
 
 		_, err = tx.NamedExecContext(ctx, query, fn)
-		return err
+		if err != nil {
+			return err
+		}
+		return ds.insertChange(ctx, tx, models.ChangeKindFn, models.ChangeOpCreate, fn.ID, fn.AppID)
 	})
 
 	if err != nil {
@@ -610,13 +866,17 @@ func (ds *SQLStore) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, er
 				memory = :memory,
 				timeout = :timeout,
 				idle_timeout = :idle_timeout,
+				init_timeout = :init_timeout,
 				config = :config,
 				annotations = :annotations,
 				updated_at = :updated_at
 			    WHERE id=:id;`)
 
 		_, err = tx.NamedExecContext(ctx, query, fn)
-		return err
+		if err != nil {
+			return err
+		}
+		return ds.insertChange(ctx, tx, models.ChangeKindFn, models.ChangeOpUpdate, fn.ID, fn.AppID)
 	})
 
 	if err != nil {
@@ -636,7 +896,7 @@ func (ds *SQLStore) GetFns(ctx context.Context, filter *models.FnFilter) (*model
 		return res, err
 	}
 
-	query := fmt.Sprintf("%s %s", fnSelector, filterQuery)
+	query := fmt.Sprintf("%s %s", fnSelector, ds.listFilter(filterQuery))
 	query = ds.db.Rebind(query)
 	rows, err := ds.db.QueryxContext(ctx, query, args...)
 	if err != nil {
@@ -703,15 +963,47 @@ func (ds *SQLStore) RemoveFn(ctx context.Context, fnID string) error {
 			return err
 		}
 
+		query = tx.Rebind(`DELETE FROM fn_revisions WHERE fn_id=?`)
+		_, err = tx.ExecContext(ctx, query, fnID)
+		if err != nil {
+			return err
+		}
+
 		query = tx.Rebind(`DELETE FROM fns WHERE id=?`)
 		_, err = tx.ExecContext(ctx, query, fnID)
+		if err != nil {
+			return err
+		}
 
-		return err
+		return ds.insertChange(ctx, tx, models.ChangeKindFn, models.ChangeOpDelete, fnID, fn.AppID)
 	})
 
 }
 
+// listFilter prefixes the filter of a list query with the clause of the db to
+// read lists at a past time, the clause must follow the table of the query
+func (ds *SQLStore) listFilter(filter string) string {
+	if ds.asOf == "" {
+		return filter
+	}
+	return ds.asOf + " " + filter
+}
+
+// Tx runs f in a transaction, which is run again if the db aborted it and
+// asks for it to be retried, e.g. on conflicts with other transactions.
 func (ds *SQLStore) Tx(f func(*sqlx.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = ds.tx(f)
+		if err == nil || !ds.helper.IsRetryableError(err) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * 10 * time.Millisecond)
+	}
+	return err
+}
+
+func (ds *SQLStore) tx(f func(*sqlx.Tx) error) error {
 	tx, err := ds.db.Beginx()
 	if err != nil {
 		return err
@@ -725,33 +1017,26 @@ func (ds *SQLStore) Tx(f func(*sqlx.Tx) error) error {
 }
 
 func (ds *SQLStore) InsertCall(ctx context.Context, call *models.Call) error {
-	query := ds.db.Rebind(`INSERT INTO calls (
-		id,
-		created_at,
-		started_at,
-		completed_at,
-		status,
-		app_id,
-		fn_id,
-		stats,
-		error
-	)
-	VALUES (
-		:id,
-		:created_at,
-		:started_at,
-		:completed_at,
-		:status,
-		:app_id,
-		:fn_id,
-		:stats,
-		:error
-	);`)
-
-	_, err := ds.db.NamedExecContext(ctx, query, call)
+	_, err := ds.db.NamedExecContext(ctx, ds.db.Rebind(insertCallQuery), call)
 	return err
 }
 
+// CompleteCall implements models.CallCompleter
+func (ds *SQLStore) CompleteCall(ctx context.Context, call *models.Call, logR io.Reader) error {
+	log := logString(logR)
+	return ds.Tx(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExecContext(ctx, tx.Rebind(insertCallQuery), call)
+		if ds.helper.IsDuplicateKeyError(err) {
+			return models.ErrCallAlreadyCompleted
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(insertLogQuery), call.ID, call.AppID, call.FnID, log)
+		return err
+	})
+}
+
 func (ds *SQLStore) GetCall1(ctx context.Context, appID, callID string) (*models.Call, error) {
 	query := fmt.Sprintf(`%s WHERE id=? AND app_id=?`, callSelector)
 	query = ds.db.Rebind(query)
@@ -811,7 +1096,7 @@ func (ds *SQLStore) GetCalls(ctx context.Context, filter *models.CallFilter) (*m
 func (ds *SQLStore) GetCalls1(ctx context.Context, filter *models.CallFilter) ([]*models.Call, error) {
 	res := []*models.Call{}
 	query, args := buildFilterCallQuery(filter)
-	query = fmt.Sprintf("%s %s", callSelector, query)
+	query = fmt.Sprintf("%s %s", callSelector, ds.listFilter(query))
 	query = ds.db.Rebind(query)
 	rows, err := ds.db.QueryxContext(ctx, query, args...)
 	if err != nil {
@@ -833,24 +1118,92 @@ func (ds *SQLStore) GetCalls1(ctx context.Context, filter *models.CallFilter) ([
 	return res, nil
 }
 
-func (ds *SQLStore) InsertLog(ctx context.Context, call *models.Call, logR io.Reader) error {
-	// coerce this into a string for sql
-	var log string
-	if stringer, ok := logR.(fmt.Stringer); ok {
-		log = stringer.String()
-	} else {
-		// TODO we could optimize for Size / buffer pool, but atm we aren't hitting
-		// this code path anyway (a fallback)
-		var b bytes.Buffer
-		io.Copy(&b, logR)
-		log = b.String()
+// InsertDeadLetter implements models.DeadLetterStore
+func (ds *SQLStore) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	b, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+	query := ds.db.Rebind(`INSERT INTO dead_letters (id, app_id, fn_id, created_at, call) VALUES (?, ?, ?, ?, ?);`)
+	_, err = ds.db.ExecContext(ctx, query, call.ID, call.AppID, call.FnID, call.CreatedAt.String(), string(b))
+	return err
+}
+
+// GetDeadLetters implements models.DeadLetterStore
+func (ds *SQLStore) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	if filter.Cursor != "" {
+		cursor, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.Cursor = string(cursor)
 	}
 
-	query := ds.db.Rebind(`INSERT INTO logs (id, app_id, fn_id, log) VALUES (?, ?, ?, ?);`)
-	_, err := ds.db.ExecContext(ctx, query, call.ID, call.AppID, call.FnID, log)
+	query, args := buildFilterCallQuery(filter)
+	query = ds.db.Rebind(fmt.Sprintf("SELECT call FROM dead_letters %s", query))
+	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &models.CallList{Items: []*models.Call{}}
+	for rows.Next() {
+		var b string
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var call models.Call
+		if err := json.Unmarshal([]byte(b), &call); err != nil {
+			continue
+		}
+		res.Items = append(res.Items, &call)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+// RemoveDeadLetter implements models.DeadLetterStore
+func (ds *SQLStore) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	query := ds.db.Rebind(`DELETE FROM dead_letters WHERE id=? AND fn_id=?`)
+	res, err := ds.db.ExecContext(ctx, query, callID, fnID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func (ds *SQLStore) InsertLog(ctx context.Context, call *models.Call, logR io.Reader) error {
+	_, err := ds.db.ExecContext(ctx, ds.db.Rebind(insertLogQuery), call.ID, call.AppID, call.FnID, logString(logR))
 	return err
 }
 
+// logString coerces a log into a string for sql
+func logString(logR io.Reader) string {
+	if stringer, ok := logR.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	// TODO we could optimize for Size / buffer pool, but atm we aren't hitting
+	// this code path anyway (a fallback)
+	var b bytes.Buffer
+	io.Copy(&b, logR)
+	return b.String()
+}
+
 func (ds *SQLStore) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
 	query := ds.db.Rebind(`SELECT log FROM logs WHERE id=? AND fn_id=?`)
 	row := ds.db.QueryRowContext(ctx, query, callID, fnID)
@@ -885,6 +1238,9 @@ func buildFilterAppQuery(filter *models.AppFilter) (string, []interface{}, error
 	if filter.Name != "" {
 		args = where(&b, args, "name=?", filter.Name)
 	}
+	if filter.NamespaceID != "" {
+		args = where(&b, args, "namespace_id=?", filter.NamespaceID)
+	}
 
 	fmt.Fprintf(&b, ` ORDER BY name ASC`) // TODO assert this is indexed
 	fmt.Fprintf(&b, ` LIMIT ?`)
@@ -909,8 +1265,14 @@ func buildFilterCallQuery(filter *models.CallFilter) (string, []interface{}) {
 	if filter.FnID != "" {
 		args = where(&b, args, "fn_id=?", filter.FnID)
 	}
+	if filter.Status != "" {
+		args = where(&b, args, "status=?", filter.Status)
+	}
+	if filter.ErrorClass != "" {
+		args = where(&b, args, "error_class=?", filter.ErrorClass)
+	}
 
-	fmt.Fprintf(&b, ` ORDER BY id DESC`) // TODO assert this is indexed
+	fmt.Fprintf(&b, ` ORDER BY id DESC`) // see indexes
 	fmt.Fprintf(&b, ` LIMIT ?`)
 	args = append(args, filter.PerPage)
 
@@ -946,6 +1308,23 @@ func buildFilterFnQuery(filter *models.FnFilter) (string, []interface{}, error)
 	return b.String(), args, nil
 }
 
+// createIndex creates an index unless it exists, mysql has no CREATE INDEX IF
+// NOT EXISTS so it is looked up first.
+func createIndex(ctx context.Context, tx *sqlx.Tx, name, table, columns string) error {
+	if tx.DriverName() == "mysql" {
+		var n int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.statistics
+			WHERE table_schema=DATABASE() AND table_name=? AND index_name=?`, table, name).Scan(&n)
+		if err != nil || n > 0 {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX %s ON %s (%s);", name, table, columns))
+		return err
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s);", name, table, columns))
+	return err
+}
+
 func where(b *bytes.Buffer, args []interface{}, colOp string, val interface{}) []interface{} {
 	if val == nil {
 		return args
@@ -1040,7 +1419,10 @@ func (ds *SQLStore) InsertTrigger(ctx context.Context, newTrigger *models.Trigge
 		);`)
 
 		_, err = tx.NamedExecContext(ctx, query, trigger)
-		return err
+		if err != nil {
+			return err
+		}
+		return ds.insertChange(ctx, tx, models.ChangeKindTrigger, models.ChangeOpCreate, trigger.ID, trigger.AppID)
 	})
 
 	if err != nil {
@@ -1082,7 +1464,10 @@ func (ds *SQLStore) UpdateTrigger(ctx context.Context, trigger *models.Trigger)
 			annotations = :annotations
 			WHERE id = :id;`)
 		_, err = tx.NamedExecContext(ctx, query, trigger)
-		return err
+		if err != nil {
+			return err
+		}
+		return ds.insertChange(ctx, tx, models.ChangeKindTrigger, models.ChangeOpUpdate, trigger.ID, trigger.AppID)
 	})
 
 	if err != nil {
@@ -1108,22 +1493,24 @@ func (ds *SQLStore) GetTrigger(ctx context.Context, appId, fnId, triggerName str
 }
 
 func (ds *SQLStore) RemoveTrigger(ctx context.Context, triggerId string) error {
-	query := ds.db.Rebind(`DELETE FROM triggers WHERE id = ?;`)
-	res, err := ds.db.ExecContext(ctx, query, triggerId)
-	if err != nil {
-		return err
-	}
-
-	n, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
+	return ds.Tx(func(tx *sqlx.Tx) error {
+		var appID string
+		query := tx.Rebind(`SELECT app_id FROM triggers WHERE id = ?;`)
+		err := tx.QueryRowContext(ctx, query, triggerId).Scan(&appID)
+		if err == sql.ErrNoRows {
+			return models.ErrTriggerNotFound
+		} else if err != nil {
+			return err
+		}
 
-	if n == 0 {
-		return models.ErrTriggerNotFound
-	}
+		query = tx.Rebind(`DELETE FROM triggers WHERE id = ?;`)
+		_, err = tx.ExecContext(ctx, query, triggerId)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return ds.insertChange(ctx, tx, models.ChangeKindTrigger, models.ChangeOpDelete, triggerId, appID)
+	})
 }
 
 func (ds *SQLStore) GetTriggerByID(ctx context.Context, triggerID string) (*models.Trigger, error) {
@@ -1189,7 +1576,7 @@ func (ds *SQLStore) GetTriggers(ctx context.Context, filter *models.TriggerFilte
 		return res, err
 	}
 
-	query := fmt.Sprintf("%s WHERE %s", triggerSelector, filterQuery)
+	query := fmt.Sprintf("%s %s", triggerSelector, ds.listFilter("WHERE "+filterQuery))
 	query = ds.db.Rebind(query)
 	rows, err := ds.db.QueryxContext(ctx, query, args...)
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/fnproject/fn/api/datastore/datastoretest"
 	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
@@ -47,6 +48,7 @@ func newWithMigrations(ctx context.Context, url *url.URL) (*SQLStore, error) {
 
 func TestDatastore(t *testing.T) {
 	ctx := context.Background()
+	changePollInterval = 50 * time.Millisecond
 	defer os.RemoveAll("sqlite_test_dir")
 	u, err := url.Parse("sqlite3://sqlite_test_dir")
 	if err != nil {
@@ -145,6 +147,32 @@ func TestDatastore(t *testing.T) {
 		both(u)
 	}
 
+	if crdb := os.Getenv("COCKROACH_URL"); crdb != "" {
+		u, err := url.Parse(crdb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the tests list what they just inserted
+		q := u.Query()
+		q.Set("as_of_system_time", "0")
+		u.RawQuery = q.Encode()
+
+		// cockroach dbs are only created fresh, without migrations
+		f := func(t *testing.T) *SQLStore {
+			ds, err := newDS(ctx, u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ds.clear()
+			return ds
+		}
+		f2 := func(t *testing.T) models.Datastore {
+			return datastoreutil.NewValidator(f(t))
+		}
+		t.Run(u.Scheme, func(t *testing.T) { datastoretest.RunAllTests(t, f2, datastoretest.NewBasicResourceProvider()) })
+		logstoretest.Test(t, f(t))
+	}
+
 }
 
 func TestClose(t *testing.T) {
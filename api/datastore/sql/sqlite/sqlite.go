@@ -69,6 +69,14 @@ func (sqliteHelper) IsDuplicateKeyError(err error) bool {
 	return false
 }
 
+func (sqliteHelper) IsRetryableError(err error) bool {
+	return false
+}
+
+func (sqliteHelper) HistoricalReadClause(url *url.URL) (string, error) {
+	return "", nil
+}
+
 func init() {
 	dbhelper.Register(sqliteHelper(0))
 }
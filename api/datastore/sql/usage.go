@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+var _ models.UsageStore = new(SQLStore)
+
+// InsertUsageRecord implements models.UsageStore
+func (ds *SQLStore) InsertUsageRecord(ctx context.Context, record *models.UsageRecord) error {
+	query := ds.db.Rebind(`INSERT INTO usage_records (id, app_id, fn_id, created_at, duration_ms, memory_mb_ms, cpu_ms, egress_bytes) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	_, err := ds.db.ExecContext(ctx, query, record.ID, record.AppID, record.FnID, record.CreatedAt.String(),
+		int64(record.DurationMs), int64(record.MemoryMBMs), int64(record.CPUMs), int64(record.EgressBytes))
+	return err
+}
+
+// GetUsage implements models.UsageStore, the records selected are aggregated
+// as they are read
+func (ds *SQLStore) GetUsage(ctx context.Context, filter *models.UsageFilter) (*models.UsageList, error) {
+	var b bytes.Buffer
+	var args []interface{}
+	args = where(&b, args, "app_id=?", filter.AppID)
+	args = where(&b, args, "fn_id=?", filter.FnID)
+	if !time.Time(filter.FromTime).IsZero() {
+		args = where(&b, args, "created_at>=?", filter.FromTime.String())
+	}
+	if !time.Time(filter.ToTime).IsZero() {
+		args = where(&b, args, "created_at<?", filter.ToTime.String())
+	}
+
+	query := ds.db.Rebind("SELECT id, app_id, fn_id, created_at, duration_ms, memory_mb_ms, cpu_ms, egress_bytes FROM usage_records " + b.String())
+	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	agg := models.NewUsageAggregator(filter)
+	for rows.Next() {
+		var r models.UsageRecord
+		var createdAt string
+		var duration, memory, cpu, egress int64
+		if err := rows.Scan(&r.ID, &r.AppID, &r.FnID, &createdAt, &duration, &memory, &cpu, &egress); err != nil {
+			return nil, err
+		}
+		t, err := common.ParseDateTime(createdAt)
+		if err != nil {
+			continue
+		}
+		r.CreatedAt = t
+		r.DurationMs, r.MemoryMBMs, r.CPUMs, r.EgressBytes = uint64(duration), uint64(memory), uint64(cpu), uint64(egress)
+		agg.Add(&r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return agg.List(), nil
+}
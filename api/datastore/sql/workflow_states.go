@@ -0,0 +1,91 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const workflowStateSelector = `SELECT chain_id, app_id, state, version, created_at, updated_at FROM workflow_states WHERE chain_id=?`
+
+var _ models.WorkflowStateStore = new(SQLStore)
+
+func (ds *SQLStore) GetWorkflowState(ctx context.Context, chainID string) (*models.WorkflowState, error) {
+	var state models.WorkflowState
+	err := ds.db.QueryRowxContext(ctx, ds.db.Rebind(workflowStateSelector), chainID).StructScan(&state)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrWorkflowStatesNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (ds *SQLStore) PutWorkflowState(ctx context.Context, newState *models.WorkflowState) (*models.WorkflowState, error) {
+	state := *newState
+	state.UpdatedAt = common.DateTime(time.Now())
+	state.Version++
+
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		var stored models.WorkflowState
+		err := tx.QueryRowxContext(ctx, tx.Rebind(workflowStateSelector), state.ChainID).StructScan(&stored)
+		if err == sql.ErrNoRows {
+			if newState.Version != 0 {
+				return models.ErrWorkflowStatesConflict
+			}
+			state.CreatedAt = state.UpdatedAt
+			query := tx.Rebind(`INSERT INTO workflow_states (chain_id, app_id, state, version, created_at, updated_at)
+				VALUES (:chain_id, :app_id, :state, :version, :created_at, :updated_at);`)
+			_, err = tx.NamedExecContext(ctx, query, &state)
+			if ds.helper.IsDuplicateKeyError(err) {
+				return models.ErrWorkflowStatesConflict
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if stored.Version != newState.Version || stored.AppID != state.AppID {
+			return models.ErrWorkflowStatesConflict
+		}
+
+		state.CreatedAt = stored.CreatedAt
+		res, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE workflow_states SET state=?, version=?, updated_at=? WHERE chain_id=? AND version=?`),
+			state.State, state.Version, state.UpdatedAt.String(), state.ChainID, stored.Version)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrWorkflowStatesConflict
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (ds *SQLStore) RemoveWorkflowState(ctx context.Context, chainID string) error {
+	res, err := ds.db.ExecContext(ctx, ds.db.Rebind(`DELETE FROM workflow_states WHERE chain_id=?`), chainID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrWorkflowStatesNotFound
+	}
+	return nil
+}
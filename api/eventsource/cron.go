@@ -0,0 +1,80 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// TriggerTypeCron triggers run their fn on a schedule, their source is a 5
+// field cron expression in UTC prefixed by cron:, e.g. cron:*/5 * * * *
+const TriggerTypeCron = "cron"
+
+const cronPrefix = "cron:"
+
+type cronProvider struct{}
+
+func (cronProvider) Type() string { return TriggerTypeCron }
+
+func (cronProvider) Validate(source string) error {
+	_, err := parseCronSource(source)
+	return err
+}
+
+func parseCronSource(source string) (*models.CronSchedule, error) {
+	if !strings.HasPrefix(source, cronPrefix) {
+		return nil, errors.New("expected cron:<minute> <hour> <day of month> <month> <day of week>")
+	}
+	return models.ParseCron(strings.TrimSpace(strings.TrimPrefix(source, cronPrefix)))
+}
+
+// Run delivers an event with an empty body and the time the fn was due in the
+// Fn-Scheduled-Time header when due. Runs missed while the source was not
+// running are not caught up.
+func (cronProvider) Run(ctx context.Context, cfg *Config, trig *models.Trigger, deliver Deliver) error {
+	sched, err := parseCronSource(trig.Source)
+	if err != nil {
+		return err
+	}
+
+	next := sched.Next(time.Now())
+	for {
+		if next.IsZero() {
+			// never due, e.g. on the 31st of February
+			<-ctx.Done()
+			return nil
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		e := &Event{Header: http.Header{"Fn-Scheduled-Time": {next.UTC().Format(time.RFC3339)}}}
+		for deliver(ctx, e) != nil {
+			// retry until the next run is due
+			if time.Now().After(sched.Next(next)) || sleep(ctx, time.Second) != nil {
+				break
+			}
+		}
+		next = sched.Next(time.Now())
+	}
+}
+
+// sleep waits for d, returning early with the error of ctx once it is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
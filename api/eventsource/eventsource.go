@@ -0,0 +1,74 @@
+// Package eventsource binds triggers to the event sources named by their
+// source, e.g. a trigger of type kafka with source kafka://orders delivers the
+// messages of the orders topic to its fn. Event sources are providers
+// registered per trigger type, the server runs a source per trigger and
+// delivers its events as async calls.
+package eventsource
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// Event is an event of a source, which becomes the request of an async call
+type Event struct {
+	Header http.Header
+	Body   []byte
+}
+
+// Deliver queues a call for an event, an event is only done once it returned
+// nil, sources retry events which failed to be delivered.
+type Deliver func(ctx context.Context, e *Event) error
+
+// Config is the configuration of the event sources of a server
+type Config struct {
+	// KafkaBrokers are the brokers of kafka:// sources which do not name brokers
+	KafkaBrokers []string
+}
+
+// Provider runs the event sources of a trigger type
+type Provider interface {
+	// Type is the trigger type of the triggers bound to sources of the provider
+	Type() string
+
+	// Validate checks the source of a trigger
+	Validate(source string) error
+
+	// Run delivers the events of the source of trig until ctx is done, it
+	// returns early only on errors of the source itself.
+	Run(ctx context.Context, cfg *Config, trig *models.Trigger, deliver Deliver) error
+}
+
+var providers = map[string]Provider{}
+
+// Register adds a provider, making its type a valid trigger type. It must be
+// called in init, before triggers are validated.
+func Register(p Provider) {
+	providers[p.Type()] = p
+	models.RegisterTriggerType(p.Type(), p.Validate)
+}
+
+// Get returns the provider of a trigger type
+func Get(triggerType string) (Provider, bool) {
+	p, ok := providers[triggerType]
+	return p, ok
+}
+
+// Types returns the trigger types with a provider
+func Types() []string {
+	types := make([]string, 0, len(providers))
+	for t := range providers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func init() {
+	Register(cronProvider{})
+	Register(kafkaProvider{})
+	Register(objectStorageProvider{})
+}
@@ -0,0 +1,123 @@
+package eventsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestValidateSources(t *testing.T) {
+	trigger := func(typ, source string) *models.Trigger {
+		return &models.Trigger{Name: "t", AppID: "app", FnID: "fn", Type: typ, Source: source}
+	}
+	for _, tr := range []*models.Trigger{
+		trigger(TriggerTypeCron, "cron:*/5 * * * *"),
+		trigger(TriggerTypeKafka, "kafka://orders"),
+		trigger(TriggerTypeKafka, "kafka://b1:9092,b2:9092/orders"),
+		trigger(TriggerTypeObjectStorage, "s3://minio:9000/us-east-1/uploads"),
+		trigger(TriggerTypeObjectStorage, "s3://key:secret@minio:9000/us-east-1/uploads/images/?ssl=false&interval=1m"),
+		trigger(models.TriggerTypeHTTP, "/hello"),
+	} {
+		if err := tr.Validate(); err != nil {
+			t.Errorf("expected %s source %s to be valid, got %v", tr.Type, tr.Source, err)
+		}
+	}
+	for _, tr := range []*models.Trigger{
+		trigger(TriggerTypeCron, "*/5 * * * *"),
+		trigger(TriggerTypeCron, "cron:61 * * * *"),
+		trigger(TriggerTypeKafka, "kafka://"),
+		trigger(TriggerTypeKafka, "kafka://b1:9092/orders/more"),
+		trigger(TriggerTypeKafka, "/orders"),
+		trigger(TriggerTypeObjectStorage, "s3://minio:9000/us-east-1"),
+		trigger(TriggerTypeObjectStorage, "s3://minio:9000/us-east-1/uploads?interval=1ms"),
+		trigger(models.TriggerTypeHTTP, "hello"),
+		trigger("queue", "/hello"),
+	} {
+		if err := tr.Validate(); err == nil {
+			t.Errorf("expected %s source %s to be invalid", tr.Type, tr.Source)
+		}
+	}
+}
+
+// fakeBucket serves ListObjectsV2 for a bucket of objects by key, with etags
+type fakeBucket struct {
+	lock    sync.Mutex
+	objects map[string]string
+}
+
+func (b *fakeBucket) put(key, etag string) {
+	b.lock.Lock()
+	b.objects[key] = etag
+	b.lock.Unlock()
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>uploads</Name><IsTruncated>false</IsTruncated>`)
+	for k, etag := range b.objects {
+		if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><ETag>%s</ETag><Size>3</Size><LastModified>2018-01-01T00:00:00.000Z</LastModified></Contents>`, k, etag)
+		}
+	}
+	fmt.Fprint(w, `</ListBucketResult>`)
+}
+
+func TestObjectStorage(t *testing.T) {
+	bucket := &fakeBucket{objects: map[string]string{"images/old.png": "1"}}
+	srv := httptest.NewServer(bucket)
+	defer srv.Close()
+
+	events := make(chan *ObjectNotification, 10)
+	deliver := func(ctx context.Context, e *Event) error {
+		var n ObjectNotification
+		if err := json.Unmarshal(e.Body, &n); err != nil {
+			t.Error(err)
+		}
+		events <- &n
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	tr := &models.Trigger{ID: "t", Type: TriggerTypeObjectStorage, Source: "s3://key:secret@" + host + "/us-east-1/uploads/images/?ssl=false&interval=1s"}
+	go objectStorageProvider{}.Run(ctx, &Config{}, tr, deliver)
+
+	time.Sleep(100 * time.Millisecond) // the first listing is the baseline
+	bucket.put("images/new.png", "2")
+	bucket.put("docs/new.txt", "3")
+
+	select {
+	case n := <-events:
+		if n.Key != "images/new.png" || n.Bucket != "uploads" || n.ETag != "2" || n.Size != 3 {
+			t.Fatalf("unexpected notification %+v", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a notification for the new object")
+	}
+
+	bucket.put("images/new.png", "4")
+	select {
+	case n := <-events:
+		if n.Key != "images/new.png" || n.ETag != "4" {
+			t.Fatalf("unexpected notification %+v", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a notification for the overwritten object")
+	}
+
+	select {
+	case n := <-events:
+		t.Fatalf("unexpected notification %+v", n)
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
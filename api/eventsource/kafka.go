@@ -0,0 +1,91 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs/kafka"
+)
+
+// TriggerTypeKafka triggers deliver the messages of a kafka topic to their fn,
+// their source is kafka://topic, using the brokers configured on the server,
+// or kafka://broker1:9092,broker2:9092/topic. Every trigger consumes its topic
+// as its own consumer group, so each message is delivered once per trigger.
+const TriggerTypeKafka = "kafka"
+
+// kafkaRedeliverAfter is when messages which failed to be delivered are tried again
+const kafkaRedeliverAfter = 30 * time.Second
+
+type kafkaProvider struct{}
+
+func (kafkaProvider) Type() string { return TriggerTypeKafka }
+
+func (kafkaProvider) Validate(source string) error {
+	_, _, err := parseKafkaSource(source)
+	return err
+}
+
+func parseKafkaSource(source string) (brokers []string, topic string, err error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "kafka" {
+		return nil, "", errors.New("expected kafka://topic or kafka://broker:port/topic")
+	}
+	path := strings.Trim(u.Path, "/")
+	if path == "" {
+		topic = u.Host
+	} else {
+		brokers, topic = strings.Split(u.Host, ","), path
+	}
+	if topic == "" || strings.Contains(topic, "/") {
+		return nil, "", errors.New("expected kafka://topic or kafka://broker:port/topic")
+	}
+	return brokers, topic, nil
+}
+
+// Run delivers the value of each message of the topic as the body of a call,
+// with its topic, partition and offset in Fn-Kafka- headers.
+func (kafkaProvider) Run(ctx context.Context, cfg *Config, trig *models.Trigger, deliver Deliver) error {
+	brokers, topic, err := parseKafkaSource(trig.Source)
+	if err != nil {
+		return err
+	}
+	if len(brokers) == 0 {
+		brokers = cfg.KafkaBrokers
+	}
+	if len(brokers) == 0 {
+		return fmt.Errorf("kafka source %s names no brokers and no brokers are configured", trig.Source)
+	}
+
+	consumer, err := kafka.NewConsumer(ctx, brokers, []string{topic}, "fn-trigger-"+trig.ID, kafkaRedeliverAfter)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	for ctx.Err() == nil {
+		msg := consumer.Next()
+		if msg == nil {
+			sleep(ctx, 100*time.Millisecond)
+			continue
+		}
+		e := &Event{
+			Header: http.Header{
+				"Fn-Kafka-Topic":     {msg.Topic},
+				"Fn-Kafka-Partition": {strconv.Itoa(int(msg.Partition))},
+				"Fn-Kafka-Offset":    {strconv.FormatInt(msg.Offset, 10)},
+			},
+			Body: msg.Value,
+		}
+		if deliver(ctx, e) == nil {
+			consumer.Ack(msg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package eventsource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// TriggerTypeObjectStorage triggers deliver a notification for every object
+// created or overwritten in a bucket of an s3 api compatible object storage.
+// Their source is
+//
+//	s3://[access_key_id:secret_access_key@]host/region/bucket[/prefix]?ssl=false&interval=30s
+//
+// without credentials, those of the server's environment are used. The bucket
+// is listed every interval, objects which existed when the source started, or
+// changed while it was not running, are not notified.
+const TriggerTypeObjectStorage = "objectstorage"
+
+const defaultObjectStorageInterval = 30 * time.Second
+
+// ObjectNotification is the body of the calls of objectstorage triggers
+type ObjectNotification struct {
+	Bucket       string    `json:"bucket"`
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+type objectStorageProvider struct{}
+
+type objectStorageSource struct {
+	config   *aws.Config
+	bucket   string
+	prefix   string
+	interval time.Duration
+}
+
+func (objectStorageProvider) Type() string { return TriggerTypeObjectStorage }
+
+func (objectStorageProvider) Validate(source string) error {
+	_, err := parseObjectStorageSource(source)
+	return err
+}
+
+func parseObjectStorageSource(source string) (*objectStorageSource, error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, errors.New("expected s3://host/region/bucket[/prefix]")
+	}
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.New("expected s3://host/region/bucket[/prefix]")
+	}
+	src := &objectStorageSource{
+		config: &aws.Config{
+			Endpoint:         aws.String(u.Host),
+			Region:           aws.String(parts[0]),
+			DisableSSL:       aws.Bool(u.Query().Get("ssl") == "false"),
+			S3ForcePathStyle: aws.Bool(true),
+		},
+		bucket:   parts[1],
+		interval: defaultObjectStorageInterval,
+	}
+	if len(parts) == 3 {
+		src.prefix = parts[2]
+	}
+	if u.User != nil {
+		secret, _ := u.User.Password()
+		src.config.Credentials = credentials.NewStaticCredentials(u.User.Username(), secret, "")
+	}
+	if i := u.Query().Get("interval"); i != "" {
+		if src.interval, err = time.ParseDuration(i); err != nil || src.interval < time.Second {
+			return nil, errors.New("interval must be a duration of at least 1s")
+		}
+	}
+	return src, nil
+}
+
+// Run delivers an ObjectNotification for each new or changed object
+func (objectStorageProvider) Run(ctx context.Context, cfg *Config, trig *models.Trigger, deliver Deliver) error {
+	src, err := parseObjectStorageSource(trig.Source)
+	if err != nil {
+		return err
+	}
+	sess, err := session.NewSession(src.config)
+	if err != nil {
+		return err
+	}
+	client := s3.New(sess)
+
+	// the objects seen by key, with their etag
+	seen, err := src.list(ctx, client, nil, nil)
+	if err != nil {
+		return err
+	}
+	for sleep(ctx, src.interval) == nil {
+		objects, err := src.list(ctx, client, seen, deliver)
+		if err != nil {
+			// a partial listing would notify the objects it missed again
+			common.Logger(ctx).WithError(err).WithField("trigger_id", trig.ID).Error("Failed to list the objects of an objectstorage source")
+			continue
+		}
+		seen = objects
+	}
+	return nil
+}
+
+// list returns the etags of the objects in the bucket by key, delivering a
+// notification for those not in seen. Objects whose notification failed are
+// left out, so that they are notified again.
+func (src *objectStorageSource) list(ctx context.Context, client *s3.S3, seen map[string]string, deliver Deliver) (map[string]string, error) {
+	objects := make(map[string]string, len(seen))
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(src.bucket), Prefix: aws.String(src.prefix)}
+	err := client.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, o := range page.Contents {
+			key, etag := aws.StringValue(o.Key), aws.StringValue(o.ETag)
+			if deliver != nil && seen[key] != etag {
+				body, _ := json.Marshal(&ObjectNotification{
+					Bucket:       src.bucket,
+					Key:          key,
+					Size:         aws.Int64Value(o.Size),
+					ETag:         etag,
+					LastModified: aws.TimeValue(o.LastModified),
+				})
+				e := &Event{Header: http.Header{"Content-Type": {"application/json"}}, Body: body}
+				if deliver(ctx, e) != nil {
+					if old, ok := seen[key]; ok {
+						objects[key] = old
+					}
+					continue
+				}
+			}
+			objects[key] = etag
+		}
+		return true
+	})
+	return objects, err
+}
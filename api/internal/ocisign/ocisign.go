@@ -0,0 +1,181 @@
+// Package ocisign signs requests to oracle cloud infrastructure apis with the
+// api keys of oci cli config files.
+package ocisign
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIKey is an oci api signing key of a user
+type APIKey struct {
+	tenancy     string
+	user        string
+	fingerprint string
+	key         *rsa.PrivateKey
+}
+
+func (k *APIKey) keyID() string {
+	return k.tenancy + "/" + k.user + "/" + k.fingerprint
+}
+
+// NewAPIKey returns the api key of a user
+func NewAPIKey(tenancy, user, fingerprint string, key *rsa.PrivateKey) *APIKey {
+	return &APIKey{tenancy: tenancy, user: user, fingerprint: fingerprint, key: key}
+}
+
+// LoadAPIKey reads the api key of a profile of an oci cli config file, see
+// https://docs.cloud.oracle.com/iaas/Content/API/Concepts/sdkconfig.htm
+func LoadAPIKey(path, profile string) (*APIKey, error) {
+	path = expandHome(path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+		case section == profile || (section == "DEFAULT" && profile != "DEFAULT"):
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			key := strings.TrimSpace(kv[0])
+			// the profile overrides values of the DEFAULT section
+			if _, ok := values[key]; !ok || section == profile {
+				values[key] = strings.TrimSpace(kv[1])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, k := range []string{"tenancy", "user", "fingerprint", "key_file"} {
+		if values[k] == "" {
+			return nil, fmt.Errorf("oci config %s is missing %s for profile %s", path, k, profile)
+		}
+	}
+
+	keyFile := expandHome(values["key_file"])
+	if !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(filepath.Dir(path), keyFile)
+	}
+	pemBytes, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(pemBytes, values["pass_phrase"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid oci api key %s: %v", keyFile, err)
+	}
+
+	return &APIKey{
+		tenancy:     values["tenancy"],
+		user:        values["user"],
+		fingerprint: values["fingerprint"],
+		key:         key,
+	}, nil
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+func parsePrivateKey(pemBytes []byte, passphrase string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		var err error
+		if der, err = x509.DecryptPEMBlock(block, []byte(passphrase)); err != nil {
+			return nil, err
+		}
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an rsa key")
+	}
+	return rsaKey, nil
+}
+
+// Sign signs a request with the oci http signature scheme, see
+// https://docs.cloud.oracle.com/iaas/Content/API/Concepts/signingrequests.htm
+// body is the body of the request, which must be set already.
+func (k *APIKey) Sign(req *http.Request, body []byte) error {
+	req.Header.Set("date", time.Now().UTC().Format(http.TimeFormat))
+
+	headers := []string{"date", "(request-target)", "host"}
+	if req.Method == http.MethodPost || req.Method == http.MethodPut {
+		sum := sha256.Sum256(body)
+		req.Header.Set("content-length", strconv.Itoa(len(body)))
+		req.Header.Set("x-content-sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		if req.Header.Get("content-type") == "" {
+			req.Header.Set("content-type", "application/json")
+		}
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	var signing bytes.Buffer
+	for i, h := range headers {
+		if i > 0 {
+			signing.WriteByte('\n')
+		}
+		switch h {
+		case "(request-target)":
+			fmt.Fprintf(&signing, "%s: %s %s", h, strings.ToLower(req.Method), req.URL.RequestURI())
+		case "host":
+			fmt.Fprintf(&signing, "%s: %s", h, req.URL.Host)
+		default:
+			fmt.Fprintf(&signing, "%s: %s", h, req.Header.Get(h))
+		}
+	}
+
+	digest := sha256.Sum256(signing.Bytes())
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("authorization", fmt.Sprintf(`Signature version="1",headers="%s",keyId="%s",algorithm="rsa-sha256",signature="%s"`,
+		strings.Join(headers, " "), k.keyID(), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
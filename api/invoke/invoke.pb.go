@@ -0,0 +1,239 @@
+// Package invoke holds the messages and service of the gRPC invoke front-end
+// of fn, see invoke.proto. There is no protoc in the build of fn, this file is
+// maintained by hand from invoke.proto: the messages only need their protobuf
+// struct tags to be marshalled, keep them in sync with the .proto.
+package invoke
+
+import (
+	context "context"
+
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+)
+
+// Request to invoke a fn, the metadata of the call are passed to the fn as
+// its request headers, e.g. fn-invoke-type: detached. The deadline of the
+// call bounds the execution of the fn.
+type InvokeRequest struct {
+	FnId                 string   `protobuf:"bytes,1,opt,name=fn_id,json=fnId,proto3" json:"fn_id,omitempty"`
+	Body                 []byte   `protobuf:"bytes,2,opt,name=body,proto3" json:"body,omitempty"`
+	ContentType          string   `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InvokeRequest) Reset()         { *m = InvokeRequest{} }
+func (m *InvokeRequest) String() string { return proto.CompactTextString(m) }
+func (*InvokeRequest) ProtoMessage()    {}
+
+func (m *InvokeRequest) GetFnId() string {
+	if m != nil {
+		return m.FnId
+	}
+	return ""
+}
+
+func (m *InvokeRequest) GetBody() []byte {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+func (m *InvokeRequest) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+// Response of a fn, its headers are sent as header metadata. Responses with
+// a status of 400 or more are returned as errors instead.
+type InvokeResponse struct {
+	Body                 []byte   `protobuf:"bytes,1,opt,name=body,proto3" json:"body,omitempty"`
+	CallId               string   `protobuf:"bytes,2,opt,name=call_id,json=callId,proto3" json:"call_id,omitempty"`
+	StatusCode           int32    `protobuf:"varint,3,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InvokeResponse) Reset()         { *m = InvokeResponse{} }
+func (m *InvokeResponse) String() string { return proto.CompactTextString(m) }
+func (*InvokeResponse) ProtoMessage()    {}
+
+func (m *InvokeResponse) GetBody() []byte {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+func (m *InvokeResponse) GetCallId() string {
+	if m != nil {
+		return m.CallId
+	}
+	return ""
+}
+
+func (m *InvokeResponse) GetStatusCode() int32 {
+	if m != nil {
+		return m.StatusCode
+	}
+	return 0
+}
+
+// Part of the response of a fn, sent as the fn writes it.
+type InvokeResponseChunk struct {
+	Data                 []byte   `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InvokeResponseChunk) Reset()         { *m = InvokeResponseChunk{} }
+func (m *InvokeResponseChunk) String() string { return proto.CompactTextString(m) }
+func (*InvokeResponseChunk) ProtoMessage()    {}
+
+func (m *InvokeResponseChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// This is a compile-time assertion to ensure that this file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// FnInvokeClient is the client API for FnInvoke service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type FnInvokeClient interface {
+	// Invokes a fn and returns its response once it is complete.
+	Invoke(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error)
+	// Invokes a fn and streams its response, the status and call id are sent
+	// as header metadata before the first chunk.
+	InvokeStream(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (FnInvoke_InvokeStreamClient, error)
+}
+
+type fnInvokeClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewFnInvokeClient(cc *grpc.ClientConn) FnInvokeClient {
+	return &fnInvokeClient{cc}
+}
+
+func (c *fnInvokeClient) Invoke(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error) {
+	out := new(InvokeResponse)
+	err := c.cc.Invoke(ctx, "/FnInvoke/Invoke", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fnInvokeClient) InvokeStream(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (FnInvoke_InvokeStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_FnInvoke_serviceDesc.Streams[0], "/FnInvoke/InvokeStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fnInvokeInvokeStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FnInvoke_InvokeStreamClient interface {
+	Recv() (*InvokeResponseChunk, error)
+	grpc.ClientStream
+}
+
+type fnInvokeInvokeStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *fnInvokeInvokeStreamClient) Recv() (*InvokeResponseChunk, error) {
+	m := new(InvokeResponseChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FnInvokeServer is the server API for FnInvoke service.
+type FnInvokeServer interface {
+	// Invokes a fn and returns its response once it is complete.
+	Invoke(context.Context, *InvokeRequest) (*InvokeResponse, error)
+	// Invokes a fn and streams its response, the status and call id are sent
+	// as header metadata before the first chunk.
+	InvokeStream(*InvokeRequest, FnInvoke_InvokeStreamServer) error
+}
+
+func RegisterFnInvokeServer(s *grpc.Server, srv FnInvokeServer) {
+	s.RegisterService(&_FnInvoke_serviceDesc, srv)
+}
+
+func _FnInvoke_Invoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FnInvokeServer).Invoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/FnInvoke/Invoke",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FnInvokeServer).Invoke(ctx, req.(*InvokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FnInvoke_InvokeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(InvokeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FnInvokeServer).InvokeStream(m, &fnInvokeInvokeStreamServer{stream})
+}
+
+type FnInvoke_InvokeStreamServer interface {
+	Send(*InvokeResponseChunk) error
+	grpc.ServerStream
+}
+
+type fnInvokeInvokeStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *fnInvokeInvokeStreamServer) Send(m *InvokeResponseChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _FnInvoke_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "FnInvoke",
+	HandlerType: (*FnInvokeServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Invoke",
+			Handler:    _FnInvoke_Invoke_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "InvokeStream",
+			Handler:       _FnInvoke_InvokeStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "invoke.proto",
+}
@@ -0,0 +1,209 @@
+// Package bolt implements a local log store backed by a bolt db file
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	callsBucket = []byte("calls")
+	logsBucket  = []byte("logs")
+)
+
+type boltLogsProvider int
+
+type store struct {
+	db *bolt.DB
+}
+
+// calls and logs are keyed by fn id then call id, so that a reverse cursor
+// over a fn prefix lists its calls newest first.
+func callKey(fnID, callID string) []byte {
+	return []byte(fnID + "\x00" + callID)
+}
+
+func fnPrefix(fnID string) []byte {
+	return []byte(fnID + "\x00")
+}
+
+func (boltLogsProvider) String() string {
+	return "bolt"
+}
+
+func (boltLogsProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "bolt"
+}
+
+// New creates a log store in the bolt db file at url path, eg. bolt:///data/fn-logs.db
+func (boltLogsProvider) New(ctx context.Context, u *url.URL) (models.LogStore, error) {
+	return New(u.Path)
+}
+
+// New creates or opens the bolt log store at path
+func New(path string) (models.LogStore, error) {
+	log := logrus.WithFields(logrus.Fields{"logstore": "bolt", "file": path})
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		log.WithError(err).Errorln("Could not create data directory for log store")
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		log.WithError(err).Errorln("Could not open BoltDB file for log store")
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{callsBucket, logsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Errorln("Error creating log store buckets")
+		db.Close()
+		return nil, err
+	}
+
+	log.Debug("BoltDb log store initialized")
+	return &store{db: db}, nil
+}
+
+func (s *store) InsertCall(ctx context.Context, call *models.Call) error {
+	b, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(callsBucket).Put(callKey(call.FnID, call.ID), b)
+	})
+}
+
+func (s *store) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	var call models.Call
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(callsBucket).Get(callKey(fnID, callID))
+		if v == nil {
+			return models.ErrCallNotFound
+		}
+		return json.Unmarshal(v, &call)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+func (s *store) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	res := &models.CallList{Items: []*models.Call{}}
+
+	prefix := fnPrefix(filter.FnID)
+	// upper bound (exclusive) of the reverse scan
+	upper := []byte(filter.FnID + "\x01")
+	if filter.Cursor != "" {
+		cursor, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		upper = callKey(filter.FnID, string(cursor))
+	}
+
+	// calls are stored at the resolution of common.DateTime marshaling
+	from := time.Time(filter.FromTime).Truncate(time.Millisecond)
+	to := time.Time(filter.ToTime).Truncate(time.Millisecond)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(callsBucket).Cursor()
+
+		k, v := c.Seek(upper)
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(res.Items) < filter.PerPage; k, v = c.Prev() {
+			var call models.Call
+			if err := json.Unmarshal(v, &call); err != nil {
+				return err
+			}
+
+			created := time.Time(call.CreatedAt)
+			if !from.IsZero() && !created.After(from) {
+				continue
+			}
+			if !to.IsZero() && !created.Before(to) {
+				continue
+			}
+			if !filter.MatchOutcome(&call) {
+				continue
+			}
+			res.Items = append(res.Items, &call)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (s *store) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	b, err := ioutil.ReadAll(callLog)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(logsBucket).Put(callKey(call.FnID, call.ID), b)
+	})
+}
+
+func (s *store) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	var b []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(logsBucket).Get(callKey(fnID, callID))
+		if v == nil {
+			return models.ErrCallLogNotFound
+		}
+		// values are only valid for the life of the transaction
+		b = append([]byte(nil), v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+func (s *store) Close() error {
+	return s.db.Close()
+}
+
+func init() {
+	logs.Register(boltLogsProvider(0))
+}
@@ -0,0 +1,74 @@
+package bolt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	logTesting "github.com/fnproject/fn/api/logs/testing"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestBolt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-bolt-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ls, err := New(filepath.Join(dir, "logs.db"))
+	if err != nil {
+		t.Fatalf("failed to create bolt log store: %v", err)
+	}
+	defer ls.Close()
+
+	logTesting.Test(t, ls)
+}
+
+func TestBoltSearchLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-bolt-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ls, err := New(filepath.Join(dir, "logs.db"))
+	if err != nil {
+		t.Fatalf("failed to create bolt log store: %v", err)
+	}
+	defer ls.Close()
+
+	ctx := context.Background()
+	fnID := id.New().String()
+	for _, text := range []string{"hello\nworld\n", "nothing here\n", "hello again\n"} {
+		call := &models.Call{ID: id.New().String(), FnID: fnID, CreatedAt: common.DateTime(time.Now())}
+		if err := ls.InsertCall(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+		if err := ls.InsertLog(ctx, call, strings.NewReader(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := models.SearchLogs(ctx, ls, &models.LogFilter{FnID: fnID, Contains: "hello", PerPage: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Lines[0] != "hello again" || res.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", res)
+	}
+
+	res, err = models.SearchLogs(ctx, ls, &models.LogFilter{FnID: fnID, Contains: "hello", PerPage: 1, Cursor: res.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Lines[0] != "hello" {
+		t.Fatalf("unexpected second page %+v", res)
+	}
+}
@@ -0,0 +1,340 @@
+// Package elasticsearch implements a log store on top of the elasticsearch
+// REST API, with native log search.
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultIndex = "fn-calls"
+	timeFormat   = time.RFC3339Nano
+)
+
+// one document per call, holding both the call and its log
+var indexMapping = []byte(`{
+	"mappings": {
+		"properties": {
+			"id":          {"type": "keyword"},
+			"fn_id":       {"type": "keyword"},
+			"app_id":      {"type": "keyword"},
+			"created_at":  {"type": "date"},
+			"status":      {"type": "keyword"},
+			"error_class": {"type": "keyword"},
+			"call":        {"type": "object", "enabled": false},
+			"log":         {"type": "text"}
+		}
+	}
+}`)
+
+type document struct {
+	ID        string `json:"id"`
+	FnID      string `json:"fn_id"`
+	AppID     string `json:"app_id"`
+	CreatedAt string `json:"created_at"`
+	// status and error class of the call, indexed to filter calls on
+	Status     string       `json:"status,omitempty"`
+	ErrorClass string       `json:"error_class,omitempty"`
+	Call       *models.Call `json:"call,omitempty"`
+	Log        *string      `json:"log,omitempty"`
+}
+
+type store struct {
+	client   *http.Client
+	endpoint string
+	index    string
+	user     *url.Userinfo
+}
+
+type esStoreProvider int
+
+func (esStoreProvider) String() string {
+	return "elasticsearch"
+}
+
+func (esStoreProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "elasticsearch" || u.Scheme == "elasticsearchs"
+}
+
+// New creates an elasticsearch log store, the url is of the form
+// elasticsearch[s]://[user:pass@]host:port/index, elasticsearchs uses https.
+func (esStoreProvider) New(ctx context.Context, u *url.URL) (models.LogStore, error) {
+	scheme := "http"
+	if u.Scheme == "elasticsearchs" {
+		scheme = "https"
+	}
+
+	index := strings.Trim(u.Path, "/")
+	if index == "" {
+		index = defaultIndex
+	}
+
+	s := &store{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		endpoint: scheme + "://" + u.Host,
+		index:    index,
+		user:     u.User,
+	}
+
+	err := s.do(ctx, http.MethodPut, "/"+index, indexMapping, nil)
+	if err != nil && !strings.Contains(err.Error(), "resource_already_exists_exception") {
+		return nil, err
+	}
+
+	common.Logger(ctx).WithFields(logrus.Fields{"host": u.Host, "index": index}).Info("elasticsearch log store initialized")
+	return s, nil
+}
+
+func (s *store) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, s.endpoint+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.user != nil {
+		pass, _ := s.user.Password()
+		req.SetBasicAuth(s.user.Username(), pass)
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && out != nil {
+		// let callers decode found=false
+	} else if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("elasticsearch %s %s: %d %s", method, path, resp.StatusCode, msg)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// upsert merges doc fields into the call's document; calls and logs are
+// inserted separately.
+func (s *store) upsert(ctx context.Context, doc *document) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"doc":           doc,
+		"doc_as_upsert": true,
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/%s/_update/%s?refresh=wait_for", s.index, url.PathEscape(doc.ID))
+	return s.do(ctx, http.MethodPost, path, body, nil)
+}
+
+func (s *store) get(ctx context.Context, fnID, callID string) (*document, error) {
+	var res struct {
+		Found  bool     `json:"found"`
+		Source document `json:"_source"`
+	}
+	path := fmt.Sprintf("/%s/_doc/%s", s.index, url.PathEscape(callID))
+	if err := s.do(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return nil, err
+	}
+	if !res.Found || res.Source.FnID != fnID {
+		return nil, nil
+	}
+	return &res.Source, nil
+}
+
+func newDocument(call *models.Call) *document {
+	return &document{
+		ID:        call.ID,
+		FnID:      call.FnID,
+		AppID:     call.AppID,
+		CreatedAt: time.Time(call.CreatedAt).UTC().Format(timeFormat),
+	}
+}
+
+func (s *store) InsertCall(ctx context.Context, call *models.Call) error {
+	doc := newDocument(call)
+	doc.Call = call
+	doc.Status, doc.ErrorClass = call.Status, call.ErrorClass
+	return s.upsert(ctx, doc)
+}
+
+func (s *store) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	doc, err := s.get(ctx, fnID, callID)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil || doc.Call == nil {
+		return nil, models.ErrCallNotFound
+	}
+	return doc.Call, nil
+}
+
+func (s *store) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	b, err := ioutil.ReadAll(callLog)
+	if err != nil {
+		return err
+	}
+	log := string(b)
+
+	doc := newDocument(call)
+	doc.Log = &log
+	return s.upsert(ctx, doc)
+}
+
+func (s *store) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	doc, err := s.get(ctx, fnID, callID)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil || doc.Log == nil {
+		return nil, models.ErrCallLogNotFound
+	}
+	return strings.NewReader(*doc.Log), nil
+}
+
+// search runs a query for the documents of a fn, newest first
+func (s *store) search(ctx context.Context, fnID string, from, to common.DateTime, cursor string, perPage int, must []interface{}) ([]document, error) {
+	filters := []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{"fn_id": fnID}},
+	}
+
+	created := map[string]interface{}{}
+	if !time.Time(from).IsZero() {
+		created["gt"] = time.Time(from).UTC().Format(timeFormat)
+	}
+	if !time.Time(to).IsZero() {
+		created["lt"] = time.Time(to).UTC().Format(timeFormat)
+	}
+	if len(created) > 0 {
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"created_at": created}})
+	}
+
+	if cursor != "" {
+		c, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"id": map[string]interface{}{"lt": string(c)}}})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"size": perPage,
+		"sort": []interface{}{map[string]interface{}{"id": "desc"}},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": filters,
+				"must":   must,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Hits struct {
+			Hits []struct {
+				Source document `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/"+s.index+"/_search", body, &res); err != nil {
+		return nil, err
+	}
+
+	docs := make([]document, 0, len(res.Hits.Hits))
+	for _, h := range res.Hits.Hits {
+		docs = append(docs, h.Source)
+	}
+	return docs, nil
+}
+
+func (s *store) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	must := []interface{}{
+		map[string]interface{}{"exists": map[string]interface{}{"field": "call"}},
+	}
+	if filter.Status != "" {
+		must = append(must, map[string]interface{}{"term": map[string]interface{}{"status": filter.Status}})
+	}
+	if filter.ErrorClass != "" {
+		must = append(must, map[string]interface{}{"term": map[string]interface{}{"error_class": filter.ErrorClass}})
+	}
+	docs, err := s.search(ctx, filter.FnID, filter.FromTime, filter.ToTime, filter.Cursor, filter.PerPage, must)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &models.CallList{Items: make([]*models.Call, 0, len(docs))}
+	for _, doc := range docs {
+		res.Items = append(res.Items, doc.Call)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+// SearchLogs implements models.LogSearcher
+func (s *store) SearchLogs(ctx context.Context, filter *models.LogFilter) (*models.LogMatchList, error) {
+	must := []interface{}{
+		map[string]interface{}{"exists": map[string]interface{}{"field": "log"}},
+	}
+	if filter.Contains != "" {
+		must = append(must, map[string]interface{}{"match_phrase": map[string]interface{}{"log": filter.Contains}})
+	}
+
+	docs, err := s.search(ctx, filter.FnID, filter.FromTime, filter.ToTime, filter.Cursor, filter.PerPage, must)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &models.LogMatchList{Items: []*models.LogMatch{}}
+	for _, doc := range docs {
+		call := &models.Call{ID: doc.ID}
+		if t, err := time.Parse(timeFormat, doc.CreatedAt); err == nil {
+			call.CreatedAt = common.DateTime(t)
+		}
+		// phrase matching is on analyzed text, narrow down to the exact lines
+		if m := models.MatchLog(call, strings.NewReader(*doc.Log), filter.Contains); m != nil {
+			res.Items = append(res.Items, m)
+		}
+	}
+
+	if len(docs) > 0 && len(docs) == filter.PerPage {
+		last := []byte(docs[len(docs)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (s *store) Close() error {
+	return nil
+}
+
+func init() {
+	logs.Register(esStoreProvider(0))
+}
@@ -0,0 +1,263 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// fakeES serves the documents of an index like elasticsearch does, and
+// records the searches made
+type fakeES struct {
+	lock     sync.Mutex
+	docs     map[string]map[string]interface{}
+	searches []map[string]interface{}
+	// hits returned by searches
+	hits []document
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "fn" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var body map[string]interface{}
+	if b, _ := ioutil.ReadAll(r.Body); len(b) > 0 {
+		if err := json.Unmarshal(b, &body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPut && len(parts) == 1:
+		if f.docs != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"resource_already_exists_exception"}}`))
+			return
+		}
+		f.docs = make(map[string]map[string]interface{})
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[1] == "_update":
+		doc, ok := f.docs[parts[2]]
+		if !ok {
+			doc = make(map[string]interface{})
+			f.docs[parts[2]] = doc
+		}
+		for k, v := range body["doc"].(map[string]interface{}) {
+			doc[k] = v
+		}
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "_doc":
+		doc, ok := f.docs[parts[2]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"found": false})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"found": true, "_source": doc})
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "_search":
+		f.searches = append(f.searches, body)
+		var hits []interface{}
+		for _, doc := range f.hits {
+			hits = append(hits, map[string]interface{}{"_source": doc})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"hits": map[string]interface{}{"hits": hits}})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeES) lastSearch() map[string]interface{} {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.searches[len(f.searches)-1]
+}
+
+func newTestStore(t *testing.T) (*store, *fakeES, *httptest.Server) {
+	es := &fakeES{}
+	srv := httptest.NewServer(es)
+
+	u, err := url.Parse(strings.Replace(srv.URL, "http://", "elasticsearch://fn:secret@", 1) + "/calls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := esStoreProvider(0).New(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to create elasticsearch log store: %v", err)
+	}
+	// the index exists once created
+	if _, err := esStoreProvider(0).New(context.Background(), u); err != nil {
+		t.Fatalf("failed to create elasticsearch log store on an existing index: %v", err)
+	}
+	return ls.(*store), es, srv
+}
+
+// jsonOf returns v as it is decoded from JSON, to compare with requests
+func jsonOf(t *testing.T, v string) interface{} {
+	var res interface{}
+	if err := json.Unmarshal([]byte(v), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestElasticsearchCallsAndLogs(t *testing.T) {
+	ctx := context.Background()
+	ls, _, srv := newTestStore(t)
+	defer srv.Close()
+
+	call := &models.Call{ID: "call1", FnID: "fn1", AppID: "app1", Status: "error", ErrorClass: "timeout",
+		CreatedAt: common.DateTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))}
+	if _, err := ls.GetCall(ctx, "fn1", "call1"); err != models.ErrCallNotFound {
+		t.Fatalf("Expected a missing call not to be found, got %v", err)
+	}
+	if err := ls.InsertLog(ctx, call, strings.NewReader("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	// the document of a call with only a log has no call
+	if _, err := ls.GetCall(ctx, "fn1", "call1"); err != models.ErrCallNotFound {
+		t.Fatalf("Expected the call not to be found before it is inserted, got %v", err)
+	}
+	if err := ls.InsertCall(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ls.GetCall(ctx, "fn1", "call1")
+	if err != nil || got.ID != "call1" || got.Status != "error" || time.Time(got.CreatedAt).Unix() != time.Time(call.CreatedAt).Unix() {
+		t.Fatalf("Expected the call to be decoded, got %+v %v", got, err)
+	}
+	log, err := ls.GetLog(ctx, "fn1", "call1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(log); string(b) != "hello\n" {
+		t.Fatalf("Expected the log to be decoded, got %q", b)
+	}
+
+	// calls of other fns are not found
+	if _, err := ls.GetCall(ctx, "fn2", "call1"); err != models.ErrCallNotFound {
+		t.Fatalf("Expected the call of another fn not to be found, got %v", err)
+	}
+	if _, err := ls.GetLog(ctx, "fn2", "call1"); err != models.ErrCallLogNotFound {
+		t.Fatalf("Expected the log of another fn not to be found, got %v", err)
+	}
+}
+
+func TestElasticsearchGetCallsQuery(t *testing.T) {
+	ctx := context.Background()
+	ls, es, srv := newTestStore(t)
+	defer srv.Close()
+	es.hits = []document{
+		{ID: "call3", FnID: "fn1", Call: &models.Call{ID: "call3"}},
+		{ID: "call2", FnID: "fn1", Call: &models.Call{ID: "call2"}},
+	}
+
+	from := common.DateTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	to := common.DateTime(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+	cursor := base64.RawURLEncoding.EncodeToString([]byte("call4"))
+	res, err := ls.GetCalls(ctx, &models.CallFilter{FnID: "fn1", FromTime: from, ToTime: to, Cursor: cursor, PerPage: 2, Status: "error", ErrorClass: "timeout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 2 || res.Items[0].ID != "call3" || res.Items[1].ID != "call2" {
+		t.Fatalf("Expected the calls of the hits, got %+v", res.Items)
+	}
+	if c, _ := base64.RawURLEncoding.DecodeString(res.NextCursor); string(c) != "call2" {
+		t.Fatalf("Expected a cursor after the last call of a full page, got %q", c)
+	}
+
+	expected := jsonOf(t, `{
+		"size": 2,
+		"sort": [{"id": "desc"}],
+		"query": {"bool": {
+			"filter": [
+				{"term": {"fn_id": "fn1"}},
+				{"range": {"created_at": {"gt": "2020-01-01T00:00:00Z", "lt": "2020-01-02T00:00:00Z"}}},
+				{"range": {"id": {"lt": "call4"}}}
+			],
+			"must": [
+				{"exists": {"field": "call"}},
+				{"term": {"status": "error"}},
+				{"term": {"error_class": "timeout"}}
+			]
+		}}
+	}`)
+	if q := es.lastSearch(); !reflect.DeepEqual(q, expected) {
+		t.Fatalf("Unexpected query %v", q)
+	}
+
+	// a page that is not full is the last one
+	res, err = ls.GetCalls(ctx, &models.CallFilter{FnID: "fn1", PerPage: 3})
+	if err != nil || len(res.Items) != 2 || res.NextCursor != "" {
+		t.Fatalf("Expected the last page, got %+v %v", res, err)
+	}
+	expected = jsonOf(t, `{
+		"size": 3,
+		"sort": [{"id": "desc"}],
+		"query": {"bool": {
+			"filter": [{"term": {"fn_id": "fn1"}}],
+			"must": [{"exists": {"field": "call"}}]
+		}}
+	}`)
+	if q := es.lastSearch(); !reflect.DeepEqual(q, expected) {
+		t.Fatalf("Unexpected query %v", q)
+	}
+
+	if _, err := ls.GetCalls(ctx, &models.CallFilter{FnID: "fn1", Cursor: "not base64!"}); err == nil {
+		t.Fatal("Expected an invalid cursor to be rejected")
+	}
+}
+
+func TestElasticsearchSearchLogs(t *testing.T) {
+	ctx := context.Background()
+	ls, es, srv := newTestStore(t)
+	defer srv.Close()
+	logs := []string{"hello again\n", "hello\nworld\n"}
+	es.hits = []document{
+		{ID: "call2", FnID: "fn1", CreatedAt: "2020-01-02T00:00:00Z", Log: &logs[0]},
+		// analyzed phrase matches may not match exactly
+		{ID: "call1", FnID: "fn1", CreatedAt: "2020-01-01T00:00:00Z", Log: &logs[1]},
+	}
+
+	res, err := models.SearchLogs(ctx, ls, &models.LogFilter{FnID: "fn1", Contains: "hello again", PerPage: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].CallID != "call2" || res.Items[0].Lines[0] != "hello again" {
+		t.Fatalf("Expected the exact matches of the hits, got %+v", res.Items)
+	}
+	if c, _ := base64.RawURLEncoding.DecodeString(res.NextCursor); string(c) != "call1" {
+		t.Fatalf("Expected a cursor after the last hit of a full page, got %q", c)
+	}
+
+	expected := jsonOf(t, `{
+		"size": 2,
+		"sort": [{"id": "desc"}],
+		"query": {"bool": {
+			"filter": [{"term": {"fn_id": "fn1"}}],
+			"must": [
+				{"exists": {"field": "log"}},
+				{"match_phrase": {"log": "hello again"}}
+			]
+		}}
+	}`)
+	if q := es.lastSearch(); !reflect.DeepEqual(q, expected) {
+		t.Fatalf("Unexpected query %v", q)
+	}
+}
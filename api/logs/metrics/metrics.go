@@ -46,6 +46,92 @@ func (m *metricls) GetLog(ctx context.Context, appName, callID string) (io.Reade
 	return m.ls.GetLog(ctx, appName, callID)
 }
 
+func (m *metricls) SearchLogs(ctx context.Context, filter *models.LogFilter) (*models.LogMatchList, error) {
+	ctx, span := trace.StartSpan(ctx, "ls_search_logs")
+	defer span.End()
+	return models.SearchLogs(ctx, m.ls, filter)
+}
+
+func (m *metricls) CompleteCall(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	ctx, span := trace.StartSpan(ctx, "ls_complete_call")
+	defer span.End()
+	cc, ok := m.ls.(models.CallCompleter)
+	if !ok {
+		return models.ErrCallCompletionUnsupported
+	}
+	return cc.CompleteCall(ctx, call, callLog)
+}
+
+func (m *metricls) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	ctx, span := trace.StartSpan(ctx, "ls_insert_dead_letter")
+	defer span.End()
+	dl, ok := m.ls.(models.DeadLetterStore)
+	if !ok {
+		return models.ErrDeadLettersUnsupported
+	}
+	return dl.InsertDeadLetter(ctx, call)
+}
+
+func (m *metricls) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	ctx, span := trace.StartSpan(ctx, "ls_get_dead_letters")
+	defer span.End()
+	dl, ok := m.ls.(models.DeadLetterStore)
+	if !ok {
+		return nil, models.ErrDeadLettersUnsupported
+	}
+	return dl.GetDeadLetters(ctx, filter)
+}
+
+func (m *metricls) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	ctx, span := trace.StartSpan(ctx, "ls_remove_dead_letter")
+	defer span.End()
+	dl, ok := m.ls.(models.DeadLetterStore)
+	if !ok {
+		return models.ErrDeadLettersUnsupported
+	}
+	return dl.RemoveDeadLetter(ctx, fnID, callID)
+}
+
+func (m *metricls) InsertAuditRecord(ctx context.Context, record *models.AuditRecord) error {
+	ctx, span := trace.StartSpan(ctx, "ls_insert_audit_record")
+	defer span.End()
+	as, ok := m.ls.(models.AuditStore)
+	if !ok {
+		return models.ErrAuditUnsupported
+	}
+	return as.InsertAuditRecord(ctx, record)
+}
+
+func (m *metricls) GetAuditRecords(ctx context.Context, filter *models.AuditFilter) (*models.AuditRecordList, error) {
+	ctx, span := trace.StartSpan(ctx, "ls_get_audit_records")
+	defer span.End()
+	as, ok := m.ls.(models.AuditStore)
+	if !ok {
+		return nil, models.ErrAuditUnsupported
+	}
+	return as.GetAuditRecords(ctx, filter)
+}
+
+func (m *metricls) InsertUsageRecord(ctx context.Context, record *models.UsageRecord) error {
+	ctx, span := trace.StartSpan(ctx, "ls_insert_usage_record")
+	defer span.End()
+	us, ok := m.ls.(models.UsageStore)
+	if !ok {
+		return models.ErrUsageUnsupported
+	}
+	return us.InsertUsageRecord(ctx, record)
+}
+
+func (m *metricls) GetUsage(ctx context.Context, filter *models.UsageFilter) (*models.UsageList, error) {
+	ctx, span := trace.StartSpan(ctx, "ls_get_usage")
+	defer span.End()
+	us, ok := m.ls.(models.UsageStore)
+	if !ok {
+		return nil, models.ErrUsageUnsupported
+	}
+	return us.GetUsage(ctx, filter)
+}
+
 func (m *metricls) Close() error {
 	return m.ls.Close()
 }
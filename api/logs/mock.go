@@ -14,8 +14,11 @@ import (
 )
 
 type mock struct {
-	Logs  map[string][]byte
-	Calls []*models.Call
+	Logs        map[string][]byte
+	Calls       []*models.Call
+	DeadLetters []*models.Call
+	Audit       []*models.AuditRecord
+	Usage       []*models.UsageRecord
 }
 
 func NewMock(args ...interface{}) models.LogStore {
@@ -62,6 +65,18 @@ func (m *mock) GetCall(ctx context.Context, fnID, callID string) (*models.Call,
 	return nil, models.ErrCallNotFound
 }
 
+func (m *mock) CompleteCall(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	for _, t := range m.Calls {
+		if t.ID == call.ID {
+			return models.ErrCallAlreadyCompleted
+		}
+	}
+	if err := m.InsertCall(ctx, call); err != nil {
+		return err
+	}
+	return m.InsertLog(ctx, call, callLog)
+}
+
 type sortC []*models.Call
 
 func (s sortC) Len() int           { return len(s) }
@@ -69,9 +84,79 @@ func (s sortC) Less(i, j int) bool { return strings.Compare(s[i].ID, s[j].ID) <
 func (s sortC) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 func (m *mock) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	return filterCalls(m.Calls, filter)
+}
+
+func (m *mock) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	m.DeadLetters = append(m.DeadLetters, call)
+	return nil
+}
+
+func (m *mock) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	return filterCalls(m.DeadLetters, filter)
+}
+
+func (m *mock) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	for i, c := range m.DeadLetters {
+		if c.ID == callID && c.FnID == fnID {
+			m.DeadLetters = append(m.DeadLetters[:i], m.DeadLetters[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrDeadLetterNotFound
+}
+
+func (m *mock) InsertAuditRecord(ctx context.Context, record *models.AuditRecord) error {
+	m.Audit = append(m.Audit, record)
+	return nil
+}
+
+func (m *mock) GetAuditRecords(ctx context.Context, filter *models.AuditFilter) (*models.AuditRecordList, error) {
+	var cursor = ""
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	res := &models.AuditRecordList{Items: []*models.AuditRecord{}}
+	// records are inserted in order, newest last
+	for i := len(m.Audit) - 1; i >= 0; i-- {
+		r := m.Audit[i]
+		if filter.PerPage > 0 && len(res.Items) == filter.PerPage {
+			break
+		}
+		if (cursor == "" || strings.Compare(cursor, r.ID) > 0) && filter.Match(r) {
+			res.Items = append(res.Items, r)
+		}
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (m *mock) InsertUsageRecord(ctx context.Context, record *models.UsageRecord) error {
+	m.Usage = append(m.Usage, record)
+	return nil
+}
+
+func (m *mock) GetUsage(ctx context.Context, filter *models.UsageFilter) (*models.UsageList, error) {
+	agg := models.NewUsageAggregator(filter)
+	for _, r := range m.Usage {
+		agg.Add(r)
+	}
+	return agg.List(), nil
+}
+
+func filterCalls(all []*models.Call, filter *models.CallFilter) (*models.CallList, error) {
 	// sort them all first for cursoring (this is for testing, n is small & mock is not concurrent..)
 	// calls are in DESC order so use sort.Reverse
-	sort.Sort(sort.Reverse(sortC(m.Calls)))
+	sort.Sort(sort.Reverse(sortC(all)))
 
 	var calls []*models.Call
 
@@ -84,7 +169,7 @@ func (m *mock) GetCalls(ctx context.Context, filter *models.CallFilter) (*models
 		cursor = string(s)
 	}
 
-	for _, c := range m.Calls {
+	for _, c := range all {
 		if filter.PerPage > 0 && len(calls) == filter.PerPage {
 			break
 		}
@@ -92,7 +177,8 @@ func (m *mock) GetCalls(ctx context.Context, filter *models.CallFilter) (*models
 		if (cursor == "" || strings.Compare(cursor, c.ID) > 0) &&
 			(filter.FnID == "" || c.FnID == filter.FnID) &&
 			(time.Time(filter.FromTime).IsZero() || time.Time(filter.FromTime).Before(time.Time(c.CreatedAt))) &&
-			(time.Time(filter.ToTime).IsZero() || time.Time(c.CreatedAt).Before(time.Time(filter.ToTime))) {
+			(time.Time(filter.ToTime).IsZero() || time.Time(c.CreatedAt).Before(time.Time(filter.ToTime))) &&
+			filter.MatchOutcome(c) {
 
 			calls = append(calls, c)
 		}
@@ -372,6 +372,10 @@ func (s *store) GetCalls(ctx context.Context, filter *models.CallFilter) (*model
 			continue
 		}
 
+		if !filter.MatchOutcome(call) {
+			continue
+		}
+
 		calls = append(calls, call)
 	}
 
@@ -138,6 +138,44 @@ func Test(t *testing.T, fnl models.LogStore) {
 		} else if calls.Items[0].ID != c2.ID {
 			t.Fatalf("Test GetCalls: time filter,  call id not expected %s vs %s", calls.Items[0].ID, c2.ID)
 		}
+
+		// make sure status and error_class work
+		c4 := *call
+		c4.ID = id.New().String()
+		c4.CreatedAt = common.DateTime(time.Time(c3.CreatedAt).Add(100 * time.Millisecond))
+		c4.Status = "error"
+		c4.Error = "ya dun goofed"
+		c4.ErrorClass = models.ErrorClassFunction
+		if err := fnl.InsertCall(ctx, &c4); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range []struct {
+			filter models.CallFilter
+			ids    []string
+		}{
+			{models.CallFilter{Status: "error"}, []string{c4.ID}},
+			{models.CallFilter{Status: "success", PerPage: 1}, []string{c3.ID}},
+			{models.CallFilter{ErrorClass: models.ErrorClassFunction}, []string{c4.ID}},
+			{models.CallFilter{Status: "error", ErrorClass: models.ErrorClassTimeout}, nil},
+		} {
+			filter := f.filter
+			filter.FnID = call.FnID
+			if filter.PerPage == 0 {
+				filter.PerPage = 100
+			}
+			calls, err = fnl.GetCalls(ctx, &filter)
+			if err != nil {
+				t.Fatalf("Test GetCalls(ctx, filter): status filter %+v, unexpected error `%v`", f.filter, err)
+			}
+			if len(calls.Items) != len(f.ids) {
+				t.Fatalf("Test GetCalls(ctx, filter): status filter %+v, unexpected length %d != `%v`", f.filter, len(f.ids), len(calls.Items))
+			}
+			for i, want := range f.ids {
+				if calls.Items[i].ID != want {
+					t.Fatalf("Test GetCalls: status filter %+v, call id not expected %s vs %s", f.filter, calls.Items[i].ID, want)
+				}
+			}
+		}
 	})
 
 	t.Run("call-log-insert-get", func(t *testing.T) {
@@ -184,6 +222,10 @@ func Test(t *testing.T, fnl models.LogStore) {
 
 	t.Run("call-get", func(t *testing.T) {
 		call.ID = id.New().String()
+		call.ParentCallID = id.New().String()
+		call.ImageDigest = "fnproject/hello@sha256:0f3a8cd1e07b36df8a4c0e6d4f1e0e2b55b7c4e1a6bd7e3a8e1f2f1c7d5e9a01"
+		call.Platform = "linux/arm64"
+		call.RequestID = id.New().String()
 		err := fnl.InsertCall(ctx, call)
 		if err != nil {
 			t.Fatalf("Test GetCall: unexpected error `%v`", err)
@@ -213,5 +255,198 @@ func Test(t *testing.T, fnl models.LogStore) {
 		if call.AppID != newCall.AppID {
 			t.Fatalf("Test GetCall: fn id mismatch `%v` `%v`", call.FnID, newCall.FnID)
 		}
+		if call.ParentCallID != newCall.ParentCallID {
+			t.Fatalf("Test GetCall: parent call id mismatch `%v` `%v`", call.ParentCallID, newCall.ParentCallID)
+		}
+		if call.ImageDigest != newCall.ImageDigest || call.Platform != newCall.Platform {
+			t.Fatalf("Test GetCall: image mismatch `%v %v` `%v %v`", call.ImageDigest, call.Platform, newCall.ImageDigest, newCall.Platform)
+		}
+		if call.RequestID != newCall.RequestID {
+			t.Fatalf("Test GetCall: request id mismatch `%v` `%v`", call.RequestID, newCall.RequestID)
+		}
+	})
+
+	t.Run("complete-call", func(t *testing.T) {
+		cc, ok := fnl.(models.CallCompleter)
+		if !ok {
+			t.Skip("log store does not complete calls")
+		}
+
+		completed := *call
+		completed.ID = id.New().String()
+		err := cc.CompleteCall(ctx, &completed, strings.NewReader("done"))
+		if err == models.ErrCallCompletionUnsupported {
+			t.Skip("log store does not complete calls")
+		}
+		if err != nil {
+			t.Fatalf("Test CompleteCall: unexpected error `%v`", err)
+		}
+		if _, err := fnl.GetCall(ctx, completed.FnID, completed.ID); err != nil {
+			t.Fatalf("Test CompleteCall: unexpected error getting the call `%v`", err)
+		}
+		logEntry, err := fnl.GetLog(ctx, completed.FnID, completed.ID)
+		if err != nil {
+			t.Fatalf("Test CompleteCall: unexpected error getting the log `%v`", err)
+		}
+		var b bytes.Buffer
+		io.Copy(&b, logEntry)
+		if b.String() != "done" {
+			t.Fatalf("Test CompleteCall: log mismatch, expected `done`, got `%v`", b.String())
+		}
+
+		if err := cc.CompleteCall(ctx, &completed, strings.NewReader("again")); err != models.ErrCallAlreadyCompleted {
+			t.Fatalf("Test CompleteCall: expected `%v`, got `%v`", models.ErrCallAlreadyCompleted, err)
+		}
+	})
+
+	t.Run("dead-letters", func(t *testing.T) {
+		dl, ok := fnl.(models.DeadLetterStore)
+		if !ok {
+			t.Skip("log store does not keep dead letters")
+		}
+
+		call.ID = id.New().String()
+		call.Attempt = 3
+		err := dl.InsertDeadLetter(ctx, call)
+		if err == models.ErrDeadLettersUnsupported {
+			t.Skip("log store does not keep dead letters")
+		}
+		if err != nil {
+			t.Fatalf("Test InsertDeadLetter: unexpected error `%v`", err)
+		}
+
+		letters, err := dl.GetDeadLetters(ctx, &models.CallFilter{FnID: call.FnID, PerPage: 100})
+		if err != nil {
+			t.Fatalf("Test GetDeadLetters: unexpected error `%v`", err)
+		}
+		if len(letters.Items) != 1 || letters.Items[0].ID != call.ID {
+			t.Fatalf("Test GetDeadLetters: expected dead letter `%v`, got `%v`", call.ID, letters.Items)
+		}
+		if letters.Items[0].Attempt != call.Attempt {
+			t.Fatalf("Test GetDeadLetters: attempt mismatch `%v` `%v`", call.Attempt, letters.Items[0].Attempt)
+		}
+
+		if err := dl.RemoveDeadLetter(ctx, call.FnID, call.ID); err != nil {
+			t.Fatalf("Test RemoveDeadLetter: unexpected error `%v`", err)
+		}
+		if err := dl.RemoveDeadLetter(ctx, call.FnID, call.ID); err != models.ErrDeadLetterNotFound {
+			t.Fatalf("Test RemoveDeadLetter: expected `%v`, got `%v`", models.ErrDeadLetterNotFound, err)
+		}
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		as, ok := fnl.(models.AuditStore)
+		if !ok {
+			t.Skip("log store does not keep audit records")
+		}
+
+		app := &models.App{ID: testApp.ID, Name: testApp.Name}
+		var ids []string
+		for i, op := range []string{models.ChangeOpCreate, models.ChangeOpUpdate, models.ChangeOpDelete} {
+			r := &models.AuditRecord{
+				ID:        id.New().String(),
+				Kind:      models.ChangeKindApp,
+				Op:        op,
+				ObjectID:  app.ID,
+				AppID:     app.ID,
+				Subject:   "ci",
+				CreatedAt: common.DateTime(time.Now().Add(time.Duration(i) * time.Second)),
+			}
+			if err := r.SetObjects(nil, app); err != nil {
+				t.Fatal(err)
+			}
+			err := as.InsertAuditRecord(ctx, r)
+			if err == models.ErrAuditUnsupported {
+				t.Skip("log store does not keep audit records")
+			}
+			if err != nil {
+				t.Fatalf("Test InsertAuditRecord: unexpected error `%v`", err)
+			}
+			ids = append(ids, r.ID)
+		}
+
+		records, err := as.GetAuditRecords(ctx, &models.AuditFilter{AppID: app.ID, PerPage: 2})
+		if err != nil {
+			t.Fatalf("Test GetAuditRecords: unexpected error `%v`", err)
+		}
+		if len(records.Items) != 2 || records.Items[0].ID != ids[2] || records.Items[1].ID != ids[1] {
+			t.Fatalf("Test GetAuditRecords: expected the 2 newest records, got `%v`", records.Items)
+		}
+		if records.Items[0].Op != models.ChangeOpDelete || records.Items[0].Subject != "ci" || len(records.Items[0].After) == 0 {
+			t.Fatalf("Test GetAuditRecords: record mismatch `%+v`", records.Items[0])
+		}
+
+		records, err = as.GetAuditRecords(ctx, &models.AuditFilter{AppID: app.ID, Cursor: records.NextCursor, PerPage: 2})
+		if err != nil {
+			t.Fatalf("Test GetAuditRecords: unexpected error `%v`", err)
+		}
+		if len(records.Items) != 1 || records.Items[0].ID != ids[0] {
+			t.Fatalf("Test GetAuditRecords: expected the oldest record on the next page, got `%v`", records.Items)
+		}
+
+		records, err = as.GetAuditRecords(ctx, &models.AuditFilter{AppID: app.ID, Subject: "someone else", PerPage: 2})
+		if err != nil {
+			t.Fatalf("Test GetAuditRecords: unexpected error `%v`", err)
+		}
+		if len(records.Items) != 0 {
+			t.Fatalf("Test GetAuditRecords: expected no records of another subject, got `%v`", records.Items)
+		}
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		us, ok := fnl.(models.UsageStore)
+		if !ok {
+			t.Skip("log store does not keep usage records")
+		}
+
+		window := time.Now().Truncate(time.Hour).Add(-2 * time.Hour)
+		for i, fnID := range []string{testFn.ID, testFn.ID, "other"} {
+			r := &models.UsageRecord{
+				ID:          id.New().String(),
+				AppID:       testApp.ID,
+				FnID:        fnID,
+				DurationMs:  100,
+				MemoryMBMs:  12800,
+				CPUMs:       50,
+				EgressBytes: 10,
+				CreatedAt:   common.DateTime(window.Add(time.Duration(i) * time.Hour)),
+			}
+			err := us.InsertUsageRecord(ctx, r)
+			if err == models.ErrUsageUnsupported {
+				t.Skip("log store does not keep usage records")
+			}
+			if err != nil {
+				t.Fatalf("Test InsertUsageRecord: unexpected error `%v`", err)
+			}
+		}
+
+		filter := &models.UsageFilter{AppID: testApp.ID, FromTime: common.DateTime(window), ToTime: common.DateTime(time.Now())}
+		if err := filter.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		usage, err := us.GetUsage(ctx, filter)
+		if err != nil {
+			t.Fatalf("Test GetUsage: unexpected error `%v`", err)
+		}
+		if len(usage.Items) != 3 || !time.Time(usage.Items[0].Start).Equal(window) {
+			t.Fatalf("Test GetUsage: expected the usage of 3 hours, got `%v`", usage.Items)
+		}
+		if u := usage.Items[0]; u.Invocations != 1 || u.MemoryMBMs != 12800 || u.CPUMs != 50 || u.EgressBytes != 10 {
+			t.Fatalf("Test GetUsage: usage mismatch `%+v`", u)
+		}
+
+		filter.Window = 24 * time.Hour
+		filter.FnID = testFn.ID
+		usage, err = us.GetUsage(ctx, filter)
+		if err != nil {
+			t.Fatalf("Test GetUsage: unexpected error `%v`", err)
+		}
+		var invocations uint64
+		for _, u := range usage.Items {
+			invocations += u.Invocations
+		}
+		if invocations != 2 {
+			t.Fatalf("Test GetUsage: expected the 2 calls of the fn, got `%v`", usage.Items)
+		}
 	})
 }
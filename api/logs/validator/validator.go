@@ -58,3 +58,114 @@ func (v *validator) GetCall(ctx context.Context, fnID, callID string) (*models.C
 	}
 	return v.LogStore.GetCall(ctx, fnID, callID)
 }
+
+// fnID will never be empty.
+func (v *validator) SearchLogs(ctx context.Context, filter *models.LogFilter) (*models.LogMatchList, error) {
+	if filter.FnID == "" {
+		return nil, models.ErrMissingFnID
+	}
+	return models.SearchLogs(ctx, v.LogStore, filter)
+}
+
+// callID or fnID will never be empty.
+func (v *validator) CompleteCall(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	if call.ID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if call.FnID == "" {
+		return models.ErrMissingFnID
+	}
+	cc, ok := v.LogStore.(models.CallCompleter)
+	if !ok {
+		return models.ErrCallCompletionUnsupported
+	}
+	return cc.CompleteCall(ctx, call, callLog)
+}
+
+// callID or fnID will never be empty.
+func (v *validator) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	if call.ID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if call.FnID == "" {
+		return models.ErrMissingFnID
+	}
+	dl, ok := v.LogStore.(models.DeadLetterStore)
+	if !ok {
+		return models.ErrDeadLettersUnsupported
+	}
+	return dl.InsertDeadLetter(ctx, call)
+}
+
+// fnID will never be empty.
+func (v *validator) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	if filter.FnID == "" {
+		return nil, models.ErrMissingFnID
+	}
+	dl, ok := v.LogStore.(models.DeadLetterStore)
+	if !ok {
+		return nil, models.ErrDeadLettersUnsupported
+	}
+	return dl.GetDeadLetters(ctx, filter)
+}
+
+// callID or fnID will never be empty.
+func (v *validator) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	if callID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if fnID == "" {
+		return models.ErrMissingFnID
+	}
+	dl, ok := v.LogStore.(models.DeadLetterStore)
+	if !ok {
+		return models.ErrDeadLettersUnsupported
+	}
+	return dl.RemoveDeadLetter(ctx, fnID, callID)
+}
+
+// id, kind and object id will never be empty.
+func (v *validator) InsertAuditRecord(ctx context.Context, record *models.AuditRecord) error {
+	if record.ID == "" || record.Kind == "" || record.ObjectID == "" {
+		return models.ErrInvalidAuditRecord
+	}
+	as, ok := v.LogStore.(models.AuditStore)
+	if !ok {
+		return models.ErrAuditUnsupported
+	}
+	return as.InsertAuditRecord(ctx, record)
+}
+
+func (v *validator) GetAuditRecords(ctx context.Context, filter *models.AuditFilter) (*models.AuditRecordList, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	as, ok := v.LogStore.(models.AuditStore)
+	if !ok {
+		return nil, models.ErrAuditUnsupported
+	}
+	return as.GetAuditRecords(ctx, filter)
+}
+
+// id, app id and fn id will never be empty.
+func (v *validator) InsertUsageRecord(ctx context.Context, record *models.UsageRecord) error {
+	if record.ID == "" || record.AppID == "" || record.FnID == "" {
+		return models.ErrInvalidUsageRecord
+	}
+	us, ok := v.LogStore.(models.UsageStore)
+	if !ok {
+		return models.ErrUsageUnsupported
+	}
+	return us.InsertUsageRecord(ctx, record)
+}
+
+func (v *validator) GetUsage(ctx context.Context, filter *models.UsageFilter) (*models.UsageList, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	us, ok := v.LogStore.(models.UsageStore)
+	if !ok {
+		return nil, models.ErrUsageUnsupported
+	}
+	return us.GetUsage(ctx, filter)
+}
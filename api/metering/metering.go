@@ -0,0 +1,212 @@
+// Package metering records what the calls of a server used to a Sink, one
+// usage record per call, so that platform teams can charge the usage of apps
+// back. Records of the log store sink are aggregated per app by the usage
+// endpoint of the API, see models.UsageStore.
+package metering
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs/kafka"
+	"github.com/fnproject/fn/fnext"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+const (
+	defaultKafkaTopic = "fn-usage"
+
+	// meterQueueSize is the number of records waiting to be written to a
+	// slow sink past which records are dropped
+	meterQueueSize = 10000
+	writeTimeout   = 10 * time.Second
+)
+
+var droppedMeasure = common.MakeMeasure("metering_dropped", "usage records dropped as their sink is behind or failing", "")
+
+// RegisterViews registers the views of the records dropped by meters
+func RegisterViews(tagKeys []string, dist []float64) {
+	err := view.Register(common.CreateView(droppedMeasure, view.Count(), tagKeys))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create view")
+	}
+}
+
+// Sink receives the usage records of the calls of a server
+type Sink interface {
+	Write(ctx context.Context, record *models.UsageRecord) error
+}
+
+// New returns the sink of a url, one of:
+//
+//	logstore                               the usage table of the log store, see models.UsageStore
+//	file:///var/log/fn/usage.log           a file records are appended to as JSON lines
+//	kafka://broker1:9092,broker2:9092/fn-usage
+//	                                       a kafka topic records are produced to as JSON, fn-usage if the path is empty
+//
+// Only the usage of the log store sink can be queried through the API.
+func New(ctx context.Context, sinkURL string, ls models.LogStore) (Sink, error) {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, fmt.Errorf("metering: bad sink url: %v", err)
+	}
+	switch {
+	case sinkURL == "logstore":
+		us, ok := ls.(models.UsageStore)
+		if !ok {
+			return nil, models.ErrUsageUnsupported
+		}
+		return NewStoreSink(us), nil
+	case u.Scheme == "file":
+		return NewFileSink(u.Path)
+	case u.Scheme == "kafka":
+		topic := strings.Trim(u.Path, "/")
+		if topic == "" {
+			topic = defaultKafkaTopic
+		}
+		return NewKafkaSink(ctx, strings.Split(u.Host, ","), topic)
+	}
+	return nil, fmt.Errorf("metering: no sink available for url %q", sinkURL)
+}
+
+// NewStoreSink returns a sink inserting records into us
+func NewStoreSink(us models.UsageStore) Sink {
+	return &storeSink{us}
+}
+
+type storeSink struct {
+	us models.UsageStore
+}
+
+func (s *storeSink) Write(ctx context.Context, record *models.UsageRecord) error {
+	return s.us.InsertUsageRecord(ctx, record)
+}
+
+// NewFileSink returns a sink appending records to the file at path as JSON
+// lines, creating it if needed.
+func NewFileSink(path string) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{f: f}, nil
+}
+
+type fileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func (s *fileSink) Write(ctx context.Context, record *models.UsageRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(b)
+	return err
+}
+
+// NewKafkaSink returns a sink producing records as JSON to topic on the
+// brokers seeds, keyed by the id of their app so that the records of an app
+// stay in order.
+func NewKafkaSink(ctx context.Context, seeds []string, topic string) (Sink, error) {
+	p, err := kafka.NewProducer(ctx, seeds, topic)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaSink{p}, nil
+}
+
+type kafkaSink struct {
+	p *kafka.Producer
+}
+
+func (s *kafkaSink) Write(ctx context.Context, record *models.UsageRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.p.Produce(ctx, []byte(record.AppID), b)
+}
+
+// Meter is a call listener writing the usage record of each call completed to
+// a sink. Records are written in the background so that a slow sink does not
+// hold calls up, they are dropped once too many wait.
+type Meter struct {
+	sink Sink
+
+	mu      sync.RWMutex
+	closed  bool
+	records chan *models.UsageRecord
+	done    chan struct{}
+}
+
+var _ fnext.CallListener = new(Meter)
+
+// NewMeter returns a meter writing to sink until it is closed
+func NewMeter(sink Sink) *Meter {
+	m := &Meter{
+		sink:    sink,
+		records: make(chan *models.UsageRecord, meterQueueSize),
+		done:    make(chan struct{}),
+	}
+	go m.write()
+	return m
+}
+
+// BeforeCall implements fnext.CallListener
+func (m *Meter) BeforeCall(ctx context.Context, call *models.Call) error {
+	return nil
+}
+
+// AfterCall implements fnext.CallListener, queueing the usage record of call
+func (m *Meter) AfterCall(ctx context.Context, call *models.Call) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil
+	}
+	select {
+	case m.records <- models.NewUsageRecord(call):
+	default:
+		stats.Record(ctx, droppedMeasure.M(1))
+		common.Logger(ctx).WithField("call_id", call.ID).Warn("dropping usage record, the usage sink is behind")
+	}
+	return nil
+}
+
+// Close writes the records queued and stops the meter, records of calls
+// completing after it is closed are lost
+func (m *Meter) Close() {
+	m.mu.Lock()
+	m.closed = true
+	close(m.records)
+	m.mu.Unlock()
+	<-m.done
+}
+
+func (m *Meter) write() {
+	defer close(m.done)
+	for r := range m.records {
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		if err := m.sink.Write(ctx, r); err != nil {
+			stats.Record(ctx, droppedMeasure.M(1))
+			logrus.WithError(err).WithFields(logrus.Fields{"call_id": r.ID, "app_id": r.AppID}).Error("cannot write usage record")
+		}
+		cancel()
+	}
+}
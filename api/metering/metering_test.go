@@ -0,0 +1,84 @@
+package metering
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestMeter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metering")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "usage.log")
+
+	sink, err := New(context.Background(), "file://"+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMeter(sink)
+
+	start := time.Now().Add(-2 * time.Second)
+	call := &models.Call{
+		ID:          "call",
+		AppID:       "app",
+		FnID:        "fn",
+		Memory:      128,
+		CPUs:        500,
+		StartedAt:   common.DateTime(start),
+		CompletedAt: common.DateTime(start.Add(2 * time.Second)),
+		Stats: drivers.Stats{
+			{Metrics: map[string]uint64{"net_tx": 1000}},
+			{Metrics: map[string]uint64{"mem_usage": 1}},
+			{Metrics: map[string]uint64{"net_tx": 5096}},
+		},
+	}
+	m.AfterCall(context.Background(), call)
+	m.Close()
+	// calls completing once the meter is closed are not metered
+	m.AfterCall(context.Background(), call)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []models.UsageRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r models.UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected the record of the call, got %+v", records)
+	}
+	r := records[0]
+	if r.ID != "call" || r.AppID != "app" || r.FnID != "fn" || r.DurationMs != 2000 {
+		t.Fatalf("Unexpected record %+v", r)
+	}
+	if r.MemoryMBMs != 128*2000 || r.CPUMs != 1000 || r.EgressBytes != 4096 {
+		t.Fatalf("Unexpected usage %+v", r)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"", "logstore", "s3://bucket", "kafka:///fn-usage"} {
+		if _, err := New(context.Background(), u, nil); err == nil {
+			t.Errorf("Expected sink url %q to be rejected", u)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package metrics exports the views of fn to metrics backends besides the
+// prometheus endpoint of the server: StatsD and DogStatsD agents, OCI
+// Monitoring and stdout. The exporters are OpenCensus view exporters, so they
+// run alongside the prometheus and otlp exporters, see New for how they are
+// configured.
+//
+// Views are cumulative, exporters send counts as counters of what was counted
+// since their last export and everything else as gauges. Distributions are
+// sent as the count and the sum of their values, suffixed _count and _sum.
+package metrics
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats/view"
+)
+
+const (
+	// TypeCounter is the type of samples of the values counted since the
+	// previous export
+	TypeCounter = "counter"
+	// TypeGauge is the type of samples of the current value of a view
+	TypeGauge = "gauge"
+)
+
+// tagAliases renames the tags fn records the app and the fn of a metric with,
+// which depend on where it is recorded, to the tags they are exported with
+var tagAliases = map[string]string{
+	"app_id":     "app",
+	"fn_app_id":  "app",
+	"fn_appname": "app_name",
+	"fn_id":      "fn",
+	"fn_fn_id":   "fn",
+}
+
+// New returns the exporter of a url, one of:
+//
+//	stdout://                                  samples are written to stdout as JSON lines
+//	statsd://localhost:8125?prefix=fn          a StatsD agent, tags are appended to names
+//	dogstatsd://localhost:8125?prefix=fn       a DogStatsD agent, tags are sent as tags
+//	oci://telemetry-ingestion.us-phoenix-1.oraclecloud.com?compartment=ocid1.compartment...&namespace=fn
+//	                                           OCI Monitoring, see OCIOptions
+//
+// oci+http:// urls post to OCI Monitoring over http, e.g. through a local
+// proxy signing requests. tags are added to every metric exported, e.g. the
+// lb group of the node or its region.
+func New(exporterURL string, tags map[string]string) (view.Exporter, error) {
+	u, err := url.Parse(exporterURL)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: bad exporter url: %v", err)
+	}
+	q := u.Query()
+	switch u.Scheme {
+	case "stdout":
+		return NewStdoutExporter(os.Stdout, tags), nil
+	case "statsd", "dogstatsd":
+		return NewStatsDExporter(StatsDOptions{
+			Addr:      u.Host,
+			Prefix:    q.Get("prefix"),
+			DogStatsD: u.Scheme == "dogstatsd",
+			Tags:      tags,
+		})
+	case "oci", "oci+http":
+		scheme := "https"
+		if u.Scheme == "oci+http" {
+			scheme = "http"
+		}
+		return NewOCIExporter(OCIOptions{
+			Endpoint:      scheme + "://" + u.Host,
+			CompartmentID: q.Get("compartment"),
+			Namespace:     q.Get("namespace"),
+			ResourceGroup: q.Get("resource_group"),
+			Tags:          tags,
+		})
+	}
+	return nil, fmt.Errorf("metrics: no exporter available for url %q", exporterURL)
+}
+
+// ParseTags parses a comma separated list of key=value tags, e.g.
+// lb_group=blue,region=phx
+func ParseTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		i := strings.Index(kv, "=")
+		if i <= 0 || i == len(kv)-1 {
+			return nil, fmt.Errorf("metrics: bad tag %q, tags are key=value", kv)
+		}
+		tags[kv[:i]] = kv[i+1:]
+	}
+	return tags, nil
+}
+
+// Sample is a value of a row of a view, with its tags enriched
+type Sample struct {
+	Name  string            `json:"name"`
+	Type  string            `json:"type"`
+	Value float64           `json:"value"`
+	Tags  map[string]string `json:"tags,omitempty"`
+	Time  time.Time         `json:"time"`
+}
+
+// flattener turns the rows of views into samples, it remembers the values
+// counters were last exported with to send what was counted since
+type flattener struct {
+	tags map[string]string
+
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+func newFlattener(tags map[string]string) *flattener {
+	return &flattener{tags: tags, last: make(map[string]float64)}
+}
+
+// samples returns the samples of the rows of vd, counters which did not count
+// anything since the last export are left out
+func (f *flattener) samples(vd *view.Data) []Sample {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := vd.View.Name
+	var samples []Sample
+	for _, row := range vd.Rows {
+		tags := make(map[string]string, len(row.Tags)+len(f.tags))
+		for k, v := range f.tags {
+			tags[k] = v
+		}
+		for _, t := range row.Tags {
+			k := t.Key.Name()
+			if alias, ok := tagAliases[k]; ok {
+				k = alias
+			}
+			tags[k] = t.Value
+		}
+
+		gauge := func(name string, v float64) {
+			samples = append(samples, Sample{Name: name, Type: TypeGauge, Value: v, Tags: tags, Time: vd.End})
+		}
+		counter := func(name string, v float64) {
+			key := name + "{" + tagString(tags, "=", ",") + "}"
+			delta := v - f.last[key]
+			if delta < 0 { // the view was registered again
+				delta = v
+			}
+			f.last[key] = v
+			if delta != 0 {
+				samples = append(samples, Sample{Name: name, Type: TypeCounter, Value: delta, Tags: tags, Time: vd.End})
+			}
+		}
+
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			counter(name, float64(data.Value))
+		case *view.SumData:
+			// fn records gauges like the calls queued with sums
+			gauge(name, data.Value)
+		case *view.LastValueData:
+			gauge(name, data.Value)
+		case *view.DistributionData:
+			counter(name+"_count", float64(data.Count))
+			counter(name+"_sum", data.Mean*float64(data.Count))
+		}
+	}
+	return samples
+}
+
+// tagString returns the tags as k=v pairs in the order of their keys
+func tagString(tags map[string]string, eq, sep string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + eq + tags[k]
+	}
+	return strings.Join(pairs, sep)
+}
@@ -0,0 +1,234 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func viewData(count int64, queued float64) []*view.Data {
+	appKey, _ := tag.NewKey("fn_app_id")
+	fnKey, _ := tag.NewKey("fn_id")
+	tags := []tag.Tag{{Key: appKey, Value: "app1"}, {Key: fnKey, Value: "fn1"}}
+	end := time.Unix(1500000000, 0)
+
+	calls := stats.Int64("metrics_test_calls", "calls", stats.UnitDimensionless)
+	queue := stats.Int64("metrics_test_queued", "queued", stats.UnitDimensionless)
+	latency := stats.Int64("metrics_test_latency", "latency", stats.UnitMilliseconds)
+	return []*view.Data{
+		{
+			View: &view.View{Name: calls.Name(), Measure: calls, Aggregation: view.Count()},
+			End:  end,
+			Rows: []*view.Row{{Tags: tags, Data: &view.CountData{Value: count}}},
+		},
+		{
+			View: &view.View{Name: queue.Name(), Measure: queue, Aggregation: view.Sum()},
+			End:  end,
+			Rows: []*view.Row{{Tags: tags, Data: &view.SumData{Value: queued}}},
+		},
+		{
+			View: &view.View{Name: latency.Name(), Measure: latency, Aggregation: view.Distribution(10, 100)},
+			End:  end,
+			Rows: []*view.Row{{Tags: tags, Data: &view.DistributionData{Count: count, Mean: 20, CountPerBucket: []int64{0, count, 0}}}},
+		},
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewStdoutExporter(&buf, map[string]string{"lb_group": "blue"})
+	for _, vd := range viewData(3, 2) {
+		e.ExportView(vd)
+	}
+
+	var samples []Sample
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var s Sample
+		if err := dec.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, s)
+	}
+	if len(samples) != 4 {
+		t.Fatalf("Expected a sample of the count, the sum and two of the distribution, got %+v", samples)
+	}
+	calls := samples[0]
+	if calls.Name != "metrics_test_calls" || calls.Type != TypeCounter || calls.Value != 3 {
+		t.Fatalf("Unexpected sample %+v", calls)
+	}
+	if calls.Tags["app"] != "app1" || calls.Tags["fn"] != "fn1" || calls.Tags["lb_group"] != "blue" || len(calls.Tags) != 3 {
+		t.Fatalf("Expected the app, fn and static tags, got %v", calls.Tags)
+	}
+	if queued := samples[1]; queued.Type != TypeGauge || queued.Value != 2 {
+		t.Fatalf("Expected sums to be gauges, got %+v", queued)
+	}
+	if sum := samples[3]; sum.Name != "metrics_test_latency_sum" || sum.Value != 60 {
+		t.Fatalf("Unexpected sample %+v", sum)
+	}
+
+	// counters send what was counted since the last export, only
+	buf.Reset()
+	for _, vd := range viewData(5, 2) {
+		e.ExportView(vd)
+	}
+	var s Sample
+	json.NewDecoder(&buf).Decode(&s)
+	if s.Name != "metrics_test_calls" || s.Value != 2 {
+		t.Fatalf("Expected a count of 2 since the last export, got %+v", s)
+	}
+	buf.Reset()
+	e.ExportView(viewData(5, 2)[0])
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no sample of counters without counts, got %s", buf.String())
+	}
+}
+
+func TestStatsDExporter(t *testing.T) {
+	for _, test := range []struct {
+		dog   bool
+		lines []string
+	}{
+		{false, []string{
+			"fn.metrics_test_calls.app1.fn1.blue:3|c",
+			"fn.metrics_test_latency_count.app1.fn1.blue:3|c",
+			"fn.metrics_test_latency_sum.app1.fn1.blue:60|c",
+			"fn.metrics_test_queued.app1.fn1.blue:2.5|g",
+		}},
+		{true, []string{
+			"fn.metrics_test_calls:3|c|#app:app1,fn:fn1,lb_group:blue",
+			"fn.metrics_test_latency_count:3|c|#app:app1,fn:fn1,lb_group:blue",
+			"fn.metrics_test_latency_sum:60|c|#app:app1,fn:fn1,lb_group:blue",
+			"fn.metrics_test_queued:2.5|g|#app:app1,fn:fn1,lb_group:blue",
+		}},
+	} {
+		agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer agent.Close()
+
+		e, err := NewStatsDExporter(StatsDOptions{
+			Addr:      agent.LocalAddr().String(),
+			DogStatsD: test.dog,
+			Tags:      map[string]string{"lb_group": "blue"},
+			OnError:   func(err error) { t.Error(err) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer e.Close()
+		for _, vd := range viewData(3, 2.5) {
+			e.ExportView(vd)
+		}
+
+		var lines []string
+		buf := make([]byte, maxStatsDPacket)
+		agent.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for len(lines) < len(test.lines) {
+			n, _, err := agent.ReadFrom(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+		}
+		sort.Strings(lines)
+		if strings.Join(lines, "\n") != strings.Join(test.lines, "\n") {
+			t.Errorf("Unexpected lines with dogstatsd %v:\n%s", test.dog, strings.Join(lines, "\n"))
+		}
+	}
+}
+
+func TestOCIExporter(t *testing.T) {
+	var posts []postMetricDataDetails
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ociMetricsPath {
+			http.NotFound(w, r)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		var body postMetricDataDetails
+		if err := json.Unmarshal(b, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		posts = append(posts, body)
+	}))
+	defer srv.Close()
+
+	if _, err := NewOCIExporter(OCIOptions{Endpoint: srv.URL, CompartmentID: "ocid1.compartment", Namespace: "oci_fn"}); err == nil {
+		t.Fatal("Expected oci namespaces to be reserved")
+	}
+
+	e, err := NewOCIExporter(OCIOptions{
+		Endpoint:      srv.URL,
+		CompartmentID: "ocid1.compartment",
+		Interval:      time.Hour,
+		Tags:          map[string]string{"lb_group": "blue"},
+		OnError:       func(err error) { t.Error(err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, vd := range viewData(3, 2) {
+		e.ExportView(vd)
+	}
+	e.ExportView(viewData(5, 1)[1])
+	e.Stop()
+
+	if len(posts) != 1 || len(posts[0].MetricData) != 4 {
+		t.Fatalf("Expected a post of 4 metrics, got %+v", posts)
+	}
+	queued := posts[0].MetricData[3]
+	if queued.Name != "metrics_test_queued" || queued.Namespace != "fn" || queued.CompartmentID != "ocid1.compartment" {
+		t.Fatalf("Unexpected metric %+v", queued)
+	}
+	if len(queued.Datapoints) != 2 || queued.Datapoints[0].Value != 2 || queued.Datapoints[1].Value != 1 {
+		t.Fatalf("Expected both exports of the view as datapoints, got %+v", queued.Datapoints)
+	}
+	if d := queued.Dimensions; d["service"] != "fn" || d["app"] != "app1" || d["fn"] != "fn1" || d["lb_group"] != "blue" {
+		t.Fatalf("Unexpected dimensions %v", d)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"", "prometheus://localhost", "oci://telemetry-ingestion.us-phoenix-1.oraclecloud.com"} {
+		if _, err := New(u, nil); err == nil {
+			t.Errorf("Expected exporter url %q to be rejected", u)
+		}
+	}
+	e, err := New("dogstatsd://127.0.0.1:8125?prefix=fnlb", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := e.(*StatsDExporter); !s.opts.DogStatsD || s.opts.Prefix != "fnlb" {
+		t.Fatalf("Unexpected options %+v", s.opts)
+	}
+	e, err = New("oci+http://localhost:8080?compartment=ocid1.compartment&namespace=fn_lb", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := e.(*OCIExporter); o.opts.Endpoint != "http://localhost:8080" || o.opts.Namespace != "fn_lb" {
+		t.Fatalf("Unexpected options %+v", o.opts)
+	}
+	e.(*OCIExporter).Stop()
+
+	if _, err := ParseTags("lb_group"); err == nil {
+		t.Fatal("Expected tags without values to be rejected")
+	}
+	tags, err := ParseTags(" lb_group=blue, region=phx,")
+	if err != nil || len(tags) != 2 || tags["lb_group"] != "blue" || tags["region"] != "phx" {
+		t.Fatalf("Unexpected tags %v %v", tags, err)
+	}
+}
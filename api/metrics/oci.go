@@ -0,0 +1,250 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats/view"
+)
+
+const (
+	ociMetricsPath = "/20180401/metrics"
+
+	defaultOCINamespace = "fn"
+	defaultOCIInterval  = time.Minute
+	// PostMetricData takes up to 50 metrics per request
+	maxOCIMetrics = 50
+	// metrics take up to 20 dimensions
+	maxOCIDimensions = 20
+	// samples recorded between two posts past this are dropped
+	maxOCISamples = 10000
+)
+
+var (
+	ociNamespace = regexp.MustCompile(`^[a-z][a-z0-9_]*[a-z0-9]$`)
+	ociReplacer  = regexp.MustCompile(`[^a-zA-Z0-9_\-$]`)
+)
+
+// OCIOptions configures an OCIExporter
+type OCIOptions struct {
+	// Endpoint is the base url of the telemetry ingestion endpoint of a
+	// region, e.g. https://telemetry-ingestion.us-phoenix-1.oraclecloud.com
+	Endpoint string
+	// CompartmentID is the ocid of the compartment metrics are posted to
+	CompartmentID string
+	// Namespace is the namespace of the metrics, fn if empty. It cannot start
+	// with oci_, which is reserved for OCI services.
+	Namespace string
+	// ResourceGroup groups the metrics in the namespace, optional
+	ResourceGroup string
+	// Interval is how often metrics are posted, a minute if 0
+	Interval time.Duration
+	// Tags are added to every metric as dimensions
+	Tags map[string]string
+	// Client posts the metrics, http.DefaultClient if nil. OCI only takes
+	// signed requests, set a client signing them with the credentials of the
+	// node, e.g. with the transport of the OCI go sdk, or use an Endpoint
+	// proxying requests which signs them.
+	Client *http.Client
+	// OnError is called with the errors of posts
+	OnError func(error)
+}
+
+// OCIExporter posts the samples of views to OCI Monitoring in batches, see
+// OCIOptions. Every metric has the dimension service=fn besides its tags as
+// OCI requires at least one.
+type OCIExporter struct {
+	opts OCIOptions
+	f    *flattener
+
+	mu      sync.Mutex
+	samples []Sample
+	dropped int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ view.Exporter = new(OCIExporter)
+
+// NewOCIExporter returns an exporter posting to the endpoint of o, it runs
+// until Stop is called
+func NewOCIExporter(o OCIOptions) (*OCIExporter, error) {
+	if !strings.HasPrefix(o.Endpoint, "http://") && !strings.HasPrefix(o.Endpoint, "https://") {
+		return nil, fmt.Errorf("metrics: oci endpoint %q is not an http url", o.Endpoint)
+	}
+	o.Endpoint = strings.TrimSuffix(o.Endpoint, "/")
+	if o.CompartmentID == "" {
+		return nil, fmt.Errorf("metrics: missing oci compartment")
+	}
+	if o.Namespace == "" {
+		o.Namespace = defaultOCINamespace
+	}
+	if !ociNamespace.MatchString(o.Namespace) || strings.HasPrefix(o.Namespace, "oci_") {
+		return nil, fmt.Errorf("metrics: bad oci namespace %q", o.Namespace)
+	}
+	if o.Interval <= 0 {
+		o.Interval = defaultOCIInterval
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	if o.OnError == nil {
+		o.OnError = func(error) {}
+	}
+
+	e := &OCIExporter{
+		opts: o,
+		f:    newFlattener(o.Tags),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go e.loop()
+	return e, nil
+}
+
+// ExportView implements view.Exporter, samples are posted with the next batch
+func (e *OCIExporter) ExportView(vd *view.Data) {
+	samples := e.f.samples(vd)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if n := maxOCISamples - len(e.samples); n < len(samples) {
+		e.dropped += len(samples) - n
+		samples = samples[:n]
+	}
+	e.samples = append(e.samples, samples...)
+}
+
+// Stop posts what is left and stops the exporter
+func (e *OCIExporter) Stop() {
+	close(e.stop)
+	<-e.done
+}
+
+func (e *OCIExporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.Flush()
+		case <-e.stop:
+			e.Flush()
+			return
+		}
+	}
+}
+
+// Flush posts the samples exported since the last batch
+func (e *OCIExporter) Flush() {
+	e.mu.Lock()
+	samples, dropped := e.samples, e.dropped
+	e.samples, e.dropped = nil, 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		e.opts.OnError(fmt.Errorf("metrics: dropped %d samples, oci exports are behind", dropped))
+	}
+	data := e.metricData(samples)
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxOCIMetrics {
+			n = maxOCIMetrics
+		}
+		if err := e.post(&postMetricDataDetails{MetricData: data[:n], BatchAtomicity: "NON_ATOMIC"}); err != nil {
+			e.opts.OnError(err)
+		}
+		data = data[n:]
+	}
+}
+
+type postMetricDataDetails struct {
+	MetricData     []metricDataDetails `json:"metricData"`
+	BatchAtomicity string              `json:"batchAtomicity"`
+}
+
+type metricDataDetails struct {
+	Namespace     string            `json:"namespace"`
+	CompartmentID string            `json:"compartmentId"`
+	ResourceGroup string            `json:"resourceGroup,omitempty"`
+	Name          string            `json:"name"`
+	Dimensions    map[string]string `json:"dimensions"`
+	Datapoints    []datapoint       `json:"datapoints"`
+}
+
+type datapoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// metricData groups samples of the same name and dimensions into metrics
+func (e *OCIExporter) metricData(samples []Sample) []metricDataDetails {
+	byKey := make(map[string]*metricDataDetails)
+	var keys []string
+	for _, s := range samples {
+		dims := map[string]string{"service": "fn"}
+		for k, v := range s.Tags {
+			if v != "" && len(dims) < maxOCIDimensions {
+				dims[ociReplacer.ReplaceAllString(k, "_")] = v
+			}
+		}
+		name := ociReplacer.ReplaceAllString(s.Name, "_")
+		key := name + "{" + tagString(dims, "=", ",") + "}"
+
+		md, ok := byKey[key]
+		if !ok {
+			md = &metricDataDetails{
+				Namespace:     e.opts.Namespace,
+				CompartmentID: e.opts.CompartmentID,
+				ResourceGroup: e.opts.ResourceGroup,
+				Name:          name,
+				Dimensions:    dims,
+			}
+			byKey[key] = md
+			keys = append(keys, key)
+		}
+		md.Datapoints = append(md.Datapoints, datapoint{Timestamp: s.Time.UTC(), Value: s.Value})
+	}
+
+	sort.Strings(keys)
+	data := make([]metricDataDetails, len(keys))
+	for i, k := range keys {
+		data[i] = *byKey[k]
+	}
+	return data
+}
+
+func (e *OCIExporter) post(body *postMetricDataDetails) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.opts.Endpoint+ociMetricsPath, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("metrics: oci post failed with status %d: %s", resp.StatusCode, msg)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/stats/view"
+)
+
+const (
+	defaultStatsDAddr   = "localhost:8125"
+	defaultStatsDPrefix = "fn"
+	// lines are sent in packets of up to this many bytes, which fit the mtu
+	// of most networks
+	maxStatsDPacket = 1432
+)
+
+// statsdReplacer replaces what StatsD reads as separators in names and tags
+var statsdReplacer = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+
+// StatsDOptions configures a StatsDExporter
+type StatsDOptions struct {
+	// Addr is the host:port of the agent, localhost:8125 if empty
+	Addr string
+	// Prefix is prepended to the names of metrics, fn if empty
+	Prefix string
+	// DogStatsD sends tags with the DogStatsD extension, plain StatsD has no
+	// tags and their values are appended to names in the order of their keys
+	DogStatsD bool
+	// Tags are added to every metric
+	Tags map[string]string
+	// OnError is called with the errors of sends
+	OnError func(error)
+}
+
+// StatsDExporter sends the samples of views to a StatsD or DogStatsD agent
+// over udp as soon as they are exported
+type StatsDExporter struct {
+	opts StatsDOptions
+	f    *flattener
+	conn net.Conn
+}
+
+var _ view.Exporter = new(StatsDExporter)
+
+// NewStatsDExporter returns an exporter sending to the agent of o
+func NewStatsDExporter(o StatsDOptions) (*StatsDExporter, error) {
+	if o.Addr == "" {
+		o.Addr = defaultStatsDAddr
+	}
+	if o.Prefix == "" {
+		o.Prefix = defaultStatsDPrefix
+	}
+	if o.OnError == nil {
+		o.OnError = func(error) {}
+	}
+	conn, err := net.Dial("udp", o.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: cannot reach statsd agent %s: %v", o.Addr, err)
+	}
+	return &StatsDExporter{opts: o, f: newFlattener(o.Tags), conn: conn}, nil
+}
+
+// ExportView implements view.Exporter
+func (e *StatsDExporter) ExportView(vd *view.Data) {
+	var packet bytes.Buffer
+	send := func() {
+		if packet.Len() == 0 {
+			return
+		}
+		if _, err := e.conn.Write(packet.Bytes()); err != nil {
+			e.opts.OnError(err)
+		}
+		packet.Reset()
+	}
+
+	for _, s := range e.f.samples(vd) {
+		line := e.line(s)
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxStatsDPacket {
+			send()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	send()
+}
+
+// Close closes the connection to the agent
+func (e *StatsDExporter) Close() error {
+	return e.conn.Close()
+}
+
+func (e *StatsDExporter) line(s Sample) string {
+	name := e.opts.Prefix + "." + s.Name
+	if !e.opts.DogStatsD {
+		keys := make([]string, 0, len(s.Tags))
+		for k := range s.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name += "." + strings.Replace(s.Tags[k], ".", "_", -1)
+		}
+	}
+
+	typ := "g"
+	if s.Type == TypeCounter {
+		typ = "c"
+	}
+	line := statsdReplacer.ReplaceAllString(name, "_") + ":" + strconv.FormatFloat(s.Value, 'f', -1, 64) + "|" + typ
+
+	if e.opts.DogStatsD && len(s.Tags) > 0 {
+		tags := make(map[string]string, len(s.Tags))
+		for k, v := range s.Tags {
+			tags[statsdReplacer.ReplaceAllString(k, "_")] = statsdReplacer.ReplaceAllString(v, "_")
+		}
+		line += "|#" + tagString(tags, ":", ",")
+	}
+	return line
+}
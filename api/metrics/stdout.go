@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"go.opencensus.io/stats/view"
+)
+
+// StdoutExporter writes the samples of views to a writer as JSON lines,
+// mostly to pipe them to a log collector or to look at them while developing
+type StdoutExporter struct {
+	f *flattener
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+var _ view.Exporter = new(StdoutExporter)
+
+// NewStdoutExporter returns an exporter writing samples with tags added to w
+func NewStdoutExporter(w io.Writer, tags map[string]string) *StdoutExporter {
+	return &StdoutExporter{f: newFlattener(tags), enc: json.NewEncoder(w)}
+}
+
+// ExportView implements view.Exporter
+func (e *StdoutExporter) ExportView(vd *view.Data) {
+	samples := e.f.samples(vd)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range samples {
+		e.enc.Encode(s)
+	}
+}
@@ -201,6 +201,20 @@ func (m Annotations) MergeChange(newVs Annotations) Annotations {
 	return newMd
 }
 
+// ChangeTo returns the delta which, merged into m by MergeChange, gives other:
+// the keys of other with their values, and deletes of the keys only in m
+func (m Annotations) ChangeTo(other Annotations) Annotations {
+	change := make(Annotations, len(m)+len(other))
+	for k := range m {
+		empty := annotationValue("null")
+		change[k] = &empty
+	}
+	for k, v := range other {
+		change[k] = v
+	}
+	return change
+}
+
 // clone produces a key-wise copy of the underlying annotations
 // publically MD can be copied by reference as it's (by contract) immutable
 func (m Annotations) clone() Annotations {
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FnAntiAffinityAnnotation spreads the calls of a fn over runners, to keep
+// noisy neighbours apart. Calls are placed on runners running fewer than
+// max_per_runner calls of the fn and of the fns listed in fns, by id, and on
+// any runner when none does. Its value is:
+//
+//	{"max_per_runner": 2, "fns": ["01D8JQHBR6NG8G00GZJ0000002"]}
+//
+// Only the calls of the annotated fn are kept apart, to keep two fns apart
+// both need the annotation.
+const FnAntiAffinityAnnotation = "fnproject.io/fn/antiAffinity"
+
+var ErrFnsInvalidAntiAffinity = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid anti-affinity annotation %s, max_per_runner must be a positive integer and fns a list of fn ids", FnAntiAffinityAnnotation),
+}
+
+// AntiAffinity limits the calls of a fn and of Fns in flight on a runner
+type AntiAffinity struct {
+	MaxPerRunner int      `json:"max_per_runner"`
+	Fns          []string `json:"fns,omitempty"`
+}
+
+// AntiAffinityFromAnnotations returns the anti-affinity of a fn, nil if not set
+func AntiAffinityFromAnnotations(annotations Annotations) (*AntiAffinity, error) {
+	v, ok := annotations.Get(FnAntiAffinityAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var aa AntiAffinity
+	if err := json.Unmarshal(v, &aa); err != nil || aa.MaxPerRunner < 1 {
+		return nil, ErrFnsInvalidAntiAffinity
+	}
+	for _, id := range aa.Fns {
+		if id == "" {
+			return nil, ErrFnsInvalidAntiAffinity
+		}
+	}
+	return &aa, nil
+}
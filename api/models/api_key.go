@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// apiKeyTokenPrefix starts the tokens of API keys, which are
+// fnk_<key id>_<secret>
+const apiKeyTokenPrefix = "fnk_"
+
+var (
+	ErrAPIKeysNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("API key not found"),
+	}
+	ErrAPIKeysUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("API keys are not supported by the datastore"),
+	}
+	ErrAPIKeysMissingName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing API key name"),
+	}
+	ErrAPIKeysInvalidScopes = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid API key scopes, must be some of write, read or invoke"),
+	}
+	ErrAPIKeysInvalidExpiry = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("API key expiry must be in the future"),
+	}
+	ErrAPIKeysInvalidGrace = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid grace period, must be a duration such as 10m"),
+	}
+	ErrAPIKeyIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("API key ID cannot be supplied on create"),
+	}
+	ErrAPIKeysRevoked = err{
+		code:  http.StatusConflict,
+		error: errors.New("API key is revoked or expired"),
+	}
+)
+
+// APIKey grants the scopes of its token to API clients such as CI systems,
+// restricted to some apps if any are set. Keys are never removed, revoked keys
+// are kept as the revocation list.
+type APIKey struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// Scopes are some of write, read and invoke, see auth.Scope
+	Scopes []string `json:"scopes"`
+	// AppIDs, if set, restrict the requests made with the key to these apps
+	AppIDs      []string         `json:"app_ids,omitempty"`
+	NamespaceID string           `json:"namespace_id,omitempty" db:"namespace_id"`
+	ExpiresAt   *common.DateTime `json:"expires_at,omitempty"`
+	RevokedAt   *common.DateTime `json:"revoked_at,omitempty"`
+	CreatedAt   common.DateTime  `json:"created_at,omitempty" db:"created_at"`
+
+	// Token is only returned when a key is created or rotated
+	Token string `json:"token,omitempty"`
+	// TokenHash is the hex encoded sha256 hash of the secret of the token
+	TokenHash string `json:"-"`
+}
+
+func (k *APIKey) Validate() error {
+	if k.Name == "" {
+		return ErrAPIKeysMissingName
+	}
+	if len(k.Scopes) == 0 {
+		return ErrAPIKeysInvalidScopes
+	}
+	for _, s := range k.Scopes {
+		switch s {
+		case "write", "read", "invoke":
+		default:
+			return ErrAPIKeysInvalidScopes
+		}
+	}
+	return nil
+}
+
+// Active returns whether the key was neither revoked nor expired at now
+func (k *APIKey) Active(now time.Time) bool {
+	if k.ExpiresAt != nil && !now.Before(time.Time(*k.ExpiresAt)) {
+		return false
+	}
+	if k.RevokedAt != nil && !now.Before(time.Time(*k.RevokedAt)) {
+		return false
+	}
+	return true
+}
+
+// Matches returns whether secret is the secret of the token of the key, in
+// constant time
+func (k *APIKey) Matches(secret string) bool {
+	sum := sha256.Sum256([]byte(secret))
+	hash := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(k.TokenHash)) == 1
+}
+
+// NewAPIKeySecret returns a random token secret and its hash, see
+// APIKey.TokenHash
+func NewAPIKeySecret() (secret, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b)
+	sum := sha256.Sum256([]byte(secret))
+	return secret, hex.EncodeToString(sum[:]), nil
+}
+
+// APIKeyToken returns the token of the key keyID with secret
+func APIKeyToken(keyID, secret string) string {
+	return apiKeyTokenPrefix + keyID + "_" + secret
+}
+
+// ParseAPIKeyToken returns the key id and secret of token, or false if it is
+// not the token of an API key
+func ParseAPIKeyToken(token string) (keyID, secret string, ok bool) {
+	if !strings.HasPrefix(token, apiKeyTokenPrefix) {
+		return "", "", false
+	}
+	i := strings.Index(token[len(apiKeyTokenPrefix):], "_")
+	if i <= 0 {
+		return "", "", false
+	}
+	keyID = token[len(apiKeyTokenPrefix) : len(apiKeyTokenPrefix)+i]
+	secret = token[len(apiKeyTokenPrefix)+i+1:]
+	return keyID, secret, secret != ""
+}
+
+type APIKeyFilter struct {
+	Name        string //match
+	NamespaceID string //match
+	Cursor      string
+	PerPage     int
+}
+
+type APIKeyList struct {
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Items      []*APIKey `json:"items"`
+}
+
+// APIKeyStore may be implemented by a Datastore to store API keys, which are
+// removed with their namespace.
+type APIKeyStore interface {
+	// InsertAPIKey inserts a key with its token hash, the token is not stored.
+	InsertAPIKey(ctx context.Context, key *APIKey) (*APIKey, error)
+
+	// GetAPIKeyByID returns a key with its token hash, or ErrAPIKeysNotFound.
+	GetAPIKeyByID(ctx context.Context, keyID string) (*APIKey, error)
+
+	// GetAPIKeys returns the keys matching filter in the order they were made,
+	// revoked keys included.
+	GetAPIKeys(ctx context.Context, filter *APIKeyFilter) (*APIKeyList, error)
+
+	// RevokeAPIKey revokes a key from at on, unless it was revoked earlier, and
+	// returns it or ErrAPIKeysNotFound.
+	RevokeAPIKey(ctx context.Context, keyID string, at common.DateTime) (*APIKey, error)
+}
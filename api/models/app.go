@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -54,11 +55,57 @@ var (
 		code:  http.StatusNotFound,
 		error: errors.New("App not found"),
 	}
+	ErrAppsInvalidPriorityClass = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid priority class annotation %s, must be a string", AppPriorityClassAnnotation),
+	}
+	ErrAppsInvalidLBGroup = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid lb group annotation %s, must be a string", AppLBGroupAnnotation),
+	}
+	ErrAppsInvalidPausedTTL = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid paused ttl annotation %s, must be an integer between 0 and %d", AppPausedTTLAnnotation, MaxIdleTimeout),
+	}
 )
 
+const (
+	// AppLogDriverAnnotation selects the docker log driver for the containers of an app,
+	// one of syslog, fluentd, journald, json-file or none. If not set, syslog is used
+	// when syslog_url is set.
+	AppLogDriverAnnotation = "fnproject.io/app/logDriver"
+
+	// AppLogOptionsAnnotation is a JSON object of string driver options passed to the
+	// log driver, e.g. {"fluentd-async": "true", "tag": "{{.Name}}"}
+	AppLogOptionsAnnotation = "fnproject.io/app/logOptions"
+
+	// AppPausedTTLAnnotation is the number of seconds a paused hot container of an app
+	// may stay resident before it is shut down, overriding the agent wide setting.
+	// Zero keeps paused containers until their idle timeout.
+	AppPausedTTLAnnotation = "fnproject.io/app/pausedTTL"
+
+	// AppPriorityClassAnnotation is the priority class calls of an app are admitted
+	// with when the agent is at capacity, one of the classes configured on the agent.
+	AppPriorityClassAnnotation = "fnproject.io/app/priorityClass"
+
+	// AppLBGroupAnnotation is the lb group calls of an app are placed in, only
+	// the runners selected by the group run them.
+	AppLBGroupAnnotation = "fnproject.io/app/lbGroup"
+)
+
+// supported docker log drivers, see https://docs.docker.com/config/containers/logging/configure/
+var logDrivers = map[string]bool{
+	"none":      true,
+	"syslog":    true,
+	"fluentd":   true,
+	"journald":  true,
+	"json-file": true,
+}
+
 type App struct {
 	ID          string          `json:"id" db:"id"`
 	Name        string          `json:"name" db:"name"`
+	NamespaceID string          `json:"namespace_id,omitempty" db:"namespace_id"`
 	Config      Config          `json:"config,omitempty" db:"config"`
 	Annotations Annotations     `json:"annotations,omitempty" db:"annotations"`
 	SyslogURL   *string         `json:"syslog_url,omitempty" db:"syslog_url"`
@@ -84,6 +131,54 @@ func (a *App) Validate() error {
 		return err
 	}
 
+	if _, _, err := LogDriverFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, _, err := PausedTTLFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := PriorityClassFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := LBGroupFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := DNSFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := ProxyFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := UserFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := CapabilitiesFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := SidecarFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := VolumesFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := AppRateLimitFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if err := ValidateConfigRefs(a.Config); err != nil {
+		return err
+	}
+
 	if a.SyslogURL != nil && *a.SyslogURL != "" {
 		url, err := url.Parse(strings.TrimSpace(*a.SyslogURL))
 		if err == nil {
@@ -123,6 +218,7 @@ func (a1 *App) Equals(a2 *App) bool {
 	eq := true
 	eq = eq && a1.ID == a2.ID
 	eq = eq && a1.Name == a2.Name
+	eq = eq && a1.NamespaceID == a2.NamespaceID
 	eq = eq && a1.Config.Equals(a2.Config)
 	eq = eq && a1.SyslogURL == a2.SyslogURL
 	eq = eq && a1.Annotations.Equals(a2.Annotations)
@@ -140,6 +236,7 @@ func (a1 *App) EqualsWithAnnotationSubset(a2 *App) bool {
 	eq := true
 	eq = eq && a1.ID == a2.ID
 	eq = eq && a1.Name == a2.Name
+	eq = eq && a1.NamespaceID == a2.NamespaceID
 	eq = eq && a1.Config.Equals(a2.Config)
 	eq = eq && a1.SyslogURL == a2.SyslogURL
 	eq = eq && a1.Annotations.Subset(a2.Annotations)
@@ -189,14 +286,85 @@ type ErrInvalidSyslog string
 func (e ErrInvalidSyslog) Code() int     { return http.StatusBadRequest }
 func (e ErrInvalidSyslog) Error() string { return string(e) }
 
+var _ APIError = ErrInvalidLogDriver("")
+
+type ErrInvalidLogDriver string
+
+func (e ErrInvalidLogDriver) Code() int     { return http.StatusBadRequest }
+func (e ErrInvalidLogDriver) Error() string { return string(e) }
+
+// LogDriverFromAnnotations returns the log driver and its options selected by
+// annotations, driver is empty if not set.
+func LogDriverFromAnnotations(annotations Annotations) (string, map[string]string, error) {
+	var driver string
+	var opts map[string]string
+
+	if _, ok := annotations.Get(AppLogDriverAnnotation); ok {
+		d, err := annotations.GetString(AppLogDriverAnnotation)
+		if err != nil || !logDrivers[d] {
+			return "", nil, ErrInvalidLogDriver(fmt.Sprintf(`invalid log driver annotation %s, only [none, syslog, fluentd, journald, json-file] are supported`, AppLogDriverAnnotation))
+		}
+		driver = d
+	}
+
+	if raw, ok := annotations.Get(AppLogOptionsAnnotation); ok {
+		if err := json.Unmarshal(raw, &opts); err != nil {
+			return "", nil, ErrInvalidLogDriver(fmt.Sprintf(`invalid log options annotation %s, must be an object of strings`, AppLogOptionsAnnotation))
+		}
+	}
+
+	return driver, opts, nil
+}
+
 // AppFilter is the filter used for querying apps
 type AppFilter struct {
-	Name    string
-	PerPage int
-	Cursor  string
+	Name        string
+	NamespaceID string // match, empty matches apps of any namespace
+	PerPage     int
+	Cursor      string
 }
 
 type AppList struct {
 	NextCursor string `json:"next_cursor,omitempty"`
 	Items      []*App `json:"items"`
 }
+
+// PausedTTLFromAnnotations returns the paused container ttl selected by annotations,
+// ok is false if not set.
+func PausedTTLFromAnnotations(annotations Annotations) (ttl time.Duration, ok bool, err error) {
+	v, ok := annotations.Get(AppPausedTTLAnnotation)
+	if !ok {
+		return 0, false, nil
+	}
+	var secs int32
+	if err := json.Unmarshal(v, &secs); err != nil || secs < 0 || secs > MaxIdleTimeout {
+		return 0, false, ErrAppsInvalidPausedTTL
+	}
+	return time.Duration(secs) * time.Second, true, nil
+}
+
+// PriorityClassFromAnnotations returns the priority class selected by annotations,
+// empty if not set.
+func PriorityClassFromAnnotations(annotations Annotations) (string, error) {
+	if _, ok := annotations.Get(AppPriorityClassAnnotation); !ok {
+		return "", nil
+	}
+	class, err := annotations.GetString(AppPriorityClassAnnotation)
+	if err != nil {
+		return "", ErrAppsInvalidPriorityClass
+	}
+	return class, nil
+}
+
+// LBGroupFromAnnotations returns the lb group selected by annotations, empty
+// if not set.
+func LBGroupFromAnnotations(annotations Annotations) (string, error) {
+	if _, ok := annotations.Get(AppLBGroupAnnotation); !ok {
+		return "", nil
+	}
+	group, err := annotations.GetString(AppLBGroupAnnotation)
+	if err != nil {
+		return "", ErrAppsInvalidLBGroup
+	}
+	return group, nil
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+var (
+	ErrBundleInvalid = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid bundle, must be a YAML document describing an app"),
+	}
+	ErrBundleDuplicateFn = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid bundle, fn names must be unique"),
+	}
+	ErrBundleDuplicateTrigger = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid bundle, trigger names must be unique within their fn"),
+	}
+)
+
+// ErrBundleMissingSecrets is returned applying a bundle which references
+// secrets the app does not have, secret values are never part of bundles.
+func ErrBundleMissingSecrets(names []string) APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("Bundle references secrets missing from the app: %v", names))
+}
+
+// AppBundle describes an app with its fns and triggers, as exported by one
+// server to be applied to an app of another. It holds no ids or timestamps,
+// objects are matched by name.
+type AppBundle struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Config      Config                 `yaml:"config,omitempty" json:"config,omitempty"`
+	Annotations map[string]interface{} `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	SyslogURL   string                 `yaml:"syslog_url,omitempty" json:"syslog_url,omitempty"`
+	// Secrets are the secrets the app must have, by name.
+	Secrets []BundleSecret `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	Fns     []BundleFn     `yaml:"fns,omitempty" json:"fns,omitempty"`
+}
+
+// BundleSecret references a secret of the app of a bundle, without its value
+type BundleSecret struct {
+	Name  string `yaml:"name" json:"name"`
+	Mount string `yaml:"mount,omitempty" json:"mount,omitempty"`
+}
+
+type BundleFn struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Image       string                 `yaml:"image" json:"image"`
+	Memory      uint64                 `yaml:"memory,omitempty" json:"memory,omitempty"`
+	Timeout     int32                  `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	IdleTimeout int32                  `yaml:"idle_timeout,omitempty" json:"idle_timeout,omitempty"`
+	InitTimeout int32                  `yaml:"init_timeout,omitempty" json:"init_timeout,omitempty"`
+	Config      Config                 `yaml:"config,omitempty" json:"config,omitempty"`
+	Annotations map[string]interface{} `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	Triggers    []BundleTrigger        `yaml:"triggers,omitempty" json:"triggers,omitempty"`
+}
+
+type BundleTrigger struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Type        string                 `yaml:"type" json:"type"`
+	Source      string                 `yaml:"source" json:"source"`
+	Annotations map[string]interface{} `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
+
+// Validate checks the names of the fns and triggers of b are unique, the
+// objects themselves are validated as they are applied.
+func (b *AppBundle) Validate() error {
+	fns := make(map[string]bool, len(b.Fns))
+	for _, fn := range b.Fns {
+		if fns[fn.Name] {
+			return ErrBundleDuplicateFn
+		}
+		fns[fn.Name] = true
+
+		triggers := make(map[string]bool, len(fn.Triggers))
+		for _, t := range fn.Triggers {
+			if triggers[t.Name] {
+				return ErrBundleDuplicateTrigger
+			}
+			triggers[t.Name] = true
+		}
+	}
+	return nil
+}
+
+// BundleAnnotations returns annotations as plain values, to be written to a
+// bundle.
+func BundleAnnotations(annotations Annotations) map[string]interface{} {
+	if len(annotations) == 0 {
+		return nil
+	}
+	res := make(map[string]interface{}, len(annotations))
+	for k, v := range annotations {
+		var value interface{}
+		if json.Unmarshal(*v, &value) == nil {
+			res[k] = value
+		}
+	}
+	return res
+}
+
+// AnnotationsFromBundle returns the annotations of the plain values read from
+// a bundle, which may hold maps with non-string keys as decoded from YAML.
+func AnnotationsFromBundle(values map[string]interface{}) (Annotations, error) {
+	res := EmptyAnnotations()
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var err error
+		res, err = res.With(k, jsonValue(values[k]))
+		if err != nil {
+			return nil, NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid bundle annotation %s: %v", k, err))
+		}
+	}
+	return res, nil
+}
+
+// jsonValue converts the maps of v with interface{} keys to maps with string
+// keys, which encoding/json can marshal
+func jsonValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, v := range x {
+			m[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(x))
+		for i, v := range x {
+			l[i] = jsonValue(v)
+		}
+		return l
+	}
+	return v
+}
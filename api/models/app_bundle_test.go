@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestAppBundleAnnotations(t *testing.T) {
+	var bundle AppBundle
+	err := yaml.Unmarshal([]byte(`
+name: a
+annotations:
+  team: {name: core, oncall: [alice, bob]}
+  tier: 1
+`), &bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	annotations, err := AnnotationsFromBundle(bundle.Annotations)
+	if err != nil {
+		t.Fatalf("Expected annotations to be read from the bundle, got %v", err)
+	}
+	team, ok := annotations.Get("team")
+	if !ok || string(team) != `{"name":"core","oncall":["alice","bob"]}` {
+		t.Fatalf("Unexpected annotation %s", team)
+	}
+
+	back, err := AnnotationsFromBundle(BundleAnnotations(annotations))
+	if err != nil || !back.Equals(annotations) {
+		t.Fatalf("Expected annotations to round trip, got %v %v", back, err)
+	}
+}
+
+func TestAppBundleValidate(t *testing.T) {
+	for i, tc := range []struct {
+		bundle AppBundle
+		err    error
+	}{
+		{AppBundle{Fns: []BundleFn{{Name: "f"}, {Name: "g"}}}, nil},
+		{AppBundle{Fns: []BundleFn{{Name: "f"}, {Name: "f"}}}, ErrBundleDuplicateFn},
+		{AppBundle{Fns: []BundleFn{{Name: "f", Triggers: []BundleTrigger{{Name: "t"}}}, {Name: "g", Triggers: []BundleTrigger{{Name: "t"}}}}}, nil},
+		{AppBundle{Fns: []BundleFn{{Name: "f", Triggers: []BundleTrigger{{Name: "t"}, {Name: "t"}}}}}, ErrBundleDuplicateTrigger},
+	} {
+		if err := tc.bundle.Validate(); err != tc.err {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.err, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -42,6 +43,7 @@ func appFieldGenerators(t *testing.T) map[string]gopter.Gen {
 	fieldGens := make(map[string]gopter.Gen)
 	fieldGens["ID"] = gen.AlphaString()
 	fieldGens["Name"] = gen.AlphaString()
+	fieldGens["NamespaceID"] = gen.AlphaString()
 	fieldGens["Config"] = configGenerator()
 	fieldGens["Annotations"] = annotationGenerator()
 	fieldGens["SyslogURL"] = gen.AlphaString().Map(func(s string) *string {
@@ -147,3 +149,122 @@ func TestAppEquality(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+func TestAppLogDriverAnnotations(t *testing.T) {
+	a, _ := EmptyAnnotations().With(AppLogDriverAnnotation, "fluentd")
+	a, _ = a.With(AppLogOptionsAnnotation, map[string]string{"fluentd-async": "true"})
+
+	driver, opts, err := LogDriverFromAnnotations(a)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if driver != "fluentd" || opts["fluentd-async"] != "true" {
+		t.Fatalf("unexpected driver %s options %v", driver, opts)
+	}
+
+	bad, _ := EmptyAnnotations().With(AppLogDriverAnnotation, "gelf")
+	app := &App{Name: "app", Annotations: bad}
+	if _, ok := app.Validate().(ErrInvalidLogDriver); !ok {
+		t.Fatalf("expected invalid log driver error")
+	}
+
+	bad, _ = EmptyAnnotations().With(AppLogOptionsAnnotation, []string{"tag"})
+	app = &App{Name: "app", Annotations: bad}
+	if _, ok := app.Validate().(ErrInvalidLogDriver); !ok {
+		t.Fatalf("expected invalid log options error")
+	}
+}
+
+func TestAppDNS(t *testing.T) {
+	for _, bad := range []string{`"10.0.0.2"`, `{"servers": ["dns.corp"]}`, `{"search": [""]}`, `{"extra_hosts": ["db.corp"]}`, `{"extra_hosts": ["db.corp:host"]}`} {
+		annotations, _ := Annotations{}.With(AppDNSAnnotation, json.RawMessage(bad))
+		if _, err := DNSFromAnnotations(annotations); err != ErrAppsInvalidDNS {
+			t.Errorf("Expected dns %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	want := DNSConfig{Servers: []string{"10.0.0.2", "::1"}, Search: []string{"corp.example.com"}, ExtraHosts: []string{"db:10.0.0.5", "v6:::1"}}
+	annotations, _ := Annotations{}.With(AppDNSAnnotation, want)
+	dns, err := DNSFromAnnotations(annotations)
+	if err != nil || !reflect.DeepEqual(*dns, want) {
+		t.Fatalf("Unexpected dns %v %v", dns, err)
+	}
+}
+
+func TestAppProxy(t *testing.T) {
+	for _, bad := range []string{`"http://proxy"`, `{"http_proxy": "proxy:3128"}`, `{"https_proxy": "/proxy"}`} {
+		annotations, _ := Annotations{}.With(AppProxyAnnotation, json.RawMessage(bad))
+		if _, err := ProxyFromAnnotations(annotations); err != ErrAppsInvalidProxy {
+			t.Errorf("Expected proxy %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(AppProxyAnnotation, json.RawMessage(`{}`))
+	if proxy, err := ProxyFromAnnotations(annotations); err != nil || *proxy != (ProxyConfig{}) {
+		t.Fatalf("Expected no proxies, got %v %v", proxy, err)
+	}
+}
+
+func TestAppSidecar(t *testing.T) {
+	for _, bad := range []string{`"redis"`, `{"sidecar": {"cmd": "redis-server"}}`, `{"init": {"image": ""}}`} {
+		annotations, _ := Annotations{}.With(AppSidecarAnnotation, json.RawMessage(bad))
+		if _, err := SidecarFromAnnotations(annotations); err != ErrAppsInvalidSidecar {
+			t.Errorf("Expected sidecar %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(AppSidecarAnnotation, json.RawMessage(`{"sidecar": {"image": "redis", "memory": 64}}`))
+	s, err := SidecarFromAnnotations(annotations)
+	if err != nil || s.Init != nil || s.Sidecar.Image != "redis" || s.Sidecar.Memory != 64 {
+		t.Fatalf("Unexpected sidecar %+v %v", s, err)
+	}
+}
+
+func TestAppVolumes(t *testing.T) {
+	for _, bad := range []string{`{"name": "a"}`, `[{"name": "a b", "path": "/a"}]`, `[{"name": "a", "path": "a"}]`, `[{"name": "a", "path": "/tmp/"}]`,
+		`[{"name": "a", "path": "/a"}, {"name": "a", "path": "/b"}]`, `[{"name": "a", "path": "/a"}, {"name": "b", "path": "/a/"}]`} {
+		annotations, _ := Annotations{}.With(AppVolumesAnnotation, json.RawMessage(bad))
+		if _, err := VolumesFromAnnotations(annotations); err != ErrAppsInvalidVolumes {
+			t.Errorf("Expected volumes %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(AppVolumesAnnotation, []Volume{{Name: "cache", Path: "/cache", Size: 512, TTL: 60}})
+	if volumes, err := VolumesFromAnnotations(annotations); err != nil || len(volumes) != 1 || volumes[0].Size != 512 {
+		t.Fatalf("Unexpected volumes %v %v", volumes, err)
+	}
+}
+
+func TestAppUser(t *testing.T) {
+	for _, bad := range []string{`1000`, `"1000:"`, `"a b"`} {
+		annotations, _ := Annotations{}.With(AppUserAnnotation, json.RawMessage(bad))
+		if _, err := UserFromAnnotations(annotations); err != ErrAppsInvalidUser {
+			t.Errorf("Expected user %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(AppUserAnnotation, "1000:1000")
+	if user, err := UserFromAnnotations(annotations); err != nil || user != "1000:1000" {
+		t.Fatalf("Unexpected user %v %v", user, err)
+	}
+
+	for user, root := range map[string]bool{"": true, "root": true, "0": true, "0:1000": true, "1000:0": false, "nobody": false, "10": false} {
+		if IsRootUser(user) != root {
+			t.Errorf("Expected user %q root to be %v", user, root)
+		}
+	}
+}
+
+func TestAppCapabilities(t *testing.T) {
+	for _, bad := range []string{`["NET_RAW"]`, `{"add": ["net_raw"]}`, `{"add": ["CAP_NET_RAW"]}`, `{"drop": ["ALL"]}`} {
+		annotations, _ := Annotations{}.With(AppCapabilitiesAnnotation, json.RawMessage(bad))
+		if _, err := CapabilitiesFromAnnotations(annotations); err != ErrAppsInvalidCapabilities {
+			t.Errorf("Expected capabilities %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(AppCapabilitiesAnnotation, Capabilities{Add: []string{"NET_RAW"}})
+	if caps, err := CapabilitiesFromAnnotations(annotations); err != nil || len(caps.Add) != 1 || caps.Add[0] != "NET_RAW" {
+		t.Fatalf("Unexpected capabilities %v %v", caps, err)
+	}
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+)
+
+// FnAssetsAnnotation mounts read-only assets distributed to runners ahead of
+// time, e.g. ML models or geo databases, into the containers of a fn, by
+// asset name. Its value is:
+//
+//	{"model": "/models/model.bin", "geoip": "/data/geoip.mmdb"}
+//
+// Calls fail with ErrAssetNotReady on runners the asset is not distributed to yet.
+const FnAssetsAnnotation = "fnproject.io/fn/assets"
+
+// asset states, see AssetStatus
+const (
+	AssetPending = "pending"
+	AssetReady   = "ready"
+	AssetFailed  = "failed"
+)
+
+var (
+	ErrFnsInvalidAssets = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid assets annotation %s, must map asset names to absolute paths", FnAssetsAnnotation),
+	}
+	ErrInvalidAsset = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid asset, name must be letters, digits, _, - and ., url an http(s) url and sha256 a hex sha256 checksum"),
+	}
+	ErrAssetNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Asset not found"),
+	}
+	ErrAssetNotReady = err{
+		code:  http.StatusServiceUnavailable,
+		error: errors.New("Asset is not distributed to this runner yet, retry later"),
+	}
+	ErrAssetsUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Assets are not enabled on this server"),
+	}
+)
+
+var (
+	assetNameRegex   = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+	assetSHA256Regex = regexp.MustCompile(`^[a-f0-9]{64}$`)
+)
+
+// Asset is a read-only file distributed to runners from url, verified
+// against its sha256 checksum
+type Asset struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// Validate checks the name, url and checksum of an asset
+func (a *Asset) Validate() error {
+	u, err := url.Parse(a.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidAsset
+	}
+	if !assetNameRegex.MatchString(a.Name) || !assetSHA256Regex.MatchString(a.SHA256) {
+		return ErrInvalidAsset
+	}
+	return nil
+}
+
+// AssetStatus is the state of an asset on a runner, one of AssetPending,
+// AssetReady or AssetFailed with Error
+type AssetStatus struct {
+	Asset
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// AssetsFromAnnotations returns the container paths of the assets of a fn by
+// asset name, none if not set
+func AssetsFromAnnotations(annotations Annotations) (map[string]string, error) {
+	v, ok := annotations.Get(FnAssetsAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var assets map[string]string
+	if err := json.Unmarshal(v, &assets); err != nil {
+		return nil, ErrFnsInvalidAssets
+	}
+	for name, p := range assets {
+		if !assetNameRegex.MatchString(name) || !path.IsAbs(p) || path.Clean(p) == "/" {
+			return nil, ErrFnsInvalidAssets
+		}
+	}
+	return assets, nil
+}
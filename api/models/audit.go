@@ -0,0 +1,157 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// AuditKindSecret is the kind of the audit records of secrets, the other
+// records are of the ChangeKind of their object
+const AuditKindSecret = "secret"
+
+var (
+	ErrAuditUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Audit records are not supported by the log store"),
+	}
+	ErrInvalidAuditRecord = err{
+		code:  http.StatusInternalServerError,
+		error: errors.New("Audit records need an id, a kind and an object id"),
+	}
+	ErrAuditInvalidKind = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid audit kind, must be one of app, fn, trigger or secret"),
+	}
+)
+
+// AuditRecord is a create, update or delete of an app, fn, trigger or secret
+// made through the API, recorded for compliance. Secrets are recorded without
+// their values.
+type AuditRecord struct {
+	ID string `json:"id"`
+	// Kind of the object changed, one of the ChangeKind constants or
+	// AuditKindSecret
+	Kind string `json:"kind"`
+	// Op is the operation, one of the ChangeOp constants
+	Op string `json:"op"`
+	// ObjectID is the id of the object changed, the name of secrets
+	ObjectID string `json:"object_id"`
+	// AppID is the app of the object changed, the id of the app itself for apps
+	AppID string `json:"app_id"`
+	// Subject is who made the change, as authenticated, if known
+	Subject string `json:"subject,omitempty"`
+	// Before is the object before the change, unset for creates
+	Before json.RawMessage `json:"before,omitempty"`
+	// After is the object after the change, unset for deletes
+	After json.RawMessage `json:"after,omitempty"`
+	// Changed lists the fields of the object which differ between Before and
+	// After
+	Changed   []string        `json:"changed,omitempty"`
+	CreatedAt common.DateTime `json:"created_at"`
+}
+
+// SetObjects sets the Before and After objects of the record, either may be
+// nil or a nil pointer, and the fields which changed between them.
+func (r *AuditRecord) SetObjects(before, after interface{}) error {
+	var err error
+	if r.Before, err = marshalAuditObject(before); err != nil {
+		return err
+	}
+	if r.After, err = marshalAuditObject(after); err != nil {
+		return err
+	}
+	r.Changed, err = changedFields(r.Before, r.After)
+	return err
+}
+
+func marshalAuditObject(obj interface{}) (json.RawMessage, error) {
+	b, err := json.Marshal(obj)
+	if err != nil || string(b) == "null" {
+		return nil, err
+	}
+	return b, nil
+}
+
+// changedFields returns the sorted names of the top level fields of the JSON
+// objects before and after which differ, either may be empty.
+func changedFields(before, after json.RawMessage) ([]string, error) {
+	var b, a map[string]json.RawMessage
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &b); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &a); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed []string
+	for k, v := range b {
+		if w, ok := a[k]; !ok || !bytes.Equal(v, w) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// AuditFilter selects audit records, newest first
+type AuditFilter struct {
+	Kind     string //match
+	ObjectID string //match
+	AppID    string //match
+	Subject  string //match
+	FromTime common.DateTime
+	ToTime   common.DateTime
+	Cursor   string
+	PerPage  int
+}
+
+// Validate checks the kind of the filter is a known one
+func (f *AuditFilter) Validate() error {
+	switch f.Kind {
+	case "", ChangeKindApp, ChangeKindFn, ChangeKindTrigger, AuditKindSecret:
+		return nil
+	}
+	return ErrAuditInvalidKind
+}
+
+// Match returns whether r is selected by the filter, ignoring its cursor and
+// page size
+func (f *AuditFilter) Match(r *AuditRecord) bool {
+	return (f.Kind == "" || r.Kind == f.Kind) &&
+		(f.ObjectID == "" || r.ObjectID == f.ObjectID) &&
+		(f.AppID == "" || r.AppID == f.AppID) &&
+		(f.Subject == "" || r.Subject == f.Subject) &&
+		(time.Time(f.FromTime).IsZero() || time.Time(f.FromTime).Before(time.Time(r.CreatedAt))) &&
+		(time.Time(f.ToTime).IsZero() || time.Time(r.CreatedAt).Before(time.Time(f.ToTime)))
+}
+
+type AuditRecordList struct {
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Items      []*AuditRecord `json:"items"`
+}
+
+// AuditStore may be implemented by a LogStore to keep audit records, which
+// are not removed with the objects they are about.
+type AuditStore interface {
+	// InsertAuditRecord stores an audit record
+	InsertAuditRecord(ctx context.Context, record *AuditRecord) error
+
+	// GetAuditRecords returns the audit records matching filter, newest first
+	GetAuditRecords(ctx context.Context, filter *AuditFilter) (*AuditRecordList, error)
+}
@@ -18,7 +18,51 @@ const (
 	TypeDetached = "detached"
 )
 
-var possibleStatuses = [...]string{"delayed", "queued", "running", "success", "error", "cancelled"}
+// MaxCallDelay is the longest an async call may be delayed, in seconds
+const MaxCallDelay = 7 * 24 * 60 * 60
+
+// IdempotencyKeyHeader is the request header clients set to make invocations
+// of a fn idempotent
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// NextFnHeader is the response header by which a fn asks for the fn it names,
+// of the same app, to be invoked asynchronously with the response as its
+// payload, chaining calls into pipelines. Chained calls get the id of the call
+// which chained them in ParentCallHeader, and their chains are cut at
+// MaxChainDepth calls, so that fns chaining themselves do not run forever.
+const NextFnHeader = "Fn-Next-Fn-Id"
+
+// ParentCallHeader is the request header of chained calls holding the id of
+// the call which chained them
+const ParentCallHeader = "Fn-Parent-Call-Id"
+
+// ChainHeader is the request header of chained calls holding the id of their
+// chain, see Call.ChainID
+const ChainHeader = "Fn-Chain-Id"
+
+// MaxChainDepth is the most calls a chain may hold, the next fn of its last
+// call is not invoked
+var MaxChainDepth int32 = 16
+
+// DeadlineHeader is the request header clients set to the RFC3339 time after
+// which they no longer want the response of a sync invocation. The deadline
+// bounds placement and execution of the call, containers get the deadline
+// left for the call in the same header.
+const DeadlineHeader = "Fn-Deadline"
+
+var possibleStatuses = [...]string{"delayed", "queued", "running", "success", "error", "timeout", "cancelled"}
+
+// Error classes group the errors of failed calls, so calls can be listed by
+// the kind of failure rather than by message, see ErrorClass.
+const (
+	ErrorClassTimeout  = "timeout"
+	ErrorClassFunction = "function"
+	ErrorClassCapacity = "capacity"
+	ErrorClassClient   = "client"
+	ErrorClassServer   = "server"
+)
+
+var possibleErrorClasses = [...]string{ErrorClassTimeout, ErrorClassFunction, ErrorClassCapacity, ErrorClassClient, ErrorClassServer}
 
 // Call is a representation of a specific invocation of a fn.
 type Call struct {
@@ -93,6 +137,9 @@ type Call struct {
 	// Hot function idle timeout in seconds before termination.
 	IdleTimeout int32 `json:"idle_timeout,omitempty" db:"-"`
 
+	// Hot function init timeout in seconds, 0 uses the default of the runner.
+	InitTimeout int32 `json:"init_timeout,omitempty" db:"-"`
+
 	// Tmpfs size in megabytes.
 	TmpFsSize uint32 `json:"tmpfs_size,omitempty" db:"-"`
 
@@ -132,25 +179,102 @@ type Call struct {
 	// status is equal to "error".
 	Error string `json:"error,omitempty" db:"error"`
 
+	// ErrorClass is the class of Error, it is only non-empty if status is
+	// equal to "error" or "timeout".
+	ErrorClass string `json:"error_class,omitempty" db:"error_class"`
+
+	// Timings is how long the call spent in each phase, in milliseconds.
+	Timings CallTimings `json:"timings,omitempty" db:"timings"`
+
 	// App this call belongs to.
 	AppID string `json:"app_id" db:"app_id"`
 
 	// Name of the app.
 	AppName string `json:"app_name" db:"app_name"`
 
+	// Namespace of the app, if any.
+	NamespaceID string `json:"namespace_id,omitempty" db:"namespace_id"`
+
 	// Trigger this call belongs to.
 	TriggerID string `json:"trigger_id" db:"trigger_id"`
 
 	// Fn this call belongs to.
 	FnID string `json:"fn_id" db:"fn_id"`
+
+	// Attempt is the number of times an async call was tried, counting this call.
+	// Failed async calls are retried as new calls according to the retry policy of their fn.
+	Attempt int32 `json:"attempt,omitempty" db:"-"`
+
+	// IdempotencyKey is the Idempotency-Key header of the request that created this call.
+	// Requests with the same key for the same fn are only executed once in a window by
+	// the server which saw the first of them, calls are not looked up by their key.
+	IdempotencyKey string `json:"idempotency_key,omitempty" db:"-"`
+
+	// ParentCallID is the id of the call which chained this call, see NextFnHeader.
+	ParentCallID string `json:"parent_call_id,omitempty" db:"parent_call_id"`
+
+	// ImageDigest is the digest the image of the fn resolved to on the runner
+	// which executed this call, e.g. the digest of a multi-arch manifest.
+	ImageDigest string `json:"image_digest,omitempty" db:"image_digest"`
+
+	// Platform is the os/architecture of the image which executed this call,
+	// e.g. linux/arm64.
+	Platform string `json:"platform,omitempty" db:"platform"`
+
+	// RequestID is the id of the request which created this call, given to
+	// the call by the first lb or API server it went through. It is sent to
+	// the containers of fns in the Fn-Request-Id header and logged with the
+	// logs about the call on each node.
+	RequestID string `json:"request_id,omitempty" db:"request_id"`
+
+	// ChainDepth is the number of calls chained before this call, 0 for calls
+	// which were not chained.
+	ChainDepth int32 `json:"chain_depth,omitempty" db:"-"`
+
+	// ChainID is the id of the first call of the chain of this call, empty for
+	// calls which were not chained, whose chain id is their own id.
+	ChainID string `json:"chain_id,omitempty" db:"-"`
+
+	// LeaseID identifies the reservation of a call reserved from a queue
+	// leasing its calls, see CallLeaser.
+	LeaseID string `json:"lease_id,omitempty" db:"-"`
 }
 
 type CallFilter struct {
-	FnID     string //match
-	FromTime common.DateTime
-	ToTime   common.DateTime
-	Cursor   string
-	PerPage  int
+	FnID       string //match
+	FromTime   common.DateTime
+	ToTime     common.DateTime
+	Status     string //match
+	ErrorClass string //match
+	Cursor     string
+	PerPage    int
+}
+
+// Validate checks the status and error class of the filter are known ones
+func (f *CallFilter) Validate() error {
+	if f.Status != "" && !oneOf(f.Status, possibleStatuses[:]) {
+		return ErrCallInvalidStatus
+	}
+	if f.ErrorClass != "" && !oneOf(f.ErrorClass, possibleErrorClasses[:]) {
+		return ErrCallInvalidErrorClass
+	}
+	return nil
+}
+
+// MatchOutcome returns whether the status and error class of c match the
+// filter, for stores that can't filter on them in their queries.
+func (f *CallFilter) MatchOutcome(c *Call) bool {
+	return (f.Status == "" || c.Status == f.Status) &&
+		(f.ErrorClass == "" || c.ErrorClass == f.ErrorClass)
+}
+
+func oneOf(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
 }
 
 type CallList struct {
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Phases of a call whose durations are kept in its CallTimings
+const (
+	// PhaseQueueWait is the time from the creation of a call until it was
+	// admitted to run, on a queue for async calls or waiting for calls in
+	// flight to finish for sync ones.
+	PhaseQueueWait = "queue_wait"
+	// PhasePlacementWait is the time a call waited for a runner and a hot
+	// container to run on, less the time spent starting a container for it.
+	PhasePlacementWait = "placement_wait"
+	// PhaseImagePull is the time spent pulling the image of the fn, for calls
+	// that are the first one a new container serves.
+	PhaseImagePull = "image_pull"
+	// PhaseContainerCreate is the time spent creating and starting a container
+	// until it was ready for calls, for calls that are the first one it serves.
+	PhaseContainerCreate = "container_create"
+	// PhaseExecution is the time from sending a call to its container until the
+	// container responded.
+	PhaseExecution = "execution"
+	// PhaseResponseWrite is the time spent writing the response of the
+	// container back to the client.
+	PhaseResponseWrite = "response_write"
+)
+
+// CallTimings holds how long a call spent in each phase, in milliseconds by
+// phase. Phases a call did not go through are left out.
+type CallTimings map[string]int64
+
+// Add adds dur to the time spent in phase
+func (t *CallTimings) Add(phase string, dur time.Duration) {
+	if *t == nil {
+		*t = make(CallTimings)
+	}
+	(*t)[phase] += int64(dur / time.Millisecond)
+}
+
+// implements sql.Valuer, returning a string
+func (t CallTimings) Value() (driver.Value, error) {
+	if len(t) < 1 {
+		return driver.Value(string("")), nil
+	}
+	var b bytes.Buffer
+	err := json.NewEncoder(&b).Encode(t)
+	// return a string type
+	return driver.Value(b.String()), err
+}
+
+// implements sql.Scanner
+func (t *CallTimings) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+	bv, err := driver.String.ConvertValue(value)
+	if err != nil {
+		return err
+	}
+	var b []byte
+	switch x := bv.(type) {
+	case []byte:
+		b = x
+	case string:
+		b = []byte(x)
+	}
+	if len(b) > 0 {
+		return json.Unmarshal(b, t)
+	}
+	*t = nil
+	return nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCallTimings(t *testing.T) {
+	var timings CallTimings
+	timings.Add(PhaseQueueWait, 1500*time.Microsecond)
+	timings.Add(PhaseQueueWait, 2*time.Millisecond)
+	timings.Add(PhaseExecution, time.Second)
+	if timings[PhaseQueueWait] != 3 || timings[PhaseExecution] != 1000 {
+		t.Fatalf("expected the time of each phase in milliseconds, got %v", timings)
+	}
+
+	v, err := timings.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scanned CallTimings
+	if err := scanned.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != 2 || scanned[PhaseQueueWait] != 3 || scanned[PhaseExecution] != 1000 {
+		t.Fatalf("expected timings to round trip, got %v", scanned)
+	}
+
+	if v, _ := CallTimings(nil).Value(); v != "" {
+		t.Fatalf("expected no timings to be stored empty, got %q", v)
+	}
+	if err := scanned.Scan(nil); err != nil || scanned != nil {
+		t.Fatalf("expected null timings to scan as none, got %v %v", scanned, err)
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// AppCapabilitiesAnnotation adds Linux capabilities to, or drops them from,
+// the capabilities agents grant the containers of an app. Its value is:
+//
+//	{"add": ["NET_RAW"], "drop": ["CHOWN"]}
+//
+// Capabilities are named without their CAP_ prefix. Agents only let apps add
+// the capabilities their operator allows.
+const AppCapabilitiesAnnotation = "fnproject.io/app/capabilities"
+
+var ErrAppsInvalidCapabilities = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid capabilities annotation %s, add and drop must be lists of capability names without the CAP_ prefix, e.g. NET_RAW", AppCapabilitiesAnnotation),
+}
+
+var capabilityRegex = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// Capabilities are the Linux capabilities added to and dropped from a
+// container
+type Capabilities struct {
+	Add  []string `json:"add,omitempty"`
+	Drop []string `json:"drop,omitempty"`
+}
+
+// CapabilitiesFromAnnotations returns the capabilities an app adds and
+// drops, nil if not set
+func CapabilitiesFromAnnotations(annotations Annotations) (*Capabilities, error) {
+	v, ok := annotations.Get(AppCapabilitiesAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	caps := &Capabilities{}
+	if err := json.Unmarshal(v, caps); err != nil {
+		return nil, ErrAppsInvalidCapabilities
+	}
+	for _, list := range [][]string{caps.Add, caps.Drop} {
+		for _, c := range list {
+			if !capabilityRegex.MatchString(c) || c == "ALL" || strings.HasPrefix(c, "CAP_") {
+				return nil, ErrAppsInvalidCapabilities
+			}
+		}
+	}
+	return caps, nil
+}
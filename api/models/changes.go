@@ -0,0 +1,57 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// kinds of objects reported by a ChangeFeed
+const (
+	ChangeKindApp     = "app"
+	ChangeKindFn      = "fn"
+	ChangeKindTrigger = "trigger"
+)
+
+// operations reported by a ChangeFeed
+const (
+	ChangeOpCreate = "create"
+	ChangeOpUpdate = "update"
+	ChangeOpDelete = "delete"
+)
+
+var ErrChangeFeedUnsupported = err{
+	code:  http.StatusNotImplemented,
+	error: errors.New("Change feeds are not supported by the datastore"),
+}
+
+// Change is a mutation of an app, fn or trigger in the datastore
+type Change struct {
+	// ID of the change, changes of a single server are ordered by id
+	ID string `json:"id" db:"id"`
+	// Kind of the object changed, one of the ChangeKind constants
+	Kind string `json:"kind" db:"kind"`
+	// Op is the operation, one of the ChangeOp constants
+	Op string `json:"op" db:"op"`
+	// ObjectID is the id of the app, fn or trigger changed
+	ObjectID string `json:"object_id" db:"object_id"`
+	// AppID is the app of the object changed, the id of the app itself for apps
+	AppID string `json:"app_id" db:"app_id"`
+	// CreatedAt is the time of the change
+	CreatedAt common.DateTime `json:"created_at" db:"created_at"`
+}
+
+// ChangeFeed may be implemented by a Datastore to let components subscribe to
+// changes of apps, fns and triggers instead of polling them.
+//
+// Changes only identify the objects changed, consumers should treat them as
+// invalidations and read the object again if they need it. Removing an app
+// also removes its fns and triggers, and removing a fn its triggers, which are
+// not reported as changes of their own.
+type ChangeFeed interface {
+	// Changes returns a channel of the changes made after the call, which is
+	// closed once ctx is done. A change may be delivered more than once.
+	Changes(ctx context.Context) (<-chan *Change, error)
+}
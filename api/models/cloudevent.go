@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TriggerCloudEventAnnotation narrows down the events a cloudevent trigger
+// binds and where the events returned by its fn go, its value is:
+//
+//	{"source": "/orders/*", "sink": "https://broker/events"}
+//
+// source is the source of the events bound, a trailing * matches all sources
+// with the prefix, if not set events of all sources are bound. sink overrides
+// the sink of the server events returned by the fn are forwarded to.
+const TriggerCloudEventAnnotation = "fnproject.io/trigger/cloudEvent"
+
+var ErrTriggerInvalidCloudEvent = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid CloudEvent annotation %s, expected {\"source\": \"...\", \"sink\": \"http(s) url\"}", TriggerCloudEventAnnotation),
+}
+
+// CloudEventBinding is which CloudEvents of the type of a cloudevent trigger
+// are bound to its fn
+type CloudEventBinding struct {
+	Source string `json:"source,omitempty"`
+	Sink   string `json:"sink,omitempty"`
+}
+
+// CloudEventBindingFromAnnotations returns the binding of a cloudevent trigger,
+// which matches all sources if not set.
+func CloudEventBindingFromAnnotations(annotations Annotations) (*CloudEventBinding, error) {
+	b := &CloudEventBinding{}
+	v, ok := annotations.Get(TriggerCloudEventAnnotation)
+	if !ok {
+		return b, nil
+	}
+	if err := json.Unmarshal(v, b); err != nil {
+		return nil, ErrTriggerInvalidCloudEvent
+	}
+	if b.Sink != "" {
+		u, err := url.Parse(b.Sink)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, ErrTriggerInvalidCloudEvent
+		}
+	}
+	return b, nil
+}
+
+// Matches returns whether events of source are bound
+func (b *CloudEventBinding) Matches(source string) bool {
+	if b.Source == "" {
+		return true
+	}
+	if strings.HasSuffix(b.Source, "*") {
+		return strings.HasPrefix(source, strings.TrimSuffix(b.Source, "*"))
+	}
+	return b.Source == source
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Config values of apps and fns may reference the config and secrets of their
+// app as ${config:NAME} and ${secret:NAME}, and escape a literal ${ as $${.
+// Config references are resolved as calls are built, against the config of
+// the app as set. Secret references are only resolved as containers start,
+// so that calls never hold secret values.
+const (
+	ConfigRefConfig = "config"
+	ConfigRefSecret = "secret"
+)
+
+var configRefRe = regexp.MustCompile(`\$\$\{|\$\{(config|secret):([^}]*)\}`)
+
+// ValidateConfigRefs checks the names of the references of the values of c
+func ValidateConfigRefs(c Config) error {
+	for k, v := range c {
+		for _, m := range configRefRe.FindAllStringSubmatch(v, -1) {
+			if m[1] == "" {
+				continue // escape
+			}
+			if m[2] == "" || (m[1] == ConfigRefSecret && !validSecretName(m[2])) {
+				return NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid reference %s in config %s", m[0], k))
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveConfigRefs returns c with the config references of its values
+// resolved against appConfig, leaving secret references and escapes.
+func ResolveConfigRefs(c, appConfig Config) (Config, error) {
+	return resolveRefs(c, ConfigRefConfig, false, func(name string) (string, bool) {
+		v, ok := appConfig[name]
+		return v, ok
+	})
+}
+
+// ResolveSecretRefs returns c with the secret references of its values
+// resolved by secret and its escapes replaced, it must run after
+// ResolveConfigRefs.
+func ResolveSecretRefs(c Config, secret func(name string) (string, bool)) (Config, error) {
+	return resolveRefs(c, ConfigRefSecret, true, secret)
+}
+
+// HasSecretRefs returns whether a value of c references a secret
+func HasSecretRefs(c Config) bool {
+	for _, v := range c {
+		for _, m := range configRefRe.FindAllStringSubmatch(v, -1) {
+			if m[1] == ConfigRefSecret {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func resolveRefs(c Config, kind string, unescape bool, resolve func(name string) (string, bool)) (Config, error) {
+	var err error
+	res := make(Config, len(c))
+	for k, v := range c {
+		if !strings.Contains(v, "${") {
+			res[k] = v
+			continue
+		}
+		res[k] = configRefRe.ReplaceAllStringFunc(v, func(ref string) string {
+			if ref == "$${" {
+				if unescape {
+					return "${"
+				}
+				return ref
+			}
+			m := configRefRe.FindStringSubmatch(ref)
+			if m[1] != kind {
+				return ref
+			}
+			resolved, ok := resolve(m[2])
+			if !ok && err == nil {
+				err = NewAPIError(http.StatusBadRequest, fmt.Errorf("Config %s references %s %s, which is not set", k, m[1], m[2]))
+			}
+			return resolved
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestValidateConfigRefs(t *testing.T) {
+	for _, v := range []string{"plain", "${config:DB_URL}", "${secret:TOKEN}", "$${secret:}", "${other:x}", "${unclosed"} {
+		if err := ValidateConfigRefs(Config{"K": v}); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", v, err)
+		}
+	}
+	for _, v := range []string{"${config:}", "${secret:}", "${secret:1TOKEN}", "${secret:A-B}"} {
+		if err := ValidateConfigRefs(Config{"K": v}); err == nil {
+			t.Errorf("Expected %q to be invalid", v)
+		}
+	}
+}
+
+func TestResolveConfigRefs(t *testing.T) {
+	app := Config{"DB_HOST": "db.prod", "DB_URL": "${config:DB_HOST}"}
+	c := Config{
+		"URL":     "postgres://${config:DB_HOST}:5432/${config:DB_HOST}",
+		"NESTED":  "${config:DB_URL}",
+		"PASS":    "${secret:DB_PASS}",
+		"ESCAPED": "$${config:DB_HOST} ${secret:TOKEN}",
+	}
+
+	c, err := ResolveConfigRefs(c, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]string{
+		"URL":     "postgres://db.prod:5432/db.prod",
+		"NESTED":  "${config:DB_HOST}", // app config is not resolved again
+		"PASS":    "${secret:DB_PASS}",
+		"ESCAPED": "$${config:DB_HOST} ${secret:TOKEN}",
+	} {
+		if c[k] != want {
+			t.Errorf("Expected %s to be %q after resolving config, got %q", k, want, c[k])
+		}
+	}
+	if !HasSecretRefs(c) {
+		t.Fatal("Expected secret references to be left")
+	}
+
+	secrets := map[string]string{"DB_PASS": "hunter2", "TOKEN": "t0k"}
+	resolved, err := ResolveSecretRefs(c, func(name string) (string, bool) {
+		v, ok := secrets[name]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved["PASS"] != "hunter2" || resolved["ESCAPED"] != "${config:DB_HOST} t0k" {
+		t.Fatalf("Expected secrets resolved and escapes replaced, got %+v", resolved)
+	}
+	if c["PASS"] != "${secret:DB_PASS}" {
+		t.Fatal("Expected resolving not to modify the config")
+	}
+
+	if _, err := ResolveConfigRefs(Config{"K": "${config:MISSING}"}, app); err == nil {
+		t.Fatal("Expected references to unset config to fail")
+	}
+	if _, err := ResolveSecretRefs(c, func(string) (string, bool) { return "", false }); err == nil {
+		t.Fatal("Expected references to unset secrets to fail")
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// FnRetryAnnotation sets the retry policy of async calls of a fn, its value is
+// an object {"max_attempts": 3, "backoff_seconds": 10}. Failed calls are queued
+// again after backoff_seconds, doubled on every attempt, until max_attempts
+// calls failed. The call is then moved to the dead letter queue of the fn.
+const FnRetryAnnotation = "fnproject.io/fn/retry"
+
+// MaxRetryAttempts bounds the number of attempts of an async call
+const MaxRetryAttempts = 100
+
+var (
+	ErrFnsInvalidRetryPolicy = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid retry annotation %s, max_attempts must be between 1 and %d and backoff_seconds between 0 and %d", FnRetryAnnotation, MaxRetryAttempts, MaxCallDelay),
+	}
+	ErrDeadLettersUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Dead letter queues are not supported by the log store"),
+	}
+	ErrDeadLetterNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Dead letter not found"),
+	}
+)
+
+// RetryPolicy is how failed async calls of a fn are retried
+type RetryPolicy struct {
+	MaxAttempts    int32 `json:"max_attempts"`
+	BackoffSeconds int32 `json:"backoff_seconds"`
+}
+
+// RetryPolicyFromAnnotations returns the retry policy of a fn, ok is false if
+// failed calls should not be retried.
+func RetryPolicyFromAnnotations(annotations Annotations) (p *RetryPolicy, ok bool, err error) {
+	v, ok := annotations.Get(FnRetryAnnotation)
+	if !ok {
+		return nil, false, nil
+	}
+	p = &RetryPolicy{}
+	if err := json.Unmarshal(v, p); err != nil ||
+		p.MaxAttempts < 1 || p.MaxAttempts > MaxRetryAttempts ||
+		p.BackoffSeconds < 0 || p.BackoffSeconds > MaxCallDelay {
+		return nil, false, ErrFnsInvalidRetryPolicy
+	}
+	return p, true, nil
+}
+
+// Backoff returns the delay in seconds before the attempt following attempt
+func (p *RetryPolicy) Backoff(attempt int32) int32 {
+	delay := int64(p.BackoffSeconds)
+	for i := int32(1); i < attempt && delay < MaxCallDelay; i++ {
+		delay *= 2
+	}
+	if delay > MaxCallDelay {
+		delay = MaxCallDelay
+	}
+	return int32(delay)
+}
+
+// DeadLetterStore may be implemented by a LogStore to keep async calls that
+// failed all their attempts.
+type DeadLetterStore interface {
+	// InsertDeadLetter adds a call to the dead letter queue of its fn
+	InsertDeadLetter(ctx context.Context, call *Call) error
+
+	// GetDeadLetters returns the dead letters of a fn matching filter, newest first
+	GetDeadLetters(ctx context.Context, filter *CallFilter) (*CallList, error)
+
+	// RemoveDeadLetter removes a call from the dead letter queue of a fn.
+	// Returns ErrDeadLetterNotFound if the call is not in the queue.
+	RemoveDeadLetter(ctx context.Context, fnID, callID string) error
+}
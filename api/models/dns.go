@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AppDNSAnnotation configures the name resolution of the containers of an
+// app, so that fns in split-horizon environments resolve internal names
+// without resolvers baked into their images. Its value is:
+//
+//	{"servers": ["10.0.0.2"], "search": ["corp.example.com"], "extra_hosts": ["db.corp.example.com:10.0.0.5"]}
+//
+// servers and search replace the ones agents are configured with, extra_hosts
+// are added to theirs.
+const AppDNSAnnotation = "fnproject.io/app/dns"
+
+var ErrAppsInvalidDNS = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid dns annotation %s, servers must be ip addresses, search domains must not be empty and extra_hosts must be of the form host:ip", AppDNSAnnotation),
+}
+
+// DNSConfig is the name resolution of a container
+type DNSConfig struct {
+	Servers    []string `json:"servers,omitempty"`
+	Search     []string `json:"search,omitempty"`
+	ExtraHosts []string `json:"extra_hosts,omitempty"`
+}
+
+// DNSFromAnnotations returns the name resolution of the containers of an
+// app, nil if not set
+func DNSFromAnnotations(annotations Annotations) (*DNSConfig, error) {
+	v, ok := annotations.Get(AppDNSAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	dns := &DNSConfig{}
+	if err := json.Unmarshal(v, dns); err != nil {
+		return nil, ErrAppsInvalidDNS
+	}
+	for _, server := range dns.Servers {
+		if net.ParseIP(server) == nil {
+			return nil, ErrAppsInvalidDNS
+		}
+	}
+	for _, domain := range dns.Search {
+		if domain == "" || strings.ContainsAny(domain, " \t") {
+			return nil, ErrAppsInvalidDNS
+		}
+	}
+	for _, host := range dns.ExtraHosts {
+		parts := strings.SplitN(host, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || net.ParseIP(parts[1]) == nil {
+			return nil, ErrAppsInvalidDNS
+		}
+	}
+	return dns, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -105,6 +106,14 @@ var (
 		code:  http.StatusBadRequest,
 		error: errors.New("from_time is not an epoch time"),
 	}
+	ErrCallInvalidStatus = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("status must be one of %v", possibleStatuses),
+	}
+	ErrCallInvalidErrorClass = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("error_class must be one of %v", possibleErrorClasses),
+	}
 	ErrInvalidMemory = err{
 		code:  http.StatusBadRequest,
 		error: fmt.Errorf("memory value is out of range. It should be between 0 and %d", MaxMemory),
@@ -122,6 +131,26 @@ var (
 		error: fmt.Errorf("Cpus is invalid. Value should be either between [%.3f and %.3f] or [%dm and %dm] milliCPU units",
 			float64(MinMilliCPUs)/1000.0, float64(MaxMilliCPUs)/1000.0, MinMilliCPUs, MaxMilliCPUs),
 	}
+	ErrCallCancelled = err{
+		code:  http.StatusConflict,
+		error: errors.New("Call was cancelled"),
+	}
+	ErrCallCompleted = err{
+		code:  http.StatusConflict,
+		error: errors.New("Call already completed, it cannot be cancelled"),
+	}
+	ErrCallAlreadyCompleted = err{
+		code:  http.StatusConflict,
+		error: errors.New("Call was already completed"),
+	}
+	ErrCallCompletionUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Completing calls is not supported by the log store"),
+	}
+	ErrCallLeaseExpired = err{
+		code:  http.StatusConflict,
+		error: errors.New("The lease of the call expired, it was queued again"),
+	}
 	ErrCallLogNotFound = err{
 		code:  http.StatusNotFound,
 		error: errors.New("Call log not found"),
@@ -130,6 +159,14 @@ var (
 		code:  http.StatusNotFound,
 		error: errors.New("Path not found"),
 	}
+	ErrUnauthenticated = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("Missing or invalid credentials"),
+	}
+	ErrForbidden = err{
+		code:  http.StatusForbidden,
+		error: errors.New("Credentials do not allow this request"),
+	}
 	ErrFunctionResponseTooBig = err{
 		code:  http.StatusBadGateway,
 		error: fmt.Errorf("function response too large"),
@@ -146,6 +183,18 @@ var (
 		code:  http.StatusBadGateway,
 		error: fmt.Errorf("invalid function response"),
 	}
+	ErrFunctionWebSocketUnsupported = err{
+		code:  http.StatusBadGateway,
+		error: errors.New("function does not accept WebSocket connections"),
+	}
+	ErrWebSocketUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("WebSocket invocations are not supported on this server"),
+	}
+	ErrWebSocketHandshake = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid WebSocket handshake, expected a GET request upgrading to websocket version 13 with a Sec-WebSocket-Key"),
+	}
 	ErrRequestContentTooBig = err{
 		code:  http.StatusRequestEntityTooLarge,
 		error: fmt.Errorf("Request content too large"),
@@ -179,6 +228,19 @@ var (
 		error: errors.New("Async functions are not supported on this server"),
 	}
 
+	ErrCallInvalidDeadline = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid %s header, must be an RFC3339 time", DeadlineHeader),
+	}
+	ErrCallDeadlineExceeded = err{
+		code:  http.StatusGatewayTimeout,
+		error: errors.New("Deadline of the request exceeded"),
+	}
+	ErrCallInvalidDelay = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid call delay, must be between 0 and %d seconds", MaxCallDelay),
+	}
+
 	ErrDetachUnsupported = err{
 		code:  http.StatusNotImplemented,
 		error: errors.New("Detach call functions are not supported on this server"),
@@ -188,6 +250,10 @@ var (
 		code:  http.StatusInternalServerError,
 		error: errors.New("Unable to find the call handle"),
 	}
+	ErrCallRunnerLost = err{
+		code:  http.StatusServiceUnavailable,
+		error: errors.New("The runner of the call was lost before the call completed, it may be retried"),
+	}
 	ErrServiceReservationFailure = err{
 		code:  http.StatusInternalServerError,
 		error: errors.New("Unable to service the request for the reservation period"),
@@ -196,8 +262,22 @@ var (
 		code:  http.StatusBadGateway,
 		error: errors.New("container failed to initialize, please ensure you are using the latest fdk / format and check the logs"),
 	}
+	ErrAdmissionFailed = err{
+		code:  http.StatusServiceUnavailable,
+		error: errors.New("Admission controller failed, please try again later"),
+	}
+	ErrFnMetricsUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Fn metrics are not enabled on this server"),
+	}
 )
 
+// ErrAdmissionDenied is returned when an admission controller rejects an app,
+// fn or trigger.
+func ErrAdmissionDenied(reason string) APIError {
+	return err{code: http.StatusBadRequest, error: fmt.Errorf("Admission denied: %s", reason)}
+}
+
 // APIError any error that implements this interface will return an API response
 // with the provided status code and error message body
 type APIError interface {
@@ -214,6 +294,28 @@ func (e err) Code() int { return e.code }
 
 func NewAPIError(code int, e error) APIError { return err{code, e} }
 
+// ErrorClass returns the class of the error a call failed with, see the
+// ErrorClass constants. Errors that aren't api errors are server ones.
+func ErrorClass(e error) string {
+	switch e {
+	case context.DeadlineExceeded, ErrCallTimeout, ErrCallDeadlineExceeded, ErrDockerPullTimeout, ErrContainerInitTimeout:
+		return ErrorClassTimeout
+	case ErrFunctionResponseTooBig, ErrFunctionResponse, ErrFunctionFailed, ErrFunctionInvalidResponse,
+		ErrFunctionWebSocketUnsupported, ErrContainerInitFail:
+		return ErrorClassFunction
+	}
+	if e == ErrCallRunnerLost {
+		return ErrorClassServer
+	}
+	switch code := GetAPIErrorCode(e); {
+	case code == http.StatusServiceUnavailable || code == http.StatusTooManyRequests:
+		return ErrorClassCapacity
+	case code >= 400 && code < 500:
+		return ErrorClassClient
+	}
+	return ErrorClassServer
+}
+
 func IsAPIError(e error) bool {
 	_, ok := e.(APIError)
 	return ok
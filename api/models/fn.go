@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -17,6 +18,8 @@ var (
 	MaxMemory      uint64 = 8 * 1024 // 8GB
 	MaxTimeout     int32  = 300      // 5m
 	MaxIdleTimeout int32  = 3600     // 1h
+	MaxInitTimeout int32  = 300      // 5m
+	MaxConcurrency uint64 = 64       // calls in flight per container
 
 	DefaultTimeout     int32  = 30  // seconds
 	DefaultIdleTimeout int32  = 30  // seconds
@@ -69,6 +72,30 @@ var (
 		code:  http.StatusBadRequest,
 		error: fmt.Errorf("idle_timeout value is out of range, must be between 0 and %d", MaxIdleTimeout),
 	}
+	ErrFnsInvalidInitTimeout = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("init_timeout value is out of range, must be between 0 and %d", MaxInitTimeout),
+	}
+	ErrFnsInvalidConcurrency = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid concurrency annotation %s, must be an integer between 1 and %d", FnConcurrencyAnnotation, MaxConcurrency),
+	}
+	ErrFnsInvalidStreamResponse = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid stream response annotation %s, must be a boolean", FnStreamResponseAnnotation),
+	}
+	ErrFnsInvalidOvercommit = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid overcommit annotation %s, must be a boolean", FnOvercommitAnnotation),
+	}
+	ErrFnsInvalidCPUPinning = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid cpu pinning annotation %s, must be a boolean", FnCPUPinningAnnotation),
+	}
+	ErrFnsInvalidSizeLimit = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid size limit annotation %s or %s, must be a positive integer number of bytes", FnMaxRequestSizeAnnotation, FnMaxResponseSizeAnnotation),
+	}
 	ErrFnsNotFound = err{
 		code:  http.StatusNotFound,
 		error: errors.New("Fn not found"),
@@ -82,6 +109,34 @@ var (
 // FnInvokeEndpointAnnotation is the annotation that exposes the fn invoke endpoint For want of a better place to put this it's here
 const FnInvokeEndpointAnnotation = "fnproject.io/fn/invokeEndpoint"
 
+// FnConcurrencyAnnotation is the number of calls a single hot container of this fn
+// may serve at the same time over its UDS listener, defaults to 1.
+const FnConcurrencyAnnotation = "fnproject.io/fn/concurrency"
+
+// FnMaxRequestSizeAnnotation and FnMaxResponseSizeAnnotation are the number of
+// bytes the request and response bodies of calls of this fn may hold, on top of
+// the limits of the server. Bodies are streamed and cut off once over the limit.
+const (
+	FnMaxRequestSizeAnnotation  = "fnproject.io/fn/maxRequestSize"
+	FnMaxResponseSizeAnnotation = "fnproject.io/fn/maxResponseSize"
+)
+
+// FnStreamResponseAnnotation, if true, makes sync invocations of this fn send its
+// response to the client as the container writes it, e.g. chunks of a chunked or
+// text/event-stream response, instead of once the call completed. Streamed
+// responses cannot report errors of the call after their first bytes.
+const FnStreamResponseAnnotation = "fnproject.io/fn/streamResponse"
+
+// FnOvercommitAnnotation, if false, opts a fn out of the memory and cpu
+// overcommit of agents: its containers are only started while the memory and
+// cpu reserved by all containers fit the agent.
+const FnOvercommitAnnotation = "fnproject.io/fn/overcommit"
+
+// FnCPUPinningAnnotation, if true, pins the hot containers of a latency
+// sensitive fn to dedicated cores of agents pinning cpus, on a single NUMA
+// node where possible. Containers run unpinned when no cores are free.
+const FnCPUPinningAnnotation = "fnproject.io/fn/cpuPinning"
+
 // Fn contains information about a function configuration.
 type Fn struct {
 	// ID is the generated resource id.
@@ -112,9 +167,14 @@ type ResourceConfig struct {
 	// Timeout is the max execution time for a function, in seconds.
 	// TODO this should probably be milliseconds?
 	Timeout int32 `json:"timeout,omitempty" db:"timeout"`
-	// IdleTimeout is the
+	// IdleTimeout is the time a hot container waits for calls before it is
+	// shut down, in seconds.
 	// TODO this should probably be milliseconds
 	IdleTimeout int32 `json:"idle_timeout,omitempty" db:"idle_timeout"`
+	// InitTimeout is the max time a hot container may take to start and be
+	// ready for calls, image pull excluded, in seconds. 0 uses the default
+	// of the runner, see agent.EnvHotStartTimeout.
+	InitTimeout int32 `json:"init_timeout,omitempty" db:"init_timeout"`
 }
 
 // SetCreated sets zeroed field to defaults.
@@ -176,13 +236,172 @@ func (f *Fn) Validate() error {
 		return ErrFnsInvalidIdleTimeout
 	}
 
+	if f.InitTimeout < 0 || f.InitTimeout > MaxInitTimeout {
+		return ErrFnsInvalidInitTimeout
+	}
+
 	if f.Memory < 1 || f.Memory > MaxMemory {
 		return ErrInvalidMemory
 	}
 
+	if _, err := ConcurrencyFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, _, err := ScheduleFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, _, err := RetryPolicyFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := InvokePolicyFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, _, err := SizeLimitsFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := StreamResponseFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := OvercommitFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := CPUPinningFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := IOLimitFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := EgressLimitFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := AssetsFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := NodeSelectorFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := AntiAffinityFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := PriorityFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := SLOFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := ResponseCacheFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := FnRateLimitFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := TrafficFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if err := ValidateConfigRefs(f.Config); err != nil {
+		return err
+	}
+
 	return f.Annotations.Validate()
 }
 
+// ConcurrencyFromAnnotations returns the number of concurrent calls per container
+// selected by annotations, 1 if not set or invalid.
+func ConcurrencyFromAnnotations(annotations Annotations) (uint64, error) {
+	v, ok := annotations.Get(FnConcurrencyAnnotation)
+	if !ok {
+		return 1, nil
+	}
+	var n uint64
+	if err := json.Unmarshal(v, &n); err != nil || n < 1 || n > MaxConcurrency {
+		return 1, ErrFnsInvalidConcurrency
+	}
+	return n, nil
+}
+
+// SizeLimitsFromAnnotations returns the request and response body size limits
+// selected by annotations, 0 if not set or invalid.
+func SizeLimitsFromAnnotations(annotations Annotations) (maxRequest, maxResponse uint64, err error) {
+	for _, l := range []struct {
+		key string
+		n   *uint64
+	}{
+		{FnMaxRequestSizeAnnotation, &maxRequest},
+		{FnMaxResponseSizeAnnotation, &maxResponse},
+	} {
+		v, ok := annotations.Get(l.key)
+		if !ok {
+			continue
+		}
+		var n uint64
+		if json.Unmarshal(v, &n) != nil || n < 1 {
+			return 0, 0, ErrFnsInvalidSizeLimit
+		}
+		*l.n = n
+	}
+	return maxRequest, maxResponse, nil
+}
+
+// StreamResponseFromAnnotations returns whether the responses of a fn are
+// streamed, false if not set.
+func StreamResponseFromAnnotations(annotations Annotations) (bool, error) {
+	v, ok := annotations.Get(FnStreamResponseAnnotation)
+	if !ok {
+		return false, nil
+	}
+	var stream bool
+	if err := json.Unmarshal(v, &stream); err != nil {
+		return false, ErrFnsInvalidStreamResponse
+	}
+	return stream, nil
+}
+
+// OvercommitFromAnnotations returns whether the containers of a fn may be
+// overcommitted, true if not set.
+func OvercommitFromAnnotations(annotations Annotations) (bool, error) {
+	v, ok := annotations.Get(FnOvercommitAnnotation)
+	if !ok {
+		return true, nil
+	}
+	var overcommit bool
+	if err := json.Unmarshal(v, &overcommit); err != nil {
+		return true, ErrFnsInvalidOvercommit
+	}
+	return overcommit, nil
+}
+
+// CPUPinningFromAnnotations returns whether the containers of a fn are pinned
+// to dedicated cores, false if not set.
+func CPUPinningFromAnnotations(annotations Annotations) (bool, error) {
+	v, ok := annotations.Get(FnCPUPinningAnnotation)
+	if !ok {
+		return false, nil
+	}
+	var pinning bool
+	if err := json.Unmarshal(v, &pinning); err != nil {
+		return false, ErrFnsInvalidCPUPinning
+	}
+	return pinning, nil
+}
+
 func (f *Fn) Clone() *Fn {
 	clone := new(Fn)
 	*clone = *f // shallow copy
@@ -216,6 +435,7 @@ func (f1 *Fn) Equals(f2 *Fn) bool {
 	eq = eq && f1.Memory == f2.Memory
 	eq = eq && f1.Timeout == f2.Timeout
 	eq = eq && f1.IdleTimeout == f2.IdleTimeout
+	eq = eq && f1.InitTimeout == f2.InitTimeout
 	eq = eq && f1.Config.Equals(f2.Config)
 	eq = eq && f1.Annotations.Equals(f2.Annotations)
 	// NOTE: datastore tests are not very fun to write with timestamp checks,
@@ -237,6 +457,7 @@ func (f1 *Fn) EqualsWithAnnotationSubset(f2 *Fn) bool {
 	eq = eq && f1.Memory == f2.Memory
 	eq = eq && f1.Timeout == f2.Timeout
 	eq = eq && f1.IdleTimeout == f2.IdleTimeout
+	eq = eq && f1.InitTimeout == f2.InitTimeout
 	eq = eq && f1.Config.Equals(f2.Config)
 	eq = eq && f1.Annotations.Subset(f2.Annotations)
 	// NOTE: datastore tests are not very fun to write with timestamp checks,
@@ -265,6 +486,9 @@ func (f *Fn) Update(patch *Fn) {
 	if patch.IdleTimeout != 0 {
 		f.IdleTimeout = patch.IdleTimeout
 	}
+	if patch.InitTimeout != 0 {
+		f.InitTimeout = patch.InitTimeout
+	}
 	if patch.Config != nil {
 		if f.Config == nil {
 			f.Config = make(Config)
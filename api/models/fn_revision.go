@@ -0,0 +1,163 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// FnRevisionHeader pins an invocation to a revision of its fn, by id, ahead
+// of any traffic split of the fn.
+const FnRevisionHeader = "Fn-Revision"
+
+// FnTrafficAnnotation splits the invocations of a fn between its revisions,
+// it maps revision ids to the percentage of invocations they run. The
+// invocations left over run the current image and config of the fn.
+const FnTrafficAnnotation = "fnproject.io/fn/traffic"
+
+var (
+	ErrFnRevisionsNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Fn revision not found"),
+	}
+	ErrFnRevisionsUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Fn revisions are not supported by the datastore"),
+	}
+	ErrFnsInvalidTraffic = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid traffic annotation %s, must map revision ids to percentages between 1 and 100 adding up to 100 at most", FnTrafficAnnotation),
+	}
+)
+
+// FnRevision is an immutable snapshot of the image and configuration of a fn,
+// one is taken each time the fn is deployed with a different image, resources
+// or config. Invocations may run past revisions, see FnTrafficAnnotation.
+type FnRevision struct {
+	ID    string `json:"id" db:"id"`
+	FnID  string `json:"fn_id" db:"fn_id"`
+	AppID string `json:"app_id" db:"app_id"`
+	// Number counts the revisions of a fn, from 1.
+	Number int64  `json:"number" db:"number"`
+	Image  string `json:"image" db:"image"`
+	// ImageDigest is the digest the image was pinned to, if it was referenced
+	// by digest.
+	ImageDigest    string          `json:"image_digest,omitempty" db:"image_digest"`
+	ResourceConfig                 // embed
+	Config         Config          `json:"config" db:"config"`
+	CreatedAt      common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+// NewFnRevision returns a revision of the current image and config of fn, it
+// is numbered and given an id as it is stored.
+func NewFnRevision(fn *Fn) *FnRevision {
+	rev := &FnRevision{
+		FnID:           fn.ID,
+		AppID:          fn.AppID,
+		Image:          fn.Image,
+		ResourceConfig: fn.ResourceConfig,
+		Config:         make(Config, len(fn.Config)),
+	}
+	if i := strings.LastIndex(fn.Image, "@"); i >= 0 {
+		rev.ImageDigest = fn.Image[i+1:]
+	}
+	for k, v := range fn.Config {
+		rev.Config[k] = v
+	}
+	return rev
+}
+
+func (r *FnRevision) Validate() error {
+	if r.FnID == "" {
+		return ErrDatastoreEmptyFnID
+	}
+	if r.Image == "" {
+		return ErrFnsMissingImage
+	}
+	return nil
+}
+
+// Deploys returns whether fn runs the image and config of r.
+func (r *FnRevision) Deploys(fn *Fn) bool {
+	return r.Image == fn.Image &&
+		r.ResourceConfig == fn.ResourceConfig &&
+		r.Config.Equals(fn.Config)
+}
+
+// Apply returns a copy of fn running the image and config of r, keeping the
+// annotations of fn.
+func (r *FnRevision) Apply(fn *Fn) *Fn {
+	clone := fn.Clone()
+	clone.Image = r.Image
+	clone.ResourceConfig = r.ResourceConfig
+	clone.Config = make(Config, len(r.Config))
+	for k, v := range r.Config {
+		clone.Config[k] = v
+	}
+	return clone
+}
+
+// FnTraffic maps revision ids to the percentage of the invocations of their
+// fn they run.
+type FnTraffic map[string]int
+
+// TrafficFromAnnotations returns the traffic split of a fn selected by
+// annotations, nil if not set.
+func TrafficFromAnnotations(annotations Annotations) (FnTraffic, error) {
+	v, ok := annotations.Get(FnTrafficAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var traffic FnTraffic
+	if err := json.Unmarshal(v, &traffic); err != nil {
+		return nil, ErrFnsInvalidTraffic
+	}
+	total := 0
+	for id, pct := range traffic {
+		if id == "" || pct < 1 || pct > 100 {
+			return nil, ErrFnsInvalidTraffic
+		}
+		total += pct
+	}
+	if total > 100 {
+		return nil, ErrFnsInvalidTraffic
+	}
+	return traffic, nil
+}
+
+// Pick returns the revision running the invocation drawn n, in [0, 100), or
+// empty if it runs the current fn.
+func (t FnTraffic) Pick(n int) string {
+	ids := make([]string, 0, len(t))
+	for id := range t {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if n < t[id] {
+			return id
+		}
+		n -= t[id]
+	}
+	return ""
+}
+
+// FnRevisionStore may be implemented by a Datastore to keep the revisions of
+// fns, which are removed with their fn.
+type FnRevisionStore interface {
+	// InsertFnRevision stores a revision, numbering it after the last revision
+	// of its fn. Returns ErrFnsNotFound if the fn does not exist.
+	InsertFnRevision(ctx context.Context, rev *FnRevision) (*FnRevision, error)
+
+	// GetFnRevisions returns the revisions of a fn, most recent first.
+	GetFnRevisions(ctx context.Context, fnID string) ([]*FnRevision, error)
+
+	// GetFnRevision returns a revision of a fn, or ErrFnRevisionsNotFound.
+	GetFnRevision(ctx context.Context, fnID, revisionID string) (*FnRevision, error)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 
@@ -20,6 +21,7 @@ func resourceConfigGenerator(t *testing.T) gopter.Gen {
 	fieldGens["Memory"] = gen.UInt64()
 	fieldGens["Timeout"] = gen.Int32()
 	fieldGens["IdleTimeout"] = gen.Int32()
+	fieldGens["InitTimeout"] = gen.Int32()
 
 	resourceConfig := ResourceConfig{}
 	resourceConfigFieldCount := reflect.TypeOf(resourceConfig).NumField()
@@ -106,3 +108,189 @@ func TestFnEquality(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+func TestFnTraffic(t *testing.T) {
+	for _, bad := range []string{`"blue"`, `{"r1": 0}`, `{"r1": 60, "r2": 50}`, `{"": 10}`} {
+		annotations, _ := Annotations{}.With(FnTrafficAnnotation, json.RawMessage(bad))
+		if _, err := TrafficFromAnnotations(annotations); err != ErrFnsInvalidTraffic {
+			t.Errorf("Expected traffic %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(FnTrafficAnnotation, FnTraffic{"r2": 10, "r1": 30})
+	traffic, err := TrafficFromAnnotations(annotations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for n := 0; n < 100; n++ {
+		counts[traffic.Pick(n)]++
+	}
+	if counts["r1"] != 30 || counts["r2"] != 10 || counts[""] != 60 {
+		t.Fatalf("Unexpected split of invocations %v", counts)
+	}
+
+	fn := &Fn{ID: "fn", AppID: "app", Image: "fnproject/hello@sha256:abc", Config: Config{"A": "1"}, Annotations: Annotations{}}
+	rev := NewFnRevision(fn)
+	if rev.ImageDigest != "sha256:abc" || !rev.Deploys(fn) {
+		t.Fatalf("Unexpected revision %+v", rev)
+	}
+	fn.Config["A"] = "2"
+	if rev.Deploys(fn) {
+		t.Fatal("Expected a revision not to deploy a fn with other config")
+	}
+	if applied := rev.Apply(fn); applied.Config["A"] != "1" || fn.Config["A"] != "2" {
+		t.Fatalf("Expected the revision config on a copy of the fn, got %v", applied.Config)
+	}
+}
+
+func TestFnIOLimit(t *testing.T) {
+	for _, bad := range []string{`"fast"`, `{"read_bps": -1}`, `{"write_iops": 1.5}`} {
+		annotations, _ := Annotations{}.With(FnIOLimitAnnotation, json.RawMessage(bad))
+		if _, err := IOLimitFromAnnotations(annotations); err != ErrFnsInvalidIOLimit {
+			t.Errorf("Expected io limit %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	if l, err := IOLimitFromAnnotations(Annotations{}); l != nil || err != nil {
+		t.Fatalf("Expected no io limit, got %v %v", l, err)
+	}
+	annotations, _ := Annotations{}.With(FnIOLimitAnnotation, IOLimit{ReadBps: 1 << 20, WriteIOPS: 100})
+	l, err := IOLimitFromAnnotations(annotations)
+	if err != nil || *l != (IOLimit{ReadBps: 1 << 20, WriteIOPS: 100}) {
+		t.Fatalf("Unexpected io limit %v %v", l, err)
+	}
+}
+
+func TestFnEgressLimit(t *testing.T) {
+	for _, bad := range []string{`"1mb"`, `-1`, `1.5`} {
+		annotations, _ := Annotations{}.With(FnEgressLimitAnnotation, json.RawMessage(bad))
+		if _, err := EgressLimitFromAnnotations(annotations); err != ErrFnsInvalidEgressLimit {
+			t.Errorf("Expected egress limit %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(FnEgressLimitAnnotation, 1<<20)
+	if rate, err := EgressLimitFromAnnotations(annotations); err != nil || rate != 1<<20 {
+		t.Fatalf("Unexpected egress limit %v %v", rate, err)
+	}
+}
+
+func TestFnAssets(t *testing.T) {
+	for _, bad := range []string{`["model"]`, `{"model": "models/model.bin"}`, `{"a/b": "/model"}`, `{"model": "/"}`} {
+		annotations, _ := Annotations{}.With(FnAssetsAnnotation, json.RawMessage(bad))
+		if _, err := AssetsFromAnnotations(annotations); err != ErrFnsInvalidAssets {
+			t.Errorf("Expected assets %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(FnAssetsAnnotation, map[string]string{"model": "/models/model.bin"})
+	if assets, err := AssetsFromAnnotations(annotations); err != nil || assets["model"] != "/models/model.bin" {
+		t.Fatalf("Unexpected assets %v %v", assets, err)
+	}
+
+	sum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	for _, bad := range []Asset{
+		{Name: "model", URL: "ftp://host/model", SHA256: sum},
+		{Name: "model", URL: "http://host/model", SHA256: "abc"},
+		{Name: "a/b", URL: "http://host/model", SHA256: sum},
+	} {
+		if err := bad.Validate(); err != ErrInvalidAsset {
+			t.Errorf("Expected asset %v to be invalid, got %v", bad, err)
+		}
+	}
+	if err := (&Asset{Name: "model", URL: "https://host/model", SHA256: sum}).Validate(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func TestFnNodeSelector(t *testing.T) {
+	for _, bad := range []string{`["arch=arm64"]`, `{"gpu": true}`, `{"": "x"}`} {
+		annotations, _ := Annotations{}.With(FnNodeSelectorAnnotation, json.RawMessage(bad))
+		if _, err := NodeSelectorFromAnnotations(annotations); err != ErrFnsInvalidNodeSelector {
+			t.Errorf("Expected node selector %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(FnNodeSelectorAnnotation, map[string]string{"arch": "arm64", "gpu": "true"})
+	if selector, err := NodeSelectorFromAnnotations(annotations); err != nil || selector["arch"] != "arm64" || selector["gpu"] != "true" {
+		t.Fatalf("Unexpected node selector %v %v", selector, err)
+	}
+}
+
+func TestFnAntiAffinity(t *testing.T) {
+	for _, bad := range []string{`2`, `{"max_per_runner": 0}`, `{"max_per_runner": 1, "fns": [""]}`} {
+		annotations, _ := Annotations{}.With(FnAntiAffinityAnnotation, json.RawMessage(bad))
+		if _, err := AntiAffinityFromAnnotations(annotations); err != ErrFnsInvalidAntiAffinity {
+			t.Errorf("Expected anti-affinity %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(FnAntiAffinityAnnotation, json.RawMessage(`{"max_per_runner": 2, "fns": ["other"]}`))
+	if aa, err := AntiAffinityFromAnnotations(annotations); err != nil || aa.MaxPerRunner != 2 || len(aa.Fns) != 1 {
+		t.Fatalf("Unexpected anti-affinity %v %v", aa, err)
+	}
+}
+
+func TestFnInitTimeout(t *testing.T) {
+	fn := &Fn{Name: "fn", AppID: "app", Image: "fnproject/hello"}
+	fn.SetDefaults()
+	if err := fn.Validate(); err != nil || fn.InitTimeout != 0 {
+		t.Fatalf("Expected fns to default to the init timeout of runners, got %d %v", fn.InitTimeout, err)
+	}
+
+	for _, bad := range []int32{-1, MaxInitTimeout + 1} {
+		fn.InitTimeout = bad
+		if err := fn.Validate(); err != ErrFnsInvalidInitTimeout {
+			t.Errorf("Expected init timeout %d to be invalid, got %v", bad, err)
+		}
+	}
+
+	fn.InitTimeout = 10
+	fn.Update(&Fn{ResourceConfig: ResourceConfig{InitTimeout: 60}})
+	if fn.InitTimeout != 60 {
+		t.Fatalf("Expected init timeout to be updated, got %d", fn.InitTimeout)
+	}
+}
+
+func TestFnPriority(t *testing.T) {
+	for _, bad := range []string{`"high"`, `-1`, `3`} {
+		annotations, _ := Annotations{}.With(FnPriorityAnnotation, json.RawMessage(bad))
+		if _, err := PriorityFromAnnotations(annotations); err != ErrFnsInvalidPriority {
+			t.Errorf("Expected priority %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(FnPriorityAnnotation, 2)
+	if p, err := PriorityFromAnnotations(annotations); err != nil || p != 2 {
+		t.Fatalf("Unexpected priority %d %v", p, err)
+	}
+}
+
+func TestFnSLO(t *testing.T) {
+	for _, bad := range []string{`"99.9"`, `{}`, `{"availability":1}`, `{"latency_target":0.9}`, `{"latency_msecs":100,"window_secs":10}`} {
+		annotations, _ := Annotations{}.With(FnSLOAnnotation, json.RawMessage(bad))
+		if _, err := SLOFromAnnotations(annotations); err != ErrFnsInvalidSLO {
+			t.Errorf("Expected slo %s to be invalid, got %v", bad, err)
+		}
+	}
+
+	annotations, _ := Annotations{}.With(FnSLOAnnotation, json.RawMessage(`{"availability":0.9,"latency_msecs":100}`))
+	slo, err := SLOFromAnnotations(annotations)
+	if err != nil || slo.LatencyTarget != DefaultSLOLatencyTarget || slo.WindowSecs != DefaultSLOWindow {
+		t.Fatalf("Expected the defaults of the slo to be set, got %+v %v", slo, err)
+	}
+
+	// 5 failures of the 10 the availability target allows, 19 of 95 successes
+	// slow where 0.95 are allowed
+	s := NewSLOStatus("fn", slo, 100, 5, 19)
+	if s.Availability != 0.95 || *s.AvailabilityBudget < 0.49 || *s.AvailabilityBudget > 0.51 {
+		t.Fatalf("Unexpected availability %+v", s)
+	}
+	if s.LatencyCompliance != 0.8 || *s.LatencyBudget > -18.9 || s.ErrorBudgetRemaining != *s.LatencyBudget {
+		t.Fatalf("Unexpected latency %+v", s)
+	}
+	if s := NewSLOStatus("fn", slo, 0, 0, 0); s.ErrorBudgetRemaining != 1 {
+		t.Fatalf("Expected the whole budget left without calls, got %+v", s)
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MaxBatchInvokeItems is the most items a batch invocation may hold
+const MaxBatchInvokeItems = 1000
+
+var (
+	ErrBatchInvokeNoItems = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Batch invocations must have items"),
+	}
+	ErrBatchInvokeTooManyItems = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Batch invocations may have at most %d items", MaxBatchInvokeItems),
+	}
+	ErrBatchInvokeInvalidConcurrency = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid max_concurrency, must not be negative"),
+	}
+)
+
+// BatchInvokeRequest invokes a fn once per item, items are invoked in
+// parallel, at most MaxConcurrency at once if set.
+type BatchInvokeRequest struct {
+	FnID           string             `json:"fn_id"`
+	MaxConcurrency int                `json:"max_concurrency,omitempty"`
+	Items          []*BatchInvokeItem `json:"items"`
+}
+
+// BatchInvokeItem is the request of one invocation of a batch, which gets the
+// headers of the batch request with Headers set on top of them. The
+// Fn-Signature of the batch is not passed on, and its Idempotency-Key is
+// suffixed with "/" and the index of the item.
+type BatchInvokeItem struct {
+	Body    string              `json:"body"`
+	Headers map[string][]string `json:"headers,omitempty"`
+}
+
+// BatchInvokeResult is the response of one invocation of a batch, Error is
+// set instead of Body if the invocation failed before the fn responded.
+type BatchInvokeResult struct {
+	Status  int                 `json:"status"`
+	CallID  string              `json:"call_id,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+	Error   *Error              `json:"error,omitempty"`
+}
+
+// BatchInvokeResponse holds the results of the items of a batch in order
+type BatchInvokeResponse struct {
+	Items []*BatchInvokeResult `json:"items"`
+}
+
+func (b *BatchInvokeRequest) Validate() error {
+	if b.FnID == "" {
+		return ErrFnsMissingID
+	}
+	if len(b.Items) == 0 {
+		return ErrBatchInvokeNoItems
+	}
+	if len(b.Items) > MaxBatchInvokeItems {
+		return ErrBatchInvokeTooManyItems
+	}
+	if b.MaxConcurrency < 0 {
+		return ErrBatchInvokeInvalidConcurrency
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// MaxFanOutBranches is the most branches a fan-out may hold
+	MaxFanOutBranches = 100
+
+	// DefaultFanOutTimeout is the deadline of fan-outs which do not set one,
+	// in seconds
+	DefaultFanOutTimeout int32 = 60
+)
+
+const (
+	// FanOutSuccess is the status of fan-outs whose branches all succeeded
+	FanOutSuccess = "success"
+	// FanOutPartial is the status of fan-outs which had enough branches
+	// succeed, but not all of them
+	FanOutPartial = "partial"
+	// FanOutFailure is the status of fan-outs which had too few branches
+	// succeed
+	FanOutFailure = "failure"
+)
+
+var (
+	ErrFanOutMissingAppID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing app_id of fan-out"),
+	}
+	ErrFanOutNoBranches = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Fan-outs must have branches"),
+	}
+	ErrFanOutTooManyBranches = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Fan-outs may have at most %d branches", MaxFanOutBranches),
+	}
+	ErrFanOutDuplicateToken = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Tokens of the branches of a fan-out must be unique"),
+	}
+	ErrFanOutInvalidMinSuccesses = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid min_successes, must be between 0 and the number of branches"),
+	}
+	ErrFanOutInvalidTimeout = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid timeout, must be between 0 and %d", MaxTimeout),
+	}
+	ErrFanOutFnNotInApp = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("The fns of the branches of a fan-out must be fns of its app"),
+	}
+	ErrFanOutDeadlineExceeded = err{
+		code:  http.StatusGatewayTimeout,
+		error: errors.New("Fan-out deadline exceeded before the branch completed"),
+	}
+	ErrFanOutAborted = err{
+		code:  http.StatusFailedDependency,
+		error: errors.New("Branch cancelled, too many other branches of the fan-out failed"),
+	}
+)
+
+// FanOutRequest invokes the fns of its branches in parallel and responds once
+// they all completed or Timeout passed. The fan-out succeeds if at least
+// MinSuccesses branches succeed, all of them if unset, and branches still
+// running are cancelled as soon as it cannot succeed anymore.
+type FanOutRequest struct {
+	AppID        string          `json:"app_id"`
+	Timeout      int32           `json:"timeout,omitempty"`
+	MinSuccesses int             `json:"min_successes,omitempty"`
+	Branches     []*FanOutBranch `json:"branches"`
+}
+
+// FanOutBranch is the request of one invocation of a fan-out, which gets the
+// headers of the fan-out request with Headers set on top of them, like a
+// BatchInvokeItem. Token
+// identifies the result of the branch, it defaults to the index of the
+// branch.
+type FanOutBranch struct {
+	Token   string              `json:"token,omitempty"`
+	FnID    string              `json:"fn_id"`
+	Body    string              `json:"body"`
+	Headers map[string][]string `json:"headers,omitempty"`
+}
+
+// FanOutResult is the response of one branch of a fan-out, Error is set
+// instead of Body if the invocation failed before the fn responded.
+type FanOutResult struct {
+	Token   string              `json:"token"`
+	FnID    string              `json:"fn_id"`
+	Status  int                 `json:"status"`
+	CallID  string              `json:"call_id,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+	Error   *Error              `json:"error,omitempty"`
+}
+
+// Succeeded returns whether the fn of the branch responded with a 2xx status
+func (r *FanOutResult) Succeeded() bool {
+	return r.Error == nil && r.Status >= 200 && r.Status < 300
+}
+
+// FanOutResponse holds the results of the branches of a fan-out in order,
+// and the tokens of the branches in the order they completed in.
+type FanOutResponse struct {
+	Status    string          `json:"status"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Completed []string        `json:"completed"`
+	Results   []*FanOutResult `json:"results"`
+}
+
+// Validate checks the fan-out and defaults the tokens of its branches
+func (f *FanOutRequest) Validate() error {
+	if f.AppID == "" {
+		return ErrFanOutMissingAppID
+	}
+	if len(f.Branches) == 0 {
+		return ErrFanOutNoBranches
+	}
+	if len(f.Branches) > MaxFanOutBranches {
+		return ErrFanOutTooManyBranches
+	}
+	if f.MinSuccesses < 0 || f.MinSuccesses > len(f.Branches) {
+		return ErrFanOutInvalidMinSuccesses
+	}
+	if f.Timeout < 0 || f.Timeout > MaxTimeout {
+		return ErrFanOutInvalidTimeout
+	}
+
+	tokens := make(map[string]bool, len(f.Branches))
+	for i, b := range f.Branches {
+		if b == nil || b.FnID == "" {
+			return ErrFnsMissingID
+		}
+		if b.Token == "" {
+			b.Token = strconv.Itoa(i)
+		}
+		if tokens[b.Token] {
+			return ErrFanOutDuplicateToken
+		}
+		tokens[b.Token] = true
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package models
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FnInvokePolicyAnnotation sets who may invoke a fn through its invoke endpoint
+// and http triggers, its value is one of:
+//
+//	{"type": "public"}
+//	{"type": "token", "token_sha256": ["<hex sha256 of a token>", ...]}
+//	{"type": "signature", "secret_name": "<name of a secret of the app>"}
+//
+// Fns without the annotation are public. Token policies require a Fn-Invoke-Token
+// header holding one of the tokens. Signature policies require a Fn-Signature
+// header of the form t=<unix time>,v1=<hex hmac-sha256 of "<unix time>.<body>">,
+// signed less than MaxInvokeSignatureAge ago, which may not be used twice. The
+// hmac key is the value of a secret of the app, see SecretStore, so that it is
+// only stored encrypted and never returned with the annotation. Invocations of
+// fns whose app lacks the secret are not authorized.
+const FnInvokePolicyAnnotation = "fnproject.io/fn/invokePolicy"
+
+// TriggerInvokePolicyAnnotation sets who may invoke a fn through an http
+// trigger, on top of the policy of the fn, in the format of
+// FnInvokePolicyAnnotation. Publicly exposed triggers may require callers to
+// sign their requests with the secret of a signature policy.
+const TriggerInvokePolicyAnnotation = "fnproject.io/trigger/invokePolicy"
+
+const (
+	InvokePolicyPublic    = "public"
+	InvokePolicyToken     = "token"
+	InvokePolicySignature = "signature"
+
+	// InvokeTokenHeader holds the token of invocations of fns with a token policy
+	InvokeTokenHeader = "Fn-Invoke-Token"
+	// InvokeSignatureHeader holds the signature of invocations of fns with a signature policy
+	InvokeSignatureHeader = "Fn-Signature"
+)
+
+// MaxInvokeSignatureAge is how old, or how far in the future, the time of a
+// signed invocation may be, which bounds the time a signed request may be replayed
+var MaxInvokeSignatureAge = 5 * time.Minute
+
+// DefaultMaxSignedBodySize is the size of the largest body of a signed
+// invocation whose signature is checked, for fns without a request size limit
+const DefaultMaxSignedBodySize = 10 * 1024 * 1024
+
+var (
+	ErrFnsInvalidInvokePolicy = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid invoke policy annotation %s, type must be one of public, token with token_sha256 hashes or signature with the secret_name of an app secret", FnInvokePolicyAnnotation),
+	}
+	ErrTriggersInvalidInvokePolicy = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid invoke policy annotation %s, type must be one of public, token with token_sha256 hashes or signature with the secret_name of an app secret", TriggerInvokePolicyAnnotation),
+	}
+	ErrInvokeNotAuthorized = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("Missing or invalid invoke token or signature"),
+	}
+	ErrInvokeSignatureReplayed = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("Invoke signature was already used"),
+	}
+)
+
+// ReplayFilter remembers the signatures of invocations, so that signed
+// requests may not be replayed while their signature is recent enough.
+type ReplayFilter interface {
+	// Seen remembers sig until expiry, and returns whether it was already
+	// remembered.
+	Seen(sig []byte, expiry time.Time) bool
+}
+
+// InvokePolicy is who may invoke a fn
+type InvokePolicy struct {
+	Type string `json:"type"`
+	// TokenHashes are the hex encoded sha256 hashes of the tokens of a token policy
+	TokenHashes []string `json:"token_sha256,omitempty"`
+	// SecretName names the secret of the app of the fn whose value signature
+	// policies sign requests with
+	SecretName string `json:"secret_name,omitempty"`
+	// Secret is the value of the secret SecretName, which callers of Authorize
+	// resolve, it is never stored
+	Secret []byte `json:"-"`
+	// MaxBodySize is the size of the largest body read to check its signature,
+	// which callers of Authorize set to the request size limit of the fn,
+	// DefaultMaxSignedBodySize if 0
+	MaxBodySize uint64 `json:"-"`
+}
+
+// InvokePolicyFromAnnotations returns the invoke policy of a fn, public if the
+// fn has none.
+func InvokePolicyFromAnnotations(annotations Annotations) (*InvokePolicy, error) {
+	p, ok := invokePolicyFromAnnotation(annotations, FnInvokePolicyAnnotation)
+	if !ok {
+		return nil, ErrFnsInvalidInvokePolicy
+	}
+	return p, nil
+}
+
+// TriggerInvokePolicyFromAnnotations returns the invoke policy of a trigger,
+// public if the trigger has none.
+func TriggerInvokePolicyFromAnnotations(annotations Annotations) (*InvokePolicy, error) {
+	p, ok := invokePolicyFromAnnotation(annotations, TriggerInvokePolicyAnnotation)
+	if !ok {
+		return nil, ErrTriggersInvalidInvokePolicy
+	}
+	return p, nil
+}
+
+// invokePolicyFromAnnotation returns the policy of annotation key, or false if
+// it is invalid
+func invokePolicyFromAnnotation(annotations Annotations, key string) (*InvokePolicy, bool) {
+	v, ok := annotations.Get(key)
+	if !ok {
+		return &InvokePolicy{Type: InvokePolicyPublic}, true
+	}
+	p := &InvokePolicy{}
+	if err := json.Unmarshal(v, p); err != nil {
+		return nil, false
+	}
+	switch p.Type {
+	case InvokePolicyPublic:
+	case InvokePolicyToken:
+		if len(p.TokenHashes) == 0 {
+			return nil, false
+		}
+		for _, h := range p.TokenHashes {
+			if b, err := hex.DecodeString(h); err != nil || len(b) != sha256.Size {
+				return nil, false
+			}
+		}
+	case InvokePolicySignature:
+		if !validSecretName(p.SecretName) {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	return p, true
+}
+
+// Authorize returns ErrInvokeNotAuthorized if req may not invoke the fn of the
+// policy at time now, or ErrInvokeSignatureReplayed if replays, if not nil,
+// saw its signature already. Checking a signature reads the body of req,
+// which is replaced by a copy, or fails with ErrRequestContentTooBig if it
+// is larger than MaxBodySize.
+func (p *InvokePolicy) Authorize(req *http.Request, now time.Time, replays ReplayFilter) error {
+	switch p.Type {
+	case InvokePolicyToken:
+		token := req.Header.Get(InvokeTokenHeader)
+		if token == "" {
+			return ErrInvokeNotAuthorized
+		}
+		sum := sha256.Sum256([]byte(token))
+		got := []byte(hex.EncodeToString(sum[:]))
+		for _, h := range p.TokenHashes {
+			if subtle.ConstantTimeCompare(got, []byte(strings.ToLower(h))) == 1 {
+				return nil
+			}
+		}
+		return ErrInvokeNotAuthorized
+
+	case InvokePolicySignature:
+		ts, sig, ok := parseInvokeSignature(req.Header.Get(InvokeSignatureHeader))
+		if !ok {
+			return ErrInvokeNotAuthorized
+		}
+		age := now.Sub(time.Unix(ts, 0))
+		if age > MaxInvokeSignatureAge || age < -MaxInvokeSignatureAge {
+			return ErrInvokeNotAuthorized
+		}
+
+		var body []byte
+		if req.Body != nil {
+			max := int64(p.MaxBodySize)
+			if max == 0 {
+				max = DefaultMaxSignedBodySize
+			}
+			if req.ContentLength > max {
+				return ErrRequestContentTooBig
+			}
+			var err error
+			body, err = ioutil.ReadAll(io.LimitReader(req.Body, max))
+			if err == nil {
+				// bodies of exactly the limit are told from larger ones,
+				// which limits of the server may fail to read
+				var one [1]byte
+				if n, rerr := req.Body.Read(one[:]); n > 0 || (rerr != nil && rerr != io.EOF) {
+					err = ErrRequestContentTooBig
+				}
+			}
+			req.Body.Close()
+			if err != nil {
+				return err
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		if len(p.Secret) == 0 || !hmac.Equal(sig, SignInvoke(p.Secret, ts, body)) {
+			return ErrInvokeNotAuthorized
+		}
+		if replays != nil && replays.Seen(sig, time.Unix(ts, 0).Add(MaxInvokeSignatureAge)) {
+			return ErrInvokeSignatureReplayed
+		}
+	}
+	return nil
+}
+
+// RedactInvokePolicies returns annotations without the plaintext secrets
+// signature policies were set with before they named app secrets instead.
+// Such policies are invalid and their secrets are never used, but they are
+// not returned either.
+func RedactInvokePolicies(annotations Annotations) Annotations {
+	for _, key := range []string{FnInvokePolicyAnnotation, TriggerInvokePolicyAnnotation} {
+		v, ok := annotations.Get(key)
+		if !ok {
+			continue
+		}
+		var policy map[string]json.RawMessage
+		if err := json.Unmarshal(v, &policy); err != nil {
+			continue
+		}
+		if _, ok := policy["secret"]; !ok {
+			continue
+		}
+		delete(policy, "secret")
+		if redacted, err := annotations.With(key, policy); err == nil {
+			annotations = redacted
+		}
+	}
+	return annotations
+}
+
+// SignInvoke returns the signature of an invocation with body at unix time ts
+func SignInvoke(secret []byte, ts int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// parseInvokeSignature parses a t=<unix time>,v1=<hex signature> header
+func parseInvokeSignature(h string) (ts int64, sig []byte, ok bool) {
+	var haveTS bool
+	for _, part := range strings.Split(h, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			t, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return 0, nil, false
+			}
+			ts, haveTS = t, true
+		case "v1":
+			s, err := hex.DecodeString(kv[1])
+			if err != nil {
+				return 0, nil, false
+			}
+			sig = s
+		}
+	}
+	return ts, sig, haveTS && sig != nil
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInvokePolicyFromAnnotationsInvalid(t *testing.T) {
+	for _, v := range []interface{}{
+		"public",
+		map[string]interface{}{"type": "private"},
+		map[string]interface{}{"type": "token"},
+		map[string]interface{}{"type": "token", "token_sha256": []string{"not-a-hash"}},
+		map[string]interface{}{"type": "signature"},
+	} {
+		a, _ := EmptyAnnotations().With(FnInvokePolicyAnnotation, v)
+		if _, err := InvokePolicyFromAnnotations(a); err != ErrFnsInvalidInvokePolicy {
+			t.Errorf("Expected %v to be an invalid policy, got %v", v, err)
+		}
+	}
+
+	p, err := InvokePolicyFromAnnotations(EmptyAnnotations())
+	if err != nil || p.Type != InvokePolicyPublic {
+		t.Fatalf("Expected fns to be public by default, got %+v %v", p, err)
+	}
+}
+
+func TestInvokePolicyToken(t *testing.T) {
+	sum := sha256.Sum256([]byte("s3cret"))
+	a, _ := EmptyAnnotations().With(FnInvokePolicyAnnotation, map[string]interface{}{
+		"type":         "token",
+		"token_sha256": []string{hex.EncodeToString(sum[:])},
+	})
+	p, err := InvokePolicyFromAnnotations(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		token string
+		err   error
+	}{
+		{"", ErrInvokeNotAuthorized},
+		{"wrong", ErrInvokeNotAuthorized},
+		{"s3cret", nil},
+	} {
+		req := httptest.NewRequest("POST", "/invoke/fn", nil)
+		if tc.token != "" {
+			req.Header.Set(InvokeTokenHeader, tc.token)
+		}
+		if err := p.Authorize(req, time.Now(), nil); err != tc.err {
+			t.Errorf("Expected token %q to return %v, got %v", tc.token, tc.err, err)
+		}
+	}
+}
+
+func TestInvokePolicySignature(t *testing.T) {
+	a, _ := EmptyAnnotations().With(FnInvokePolicyAnnotation, map[string]interface{}{"type": "signature", "secret_name": "HOOK_KEY"})
+	p, err := InvokePolicyFromAnnotations(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.SecretName != "HOOK_KEY" {
+		t.Fatalf("Expected the policy to name the secret, got %+v", p)
+	}
+
+	// the key of the secret is resolved by the server, without it nothing is
+	// authorized
+	req := httptest.NewRequest("POST", "/t/app/hello", strings.NewReader(`{}`))
+	req.Header.Set(InvokeSignatureHeader, fmt.Sprintf("t=%d,v1=%x", time.Now().Unix(), SignInvoke(nil, time.Now().Unix(), []byte(`{}`))))
+	if err := p.Authorize(req, time.Now(), nil); err != ErrInvokeNotAuthorized {
+		t.Fatalf("Expected a policy without its key to authorize nothing, got %v", err)
+	}
+	p.Secret = []byte("key")
+
+	now := time.Unix(1500000000, 0)
+	body := `{"name":"fn"}`
+	sign := func(secret string, ts time.Time, body string) string {
+		return fmt.Sprintf("t=%d,v1=%x", ts.Unix(), SignInvoke([]byte(secret), ts.Unix(), []byte(body)))
+	}
+
+	for _, tc := range []struct {
+		signature string
+		body      string
+		err       error
+	}{
+		{"", body, ErrInvokeNotAuthorized},
+		{sign("key", now, body), body, nil},
+		{sign("key", now.Add(-time.Minute), body), body, nil},
+		{sign("other", now, body), body, ErrInvokeNotAuthorized},
+		{sign("key", now, body), `{"name":"other"}`, ErrInvokeNotAuthorized},
+		{sign("key", now.Add(-time.Hour), body), body, ErrInvokeNotAuthorized},
+		{sign("key", now.Add(time.Hour), body), body, ErrInvokeNotAuthorized},
+		{"t=1500000000,v1=zz", body, ErrInvokeNotAuthorized},
+	} {
+		req := httptest.NewRequest("POST", "/t/app/hello", strings.NewReader(tc.body))
+		if tc.signature != "" {
+			req.Header.Set(InvokeSignatureHeader, tc.signature)
+		}
+		if err := p.Authorize(req, now, nil); err != tc.err {
+			t.Errorf("Expected signature %q to return %v, got %v", tc.signature, tc.err, err)
+			continue
+		}
+		if tc.err == nil {
+			b, _ := ioutil.ReadAll(req.Body)
+			if string(b) != tc.body {
+				t.Errorf("Expected the body to still be readable, got %q", b)
+			}
+		}
+	}
+}
+
+func TestInvokePolicySignatureBodyLimit(t *testing.T) {
+	p := &InvokePolicy{Type: InvokePolicySignature, SecretName: "key", Secret: []byte("key"), MaxBodySize: 4}
+	now := time.Unix(1500000000, 0)
+
+	for i, tc := range []struct {
+		body string
+		// whether the length of the body is unknown, or limited by the server
+		chunked bool
+		server  int64
+		err     error
+	}{
+		{body: "1234"},
+		{body: "12345", err: ErrRequestContentTooBig},
+		{body: "1234", chunked: true},
+		{body: "12345", chunked: true, err: ErrRequestContentTooBig},
+		{body: "1234", chunked: true, server: 4},
+		{body: "12345", chunked: true, server: 4, err: ErrRequestContentTooBig},
+	} {
+		req := httptest.NewRequest("POST", "/invoke/fn", strings.NewReader(tc.body))
+		req.Header.Set(InvokeSignatureHeader, fmt.Sprintf("t=%d,v1=%x", now.Unix(), SignInvoke(p.Secret, now.Unix(), []byte(tc.body))))
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		if tc.server > 0 {
+			req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, tc.server)
+		}
+		if err := p.Authorize(req, now, nil); err != tc.err {
+			t.Errorf("Test %d: expected a body of %d bytes to return %v, got %v", i, len(tc.body), tc.err, err)
+		}
+	}
+}
+
+type replays map[string]time.Time
+
+func (r replays) Seen(sig []byte, expiry time.Time) bool {
+	_, ok := r[string(sig)]
+	r[string(sig)] = expiry
+	return ok
+}
+
+func TestTriggerInvokePolicySignatureReplay(t *testing.T) {
+	a, _ := EmptyAnnotations().With(TriggerInvokePolicyAnnotation, map[string]interface{}{"type": "signature"})
+	trigger := &Trigger{Name: "hook", AppID: "app", FnID: "fn", Type: "http", Source: "/hook", Annotations: a}
+	if err := trigger.Validate(); err != ErrTriggersInvalidInvokePolicy {
+		t.Fatalf("Expected a signature policy without a secret to be invalid, got %v", err)
+	}
+
+	// plaintext secrets are no longer accepted
+	a, _ = EmptyAnnotations().With(TriggerInvokePolicyAnnotation, map[string]interface{}{"type": "signature", "secret": "key"})
+	trigger.Annotations = a
+	if err := trigger.Validate(); err != ErrTriggersInvalidInvokePolicy {
+		t.Fatalf("Expected a signature policy with a plaintext secret to be invalid, got %v", err)
+	}
+
+	a, _ = EmptyAnnotations().With(TriggerInvokePolicyAnnotation, map[string]interface{}{"type": "signature", "secret_name": "HOOK_KEY"})
+	trigger.Annotations = a
+	if err := trigger.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := TriggerInvokePolicyFromAnnotations(trigger.Annotations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Secret = []byte("key")
+
+	now := time.Unix(1500000000, 0)
+	signature := fmt.Sprintf("t=%d,v1=%x", now.Unix(), SignInvoke([]byte("key"), now.Unix(), []byte("{}")))
+	seen := replays{}
+	for i, expected := range []error{nil, ErrInvokeSignatureReplayed} {
+		req := httptest.NewRequest("POST", "/t/app/hook", strings.NewReader("{}"))
+		req.Header.Set(InvokeSignatureHeader, signature)
+		if err := p.Authorize(req, now, seen); err != expected {
+			t.Fatalf("Request %d: expected %v, got %v", i, expected, err)
+		}
+	}
+	if exp := seen[string(SignInvoke([]byte("key"), now.Unix(), []byte("{}")))]; !exp.Equal(now.Add(MaxInvokeSignatureAge)) {
+		t.Fatalf("Expected the signature to be remembered until it is too old, got %v", exp)
+	}
+}
+
+func TestRedactInvokePolicies(t *testing.T) {
+	a, _ := EmptyAnnotations().With(FnInvokePolicyAnnotation, map[string]interface{}{"type": "signature", "secret": "key"})
+	a, _ = a.With(TriggerInvokePolicyAnnotation, map[string]interface{}{"type": "signature", "secret_name": "HOOK_KEY"})
+	a, _ = a.With("other", map[string]interface{}{"secret": "kept"})
+
+	redacted := RedactInvokePolicies(a)
+	if v, _ := redacted.Get(FnInvokePolicyAnnotation); string(v) != `{"type":"signature"}` {
+		t.Errorf("Expected the plaintext secret to be redacted, got %s", v)
+	}
+	if v, _ := redacted.Get(TriggerInvokePolicyAnnotation); string(v) != `{"secret_name":"HOOK_KEY","type":"signature"}` {
+		t.Errorf("Expected the name of the secret to be kept, got %s", v)
+	}
+	if v, _ := redacted.Get("other"); string(v) != `{"secret":"kept"}` {
+		t.Errorf("Expected other annotations to be kept, got %s", v)
+	}
+	if v, _ := a.Get(FnInvokePolicyAnnotation); !strings.Contains(string(v), "key") {
+		t.Errorf("Expected the annotations to be left as they were, got %s", v)
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FnIOLimitAnnotation throttles the disk I/O of the containers of a fn on the
+// block devices configured on agents, its value is:
+//
+//	{"read_bps": 10485760, "write_bps": 10485760, "read_iops": 100, "write_iops": 100}
+//
+// read_bps and write_bps are in bytes per second. Each is unlimited if 0.
+const FnIOLimitAnnotation = "fnproject.io/fn/ioLimit"
+
+// FnEgressLimitAnnotation limits the egress bandwidth of each container of a
+// fn to a number of bytes per second, so that a fn cannot saturate the network
+// of an agent. Unlimited if 0.
+const FnEgressLimitAnnotation = "fnproject.io/fn/egressLimit"
+
+var (
+	ErrFnsInvalidIOLimit = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid io limit annotation %s, read_bps, write_bps, read_iops and write_iops must not be negative", FnIOLimitAnnotation),
+	}
+	ErrFnsInvalidEgressLimit = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid egress limit annotation %s, must be a positive integer number of bytes per second", FnEgressLimitAnnotation),
+	}
+)
+
+// IOLimit is the disk I/O a container may do per device
+type IOLimit struct {
+	ReadBps   uint64 `json:"read_bps,omitempty"`
+	WriteBps  uint64 `json:"write_bps,omitempty"`
+	ReadIOPS  uint64 `json:"read_iops,omitempty"`
+	WriteIOPS uint64 `json:"write_iops,omitempty"`
+}
+
+// IOLimitFromAnnotations returns the disk I/O limit of a fn, nil if not set
+func IOLimitFromAnnotations(annotations Annotations) (*IOLimit, error) {
+	v, ok := annotations.Get(FnIOLimitAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	l := &IOLimit{}
+	if err := json.Unmarshal(v, l); err != nil {
+		return nil, ErrFnsInvalidIOLimit
+	}
+	return l, nil
+}
+
+// EgressLimitFromAnnotations returns the egress bandwidth limit of a fn in
+// bytes per second, 0 if not set
+func EgressLimitFromAnnotations(annotations Annotations) (uint64, error) {
+	v, ok := annotations.Get(FnEgressLimitAnnotation)
+	if !ok {
+		return 0, nil
+	}
+	var rate uint64
+	if err := json.Unmarshal(v, &rate); err != nil {
+		return 0, ErrFnsInvalidEgressLimit
+	}
+	return rate, nil
+}
@@ -1,8 +1,13 @@
 package models
 
 import (
+	"bufio"
 	"context"
+	"encoding/base64"
 	"io"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
 )
 
 type LogStore interface {
@@ -35,3 +40,131 @@ type LogStore interface {
 	// Close is not safe to be called from multiple threads.
 	io.Closer
 }
+
+// IsCancelledCall returns whether the queued call was cancelled, calls are only
+// stored once they ran unless they were cancelled before.
+func IsCancelledCall(ctx context.Context, ls LogStore, call *Call) (bool, error) {
+	if ls == nil {
+		return false, nil
+	}
+	stored, err := ls.GetCall(ctx, call.FnID, call.ID)
+	if err == ErrCallNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return stored.Status == "cancelled", nil
+}
+
+// CallCompleter may be implemented by a LogStore to complete queued calls
+// exactly once.
+type CallCompleter interface {
+	// CompleteCall inserts call and its log in one transaction, or returns
+	// ErrCallAlreadyCompleted if the call was stored already.
+	CompleteCall(ctx context.Context, call *Call, callLog io.Reader) error
+}
+
+// LogFilter is the filter used for searching call logs of a fn
+type LogFilter struct {
+	FnID     string // match
+	FromTime common.DateTime
+	ToTime   common.DateTime
+	// Contains matches log lines containing this substring, empty matches any log
+	Contains string
+	Cursor   string
+	PerPage  int
+}
+
+// LogMatch holds the matching lines of a single call log
+type LogMatch struct {
+	CallID    string          `json:"call_id"`
+	CreatedAt common.DateTime `json:"created_at"`
+	Lines     []string        `json:"lines"`
+}
+
+type LogMatchList struct {
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Items      []*LogMatch `json:"items"`
+}
+
+// LogSearcher may be implemented by a LogStore that can search logs natively.
+type LogSearcher interface {
+	// SearchLogs returns the logs of calls of a fn that satisfy the filter, newest first.
+	SearchLogs(ctx context.Context, filter *LogFilter) (*LogMatchList, error)
+}
+
+// maxLogScanPages bounds the number of call pages a search without native
+// support reads per request
+const maxLogScanPages = 10
+
+// SearchLogs searches the logs of a store, natively if the store implements
+// LogSearcher, otherwise by walking the calls of the fn and reading their logs.
+func SearchLogs(ctx context.Context, ls LogStore, filter *LogFilter) (*LogMatchList, error) {
+	if s, ok := ls.(LogSearcher); ok {
+		return s.SearchLogs(ctx, filter)
+	}
+
+	res := &LogMatchList{Items: []*LogMatch{}}
+	cf := CallFilter{
+		FnID:     filter.FnID,
+		FromTime: filter.FromTime,
+		ToTime:   filter.ToTime,
+		Cursor:   filter.Cursor,
+		PerPage:  filter.PerPage,
+	}
+
+	for page := 0; page < maxLogScanPages; page++ {
+		calls, err := ls.GetCalls(ctx, &cf)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, call := range calls.Items {
+			r, err := ls.GetLog(ctx, call.FnID, call.ID)
+			if err == ErrCallLogNotFound {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			if m := MatchLog(call, r, filter.Contains); m != nil {
+				res.Items = append(res.Items, m)
+			}
+
+			// resume after the last call we looked at
+			if len(res.Items) >= filter.PerPage {
+				if i < len(calls.Items)-1 || calls.NextCursor != "" {
+					res.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(call.ID))
+				}
+				return res, nil
+			}
+		}
+
+		res.NextCursor = calls.NextCursor
+		if calls.NextCursor == "" {
+			return res, nil
+		}
+		cf.Cursor = calls.NextCursor
+	}
+
+	return res, nil
+}
+
+// MatchLog returns the lines of a call log containing a substring, or nil
+// if no line matches. An empty substring matches all lines.
+func MatchLog(call *Call, r io.Reader, contains string) *LogMatch {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.Contains(line, contains) {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	return &LogMatch{CallID: call.ID, CreatedAt: call.CreatedAt, Lines: lines}
+}
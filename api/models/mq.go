@@ -3,6 +3,7 @@ package models
 import (
 	"context"
 	"io"
+	"time"
 )
 
 // Message Queue is used to impose a total ordering on jobs that it will
@@ -57,3 +58,35 @@ type MessageQueue interface {
 	// Close is not safe to be called from multiple threads.
 	io.Closer
 }
+
+// ReservationKeeper may be implemented by a MessageQueue that keeps the
+// reservation of a call alive for as long as the server that reserved it is
+// up, instead of timing it out. Calls of such queues are only deleted once they
+// finished, so that they are delivered again if the server running them dies.
+type ReservationKeeper interface {
+	KeepsReservations() bool
+}
+
+// KeepsReservations reports whether mq keeps reservations of running calls alive
+func KeepsReservations(mq MessageQueue) bool {
+	k, ok := mq.(ReservationKeeper)
+	return ok && k.KeepsReservations()
+}
+
+// CallLeaser may be implemented by a MessageQueue that leases the calls it
+// reserves, for exactly-once completion of async calls. Reserve returns calls
+// with a LeaseID, and calls whose lease expires go back to the queue. Delete
+// and RenewLease fail with ErrCallLeaseExpired for calls whose lease expired,
+// as the call may be leased to another server by then.
+type CallLeaser interface {
+	LeasesCalls() bool
+
+	// RenewLease extends the lease of call to d from now.
+	RenewLease(ctx context.Context, call *Call, d time.Duration) error
+}
+
+// LeasesCalls reports whether mq leases the calls it reserves
+func LeasesCalls(mq MessageQueue) bool {
+	l, ok := mq.(CallLeaser)
+	return ok && l.LeasesCalls()
+}
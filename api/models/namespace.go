@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// NamespaceHeader is the request header API clients set to the id of the
+// namespace their requests are scoped to
+const NamespaceHeader = "Fn-Namespace-Id"
+
+var (
+	ErrNamespacesMissingID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing namespace ID"),
+	}
+	ErrNamespaceIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Namespace ID cannot be supplied on create"),
+	}
+	ErrNamespacesIDMismatch = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Namespace ID in path does not match ID in body"),
+	}
+	ErrNamespacesMissingNew = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing new namespace"),
+	}
+	ErrNamespacesTooLongName = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Namespace name must be %v characters or less", maxAppName),
+	}
+	ErrNamespacesInvalidName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid namespace name"),
+	}
+	ErrNamespacesAlreadyExists = err{
+		code:  http.StatusConflict,
+		error: errors.New("Namespace already exists"),
+	}
+	ErrNamespacesNameImmutable = err{
+		code:  http.StatusConflict,
+		error: errors.New("Could not update - name is immutable"),
+	}
+	ErrNamespacesNotEmpty = err{
+		code:  http.StatusConflict,
+		error: errors.New("Namespace still has apps"),
+	}
+	ErrNamespacesNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Namespace not found"),
+	}
+	ErrNamespacesUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Namespaces are not supported by the datastore"),
+	}
+	ErrNamespacesScoped = err{
+		code:  http.StatusForbidden,
+		error: errors.New("Namespaces cannot be managed from within a namespace"),
+	}
+	ErrAppsNamespaceImmutable = err{
+		code:  http.StatusConflict,
+		error: errors.New("Could not update - namespace is immutable"),
+	}
+)
+
+// Namespace is a tenant of a deployment, which owns a set of apps. Apps
+// without a namespace belong to no tenant and are only visible to requests
+// that are not scoped to a namespace.
+type Namespace struct {
+	ID          string          `json:"id" db:"id"`
+	Name        string          `json:"name" db:"name"`
+	Annotations Annotations     `json:"annotations,omitempty" db:"annotations"`
+	CreatedAt   common.DateTime `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt   common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+func (n *Namespace) Validate() error {
+	if n.Name == "" {
+		return ErrMissingName
+	}
+	if len(n.Name) > maxAppName {
+		return ErrNamespacesTooLongName
+	}
+	for _, c := range n.Name {
+		if !(unicode.IsLetter(c) || unicode.IsNumber(c) || c == '_' || c == '-') {
+			return ErrNamespacesInvalidName
+		}
+	}
+	return n.Annotations.Validate()
+}
+
+func (n *Namespace) Clone() *Namespace {
+	clone := new(Namespace)
+	*clone = *n // shallow copy, annotations are replaced on change
+	return clone
+}
+
+func (n1 *Namespace) Equals(n2 *Namespace) bool {
+	eq := true
+	eq = eq && n1.ID == n2.ID
+	eq = eq && n1.Name == n2.Name
+	eq = eq && n1.Annotations.Equals(n2.Annotations)
+	return eq
+}
+
+// Update merges the annotations of patch into n.
+func (n *Namespace) Update(patch *Namespace) {
+	original := n.Clone()
+
+	n.Annotations = n.Annotations.MergeChange(patch.Annotations)
+
+	if !n.Equals(original) {
+		n.UpdatedAt = common.DateTime(time.Now())
+	}
+}
+
+type NamespaceFilter struct {
+	Name    string
+	PerPage int
+	Cursor  string
+}
+
+type NamespaceList struct {
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Items      []*Namespace `json:"items"`
+}
+
+// NamespaceStore may be implemented by a Datastore to store namespaces. Apps
+// refer to their namespace by id.
+type NamespaceStore interface {
+	// InsertNamespace inserts a namespace, returning ErrNamespacesAlreadyExists if
+	// one with the same name exists.
+	InsertNamespace(ctx context.Context, ns *Namespace) (*Namespace, error)
+
+	// UpdateNamespace updates the annotations of a namespace.
+	UpdateNamespace(ctx context.Context, ns *Namespace) (*Namespace, error)
+
+	// GetNamespaceByID returns a namespace, or ErrNamespacesNotFound.
+	GetNamespaceByID(ctx context.Context, nsID string) (*Namespace, error)
+
+	// GetNamespaces returns the namespaces matching filter ordered by name.
+	GetNamespaces(ctx context.Context, filter *NamespaceFilter) (*NamespaceList, error)
+
+	// RemoveNamespace removes a namespace, which must not have apps left, or
+	// returns ErrNamespacesNotEmpty.
+	RemoveNamespace(ctx context.Context, nsID string) error
+}
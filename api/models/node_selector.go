@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FnNodeSelectorAnnotation places the calls of a fn only on the runners
+// whose labels have all of its key/value pairs, see FN_RUNNER_LABELS. Its
+// value is:
+//
+//	{"arch": "arm64", "gpu": "true"}
+const FnNodeSelectorAnnotation = "fnproject.io/fn/nodeSelector"
+
+var ErrFnsInvalidNodeSelector = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid node selector annotation %s, must map label names to string values", FnNodeSelectorAnnotation),
+}
+
+// NodeSelectorFromAnnotations returns the labels runners must have to run
+// calls, nil if not set
+func NodeSelectorFromAnnotations(annotations Annotations) (map[string]string, error) {
+	v, ok := annotations.Get(FnNodeSelectorAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var selector map[string]string
+	if err := json.Unmarshal(v, &selector); err != nil {
+		return nil, ErrFnsInvalidNodeSelector
+	}
+	for k := range selector {
+		if k == "" {
+			return nil, ErrFnsInvalidNodeSelector
+		}
+	}
+	return selector, nil
+}
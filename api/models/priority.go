@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FnPriorityAnnotation is the priority of the calls of a fn, see Call.Priority,
+// from 0 to MaxPriority. On a runner at capacity, calls may evict idle
+// containers of fns of the same or lower priority to start their own, frozen
+// containers of the lowest priority first, while lower priority calls wait.
+const FnPriorityAnnotation = "fnproject.io/fn/priority"
+
+// MaxPriority is the highest priority of calls
+const MaxPriority int32 = 2
+
+var ErrFnsInvalidPriority = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid priority annotation %s, must be an integer between 0 and %d", FnPriorityAnnotation, MaxPriority),
+}
+
+// PriorityFromAnnotations returns the priority of calls selected by
+// annotations, 0 if not set
+func PriorityFromAnnotations(annotations Annotations) (int32, error) {
+	v, ok := annotations.Get(FnPriorityAnnotation)
+	if !ok {
+		return 0, nil
+	}
+	var p int32
+	if err := json.Unmarshal(v, &p); err != nil || p < 0 || p > MaxPriority {
+		return 0, ErrFnsInvalidPriority
+	}
+	return p, nil
+}
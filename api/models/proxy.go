@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// AppProxyAnnotation replaces the HTTP(S) proxies agents inject into the
+// containers of an app, its value is:
+//
+//	{"http_proxy": "http://proxy:3128", "https_proxy": "http://proxy:3128", "no_proxy": "localhost,.corp"}
+//
+// Unset proxies are not injected, {} disables the proxies for an app.
+const AppProxyAnnotation = "fnproject.io/app/proxy"
+
+var ErrAppsInvalidProxy = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid proxy annotation %s, http_proxy and https_proxy must be absolute urls", AppProxyAnnotation),
+}
+
+// ProxyConfig is the HTTP(S) proxies of a container
+type ProxyConfig struct {
+	HTTPProxy  string `json:"http_proxy,omitempty"`
+	HTTPSProxy string `json:"https_proxy,omitempty"`
+	NoProxy    string `json:"no_proxy,omitempty"`
+}
+
+// ProxyFromAnnotations returns the proxies of the containers of an app, nil
+// if not set
+func ProxyFromAnnotations(annotations Annotations) (*ProxyConfig, error) {
+	v, ok := annotations.Get(AppProxyAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	proxy := &ProxyConfig{}
+	if err := json.Unmarshal(v, proxy); err != nil {
+		return nil, ErrAppsInvalidProxy
+	}
+	for _, p := range []string{proxy.HTTPProxy, proxy.HTTPSProxy} {
+		if p == "" {
+			continue
+		}
+		if u, err := url.Parse(p); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, ErrAppsInvalidProxy
+		}
+	}
+	return proxy, nil
+}
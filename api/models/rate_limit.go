@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// AppRateLimitAnnotation and FnRateLimitAnnotation limit the invocations of all
+// fns of an app and of a single fn on a server, their value is:
+//
+//	{"rate": 10, "burst": 20, "max_concurrent": 5}
+//
+// rate is the number of invocations per second, allowing bursts of up to burst
+// invocations, which defaults to rate. max_concurrent is the number of sync
+// invocations which may execute at the same time. Each is unlimited if 0.
+// Invocations over a limit are rejected with 429 Too Many Requests.
+const (
+	AppRateLimitAnnotation = "fnproject.io/app/rateLimit"
+	FnRateLimitAnnotation  = "fnproject.io/fn/rateLimit"
+)
+
+var (
+	ErrAppsInvalidRateLimit = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid rate limit annotation %s, rate, burst and max_concurrent must not be negative", AppRateLimitAnnotation),
+	}
+	ErrFnsInvalidRateLimit = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid rate limit annotation %s, rate, burst and max_concurrent must not be negative", FnRateLimitAnnotation),
+	}
+	ErrRateLimited = err{
+		code:  http.StatusTooManyRequests,
+		error: errors.New("Rate limit exceeded, retry later"),
+	}
+	ErrConcurrencyLimited = err{
+		code:  http.StatusTooManyRequests,
+		error: errors.New("Too many concurrent invocations, retry later"),
+	}
+)
+
+// RateLimit is how many invocations of an app or fn are allowed
+type RateLimit struct {
+	Rate          float64 `json:"rate"`
+	Burst         int     `json:"burst,omitempty"`
+	MaxConcurrent int     `json:"max_concurrent,omitempty"`
+}
+
+// AppRateLimitFromAnnotations returns the rate limit of an app, nil if not set
+func AppRateLimitFromAnnotations(annotations Annotations) (*RateLimit, error) {
+	return rateLimitFromAnnotations(annotations, AppRateLimitAnnotation, ErrAppsInvalidRateLimit)
+}
+
+// FnRateLimitFromAnnotations returns the rate limit of a fn, nil if not set
+func FnRateLimitFromAnnotations(annotations Annotations) (*RateLimit, error) {
+	return rateLimitFromAnnotations(annotations, FnRateLimitAnnotation, ErrFnsInvalidRateLimit)
+}
+
+func rateLimitFromAnnotations(annotations Annotations, key string, invalid error) (*RateLimit, error) {
+	v, ok := annotations.Get(key)
+	if !ok {
+		return nil, nil
+	}
+	l := &RateLimit{}
+	if err := json.Unmarshal(v, l); err != nil || l.Rate < 0 || l.Burst < 0 || l.MaxConcurrent < 0 {
+		return nil, invalid
+	}
+	if l.Burst == 0 && l.Rate > 0 {
+		l.Burst = int(l.Rate)
+		if l.Burst < 1 {
+			l.Burst = 1
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FnResponseCacheAnnotation caches the successful responses of synchronous
+// invocations of a fn, which must not have side effects, its value is:
+//
+//	{"ttl_seconds": 60, "vary": ["Accept", "Authorization"]}
+//
+// Invocations with the same method, url, body and values of the vary headers
+// get the cached response for ttl_seconds without executing the fn.
+// Invocations with a Cache-Control: no-cache header always execute the fn.
+const FnResponseCacheAnnotation = "fnproject.io/fn/responseCache"
+
+// ResponseCacheHeader is set on invoke responses of fns with a response cache
+// to HIT or MISS
+const ResponseCacheHeader = "Fn-Cache"
+
+// MaxResponseCacheTTL is the longest time responses may be cached, 1 day
+const MaxResponseCacheTTL = 24 * 60 * 60
+
+var ErrFnsInvalidResponseCache = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid response cache annotation %s, ttl_seconds must be between 1 and %d and vary a list of header names", FnResponseCacheAnnotation, MaxResponseCacheTTL),
+}
+
+// ResponseCachePolicy is how the responses of a fn are cached
+type ResponseCachePolicy struct {
+	TTLSeconds int      `json:"ttl_seconds"`
+	Vary       []string `json:"vary,omitempty"`
+}
+
+// ResponseCacheFromAnnotations returns the response cache policy of a fn, nil
+// if its responses are not cached.
+func ResponseCacheFromAnnotations(annotations Annotations) (*ResponseCachePolicy, error) {
+	v, ok := annotations.Get(FnResponseCacheAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	p := &ResponseCachePolicy{}
+	if err := json.Unmarshal(v, p); err != nil || p.TTLSeconds < 1 || p.TTLSeconds > MaxResponseCacheTTL {
+		return nil, ErrFnsInvalidResponseCache
+	}
+	for i, h := range p.Vary {
+		if h == "" {
+			return nil, ErrFnsInvalidResponseCache
+		}
+		p.Vary[i] = http.CanonicalHeaderKey(h)
+	}
+	return p, nil
+}
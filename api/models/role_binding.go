@@ -0,0 +1,117 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// predefined roles role bindings may grant, see auth.RoleScopes
+const (
+	// RoleAdmin manages everything, including namespaces and role bindings
+	RoleAdmin = "admin"
+	// RoleDeveloper manages and invokes apps, fns, triggers and secrets
+	RoleDeveloper = "developer"
+	// RoleInvoker invokes fns
+	RoleInvoker = "invoker"
+	// RoleViewer reads apps, fns, triggers, calls and logs
+	RoleViewer = "viewer"
+)
+
+var (
+	ErrRoleBindingsNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Role binding not found"),
+	}
+	ErrRoleBindingsUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Role bindings are not supported by the datastore"),
+	}
+	ErrRoleBindingsMissingSubject = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing role binding subject"),
+	}
+	ErrRoleBindingsInvalidRole = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid role, must be one of admin, developer, invoker or viewer"),
+	}
+	ErrRoleBindingIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Role binding ID cannot be supplied on create"),
+	}
+	ErrRoleBindingsAlreadyExists = err{
+		code:  http.StatusConflict,
+		error: errors.New("Role binding already exists"),
+	}
+)
+
+// RoleBinding grants a role to the subject of authenticated requests on an app,
+// on the apps of a namespace, or on everything if bound to neither.
+type RoleBinding struct {
+	ID      string `json:"id" db:"id"`
+	Subject string `json:"subject" db:"subject"`
+	Role    string `json:"role" db:"role"`
+	// NamespaceID is the namespace of the binding, or of its app
+	NamespaceID string          `json:"namespace_id,omitempty" db:"namespace_id"`
+	AppID       string          `json:"app_id,omitempty" db:"app_id"`
+	CreatedAt   common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+func (b *RoleBinding) Validate() error {
+	if b.Subject == "" {
+		return ErrRoleBindingsMissingSubject
+	}
+	switch b.Role {
+	case RoleAdmin, RoleDeveloper, RoleInvoker, RoleViewer:
+		return nil
+	}
+	return ErrRoleBindingsInvalidRole
+}
+
+// Covers returns whether the binding applies to the app appID of namespace
+// nsID, or to the namespace itself if appID is empty. Bindings to an app only
+// cover their app.
+func (b *RoleBinding) Covers(nsID, appID string) bool {
+	switch {
+	case b.AppID != "":
+		return appID != "" && b.AppID == appID
+	case b.NamespaceID != "":
+		return b.NamespaceID == nsID
+	}
+	return true
+}
+
+type RoleBindingFilter struct {
+	Subject     string //match
+	NamespaceID string //match
+	AppID       string //match
+	Cursor      string
+	PerPage     int
+}
+
+type RoleBindingList struct {
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Items      []*RoleBinding `json:"items"`
+}
+
+// RoleBindingStore may be implemented by a Datastore to store role bindings,
+// which are removed with their app or namespace.
+type RoleBindingStore interface {
+	// InsertRoleBinding inserts a role binding, returning
+	// ErrRoleBindingsAlreadyExists if the subject has the same role on the same
+	// app or namespace.
+	InsertRoleBinding(ctx context.Context, rb *RoleBinding) (*RoleBinding, error)
+
+	// GetRoleBindingByID returns a role binding, or ErrRoleBindingsNotFound.
+	GetRoleBindingByID(ctx context.Context, rbID string) (*RoleBinding, error)
+
+	// GetRoleBindings returns the role bindings matching filter in the order
+	// they were made, all of them if filter.PerPage is 0.
+	GetRoleBindings(ctx context.Context, filter *RoleBindingFilter) (*RoleBindingList, error)
+
+	// RemoveRoleBinding removes a role binding, or returns
+	// ErrRoleBindingsNotFound.
+	RemoveRoleBinding(ctx context.Context, rbID string) error
+}
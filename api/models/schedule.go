@@ -0,0 +1,176 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FnScheduleAnnotation makes a fn run periodically, its value is an object
+// {"cron": "<minute> <hour> <day of month> <month> <day of week>", "payload": "..."}
+// with the schedule in UTC and an optional request body for the calls.
+const FnScheduleAnnotation = "fnproject.io/fn/schedule"
+
+var (
+	ErrFnsInvalidSchedule = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid schedule annotation %s, must be an object with a 5 field cron expression", FnScheduleAnnotation),
+	}
+)
+
+// FnSchedule is the periodic execution of a fn
+type FnSchedule struct {
+	Cron    string `json:"cron"`
+	Payload string `json:"payload,omitempty"`
+
+	schedule *CronSchedule
+}
+
+// Next returns the first time after t the fn should run
+func (s *FnSchedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t)
+}
+
+// ScheduleFromAnnotations returns the schedule of a fn, ok is false if the fn
+// is not scheduled.
+func ScheduleFromAnnotations(annotations Annotations) (s *FnSchedule, ok bool, err error) {
+	v, ok := annotations.Get(FnScheduleAnnotation)
+	if !ok {
+		return nil, false, nil
+	}
+	s = &FnSchedule{}
+	if err := json.Unmarshal(v, s); err != nil {
+		return nil, false, ErrFnsInvalidSchedule
+	}
+	s.schedule, err = ParseCron(s.Cron)
+	if err != nil {
+		return nil, false, ErrFnsInvalidSchedule
+	}
+	return s, true, nil
+}
+
+// CronSchedule is a parsed cron expression, one bit per allowed value of each field
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// day of month and day of week match either when both are restricted
+	anyDom, anyDow bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = [...]cronField{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// ParseCron parses a 5 field cron expression. Fields may be *, a value, a range
+// a-b, a list of those separated by commas, each optionally followed by a /step.
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", expr, len(cronFields))
+	}
+
+	var bits [len(cronFields)]uint64
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %v", expr, err)
+		}
+		bits[i] = b
+	}
+
+	// both 0 and 7 are sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &CronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		lo, hi := bounds.min, bounds.max
+		if part != "*" {
+			var err error
+			bound := strings.SplitN(part, "-", 2)
+			if lo, err = strconv.Atoi(bound[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bound) == 2 {
+				if hi, err = strconv.Atoi(bound[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				hi = bounds.max
+			}
+		}
+		if lo < bounds.min || hi > bounds.max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, bounds.min, bounds.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.anyDom && s.anyDow:
+		return true
+	case s.anyDom:
+		return dow
+	case s.anyDow:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first time strictly after t matching the schedule, in UTC.
+// It returns the zero time if nothing matches in the next 5 years, eg. for the 31st of February.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
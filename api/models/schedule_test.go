@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("Expected error parsing %q", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2018, 11, 14, 10, 30, 15, 0, time.UTC) // a wednesday
+
+	for _, tc := range []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2018, 11, 14, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2018, 11, 14, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2018, 11, 14, 11, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2018, 11, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2018, 11, 14, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2018, 11, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2018, 11, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2018, 11, 16, 0, 0, 0, 0, time.UTC)}, // friday or the 13th
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	} {
+		s, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tc.expr, err)
+		}
+		if next := s.Next(from); !next.Equal(tc.next) {
+			t.Errorf("Expected next run of %q at %v got %v", tc.expr, tc.next, next)
+		}
+	}
+}
+
+func TestScheduleFromAnnotations(t *testing.T) {
+	a, _ := EmptyAnnotations().With(FnScheduleAnnotation, map[string]string{"cron": "*/5 * * * *", "payload": "{}"})
+	s, ok, err := ScheduleFromAnnotations(a)
+	if err != nil || !ok || s.Payload != "{}" {
+		t.Fatalf("Unexpected schedule %v %v %v", s, ok, err)
+	}
+
+	a, _ = EmptyAnnotations().With(FnScheduleAnnotation, map[string]string{"cron": "every minute"})
+	if _, _, err := ScheduleFromAnnotations(a); err != ErrFnsInvalidSchedule {
+		t.Fatalf("Expected invalid schedule error got %v", err)
+	}
+}
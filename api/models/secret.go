@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+const (
+	// SecretMountEnv injects a secret into the environment of the containers of
+	// the fns of its app, under its name
+	SecretMountEnv = "env"
+	// SecretMountFile writes a secret to a file named after it in
+	// SecretsMountPath of the containers of the fns of its app
+	SecretMountFile = "file"
+
+	// SecretsMountPath is the directory of containers file secrets are mounted in
+	SecretsMountPath = "/run/secrets"
+
+	maxSecretName  = 255
+	maxSecretValue = 64 * 1024
+)
+
+var (
+	ErrSecretsNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Secret not found"),
+	}
+	ErrSecretsInvalidName = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid secret name, secret names must be %v characters or less and may only contain letters, digits and underscores, not starting with a digit", maxSecretName),
+	}
+	ErrSecretsMissingValue = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing secret value"),
+	}
+	ErrSecretsValueTooLong = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Secret values must be %v bytes or less", maxSecretValue),
+	}
+	ErrSecretsInvalidMount = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid secret mount, must be one of %s or %s", SecretMountEnv, SecretMountFile),
+	}
+	ErrSecretsUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Secrets are not supported by the datastore"),
+	}
+	ErrSecretsNoKMS = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Secrets require a key management service to be configured"),
+	}
+)
+
+// Secret is a value made available to the fns of an app at runtime, in their
+// environment or as a file. Values are only ever accepted, they are stored
+// encrypted and never returned by the API.
+type Secret struct {
+	Name      string          `json:"name"`
+	Value     string          `json:"value,omitempty"`
+	Mount     string          `json:"mount,omitempty"`
+	CreatedAt common.DateTime `json:"created_at,omitempty"`
+	UpdatedAt common.DateTime `json:"updated_at,omitempty"`
+}
+
+// Validate checks the name, value and mount of s, defaulting its mount to
+// SecretMountEnv.
+func (s *Secret) Validate() error {
+	if !validSecretName(s.Name) {
+		return ErrSecretsInvalidName
+	}
+	if s.Value == "" {
+		return ErrSecretsMissingValue
+	}
+	if len(s.Value) > maxSecretValue {
+		return ErrSecretsValueTooLong
+	}
+	switch s.Mount {
+	case "":
+		s.Mount = SecretMountEnv
+	case SecretMountEnv, SecretMountFile:
+	default:
+		return ErrSecretsInvalidMount
+	}
+	return nil
+}
+
+// validSecretName checks name is usable as both an environment variable and a
+// file name
+func validSecretName(name string) bool {
+	if name == "" || len(name) > maxSecretName {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// StoredSecret is a secret as kept by a SecretStore, its value encrypted.
+type StoredSecret struct {
+	AppID      string          `json:"app_id" db:"app_id"`
+	Name       string          `json:"name" db:"name"`
+	Mount      string          `json:"mount" db:"mount"`
+	Ciphertext []byte          `json:"ciphertext" db:"ciphertext"`
+	CreatedAt  common.DateTime `json:"created_at" db:"created_at"`
+	UpdatedAt  common.DateTime `json:"updated_at" db:"updated_at"`
+}
+
+func (s *StoredSecret) Validate() error {
+	if s.AppID == "" {
+		return ErrAppsMissingID
+	}
+	if !validSecretName(s.Name) {
+		return ErrSecretsInvalidName
+	}
+	if s.Mount != SecretMountEnv && s.Mount != SecretMountFile {
+		return ErrSecretsInvalidMount
+	}
+	if len(s.Ciphertext) == 0 {
+		return ErrSecretsMissingValue
+	}
+	return nil
+}
+
+// SecretStore may be implemented by a Datastore to store the encrypted secrets
+// of apps, which are removed with their app.
+type SecretStore interface {
+	// PutSecret inserts or replaces the secret of an app with the same name,
+	// returning ErrAppsNotFound if the app does not exist.
+	PutSecret(ctx context.Context, secret *StoredSecret) (*StoredSecret, error)
+
+	// GetSecrets returns the secrets of an app ordered by name.
+	GetSecrets(ctx context.Context, appID string) ([]*StoredSecret, error)
+
+	// RemoveSecret removes a secret of an app, or returns ErrSecretsNotFound.
+	RemoveSecret(ctx context.Context, appID, name string) error
+}
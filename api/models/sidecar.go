@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// AppSidecarAnnotation declares containers agents run with each hot container
+// of an app, in the network namespace they share with it, e.g. a secrets
+// fetcher or a local cache. Its value is:
+//
+//	{"init": {"image": "busybox", "cmd": "sh /scripts/register.sh"},
+//	 "sidecar": {"image": "redis:alpine", "env": {"MAXMEMORY": "32mb"}, "memory": 64}}
+//
+// init runs to completion before the fn container is created, failing the
+// container if it exits with a non zero status. sidecar is started before the
+// fn container and removed after it. memory is in MB, it is not reserved by
+// agents. Either may be omitted.
+const AppSidecarAnnotation = "fnproject.io/app/sidecar"
+
+var ErrAppsInvalidSidecar = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid sidecar annotation %s, init and sidecar must have an image", AppSidecarAnnotation),
+}
+
+// CompanionContainer is a container run with the containers of a fn
+type CompanionContainer struct {
+	Image  string            `json:"image"`
+	Cmd    string            `json:"cmd,omitempty"`
+	Env    map[string]string `json:"env,omitempty"`
+	Memory uint64            `json:"memory,omitempty"`
+}
+
+// SidecarConfig is the containers run with the containers of a fn
+type SidecarConfig struct {
+	Init    *CompanionContainer `json:"init,omitempty"`
+	Sidecar *CompanionContainer `json:"sidecar,omitempty"`
+}
+
+// SidecarFromAnnotations returns the containers run with the containers of an
+// app, nil if not set
+func SidecarFromAnnotations(annotations Annotations) (*SidecarConfig, error) {
+	v, ok := annotations.Get(AppSidecarAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	s := &SidecarConfig{}
+	if err := json.Unmarshal(v, s); err != nil {
+		return nil, ErrAppsInvalidSidecar
+	}
+	for _, c := range []*CompanionContainer{s.Init, s.Sidecar} {
+		if c != nil && c.Image == "" {
+			return nil, ErrAppsInvalidSidecar
+		}
+	}
+	return s, nil
+}
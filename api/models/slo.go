@@ -0,0 +1,137 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// FnSLOAnnotation is the service level objective of a fn, the share of its
+// calls which must succeed and the share which must complete within a latency,
+// over a rolling window. Its value is:
+//
+//	{"availability": 0.999, "latency_msecs": 300, "latency_target": 0.99, "window_secs": 3600}
+//
+// Either objective may be left out, latency_target is 0.99 when only
+// latency_msecs is set and the window an hour by default. The latency of a
+// call is from its creation to its completion, including the time it queued.
+const FnSLOAnnotation = "fnproject.io/fn/slo"
+
+const (
+	// DefaultSLOWindow is the rolling window of SLOs in seconds by default
+	DefaultSLOWindow = 3600
+	// MaxSLOWindow is the longest rolling window of SLOs in seconds
+	MaxSLOWindow = 86400
+	// DefaultSLOLatencyTarget is the share of calls which must be fast enough
+	// when a SLO only sets a latency
+	DefaultSLOLatencyTarget = 0.99
+)
+
+var (
+	ErrFnsInvalidSLO = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("invalid slo annotation %s, availability and latency_target must be between 0 and 1 exclusive, latency_msecs positive and window_secs between 60 and %d", FnSLOAnnotation, MaxSLOWindow),
+	}
+	ErrFnsMissingSLO = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Fn has no slo annotation"),
+	}
+)
+
+// SLO is the service level objective of a fn, see FnSLOAnnotation
+type SLO struct {
+	Availability  float64 `json:"availability,omitempty"`
+	LatencyMsecs  int64   `json:"latency_msecs,omitempty"`
+	LatencyTarget float64 `json:"latency_target,omitempty"`
+	WindowSecs    int64   `json:"window_secs,omitempty"`
+}
+
+// SLOFromAnnotations returns the SLO of a fn with its defaults set, nil if not
+// set
+func SLOFromAnnotations(annotations Annotations) (*SLO, error) {
+	v, ok := annotations.Get(FnSLOAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var slo SLO
+	if err := json.Unmarshal(v, &slo); err != nil {
+		return nil, ErrFnsInvalidSLO
+	}
+	if slo.LatencyMsecs > 0 && slo.LatencyTarget == 0 {
+		slo.LatencyTarget = DefaultSLOLatencyTarget
+	}
+	if slo.WindowSecs == 0 {
+		slo.WindowSecs = DefaultSLOWindow
+	}
+	if slo.Availability < 0 || slo.Availability >= 1 ||
+		slo.LatencyTarget < 0 || slo.LatencyTarget >= 1 ||
+		slo.LatencyMsecs < 0 || (slo.LatencyTarget > 0 && slo.LatencyMsecs == 0) ||
+		(slo.Availability == 0 && slo.LatencyMsecs == 0) ||
+		slo.WindowSecs < 60 || slo.WindowSecs > MaxSLOWindow {
+		return nil, ErrFnsInvalidSLO
+	}
+	return &slo, nil
+}
+
+// SLOStatus is the compliance of the calls of a fn with its SLO over its
+// rolling window. Compliance and the budgets are 1 without calls.
+type SLOStatus struct {
+	FnID string `json:"fn_id"`
+	SLO  *SLO   `json:"slo"`
+	// Calls is the number of calls completed, Failures those which failed or
+	// timed out and Slow those which succeeded slower than the latency of the
+	// SLO. Cancelled calls are not counted.
+	Calls    int64 `json:"calls"`
+	Failures int64 `json:"failures"`
+	Slow     int64 `json:"slow"`
+	// Availability is the share of calls which succeeded
+	Availability float64 `json:"availability"`
+	// LatencyCompliance is the share of calls which succeeded which were fast
+	// enough
+	LatencyCompliance float64 `json:"latency_compliance"`
+	// AvailabilityBudget and LatencyBudget are the shares of the error budgets
+	// of the objectives left, the failures or slow calls the SLO allows which
+	// did not happen. They are negative once the objective is missed.
+	AvailabilityBudget *float64 `json:"availability_budget_remaining,omitempty"`
+	LatencyBudget      *float64 `json:"latency_budget_remaining,omitempty"`
+	// ErrorBudgetRemaining is the smallest budget left of the objectives
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+}
+
+// NewSLOStatus returns the status of a fn which completed calls calls, of
+// which failures failed and slow were slower than the latency of slo
+func NewSLOStatus(fnID string, slo *SLO, calls, failures, slow int64) *SLOStatus {
+	s := &SLOStatus{
+		FnID:              fnID,
+		SLO:               slo,
+		Calls:             calls,
+		Failures:          failures,
+		Slow:              slow,
+		Availability:      1,
+		LatencyCompliance: 1,
+	}
+	if calls > 0 {
+		s.Availability = float64(calls-failures) / float64(calls)
+	}
+	if succeeded := calls - failures; succeeded > 0 {
+		s.LatencyCompliance = float64(succeeded-slow) / float64(succeeded)
+	}
+
+	budget := func(compliance, target float64) *float64 {
+		b := 1 - (1-compliance)/(1-target)
+		return &b
+	}
+	s.ErrorBudgetRemaining = 1
+	if slo.Availability > 0 {
+		s.AvailabilityBudget = budget(s.Availability, slo.Availability)
+		s.ErrorBudgetRemaining = *s.AvailabilityBudget
+	}
+	if slo.LatencyMsecs > 0 {
+		s.LatencyBudget = budget(s.LatencyCompliance, slo.LatencyTarget)
+		if *s.LatencyBudget < s.ErrorBudgetRemaining {
+			s.ErrorBudgetRemaining = *s.LatencyBudget
+		}
+	}
+	return s
+}
@@ -60,9 +60,27 @@ func (t *Trigger) EqualsWithAnnotationSubset(t2 *Trigger) bool {
 //TriggerTypeHTTP represents an HTTP trigger
 const TriggerTypeHTTP = "http"
 
-var triggerTypes = []string{TriggerTypeHTTP}
+// TriggerTypeCloudEvent represents a binding of the CloudEvents of a type sent to
+// the CloudEvents endpoint of an app, its source is the event type prefixed by /
+const TriggerTypeCloudEvent = "cloudevent"
+
+var triggerTypes = []string{TriggerTypeHTTP, TriggerTypeCloudEvent}
+
+// sourceValidators check the sources of trigger types other than http and
+// cloudevent, whose sources are paths
+var sourceValidators = map[string]func(source string) error{}
+
+// RegisterTriggerType adds a trigger type bound to an event source, whose
+// sources are checked by validate. It must be called before triggers are
+// validated, i.e. in init.
+func RegisterTriggerType(triggerType string, validate func(source string) error) {
+	if !ValidTriggerType(triggerType) {
+		triggerTypes = append(triggerTypes, triggerType)
+	}
+	sourceValidators[triggerType] = validate
+}
 
-//ValidTriggerTypes lists the supported trigger types in this service
+// ValidTriggerTypes lists the supported trigger types in this service
 func ValidTriggerTypes() []string {
 	return triggerTypes
 }
@@ -169,7 +187,11 @@ func (t *Trigger) Validate() error {
 		return ErrTriggerMissingSource
 	}
 
-	if !strings.HasPrefix(t.Source, "/") {
+	if validate, ok := sourceValidators[t.Type]; ok {
+		if err := validate(t.Source); err != nil {
+			return NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid %s trigger source: %v", t.Type, err))
+		}
+	} else if !strings.HasPrefix(t.Source, "/") {
 		return ErrTriggerMissingSourcePrefix
 	}
 
@@ -178,6 +200,14 @@ func (t *Trigger) Validate() error {
 		return err
 	}
 
+	if _, err := CloudEventBindingFromAnnotations(t.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := TriggerInvokePolicyFromAnnotations(t.Annotations); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+const (
+	// DefaultUsageWindow is the window usage is aggregated over by default
+	DefaultUsageWindow = time.Hour
+	// MinUsageWindow is the smallest window usage is aggregated over
+	MinUsageWindow = time.Minute
+	// MaxUsageWindows is the most windows usage is aggregated into at once
+	MaxUsageWindows = 10000
+	// defaultUsageRange is how far back usage is aggregated without from_time
+	defaultUsageRange = 24 * time.Hour
+)
+
+var (
+	ErrUsageUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Usage records are not supported by the log store"),
+	}
+	ErrInvalidUsageRecord = err{
+		code:  http.StatusInternalServerError,
+		error: errors.New("Usage records need an id, an app id and a fn id"),
+	}
+	ErrUsageInvalidWindow = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid usage window, must be a number of seconds of at least 60 and at most 10000 windows between from_time and to_time"),
+	}
+)
+
+// UsageRecord is what a call used, metered to charge the usage of apps back.
+// Memory and CPU are what the call was allocated over its duration, not what
+// it actually used.
+type UsageRecord struct {
+	// ID is the id of the call
+	ID    string `json:"id"`
+	AppID string `json:"app_id"`
+	FnID  string `json:"fn_id"`
+	// DurationMs is how long the call ran, from its start to its completion
+	DurationMs uint64 `json:"duration_ms"`
+	// MemoryMBMs is the memory of the call in MB times its duration
+	MemoryMBMs uint64 `json:"memory_mb_ms"`
+	// CPUMs is the cpus of the call times its duration, a call of 500m
+	// running for a second uses 500 cpu ms
+	CPUMs uint64 `json:"cpu_ms"`
+	// EgressBytes is what the container of the call sent while it ran, if its
+	// driver reports it. Calls shorter than the interval of the stats of their
+	// driver have none.
+	EgressBytes uint64          `json:"egress_bytes,omitempty"`
+	CreatedAt   common.DateTime `json:"created_at"`
+}
+
+// NewUsageRecord returns the usage record of a completed call
+func NewUsageRecord(call *Call) *UsageRecord {
+	r := &UsageRecord{
+		ID:        call.ID,
+		AppID:     call.AppID,
+		FnID:      call.FnID,
+		CreatedAt: call.CompletedAt,
+	}
+	if time.Time(r.CreatedAt).IsZero() {
+		r.CreatedAt = common.DateTime(time.Now())
+	}
+	if started := time.Time(call.StartedAt); !started.IsZero() && time.Time(r.CreatedAt).After(started) {
+		r.DurationMs = uint64(time.Time(r.CreatedAt).Sub(started) / time.Millisecond)
+	}
+	r.MemoryMBMs = call.Memory * r.DurationMs
+	r.CPUMs = uint64(call.CPUs) * r.DurationMs / 1000
+
+	// net_tx of the stats of drivers is what their container sent since it
+	// started, hot containers run calls before this one
+	var first, last uint64
+	var seen bool
+	for _, s := range call.Stats {
+		tx, ok := s.Metrics["net_tx"]
+		if !ok {
+			continue
+		}
+		if !seen || tx < first {
+			first = tx
+		}
+		if tx > last {
+			last = tx
+		}
+		seen = true
+	}
+	if last > first {
+		r.EgressBytes = last - first
+	}
+	return r
+}
+
+// Usage is the usage of an app, or of a fn of the app, over a window of time
+type Usage struct {
+	AppID       string          `json:"app_id"`
+	FnID        string          `json:"fn_id,omitempty"`
+	Start       common.DateTime `json:"start"`
+	End         common.DateTime `json:"end"`
+	Invocations uint64          `json:"invocations"`
+	DurationMs  uint64          `json:"duration_ms"`
+	MemoryMBMs  uint64          `json:"memory_mb_ms"`
+	CPUMs       uint64          `json:"cpu_ms"`
+	EgressBytes uint64          `json:"egress_bytes"`
+}
+
+// UsageFilter selects the usage records of an app between two times, which
+// are aggregated over windows of the filter
+type UsageFilter struct {
+	AppID    string //match
+	FnID     string //match
+	FromTime common.DateTime
+	ToTime   common.DateTime
+	// Window is the length of the windows usage is aggregated over, windows
+	// start at multiples of it since the unix epoch
+	Window time.Duration
+	// ByFn aggregates the usage of each fn on its own
+	ByFn bool
+}
+
+// Validate checks the filter selects an app and a number of windows that is
+// not too large, setting the defaults of its window and times
+func (f *UsageFilter) Validate() error {
+	if f.AppID == "" {
+		return ErrAppsMissingID
+	}
+	if f.Window == 0 {
+		f.Window = DefaultUsageWindow
+	}
+	if time.Time(f.ToTime).IsZero() {
+		f.ToTime = common.DateTime(time.Now())
+	}
+	if time.Time(f.FromTime).IsZero() {
+		f.FromTime = common.DateTime(time.Time(f.ToTime).Add(-defaultUsageRange))
+	}
+	if time.Time(f.ToTime).Before(time.Time(f.FromTime)) {
+		return ErrInvalidToTime
+	}
+	if f.Window < MinUsageWindow || time.Time(f.ToTime).Sub(time.Time(f.FromTime))/f.Window >= MaxUsageWindows {
+		return ErrUsageInvalidWindow
+	}
+	return nil
+}
+
+// Match returns whether r is selected by the filter
+func (f *UsageFilter) Match(r *UsageRecord) bool {
+	return r.AppID == f.AppID &&
+		(f.FnID == "" || r.FnID == f.FnID) &&
+		!time.Time(r.CreatedAt).Before(time.Time(f.FromTime)) &&
+		time.Time(r.CreatedAt).Before(time.Time(f.ToTime))
+}
+
+type UsageList struct {
+	Items []*Usage `json:"items"`
+}
+
+type usageKey struct {
+	start int64
+	fnID  string
+}
+
+// UsageAggregator sums usage records into the windows of a filter
+type UsageAggregator struct {
+	filter  *UsageFilter
+	windows map[usageKey]*Usage
+}
+
+// NewUsageAggregator returns an aggregator of the records selected by filter,
+// which must be valid
+func NewUsageAggregator(filter *UsageFilter) *UsageAggregator {
+	return &UsageAggregator{filter: filter, windows: make(map[usageKey]*Usage)}
+}
+
+// Add adds r to the usage of its window, if the filter selects it
+func (a *UsageAggregator) Add(r *UsageRecord) {
+	if !a.filter.Match(r) {
+		return
+	}
+	start := time.Time(r.CreatedAt).Truncate(a.filter.Window)
+	key := usageKey{start: start.UnixNano()}
+	if a.filter.ByFn {
+		key.fnID = r.FnID
+	}
+	u, ok := a.windows[key]
+	if !ok {
+		u = &Usage{
+			AppID: a.filter.AppID,
+			FnID:  key.fnID,
+			Start: common.DateTime(start),
+			End:   common.DateTime(start.Add(a.filter.Window)),
+		}
+		a.windows[key] = u
+	}
+	u.Invocations++
+	u.DurationMs += r.DurationMs
+	u.MemoryMBMs += r.MemoryMBMs
+	u.CPUMs += r.CPUMs
+	u.EgressBytes += r.EgressBytes
+}
+
+// List returns the usage of the windows records were added to, oldest first
+// and by fn id within a window. Windows without records are left out.
+func (a *UsageAggregator) List() *UsageList {
+	res := &UsageList{Items: make([]*Usage, 0, len(a.windows))}
+	for _, u := range a.windows {
+		res.Items = append(res.Items, u)
+	}
+	sort.Slice(res.Items, func(i, j int) bool {
+		ui, uj := res.Items[i], res.Items[j]
+		if !time.Time(ui.Start).Equal(time.Time(uj.Start)) {
+			return time.Time(ui.Start).Before(time.Time(uj.Start))
+		}
+		return ui.FnID < uj.FnID
+	})
+	return res
+}
+
+// UsageStore may be implemented by a LogStore to keep usage records, which
+// are not removed with the apps and fns they are about.
+type UsageStore interface {
+	// InsertUsageRecord stores a usage record
+	InsertUsageRecord(ctx context.Context, record *UsageRecord) error
+
+	// GetUsage returns the usage of the records matching filter, aggregated
+	// over its windows
+	GetUsage(ctx context.Context, filter *UsageFilter) (*UsageList, error)
+}
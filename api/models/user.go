@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// AppUserAnnotation sets the user, and optionally the group, the containers
+// of an app run as, in place of the one of the agent or of the image. Its
+// value is a user name or uid, e.g. "1000:1000" or "nobody".
+const AppUserAnnotation = "fnproject.io/app/user"
+
+var ErrAppsInvalidUser = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid user annotation %s, must be a user or uid, optionally followed by :group or :gid", AppUserAnnotation),
+}
+
+var userRegex = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]*(:[a-zA-Z0-9_][a-zA-Z0-9_.-]*)?$`)
+
+// UserFromAnnotations returns the user of the containers of an app, empty if
+// not set
+func UserFromAnnotations(annotations Annotations) (string, error) {
+	v, ok := annotations.Get(AppUserAnnotation)
+	if !ok {
+		return "", nil
+	}
+	var user string
+	if err := json.Unmarshal(v, &user); err != nil || !userRegex.MatchString(user) {
+		return "", ErrAppsInvalidUser
+	}
+	return user, nil
+}
+
+// IsRootUser returns whether a container user, as in the USER of an image,
+// runs as root. An empty user runs as root.
+func IsRootUser(user string) bool {
+	name := strings.SplitN(user, ":", 2)[0]
+	return name == "" || name == "root" || strings.Trim(name, "0") == ""
+}
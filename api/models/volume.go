@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+)
+
+// AppVolumesAnnotation declares named persistent volumes mounted into the
+// containers of an app, which survive their containers. Its value is:
+//
+//	[{"name": "cache", "path": "/data/cache", "size": 512, "ttl": 86400}]
+//
+// Volumes are directories managed by agents, or docker volumes if agents do
+// not manage volumes. size is the quota of a volume in MB, a volume over its
+// quota is mounted read-only until its usage drops. ttl is the number of
+// seconds a volume no container uses is kept before it is removed. Each is
+// unlimited if 0. Quotas and ttls only apply to volumes managed by agents.
+const AppVolumesAnnotation = "fnproject.io/app/volumes"
+
+var ErrAppsInvalidVolumes = err{
+	code:  http.StatusBadRequest,
+	error: fmt.Errorf("invalid volumes annotation %s, volumes must have distinct names of letters, digits, _ and -, and distinct absolute paths other than / and /tmp", AppVolumesAnnotation),
+}
+
+var volumeNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Volume is a persistent volume of an app
+type Volume struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size uint64 `json:"size,omitempty"`
+	TTL  uint64 `json:"ttl,omitempty"`
+}
+
+// VolumesFromAnnotations returns the persistent volumes of an app, none if
+// not set
+func VolumesFromAnnotations(annotations Annotations) ([]Volume, error) {
+	v, ok := annotations.Get(AppVolumesAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var volumes []Volume
+	if err := json.Unmarshal(v, &volumes); err != nil {
+		return nil, ErrAppsInvalidVolumes
+	}
+	names := make(map[string]bool, len(volumes))
+	paths := make(map[string]bool, len(volumes))
+	for _, vol := range volumes {
+		p := path.Clean(vol.Path)
+		if !volumeNameRegex.MatchString(vol.Name) || !path.IsAbs(p) || p == "/" || p == "/tmp" || names[vol.Name] || paths[p] {
+			return nil, ErrAppsInvalidVolumes
+		}
+		names[vol.Name] = true
+		paths[p] = true
+	}
+	return volumes, nil
+}
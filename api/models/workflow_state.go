@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// MaxWorkflowStateSize is the largest state a workflow may store, in bytes,
+// which fits the text columns of all sql dbs
+const MaxWorkflowStateSize = 60 * 1024
+
+var (
+	ErrWorkflowStatesNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Workflow state not found"),
+	}
+	ErrWorkflowStatesUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Workflow states are not supported by the datastore"),
+	}
+	ErrWorkflowStatesMissingChainID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing workflow state chain ID"),
+	}
+	ErrWorkflowStatesMissingAppID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing workflow state app ID"),
+	}
+	ErrWorkflowStatesTooBig = err{
+		code:  http.StatusRequestEntityTooLarge,
+		error: fmt.Errorf("Workflow state is too big, the maximum is %d bytes", MaxWorkflowStateSize),
+	}
+	ErrWorkflowStatesConflict = err{
+		code:  http.StatusConflict,
+		error: errors.New("Workflow state was changed since it was read"),
+	}
+)
+
+// WorkflowState is the state a workflow engine keeps for one execution of a
+// workflow, whose calls are chained in the chain ChainID, see Call.ChainID.
+// The state is opaque to Fn.
+type WorkflowState struct {
+	ChainID string `json:"chain_id" db:"chain_id"`
+	AppID   string `json:"app_id" db:"app_id"`
+	State   string `json:"state" db:"state"`
+	// Version is incremented by every put of the state, see
+	// WorkflowStateStore.PutWorkflowState
+	Version   int64           `json:"version" db:"version"`
+	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+func (s *WorkflowState) Validate() error {
+	if s.ChainID == "" {
+		return ErrWorkflowStatesMissingChainID
+	}
+	if s.AppID == "" {
+		return ErrWorkflowStatesMissingAppID
+	}
+	if len(s.State) > MaxWorkflowStateSize {
+		return ErrWorkflowStatesTooBig
+	}
+	return nil
+}
+
+// WorkflowStateStore may be implemented by a Datastore to store the state of
+// workflows by chain, which is removed with its app.
+type WorkflowStateStore interface {
+	// GetWorkflowState returns the state of the chain chainID, or
+	// ErrWorkflowStatesNotFound.
+	GetWorkflowState(ctx context.Context, chainID string) (*WorkflowState, error)
+
+	// PutWorkflowState stores state if its version is the version stored, 0
+	// if none is, and returns it with its version incremented. States which
+	// were put since state was read are not overwritten, it returns
+	// ErrWorkflowStatesConflict for them instead.
+	PutWorkflowState(ctx context.Context, state *WorkflowState) (*WorkflowState, error)
+
+	// RemoveWorkflowState removes the state of the chain chainID, or returns
+	// ErrWorkflowStatesNotFound.
+	RemoveWorkflowState(ctx context.Context, chainID string) error
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/boltdb/bolt"
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/api/mqs"
 	"github.com/sirupsen/logrus"
@@ -36,6 +37,15 @@ func jobKey(jobID string) []byte {
 	return b
 }
 
+// leaseKey is the key of the lease of a reserved job, which sorts before the
+// reservation keys of the timeout bucket
+func leaseKey(jobID string) []byte {
+	b := make([]byte, len(jobID)+1)
+	b[0] = 'l'
+	copy(b[1:], []byte(jobID))
+	return b
+}
+
 const timeoutToIDKeyPrefix = "id:"
 
 func timeoutToIDKey(timeout []byte) []byte {
@@ -138,6 +148,11 @@ func (mq *BoltDbMQ) Start() {
 						if err != nil {
 							return err
 						}
+						// the lease of the job expired with its reservation
+						if jobID := timeoutBucket.Get(timeoutToIDKey(k)); jobID != nil {
+							timeoutBucket.Delete(jobKey(string(jobID)))
+							timeoutBucket.Delete(leaseKey(string(jobID)))
+						}
 						timeoutBucket.Delete(k)
 						timeoutBucket.Delete(timeoutToIDKey(k))
 					}
@@ -324,6 +339,9 @@ func (mq *BoltDbMQ) Reserve(ctx context.Context) (*models.Call, error) {
 		b.Put(reservationKey, value)
 		b.Put(jobKey(job.ID), reservationKey)
 		b.Put(timeoutToIDKey(reservationKey), []byte(job.ID))
+		// reservations are leases, see RenewLease
+		job.LeaseID = id.New().String()
+		b.Put(leaseKey(job.ID), []byte(job.LeaseID))
 
 		// Commit the transaction and check for error.
 		if err := tx.Commit(); err != nil {
@@ -348,11 +366,14 @@ func (mq *BoltDbMQ) Delete(ctx context.Context, job *models.Call) error {
 		k := jobKey(job.ID)
 
 		reservationKey := b.Get(k)
+		if job.LeaseID != "" && (reservationKey == nil || string(b.Get(leaseKey(job.ID))) != job.LeaseID) {
+			return models.ErrCallLeaseExpired
+		}
 		if reservationKey == nil {
 			return errors.New("Not found")
 		}
 
-		for _, k := range [][]byte{k, timeoutToIDKey(reservationKey), reservationKey} {
+		for _, k := range [][]byte{k, leaseKey(job.ID), timeoutToIDKey(reservationKey), reservationKey} {
 			err := b.Delete(k)
 			if err != nil {
 				return err
@@ -363,6 +384,41 @@ func (mq *BoltDbMQ) Delete(ctx context.Context, job *models.Call) error {
 	})
 }
 
+// LeasesCalls is true, reservations are leases which time out after a
+// minute unless they are renewed. Leases are stored with the queue, they
+// survive restarts of the server.
+func (mq *BoltDbMQ) LeasesCalls() bool {
+	return true
+}
+
+// RenewLease implements models.CallLeaser
+func (mq *BoltDbMQ) RenewLease(ctx context.Context, job *models.Call, d time.Duration) error {
+	return mq.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(timeoutName(int(*job.Priority)))
+		reservationKey := b.Get(jobKey(job.ID))
+		if reservationKey == nil || string(b.Get(leaseKey(job.ID))) != job.LeaseID {
+			return models.ErrCallLeaseExpired
+		}
+		// values of the transaction are invalid once their keys change
+		reservationKey = append([]byte(nil), reservationKey...)
+		value := append([]byte(nil), b.Get(reservationKey)...)
+
+		renewedKey := resKey(reservationKey[len(resKeyPrefix)+8:], time.Now().Add(d))
+		for _, k := range [][]byte{timeoutToIDKey(reservationKey), reservationKey} {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		if err := b.Put(renewedKey, value); err != nil {
+			return err
+		}
+		if err := b.Put(jobKey(job.ID), renewedKey); err != nil {
+			return err
+		}
+		return b.Put(timeoutToIDKey(renewedKey), []byte(job.ID))
+	})
+}
+
 // Close shuts down the bolt db connection and
 // stops the goroutine associated with the ticker
 func (mq *BoltDbMQ) Close() error {
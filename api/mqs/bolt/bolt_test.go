@@ -0,0 +1,86 @@
+package bolt
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func openTestMQ(t *testing.T, path string) *BoltDbMQ {
+	mq, err := boltProvider(0).New(&url.URL{Scheme: "bolt", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mq.(*BoltDbMQ)
+}
+
+func TestLeases(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "boltmq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fn.mq")
+
+	mq := openTestMQ(t, path)
+	if !models.LeasesCalls(mq) {
+		t.Fatal("Expected the bolt queue to lease its calls")
+	}
+	priority := int32(0)
+	if _, err := mq.Push(ctx, &models.Call{ID: "call", Priority: &priority}); err != nil {
+		t.Fatal(err)
+	}
+	leased, err := mq.Reserve(ctx)
+	if err != nil || leased == nil || leased.LeaseID == "" {
+		t.Fatalf("Expected the call to be leased, got %+v %v", leased, err)
+	}
+
+	// leases survive restarts
+	mq.Close()
+	mq = openTestMQ(t, path)
+	defer mq.Close()
+	if err := mq.RenewLease(ctx, leased, time.Minute); err != nil {
+		t.Fatalf("Expected the lease to be renewed after a restart, got %v", err)
+	}
+	other := *leased
+	other.LeaseID = "other"
+	if err := mq.RenewLease(ctx, &other, time.Minute); err != models.ErrCallLeaseExpired {
+		t.Fatalf("Expected another lease not to be renewed, got %v", err)
+	}
+	if err := mq.Delete(ctx, &other); err != models.ErrCallLeaseExpired {
+		t.Fatalf("Expected the call not to be deleted with another lease, got %v", err)
+	}
+
+	// an expired lease puts the call back in the queue, to be leased again
+	if err := mq.RenewLease(ctx, leased, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	var again *models.Call
+	for deadline := time.Now().Add(5 * time.Second); again == nil && time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if again, err = mq.Reserve(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if again == nil || again.ID != leased.ID || again.LeaseID == leased.LeaseID {
+		t.Fatalf("Expected the call to be leased again once its lease expired, got %+v", again)
+	}
+	if err := mq.RenewLease(ctx, leased, time.Minute); err != models.ErrCallLeaseExpired {
+		t.Fatalf("Expected the expired lease not to be renewed, got %v", err)
+	}
+	if err := mq.Delete(ctx, leased); err != models.ErrCallLeaseExpired {
+		t.Fatalf("Expected the call not to be deleted with the expired lease, got %v", err)
+	}
+	if err := mq.Delete(ctx, again); err != nil {
+		t.Fatalf("Expected the call to be deleted with its lease, got %v", err)
+	}
+	if call, err := mq.Reserve(ctx); err != nil || call != nil {
+		t.Fatalf("Expected the queue to be empty, got %+v %v", call, err)
+	}
+}
@@ -0,0 +1,333 @@
+package kafka
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const clientID = "fn"
+
+// broker is a connection to a kafka broker. Requests on a connection are
+// serialized, kafka answers them in order anyway.
+type broker struct {
+	addr string
+
+	lock          sync.Mutex
+	conn          net.Conn
+	rd            *bufio.Reader
+	correlationID int32
+}
+
+func newBroker(addr string) *broker {
+	return &broker{addr: addr}
+}
+
+// request sends a request with the body written by body and returns a decoder
+// positioned at the body of the response
+func (b *broker) request(ctx context.Context, key, version int16, body func(e *encoder)) (*decoder, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", b.addr)
+		if err != nil {
+			return nil, err
+		}
+		b.conn = conn
+		b.rd = bufio.NewReader(conn)
+	}
+
+	b.correlationID++
+	var e encoder
+	e.int32(0) // size, set below
+	e.int16(key)
+	e.int16(version)
+	e.int32(b.correlationID)
+	e.string(clientID)
+	body(&e)
+	binary.BigEndian.PutUint32(e.buf[:4], uint32(len(e.buf)-4))
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	b.conn.SetDeadline(deadline)
+
+	resp, err := b.roundTrip(e.buf)
+	if err != nil {
+		b.closeLocked()
+		return nil, err
+	}
+	d := &decoder{buf: resp}
+	if id := d.int32(); id != b.correlationID {
+		b.closeLocked()
+		return nil, fmt.Errorf("kafka: correlation id mismatch, got %d expected %d", id, b.correlationID)
+	}
+	return d, nil
+}
+
+func (b *broker) roundTrip(req []byte) ([]byte, error) {
+	if _, err := b.conn.Write(req); err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(b.rd, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(b.rd, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (b *broker) close() {
+	b.lock.Lock()
+	b.closeLocked()
+	b.lock.Unlock()
+}
+
+func (b *broker) closeLocked() {
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+		b.rd = nil
+	}
+}
+
+// topicPartition identifies a partition of a topic
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// cluster keeps the connections to the brokers of a cluster and the leaders of
+// the partitions of the topics used by the mq
+type cluster struct {
+	seeds  []string
+	topics []string
+
+	lock       sync.Mutex
+	brokers    map[string]*broker
+	nodes      map[int32]string
+	leaders    map[topicPartition]int32
+	partitions map[string][]int32
+}
+
+func newCluster(seeds, topics []string) *cluster {
+	return &cluster{
+		seeds:      seeds,
+		topics:     topics,
+		brokers:    make(map[string]*broker),
+		nodes:      make(map[int32]string),
+		leaders:    make(map[topicPartition]int32),
+		partitions: make(map[string][]int32),
+	}
+}
+
+func (c *cluster) broker(addr string) *broker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	b, ok := c.brokers[addr]
+	if !ok {
+		b = newBroker(addr)
+		c.brokers[addr] = b
+	}
+	return b
+}
+
+// refreshMetadata looks up the brokers of the cluster and the partitions and
+// their leaders of the topics of the mq, trying the seed brokers in turn.
+func (c *cluster) refreshMetadata(ctx context.Context) error {
+	var err error
+	for _, seed := range c.seeds {
+		var d *decoder
+		d, err = c.broker(seed).request(ctx, apiMetadata, 1, func(e *encoder) {
+			e.arrayLen(len(c.topics))
+			for _, t := range c.topics {
+				e.string(t)
+			}
+		})
+		if err != nil {
+			continue
+		}
+
+		nodes := make(map[int32]string)
+		for i, n := 0, d.arrayLen(); i < n; i++ {
+			id := d.int32()
+			host := d.string()
+			port := d.int32()
+			d.string() // rack
+			nodes[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+		}
+		d.int32() // controller id
+
+		leaders := make(map[topicPartition]int32)
+		partitions := make(map[string][]int32)
+		for i, n := 0, d.arrayLen(); i < n; i++ {
+			topicErr := d.int16()
+			topic := d.string()
+			d.int8() // is internal
+			for j, m := 0, d.arrayLen(); j < m; j++ {
+				partErr := d.int16()
+				partition := d.int32()
+				leader := d.int32()
+				for k, r := 0, d.arrayLen(); k < r; k++ {
+					d.int32() // replicas
+				}
+				for k, r := 0, d.arrayLen(); k < r; k++ {
+					d.int32() // isr
+				}
+				if topicErr == 0 && kafkaError(partErr) != errLeaderNotAvailable {
+					leaders[topicPartition{topic, partition}] = leader
+					partitions[topic] = append(partitions[topic], partition)
+				}
+			}
+		}
+		if d.err != nil {
+			err = d.err
+			continue
+		}
+
+		c.lock.Lock()
+		c.nodes, c.leaders, c.partitions = nodes, leaders, partitions
+		c.lock.Unlock()
+		return nil
+	}
+	if err == nil {
+		err = errors.New("kafka: no seed brokers")
+	}
+	return err
+}
+
+// leader returns the address of the leader of a partition
+func (c *cluster) leader(tp topicPartition) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	id, ok := c.leaders[tp]
+	if !ok {
+		return "", false
+	}
+	addr, ok := c.nodes[id]
+	return addr, ok
+}
+
+// partitionsOf returns the partitions of a topic
+func (c *cluster) partitionsOf(topic string) []int32 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.partitions[topic]
+}
+
+// coordinator returns the broker coordinating a consumer group
+// produce writes rec to the partition of its key, refreshing the metadata of
+// the cluster once if it is out of date
+func (c *cluster) produce(ctx context.Context, topic string, rec record) error {
+	var err error
+	for retry := 0; ; retry++ {
+		err = c.produceOnce(ctx, topic, rec)
+		if kerr, ok := err.(kafkaError); !ok || !kerr.needsMetadata() || retry > 0 {
+			break
+		}
+		if err = c.refreshMetadata(ctx); err != nil {
+			break
+		}
+	}
+	return err
+}
+
+func (c *cluster) produceOnce(ctx context.Context, topic string, rec record) error {
+	partitions := c.partitionsOf(topic)
+	if len(partitions) == 0 {
+		return errUnknownTopicOrPartition
+	}
+	h := fnv.New32a()
+	h.Write(rec.Key)
+	tp := topicPartition{topic, partitions[h.Sum32()%uint32(len(partitions))]}
+
+	addr, ok := c.leader(tp)
+	if !ok {
+		return errLeaderNotAvailable
+	}
+
+	batch := encodeRecordBatch([]record{rec})
+	d, err := c.broker(addr).request(ctx, apiProduce, 3, func(e *encoder) {
+		e.nullString() // transactional id
+		e.int16(-1)    // acks from all in sync replicas
+		e.int32(10000) // timeout
+		e.arrayLen(1)
+		e.string(tp.topic)
+		e.arrayLen(1)
+		e.int32(tp.partition)
+		e.bytes(batch)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		d.string()
+		for j, m := 0, d.arrayLen(); j < m; j++ {
+			d.int32() // partition
+			code := d.int16()
+			d.int64() // base offset
+			d.int64() // log append time
+			if err := asError(code); err != nil {
+				return err
+			}
+		}
+	}
+	return d.err
+}
+
+func (c *cluster) coordinator(ctx context.Context, group string) (*broker, error) {
+	var err error
+	for _, seed := range c.seeds {
+		var d *decoder
+		d, err = c.broker(seed).request(ctx, apiFindCoordinator, 1, func(e *encoder) {
+			e.string(group)
+			e.int8(0) // group key type
+		})
+		if err != nil {
+			continue
+		}
+		d.int32() // throttle time
+		code := d.int16()
+		d.string() // error message
+		d.int32()  // node id
+		host := d.string()
+		port := d.int32()
+		if d.err != nil {
+			err = d.err
+			continue
+		}
+		if err = asError(code); err != nil {
+			continue
+		}
+		return c.broker(net.JoinHostPort(host, strconv.Itoa(int(port)))), nil
+	}
+	if err == nil {
+		err = errors.New("kafka: no seed brokers")
+	}
+	return nil, err
+}
+
+func (c *cluster) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, b := range c.brokers {
+		b.close()
+	}
+}
@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Consumer reads the messages of topics as a member of a consumer group, the
+// way KafkaMQ reads calls. Offsets are committed up to the oldest message that
+// was not acked, messages not acked within the redelivery timeout are returned
+// by Next again.
+type Consumer struct {
+	mq *KafkaMQ
+}
+
+// Message is a message read from a topic
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Value     []byte
+
+	partition *partitionState
+	message   *message
+}
+
+// NewConsumer joins group on the brokers seeds and consumes topics until it is
+// closed.
+func NewConsumer(ctx context.Context, seeds, topics []string, group string, redeliverAfter time.Duration) (*Consumer, error) {
+	if len(seeds) == 0 || len(topics) == 0 || group == "" {
+		return nil, errors.New("a kafka consumer needs brokers, topics and a group")
+	}
+	mq := newMember(seeds, topics, group, redeliverAfter)
+	if err := mq.consumer.refreshMetadata(ctx); err != nil {
+		return nil, err
+	}
+	go mq.consume()
+	return &Consumer{mq: mq}, nil
+}
+
+// Next returns the oldest message which is not acked or being processed, nil
+// if there is none.
+func (c *Consumer) Next() *Message {
+	c.mq.lock.Lock()
+	defer c.mq.lock.Unlock()
+
+	now := time.Now()
+	p, m := c.mq.next(now)
+	if m == nil {
+		return nil
+	}
+	m.reservedUntil = now.Add(c.mq.reserveTimeout)
+	return &Message{
+		Topic:     p.tp.topic,
+		Partition: p.tp.partition,
+		Offset:    m.offset,
+		Value:     m.value,
+		partition: p,
+		message:   m,
+	}
+}
+
+// Ack marks a message processed, its offset is committed once the messages
+// before it are acked too. Acking messages of partitions which moved to
+// another member in the meantime has no effect, they are read again there.
+func (c *Consumer) Ack(msg *Message) {
+	c.mq.lock.Lock()
+	defer c.mq.lock.Unlock()
+
+	if c.mq.partitions[msg.partition.tp] != msg.partition {
+		return
+	}
+	msg.message.deleted = true
+	msg.partition.trim()
+}
+
+// Close leaves the consumer group, committing the offsets of acked messages first
+func (c *Consumer) Close() error {
+	return c.mq.Close()
+}
@@ -0,0 +1,840 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTopicPrefix    = "fn-calls"
+	defaultGroup          = "fn-async"
+	defaultReserveTimeout = time.Minute
+
+	sessionTimeout    = 30 * time.Second
+	rebalanceTimeout  = 60 * time.Second
+	heartbeatInterval = 3 * time.Second
+	commitInterval    = time.Second
+	fetchMaxWait      = 500 * time.Millisecond
+	fetchMaxBytes     = 1 << 20
+
+	// maxBuffered is the number of uncompleted messages kept per partition,
+	// fetching a partition pauses once it is reached
+	maxBuffered = 100
+)
+
+type kafkaProvider int
+
+func (kafkaProvider) Supports(url *url.URL) bool {
+	switch url.Scheme {
+	case "kafka":
+		return true
+	}
+	return false
+}
+
+func (kafkaProvider) String() string {
+	return "kafka"
+}
+
+// New returns a kafka mq for a URL of the form
+// kafka://broker1:9092,broker2:9092/topic-prefix?group=fn-async&reserve_timeout=1m
+//
+// Calls are produced to a topic per priority, <topic-prefix>-0 to <topic-prefix>-2,
+// which have to exist or be auto created by the brokers. Fn servers consuming the
+// queue form the consumer group named by group and split the partitions of the
+// topics among them.
+//
+// The kafka mq is experimental: it speaks the kafka protocol itself rather than
+// through a maintained client, and is tested against brokers only when
+// KAFKA_URL is set, see TestKafka.
+func (kafkaProvider) New(url *url.URL) (models.MessageQueue, error) {
+	seeds := strings.Split(url.Host, ",")
+	if url.Host == "" {
+		return nil, errors.New("kafka mq URL must name at least one broker")
+	}
+
+	prefix := strings.Trim(url.Path, "/")
+	if prefix == "" {
+		prefix = defaultTopicPrefix
+	}
+	group := url.Query().Get("group")
+	if group == "" {
+		group = defaultGroup
+	}
+	reserveTimeout := defaultReserveTimeout
+	if t := url.Query().Get("reserve_timeout"); t != "" {
+		var err error
+		if reserveTimeout, err = time.ParseDuration(t); err != nil {
+			return nil, fmt.Errorf("invalid kafka mq reserve_timeout %q: %v", t, err)
+		}
+	}
+
+	mq := newKafkaMQ(seeds, prefix, group, reserveTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := mq.producer.refreshMetadata(ctx); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"brokers": url.Host}).Error("Error connecting to kafka")
+		return nil, err
+	}
+
+	logrus.Warn("WARNING: Experimental Kafka MQ Enabled")
+	logrus.WithFields(logrus.Fields{"brokers": url.Host, "topics": mq.topics, "group": group}).Info("Kafka mq initialized")
+	go mq.consume()
+	return mq, nil
+}
+
+// KafkaMQ is a message queue backed by kafka. Reserved calls are tracked in
+// memory and the offsets of a partition are committed up to the oldest call
+// that was not deleted yet, so that calls of a crashed server are delivered
+// again to the member of the group that takes over its partitions.
+type KafkaMQ struct {
+	producer *cluster
+	consumer *cluster
+
+	topics         []string // in increasing priority
+	delays         bool     // whether values are calls which may be delayed
+	group          string
+	reserveTimeout time.Duration
+
+	lock       sync.Mutex
+	generation int32
+	memberID   string
+	partitions map[topicPartition]*partitionState
+	reserved   map[string]*reservation
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// partitionState is what a member knows about a partition assigned to it
+type partitionState struct {
+	tp          topicPartition
+	fetchOffset int64
+	committed   int64
+	messages    []*message // fetched and not deleted, in offset order
+}
+
+// commitOffset is the offset consumption of the partition resumes at
+func (p *partitionState) commitOffset() int64 {
+	if len(p.messages) > 0 {
+		return p.messages[0].offset
+	}
+	return p.fetchOffset
+}
+
+// trim drops the deleted messages at the head of the partition
+func (p *partitionState) trim() {
+	i := 0
+	for i < len(p.messages) && p.messages[i].deleted {
+		i++
+	}
+	p.messages = p.messages[i:]
+}
+
+type message struct {
+	offset        int64
+	readyAt       time.Time
+	value         []byte
+	reservedUntil time.Time
+	deleted       bool
+}
+
+type reservation struct {
+	partition *partitionState
+	message   *message
+}
+
+func newKafkaMQ(seeds []string, prefix, group string, reserveTimeout time.Duration) *KafkaMQ {
+	topics := make([]string, 3)
+	for i := range topics {
+		topics[i] = fmt.Sprintf("%s-%d", prefix, i)
+	}
+	mq := newMember(seeds, topics, group, reserveTimeout)
+	mq.delays = true
+	return mq
+}
+
+// newMember returns a member of group consuming topics, which does not join
+// the group until consume runs
+func newMember(seeds, topics []string, group string, reserveTimeout time.Duration) *KafkaMQ {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &KafkaMQ{
+		producer:       newCluster(seeds, topics),
+		consumer:       newCluster(seeds, topics),
+		topics:         topics,
+		group:          group,
+		reserveTimeout: reserveTimeout,
+		partitions:     make(map[topicPartition]*partitionState),
+		reserved:       make(map[string]*reservation),
+		ctx:            ctx,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+	}
+}
+
+func priority(job *models.Call) int {
+	if job.Priority == nil || *job.Priority < 0 {
+		return 0
+	}
+	if *job.Priority > 2 {
+		return 2
+	}
+	return int(*job.Priority)
+}
+
+func (mq *KafkaMQ) Push(ctx context.Context, job *models.Call) (*models.Call, error) {
+	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+
+	buf, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	rec := record{Key: []byte(job.ID), Value: buf, Timestamp: time.Now()}
+	if err := mq.producer.produce(ctx, mq.topics[priority(job)], rec); err != nil {
+		return nil, err
+	}
+
+	log.Debugln("Pushed to MQ")
+	return job, nil
+}
+
+// Reserve returns the oldest ready call of the highest priority, if any. The
+// call is delivered again if it is not deleted within the reserve timeout.
+func (mq *KafkaMQ) Reserve(ctx context.Context) (*models.Call, error) {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	now := time.Now()
+	for {
+		p, m := mq.next(now)
+		if m == nil {
+			return nil, nil
+		}
+
+		var job models.Call
+		if err := json.Unmarshal(m.value, &job); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"topic": p.tp.topic, "partition": p.tp.partition, "offset": m.offset}).Error("Skipping malformed call in kafka mq")
+			m.deleted = true
+			p.trim()
+			continue
+		}
+
+		m.reservedUntil = now.Add(mq.reserveTimeout)
+		mq.reserved[job.ID] = &reservation{partition: p, message: m}
+
+		_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+		log.Debugln("Reserved")
+		return &job, nil
+	}
+}
+
+// next returns the oldest ready message of the highest priority topic, if
+// any. mq.lock must be held.
+func (mq *KafkaMQ) next(now time.Time) (*partitionState, *message) {
+	for i := len(mq.topics) - 1; i >= 0; i-- {
+		for _, p := range mq.partitions {
+			if p.tp.topic != mq.topics[i] {
+				continue
+			}
+			for _, m := range p.messages {
+				if m.deleted || m.reservedUntil.After(now) || m.readyAt.After(now) {
+					continue
+				}
+				return p, m
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (mq *KafkaMQ) Delete(ctx context.Context, job *models.Call) error {
+	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+	defer log.Debugln("Deleted")
+
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	r, ok := mq.reserved[job.ID]
+	if !ok {
+		// the partition moved to another member, which will deliver the call again
+		return nil
+	}
+	delete(mq.reserved, job.ID)
+	r.message.deleted = true
+	r.partition.trim()
+	return nil
+}
+
+// Close leaves the consumer group, committing the offsets of deleted calls first
+func (mq *KafkaMQ) Close() error {
+	mq.cancel()
+	<-mq.done
+	mq.producer.close()
+	mq.consumer.close()
+	return nil
+}
+
+// consume keeps this server a member of the consumer group and fetches the
+// calls of the partitions assigned to it
+func (mq *KafkaMQ) consume() {
+	defer close(mq.done)
+
+	for mq.ctx.Err() == nil {
+		err := mq.session(mq.ctx)
+		if err == nil || mq.ctx.Err() != nil {
+			continue
+		}
+		logrus.WithError(err).WithFields(logrus.Fields{"group": mq.group}).Warn("Kafka consumer group session ended, rejoining")
+		select {
+		case <-mq.ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// session is a single generation of the consumer group
+func (mq *KafkaMQ) session(ctx context.Context) error {
+	if err := mq.consumer.refreshMetadata(ctx); err != nil {
+		return err
+	}
+	coord, err := mq.consumer.coordinator(ctx, mq.group)
+	if err != nil {
+		return err
+	}
+	assigned, err := mq.join(ctx, coord)
+	if err != nil {
+		return err
+	}
+	if err := mq.assign(ctx, coord, assigned); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	commit := time.NewTicker(commitInterval)
+	defer commit.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			leaveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := mq.commit(leaveCtx, coord); err != nil {
+				logrus.WithError(err).Warn("Error committing kafka offsets")
+			}
+			return mq.leave(leaveCtx, coord)
+		case <-heartbeat.C:
+			if err := mq.heartbeat(ctx, coord); err != nil {
+				return err
+			}
+		case <-commit.C:
+			if err := mq.commit(ctx, coord); err != nil {
+				return err
+			}
+		default:
+		}
+
+		if err := mq.fetch(ctx); err != nil {
+			if kerr, ok := err.(kafkaError); ok && kerr.needsMetadata() {
+				if err := mq.consumer.refreshMetadata(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+	}
+}
+
+// join joins the consumer group and returns the partitions assigned to this member
+func (mq *KafkaMQ) join(ctx context.Context, coord *broker) (map[string][]int32, error) {
+	ctx, cancel := context.WithTimeout(ctx, rebalanceTimeout+5*time.Second)
+	defer cancel()
+
+	var subscription encoder
+	subscription.int16(0)
+	subscription.arrayLen(len(mq.topics))
+	for _, t := range mq.topics {
+		subscription.string(t)
+	}
+	subscription.bytes(nil)
+
+	mq.lock.Lock()
+	memberID := mq.memberID
+	mq.lock.Unlock()
+
+	d, err := coord.request(ctx, apiJoinGroup, 2, func(e *encoder) {
+		e.string(mq.group)
+		e.int32(int32(sessionTimeout / time.Millisecond))
+		e.int32(int32(rebalanceTimeout / time.Millisecond))
+		e.string(memberID)
+		e.string("consumer")
+		e.arrayLen(1)
+		e.string("range")
+		e.bytes(subscription.buf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.int32() // throttle time
+	code := d.int16()
+	generation := d.int32()
+	d.string() // protocol name
+	leader := d.string()
+	memberID = d.string()
+	var members []string
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		members = append(members, d.string())
+		d.bytes() // subscription, all members subscribe to the same topics
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := asError(code); err != nil {
+		if err == errUnknownMemberID {
+			mq.lock.Lock()
+			mq.memberID = ""
+			mq.lock.Unlock()
+		}
+		return nil, err
+	}
+
+	mq.lock.Lock()
+	mq.generation, mq.memberID = generation, memberID
+	mq.lock.Unlock()
+
+	var assignments map[string][]byte
+	if leader == memberID {
+		assignments = mq.rangeAssign(members)
+	}
+
+	d, err = coord.request(ctx, apiSyncGroup, 1, func(e *encoder) {
+		e.string(mq.group)
+		e.int32(generation)
+		e.string(memberID)
+		e.arrayLen(len(assignments))
+		for member, a := range assignments {
+			e.string(member)
+			e.bytes(a)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.int32() // throttle time
+	code = d.int16()
+	assignment := d.bytes()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := asError(code); err != nil {
+		return nil, err
+	}
+
+	assigned := make(map[string][]int32)
+	if len(assignment) == 0 {
+		return assigned, nil
+	}
+	ad := decoder{buf: assignment}
+	ad.int16() // version
+	for i, n := 0, ad.arrayLen(); i < n; i++ {
+		topic := ad.string()
+		for j, m := 0, ad.arrayLen(); j < m; j++ {
+			assigned[topic] = append(assigned[topic], ad.int32())
+		}
+	}
+	return assigned, ad.err
+}
+
+// rangeAssign splits the partitions of every topic into contiguous ranges,
+// one per member, the way the range assignor of the java consumer does.
+func (mq *KafkaMQ) rangeAssign(members []string) map[string][]byte {
+	sort.Strings(members)
+
+	perMember := make(map[string]map[string][]int32, len(members))
+	for _, m := range members {
+		perMember[m] = make(map[string][]int32)
+	}
+	for _, topic := range mq.topics {
+		partitions := append([]int32(nil), mq.consumer.partitionsOf(topic)...)
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+		size, extra := len(partitions)/len(members), len(partitions)%len(members)
+		start := 0
+		for i, m := range members {
+			n := size
+			if i < extra {
+				n++
+			}
+			if n > 0 {
+				perMember[m][topic] = partitions[start : start+n]
+			}
+			start += n
+		}
+	}
+
+	res := make(map[string][]byte, len(members))
+	for m, topics := range perMember {
+		var e encoder
+		e.int16(0)
+		e.arrayLen(len(topics))
+		for topic, partitions := range topics {
+			e.string(topic)
+			e.arrayLen(len(partitions))
+			for _, p := range partitions {
+				e.int32(p)
+			}
+		}
+		e.bytes(nil)
+		res[m] = e.buf
+	}
+	return res
+}
+
+// assign replaces the partitions of this member with the ones assigned in the
+// current generation, starting at their committed offsets. Reservations of
+// partitions that moved are dropped, their calls are delivered again by the new owner.
+func (mq *KafkaMQ) assign(ctx context.Context, coord *broker, assigned map[string][]int32) error {
+	d, err := coord.request(ctx, apiOffsetFetch, 1, func(e *encoder) {
+		e.string(mq.group)
+		e.arrayLen(len(assigned))
+		for topic, partitions := range assigned {
+			e.string(topic)
+			e.arrayLen(len(partitions))
+			for _, p := range partitions {
+				e.int32(p)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	offsets := make(map[topicPartition]int64)
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		topic := d.string()
+		for j, m := 0, d.arrayLen(); j < m; j++ {
+			partition := d.int32()
+			offset := d.int64()
+			d.string() // metadata
+			if err := asError(d.int16()); err != nil {
+				return err
+			}
+			offsets[topicPartition{topic, partition}] = offset
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	for tp, offset := range offsets {
+		if offset < 0 {
+			if offsets[tp], err = mq.earliestOffset(ctx, tp); err != nil {
+				return err
+			}
+		}
+	}
+
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	partitions := make(map[topicPartition]*partitionState, len(offsets))
+	for tp, offset := range offsets {
+		if p, ok := mq.partitions[tp]; ok && p.committed == offset {
+			// still ours and nobody else committed in between, keep the reservations
+			partitions[tp] = p
+			continue
+		}
+		partitions[tp] = &partitionState{tp: tp, fetchOffset: offset, committed: offset}
+	}
+	for id, r := range mq.reserved {
+		if partitions[r.partition.tp] != r.partition {
+			delete(mq.reserved, id)
+		}
+	}
+	mq.partitions = partitions
+
+	logrus.WithFields(logrus.Fields{"group": mq.group, "generation": mq.generation, "partitions": len(partitions)}).Info("Kafka consumer group partitions assigned")
+	return nil
+}
+
+// earliestOffset returns the oldest offset still available in a partition
+func (mq *KafkaMQ) earliestOffset(ctx context.Context, tp topicPartition) (int64, error) {
+	addr, ok := mq.consumer.leader(tp)
+	if !ok {
+		return 0, errLeaderNotAvailable
+	}
+	d, err := mq.consumer.broker(addr).request(ctx, apiListOffsets, 1, func(e *encoder) {
+		e.int32(-1) // replica id
+		e.arrayLen(1)
+		e.string(tp.topic)
+		e.arrayLen(1)
+		e.int32(tp.partition)
+		e.int64(-2) // earliest
+	})
+	if err != nil {
+		return 0, err
+	}
+	var offset int64
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		d.string()
+		for j, m := 0, d.arrayLen(); j < m; j++ {
+			d.int32() // partition
+			if err := asError(d.int16()); err != nil {
+				return 0, err
+			}
+			d.int64() // timestamp
+			offset = d.int64()
+		}
+	}
+	return offset, d.err
+}
+
+// fetch reads the next messages of the assigned partitions that are not full yet
+func (mq *KafkaMQ) fetch(ctx context.Context) error {
+	type fetchPartition struct {
+		tp     topicPartition
+		offset int64
+	}
+
+	mq.lock.Lock()
+	plan := make(map[string][]fetchPartition)
+	for tp, p := range mq.partitions {
+		if len(p.messages) >= maxBuffered {
+			continue
+		}
+		addr, ok := mq.consumer.leader(tp)
+		if !ok {
+			mq.lock.Unlock()
+			return errLeaderNotAvailable
+		}
+		plan[addr] = append(plan[addr], fetchPartition{tp, p.fetchOffset})
+	}
+	mq.lock.Unlock()
+
+	if len(plan) == 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(fetchMaxWait):
+		}
+		return nil
+	}
+
+	for addr, partitions := range plan {
+		reqCtx, cancel := context.WithTimeout(ctx, fetchMaxWait+10*time.Second)
+		d, err := mq.consumer.broker(addr).request(reqCtx, apiFetch, 4, func(e *encoder) {
+			e.int32(-1) // replica id
+			e.int32(int32(fetchMaxWait / time.Millisecond))
+			e.int32(1) // min bytes
+			e.int32(fetchMaxBytes)
+			e.int8(0) // read uncommitted
+			e.arrayLen(len(partitions))
+			for _, p := range partitions {
+				e.string(p.tp.topic)
+				e.arrayLen(1)
+				e.int32(p.tp.partition)
+				e.int64(p.offset)
+				e.int32(fetchMaxBytes)
+			}
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		d.int32() // throttle time
+		for i, n := 0, d.arrayLen(); i < n; i++ {
+			topic := d.string()
+			for j, m := 0, d.arrayLen(); j < m; j++ {
+				tp := topicPartition{topic, d.int32()}
+				code := d.int16()
+				d.int64() // high watermark
+				d.int64() // last stable offset
+				for k, a := 0, d.arrayLen(); k < a; k++ {
+					d.int64() // producer id
+					d.int64() // first offset
+				}
+				records := d.bytes()
+				if d.err != nil {
+					return d.err
+				}
+
+				switch err := asError(code); err {
+				case nil:
+				case errOffsetOutOfRange:
+					offset, err := mq.earliestOffset(ctx, tp)
+					if err != nil {
+						return err
+					}
+					mq.resetPartition(tp, offset)
+					continue
+				default:
+					return err
+				}
+
+				recs, err := decodeRecordBatches(records)
+				if err != nil {
+					logrus.WithError(err).WithFields(logrus.Fields{"topic": tp.topic, "partition": tp.partition}).Error("Error decoding kafka records")
+				}
+				mq.add(tp, recs)
+			}
+		}
+	}
+	return nil
+}
+
+// add buffers fetched records of a partition
+func (mq *KafkaMQ) add(tp topicPartition, recs []record) {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	p, ok := mq.partitions[tp]
+	if !ok {
+		return
+	}
+	for _, r := range recs {
+		if r.Offset < p.fetchOffset {
+			continue // batches may start before the requested offset
+		}
+		readyAt := r.Timestamp
+		var delay struct {
+			Delay int32 `json:"delay"`
+		}
+		if mq.delays && json.Unmarshal(r.Value, &delay) == nil && delay.Delay > 0 {
+			readyAt = readyAt.Add(time.Duration(delay.Delay) * time.Second)
+		}
+		p.messages = append(p.messages, &message{offset: r.Offset, readyAt: readyAt, value: r.Value})
+		p.fetchOffset = r.Offset + 1
+	}
+}
+
+func (mq *KafkaMQ) resetPartition(tp topicPartition, offset int64) {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	if p, ok := mq.partitions[tp]; ok {
+		logrus.WithFields(logrus.Fields{"topic": tp.topic, "partition": tp.partition, "offset": offset}).Warn("Kafka offset out of range, resetting to earliest")
+		p.fetchOffset = offset
+		p.messages = nil
+		for id, r := range mq.reserved {
+			if r.partition == p {
+				delete(mq.reserved, id)
+			}
+		}
+	}
+}
+
+// commit commits the offsets of the partitions up to their oldest undeleted call
+func (mq *KafkaMQ) commit(ctx context.Context, coord *broker) error {
+	mq.lock.Lock()
+	generation, memberID := mq.generation, mq.memberID
+	offsets := make(map[topicPartition]int64)
+	for tp, p := range mq.partitions {
+		if offset := p.commitOffset(); offset != p.committed {
+			offsets[tp] = offset
+		}
+	}
+	mq.lock.Unlock()
+
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	d, err := coord.request(ctx, apiOffsetCommit, 2, func(e *encoder) {
+		e.string(mq.group)
+		e.int32(generation)
+		e.string(memberID)
+		e.int64(-1) // retention time, broker default
+		e.arrayLen(len(offsets))
+		for tp, offset := range offsets {
+			e.string(tp.topic)
+			e.arrayLen(1)
+			e.int32(tp.partition)
+			e.int64(offset)
+			e.nullString()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		d.string()
+		for j, m := 0, d.arrayLen(); j < m; j++ {
+			d.int32() // partition
+			if err := asError(d.int16()); err != nil {
+				return err
+			}
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	mq.lock.Lock()
+	for tp, offset := range offsets {
+		if p, ok := mq.partitions[tp]; ok {
+			p.committed = offset
+		}
+	}
+	mq.lock.Unlock()
+	return nil
+}
+
+func (mq *KafkaMQ) heartbeat(ctx context.Context, coord *broker) error {
+	mq.lock.Lock()
+	generation, memberID := mq.generation, mq.memberID
+	mq.lock.Unlock()
+
+	d, err := coord.request(ctx, apiHeartbeat, 1, func(e *encoder) {
+		e.string(mq.group)
+		e.int32(generation)
+		e.string(memberID)
+	})
+	if err != nil {
+		return err
+	}
+	d.int32() // throttle time
+	code := d.int16()
+	if d.err != nil {
+		return d.err
+	}
+	return asError(code)
+}
+
+func (mq *KafkaMQ) leave(ctx context.Context, coord *broker) error {
+	mq.lock.Lock()
+	memberID := mq.memberID
+	mq.lock.Unlock()
+
+	d, err := coord.request(ctx, apiLeaveGroup, 1, func(e *encoder) {
+		e.string(mq.group)
+		e.string(memberID)
+	})
+	if err != nil {
+		return err
+	}
+	d.int32() // throttle time
+	code := d.int16()
+	if d.err != nil {
+		return d.err
+	}
+	return asError(code)
+}
+
+func init() {
+	mqs.AddProvider(kafkaProvider(0))
+}
@@ -0,0 +1,201 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestRecordBatchRoundTrip(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	in := []record{
+		{Key: []byte("a"), Value: []byte(`{"id":"a"}`), Timestamp: now},
+		{Key: nil, Value: []byte(`{"id":"b","delay":5}`), Timestamp: now.Add(time.Second)},
+	}
+
+	batch := encodeRecordBatch(in)
+	// the broker assigns offsets, pretend this batch was appended at 42
+	copy(batch[:8], []byte{0, 0, 0, 0, 0, 0, 0, 42})
+
+	// a fetch may end in the middle of the next batch
+	buf := append(append([]byte(nil), batch...), batch[:20]...)
+
+	out, err := decodeRecordBatches(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("expected %d records, got %d", len(in), len(out))
+	}
+	for i, r := range out {
+		if r.Offset != int64(42+i) {
+			t.Fatalf("record %d: expected offset %d, got %d", i, 42+i, r.Offset)
+		}
+		if !bytes.Equal(r.Key, in[i].Key) || !bytes.Equal(r.Value, in[i].Value) {
+			t.Fatalf("record %d: mismatch %q/%q, expected %q/%q", i, r.Key, r.Value, in[i].Key, in[i].Value)
+		}
+		if !r.Timestamp.Equal(in[i].Timestamp) {
+			t.Fatalf("record %d: expected timestamp %v, got %v", i, in[i].Timestamp, r.Timestamp)
+		}
+	}
+
+	batch[len(batch)-1] ^= 0xff
+	if _, err := decodeRecordBatches(batch); err != errCorruptBatch {
+		t.Fatalf("expected crc mismatch, got %v", err)
+	}
+}
+
+func TestRangeAssign(t *testing.T) {
+	mq := newKafkaMQ([]string{"localhost:9092"}, "calls", "group", time.Minute)
+	mq.consumer.partitions = map[string][]int32{
+		"calls-0": {2, 0, 1},
+		"calls-1": {0},
+	}
+
+	assignments := mq.rangeAssign([]string{"m2", "m1"})
+
+	expected := map[string]map[string][]int32{
+		"m1": {"calls-0": {0, 1}, "calls-1": {0}},
+		"m2": {"calls-0": {2}},
+	}
+	for member, topics := range expected {
+		d := decoder{buf: assignments[member]}
+		d.int16()
+		got := make(map[string][]int32)
+		for i, n := 0, d.arrayLen(); i < n; i++ {
+			topic := d.string()
+			for j, m := 0, d.arrayLen(); j < m; j++ {
+				got[topic] = append(got[topic], d.int32())
+			}
+		}
+		if d.err != nil {
+			t.Fatal(d.err)
+		}
+		if len(got) != len(topics) {
+			t.Fatalf("member %s: expected %v, got %v", member, topics, got)
+		}
+		for topic, partitions := range topics {
+			if len(got[topic]) != len(partitions) {
+				t.Fatalf("member %s: expected %v, got %v", member, topics, got)
+			}
+			for i := range partitions {
+				if got[topic][i] != partitions[i] {
+					t.Fatalf("member %s: expected %v, got %v", member, topics, got)
+				}
+			}
+		}
+	}
+}
+
+func TestReserveDelete(t *testing.T) {
+	ctx := context.Background()
+	mq := newKafkaMQ([]string{"localhost:9092"}, "calls", "group", time.Minute)
+	tp := topicPartition{"calls-0", 0}
+	mq.partitions[tp] = &partitionState{tp: tp, fetchOffset: 10, committed: 10}
+
+	now := time.Now()
+	mq.add(tp, []record{
+		{Offset: 9, Value: []byte(`{"id":"old"}`), Timestamp: now},
+		{Offset: 10, Value: []byte(`{"id":"delayed","delay":60}`), Timestamp: now},
+		{Offset: 11, Value: []byte(`{"id":"ready"}`), Timestamp: now},
+	})
+
+	call, err := mq.Reserve(ctx)
+	if err != nil || call == nil || call.ID != "ready" {
+		t.Fatalf("expected to reserve the ready call, got %+v %v", call, err)
+	}
+	if call, _ := mq.Reserve(ctx); call != nil {
+		t.Fatalf("expected no call to be ready, got %+v", call)
+	}
+
+	if err := mq.Delete(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	p := mq.partitions[tp]
+	if offset := p.commitOffset(); offset != 10 {
+		t.Fatalf("expected commits to stop at the delayed call, got offset %d", offset)
+	}
+	if p.fetchOffset != 12 {
+		t.Fatalf("expected to fetch from offset 12, got %d", p.fetchOffset)
+	}
+}
+
+// TestKafka runs against the brokers of KAFKA_URL, e.g. kafka://localhost:9092,
+// which must auto create topics
+func TestKafka(t *testing.T) {
+	brokers := os.Getenv("KAFKA_URL")
+	if brokers == "" {
+		t.Skip("no kafka brokers specified in KAFKA_URL, skipping")
+		return
+	}
+	u, err := url.Parse(brokers)
+	if err != nil {
+		t.Fatalf("failed to parse url: %v", err)
+	}
+	// topics and group of this run only
+	run := fmt.Sprintf("fn-test-%d", time.Now().UnixNano())
+	u.Path = "/" + run
+	u.RawQuery = "group=" + run + "&reserve_timeout=5s"
+
+	ctx := context.Background()
+	open := func() *KafkaMQ {
+		mq, err := kafkaProvider(0).New(u)
+		if err != nil {
+			t.Fatalf("failed to create kafka mq: %v", err)
+		}
+		return mq.(*KafkaMQ)
+	}
+	push := func(mq *KafkaMQ, id string, priority int32) {
+		if _, err := mq.Push(ctx, &models.Call{ID: id, Priority: &priority}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// joining the group and fetching take a while
+	reserve := func(mq *KafkaMQ) *models.Call {
+		for deadline := time.Now().Add(time.Minute); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+			call, err := mq.Reserve(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if call != nil {
+				return call
+			}
+		}
+		t.Fatal("Expected a call to be reserved")
+		return nil
+	}
+
+	mq := open()
+	push(mq, "a", 0)
+	push(mq, "b", 2)
+	reserved := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		call := reserve(mq)
+		reserved[call.ID] = true
+		if err := mq.Delete(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reserved["a"] || !reserved["b"] {
+		t.Fatalf("Expected the calls pushed to be reserved, got %v", reserved)
+	}
+	// a reserved call which is not deleted is delivered again
+	push(mq, "c", 1)
+	if call := reserve(mq); call.ID != "c" {
+		t.Fatalf("Expected the call pushed to be reserved, got %s", call.ID)
+	}
+	mq.Close()
+
+	// the offsets of deleted calls were committed when leaving the group
+	mq = open()
+	defer mq.Close()
+	if call := reserve(mq); call.ID != "c" {
+		t.Fatalf("Expected the call which was not deleted to be delivered again, got %s", call.ID)
+	}
+}
@@ -0,0 +1,39 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Producer writes messages to a topic, the way KafkaMQ pushes calls. Messages
+// of the same key are written to the same partition.
+type Producer struct {
+	cluster *cluster
+	topic   string
+}
+
+// NewProducer returns a producer writing to topic on the brokers seeds
+func NewProducer(ctx context.Context, seeds []string, topic string) (*Producer, error) {
+	if len(seeds) == 0 || topic == "" {
+		return nil, errors.New("a kafka producer needs brokers and a topic")
+	}
+	c := newCluster(seeds, []string{topic})
+	if err := c.refreshMetadata(ctx); err != nil {
+		c.close()
+		return nil, err
+	}
+	return &Producer{cluster: c, topic: topic}, nil
+}
+
+// Produce writes a message once it is acked by all the in sync replicas of
+// its partition
+func (p *Producer) Produce(ctx context.Context, key, value []byte) error {
+	return p.cluster.produce(ctx, p.topic, record{Key: key, Value: value, Timestamp: time.Now()})
+}
+
+// Close closes the connections to the brokers
+func (p *Producer) Close() error {
+	p.cluster.close()
+	return nil
+}
@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"time"
+)
+
+var (
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+	errUnsupportedBatch = errors.New("kafka: only uncompressed record batches of magic 2 are supported")
+	errCorruptBatch     = errors.New("kafka: record batch crc mismatch")
+)
+
+// record is a message of a partition
+type record struct {
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+}
+
+// recordBatchHeaderSize is the size of a record batch up to its records
+const recordBatchHeaderSize = 61
+
+// encodeRecordBatch returns a v2 (magic 2) uncompressed record batch of records,
+// which is what produce requests carry since kafka 0.11.
+func encodeRecordBatch(records []record) []byte {
+	now := time.Now()
+	first := now
+	if len(records) > 0 && !records[0].Timestamp.IsZero() {
+		first = records[0].Timestamp
+	}
+	firstTS := first.UnixNano() / int64(time.Millisecond)
+	maxTS := firstTS
+
+	var recs encoder
+	for i, r := range records {
+		ts := firstTS
+		if !r.Timestamp.IsZero() {
+			ts = r.Timestamp.UnixNano() / int64(time.Millisecond)
+		}
+		if ts > maxTS {
+			maxTS = ts
+		}
+
+		var body encoder
+		body.int8(0) // attributes
+		body.varint(ts - firstTS)
+		body.varint(int64(i))
+		body.varbytes(r.Key)
+		body.varbytes(r.Value)
+		body.varint(0) // headers
+
+		recs.varint(int64(len(body.buf)))
+		recs.buf = append(recs.buf, body.buf...)
+	}
+
+	var e encoder
+	e.int64(0)  // base offset, assigned by the broker
+	e.int32(0)  // batch length, set below
+	e.int32(-1) // partition leader epoch
+	e.int8(2)   // magic
+	e.int32(0)  // crc, set below
+	crcStart := len(e.buf)
+	e.int16(0) // attributes: no compression, create time, not transactional
+	e.int32(int32(len(records) - 1))
+	e.int64(firstTS)
+	e.int64(maxTS)
+	e.int64(-1) // producer id
+	e.int16(-1) // producer epoch
+	e.int32(-1) // base sequence
+	e.arrayLen(len(records))
+	e.buf = append(e.buf, recs.buf...)
+
+	binary.BigEndian.PutUint32(e.buf[8:12], uint32(len(e.buf)-12))
+	binary.BigEndian.PutUint32(e.buf[crcStart-4:crcStart], crc32.Checksum(e.buf[crcStart:], castagnoli))
+	return e.buf
+}
+
+// decodeRecordBatches returns the records of a fetch response. The last batch
+// may be cut off by the fetch size, such a batch is ignored and fetched again
+// on the next request.
+func decodeRecordBatches(buf []byte) ([]record, error) {
+	var res []record
+	for len(buf) >= recordBatchHeaderSize {
+		d := decoder{buf: buf}
+		baseOffset := d.int64()
+		length := int(d.int32())
+		if length < recordBatchHeaderSize-12 {
+			return res, errShortBuffer
+		}
+		if 12+length > len(buf) {
+			break // partial batch
+		}
+		batch := buf[:12+length]
+		buf = buf[12+length:]
+
+		d = decoder{buf: batch, off: 16}
+		magic := d.int8()
+		crc := uint32(d.int32())
+		if magic != 2 {
+			return res, errUnsupportedBatch
+		}
+		if crc32.Checksum(batch[21:], castagnoli) != crc {
+			return res, errCorruptBatch
+		}
+		attributes := d.int16()
+		if attributes&0x7 != 0 {
+			return res, errUnsupportedBatch
+		}
+		d.int32() // last offset delta
+		firstTS := d.int64()
+		d.int64() // max timestamp
+		d.int64() // producer id
+		d.int16() // producer epoch
+		d.int32() // base sequence
+		n := d.arrayLen()
+
+		if attributes&0x20 != 0 {
+			continue // control batch of a transaction
+		}
+
+		for i := 0; i < n; i++ {
+			size := d.varint()
+			rd := decoder{buf: d.next(int(size))}
+			if d.err != nil {
+				return res, d.err
+			}
+			rd.int8() // attributes
+			tsDelta := rd.varint()
+			offsetDelta := rd.varint()
+			key := rd.varbytes()
+			value := rd.varbytes()
+			if rd.err != nil {
+				return res, rd.err
+			}
+			ts := firstTS + tsDelta
+			res = append(res, record{
+				Offset:    baseOffset + offsetDelta,
+				Timestamp: time.Unix(ts/1000, (ts%1000)*int64(time.Millisecond)),
+				Key:       key,
+				Value:     value,
+			})
+		}
+	}
+	return res, nil
+}
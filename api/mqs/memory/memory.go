@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/api/mqs"
 	"github.com/google/btree"
@@ -107,6 +108,8 @@ func (mq *MemoryMQ) start() {
 type callItem struct {
 	Call    *models.Call
 	StartAt time.Time
+	// LeaseID is the lease of reserved calls, see Reserve
+	LeaseID string
 }
 
 func (ji *callItem) Less(than btree.Item) bool {
@@ -140,11 +143,12 @@ func (mq *MemoryMQ) Push(ctx context.Context, job *models.Call) (*models.Call, e
 	return mq.pushForce(job)
 }
 
-func (mq *MemoryMQ) pushTimeout(job *models.Call) error {
+func (mq *MemoryMQ) pushTimeout(job *models.Call, leaseID string) error {
 
 	ji := &callItem{
 		Call:    job,
 		StartAt: time.Now().Add(time.Minute),
+		LeaseID: leaseID,
 	}
 	mq.Mutex.Lock()
 	mq.Timeouts[job.ID] = ji
@@ -177,9 +181,14 @@ func (mq *MemoryMQ) Reserve(ctx context.Context) (*models.Call, error) {
 		return nil, nil
 	}
 
+	// the queued call is pushed again if its lease expires, reserve a copy
+	// of it so that its next lease does not change the lease of this one
+	leased := *job
+	leased.LeaseID = id.New().String()
+
 	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
 	log.Debugln("Reserved")
-	return job, mq.pushTimeout(job)
+	return &leased, mq.pushTimeout(job, leased.LeaseID)
 }
 
 func (mq *MemoryMQ) Delete(ctx context.Context, job *models.Call) error {
@@ -187,7 +196,10 @@ func (mq *MemoryMQ) Delete(ctx context.Context, job *models.Call) error {
 
 	mq.Mutex.Lock()
 	defer mq.Mutex.Unlock()
-	_, exists := mq.Timeouts[job.ID]
+	ji, exists := mq.Timeouts[job.ID]
+	if job.LeaseID != "" && (!exists || ji.LeaseID != job.LeaseID) {
+		return models.ErrCallLeaseExpired
+	}
 	if !exists {
 		return errors.New("Not reserved")
 	}
@@ -197,6 +209,24 @@ func (mq *MemoryMQ) Delete(ctx context.Context, job *models.Call) error {
 	return nil
 }
 
+// LeasesCalls is true, reservations are leases which time out after a
+// minute unless they are renewed
+func (mq *MemoryMQ) LeasesCalls() bool {
+	return true
+}
+
+// RenewLease implements models.CallLeaser
+func (mq *MemoryMQ) RenewLease(ctx context.Context, job *models.Call, d time.Duration) error {
+	mq.Mutex.Lock()
+	defer mq.Mutex.Unlock()
+	ji, exists := mq.Timeouts[job.ID]
+	if !exists || ji.LeaseID != job.LeaseID {
+		return models.ErrCallLeaseExpired
+	}
+	ji.StartAt = time.Now().Add(d)
+	return nil
+}
+
 // Close stops the associated goroutines by stopping the ticker
 func (mq *MemoryMQ) Close() error {
 	mq.Ticker.Stop()
@@ -0,0 +1,315 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errConnClosed = errors.New("nats: connection closed")
+
+// msg is a message delivered to the inbox of a conn
+type msg struct {
+	subject string
+	reply   string
+	// status of a message sent by the server itself, e.g. 404 when a pull
+	// request found no messages. 0 for regular messages.
+	status int
+	data   []byte
+}
+
+// conn is a connection speaking the core nats protocol, enough of it to make
+// requests to the jetstream api. Replies of all requests go to a single
+// wildcard inbox subscription.
+type conn struct {
+	nc    net.Conn
+	wlock sync.Mutex
+	w     *bufio.Writer
+
+	inbox string
+
+	lock    sync.Mutex
+	replies map[string]chan *msg
+	next    uint64
+	err     error
+	closed  chan struct{}
+}
+
+type connectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Headers  bool   `json:"headers"`
+	NoResp   bool   `json:"no_responders"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// dial connects to the nats server of u, using the user info of u to authenticate
+func dial(ctx context.Context, u *url.URL) (*conn, error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	var dialer net.Dialer
+	nc, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+
+	rd := bufio.NewReader(nc)
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		nc.Close()
+		return nil, fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+
+	opts := connectOptions{Headers: true, NoResp: true, Name: "fn", Lang: "go", Version: "0.0.1", Protocol: 1}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts.User, opts.Pass = u.User.Username(), pass
+		} else {
+			opts.Token = u.User.Username()
+		}
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	c := &conn{
+		nc:      nc,
+		w:       bufio.NewWriter(nc),
+		inbox:   "_INBOX." + hex.EncodeToString(id[:]) + ".",
+		replies: make(map[string]chan *msg),
+		closed:  make(chan struct{}),
+	}
+	fmt.Fprintf(c.w, "CONNECT %s\r\nSUB %s* 1\r\nPING\r\n", connect, c.inbox)
+	if err := c.w.Flush(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	// the server answers the PING once it processed CONNECT and SUB
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			nc.Close()
+			return nil, fmt.Errorf("nats: %s", strings.TrimSpace(line[4:]))
+		}
+		if strings.HasPrefix(line, "PONG") {
+			break
+		}
+	}
+
+	nc.SetDeadline(time.Time{})
+	go c.readLoop(rd)
+	return c, nil
+}
+
+// publish sends data to subject, asking for replies to reply if it is not empty
+func (c *conn) publish(subject, reply string, data []byte) error {
+	c.wlock.Lock()
+	defer c.wlock.Unlock()
+
+	if reply != "" {
+		fmt.Fprintf(c.w, "PUB %s %s %d\r\n", subject, reply, len(data))
+	} else {
+		fmt.Fprintf(c.w, "PUB %s %d\r\n", subject, len(data))
+	}
+	c.w.Write(data)
+	c.w.WriteString("\r\n")
+	return c.w.Flush()
+}
+
+// request publishes data to subject and waits for the first reply
+func (c *conn) request(ctx context.Context, subject string, data []byte) (*msg, error) {
+	ch := make(chan *msg, 1)
+
+	c.lock.Lock()
+	if c.err != nil {
+		c.lock.Unlock()
+		return nil, c.err
+	}
+	c.next++
+	reply := c.inbox + strconv.FormatUint(c.next, 36)
+	c.replies[reply] = ch
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.replies, reply)
+		c.lock.Unlock()
+	}()
+
+	if err := c.publish(subject, reply, data); err != nil {
+		return nil, err
+	}
+
+	select {
+	case m := <-ch:
+		return m, nil
+	case <-c.closed:
+		return nil, c.closeErr()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *conn) closeErr() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+// readLoop dispatches the messages of the inbox subscription to waiting requests
+func (c *conn) readLoop(rd *bufio.Reader) {
+	err := c.read(rd)
+
+	c.lock.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.lock.Unlock()
+	close(c.closed)
+}
+
+func (c *conn) read(rd *bufio.Reader) error {
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			c.wlock.Lock()
+			c.w.WriteString("PONG\r\n")
+			err = c.w.Flush()
+			c.wlock.Unlock()
+			if err != nil {
+				return err
+			}
+		case "MSG", "HMSG":
+			m, err := readMsg(rd, args)
+			if err != nil {
+				return err
+			}
+			c.lock.Lock()
+			ch, ok := c.replies[m.subject]
+			c.lock.Unlock()
+			if ok {
+				select {
+				case ch <- m:
+				default:
+				}
+			}
+		case "-ERR":
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, args[0])))
+		}
+	}
+}
+
+// readMsg reads the payload of a MSG or HMSG, whose control line is split into args:
+// MSG <subject> <sid> [reply-to] <#bytes>
+// HMSG <subject> <sid> [reply-to] <#header bytes> <#total bytes>
+func readMsg(rd *bufio.Reader, args []string) (*msg, error) {
+	headers := strings.ToUpper(args[0]) == "HMSG"
+	sizes := 1
+	if headers {
+		sizes = 2
+	}
+	if len(args) != 3+sizes && len(args) != 4+sizes {
+		return nil, fmt.Errorf("nats: malformed %s", args[0])
+	}
+
+	m := &msg{subject: args[1]}
+	if len(args) == 4+sizes {
+		m.reply = args[3]
+	}
+	total, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || total < 0 {
+		return nil, fmt.Errorf("nats: malformed %s size", args[0])
+	}
+	hdrLen := 0
+	if headers {
+		if hdrLen, err = strconv.Atoi(args[len(args)-2]); err != nil || hdrLen < 0 || hdrLen > total {
+			return nil, fmt.Errorf("nats: malformed %s header size", args[0])
+		}
+	}
+
+	payload := make([]byte, total+2)
+	if _, err := io.ReadFull(rd, payload); err != nil {
+		return nil, err
+	}
+	payload = payload[:total]
+
+	if headers {
+		m.status = parseStatus(payload[:hdrLen])
+	}
+	m.data = payload[hdrLen:]
+	return m, nil
+}
+
+// parseStatus returns the status code of a header block starting with a
+// "NATS/1.0 404 No Messages" like line, 0 if there is none
+func parseStatus(hdr []byte) int {
+	line := string(hdr)
+	if i := strings.Index(line, "\r\n"); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "NATS/") {
+		return 0
+	}
+	status, _ := strconv.Atoi(fields[1])
+	return status
+}
+
+func (c *conn) close() error {
+	c.lock.Lock()
+	if c.err == nil {
+		c.err = errConnClosed
+	}
+	c.lock.Unlock()
+	return c.nc.Close()
+}
@@ -0,0 +1,382 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultStream  = "FN_CALLS"
+	defaultAckWait = time.Minute
+	requestTimeout = 5 * time.Second
+
+	// jetstream api error codes
+	errCodeStreamNameInUse = 10058
+)
+
+type natsProvider int
+
+func (natsProvider) Supports(url *url.URL) bool {
+	switch url.Scheme {
+	case "nats":
+		return true
+	}
+	return false
+}
+
+func (natsProvider) String() string {
+	return "nats"
+}
+
+// New returns a nats jetstream mq for a URL of the form
+// nats://[user:pass@]host:4222/STREAM?ack_wait=1m
+//
+// Calls are published to the subjects <stream>.p0 to <stream>.p2 (lower cased)
+// of a work queue stream, which is created if it does not exist, and consumed
+// through a durable pull consumer per priority shared by all fn servers.
+//
+// The nats mq is experimental: it speaks the nats and jetstream protocols
+// itself rather than through the nats.go client, and is tested against a
+// nats-server only when NATS_URL is set, see TestNats.
+func (natsProvider) New(url *url.URL) (models.MessageQueue, error) {
+	stream := strings.Trim(url.Path, "/")
+	if stream == "" {
+		stream = defaultStream
+	}
+	ackWait := defaultAckWait
+	if t := url.Query().Get("ack_wait"); t != "" {
+		var err error
+		if ackWait, err = time.ParseDuration(t); err != nil {
+			return nil, fmt.Errorf("invalid nats mq ack_wait %q: %v", t, err)
+		}
+	}
+
+	mq := newNatsMQ(url, stream, ackWait)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := mq.setup(ctx); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"host": url.Host, "stream": stream}).Error("Error setting up nats jetstream mq")
+		return nil, err
+	}
+
+	go mq.keepAlive()
+
+	logrus.Warn("WARNING: Experimental Nats MQ Enabled")
+	logrus.WithFields(logrus.Fields{"host": url.Host, "stream": stream}).Info("Nats jetstream mq initialized")
+	return mq, nil
+}
+
+// NatsMQ is a message queue backed by a nats jetstream work queue stream.
+// Calls are acknowledged once they finished (see models.ReservationKeeper), while
+// they run the reservation is extended periodically. If the server running a
+// call dies, jetstream delivers the call again once the ack wait passed.
+type NatsMQ struct {
+	url      *url.URL
+	stream   string
+	subjects [3]string
+	durables [3]string
+	ackWait  time.Duration
+
+	lock     sync.Mutex
+	c        *conn
+	reserved map[string]string // call id to ack subject
+
+	done chan struct{}
+}
+
+func newNatsMQ(u *url.URL, stream string, ackWait time.Duration) *NatsMQ {
+	mq := &NatsMQ{
+		url:      u,
+		stream:   stream,
+		ackWait:  ackWait,
+		reserved: make(map[string]string),
+		done:     make(chan struct{}),
+	}
+	for i := range mq.subjects {
+		mq.subjects[i] = fmt.Sprintf("%s.p%d", strings.ToLower(stream), i)
+		mq.durables[i] = fmt.Sprintf("fn-p%d", i)
+	}
+	return mq
+}
+
+// conn returns the connection to the server, reconnecting if it was lost
+func (mq *NatsMQ) conn(ctx context.Context) (*conn, error) {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	if mq.c != nil {
+		select {
+		case <-mq.c.closed:
+			logrus.WithError(mq.c.closeErr()).Warn("Lost connection to nats, reconnecting")
+			mq.c = nil
+		default:
+			return mq.c, nil
+		}
+	}
+	c, err := dial(ctx, mq.url)
+	if err != nil {
+		return nil, err
+	}
+	mq.c = c
+	return c, nil
+}
+
+type apiError struct {
+	Code        int    `json:"code"`
+	ErrCode     int    `json:"err_code"`
+	Description string `json:"description"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("nats jetstream: %s (%d)", e.Description, e.ErrCode)
+}
+
+// api makes a request to the jetstream api and decodes the reply into res
+func (mq *NatsMQ) api(ctx context.Context, subject string, req, res interface{}) error {
+	c, err := mq.conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if req != nil {
+		if body, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	m, err := c.request(ctx, subject, body)
+	if err != nil {
+		return err
+	}
+	if m.status == 503 {
+		return fmt.Errorf("nats: no responders for %s, is jetstream enabled?", subject)
+	}
+
+	var reply struct {
+		Error *apiError `json:"error"`
+	}
+	if err := json.Unmarshal(m.data, &reply); err != nil {
+		return err
+	}
+	if reply.Error != nil {
+		return reply.Error
+	}
+	if res != nil {
+		return json.Unmarshal(m.data, res)
+	}
+	return nil
+}
+
+// setup creates the stream and its consumers if they do not exist yet
+func (mq *NatsMQ) setup(ctx context.Context) error {
+	stream := map[string]interface{}{
+		"name":      mq.stream,
+		"subjects":  mq.subjects[:],
+		"retention": "workqueue",
+		"storage":   "file",
+	}
+	err := mq.api(ctx, "$JS.API.STREAM.CREATE."+mq.stream, stream, nil)
+	if aerr, ok := err.(*apiError); ok && aerr.ErrCode == errCodeStreamNameInUse {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, durable := range mq.durables {
+		consumer := map[string]interface{}{
+			"stream_name": mq.stream,
+			"config": map[string]interface{}{
+				"durable_name":   durable,
+				"ack_policy":     "explicit",
+				"ack_wait":       mq.ackWait.Nanoseconds(),
+				"max_deliver":    -1,
+				"filter_subject": mq.subjects[i],
+				"deliver_policy": "all",
+				"replay_policy":  "instant",
+			},
+		}
+		if err := mq.api(ctx, "$JS.API.CONSUMER.DURABLE.CREATE."+mq.stream+"."+durable, consumer, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func priority(job *models.Call) int {
+	if job.Priority == nil || *job.Priority < 0 {
+		return 0
+	}
+	if *job.Priority > 2 {
+		return 2
+	}
+	return int(*job.Priority)
+}
+
+func (mq *NatsMQ) Push(ctx context.Context, job *models.Call) (*models.Call, error) {
+	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+
+	buf, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	// publishing to a stream subject with a reply subject gets an ack once the
+	// message is stored, an error otherwise
+	var ack struct {
+		Stream string `json:"stream"`
+		Seq    uint64 `json:"seq"`
+	}
+	if err := mq.api(ctx, mq.subjects[priority(job)], json.RawMessage(buf), &ack); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{"seq": ack.Seq}).Debugln("Pushed to MQ")
+	return job, nil
+}
+
+// Reserve pulls the next call of the highest priority that has a ready call.
+// Delayed calls that are not due yet are handed back to jetstream to be
+// delivered again once their delay passed.
+func (mq *NatsMQ) Reserve(ctx context.Context) (*models.Call, error) {
+	c, err := mq.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(mq.durables) - 1; i >= 0; i-- {
+		for {
+			reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+			m, err := c.request(reqCtx, "$JS.API.CONSUMER.MSG.NEXT."+mq.stream+"."+mq.durables[i], []byte(`{"batch":1,"no_wait":true}`))
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+			if m.status != 0 || m.reply == "" {
+				break // 404 no messages, 408 request timeout
+			}
+
+			var job models.Call
+			if err := json.Unmarshal(m.data, &job); err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"subject": m.subject}).Error("Dropping malformed call from nats mq")
+				c.publish(m.reply, "", []byte("+TERM"))
+				continue
+			}
+
+			if wait := readyIn(&job); wait > 0 {
+				nak := fmt.Sprintf(`-NAK {"delay": %d}`, wait.Nanoseconds())
+				if err := c.publish(m.reply, "", []byte(nak)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			mq.lock.Lock()
+			mq.reserved[job.ID] = m.reply
+			mq.lock.Unlock()
+
+			_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+			log.Debugln("Reserved")
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// readyIn returns how long the delay of a call has left to run
+func readyIn(job *models.Call) time.Duration {
+	if job.Delay <= 0 {
+		return 0
+	}
+	return time.Until(time.Time(job.CreatedAt).Add(time.Duration(job.Delay) * time.Second))
+}
+
+func (mq *NatsMQ) Delete(ctx context.Context, job *models.Call) error {
+	mq.lock.Lock()
+	reply, ok := mq.reserved[job.ID]
+	delete(mq.reserved, job.ID)
+	mq.lock.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+	defer log.Debugln("Deleted")
+
+	c, err := mq.conn(ctx)
+	if err != nil {
+		return err
+	}
+	return c.publish(reply, "", []byte("+ACK"))
+}
+
+// KeepsReservations is true, calls are acknowledged once they finished
+func (mq *NatsMQ) KeepsReservations() bool {
+	return true
+}
+
+// keepAlive tells jetstream that the reserved calls are still in progress, so
+// that they are not delivered again while they run
+func (mq *NatsMQ) keepAlive() {
+	interval := mq.ackWait / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mq.done:
+			return
+		case <-ticker.C:
+		}
+
+		mq.lock.Lock()
+		replies := make([]string, 0, len(mq.reserved))
+		for _, reply := range mq.reserved {
+			replies = append(replies, reply)
+		}
+		c := mq.c
+		mq.lock.Unlock()
+
+		if c == nil {
+			continue
+		}
+		for _, reply := range replies {
+			if err := c.publish(reply, "", []byte("+WPI")); err != nil {
+				logrus.WithError(err).Warn("Error extending nats mq reservation")
+				break
+			}
+		}
+	}
+}
+
+// Close stops extending reservations and closes the connection. Calls that are
+// still running are delivered again once their ack wait passed.
+func (mq *NatsMQ) Close() error {
+	close(mq.done)
+
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+	if mq.c != nil {
+		return mq.c.close()
+	}
+	return nil
+}
+
+func init() {
+	mqs.AddProvider(natsProvider(0))
+}
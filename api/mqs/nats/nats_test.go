@@ -0,0 +1,215 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+type pub struct {
+	subject, reply, data string
+}
+
+// fakeServer speaks enough of the nats protocol to answer requests with
+// the frames returned by handle. All publishes are sent to pubs.
+func fakeServer(t *testing.T, handle func(p pub) string) (*url.URL, chan pub) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubs := make(chan pub, 100)
+
+	go func() {
+		nc, err := l.Accept()
+		l.Close()
+		if err != nil {
+			return
+		}
+		defer nc.Close()
+		rd := bufio.NewReader(nc)
+		fmt.Fprint(nc, "INFO {\"headers\":true}\r\n")
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args := strings.Fields(line)
+			switch args[0] {
+			case "PING":
+				fmt.Fprint(nc, "PONG\r\n")
+			case "PUB":
+				n, _ := strconv.Atoi(args[len(args)-1])
+				data := make([]byte, n+2)
+				if _, err := io.ReadFull(rd, data); err != nil {
+					return
+				}
+				p := pub{subject: args[1], data: string(data[:n])}
+				if len(args) == 4 {
+					p.reply = args[2]
+				}
+				pubs <- p
+				if frames := handle(p); frames != "" {
+					fmt.Fprint(nc, frames)
+				}
+			}
+		}
+	}()
+
+	return &url.URL{Scheme: "nats", Host: l.Addr().String(), Path: "/CALLS"}, pubs
+}
+
+func hmsg(subject, reply string, status string, data string) string {
+	hdr := "NATS/1.0"
+	if status != "" {
+		hdr += " " + status
+	}
+	hdr += "\r\n\r\n"
+	return fmt.Sprintf("HMSG %s 1 %s %d %d\r\n%s%s\r\n", subject, reply, len(hdr), len(hdr)+len(data), hdr, data)
+}
+
+func TestReserveDelete(t *testing.T) {
+	created, _ := json.Marshal(common.DateTime(time.Now()))
+	delayed := fmt.Sprintf(`{"id":"delayed","delay":60,"created_at":%s}`, created)
+	ready := `{"id":"ready"}`
+
+	u, pubs := fakeServer(t, func(p pub) string {
+		switch p.subject {
+		case "$JS.API.CONSUMER.MSG.NEXT.CALLS.fn-p2", "$JS.API.CONSUMER.MSG.NEXT.CALLS.fn-p1":
+			return hmsg(p.reply, "", "404 No Messages", "")
+		case "$JS.API.CONSUMER.MSG.NEXT.CALLS.fn-p0":
+			if strings.HasSuffix(p.reply, ".3") {
+				return fmt.Sprintf("MSG %s 1 $JS.ACK.CALLS.fn-p0.1.1.1.0.1 %d\r\n%s\r\n", p.reply, len(delayed), delayed)
+			}
+			return fmt.Sprintf("MSG %s 1 $JS.ACK.CALLS.fn-p0.1.2.2.0.0 %d\r\n%s\r\n", p.reply, len(ready), ready)
+		}
+		return ""
+	})
+
+	mq := newNatsMQ(u, "CALLS", time.Minute)
+	defer mq.Close()
+
+	ctx := context.Background()
+	call, err := mq.Reserve(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call == nil || call.ID != "ready" {
+		t.Fatalf("expected to reserve the ready call, got %+v", call)
+	}
+
+	expected := []pub{
+		{subject: "$JS.API.CONSUMER.MSG.NEXT.CALLS.fn-p2"},
+		{subject: "$JS.API.CONSUMER.MSG.NEXT.CALLS.fn-p1"},
+		{subject: "$JS.API.CONSUMER.MSG.NEXT.CALLS.fn-p0"},
+		{subject: "$JS.ACK.CALLS.fn-p0.1.1.1.0.1", data: "-NAK"},
+		{subject: "$JS.API.CONSUMER.MSG.NEXT.CALLS.fn-p0"},
+	}
+	for _, e := range expected {
+		p := <-pubs
+		if p.subject != e.subject || !strings.HasPrefix(p.data, e.data) {
+			t.Fatalf("expected publish to %s %q, got %s %q", e.subject, e.data, p.subject, p.data)
+		}
+	}
+
+	if err := mq.Delete(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	if p := <-pubs; p.subject != "$JS.ACK.CALLS.fn-p0.1.2.2.0.0" || p.data != "+ACK" {
+		t.Fatalf("expected call to be acked, got %s %q", p.subject, p.data)
+	}
+
+	// deleting an unknown call, e.g. a sync one, succeeds without acking anything
+	if err := mq.Delete(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestNats runs against the nats-server of NATS_URL, e.g. nats://localhost:4222,
+// which must have jetstream enabled
+func TestNats(t *testing.T) {
+	server := os.Getenv("NATS_URL")
+	if server == "" {
+		t.Skip("no nats server specified in NATS_URL, skipping")
+		return
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		t.Fatalf("failed to parse url: %v", err)
+	}
+	// stream of this run only
+	u.Path = fmt.Sprintf("/FNTEST%d", time.Now().UnixNano())
+	u.RawQuery = "ack_wait=1s"
+
+	ctx := context.Background()
+	open := func() *NatsMQ {
+		mq, err := natsProvider(0).New(u)
+		if err != nil {
+			t.Fatalf("failed to create nats mq: %v", err)
+		}
+		return mq.(*NatsMQ)
+	}
+	push := func(mq *NatsMQ, id string, priority int32) {
+		if _, err := mq.Push(ctx, &models.Call{ID: id, Priority: &priority}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reserve := func(mq *NatsMQ) *models.Call {
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+			call, err := mq.Reserve(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if call != nil {
+				return call
+			}
+		}
+		t.Fatal("Expected a call to be reserved")
+		return nil
+	}
+
+	mq := open()
+	push(mq, "a", 0)
+	push(mq, "b", 2)
+	// calls of the highest priority are reserved first
+	for _, id := range []string{"b", "a"} {
+		call := reserve(mq)
+		if call.ID != id {
+			t.Fatalf("Expected call %s to be reserved, got %s", id, call.ID)
+		}
+		if err := mq.Delete(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if call, err := mq.Reserve(ctx); call != nil || err != nil {
+		t.Fatalf("Expected deleted calls not to be reserved again, got %+v %v", call, err)
+	}
+
+	// a reserved call which is not deleted is delivered again once its
+	// reservation is no longer extended
+	push(mq, "c", 1)
+	if call := reserve(mq); call.ID != "c" {
+		t.Fatalf("Expected the call pushed to be reserved, got %s", call.ID)
+	}
+	mq.Close()
+
+	mq = open()
+	defer func() {
+		mq.api(ctx, "$JS.API.STREAM.DELETE."+mq.stream, nil, nil)
+		mq.Close()
+	}()
+	if call := reserve(mq); call.ID != "c" {
+		t.Fatalf("Expected the call which was not deleted to be delivered again, got %s", call.ID)
+	}
+}
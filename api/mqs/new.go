@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"time"
 
 	"go.opencensus.io/trace"
 
@@ -73,6 +74,24 @@ func (m *metricMQ) Delete(ctx context.Context, t *models.Call) error {
 	return m.mq.Delete(ctx, t)
 }
 
+func (m *metricMQ) KeepsReservations() bool {
+	return models.KeepsReservations(m.mq)
+}
+
+func (m *metricMQ) LeasesCalls() bool {
+	return models.LeasesCalls(m.mq)
+}
+
+func (m *metricMQ) RenewLease(ctx context.Context, t *models.Call, d time.Duration) error {
+	ctx, span := trace.StartSpan(ctx, "mq_renew_lease")
+	defer span.End()
+	l, ok := m.mq.(models.CallLeaser)
+	if !ok {
+		return models.ErrCallLeaseExpired
+	}
+	return l.RenewLease(ctx, t, d)
+}
+
 // Close closes the underlying message queue
 func (m *metricMQ) Close() error {
 	return m.mq.Close()
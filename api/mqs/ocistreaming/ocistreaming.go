@@ -0,0 +1,445 @@
+package ocistreaming
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/internal/ocisign"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultGroup   = "fn-async"
+	defaultConfig  = "~/.oci/config"
+	defaultProfile = "DEFAULT"
+
+	// instanceTimeout is how long the partitions of a consumer instance stay
+	// reserved to it without a request
+	instanceTimeout = 30 * time.Second
+	batchSize       = 10
+
+	// holdDelayed is how long a call that is not due yet may hold up the
+	// commit of its batch, before it is appended to the stream again
+	holdDelayed = 30 * time.Second
+)
+
+type streamingProvider int
+
+func (streamingProvider) Supports(url *url.URL) bool {
+	switch url.Scheme {
+	case "ocistreaming":
+		return true
+	}
+	return false
+}
+
+func (streamingProvider) String() string {
+	return "ocistreaming"
+}
+
+// New returns an oci streaming mq for a URL of the form
+// ocistreaming://<messages endpoint>/<stream ocid>?group=fn-async&config=~/.oci/config&profile=DEFAULT
+//
+// Requests are signed with the api key of the profile of an oci cli config
+// file. Fn servers consume the stream as instances of a consumer group, the
+// stream partitions are split among them. Streams have no priorities, calls
+// are consumed in the order they were appended.
+func (streamingProvider) New(u *url.URL) (models.MessageQueue, error) {
+	streamID := strings.Trim(u.Path, "/")
+	if u.Host == "" || streamID == "" {
+		return nil, fmt.Errorf("oci streaming mq url must name the messages endpoint and the stream, e.g. ocistreaming://cell-1.streaming.us-phoenix-1.oci.oraclecloud.com/ocid1.stream.oc1...")
+	}
+	q := u.Query()
+	group := q.Get("group")
+	if group == "" {
+		group = defaultGroup
+	}
+	config := q.Get("config")
+	if config == "" {
+		config = defaultConfig
+	}
+	profile := q.Get("profile")
+	if profile == "" {
+		profile = defaultProfile
+	}
+
+	key, err := ocisign.LoadAPIKey(config, profile)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"config": config, "profile": profile}).Error("Error loading oci api key")
+		return nil, err
+	}
+
+	scheme := "https"
+	if q.Get("ssl") == "false" {
+		scheme = "http"
+	}
+	client := &streamClient{
+		base:   scheme + "://" + u.Host + "/20180418/streams/" + url.PathEscape(streamID),
+		key:    key,
+		client: &http.Client{Timeout: time.Minute},
+	}
+
+	host, _ := os.Hostname()
+	mq := newStreamingMQ(client, group, host+"-"+id.New().String())
+	go mq.keepAlive()
+
+	logrus.WithFields(logrus.Fields{"endpoint": u.Host, "stream": streamID, "group": group, "instance": mq.instance}).Info("OCI streaming mq initialized")
+	return mq, nil
+}
+
+// streamClient makes requests to the oci streaming api for a stream
+type streamClient struct {
+	base   string
+	key    *ocisign.APIKey
+	client *http.Client
+}
+
+// apiError is an error response of the oci api
+type apiError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("oci streaming: %s: %s (%d)", e.Code, e.Message, e.Status)
+}
+
+// do makes a request to the stream and decodes the response into out
+func (c *streamClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) (http.Header, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.key.Sign(req, body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		aerr := &apiError{Status: resp.StatusCode}
+		json.Unmarshal(data, aerr)
+		return nil, aerr
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, err
+		}
+	}
+	return resp.Header, nil
+}
+
+type cursor struct {
+	Value string `json:"value"`
+}
+
+type streamMessage struct {
+	Key       []byte    `json:"key"`
+	Value     []byte    `json:"value"`
+	Partition string    `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StreamingMQ is a message queue backed by an oci stream. The mq reads the
+// stream in batches through a group cursor and commits a batch once all of
+// its calls finished (see models.ReservationKeeper). If the server running a
+// batch dies, its partitions move to another instance of the group, which
+// reads the batch again.
+type StreamingMQ struct {
+	client   *streamClient
+	group    string
+	instance string
+
+	lock       sync.Mutex
+	cursor     string     // cursor to read the next batch with
+	nextCursor string     // cursor after the current batch, committed once it finished
+	batch      []*message // current batch
+	reserved   map[string]*message
+
+	done chan struct{}
+}
+
+type message struct {
+	value     []byte
+	readyAt   time.Time
+	heldSince time.Time
+	reserved  bool
+	deleted   bool
+}
+
+func newStreamingMQ(client *streamClient, group, instance string) *StreamingMQ {
+	return &StreamingMQ{
+		client:   client,
+		group:    group,
+		instance: instance,
+		reserved: make(map[string]*message),
+		done:     make(chan struct{}),
+	}
+}
+
+func (mq *StreamingMQ) Push(ctx context.Context, job *models.Call) (*models.Call, error) {
+	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+
+	buf, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	if err := mq.put(ctx, []byte(job.ID), buf); err != nil {
+		return nil, err
+	}
+
+	log.Debugln("Pushed to MQ")
+	return job, nil
+}
+
+func (mq *StreamingMQ) put(ctx context.Context, key, value []byte) error {
+	type entry struct {
+		Key   []byte `json:"key"`
+		Value []byte `json:"value"`
+	}
+	var res struct {
+		Failures int `json:"failures"`
+		Entries  []struct {
+			Error        string `json:"error"`
+			ErrorMessage string `json:"errorMessage"`
+		} `json:"entries"`
+	}
+	in := map[string][]entry{"messages": {{Key: key, Value: value}}}
+	if _, err := mq.client.do(ctx, http.MethodPost, "/messages", nil, in, &res); err != nil {
+		return err
+	}
+	if res.Failures > 0 && len(res.Entries) > 0 {
+		return fmt.Errorf("oci streaming: %s: %s", res.Entries[0].Error, res.Entries[0].ErrorMessage)
+	}
+	return nil
+}
+
+// Reserve returns the next ready call of the current batch, reading the next
+// batch once all calls of the current one finished.
+func (mq *StreamingMQ) Reserve(ctx context.Context) (*models.Call, error) {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	if mq.finished() {
+		if err := mq.nextBatch(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	for _, m := range mq.batch {
+		if m.deleted || m.reserved {
+			continue
+		}
+
+		var job models.Call
+		if err := json.Unmarshal(m.value, &job); err != nil {
+			logrus.WithError(err).Error("Dropping malformed call from oci streaming mq")
+			m.deleted = true
+			continue
+		}
+
+		if m.readyAt.After(now) {
+			if m.heldSince.IsZero() {
+				m.heldSince = now
+			}
+			if now.Sub(m.heldSince) >= holdDelayed && mq.onlyDelayedLeft() {
+				// append the call again instead of holding up the whole stream
+				if err := mq.put(ctx, []byte(job.ID), m.value); err != nil {
+					return nil, err
+				}
+				m.deleted = true
+			}
+			continue
+		}
+
+		m.reserved = true
+		mq.reserved[job.ID] = m
+
+		_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+		log.Debugln("Reserved")
+		return &job, nil
+	}
+	return nil, nil
+}
+
+// finished reports whether all calls of the current batch were deleted
+func (mq *StreamingMQ) finished() bool {
+	for _, m := range mq.batch {
+		if !m.deleted {
+			return false
+		}
+	}
+	return true
+}
+
+// onlyDelayedLeft reports whether the calls of the batch that were not deleted yet are all delayed
+func (mq *StreamingMQ) onlyDelayedLeft() bool {
+	for _, m := range mq.batch {
+		if !m.deleted && (m.reserved || m.heldSince.IsZero()) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextBatch commits the current batch, if any, and reads the next one
+func (mq *StreamingMQ) nextBatch(ctx context.Context) error {
+	var c cursor
+	switch {
+	case mq.nextCursor != "":
+		_, err := mq.client.do(ctx, http.MethodPost, "/commit", url.Values{"cursor": {mq.nextCursor}}, nil, &c)
+		if err != nil {
+			mq.resetCursor(err)
+			return err
+		}
+		mq.nextCursor = ""
+		mq.cursor = c.Value
+	case mq.cursor == "":
+		_, err := mq.client.do(ctx, http.MethodPost, "/groupCursors", nil, map[string]interface{}{
+			"groupName":    mq.group,
+			"instanceName": mq.instance,
+			"type":         "TRIM_HORIZON",
+			"commitOnGet":  false,
+			"timeoutInMs":  int64(instanceTimeout / time.Millisecond),
+		}, &c)
+		if err != nil {
+			return err
+		}
+		mq.cursor = c.Value
+	}
+
+	var msgs []streamMessage
+	header, err := mq.client.do(ctx, http.MethodGet, "/messages", url.Values{"cursor": {mq.cursor}, "limit": {fmt.Sprint(batchSize)}}, nil, &msgs)
+	if err != nil {
+		mq.resetCursor(err)
+		return err
+	}
+
+	mq.batch = mq.batch[:0]
+	for _, sm := range msgs {
+		m := &message{value: sm.Value, readyAt: sm.Timestamp}
+		var delay struct {
+			Delay     int32           `json:"delay"`
+			CreatedAt common.DateTime `json:"created_at"`
+		}
+		if json.Unmarshal(sm.Value, &delay) == nil && delay.Delay > 0 {
+			m.readyAt = time.Time(delay.CreatedAt).Add(time.Duration(delay.Delay) * time.Second)
+		}
+		mq.batch = append(mq.batch, m)
+	}
+	if len(msgs) > 0 {
+		mq.nextCursor = header.Get("opc-next-cursor")
+	} else if next := header.Get("opc-next-cursor"); next != "" {
+		mq.cursor = next
+	}
+	return nil
+}
+
+// resetCursor starts over with a new group cursor after the cursor was
+// rejected, uncommitted calls are read again
+func (mq *StreamingMQ) resetCursor(err error) {
+	if aerr, ok := err.(*apiError); ok && aerr.Status/100 == 4 {
+		logrus.WithError(err).Warn("OCI streaming cursor rejected, creating a new group cursor")
+		mq.cursor, mq.nextCursor, mq.batch = "", "", nil
+		for callID := range mq.reserved {
+			delete(mq.reserved, callID)
+		}
+	}
+}
+
+func (mq *StreamingMQ) Delete(ctx context.Context, job *models.Call) error {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	m, ok := mq.reserved[job.ID]
+	if !ok {
+		return nil
+	}
+	delete(mq.reserved, job.ID)
+	m.deleted = true
+
+	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+	log.Debugln("Deleted")
+	return nil
+}
+
+// KeepsReservations is true, batches are committed once their calls finished
+func (mq *StreamingMQ) KeepsReservations() bool {
+	return true
+}
+
+// keepAlive keeps the partitions of a batch reserved to this instance while its calls run
+func (mq *StreamingMQ) keepAlive() {
+	ticker := time.NewTicker(instanceTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mq.done:
+			return
+		case <-ticker.C:
+		}
+
+		mq.lock.Lock()
+		if mq.nextCursor != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), instanceTimeout/3)
+			var c cursor
+			_, err := mq.client.do(ctx, http.MethodPost, "/heartbeat", url.Values{"cursor": {mq.nextCursor}}, nil, &c)
+			cancel()
+			if err != nil {
+				logrus.WithError(err).Warn("Error sending oci streaming heartbeat")
+			} else if c.Value != "" {
+				mq.nextCursor = c.Value
+			}
+		}
+		mq.lock.Unlock()
+	}
+}
+
+// Close stops the heartbeats, calls of the current batch that did not finish
+// are read again by the instance the partitions move to.
+func (mq *StreamingMQ) Close() error {
+	close(mq.done)
+	return nil
+}
+
+func init() {
+	mqs.AddProvider(streamingProvider(0))
+}
@@ -0,0 +1,119 @@
+package ocistreaming
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/internal/ocisign"
+)
+
+var authRe = regexp.MustCompile(`^Signature version="1",headers="([^"]+)",keyId="([^"]+)",algorithm="rsa-sha256",signature="([^"]+)"$`)
+
+// verify checks the oci signature of a request
+func verify(r *http.Request, pub *rsa.PublicKey) error {
+	m := authRe.FindStringSubmatch(r.Header.Get("authorization"))
+	if m == nil {
+		return fmt.Errorf("malformed authorization %q", r.Header.Get("authorization"))
+	}
+	var lines []string
+	for _, h := range strings.Fields(m[1]) {
+		switch h {
+		case "(request-target)":
+			lines = append(lines, h+": "+strings.ToLower(r.Method)+" "+r.URL.RequestURI())
+		case "host":
+			lines = append(lines, h+": "+r.Host)
+		default:
+			lines = append(lines, h+": "+r.Header.Get(h))
+		}
+	}
+	sig, err := base64.StdEncoding.DecodeString(m[3])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+}
+
+func TestReserveCommit(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batches := [][]string{{`{"id":"a"}`, `{"id":"b"}`}, {}}
+	var requests []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := verify(r, &key.PublicKey); err != nil {
+			t.Errorf("bad signature: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/20180418/streams/stream")
+		requests = append(requests, r.Method+" "+path+" "+r.URL.Query().Get("cursor"))
+
+		switch path {
+		case "/groupCursors":
+			fmt.Fprint(w, `{"value":"c0"}`)
+		case "/commit":
+			fmt.Fprint(w, `{"value":"c2"}`)
+		case "/messages":
+			var msgs []streamMessage
+			for _, v := range batches[0] {
+				msgs = append(msgs, streamMessage{Value: []byte(v), Timestamp: time.Now()})
+			}
+			batches = batches[1:]
+			w.Header().Set("opc-next-cursor", "c1")
+			json.NewEncoder(w).Encode(msgs)
+		}
+	}))
+	defer srv.Close()
+
+	client := &streamClient{
+		base:   srv.URL + "/20180418/streams/stream",
+		key:    ocisign.NewAPIKey("t", "u", "f", key),
+		client: http.DefaultClient,
+	}
+	mq := newStreamingMQ(client, "group", "instance")
+
+	ctx := context.Background()
+	a, err := mq.Reserve(ctx)
+	if err != nil || a == nil || a.ID != "a" {
+		t.Fatalf("expected to reserve a, got %+v %v", a, err)
+	}
+	b, err := mq.Reserve(ctx)
+	if err != nil || b == nil || b.ID != "b" {
+		t.Fatalf("expected to reserve b, got %+v %v", b, err)
+	}
+	if c, _ := mq.Reserve(ctx); c != nil {
+		t.Fatalf("expected nothing to reserve while the batch runs, got %+v", c)
+	}
+
+	mq.Delete(ctx, a)
+	mq.Delete(ctx, b)
+	if c, err := mq.Reserve(ctx); err != nil || c != nil {
+		t.Fatalf("expected an empty batch, got %+v %v", c, err)
+	}
+
+	expected := []string{
+		"POST /groupCursors ",
+		"GET /messages c0",
+		"POST /commit c1",
+		"GET /messages c2",
+	}
+	if strings.Join(requests, "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+}
@@ -0,0 +1,117 @@
+package sqs
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+// sqsClient makes the few SQS api calls the mq needs. The SQS service client
+// of the aws sdk is not vendored, so this sets up a client the same way the
+// generated service clients do and defines the shapes of the calls used.
+type sqsClient struct {
+	*client.Client
+}
+
+func newSQSClient(p client.ConfigProvider, cfgs ...*aws.Config) *sqsClient {
+	c := p.ClientConfig("sqs", cfgs...)
+	svc := &sqsClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "sqs",
+				ServiceID:     "SQS",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2012-11-05",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+	return svc
+}
+
+func (c *sqsClient) call(ctx aws.Context, action string, input, output interface{}) error {
+	req := c.NewRequest(&request.Operation{Name: action, HTTPMethod: "POST", HTTPPath: "/"}, input, output)
+	req.SetContext(ctx)
+	return req.Send()
+}
+
+type createQueueInput struct {
+	_ struct{} `type:"structure"`
+
+	Attributes map[string]*string `locationName:"Attribute" locationNameKey:"Name" locationNameValue:"Value" type:"map" flattened:"true"`
+	QueueName  *string            `type:"string" required:"true"`
+}
+
+type createQueueOutput struct {
+	_ struct{} `type:"structure"`
+
+	QueueUrl *string `type:"string"`
+}
+
+type sendMessageInput struct {
+	_ struct{} `type:"structure"`
+
+	DelaySeconds *int64  `type:"integer"`
+	MessageBody  *string `type:"string" required:"true"`
+	QueueUrl     *string `type:"string" required:"true"`
+}
+
+type sendMessageOutput struct {
+	_ struct{} `type:"structure"`
+
+	MessageId *string `type:"string"`
+}
+
+type receiveMessageInput struct {
+	_ struct{} `type:"structure"`
+
+	MaxNumberOfMessages *int64  `type:"integer"`
+	QueueUrl            *string `type:"string" required:"true"`
+	VisibilityTimeout   *int64  `type:"integer"`
+	WaitTimeSeconds     *int64  `type:"integer"`
+}
+
+type receiveMessageOutput struct {
+	_ struct{} `type:"structure"`
+
+	Messages []*sqsMessage `locationNameList:"Message" type:"list" flattened:"true"`
+}
+
+type sqsMessage struct {
+	_ struct{} `type:"structure"`
+
+	Body          *string `type:"string"`
+	MessageId     *string `type:"string"`
+	ReceiptHandle *string `type:"string"`
+}
+
+type deleteMessageInput struct {
+	_ struct{} `type:"structure"`
+
+	QueueUrl      *string `type:"string" required:"true"`
+	ReceiptHandle *string `type:"string" required:"true"`
+}
+
+type changeMessageVisibilityInput struct {
+	_ struct{} `type:"structure"`
+
+	QueueUrl          *string `type:"string" required:"true"`
+	ReceiptHandle     *string `type:"string" required:"true"`
+	VisibilityTimeout *int64  `type:"integer" required:"true"`
+}
+
+type emptyOutput struct {
+	_ struct{} `type:"structure"`
+}
@@ -0,0 +1,316 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultQueuePrefix = "fn-calls"
+	defaultVisibility  = time.Minute
+
+	// longest delay and visibility timeout sqs supports
+	maxDelay      = 15 * time.Minute
+	maxVisibility = 12 * time.Hour
+)
+
+type sqsProvider int
+
+func (sqsProvider) Supports(url *url.URL) bool {
+	switch url.Scheme {
+	case "sqs":
+		return true
+	}
+	return false
+}
+
+func (sqsProvider) String() string {
+	return "sqs"
+}
+
+// New returns an SQS mq for a URL of the form
+// sqs://[access_key_id:secret_access_key@]host/region/queue-prefix?ssl=true&visibility_timeout=1m
+//
+// host may be left empty to use the public endpoint of the region, and the
+// credentials to use the default aws credential chain. Calls are sent to a queue
+// per priority, <queue-prefix>-0 to <queue-prefix>-2, which are created if they
+// do not exist.
+func (sqsProvider) New(u *url.URL) (models.MessageQueue, error) {
+	strs := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+	region := strs[0]
+	if region == "" {
+		return nil, fmt.Errorf("must provide a region in the path of the sqs mq url, e.g. sqs:///us-east-1/fn-calls")
+	}
+	prefix := defaultQueuePrefix
+	if len(strs) == 2 && strs[1] != "" {
+		prefix = strs[1]
+	}
+
+	visibility := defaultVisibility
+	if v := u.Query().Get("visibility_timeout"); v != "" {
+		var err error
+		if visibility, err = time.ParseDuration(v); err != nil || visibility < time.Second || visibility > maxVisibility {
+			return nil, fmt.Errorf("invalid sqs mq visibility_timeout %q, must be between 1s and %v", v, maxVisibility)
+		}
+	}
+
+	config := &aws.Config{
+		Region:     aws.String(region),
+		DisableSSL: aws.Bool(u.Host != "" && u.Query().Get("ssl") == "false"),
+	}
+	if u.Host != "" {
+		config.Endpoint = aws.String(u.Host)
+	}
+	if u.User != nil {
+		secret, _ := u.User.Password()
+		config.Credentials = credentials.NewStaticCredentials(u.User.Username(), secret, "")
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mq := newSQSMQ(newSQSClient(sess), visibility)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for i := range mq.queues {
+		name := fmt.Sprintf("%s-%d", prefix, i)
+		var out createQueueOutput
+		err := mq.client.call(ctx, "CreateQueue", &createQueueInput{
+			QueueName: aws.String(name),
+			Attributes: map[string]*string{
+				"VisibilityTimeout": aws.String(strconv.Itoa(int(visibility / time.Second))),
+			},
+		}, &out)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"queue": name, "region": region}).Error("Error creating sqs queue")
+			return nil, err
+		}
+		mq.queues[i] = aws.StringValue(out.QueueUrl)
+	}
+
+	go mq.keepAlive()
+
+	logrus.WithFields(logrus.Fields{"queues": mq.queues, "region": region}).Info("SQS mq initialized")
+	return mq, nil
+}
+
+// SQSMQ is a message queue backed by a SQS queue per priority. A reserved
+// call is a received message hidden for the visibility timeout, the lease of
+// which is extended until the call finished (see models.ReservationKeeper).
+// If the server running a call dies, SQS makes the call visible again once
+// the visibility timeout passed.
+type SQSMQ struct {
+	client     *sqsClient
+	queues     [3]string
+	visibility time.Duration
+
+	lock     sync.Mutex
+	reserved map[string]*lease
+
+	done chan struct{}
+}
+
+// lease is the receipt of a reserved call
+type lease struct {
+	queue   string
+	receipt string
+}
+
+func newSQSMQ(client *sqsClient, visibility time.Duration) *SQSMQ {
+	return &SQSMQ{
+		client:     client,
+		visibility: visibility,
+		reserved:   make(map[string]*lease),
+		done:       make(chan struct{}),
+	}
+}
+
+func priority(job *models.Call) int {
+	if job.Priority == nil || *job.Priority < 0 {
+		return 0
+	}
+	if *job.Priority > 2 {
+		return 2
+	}
+	return int(*job.Priority)
+}
+
+func (mq *SQSMQ) Push(ctx context.Context, job *models.Call) (*models.Call, error) {
+	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+
+	buf, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqs delays messages for up to 15 minutes, longer delays are served by
+	// hiding the message again once it is received too early
+	delay := time.Duration(job.Delay) * time.Second
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	var out sendMessageOutput
+	err = mq.client.call(ctx, "SendMessage", &sendMessageInput{
+		QueueUrl:     aws.String(mq.queues[priority(job)]),
+		MessageBody:  aws.String(string(buf)),
+		DelaySeconds: aws.Int64(int64(delay / time.Second)),
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{"message_id": aws.StringValue(out.MessageId)}).Debugln("Pushed to MQ")
+	return job, nil
+}
+
+// Reserve receives the next call of the highest priority queue that has a
+// ready call, hiding it for the visibility timeout.
+func (mq *SQSMQ) Reserve(ctx context.Context) (*models.Call, error) {
+	for i := len(mq.queues) - 1; i >= 0; i-- {
+		for {
+			var out receiveMessageOutput
+			err := mq.client.call(ctx, "ReceiveMessage", &receiveMessageInput{
+				QueueUrl:            aws.String(mq.queues[i]),
+				MaxNumberOfMessages: aws.Int64(1),
+				VisibilityTimeout:   aws.Int64(int64(mq.visibility / time.Second)),
+				WaitTimeSeconds:     aws.Int64(0),
+			}, &out)
+			if err != nil {
+				return nil, err
+			}
+			if len(out.Messages) == 0 {
+				break
+			}
+			m := out.Messages[0]
+			l := &lease{queue: mq.queues[i], receipt: aws.StringValue(m.ReceiptHandle)}
+
+			var job models.Call
+			if err := json.Unmarshal([]byte(aws.StringValue(m.Body)), &job); err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"message_id": aws.StringValue(m.MessageId)}).Error("Dropping malformed call from sqs mq")
+				mq.deleteMessage(ctx, l)
+				continue
+			}
+
+			if wait := readyIn(&job); wait > 0 {
+				if wait > maxVisibility {
+					wait = maxVisibility
+				}
+				if err := mq.changeVisibility(ctx, l, wait); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			mq.lock.Lock()
+			mq.reserved[job.ID] = l
+			mq.lock.Unlock()
+
+			_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+			log.Debugln("Reserved")
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// readyIn returns how long the delay of a call has left to run
+func readyIn(job *models.Call) time.Duration {
+	if job.Delay <= 0 {
+		return 0
+	}
+	return time.Until(time.Time(job.CreatedAt).Add(time.Duration(job.Delay) * time.Second))
+}
+
+func (mq *SQSMQ) Delete(ctx context.Context, job *models.Call) error {
+	mq.lock.Lock()
+	l, ok := mq.reserved[job.ID]
+	delete(mq.reserved, job.ID)
+	mq.lock.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	_, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": job.ID})
+	defer log.Debugln("Deleted")
+	return mq.deleteMessage(ctx, l)
+}
+
+func (mq *SQSMQ) deleteMessage(ctx context.Context, l *lease) error {
+	return mq.client.call(ctx, "DeleteMessage", &deleteMessageInput{
+		QueueUrl:      aws.String(l.queue),
+		ReceiptHandle: aws.String(l.receipt),
+	}, &emptyOutput{})
+}
+
+func (mq *SQSMQ) changeVisibility(ctx context.Context, l *lease, d time.Duration) error {
+	return mq.client.call(ctx, "ChangeMessageVisibility", &changeMessageVisibilityInput{
+		QueueUrl:          aws.String(l.queue),
+		ReceiptHandle:     aws.String(l.receipt),
+		VisibilityTimeout: aws.Int64(int64(d / time.Second)),
+	}, &emptyOutput{})
+}
+
+// KeepsReservations is true, calls are deleted once they finished
+func (mq *SQSMQ) KeepsReservations() bool {
+	return true
+}
+
+// keepAlive extends the visibility timeout of the reserved calls, so that they
+// are not received again while they run
+func (mq *SQSMQ) keepAlive() {
+	ticker := time.NewTicker(mq.visibility / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mq.done:
+			return
+		case <-ticker.C:
+		}
+
+		mq.lock.Lock()
+		leases := make([]*lease, 0, len(mq.reserved))
+		for _, l := range mq.reserved {
+			leases = append(leases, l)
+		}
+		mq.lock.Unlock()
+
+		for _, l := range leases {
+			ctx, cancel := context.WithTimeout(context.Background(), mq.visibility/3)
+			err := mq.changeVisibility(ctx, l, mq.visibility)
+			cancel()
+			if err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"queue": l.queue}).Warn("Error extending sqs mq lease")
+			}
+		}
+	}
+}
+
+// Close stops extending leases, calls that are still running are received
+// again once their visibility timeout passed.
+func (mq *SQSMQ) Close() error {
+	close(mq.done)
+	return nil
+}
+
+func init() {
+	mqs.AddProvider(sqsProvider(0))
+}
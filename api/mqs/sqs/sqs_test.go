@@ -0,0 +1,80 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/fnproject/fn/api/common"
+)
+
+func TestReserveDelete(t *testing.T) {
+	created, _ := json.Marshal(common.DateTime(time.Now()))
+	delayed := fmt.Sprintf(`{"id":"delayed","delay":3600,"created_at":%s}`, created)
+	ready := `{"id":"ready"}`
+
+	// the delayed message is received first, then the ready one
+	messages := []string{delayed, ready}
+	var actions []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		action := r.Form.Get("Action")
+		actions = append(actions, fmt.Sprintf("%s %s %s", action, r.Form.Get("ReceiptHandle"), r.Form.Get("VisibilityTimeout")))
+
+		fmt.Fprintf(w, "<%sResponse><%sResult>", action, action)
+		if action == "ReceiveMessage" && r.Form.Get("QueueUrl") == "q0" && len(messages) > 0 {
+			fmt.Fprintf(w, "<Message><MessageId>m%d</MessageId><ReceiptHandle>r%d</ReceiptHandle><Body>%s</Body></Message>",
+				len(messages), len(messages), html.EscapeString(messages[0]))
+			messages = messages[1:]
+		}
+		fmt.Fprintf(w, "</%sResult></%sResponse>", action, action)
+	}))
+	defer srv.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+	mq := newSQSMQ(newSQSClient(sess), time.Minute)
+	mq.queues = [3]string{"q0", "q1", "q2"}
+
+	ctx := context.Background()
+	call, err := mq.Reserve(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call == nil || call.ID != "ready" {
+		t.Fatalf("expected to reserve the ready call, got %+v", call)
+	}
+	if err := mq.Delete(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"ReceiveMessage  60",
+		"ReceiveMessage  60",
+		"ReceiveMessage  60",
+		"ChangeMessageVisibility r2 3599",
+		"ReceiveMessage  60",
+		"DeleteMessage r1 ",
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected actions %v, got %v", expected, actions)
+	}
+	for i := range expected {
+		// the delay left may be rounded up to the full hour
+		if actions[i] != expected[i] && !(i == 3 && actions[i] == "ChangeMessageVisibility r2 3600") {
+			t.Fatalf("expected actions %v, got %v", expected, actions)
+		}
+	}
+}
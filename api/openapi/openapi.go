@@ -0,0 +1,329 @@
+// Package openapi builds OpenAPI v3 documents from the routes of a server and
+// the Go types of the models its handlers read and write, so that documents
+// cannot drift from the code they describe.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Version is the version of the OpenAPI specification of the documents built
+const Version = "3.0.3"
+
+const contentTypeJSON = "application/json"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem holds the operations of a path by lower case method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Schema is the subset of the OpenAPI schema object used to describe Go
+// types. The empty schema allows any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Endpoint describes what the handler of a route reads and writes. Path
+// parameters are taken from the route itself.
+type Endpoint struct {
+	// ID names the operation, unique within the document
+	ID      string
+	Summary string
+	// Query lists the query parameters read by the handler
+	Query []string
+	// Request is a value of the type of the body read, nil if none is read
+	Request interface{}
+	// Response is a value of the type of the body written, nil if none is
+	// written
+	Response interface{}
+	// Status is the status of a successful response, 200 if unset
+	Status int
+	// ContentType is the type of the bodies read and written, JSON if unset.
+	// Bodies of other types are described as raw strings.
+	ContentType string
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	dateTimeType      = reflect.TypeOf(common.DateTime{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Builder adds the operations of routes to a document, defining the schemas
+// of the types they use as components.
+type Builder struct {
+	doc   *Document
+	names map[reflect.Type]string
+	error *Schema
+}
+
+// NewBuilder returns a builder of a document titled title, whose error
+// responses have the type of errorModel.
+func NewBuilder(title, version string, errorModel interface{}) *Builder {
+	b := &Builder{
+		doc: &Document{
+			OpenAPI:    Version,
+			Info:       Info{Title: title, Version: version},
+			Paths:      make(map[string]*PathItem),
+			Components: Components{Schemas: make(map[string]*Schema)},
+		},
+		names: make(map[reflect.Type]string),
+	}
+	b.error = b.Schema(reflect.TypeOf(errorModel))
+	return b
+}
+
+// Add adds the operation of the route method path, a gin path whose
+// parameters are named :param or *param, served as described by e.
+func (b *Builder) Add(method, path string, e *Endpoint) {
+	op := &Operation{
+		OperationID: e.ID,
+		Summary:     e.Summary,
+		Responses:   make(map[string]*Response),
+	}
+
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if len(s) > 1 && (s[0] == ':' || s[0] == '*') {
+			op.Parameters = append(op.Parameters, &Parameter{Name: s[1:], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+			s = "{" + s[1:] + "}"
+		}
+		segments = append(segments, s)
+	}
+	// tag operations by the resource they are under, e.g. apps
+	if tag := resource(path); tag != "" {
+		op.Tags = []string{tag}
+	}
+	for _, q := range e.Query {
+		op.Parameters = append(op.Parameters, &Parameter{Name: q, In: "query", Schema: &Schema{Type: "string"}})
+	}
+
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = contentTypeJSON
+	}
+	if e.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{contentType: {Schema: b.body(contentType, e.Request)}},
+		}
+	}
+
+	status := e.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	res := &Response{Description: http.StatusText(status)}
+	if e.Response != nil {
+		res.Content = map[string]*MediaType{contentType: {Schema: b.body(contentType, e.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = res
+	op.Responses["default"] = &Response{
+		Description: "Error",
+		Content:     map[string]*MediaType{contentTypeJSON: {Schema: b.error}},
+	}
+
+	p := strings.Join(segments, "/")
+	item, ok := b.doc.Paths[p]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[p] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// Document returns the document built so far
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+func (b *Builder) body(contentType string, v interface{}) *Schema {
+	if contentType != contentTypeJSON {
+		return &Schema{Type: "string"}
+	}
+	return b.Schema(reflect.TypeOf(v))
+}
+
+// Schema returns the schema of the JSON encoding of values of t. Named struct
+// types are defined as components and referred to.
+func (b *Builder) Schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType || t == dateTimeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case implements(t, jsonMarshalerType):
+		return &Schema{}
+	case implements(t, textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return b.ref(t)
+	}
+	return &Schema{}
+}
+
+func (b *Builder) ref(t reflect.Type) *Schema {
+	name, ok := b.names[t]
+	if !ok {
+		name = componentName(t)
+		for i := 2; b.doc.Components.Schemas[name] != nil; i++ {
+			name = componentName(t) + strconv.Itoa(i)
+		}
+		b.names[t] = name
+		// reserve the name before defining the schema, which may refer to t
+		b.doc.Components.Schemas[name] = &Schema{}
+		*b.doc.Components.Schemas[name] = *b.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object returns the schema of a struct, with the fields of its embedded
+// structs inlined as encoding/json does
+func (b *Builder) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := fieldName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for n, p := range b.object(ft).Properties {
+					if _, ok := s.Properties[n]; !ok {
+						s.Properties[n] = p
+					}
+				}
+				continue
+			}
+			name = f.Name
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = b.Schema(f.Type)
+	}
+	return s
+}
+
+// fieldName returns the name of the JSON property of a field, empty for
+// untagged fields, or false if the field is not encoded
+func fieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" && !f.Anonymous {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	return strings.Split(tag, ",")[0], true
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
+
+func componentName(t reflect.Type) string {
+	r := []rune(t.Name())
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// resource returns the first segment of path after the version, if any
+func resource(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 1 && segments[0] == "v2" {
+		return segments[1]
+	}
+	return segments[0]
+}
@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+type limits struct {
+	Memory uint64 `json:"memory,omitempty"`
+}
+
+type thing struct {
+	ID string `json:"id"`
+	limits
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels"`
+	Parent  *thing            `json:"parent,omitempty"`
+	Created common.DateTime   `json:"created_at"`
+	Secret  string            `json:"-"`
+	hidden  string
+}
+
+func TestSchema(t *testing.T) {
+	b := NewBuilder("test", "1", struct {
+		Message string `json:"message"`
+	}{})
+
+	s := b.Schema(reflect.TypeOf(&thing{}))
+	if s.Ref != "#/components/schemas/Thing" {
+		t.Fatalf("Expected a reference to the thing component, got %+v", s)
+	}
+
+	c := b.Document().Components.Schemas["Thing"]
+	for name, want := range map[string]string{
+		"id":         "string",
+		"memory":     "integer",
+		"tags":       "array",
+		"labels":     "object",
+		"created_at": "string",
+	} {
+		if p := c.Properties[name]; p == nil || p.Type != want {
+			t.Errorf("Expected property %s of type %s, got %+v", name, want, p)
+		}
+	}
+	if p := c.Properties["parent"]; p == nil || p.Ref != s.Ref {
+		t.Errorf("Expected the parent to refer to the thing component, got %+v", p)
+	}
+	if len(c.Properties) != 6 {
+		t.Errorf("Expected unencoded fields to be left out, got %v", c.Properties)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	b := NewBuilder("test", "1", struct{}{})
+	b.Add("DELETE", "/v2/things/:thingID", &Endpoint{ID: "DeleteThing", Status: 204})
+	b.Add("GET", "/t/:app/*source", &Endpoint{ID: "Trigger", Query: []string{"q"}})
+
+	doc := b.Document()
+	op := (*doc.Paths["/v2/things/{thingID}"])["delete"]
+	if op == nil || op.Responses["204"] == nil || op.Responses["default"] == nil || op.Tags[0] != "things" {
+		t.Fatalf("Expected the operation deleting things, got %+v", op)
+	}
+	op = (*doc.Paths["/t/{app}/{source}"])["get"]
+	if op == nil || len(op.Parameters) != 3 || op.Parameters[2].In != "query" {
+		t.Fatalf("Expected path and query parameters, got %+v", op)
+	}
+}
@@ -0,0 +1,154 @@
+package otlp
+
+import (
+	"sort"
+	"strconv"
+
+	"go.opencensus.io/stats/view"
+)
+
+// cumulative aggregation temporality of OTLP, views aggregate since they were
+// registered
+const aggregationTemporalityCumulative = 2
+
+type metricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	Sum         *sum       `json:"sum,omitempty"`
+	Gauge       *gauge     `json:"gauge,omitempty"`
+	Histogram   *histogram `json:"histogram,omitempty"`
+}
+
+type sum struct {
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type histogram struct {
+	DataPoints             []histogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type numberDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             *string    `json:"asInt,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+}
+
+type histogramDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+	Min               float64    `json:"min"`
+	Max               float64    `json:"max"`
+}
+
+func (e *Exporter) metrics(views map[string]*view.Data) *metricsRequest {
+	names := make([]string, 0, len(views))
+	for name := range views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]metric, 0, len(views))
+	for _, name := range names {
+		if m, ok := convertView(views[name]); ok {
+			out = append(out, m)
+		}
+	}
+	return &metricsRequest{ResourceMetrics: []resourceMetrics{{
+		Resource:     e.resource,
+		ScopeMetrics: []scopeMetrics{{Scope: scope{Name: e.opts.ServiceName}, Metrics: out}},
+	}}}
+}
+
+// convertView returns the metric of the data of a view. Count views are
+// monotonic sums, sum views are not as fn records gauges like the calls queued
+// with them.
+func convertView(vd *view.Data) (metric, bool) {
+	m := metric{Name: vd.View.Name, Description: vd.View.Description}
+	if vd.View.Measure != nil {
+		m.Unit = vd.View.Measure.Unit()
+	}
+	start, end := nanos(vd.Start), nanos(vd.End)
+
+	switch vd.View.Aggregation.Type {
+	case view.AggTypeCount, view.AggTypeSum:
+		m.Sum = &sum{
+			AggregationTemporality: aggregationTemporalityCumulative,
+			IsMonotonic:            vd.View.Aggregation.Type == view.AggTypeCount,
+		}
+	case view.AggTypeLastValue:
+		m.Gauge = &gauge{}
+	case view.AggTypeDistribution:
+		m.Histogram = &histogram{AggregationTemporality: aggregationTemporalityCumulative}
+	default:
+		return m, false
+	}
+
+	for _, row := range vd.Rows {
+		attrs := make([]keyValue, 0, len(row.Tags))
+		for _, t := range row.Tags {
+			attrs = append(attrs, stringAttribute(t.Key.Name(), t.Value))
+		}
+		point := numberDataPoint{Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: end}
+
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			v := strconv.FormatInt(data.Value, 10)
+			point.AsInt = &v
+			m.Sum.DataPoints = append(m.Sum.DataPoints, point)
+		case *view.SumData:
+			v := data.Value
+			point.AsDouble = &v
+			m.Sum.DataPoints = append(m.Sum.DataPoints, point)
+		case *view.LastValueData:
+			v := data.Value
+			point.AsDouble = &v
+			m.Gauge.DataPoints = append(m.Gauge.DataPoints, point)
+		case *view.DistributionData:
+			counts := make([]string, len(data.CountPerBucket))
+			for i, c := range data.CountPerBucket {
+				counts[i] = strconv.FormatInt(c, 10)
+			}
+			m.Histogram.DataPoints = append(m.Histogram.DataPoints, histogramDataPoint{
+				Attributes:        attrs,
+				StartTimeUnixNano: start,
+				TimeUnixNano:      end,
+				Count:             strconv.FormatInt(data.Count, 10),
+				Sum:               data.Mean * float64(data.Count),
+				BucketCounts:      counts,
+				ExplicitBounds:    vd.View.Aggregation.Buckets,
+				Min:               data.Min,
+				Max:               data.Max,
+			})
+		}
+	}
+	return m, true
+}
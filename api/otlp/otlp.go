@@ -0,0 +1,244 @@
+// Package otlp exports the spans and the views of fn to an OpenTelemetry
+// collector with OTLP over http, using its JSON encoding. fn is instrumented
+// with OpenCensus, the exporter translates what OpenCensus records, so it
+// runs alongside the jaeger, zipkin and prometheus exporters. See
+// https://opentelemetry.io/docs/specs/otlp/ for the protocol.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+)
+
+const (
+	tracesPath  = "/v1/traces"
+	metricsPath = "/v1/metrics"
+
+	defaultInterval = 5 * time.Second
+	// spans recorded between two exports past this are dropped
+	maxSpans = 4096
+)
+
+// Options configures an Exporter
+type Options struct {
+	// Endpoint is the base url of the collector, e.g. http://localhost:4318
+	Endpoint string
+	// ServiceName is the service.name of the resource exported, fn if empty
+	ServiceName string
+	// Interval is how often spans and views are sent to the collector
+	Interval time.Duration
+	// Client sends the exports, http.DefaultClient if nil
+	Client *http.Client
+	// OnError is called with the errors of exports
+	OnError func(error)
+}
+
+// Exporter is a trace.Exporter and a view.Exporter that sends spans and views
+// to an OpenTelemetry collector in batches, see Options.
+type Exporter struct {
+	opts     Options
+	resource resource
+
+	mu      sync.Mutex
+	spans   []*trace.SpanData
+	dropped int
+	views   map[string]*view.Data
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ trace.Exporter = new(Exporter)
+var _ view.Exporter = new(Exporter)
+
+// NewExporter returns an exporter sending to the collector of o, it runs until
+// Stop is called
+func NewExporter(o Options) (*Exporter, error) {
+	if o.Endpoint == "" {
+		return nil, fmt.Errorf("otlp: missing collector endpoint")
+	}
+	if !strings.HasPrefix(o.Endpoint, "http://") && !strings.HasPrefix(o.Endpoint, "https://") {
+		return nil, fmt.Errorf("otlp: collector endpoint %q is not an http url", o.Endpoint)
+	}
+	o.Endpoint = strings.TrimSuffix(o.Endpoint, "/")
+	if o.ServiceName == "" {
+		o.ServiceName = "fn"
+	}
+	if o.Interval <= 0 {
+		o.Interval = defaultInterval
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	if o.OnError == nil {
+		o.OnError = func(error) {}
+	}
+
+	e := &Exporter{
+		opts:     o,
+		resource: resource{Attributes: []keyValue{stringAttribute("service.name", o.ServiceName)}},
+		views:    make(map[string]*view.Data),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go e.loop()
+	return e, nil
+}
+
+// ExportSpan implements trace.Exporter, spans are sent with the next batch
+func (e *Exporter) ExportSpan(sd *trace.SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.spans) >= maxSpans {
+		e.dropped++
+		return
+	}
+	e.spans = append(e.spans, sd)
+}
+
+// ExportView implements view.Exporter. Views are cumulative, only the latest
+// data of each is sent with the next batch.
+func (e *Exporter) ExportView(vd *view.Data) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.views[vd.View.Name] = vd
+}
+
+// Stop sends what is left and stops the exporter
+func (e *Exporter) Stop() {
+	close(e.stop)
+	<-e.done
+}
+
+func (e *Exporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.Flush()
+		case <-e.stop:
+			e.Flush()
+			return
+		}
+	}
+}
+
+// Flush sends the spans and views recorded since the last batch
+func (e *Exporter) Flush() {
+	e.mu.Lock()
+	spans, dropped, views := e.spans, e.dropped, e.views
+	e.spans, e.dropped, e.views = nil, 0, make(map[string]*view.Data)
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		e.opts.OnError(fmt.Errorf("otlp: dropped %d spans over the limit of a batch", dropped))
+	}
+	if len(spans) > 0 {
+		if err := e.post(tracesPath, e.traces(spans)); err != nil {
+			e.opts.OnError(err)
+		}
+	}
+	if len(views) > 0 {
+		if err := e.post(metricsPath, e.metrics(views)); err != nil {
+			e.opts.OnError(err)
+		}
+	}
+}
+
+func (e *Exporter) post(path string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.opts.Interval)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, e.opts.Endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp: export to %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp: export to %s failed with status %d", path, resp.StatusCode)
+	}
+	return nil
+}
+
+// the types below are the JSON encoding of the OTLP protobuf messages. 64 bit
+// integers are encoded as strings and trace and span ids in hex.
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+func stringAttribute(k, v string) keyValue {
+	return keyValue{Key: k, Value: anyValue{StringValue: &v}}
+}
+
+func attributes(attrs map[string]interface{}) []keyValue {
+	kvs := make([]keyValue, 0, len(attrs))
+	for k, v := range attrs {
+		kv := keyValue{Key: k}
+		switch v := v.(type) {
+		case string:
+			kv.Value.StringValue = &v
+		case bool:
+			kv.Value.BoolValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			kv.Value.IntValue = &s
+		case float64:
+			kv.Value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			kv.Value.StringValue = &s
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+func nanos(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
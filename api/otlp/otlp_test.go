@@ -0,0 +1,124 @@
+package otlp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+)
+
+type collector struct {
+	mu     sync.Mutex
+	bodies map[string][]map[string]interface{}
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	var body map[string]interface{}
+	if err := json.Unmarshal(b, &body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.bodies[r.URL.Path] = append(c.bodies[r.URL.Path], body)
+	c.mu.Unlock()
+}
+
+func TestExporter(t *testing.T) {
+	c := &collector{bodies: make(map[string][]map[string]interface{})}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	var errs []error
+	e, err := NewExporter(Options{
+		Endpoint: srv.URL + "/",
+		Interval: time.Hour,
+		OnError:  func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	e.ExportSpan(&trace.SpanData{
+		SpanContext: trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}, TraceOptions: 1},
+		Name:        "serve_http",
+		SpanKind:    trace.SpanKindServer,
+		StartTime:   start,
+		EndTime:     start.Add(time.Second),
+		Attributes:  map[string]interface{}{"fn_id": "abc"},
+		Status:      trace.Status{Code: 2, Message: "boom"},
+	})
+
+	key, _ := tag.NewKey("fn_path")
+	m := stats.Int64("otlp_test_calls", "calls", stats.UnitDimensionless)
+	v := &view.View{Name: m.Name(), Measure: m, TagKeys: []tag.Key{key}, Aggregation: view.Distribution(1, 10)}
+	e.ExportView(&view.Data{
+		View:  v,
+		Start: start,
+		End:   start.Add(time.Second),
+		Rows: []*view.Row{{
+			Tags: []tag.Tag{{Key: key, Value: "/hello"}},
+			Data: &view.DistributionData{Count: 2, Min: 1, Max: 5, Mean: 3, CountPerBucket: []int64{0, 2, 0}},
+		}},
+	})
+
+	e.Stop()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected export errors: %v", errs)
+	}
+
+	traces := c.bodies[tracesPath]
+	if len(traces) != 1 {
+		t.Fatalf("expected one traces export, got %d", len(traces))
+	}
+	span := traces[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	if span["traceId"] != "01000000000000000000000000000000" || span["spanId"] != "0200000000000000" {
+		t.Fatalf("unexpected span ids: %v", span)
+	}
+	if span["name"] != "serve_http" || span["kind"] != float64(2) {
+		t.Fatalf("unexpected span: %v", span)
+	}
+	if span["status"].(map[string]interface{})["code"] != float64(2) {
+		t.Fatalf("expected an error status: %v", span["status"])
+	}
+
+	metrics := c.bodies[metricsPath]
+	if len(metrics) != 1 {
+		t.Fatalf("expected one metrics export, got %d", len(metrics))
+	}
+	metric := metrics[0]["resourceMetrics"].([]interface{})[0].(map[string]interface{})["scopeMetrics"].([]interface{})[0].(map[string]interface{})["metrics"].([]interface{})[0].(map[string]interface{})
+	if metric["name"] != "otlp_test_calls" {
+		t.Fatalf("unexpected metric: %v", metric)
+	}
+	point := metric["histogram"].(map[string]interface{})["dataPoints"].([]interface{})[0].(map[string]interface{})
+	if point["count"] != "2" || point["sum"] != float64(6) {
+		t.Fatalf("unexpected histogram: %v", point)
+	}
+	attrs := point["attributes"].([]interface{})
+	if len(attrs) != 1 || attrs[0].(map[string]interface{})["key"] != "fn_path" {
+		t.Fatalf("expected the tags of the row as attributes, got %v", attrs)
+	}
+
+	// nothing new was recorded, nothing is sent
+	e.Flush()
+	if len(c.bodies[tracesPath]) != 1 || len(c.bodies[metricsPath]) != 1 {
+		t.Fatal("expected no exports without new data")
+	}
+}
+
+func TestNewExporterEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "localhost:4318", "grpc://localhost:4317"} {
+		if _, err := NewExporter(Options{Endpoint: endpoint}); err == nil {
+			t.Errorf("expected %q to be rejected", endpoint)
+		}
+	}
+}
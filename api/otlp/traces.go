@@ -0,0 +1,140 @@
+package otlp
+
+import (
+	"encoding/hex"
+
+	"go.opencensus.io/trace"
+)
+
+// span kinds and status codes of OTLP
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+
+	statusCodeError = 2
+)
+
+type tracesRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	TraceState        string     `json:"traceState,omitempty"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Events            []event    `json:"events,omitempty"`
+	Links             []link     `json:"links,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type event struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []keyValue `json:"attributes,omitempty"`
+}
+
+type link struct {
+	TraceID    string     `json:"traceId"`
+	SpanID     string     `json:"spanId"`
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *Exporter) traces(spans []*trace.SpanData) *tracesRequest {
+	out := make([]span, 0, len(spans))
+	for _, sd := range spans {
+		out = append(out, convertSpan(sd))
+	}
+	return &tracesRequest{ResourceSpans: []resourceSpans{{
+		Resource:   e.resource,
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: e.opts.ServiceName}, Spans: out}},
+	}}}
+}
+
+func convertSpan(sd *trace.SpanData) span {
+	s := span{
+		TraceID:           hex.EncodeToString(sd.TraceID[:]),
+		SpanID:            hex.EncodeToString(sd.SpanID[:]),
+		Name:              sd.Name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: nanos(sd.StartTime),
+		EndTimeUnixNano:   nanos(sd.EndTime),
+		Attributes:        attributes(sd.Attributes),
+	}
+	if sd.ParentSpanID != (trace.SpanID{}) {
+		s.ParentSpanID = hex.EncodeToString(sd.ParentSpanID[:])
+	}
+	if sd.Tracestate != nil {
+		for i, e := range sd.Tracestate.Entries() {
+			if i > 0 {
+				s.TraceState += ","
+			}
+			s.TraceState += e.Key + "=" + e.Value
+		}
+	}
+	switch sd.SpanKind {
+	case trace.SpanKindServer:
+		s.Kind = spanKindServer
+	case trace.SpanKindClient:
+		s.Kind = spanKindClient
+	}
+
+	// OpenCensus codes are the gRPC ones, where 0 is OK
+	if sd.Code != 0 {
+		s.Status = status{Code: statusCodeError, Message: sd.Message}
+	}
+
+	for _, a := range sd.Annotations {
+		s.Events = append(s.Events, event{TimeUnixNano: nanos(a.Time), Name: a.Message, Attributes: attributes(a.Attributes)})
+	}
+	for _, m := range sd.MessageEvents {
+		name := "message"
+		switch m.EventType {
+		case trace.MessageEventTypeSent:
+			name = "message.sent"
+		case trace.MessageEventTypeRecv:
+			name = "message.received"
+		}
+		s.Events = append(s.Events, event{TimeUnixNano: nanos(m.Time), Name: name, Attributes: attributes(map[string]interface{}{
+			"message.id":                m.MessageID,
+			"message.uncompressed_size": m.UncompressedByteSize,
+			"message.compressed_size":   m.CompressedByteSize,
+		})})
+	}
+	for _, l := range sd.Links {
+		attrs := attributes(l.Attributes)
+		switch l.Type {
+		case trace.LinkTypeChild:
+			attrs = append(attrs, stringAttribute("link.type", "child"))
+		case trace.LinkTypeParent:
+			attrs = append(attrs, stringAttribute("link.type", "parent"))
+		}
+		s.Links = append(s.Links, link{
+			TraceID:    hex.EncodeToString(l.TraceID[:]),
+			SpanID:     hex.EncodeToString(l.SpanID[:]),
+			Attributes: attrs,
+		})
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package respcache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key     string
+	entry   *Entry
+	size    int
+	expires time.Time
+}
+
+// memory is an lru of responses bounded by their number and total size
+type memory struct {
+	lock       sync.Mutex
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	entries    map[string]*list.Element
+	order      *list.List // most recently used first
+}
+
+// NewMemory returns a store keeping at most maxEntries responses of at most
+// maxBytes together in memory, evicting the least recently used first.
+func NewMemory(maxEntries, maxBytes int) Store {
+	return &memory{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (m *memory) Get(ctx context.Context, key string) (*Entry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	me := e.Value.(*memoryEntry)
+	if time.Now().After(me.expires) {
+		m.remove(e)
+		return nil, nil
+	}
+	m.order.MoveToFront(e)
+	return me.entry, nil
+}
+
+func (m *memory) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error {
+	size := entry.size()
+	if size > m.maxBytes {
+		return nil // would evict everything else
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if e, ok := m.entries[key]; ok {
+		m.remove(e)
+	}
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, entry: entry, size: size, expires: time.Now().Add(ttl)})
+	m.bytes += size
+	for m.order.Len() > m.maxEntries || m.bytes > m.maxBytes {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+func (m *memory) remove(e *list.Element) {
+	me := m.order.Remove(e).(*memoryEntry)
+	delete(m.entries, me.key)
+	m.bytes -= me.size
+}
@@ -0,0 +1,74 @@
+package respcache
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// redisStore keeps responses in redis as json, expired by redis
+type redisStore struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+func newRedis(u *url.URL) (Store, error) {
+	pool := &redis.Pool{
+		MaxIdle:     128,
+		MaxActive:   512,
+		Wait:        true,
+		IdleTimeout: 300 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(u.String())
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	// fail on start rather than on the first cached invocation
+	conn := pool.Get()
+	defer conn.Close()
+	if err := conn.Err(); err != nil {
+		return nil, err
+	}
+	return &redisStore{pool: pool, prefix: u.Path + "respcache:"}, nil
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) (*Entry, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	b, err := redis.Bytes(conn.Do("GET", r.prefix+key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, e *Entry, ttl time.Duration) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	ms := int64(ttl / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	_, err = conn.Do("SET", r.prefix+key, b, "PX", ms)
+	return err
+}
@@ -0,0 +1,84 @@
+// Package respcache stores the responses of fns with a response cache, see
+// models.FnResponseCacheAnnotation.
+package respcache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Entry is a cached response
+type Entry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+func (e *Entry) size() int {
+	n := len(e.Body)
+	for k, vs := range e.Header {
+		n += len(k)
+		for _, v := range vs {
+			n += len(v)
+		}
+	}
+	return n
+}
+
+// Store is where cached responses are kept
+type Store interface {
+	// Get returns the response stored for key, nil if there is none or it expired.
+	Get(ctx context.Context, key string) (*Entry, error)
+
+	// Set stores the response for key for ttl.
+	Set(ctx context.Context, key string, e *Entry, ttl time.Duration) error
+}
+
+const (
+	defaultMaxEntries = 10000
+	defaultMaxBytes   = 64 * 1024 * 1024
+)
+
+// New returns the store for a url, options are one of:
+//
+//	memory://?entries=10000&max_bytes=67108864 an lru of at most entries
+//	responses of at most max_bytes together, in this process
+//	redis://host:port/prefix responses shared by all servers using the redis
+func New(u string) (Store, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response cache url %s: %v", u, err)
+	}
+	switch parsed.Scheme {
+	case "memory":
+		q := parsed.Query()
+		entries, err := intParam(q, "entries", defaultMaxEntries)
+		if err != nil {
+			return nil, err
+		}
+		maxBytes, err := intParam(q, "max_bytes", defaultMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewMemory(entries, maxBytes), nil
+	case "redis":
+		return newRedis(parsed)
+	}
+	return nil, fmt.Errorf("response cache url %s has an unsupported scheme, expected memory or redis", u)
+}
+
+func intParam(q url.Values, name string, def int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i <= 0 {
+		return 0, fmt.Errorf("invalid response cache %s %q", name, v)
+	}
+	return i, nil
+}
@@ -0,0 +1,73 @@
+package respcache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	s, err := New("memory://?entries=2&max_bytes=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := func(body string) *Entry {
+		return &Entry{Status: http.StatusOK, Header: http.Header{}, Body: []byte(body)}
+	}
+	get := func(key string) string {
+		e, err := s.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e == nil {
+			return ""
+		}
+		return string(e.Body)
+	}
+
+	s.Set(ctx, "a", entry("aaa"), time.Minute)
+	s.Set(ctx, "b", entry("bbb"), time.Minute)
+	if get("a") != "aaa" || get("b") != "bbb" {
+		t.Fatal("expected stored responses to be returned")
+	}
+
+	// a was used least recently
+	s.Set(ctx, "c", entry("ccc"), time.Minute)
+	if get("a") != "" || get("b") != "bbb" || get("c") != "ccc" {
+		t.Fatal("expected the least recently used response to be evicted over the entries limit")
+	}
+
+	// b and c do not fit with d
+	s.Set(ctx, "d", entry("dddddd"), time.Minute)
+	if get("b") != "" || get("d") != "dddddd" {
+		t.Fatal("expected responses to be evicted over the bytes limit")
+	}
+
+	s.Set(ctx, "big", entry(string(bytes.Repeat([]byte("x"), 11))), time.Minute)
+	if get("big") != "" || get("d") != "dddddd" {
+		t.Fatal("expected responses larger than the cache not to be stored")
+	}
+
+	s.Set(ctx, "e", entry("e"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if get("e") != "" {
+		t.Fatal("expected expired responses not to be returned")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"memory://", "memory://?entries=5"} {
+		if _, err := New(u); err != nil {
+			t.Errorf("expected %s to be valid, got %v", u, err)
+		}
+	}
+	for _, u := range []string{"memory://?entries=0", "memory://?max_bytes=x", "file:///tmp"} {
+		if _, err := New(u); err == nil {
+			t.Errorf("expected %s to be invalid", u)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package runnerpool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+
+	"go.opencensus.io/stats"
+)
+
+// ErrActivatorQueueFull is returned for calls arriving while the pool has no
+// runners and the activator queue holds as many calls as it may
+var ErrActivatorQueueFull = models.NewAPIError(http.StatusServiceUnavailable, errors.New("Too many calls are waiting for runners to start, retry later"))
+
+// ActivatorConfig configures how calls wait for a pool scaled to zero runners
+type ActivatorConfig struct {
+	// Maximum number of calls waiting for runners, 0 waits for none
+	MaxQueued int `json:"max_queued"`
+
+	// Maximum time a call waits for runners, after which it is placed as if
+	// the pool had none
+	Timeout time.Duration `json:"timeout"`
+
+	// Interval the pool is checked for runners at while calls wait
+	Poll time.Duration `json:"poll"`
+}
+
+func NewActivatorConfig() ActivatorConfig {
+	return ActivatorConfig{
+		MaxQueued: 1000,
+		Timeout:   time.Minute,
+		Poll:      100 * time.Millisecond,
+	}
+}
+
+// scaleWaker is implemented by pools that add runners on demand, to do so
+// without waiting for the next evaluation of the demand
+type scaleWaker interface {
+	wake()
+}
+
+// activatorPool wraps a runner pool and holds calls while the pool has no
+// runners at all, eg. while it is scaled to zero and a scaling pool starts
+// runners, in place of placers retrying an empty pool until they time out
+type activatorPool struct {
+	pool   RunnerPool
+	cfg    ActivatorConfig
+	queued int64
+}
+
+// NewActivatorPool returns a runner pool queueing calls while pool has no runners
+func NewActivatorPool(pool RunnerPool, cfg *ActivatorConfig) RunnerPool {
+	return &activatorPool{pool: pool, cfg: *cfg}
+}
+
+func (p *activatorPool) inner() RunnerPool { return p.pool }
+
+func (p *activatorPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := p.pool.Runners(ctx, call)
+	if len(runners) > 0 || call == nil || !p.scaledToZero(ctx) {
+		return runners, err
+	}
+
+	n := atomic.AddInt64(&p.queued, 1)
+	if n > int64(p.cfg.MaxQueued) {
+		atomic.AddInt64(&p.queued, -1)
+		stats.Record(ctx, activatorRejectedCountMeasure.M(0))
+		return nil, ErrActivatorQueueFull
+	}
+	stats.Record(ctx, activatorQueuedMeasure.M(n))
+	start := time.Now()
+	defer func() {
+		stats.Record(ctx, activatorQueuedMeasure.M(atomic.AddInt64(&p.queued, -1)))
+		stats.Record(ctx, activatorWaitMeasure.M(int64(time.Since(start)/time.Millisecond)))
+	}()
+
+	p.wake()
+
+	timeout := time.NewTimer(p.cfg.Timeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(p.cfg.Poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return runners, err
+		case <-timeout.C:
+			return runners, err
+		case <-ticker.C:
+		}
+		runners, err = p.pool.Runners(ctx, call)
+		if len(runners) > 0 {
+			return runners, err
+		}
+	}
+}
+
+// scaledToZero tells whether the pool wrapped by p has no runners, rather
+// than wrapping pools leaving out every runner
+func (p *activatorPool) scaledToZero(ctx context.Context) bool {
+	runners, err := BasePool(p.pool).Runners(ctx, nil)
+	return err == nil && len(runners) == 0
+}
+
+// wake asks the first scaling pool wrapped by p to add runners now
+func (p *activatorPool) wake() {
+	rp := p.pool
+	for {
+		if w, ok := rp.(scaleWaker); ok {
+			w.wake()
+			return
+		}
+		wp, ok := rp.(wrappingPool)
+		if !ok {
+			return
+		}
+		rp = wp.inner()
+	}
+}
+
+func (p *activatorPool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(ctx)
+}
@@ -0,0 +1,54 @@
+package runnerpool
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+
+	"go.opencensus.io/stats"
+)
+
+// antiAffinityPool wraps a runner pool and leaves out the runners with as
+// many calls in flight as the anti-affinity of a call allows, see
+// models.FnAntiAffinityAnnotation. Only calls placed by this lb are counted.
+// When every runner is at the limit, calls are placed on any runner rather
+// than waiting for one to free up.
+type antiAffinityPool struct {
+	pool RunnerPool
+}
+
+// NewAntiAffinityPool returns a runner pool spreading the calls of fns with
+// an anti-affinity over the runners of pool
+func NewAntiAffinityPool(pool RunnerPool) RunnerPool {
+	return &antiAffinityPool{pool: pool}
+}
+
+func (p *antiAffinityPool) inner() RunnerPool { return p.pool }
+
+func (p *antiAffinityPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := p.pool.Runners(ctx, call)
+	if err != nil || call == nil || call.Model() == nil {
+		return runners, err
+	}
+	aa, err := models.AntiAffinityFromAnnotations(call.Model().Annotations)
+	if err != nil || aa == nil {
+		return runners, err
+	}
+
+	fnIDs := append([]string{call.Model().FnID}, aa.Fns...)
+	spread := make([]Runner, 0, len(runners))
+	for _, r := range runners {
+		if fnsActiveOn(r.Address(), fnIDs...) < aa.MaxPerRunner {
+			spread = append(spread, r)
+		}
+	}
+	if len(spread) == 0 {
+		stats.Record(ctx, antiAffinityCountMeasure.M(0))
+		return runners, nil
+	}
+	return spread, nil
+}
+
+func (p *antiAffinityPool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(ctx)
+}
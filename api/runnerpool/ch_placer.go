@@ -41,6 +41,9 @@ func (p *chPlacer) PlaceCall(ctx context.Context, rp RunnerPool, call RunnerCall
 	for {
 		var runners []Runner
 		runners, runnerPoolErr = rp.Runners(ctx, call)
+		if runnerPoolErr == ErrActivatorQueueFull {
+			break
+		}
 
 		i := int(jumpConsistentHash(sum64, int32(len(runners))))
 		for j := 0; j < len(runners) && !state.IsDone(); j++ {
@@ -60,6 +63,10 @@ func (p *chPlacer) PlaceCall(ctx context.Context, rp RunnerPool, call RunnerCall
 		}
 	}
 
+	if err := state.RejectedErr(); err != nil {
+		return err
+	}
+
 	if runnerPoolErr != nil {
 		// If we haven't been able to place the function and we got an error
 		// from the runner pool, return that error (since we don't have
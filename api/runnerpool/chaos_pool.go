@@ -0,0 +1,76 @@
+package runnerpool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fnproject/fn/api/chaos"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// chaosPool is a runner pool whose runners are delayed and reject calls as
+// faults are injected
+type chaosPool struct {
+	pool   RunnerPool
+	faults *chaos.Faults
+
+	lock    sync.Mutex
+	runners map[string]*chaosRunner
+}
+
+// NewChaosPool returns a runner pool injecting the latency and runner
+// rejection faults of faults into TryExec of the runners of pool. Rejected
+// calls fail as if the runner was busy, for the placer to try other runners.
+func NewChaosPool(pool RunnerPool, faults *chaos.Faults) RunnerPool {
+	logrus.Warn("Creating new chaos runnerpool, runners will be delayed and reject calls")
+	return &chaosPool{
+		pool:    pool,
+		faults:  faults,
+		runners: make(map[string]*chaosRunner),
+	}
+}
+
+func (p *chaosPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := p.pool.Runners(ctx, call)
+
+	res := make([]Runner, 0, len(runners))
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// runners are wrapped once, so that pools wrapping this one see the same
+	// runner across calls
+	for _, r := range runners {
+		cr, ok := p.runners[r.Address()]
+		if !ok || cr.Runner != r {
+			cr = &chaosRunner{Runner: r, faults: p.faults}
+			p.runners[r.Address()] = cr
+		}
+		res = append(res, cr)
+	}
+	return res, err
+}
+
+func (p *chaosPool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(ctx)
+}
+
+type chaosRunner struct {
+	Runner
+	faults *chaos.Faults
+}
+
+func (r *chaosRunner) TryExec(ctx context.Context, call RunnerCall) (bool, error) {
+	if err := r.faults.Delay(ctx); err != nil {
+		return false, err
+	}
+	if r.faults.Inject(ctx, chaos.FaultReject) {
+		return false, models.ErrCallTimeoutServerBusy
+	}
+	return r.Runner.TryExec(ctx, call)
+}
+
+func (r *chaosRunner) Zone() string {
+	return RunnerZone(r.Runner)
+}
@@ -0,0 +1,246 @@
+package runnerpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+)
+
+// CircuitBreakerConfig configures the per runner circuit breakers of a runner pool
+type CircuitBreakerConfig struct {
+	// Ratio of failed TryExec attempts in a window above which a runner is skipped
+	ErrorThreshold float64 `json:"error_threshold"`
+
+	// Minimum number of attempts in a window before the error ratio is considered
+	MinRequests uint64 `json:"min_requests"`
+
+	// Length of the window attempts are counted in
+	Window time.Duration `json:"window"`
+
+	// Amount of time a runner is skipped before a single probe call is let through
+	OpenTimeout time.Duration `json:"open_timeout"`
+}
+
+func NewCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		ErrorThreshold: 0.5,
+		MinRequests:    10,
+		Window:         10 * time.Second,
+		OpenTimeout:    5 * time.Second,
+	}
+}
+
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // runner is used
+	breakerOpen                         // runner is skipped
+	breakerHalfOpen                     // a probe call is in flight
+)
+
+// circuitBreakerPool wraps a runner pool and skips runners that keep failing
+type circuitBreakerPool struct {
+	pool    RunnerPool
+	cfg     CircuitBreakerConfig
+	lock    sync.Mutex
+	runners map[string]*breakerRunner
+	// when runners were last pruned of those no longer in the pool
+	prunedAt time.Time
+}
+
+// NewCircuitBreakerPool returns a runner pool that tracks TryExec failures of the
+// runners of pool and temporarily leaves out runners whose error ratio exceeds
+// the configured threshold. Once OpenTimeout has passed, a single probe call is
+// sent to such a runner, which is used again if the probe succeeds.
+func NewCircuitBreakerPool(pool RunnerPool, cfg *CircuitBreakerConfig) RunnerPool {
+	logrus.Infof("Creating new circuit breaker runnerpool with config=%+v", cfg)
+	return &circuitBreakerPool{
+		pool:    pool,
+		cfg:     *cfg,
+		runners: make(map[string]*breakerRunner),
+	}
+}
+
+func (p *circuitBreakerPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := p.pool.Runners(ctx, call)
+
+	now := time.Now()
+	p.prune(ctx, now)
+	res := make([]Runner, 0, len(runners))
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, r := range runners {
+		br, ok := p.runners[r.Address()]
+		if !ok || br.Runner != r {
+			br = &breakerRunner{Runner: r, cfg: &p.cfg, windowStart: now}
+			p.runners[r.Address()] = br
+		}
+		if br.available(now) {
+			res = append(res, br)
+		}
+	}
+	return res, err
+}
+
+// prune forgets the breakers of runners no longer in the pool, at most once
+// per window. The runners of calls are filtered by the pools wrapped, eg. by
+// lb group, so the runners of the base pool are listed instead.
+func (p *circuitBreakerPool) prune(ctx context.Context, now time.Time) {
+	p.lock.Lock()
+	due := now.Sub(p.prunedAt) >= p.cfg.Window
+	if due {
+		p.prunedAt = now
+	}
+	p.lock.Unlock()
+	if !due {
+		return
+	}
+
+	runners, err := BasePool(p.pool).Runners(ctx, nil)
+	if err != nil {
+		return
+	}
+	current := make(map[string]bool, len(runners))
+	for _, r := range runners {
+		current[r.Address()] = true
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for addr := range p.runners {
+		if !current[addr] {
+			delete(p.runners, addr)
+		}
+	}
+}
+
+func (p *circuitBreakerPool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(ctx)
+}
+
+// breakerRunner is a runner with a circuit breaker
+type breakerRunner struct {
+	Runner
+	cfg *CircuitBreakerConfig
+
+	lock        sync.Mutex
+	state       breakerState
+	openedAt    time.Time
+	windowStart time.Time
+	requests    uint64
+	failures    uint64
+}
+
+// available reports whether the runner should be offered to placers
+func (r *breakerRunner) available(now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch r.state {
+	case breakerOpen:
+		return now.Sub(r.openedAt) >= r.cfg.OpenTimeout
+	case breakerHalfOpen:
+		return false
+	}
+	return true
+}
+
+// acquire reports whether a call may be tried on the runner, turning an open
+// breaker past its timeout into a half open one that lets this call probe.
+func (r *breakerRunner) acquire(now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch r.state {
+	case breakerOpen:
+		if now.Sub(r.openedAt) < r.cfg.OpenTimeout {
+			return false
+		}
+		r.state = breakerHalfOpen
+	case breakerHalfOpen:
+		return false
+	}
+	return true
+}
+
+// record accounts the outcome of a call tried on the runner
+func (r *breakerRunner) record(ctx context.Context, now time.Time, failed bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.state == breakerHalfOpen {
+		if failed {
+			r.open(ctx, now)
+		} else {
+			r.state = breakerClosed
+			r.reset(now)
+		}
+		return
+	}
+
+	if now.Sub(r.windowStart) >= r.cfg.Window {
+		r.reset(now)
+	}
+
+	r.requests++
+	if failed {
+		r.failures++
+	}
+
+	if r.state == breakerClosed && r.requests >= r.cfg.MinRequests &&
+		float64(r.failures)/float64(r.requests) >= r.cfg.ErrorThreshold {
+		r.open(ctx, now)
+	}
+}
+
+func (r *breakerRunner) open(ctx context.Context, now time.Time) {
+	common.Logger(ctx).WithField("runner_addr", r.Address()).Warn("Runner circuit breaker open, skipping runner")
+	stats.Record(ctx, circuitOpenCountMeasure.M(0))
+	r.state = breakerOpen
+	r.openedAt = now
+	r.reset(now)
+}
+
+func (r *breakerRunner) reset(now time.Time) {
+	r.windowStart = now
+	r.requests = 0
+	r.failures = 0
+}
+
+func (r *breakerRunner) TryExec(ctx context.Context, call RunnerCall) (bool, error) {
+	if !r.acquire(time.Now()) {
+		return false, models.ErrCallTimeoutServerBusy
+	}
+
+	placed, err := r.Runner.TryExec(ctx, call)
+	r.record(ctx, time.Now(), isRunnerFailure(ctx, placed, err))
+	return placed, err
+}
+
+func (r *breakerRunner) Zone() string {
+	return RunnerZone(r.Runner)
+}
+
+// isRunnerFailure reports whether a TryExec outcome points at a faulty runner.
+// Busy or rejecting runners, client cancellations and errors of the function itself do not.
+func isRunnerFailure(ctx context.Context, placed bool, err error) bool {
+	if err == nil || err == models.ErrCallTimeoutServerBusy || ctx.Err() != nil {
+		return false
+	}
+	if rej, ok := err.(*RunnerRejection); ok {
+		// a runner that failed a call before responding is at fault
+		return rej.Reason == RejectFailed
+	}
+	if _, ok := err.(models.APIError); ok && placed {
+		return false
+	}
+	return true
+}
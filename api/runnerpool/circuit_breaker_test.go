@@ -0,0 +1,197 @@
+package runnerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+type testRunner struct {
+	addr string
+	busy bool
+}
+
+func (r *testRunner) TryExec(ctx context.Context, call RunnerCall) (bool, error) {
+	if r.busy {
+		return false, models.ErrCallTimeoutServerBusy
+	}
+	return true, nil
+}
+
+func (r *testRunner) Status(ctx context.Context) (*RunnerStatus, error) { return nil, nil }
+func (r *testRunner) Close(ctx context.Context) error                   { return nil }
+func (r *testRunner) Address() string                                   { return r.addr }
+
+type testPool struct {
+	runners []Runner
+}
+
+func (p *testPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	return p.runners, nil
+}
+
+func (p *testPool) Shutdown(ctx context.Context) error { return nil }
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	cfg := CircuitBreakerConfig{ErrorThreshold: 0.5, MinRequests: 4, Window: 10 * time.Second, OpenTimeout: 5 * time.Second}
+
+	type step struct {
+		at time.Duration
+		// try a call, which fails if failed, and expect it to be let through if acquired
+		failed, acquired bool
+		state            breakerState
+	}
+	for _, tc := range []struct {
+		name  string
+		steps []step
+	}{
+		{"stays closed below min requests", []step{
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+		}},
+		{"stays closed below the threshold", []step{
+			{acquired: true, state: breakerClosed},
+			{acquired: true, state: breakerClosed},
+			{acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+		}},
+		{"opens at the threshold", []step{
+			{acquired: true, state: breakerClosed},
+			{acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerOpen},
+			{at: time.Second, state: breakerOpen},
+		}},
+		{"counts failures in a window", []step{
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+			{at: 11 * time.Second, failed: true, acquired: true, state: breakerClosed},
+		}},
+		{"closes after a successful probe", []step{
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerOpen},
+			{at: 6 * time.Second, acquired: true, state: breakerClosed},
+			{at: 6 * time.Second, failed: true, acquired: true, state: breakerClosed},
+		}},
+		{"opens again after a failed probe", []step{
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerClosed},
+			{failed: true, acquired: true, state: breakerOpen},
+			{at: 6 * time.Second, failed: true, acquired: true, state: breakerOpen},
+			{at: 10 * time.Second, state: breakerOpen},
+			{at: 11 * time.Second, acquired: true, state: breakerClosed},
+		}},
+	} {
+		start := time.Now()
+		r := &breakerRunner{Runner: &testRunner{addr: "r"}, cfg: &cfg, windowStart: start}
+		for i, s := range tc.steps {
+			now := start.Add(s.at)
+			acquired := r.acquire(now)
+			if acquired != s.acquired {
+				t.Fatalf("%s: step %d: expected acquired %v, got %v", tc.name, i, s.acquired, acquired)
+			}
+			if acquired {
+				r.record(context.Background(), now, s.failed)
+			}
+			if r.state != s.state {
+				t.Fatalf("%s: step %d: expected state %v, got %v", tc.name, i, s.state, r.state)
+			}
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	cfg := CircuitBreakerConfig{ErrorThreshold: 0.5, MinRequests: 1, Window: 10 * time.Second, OpenTimeout: 5 * time.Second}
+	now := time.Now()
+	r := &breakerRunner{Runner: &testRunner{addr: "r"}, cfg: &cfg, windowStart: now}
+
+	r.record(context.Background(), now, true)
+	if r.available(now) || r.acquire(now) {
+		t.Fatal("Expected an open runner to be skipped")
+	}
+
+	// a single probe is let through while it is in flight
+	now = now.Add(cfg.OpenTimeout)
+	if !r.available(now) || !r.acquire(now) || r.state != breakerHalfOpen {
+		t.Fatalf("Expected a probe to be let through, got state %v", r.state)
+	}
+	if r.available(now) || r.acquire(now) {
+		t.Fatal("Expected a probing runner to be skipped")
+	}
+	r.record(context.Background(), now, false)
+	if !r.available(now) || r.state != breakerClosed {
+		t.Fatalf("Expected the runner to be used after its probe succeeded, got state %v", r.state)
+	}
+}
+
+func TestCircuitBreakerPrune(t *testing.T) {
+	ctx := context.Background()
+	base := &testPool{runners: []Runner{&testRunner{addr: "a"}, &testRunner{addr: "b"}}}
+	cfg := NewCircuitBreakerConfig()
+	p := NewCircuitBreakerPool(base, &cfg).(*circuitBreakerPool)
+
+	if runners, err := p.Runners(ctx, nil); err != nil || len(runners) != 2 || len(p.runners) != 2 {
+		t.Fatalf("Expected breakers of both runners, got %d %v", len(p.runners), err)
+	}
+
+	// removed runners are forgotten once the window has passed
+	base.runners = base.runners[1:]
+	p.Runners(ctx, nil)
+	if len(p.runners) != 2 {
+		t.Fatalf("Expected runners to be pruned at most once per window, got %d", len(p.runners))
+	}
+	p.prunedAt = p.prunedAt.Add(-cfg.Window)
+	p.Runners(ctx, nil)
+	if _, ok := p.runners["a"]; ok || len(p.runners) != 1 {
+		t.Fatalf("Expected the removed runner to be forgotten, got %d", len(p.runners))
+	}
+}
+
+func TestCircuitBreakerPruneFilteredRunners(t *testing.T) {
+	ctx := context.Background()
+	base := &testPool{runners: []Runner{&testRunner{addr: "a"}, &testRunner{addr: "b"}}}
+	cfg := NewCircuitBreakerConfig()
+	filtered := &filteringPool{pool: base}
+	p := NewCircuitBreakerPool(filtered, &cfg).(*circuitBreakerPool)
+
+	p.Runners(ctx, nil)
+	filtered.only = "b"
+	p.prunedAt = time.Time{}
+	if runners, err := p.Runners(ctx, nil); err != nil || len(runners) != 1 {
+		t.Fatalf("Expected the filtered runners, got %d %v", len(runners), err)
+	}
+	if len(p.runners) != 2 {
+		t.Fatalf("Expected the breakers of runners filtered out of a call to be kept, got %d", len(p.runners))
+	}
+}
+
+// filteringPool leaves out the runners of its pool but one, like lb groups do
+type filteringPool struct {
+	pool RunnerPool
+	only string
+}
+
+func (p *filteringPool) inner() RunnerPool { return p.pool }
+
+func (p *filteringPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := p.pool.Runners(ctx, call)
+	if p.only == "" {
+		return runners, err
+	}
+	for _, r := range runners {
+		if r.Address() == p.only {
+			return []Runner{r}, err
+		}
+	}
+	return nil, errors.New("no runner")
+}
+
+func (p *filteringPool) Shutdown(ctx context.Context) error { return nil }
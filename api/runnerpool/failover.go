@@ -0,0 +1,27 @@
+package runnerpool
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// FailoverHeader is set on the responses of calls that failed over to another
+// runner to the number of runners that failed them
+const FailoverHeader = "Fn-Failover"
+
+// IsIdempotent reports whether running call again after a runner failed while
+// running it is safe. Calls of GET and HEAD requests are, as are calls made
+// with an Idempotency-Key.
+func IsIdempotent(call *models.Call) bool {
+	if call == nil {
+		return false
+	}
+	return call.Method == http.MethodGet || call.Method == http.MethodHead || call.IdempotencyKey != ""
+}
+
+// CanFailover reports whether a call may run again on another runner after
+// its runner failed while running it, which idempotent and async calls may.
+func CanFailover(call *models.Call) bool {
+	return IsIdempotent(call) || (call != nil && call.Type == models.TypeAsync)
+}
@@ -0,0 +1,243 @@
+package runnerpool
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+var (
+	// ErrLBGroupNotFound is returned for calls placed in an lb group that
+	// does not exist
+	ErrLBGroupNotFound = models.NewAPIError(http.StatusServiceUnavailable, errors.New("LB group of the call not found"))
+
+	// ErrInvalidLBGroup is returned for an lb group without id or with
+	// inconsistent capacity bounds
+	ErrInvalidLBGroup = models.NewAPIError(http.StatusBadRequest, errors.New("LB group needs an id, min_runners no larger than max_runners and a canary weight between 0 and 100"))
+)
+
+// LBGroup is a subset of the runners of a pool, calls of apps annotated with
+// models.AppLBGroupAnnotation are only placed on the runners of their group
+type LBGroup struct {
+	ID string `json:"id"`
+	// Selector selects the runners whose labels have all of its key/value
+	// pairs, an empty selector selects every runner
+	Selector map[string]string `json:"selector,omitempty"`
+	// MinRunners is the number of runners below which calls of the group
+	// spill over to every runner of the pool, 0 never spills over
+	MinRunners int `json:"min_runners"`
+	// MaxRunners caps the number of runners used by the group, 0 uses every
+	// selected runner
+	MaxRunners int `json:"max_runners"`
+	// Canary splits the runners of the group in two, to roll out new runner
+	// images or configurations to part of the calls first
+	Canary *LBCanary `json:"canary,omitempty"`
+}
+
+// LBCanary is the canary sub-pool of an lb group, the runners of the group
+// it selects are given Weight percent of the calls of the group and the
+// others the remaining calls. Calls go to the other sub-pool when one has no
+// runners.
+type LBCanary struct {
+	Selector map[string]string `json:"selector"`
+	Weight   int               `json:"weight"`
+}
+
+func (g *LBGroup) validate() error {
+	if g.ID == "" || g.MinRunners < 0 || g.MaxRunners < 0 || (g.MaxRunners > 0 && g.MinRunners > g.MaxRunners) {
+		return ErrInvalidLBGroup
+	}
+	if g.Canary != nil && (g.Canary.Weight < 0 || g.Canary.Weight > 100) {
+		return ErrInvalidLBGroup
+	}
+	return nil
+}
+
+func selects(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// RunnerLabels returns the labels of the runner at an address, nil if it has
+// none. Runners that registered with the lb have labels, see agent.RunnerRegistry.
+type RunnerLabels func(addr string) map[string]string
+
+// LBGroups are the lb groups of a pool, by id
+type LBGroups struct {
+	lock   sync.RWMutex
+	groups map[string]LBGroup
+}
+
+func NewLBGroups() *LBGroups {
+	return &LBGroups{groups: make(map[string]LBGroup)}
+}
+
+// Put creates or replaces the group with the id of g
+func (gs *LBGroups) Put(g LBGroup) error {
+	if err := g.validate(); err != nil {
+		return err
+	}
+	gs.lock.Lock()
+	gs.groups[g.ID] = g
+	gs.lock.Unlock()
+	return nil
+}
+
+func (gs *LBGroups) Get(id string) (LBGroup, bool) {
+	gs.lock.RLock()
+	defer gs.lock.RUnlock()
+	g, ok := gs.groups[id]
+	return g, ok
+}
+
+// Delete removes a group, calls of apps in the group fail until it is created again
+func (gs *LBGroups) Delete(id string) bool {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+	_, ok := gs.groups[id]
+	delete(gs.groups, id)
+	return ok
+}
+
+// SetCanary replaces the canary of the group id, a nil canary removes it
+func (gs *LBGroups) SetCanary(id string, canary *LBCanary) (LBGroup, error) {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+	g, ok := gs.groups[id]
+	if !ok {
+		return g, ErrLBGroupNotFound
+	}
+	g.Canary = canary
+	if err := g.validate(); err != nil {
+		return g, err
+	}
+	gs.groups[id] = g
+	return g, nil
+}
+
+// List returns the groups sorted by id
+func (gs *LBGroups) List() []LBGroup {
+	gs.lock.RLock()
+	res := make([]LBGroup, 0, len(gs.groups))
+	for _, g := range gs.groups {
+		res = append(res, g)
+	}
+	gs.lock.RUnlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// members returns the runners of g among runners split in the stable and
+// canary sub-pools, keeping their order, and whether calls of the group spill
+// over to every runner
+func (g *LBGroup) members(runners []Runner, labels RunnerLabels) (stable, canary []Runner, spill bool) {
+	stable = make([]Runner, 0, len(runners))
+	for _, r := range runners {
+		if g.MaxRunners > 0 && len(stable)+len(canary) == g.MaxRunners {
+			break
+		}
+		var l map[string]string
+		if labels != nil {
+			l = labels(r.Address())
+		}
+		if !selects(g.Selector, l) {
+			continue
+		}
+		if g.Canary != nil && selects(g.Canary.Selector, l) {
+			canary = append(canary, r)
+		} else {
+			stable = append(stable, r)
+		}
+	}
+	return stable, canary, len(stable)+len(canary) < g.MinRunners
+}
+
+// isCanary tells whether a call goes to the canary sub-pool of g, calls are
+// bucketed by id so that retries of a call go to the same sub-pool
+func (g *LBGroup) isCanary(call *models.Call) bool {
+	if g.Canary == nil || g.Canary.Weight == 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(call.ID))
+	return int(h.Sum32()%100) < g.Canary.Weight
+}
+
+// lbGroupPool wraps a runner pool and returns the runners of the lb group of
+// a call, calls without a group are placed on every runner of the pool
+type lbGroupPool struct {
+	pool   RunnerPool
+	groups *LBGroups
+	labels RunnerLabels
+}
+
+// NewLBGroupPool returns a runner pool placing the calls of each of groups
+// on the runners of pool the group selects by their labels
+func NewLBGroupPool(pool RunnerPool, groups *LBGroups, labels RunnerLabels) RunnerPool {
+	return &lbGroupPool{pool: pool, groups: groups, labels: labels}
+}
+
+func (p *lbGroupPool) inner() RunnerPool { return p.pool }
+
+func (p *lbGroupPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := p.pool.Runners(ctx, call)
+	if err != nil || call == nil || call.Model() == nil {
+		return runners, err
+	}
+	id, err := models.LBGroupFromAnnotations(call.Model().Annotations)
+	if err != nil || id == "" {
+		return runners, err
+	}
+
+	g, ok := p.groups.Get(id)
+	if !ok {
+		return nil, ErrLBGroupNotFound
+	}
+	stable, canary, spill := g.members(runners, p.labels)
+	switch {
+	case spill:
+		return runners, nil
+	case len(canary) > 0 && (len(stable) == 0 || g.isCanary(call.Model())):
+		return canary, nil
+	}
+	return stable, nil
+}
+
+func (p *lbGroupPool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(ctx)
+}
+
+// LBGroupMembers returns the runners of rp selected by the group id, split
+// in the stable and canary sub-pools, rp being a pool wrapping a pool
+// returned by NewLBGroupPool
+func LBGroupMembers(ctx context.Context, rp RunnerPool, id string) (stable, canary []Runner, err error) {
+	gp, ok := rp.(*lbGroupPool)
+	for !ok {
+		w, isWrapping := rp.(wrappingPool)
+		if !isWrapping {
+			return nil, nil, errors.New("runner pool has no lb groups")
+		}
+		rp = w.inner()
+		gp, ok = rp.(*lbGroupPool)
+	}
+
+	g, ok := gp.groups.Get(id)
+	if !ok {
+		return nil, nil, ErrLBGroupNotFound
+	}
+	runners, err := gp.pool.Runners(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	stable, canary, _ = g.members(runners, gp.labels)
+	return stable, canary, nil
+}
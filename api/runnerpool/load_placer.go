@@ -0,0 +1,133 @@
+package runnerpool
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/fnproject/fn/api/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loadPlacer orders runners by the number of calls this lb has in flight on them
+type loadPlacer struct {
+	cfg     PlacerConfig
+	binpack bool
+	target  int64
+
+	lock     sync.Mutex
+	inflight map[string]int64
+}
+
+// NewSpreadPlacer returns a placer that tries the least loaded runners first,
+// spreading calls and their hot containers over as many runners as possible.
+func NewSpreadPlacer(cfg *PlacerConfig) Placer {
+	logrus.Infof("Creating new spread runnerpool placer with config=%+v", cfg)
+	return &loadPlacer{
+		cfg:      *cfg,
+		inflight: make(map[string]int64),
+	}
+}
+
+// NewBinPackPlacer returns a placer that fills runners up to target calls in
+// flight, trying the most loaded runners below target first. Only when all
+// runners reached target, the least loaded ones are tried. Packing calls on
+// few runners leaves the others idle so that they can be scaled down.
+func NewBinPackPlacer(cfg *PlacerConfig, target int) Placer {
+	logrus.Infof("Creating new binpack runnerpool placer target=%d with config=%+v", target, cfg)
+	return &loadPlacer{
+		cfg:      *cfg,
+		binpack:  true,
+		target:   int64(target),
+		inflight: make(map[string]int64),
+	}
+}
+
+func (sp *loadPlacer) GetPlacerConfig() PlacerConfig {
+	return sp.cfg
+}
+
+func (sp *loadPlacer) PlaceCall(ctx context.Context, rp RunnerPool, call RunnerCall) error {
+	state := NewPlacerTracker(ctx, &sp.cfg, call)
+	defer state.HandleDone()
+
+	var runnerPoolErr error
+	for {
+		var runners []Runner
+		runners, runnerPoolErr = rp.Runners(ctx, call)
+		if runnerPoolErr == ErrActivatorQueueFull {
+			break
+		}
+
+		for _, r := range sp.order(runners) {
+			if state.IsDone() {
+				break
+			}
+
+			sp.add(r.Address(), 1)
+			placed, err := state.TryRunner(r, call)
+			sp.add(r.Address(), -1)
+			if placed {
+				return err
+			}
+		}
+
+		if !state.RetryAllBackoff(len(runners)) {
+			break
+		}
+	}
+
+	if err := state.RejectedErr(); err != nil {
+		return err
+	}
+
+	if runnerPoolErr != nil {
+		// If we haven't been able to place the function and we got an error
+		// from the runner pool, return that error (since we don't have
+		// enough runners to handle the current load and the runner pool is
+		// having trouble).
+		state.HandleFindRunnersFailure(runnerPoolErr)
+		return runnerPoolErr
+	}
+	return models.ErrCallTimeoutServerBusy
+}
+
+// add adjusts the number of calls in flight on a runner
+func (sp *loadPlacer) add(addr string, delta int64) {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+
+	if n := sp.inflight[addr] + delta; n > 0 {
+		sp.inflight[addr] = n
+	} else {
+		delete(sp.inflight, addr)
+	}
+}
+
+// order returns runners in the order they should be tried
+func (sp *loadPlacer) order(runners []Runner) []Runner {
+	loads := make(map[Runner]int64, len(runners))
+	sp.lock.Lock()
+	for _, r := range runners {
+		loads[r] = sp.inflight[r.Address()]
+	}
+	sp.lock.Unlock()
+
+	res := make([]Runner, len(runners))
+	copy(res, runners)
+	sort.SliceStable(res, func(i, j int) bool {
+		li, lj := loads[res[i]], loads[res[j]]
+		if sp.binpack {
+			fullI, fullJ := li >= sp.target, lj >= sp.target
+			if fullI != fullJ {
+				return !fullI
+			}
+			if !fullI {
+				return li > lj
+			}
+		}
+		return li < lj
+	})
+	return res
+}
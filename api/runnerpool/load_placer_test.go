@@ -0,0 +1,50 @@
+package runnerpool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadPlacerOrder(t *testing.T) {
+	runners := []Runner{&testRunner{addr: "a"}, &testRunner{addr: "b"}, &testRunner{addr: "c"}, &testRunner{addr: "d"}}
+	cfg := NewPlacerConfig()
+
+	for _, tc := range []struct {
+		name     string
+		placer   Placer
+		inflight map[string]int64
+		expected string
+	}{
+		{"spread keeps the order of idle runners", NewSpreadPlacer(&cfg), nil, "abcd"},
+		{"spread tries the least loaded runners first", NewSpreadPlacer(&cfg), map[string]int64{"a": 3, "b": 1, "d": 2}, "cbda"},
+		{"binpack keeps the order of idle runners", NewBinPackPlacer(&cfg, 2), nil, "abcd"},
+		{"binpack tries the most loaded runners below target first", NewBinPackPlacer(&cfg, 3), map[string]int64{"b": 1, "c": 2}, "cbad"},
+		{"binpack tries full runners last, least loaded first", NewBinPackPlacer(&cfg, 2), map[string]int64{"a": 4, "b": 1, "c": 2, "d": 3}, "bcda"},
+	} {
+		lp := tc.placer.(*loadPlacer)
+		for addr, n := range tc.inflight {
+			lp.add(addr, n)
+		}
+		var order string
+		for _, r := range lp.order(runners) {
+			order += r.Address()
+		}
+		if order != tc.expected {
+			t.Errorf("%s: expected order %s, got %s", tc.name, tc.expected, order)
+		}
+	}
+}
+
+func TestLoadPlacerInflight(t *testing.T) {
+	cfg := NewPlacerConfig()
+	lp := NewSpreadPlacer(&cfg).(*loadPlacer)
+
+	lp.add("a", 1)
+	lp.add("a", 1)
+	lp.add("b", 1)
+	lp.add("a", -1)
+	lp.add("b", -1)
+	if !reflect.DeepEqual(lp.inflight, map[string]int64{"a": 1}) {
+		t.Fatalf("Expected only runners with calls in flight to be kept, got %v", lp.inflight)
+	}
+}
@@ -35,6 +35,9 @@ func (sp *naivePlacer) PlaceCall(ctx context.Context, rp RunnerPool, call Runner
 	for {
 		var runners []Runner
 		runners, runnerPoolErr = rp.Runners(ctx, call)
+		if runnerPoolErr == ErrActivatorQueueFull {
+			break
+		}
 
 		for j := 0; j < len(runners) && !state.IsDone(); j++ {
 
@@ -52,6 +55,10 @@ func (sp *naivePlacer) PlaceCall(ctx context.Context, rp RunnerPool, call Runner
 		}
 	}
 
+	if err := state.RejectedErr(); err != nil {
+		return err
+	}
+
 	if runnerPoolErr != nil {
 		// If we haven't been able to place the function and we got an error
 		// from the runner pool, return that error (since we don't have
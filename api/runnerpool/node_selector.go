@@ -0,0 +1,58 @@
+package runnerpool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// ErrNoCompatibleRunners is returned for calls whose node selector selects
+// none of the runners of the pool
+var ErrNoCompatibleRunners = models.NewAPIError(http.StatusServiceUnavailable, errors.New("No runner matches the node selector of the fn"))
+
+// nodeSelectorPool wraps a runner pool and returns the runners whose labels
+// match the node selector of a call, see models.FnNodeSelectorAnnotation
+type nodeSelectorPool struct {
+	pool   RunnerPool
+	labels RunnerLabels
+}
+
+// NewNodeSelectorPool returns a runner pool placing the calls of fns with a
+// node selector only on the runners of pool it selects by their labels
+func NewNodeSelectorPool(pool RunnerPool, labels RunnerLabels) RunnerPool {
+	return &nodeSelectorPool{pool: pool, labels: labels}
+}
+
+func (p *nodeSelectorPool) inner() RunnerPool { return p.pool }
+
+func (p *nodeSelectorPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := p.pool.Runners(ctx, call)
+	if err != nil || call == nil || call.Model() == nil {
+		return runners, err
+	}
+	selector, err := models.NodeSelectorFromAnnotations(call.Model().Annotations)
+	if err != nil || len(selector) == 0 {
+		return runners, err
+	}
+
+	selected := make([]Runner, 0, len(runners))
+	for _, r := range runners {
+		var l map[string]string
+		if p.labels != nil {
+			l = p.labels(r.Address())
+		}
+		if selects(selector, l) {
+			selected = append(selected, r)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, ErrNoCompatibleRunners
+	}
+	return selected, nil
+}
+
+func (p *nodeSelectorPool) Shutdown(ctx context.Context) error {
+	return p.pool.Shutdown(ctx)
+}
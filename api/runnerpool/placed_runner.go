@@ -0,0 +1,40 @@
+package runnerpool
+
+import (
+	"context"
+	"sync"
+)
+
+type placedRunnerKey struct{}
+
+// PlacedRunner holds the address of the runner a call was placed on, for
+// those handling the request of the call to report it once it is done
+type PlacedRunner struct {
+	lock sync.Mutex
+	addr string
+}
+
+// Address returns the address of the runner the call was placed on, empty if
+// it was not placed
+func (p *PlacedRunner) Address() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.addr
+}
+
+func (p *PlacedRunner) set(addr string) {
+	p.lock.Lock()
+	p.addr = addr
+	p.lock.Unlock()
+}
+
+// WithPlacedRunner returns a context in which placers record the runner calls
+// are placed on in p
+func WithPlacedRunner(ctx context.Context, p *PlacedRunner) context.Context {
+	return context.WithValue(ctx, placedRunnerKey{}, p)
+}
+
+func placedRunnerFromContext(ctx context.Context) *PlacedRunner {
+	p, _ := ctx.Value(placedRunnerKey{}).(*PlacedRunner)
+	return p
+}
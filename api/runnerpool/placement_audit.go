@@ -0,0 +1,91 @@
+package runnerpool
+
+import (
+	"sync"
+	"time"
+)
+
+// maxAuditAttempts bounds the attempts kept per audit, placers may retry
+// runners for a long time
+const maxAuditAttempts = 100
+
+// PlacementAttempt is a single TryExec of a call on a runner
+type PlacementAttempt struct {
+	Runner    string        `json:"runner"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Placed    bool          `json:"placed"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// PlacementAudit records the decisions a placer made for a call
+type PlacementAudit struct {
+	CallID      string             `json:"call_id"`
+	AppID       string             `json:"app_id"`
+	FnID        string             `json:"fn_id"`
+	StartedAt   time.Time          `json:"started_at"`
+	CompletedAt time.Time          `json:"completed_at"`
+	Passes      int                `json:"passes"`
+	EmptyPasses int                `json:"empty_passes"`
+	RetryWait   time.Duration      `json:"retry_wait"`
+	Attempts    []PlacementAttempt `json:"attempts"`
+	Dropped     int                `json:"dropped_attempts,omitempty"`
+	Placed      bool               `json:"placed"`
+	Error       string             `json:"error,omitempty"`
+}
+
+func (a *PlacementAudit) addAttempt(attempt PlacementAttempt) {
+	if len(a.Attempts) >= maxAuditAttempts {
+		a.Dropped++
+		return
+	}
+	a.Attempts = append(a.Attempts, attempt)
+}
+
+// PlacementAuditLog keeps the placement audits of the most recent calls
+type PlacementAuditLog struct {
+	lock   sync.RWMutex
+	size   int
+	next   int
+	ring   []string
+	audits map[string]*PlacementAudit
+}
+
+// NewPlacementAuditLog returns an audit log holding up to size audits
+func NewPlacementAuditLog(size int) *PlacementAuditLog {
+	return &PlacementAuditLog{
+		size:   size,
+		ring:   make([]string, size),
+		audits: make(map[string]*PlacementAudit, size),
+	}
+}
+
+// Record adds an audit to the log, evicting the oldest one if the log is full
+func (l *PlacementAuditLog) Record(audit *PlacementAudit) {
+	if l == nil || l.size == 0 {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if old := l.ring[l.next]; old != "" {
+		delete(l.audits, old)
+	}
+	l.ring[l.next] = audit.CallID
+	l.audits[audit.CallID] = audit
+	l.next = (l.next + 1) % l.size
+}
+
+// Get returns the audit of a call, if it is still in the log
+func (l *PlacementAuditLog) Get(callID string) (*PlacementAudit, bool) {
+	if l == nil {
+		return nil, false
+	}
+
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	audit, ok := l.audits[callID]
+	return audit, ok
+}
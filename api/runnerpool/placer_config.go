@@ -14,6 +14,14 @@ type PlacerConfig struct {
 
 	// Maximum amount of time a placer can hold an ack sync request during runner attempts
 	DetachedPlacerTimeout time.Duration `json:"detached_placer_timeout"`
+
+	// If set, placers record an audit of their decisions for each call
+	AuditLog *PlacementAuditLog `json:"-"`
+
+	// Maximum number of times an idempotent or async call is moved to another runner
+	// after the runner it was placed on failed before responding. Zero
+	// disables failover, such failures are returned to the client.
+	MaxFailovers int `json:"max_failovers"`
 }
 
 func NewPlacerConfig() PlacerConfig {
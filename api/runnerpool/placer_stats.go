@@ -13,18 +13,27 @@ import (
 )
 
 var (
-	attemptCountMeasure      = common.MakeMeasure("lb_placer_attempt_count", "LB Placer Number of Runners Attempted Count", "")
-	errorPoolCountMeasure    = common.MakeMeasure("lb_placer_rp_error_count", "LB Placer RunnerPool RunnerList Error Count", "")
-	emptyPoolCountMeasure    = common.MakeMeasure("lb_placer_rp_empty_count", "LB Placer RunnerPool RunnerList Empty Count", "")
-	cancelCountMeasure       = common.MakeMeasure("lb_placer_client_cancelled_count", "LB Placer Client Cancel Count", "")
-	timeoutCountMeasure      = common.MakeMeasure("lb_placer_client_timeout_count", "LB Placer Client Timeout Count", "")
-	placerTimeoutMeasure     = common.MakeMeasure("lb_placer_timeout_count", "LB Placer Timeout Count", "")
-	placedErrorCountMeasure  = common.MakeMeasure("lb_placer_placed_error_count", "LB Placer Placed Call Count With Errors", "")
-	placedAbortCountMeasure  = common.MakeMeasure("lb_placer_placed_abort_count", "LB Placer Placed Call Count With Client Timeout/Cancel", "")
-	placedOKCountMeasure     = common.MakeMeasure("lb_placer_placed_ok_count", "LB Placer Placed Call Count Without Errors", "")
-	retryTooBusyCountMeasure = common.MakeMeasure("lb_placer_retry_busy_count", "LB Placer Retry Count - Too Busy", "")
-	retryErrorCountMeasure   = common.MakeMeasure("lb_placer_retry_error_count", "LB Placer Retry Count - Errors", "")
-	placerLatencyMeasure     = common.MakeMeasure("lb_placer_latency", "LB Placer Latency", "msecs")
+	attemptCountMeasure       = common.MakeMeasure("lb_placer_attempt_count", "LB Placer Number of Runners Attempted Count", "")
+	errorPoolCountMeasure     = common.MakeMeasure("lb_placer_rp_error_count", "LB Placer RunnerPool RunnerList Error Count", "")
+	emptyPoolCountMeasure     = common.MakeMeasure("lb_placer_rp_empty_count", "LB Placer RunnerPool RunnerList Empty Count", "")
+	cancelCountMeasure        = common.MakeMeasure("lb_placer_client_cancelled_count", "LB Placer Client Cancel Count", "")
+	timeoutCountMeasure       = common.MakeMeasure("lb_placer_client_timeout_count", "LB Placer Client Timeout Count", "")
+	placerTimeoutMeasure      = common.MakeMeasure("lb_placer_timeout_count", "LB Placer Timeout Count", "")
+	placedErrorCountMeasure   = common.MakeMeasure("lb_placer_placed_error_count", "LB Placer Placed Call Count With Errors", "")
+	placedAbortCountMeasure   = common.MakeMeasure("lb_placer_placed_abort_count", "LB Placer Placed Call Count With Client Timeout/Cancel", "")
+	placedOKCountMeasure      = common.MakeMeasure("lb_placer_placed_ok_count", "LB Placer Placed Call Count Without Errors", "")
+	retryTooBusyCountMeasure  = common.MakeMeasure("lb_placer_retry_busy_count", "LB Placer Retry Count - Too Busy", "")
+	retryErrorCountMeasure    = common.MakeMeasure("lb_placer_retry_error_count", "LB Placer Retry Count - Errors", "")
+	retryRejectedCountMeasure = common.MakeMeasure("lb_placer_retry_rejected_count", "LB Placer Retry Count - Rejected", "")
+	placerLatencyMeasure      = common.MakeMeasure("lb_placer_latency", "LB Placer Latency", "msecs")
+	circuitOpenCountMeasure   = common.MakeMeasure("lb_runner_circuit_open_count", "LB Runner Circuit Breaker Open Count", "")
+	crossZoneCountMeasure     = common.MakeMeasure("lb_placer_cross_zone_count", "LB Placer Placed Call Count On Runners Of Other Zones", "")
+	failoverCountMeasure      = common.MakeMeasure("lb_placer_failover_count", "LB Placer Call Count Failed Over To Another Runner", "")
+	antiAffinityCountMeasure  = common.MakeMeasure("lb_placer_anti_affinity_fallback_count", "LB Placer Anti-Affinity Fallback Count", "")
+
+	activatorQueuedMeasure        = common.MakeMeasure("lb_activator_queued", "LB Activator Calls Waiting For Runners", "")
+	activatorWaitMeasure          = common.MakeMeasure("lb_activator_wait", "LB Activator Time Calls Waited For Runners", "msecs")
+	activatorRejectedCountMeasure = common.MakeMeasure("lb_activator_rejected_count", "LB Activator Call Count Rejected With A Full Queue", "")
 )
 
 // Helper struct for tracking LB Placer latency and attempt counts
@@ -80,7 +89,15 @@ func RegisterPlacerViews(tagKeys []string, latencyDist []float64) {
 		common.CreateView(placedOKCountMeasure, view.Count(), tagKeys),
 		common.CreateView(retryTooBusyCountMeasure, view.Count(), tagKeys),
 		common.CreateView(retryErrorCountMeasure, view.Count(), tagKeys),
+		common.CreateView(retryRejectedCountMeasure, view.Count(), tagKeys),
 		common.CreateView(placerLatencyMeasure, view.Distribution(latencyDist...), tagKeys),
+		common.CreateView(circuitOpenCountMeasure, view.Count(), tagKeys),
+		common.CreateView(crossZoneCountMeasure, view.Count(), tagKeys),
+		common.CreateView(failoverCountMeasure, view.Count(), tagKeys),
+		common.CreateView(antiAffinityCountMeasure, view.Count(), tagKeys),
+		common.CreateView(activatorQueuedMeasure, view.LastValue(), tagKeys),
+		common.CreateView(activatorWaitMeasure, view.Distribution(latencyDist...), tagKeys),
+		common.CreateView(activatorRejectedCountMeasure, view.Count(), tagKeys),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot create view")
@@ -2,12 +2,14 @@ package runnerpool
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 
 	"go.opencensus.io/stats"
+	"go.opencensus.io/trace"
 )
 
 type placerTracker struct {
@@ -17,6 +19,14 @@ type placerTracker struct {
 	cancel     context.CancelFunc
 	tracker    *attemptTracker
 	isPlaced   bool
+	span       *trace.Span
+	audit      *PlacementAudit
+
+	// permanent rejections in the current pass over the runner list
+	rejections    int
+	lastRejection *RunnerRejection
+	// runners that failed the call before responding, see PlacerConfig.MaxFailovers
+	failovers int
 }
 
 func NewPlacerTracker(requestCtx context.Context, cfg *PlacerConfig, call RunnerCall) *placerTracker {
@@ -26,6 +36,19 @@ func NewPlacerTracker(requestCtx context.Context, cfg *PlacerConfig, call Runner
 		timeout = cfg.DetachedPlacerTimeout
 	}
 
+	requestCtx, span := trace.StartSpan(requestCtx, "lb_placer_place_call")
+
+	var audit *PlacementAudit
+	if cfg.AuditLog != nil {
+		model := call.Model()
+		audit = &PlacementAudit{
+			CallID:    model.ID,
+			AppID:     model.AppID,
+			FnID:      model.FnID,
+			StartedAt: time.Now(),
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	return &placerTracker{
 		cfg:        cfg,
@@ -33,6 +56,8 @@ func NewPlacerTracker(requestCtx context.Context, cfg *PlacerConfig, call Runner
 		placerCtx:  ctx,
 		cancel:     cancel,
 		tracker:    newAttemptTracker(requestCtx),
+		span:       span,
+		audit:      audit,
 	}
 }
 
@@ -45,6 +70,9 @@ func (tr *placerTracker) IsDone() bool {
 func (tr *placerTracker) HandleFindRunnersFailure(err error) {
 	common.Logger(tr.requestCtx).WithError(err).Error("Failed to find runners for call")
 	stats.Record(tr.requestCtx, errorPoolCountMeasure.M(0))
+	if tr.audit != nil {
+		tr.audit.Error = err.Error()
+	}
 }
 
 // TryRunner is a convenience function to TryExec a call on a runner and
@@ -54,15 +82,59 @@ func (tr *placerTracker) TryRunner(r Runner, call RunnerCall) (bool, error) {
 
 	// WARNING: Do not use placerCtx here to let requestCtx take its time
 	// during container execution.
-	ctx, cancel := context.WithCancel(tr.requestCtx)
+	ctx, span := trace.StartSpan(tr.requestCtx, "lb_placer_try_runner")
+	span.AddAttributes(trace.StringAttribute("runner_addr", r.Address()))
+	ctx, cancel := context.WithCancel(ctx)
+	start := time.Now()
+	addRunnerActive(tr.requestCtx, r.Address(), 1)
+	addFnActive(r.Address(), call.Model().FnID, 1)
 	isPlaced, err := r.TryExec(ctx, call)
+	addFnActive(r.Address(), call.Model().FnID, -1)
+	addRunnerActive(tr.requestCtx, r.Address(), -1)
+	recordRunnerRequest(tr.requestCtx, r.Address(), isPlaced, err, time.Since(start))
 	cancel()
 
+	if rej, ok := err.(*RunnerRejection); ok && !isPlaced && rej.Reason == RejectFailed {
+		if tr.failovers < tr.cfg.MaxFailovers {
+			tr.failovers++
+			stats.Record(tr.requestCtx, failoverCountMeasure.M(0))
+			call.ResponseWriter().Header().Set(FailoverHeader, strconv.Itoa(tr.failovers))
+		} else {
+			// out of failovers, the call ran and failed
+			isPlaced, err = true, rej.Err
+		}
+	}
+
+	span.AddAttributes(trace.BoolAttribute("placed", isPlaced))
+	if err != nil {
+		span.AddAttributes(trace.StringAttribute("error", err.Error()))
+	}
+	span.End()
+
+	if tr.audit != nil {
+		attempt := PlacementAttempt{
+			Runner:    r.Address(),
+			StartedAt: start,
+			Duration:  time.Since(start),
+			Placed:    isPlaced,
+		}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		tr.audit.addAttempt(attempt)
+	}
+
 	if !isPlaced {
 
 		// Too Busy is super common case, we track it separately
 		if err == models.ErrCallTimeoutServerBusy {
 			stats.Record(tr.requestCtx, retryTooBusyCountMeasure.M(0))
+		} else if rej, ok := err.(*RunnerRejection); ok {
+			stats.Record(tr.requestCtx, retryRejectedCountMeasure.M(0))
+			if rej.Reason.IsPermanent() {
+				tr.rejections++
+				tr.lastRejection = rej
+			}
 		} else if tr.requestCtx.Err() != err {
 			// only record retry due to an error if client did not abort/cancel/timeout
 			stats.Record(tr.requestCtx, retryErrorCountMeasure.M(0))
@@ -83,6 +155,10 @@ func (tr *placerTracker) TryRunner(r Runner, call RunnerCall) (bool, error) {
 			stats.Record(tr.requestCtx, placedErrorCountMeasure.M(0))
 		}
 
+		if p := placedRunnerFromContext(tr.requestCtx); p != nil {
+			p.set(r.Address())
+		}
+
 		// Call is now committed. In other words, it was 'run'. We are done.
 		tr.isPlaced = true
 	}
@@ -111,6 +187,31 @@ func (tr *placerTracker) HandleDone() {
 
 	tr.tracker.finalizeAttempts(tr.isPlaced)
 	tr.cancel()
+
+	if tr.audit != nil {
+		tr.audit.Placed = tr.isPlaced
+		tr.audit.CompletedAt = time.Now()
+		if !tr.isPlaced && tr.audit.Error == "" {
+			if err := tr.requestCtx.Err(); err != nil {
+				tr.audit.Error = err.Error()
+			} else {
+				tr.audit.Error = models.ErrCallTimeoutServerBusy.Error()
+			}
+		}
+		tr.cfg.AuditLog.Record(tr.audit)
+	}
+
+	tr.span.AddAttributes(trace.BoolAttribute("placed", tr.isPlaced))
+	tr.span.End()
+}
+
+// RejectedErr returns the error runners rejected the call with if all of
+// them declined it for good in the last pass, nil otherwise.
+func (tr *placerTracker) RejectedErr() error {
+	if tr.lastRejection == nil || tr.requestCtx.Err() != nil || tr.placerCtx.Err() != nil {
+		return nil
+	}
+	return tr.lastRejection.Err
 }
 
 // RetryAllBackoff blocks until it is time to try the runner list again. Returns
@@ -123,6 +224,22 @@ func (tr *placerTracker) RetryAllBackoff(numOfRunners int) bool {
 		stats.Record(tr.requestCtx, emptyPoolCountMeasure.M(0))
 	}
 
+	// Every runner declined the call for good, retrying is pointless.
+	rejections := tr.rejections
+	tr.rejections = 0
+	if numOfRunners > 0 && rejections >= numOfRunners {
+		return false
+	}
+
+	if tr.audit != nil {
+		tr.audit.Passes++
+		if numOfRunners == 0 {
+			tr.audit.EmptyPasses++
+		}
+		start := time.Now()
+		defer func() { tr.audit.RetryWait += time.Since(start) }()
+	}
+
 	select {
 	case <-tr.requestCtx.Done(): // client side timeout/cancel
 		return false
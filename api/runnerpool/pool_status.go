@@ -0,0 +1,194 @@
+package runnerpool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// requests of runners are counted in buckets over the last statusWindow
+	statusBucket  = 10 * time.Second
+	statusBuckets = 6
+	statusWindow  = statusBucket * statusBuckets
+)
+
+// Health of a runner in a PoolStatus
+const (
+	RunnerHealthy   = "healthy"
+	RunnerUnhealthy = "unhealthy"
+	RunnerProbing   = "probing"
+)
+
+// PoolStatus is a snapshot of the runners of a pool and of how calls were
+// recently spread over them
+type PoolStatus struct {
+	Runners []RunnerState `json:"runners"`
+	// Window is the time requests of runners are counted over
+	Window time.Duration `json:"window"`
+	// Requests is the number of calls tried on any runner in the window
+	Requests int64 `json:"requests"`
+}
+
+// RunnerState is the state of a runner in a PoolStatus
+type RunnerState struct {
+	Address string `json:"address"`
+	Zone    string `json:"zone,omitempty"`
+	// Health is healthy unless the circuit breaker of the runner is open
+	// (unhealthy) or a probe call is in flight (probing)
+	Health   string `json:"health"`
+	InFlight int64  `json:"in_flight"`
+	// Requests counts the calls tried on the runner in the window by result,
+	// see the RunnerResult constants
+	Requests map[string]int64 `json:"requests"`
+	// Share is the fraction of the calls tried in the window that were tried
+	// on the runner
+	Share float64 `json:"share"`
+}
+
+// wrappingPool is implemented by runner pools wrapping another pool
+type wrappingPool interface {
+	inner() RunnerPool
+}
+
+func (p *circuitBreakerPool) inner() RunnerPool { return p.pool }
+func (p *scalingPool) inner() RunnerPool        { return p.pool }
+func (p *chaosPool) inner() RunnerPool          { return p.pool }
+
+// BasePool returns the pool wrapped by the circuit breaker, scaling and chaos
+// pools of rp, or rp if it wraps no pool
+func BasePool(rp RunnerPool) RunnerPool {
+	for {
+		w, ok := rp.(wrappingPool)
+		if !ok {
+			return rp
+		}
+		rp = w.inner()
+	}
+}
+
+// health returns the health of the runner at addr
+func (p *circuitBreakerPool) health(addr string) string {
+	p.lock.Lock()
+	br, ok := p.runners[addr]
+	p.lock.Unlock()
+	if !ok {
+		return RunnerHealthy
+	}
+
+	br.lock.Lock()
+	defer br.lock.Unlock()
+	switch br.state {
+	case breakerOpen:
+		return RunnerUnhealthy
+	case breakerHalfOpen:
+		return RunnerProbing
+	}
+	return RunnerHealthy
+}
+
+// Status returns the status of the runners of rp. Unlike placers, it sees the
+// runners wrapping pools leave out, eg. those with an open circuit breaker.
+func Status(ctx context.Context, rp RunnerPool) (*PoolStatus, error) {
+	var breakers *circuitBreakerPool
+	for {
+		if bp, ok := rp.(*circuitBreakerPool); ok && breakers == nil {
+			breakers = bp
+		}
+		w, ok := rp.(wrappingPool)
+		if !ok {
+			break
+		}
+		rp = w.inner()
+	}
+
+	runners, err := rp.Runners(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	status := &PoolStatus{Runners: make([]RunnerState, 0, len(runners)), Window: statusWindow}
+	for _, r := range runners {
+		state := RunnerState{
+			Address:  r.Address(),
+			Zone:     RunnerZone(r),
+			Health:   RunnerHealthy,
+			InFlight: runnerActive(r.Address()),
+			Requests: recentRequests.get(r.Address(), now),
+		}
+		if breakers != nil {
+			state.Health = breakers.health(r.Address())
+		}
+		for _, n := range state.Requests {
+			status.Requests += n
+		}
+		status.Runners = append(status.Runners, state)
+	}
+	sort.Slice(status.Runners, func(i, j int) bool { return status.Runners[i].Address < status.Runners[j].Address })
+
+	if status.Requests > 0 {
+		for i := range status.Runners {
+			var n int64
+			for _, c := range status.Runners[i].Requests {
+				n += c
+			}
+			status.Runners[i].Share = float64(n) / float64(status.Requests)
+		}
+	}
+	return status, nil
+}
+
+// requestWindow counts the requests of runners by result over the last
+// statusWindow, in buckets of statusBucket
+type requestWindow struct {
+	lock    sync.Mutex
+	runners map[string]*runnerBuckets
+}
+
+type runnerBuckets struct {
+	epochs [statusBuckets]int64
+	counts [statusBuckets]map[string]int64
+}
+
+var recentRequests = &requestWindow{runners: make(map[string]*runnerBuckets)}
+
+func (w *requestWindow) add(addr, result string, now time.Time) {
+	epoch := now.UnixNano() / int64(statusBucket)
+	i := epoch % statusBuckets
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	b, ok := w.runners[addr]
+	if !ok {
+		b = new(runnerBuckets)
+		w.runners[addr] = b
+	}
+	if b.epochs[i] != epoch || b.counts[i] == nil {
+		b.epochs[i] = epoch
+		b.counts[i] = make(map[string]int64)
+	}
+	b.counts[i][result]++
+}
+
+func (w *requestWindow) get(addr string, now time.Time) map[string]int64 {
+	epoch := now.UnixNano() / int64(statusBucket)
+	res := make(map[string]int64)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	b, ok := w.runners[addr]
+	if !ok {
+		return res
+	}
+	for i := range b.epochs {
+		if epoch-b.epochs[i] >= statusBuckets {
+			continue
+		}
+		for result, n := range b.counts[i] {
+			res[result] += n
+		}
+	}
+	return res
+}
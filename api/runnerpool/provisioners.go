@@ -0,0 +1,86 @@
+package runnerpool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// execProvisioner runs a command to scale runners
+type execProvisioner struct {
+	path string
+}
+
+// NewExecProvisioner returns a provisioner running the executable at path for
+// every scale request. The request is passed in the FN_SCALE_DELTA and
+// FN_SCALE_RUNNERS environment variables and as JSON on stdin.
+func NewExecProvisioner(path string) Provisioner {
+	return &execProvisioner{path: path}
+}
+
+func (p *execProvisioner) Scale(ctx context.Context, req ScaleRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Env = append(os.Environ(),
+		"FN_SCALE_DELTA="+strconv.Itoa(req.Delta),
+		"FN_SCALE_RUNNERS="+strconv.Itoa(req.Runners),
+	)
+	cmd.Stdin = bytes.NewReader(body)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("scale hook %s failed: %v: %s", p.path, err, out)
+	}
+	return nil
+}
+
+const webhookTimeout = 30 * time.Second
+
+// webhookProvisioner posts scale requests to an http endpoint
+type webhookProvisioner struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookProvisioner returns a provisioner posting every scale request as JSON
+// to url, any non 2xx response is an error. Cloud APIs can be driven by a small
+// service behind such a webhook.
+func NewWebhookProvisioner(url string) Provisioner {
+	return &webhookProvisioner{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (p *webhookProvisioner) Scale(ctx context.Context, req ScaleRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("scale webhook %s returned status %d", p.url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package runnerpool
+
+// RejectionMetadataKey is the gRPC header a runner sets to tell the lb why it
+// declined a call without running it
+const RejectionMetadataKey = "fn-rejection"
+
+// RejectReason explains why a runner declined a call
+type RejectReason string
+
+const (
+	// RejectTooBusy means the runner is at capacity, the call may be retried later
+	RejectTooBusy RejectReason = "too-busy"
+	// RejectOverMemory means the call can never fit the resources of the runner
+	RejectOverMemory RejectReason = "over-memory"
+	// RejectUnsupported means the runner cannot run this kind of call, eg. detached calls or a blocked image
+	RejectUnsupported RejectReason = "unsupported"
+	// RejectDraining means the runner is shutting down and takes no new calls
+	RejectDraining RejectReason = "draining"
+	// RejectFailed means the runner failed before responding to an idempotent
+	// or async call, which may fail over to another runner, see
+	// PlacerConfig.MaxFailovers
+	RejectFailed RejectReason = "failed"
+)
+
+// IsPermanent reports whether retrying the call on the same runner is pointless
+func (r RejectReason) IsPermanent() bool {
+	return r == RejectOverMemory || r == RejectUnsupported
+}
+
+// RunnerRejection is returned by Runner.TryExec along with placed=false when a
+// runner declined a call. Err is the error the runner rejected the call with.
+type RunnerRejection struct {
+	Reason RejectReason
+	Err    error
+}
+
+func (e *RunnerRejection) Error() string {
+	return "runner rejected call (" + string(e.Reason) + "): " + e.Err.Error()
+}
@@ -25,6 +25,20 @@ type RunnerPool interface {
 	Shutdown(ctx context.Context) error
 }
 
+// NodeProvider discovers the addresses of the runners a pool should use
+type NodeProvider interface {
+	// returns the current runner addresses, an error leaves the pool unchanged
+	Nodes(ctx context.Context) ([]string, error)
+}
+
+// RunnerSetter is implemented by runner pools whose runners are listed in the
+// configuration of the lb, so that the list can be changed without a restart
+type RunnerSetter interface {
+	// SetRunners replaces the runners of the pool by those at addresses,
+	// runners whose address is still listed are kept
+	SetRunners(ctx context.Context, addresses []string)
+}
+
 // MTLSRunnerFactory represents a factory method for constructing runners using mTLS
 type MTLSRunnerFactory func(addr string, tlsConf *tls.Config) (Runner, error)
 
@@ -65,4 +79,10 @@ type RunnerCall interface {
 	// For metrics/stats, add special accounting for time spent in customer code
 	AddUserExecutionTime(dur time.Duration)
 	GetUserExecutionTime() *time.Duration
+	// AddTimings adds the time in milliseconds the call spent in each phase
+	// on a runner, see models.CallTimings
+	AddTimings(timings models.CallTimings)
+	// SetResolvedImage records the digest and platform the image of the call
+	// resolved to on the runner which executed it
+	SetResolvedImage(digest, platform string)
 }
@@ -0,0 +1,176 @@
+package runnerpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// Outcomes of a TryExec on a runner, the runner_result tag of its metrics
+const (
+	RunnerResultPlaced   = "placed"
+	RunnerResultError    = "error"
+	RunnerResultBusy     = "busy"
+	RunnerResultRejected = "rejected"
+	RunnerResultAborted  = "aborted"
+)
+
+var (
+	runnerAddrKey   = common.MakeKey("runner_addr")
+	runnerResultKey = common.MakeKey("runner_result")
+)
+
+var (
+	runnerRequestCountMeasure = common.MakeMeasure("lb_runner_request_count", "LB Runner Request Count", "")
+	runnerLatencyMeasure      = common.MakeMeasure("lb_runner_latency", "LB Runner Request Latency", "msecs")
+	runnerActiveMeasure       = common.MakeMeasure("lb_runner_active_requests", "LB Runner Requests In Flight", "")
+	runnerMemberMeasure       = common.MakeMeasure("lb_runner_member", "LB Runner Is A Member Of The Pool", "")
+	runnerPoolSizeMeasure     = common.MakeMeasure("lb_runner_pool_size", "LB Runner Pool Size", "")
+)
+
+// requests in flight on each runner by address, runners are shared by the
+// placements of all calls
+var runnersActive = struct {
+	sync.Mutex
+	count map[string]int64
+}{count: make(map[string]int64)}
+
+// requests in flight on each runner by fn, see antiAffinityPool
+var fnsActive = struct {
+	sync.Mutex
+	count map[fnRunner]int
+}{count: make(map[fnRunner]int)}
+
+type fnRunner struct {
+	addr string
+	fnID string
+}
+
+func runnerCtx(ctx context.Context, addr string) context.Context {
+	ctx, err := tag.New(ctx, tag.Upsert(runnerAddrKey, addr))
+	if err != nil {
+		logrus.WithError(err).Fatalf("cannot add tag %v=%v", runnerAddrKey, addr)
+	}
+	return ctx
+}
+
+func addRunnerActive(ctx context.Context, addr string, delta int64) {
+	runnersActive.Lock()
+	n := runnersActive.count[addr] + delta
+	if n <= 0 {
+		delete(runnersActive.count, addr)
+	} else {
+		runnersActive.count[addr] = n
+	}
+	runnersActive.Unlock()
+	stats.Record(runnerCtx(ctx, addr), runnerActiveMeasure.M(n))
+}
+
+// runnerResult classifies the outcome of a TryExec for the metrics of the runner
+func runnerResult(ctx context.Context, placed bool, err error) string {
+	switch {
+	case err == nil && placed:
+		return RunnerResultPlaced
+	case err != nil && ctx.Err() == err:
+		return RunnerResultAborted
+	case err == models.ErrCallTimeoutServerBusy:
+		return RunnerResultBusy
+	}
+	if _, ok := err.(*RunnerRejection); ok {
+		return RunnerResultRejected
+	}
+	return RunnerResultError
+}
+
+func addFnActive(addr, fnID string, delta int) {
+	if fnID == "" {
+		return
+	}
+	key := fnRunner{addr, fnID}
+	fnsActive.Lock()
+	if n := fnsActive.count[key] + delta; n <= 0 {
+		delete(fnsActive.count, key)
+	} else {
+		fnsActive.count[key] = n
+	}
+	fnsActive.Unlock()
+}
+
+// fnsActiveOn returns the requests of fnIDs in flight on the runner at addr
+func fnsActiveOn(addr string, fnIDs ...string) int {
+	fnsActive.Lock()
+	defer fnsActive.Unlock()
+	n := 0
+	for _, id := range fnIDs {
+		n += fnsActive.count[fnRunner{addr, id}]
+	}
+	return n
+}
+
+func runnerActive(addr string) int64 {
+	runnersActive.Lock()
+	defer runnersActive.Unlock()
+	return runnersActive.count[addr]
+}
+
+func recordRunnerRequest(ctx context.Context, addr string, placed bool, err error, latency time.Duration) {
+	result := runnerResult(ctx, placed, err)
+	recentRequests.add(addr, result, time.Now())
+
+	ctx = runnerCtx(ctx, addr)
+	stats.Record(ctx, runnerLatencyMeasure.M(int64(latency/time.Millisecond)))
+
+	ctx, tagErr := tag.New(ctx, tag.Upsert(runnerResultKey, result))
+	if tagErr != nil {
+		logrus.WithError(tagErr).Fatalf("cannot add tag %v", runnerResultKey)
+	}
+	stats.Record(ctx, runnerRequestCountMeasure.M(0))
+}
+
+// StatsRunnerMembers records the runners of a pool after its membership
+// changed, the addresses of runners that left the pool are reported as no
+// longer members.
+func StatsRunnerMembers(ctx context.Context, runners []Runner, removed []string) {
+	stats.Record(ctx, runnerPoolSizeMeasure.M(int64(len(runners))))
+	for _, r := range runners {
+		stats.Record(runnerCtx(ctx, r.Address()), runnerMemberMeasure.M(1))
+	}
+	for _, addr := range removed {
+		stats.Record(runnerCtx(ctx, addr), runnerMemberMeasure.M(0))
+	}
+}
+
+// RegisterRunnerViews registers the views of the runners of the pool, broken
+// down by runner address
+func RegisterRunnerViews(tagKeys []string, latencyDist []float64) {
+	runnerTags := withKey(tagKeys, runnerAddrKey.Name())
+	err := view.Register(
+		common.CreateView(runnerRequestCountMeasure, view.Count(), withKey(runnerTags, runnerResultKey.Name())),
+		common.CreateView(runnerLatencyMeasure, view.Distribution(latencyDist...), runnerTags),
+		common.CreateView(runnerActiveMeasure, view.LastValue(), runnerTags),
+		common.CreateView(runnerMemberMeasure, view.LastValue(), runnerTags),
+		common.CreateView(runnerPoolSizeMeasure, view.LastValue(), tagKeys),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create view")
+	}
+}
+
+func withKey(tagKeys []string, key string) []string {
+	keys := make([]string, 0, len(tagKeys)+1)
+	keys = append(keys, key)
+	for _, k := range tagKeys {
+		if k != key {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
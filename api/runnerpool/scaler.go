@@ -0,0 +1,227 @@
+package runnerpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ScaleRequest asks a provisioner to change the number of runners of a pool
+type ScaleRequest struct {
+	// Number of runners currently in the pool
+	Runners int `json:"runners"`
+	// Number of runners to add, negative to remove runners
+	Delta int `json:"delta"`
+	// Demand seen by the lb since the previous scale evaluation
+	Placed uint64 `json:"placed"`
+	Busy   uint64 `json:"busy"`
+	Empty  uint64 `json:"empty"`
+}
+
+// Provisioner adds or removes runner nodes. New runners are expected to show
+// up in the runner pool on their own, eg. through runner discovery.
+type Provisioner interface {
+	Scale(ctx context.Context, req ScaleRequest) error
+}
+
+// ScalerConfig configures when a scaling pool asks for more or less runners
+type ScalerConfig struct {
+	// Interval demand is evaluated at
+	Interval time.Duration `json:"interval"`
+
+	// Ratio of busy placement attempts in an interval above which a runner is added
+	BusyThreshold float64 `json:"busy_threshold"`
+
+	// Number of consecutive intervals without any call before a runner is removed
+	IdleIntervals int `json:"idle_intervals"`
+
+	// Bounds of the pool size, MaxRunners of zero means no upper bound
+	MinRunners int `json:"min_runners"`
+	MaxRunners int `json:"max_runners"`
+
+	// Minimum time between two scale requests, new runners need time to register
+	Cooldown time.Duration `json:"cooldown"`
+}
+
+func NewScalerConfig() ScalerConfig {
+	return ScalerConfig{
+		Interval:      10 * time.Second,
+		BusyThreshold: 0.2,
+		IdleIntervals: 30,
+		MinRunners:    1,
+		Cooldown:      time.Minute,
+	}
+}
+
+// scalingPool wraps a runner pool and tracks the placement demand on it
+type scalingPool struct {
+	pool        RunnerPool
+	provisioner Provisioner
+	cfg         ScalerConfig
+
+	placed  uint64
+	busy    uint64
+	empty   uint64
+	runners int64
+
+	lock    sync.Mutex
+	wrapped map[string]*scalingRunner
+
+	idle      int
+	lastScale time.Time
+
+	wakeC  chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewScalingPool returns a runner pool that aggregates the placement demand on
+// pool and calls provisioner to add runners when runners keep being too busy
+// or no runner is available, and to remove runners when the pool is idle.
+func NewScalingPool(pool RunnerPool, provisioner Provisioner, cfg *ScalerConfig) RunnerPool {
+	logrus.Infof("Creating new scaling runnerpool with config=%+v", cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &scalingPool{
+		pool:        pool,
+		provisioner: provisioner,
+		cfg:         *cfg,
+		wrapped:     make(map[string]*scalingRunner),
+		wakeC:       make(chan struct{}, 1),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	go p.scaleLoop(ctx)
+	return p
+}
+
+func (p *scalingPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := p.pool.Runners(ctx, call)
+	atomic.StoreInt64(&p.runners, int64(len(runners)))
+	if len(runners) == 0 {
+		atomic.AddUint64(&p.empty, 1)
+	}
+
+	res := make([]Runner, 0, len(runners))
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// keep the wrappers stable, outer pools may track runners by identity
+	for _, r := range runners {
+		sr, ok := p.wrapped[r.Address()]
+		if !ok || sr.Runner != r {
+			sr = &scalingRunner{Runner: r, pool: p}
+			p.wrapped[r.Address()] = sr
+		}
+		res = append(res, sr)
+	}
+	return res, err
+}
+
+func (p *scalingPool) Shutdown(ctx context.Context) error {
+	p.cancel()
+	<-p.done
+	return p.pool.Shutdown(ctx)
+}
+
+func (p *scalingPool) scaleLoop(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wakeC:
+			p.scale(ctx, p.evaluate(time.Now()))
+		case now := <-ticker.C:
+			p.scale(ctx, p.evaluate(now))
+		}
+	}
+}
+
+// wake evaluates the demand without waiting for the next interval, eg. for
+// calls waiting for a pool without runners
+func (p *scalingPool) wake() {
+	select {
+	case p.wakeC <- struct{}{}:
+	default:
+	}
+}
+
+func (p *scalingPool) scale(ctx context.Context, req ScaleRequest) {
+	if req.Delta == 0 {
+		return
+	}
+	logrus.WithFields(logrus.Fields{"runners": req.Runners, "delta": req.Delta}).Info("Scaling runner pool")
+	if err := p.provisioner.Scale(ctx, req); err != nil {
+		logrus.WithError(err).Error("Failed to scale runner pool")
+	}
+}
+
+// evaluate resets the demand counters and returns how the pool should be scaled
+func (p *scalingPool) evaluate(now time.Time) ScaleRequest {
+	req := ScaleRequest{
+		Runners: int(atomic.LoadInt64(&p.runners)),
+		Placed:  atomic.SwapUint64(&p.placed, 0),
+		Busy:    atomic.SwapUint64(&p.busy, 0),
+		Empty:   atomic.SwapUint64(&p.empty, 0),
+	}
+
+	if req.Placed == 0 && req.Busy == 0 && req.Empty == 0 {
+		p.idle++
+	} else {
+		p.idle = 0
+	}
+
+	if now.Sub(p.lastScale) < p.cfg.Cooldown {
+		return req
+	}
+
+	tries := req.Placed + req.Busy
+	switch {
+	case req.Runners < p.cfg.MinRunners:
+		req.Delta = p.cfg.MinRunners - req.Runners
+	case p.cfg.MaxRunners > 0 && req.Runners >= p.cfg.MaxRunners:
+		if req.Runners > p.cfg.MaxRunners {
+			req.Delta = p.cfg.MaxRunners - req.Runners
+		}
+	case req.Runners == 0 && req.Empty > 0,
+		tries > 0 && float64(req.Busy)/float64(tries) >= p.cfg.BusyThreshold:
+		req.Delta = 1
+	case p.cfg.IdleIntervals > 0 && p.idle >= p.cfg.IdleIntervals && req.Runners > p.cfg.MinRunners:
+		req.Delta = -1
+	}
+
+	if req.Delta != 0 {
+		p.lastScale = now
+		p.idle = 0
+	}
+	return req
+}
+
+// scalingRunner is a runner accounting placement outcomes to its scaling pool
+type scalingRunner struct {
+	Runner
+	pool *scalingPool
+}
+
+func (r *scalingRunner) TryExec(ctx context.Context, call RunnerCall) (bool, error) {
+	placed, err := r.Runner.TryExec(ctx, call)
+	if placed {
+		atomic.AddUint64(&r.pool.placed, 1)
+	} else if err == models.ErrCallTimeoutServerBusy {
+		atomic.AddUint64(&r.pool.busy, 1)
+	}
+	return placed, err
+}
+
+func (r *scalingRunner) Zone() string {
+	return RunnerZone(r.Runner)
+}
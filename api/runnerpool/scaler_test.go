@@ -0,0 +1,99 @@
+package runnerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScalerEvaluate(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		min, max            int
+		runners             int64
+		placed, busy, empty uint64
+		// idle intervals before this one
+		idle int
+		// whether the pool was scaled within the cooldown
+		cooling bool
+		delta   int
+	}{
+		{name: "adds runners below the minimum", min: 2, max: 4, runners: 0, delta: 2},
+		{name: "removes runners above the maximum", max: 4, runners: 6, delta: -2},
+		{name: "adds no runner at the maximum", max: 4, runners: 4, placed: 1, busy: 9},
+		{name: "adds a runner for calls without runners", runners: 0, empty: 1, delta: 1},
+		{name: "adds a runner when runners are busy", runners: 2, placed: 8, busy: 2, delta: 1},
+		{name: "adds no runner when runners are busy below the threshold", runners: 2, placed: 9, busy: 1},
+		{name: "adds no runner within the cooldown", runners: 2, busy: 10, cooling: true},
+		{name: "removes a runner once idle", min: 1, runners: 3, idle: 1, delta: -1},
+		{name: "removes no runner before the pool is idle long enough", min: 1, runners: 3},
+		{name: "removes no runner at the minimum", min: 3, runners: 3, idle: 5},
+		{name: "removes no runner within the cooldown", min: 1, runners: 3, idle: 5, cooling: true},
+	} {
+		now := time.Now()
+		p := &scalingPool{
+			cfg:     ScalerConfig{BusyThreshold: 0.2, IdleIntervals: 2, MinRunners: tc.min, MaxRunners: tc.max, Cooldown: time.Minute},
+			runners: tc.runners, placed: tc.placed, busy: tc.busy, empty: tc.empty,
+			idle: tc.idle,
+		}
+		if tc.cooling {
+			p.lastScale = now.Add(-30 * time.Second)
+		}
+
+		req := p.evaluate(now)
+		if req.Delta != tc.delta {
+			t.Errorf("%s: expected delta %d, got %d", tc.name, tc.delta, req.Delta)
+		}
+		if req.Runners != int(tc.runners) || req.Placed != tc.placed || req.Busy != tc.busy || req.Empty != tc.empty {
+			t.Errorf("%s: expected the demand in the request, got %+v", tc.name, req)
+		}
+		if p.placed != 0 || p.busy != 0 || p.empty != 0 {
+			t.Errorf("%s: expected the demand to be reset", tc.name)
+		}
+		if scaled := p.lastScale.Equal(now); scaled != (tc.delta != 0) || (scaled && p.idle != 0) {
+			t.Errorf("%s: expected the pool to be scaled %v, got last scale %v idle %d", tc.name, tc.delta != 0, p.lastScale, p.idle)
+		}
+	}
+}
+
+type testProvisioner struct {
+	reqs chan ScaleRequest
+}
+
+func (p *testProvisioner) Scale(ctx context.Context, req ScaleRequest) error {
+	p.reqs <- req
+	return nil
+}
+
+func TestScalingPoolDemand(t *testing.T) {
+	ctx := context.Background()
+	base := &testPool{runners: []Runner{&testRunner{addr: "a"}, &testRunner{addr: "b", busy: true}}}
+	provisioner := &testProvisioner{reqs: make(chan ScaleRequest, 1)}
+	cfg := NewScalerConfig()
+	cfg.Interval = time.Hour
+	cfg.MinRunners = 0
+	rp := NewScalingPool(base, provisioner, &cfg)
+	defer rp.Shutdown(ctx)
+
+	runners, err := rp.Runners(ctx, nil)
+	if err != nil || len(runners) != 2 {
+		t.Fatalf("Expected the runners of the pool, got %d %v", len(runners), err)
+	}
+	for _, r := range runners {
+		r.TryExec(ctx, nil)
+	}
+	if again, _ := rp.Runners(ctx, nil); again[0] != runners[0] || again[1] != runners[1] {
+		t.Fatal("Expected the runners to be wrapped once")
+	}
+
+	// busy runners get the pool a runner more
+	rp.(*scalingPool).wake()
+	select {
+	case req := <-provisioner.reqs:
+		if req.Runners != 2 || req.Placed != 1 || req.Busy != 1 || req.Delta != 1 {
+			t.Fatalf("Unexpected scale request %+v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the pool to be scaled")
+	}
+}
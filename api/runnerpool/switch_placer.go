@@ -0,0 +1,40 @@
+package runnerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// SwitchPlacer is a Placer delegating to another placer that can be replaced
+// while calls are placed, eg. when the placement algorithm of an lb is reloaded
+type SwitchPlacer struct {
+	lock   sync.RWMutex
+	placer Placer
+}
+
+// NewSwitchPlacer returns a SwitchPlacer placing calls with p until Set is called
+func NewSwitchPlacer(p Placer) *SwitchPlacer {
+	return &SwitchPlacer{placer: p}
+}
+
+// Set makes p place the calls placed from now on, calls being placed finish
+// their placement with the previous placer
+func (sp *SwitchPlacer) Set(p Placer) {
+	sp.lock.Lock()
+	sp.placer = p
+	sp.lock.Unlock()
+}
+
+func (sp *SwitchPlacer) get() Placer {
+	sp.lock.RLock()
+	defer sp.lock.RUnlock()
+	return sp.placer
+}
+
+func (sp *SwitchPlacer) PlaceCall(ctx context.Context, rp RunnerPool, call RunnerCall) error {
+	return sp.get().PlaceCall(ctx, rp, call)
+}
+
+func (sp *SwitchPlacer) GetPlacerConfig() PlacerConfig {
+	return sp.get().GetPlacerConfig()
+}
@@ -0,0 +1,43 @@
+package runnerpool
+
+import (
+	"strings"
+)
+
+// ZoneRunner is implemented by runners that know the zone they run in
+type ZoneRunner interface {
+	Zone() string
+}
+
+// RunnerZone returns the zone of a runner, empty if unknown
+func RunnerZone(r Runner) string {
+	if zr, ok := r.(ZoneRunner); ok {
+		return zr.Zone()
+	}
+	return ""
+}
+
+// ParseRunnerAddress splits a runner address of the form host:port[@zone]
+func ParseRunnerAddress(s string) (addr, zone string) {
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// WithZone labels a runner with the zone it runs in
+func WithZone(r Runner, zone string) Runner {
+	if zone == "" {
+		return r
+	}
+	return &zonedRunner{Runner: r, zone: zone}
+}
+
+type zonedRunner struct {
+	Runner
+	zone string
+}
+
+func (r *zonedRunner) Zone() string {
+	return r.zone
+}
@@ -0,0 +1,107 @@
+package runnerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+)
+
+type zonePlacer struct {
+	cfg       PlacerConfig
+	zone      string
+	spillWait time.Duration
+	rrIndex   uint64
+}
+
+// NewZonePlacer returns a placer that prefers runners in zone. Runners of other
+// zones are only tried once a call could not be placed locally for spillWait,
+// or right away if there is no runner in zone.
+func NewZonePlacer(cfg *PlacerConfig, zone string, spillWait time.Duration) Placer {
+	logrus.Infof("Creating new zone aware runnerpool placer zone=%s spill_wait=%v with config=%+v", zone, spillWait, cfg)
+	return &zonePlacer{
+		cfg:       *cfg,
+		zone:      zone,
+		spillWait: spillWait,
+		rrIndex:   uint64(time.Now().Nanosecond()),
+	}
+}
+
+func (sp *zonePlacer) GetPlacerConfig() PlacerConfig {
+	return sp.cfg
+}
+
+func (sp *zonePlacer) PlaceCall(ctx context.Context, rp RunnerPool, call RunnerCall) error {
+	state := NewPlacerTracker(ctx, &sp.cfg, call)
+	defer state.HandleDone()
+
+	start := time.Now()
+
+	var runnerPoolErr error
+	for {
+		var runners []Runner
+		runners, runnerPoolErr = rp.Runners(ctx, call)
+		if runnerPoolErr == ErrActivatorQueueFull {
+			break
+		}
+
+		var local, remote []Runner
+		for _, r := range runners {
+			if RunnerZone(r) == sp.zone {
+				local = append(local, r)
+			} else {
+				remote = append(remote, r)
+			}
+		}
+
+		tried := len(local)
+		if placed, err := sp.tryRunners(state, local, call); placed {
+			return err
+		}
+
+		if len(local) == 0 || time.Since(start) >= sp.spillWait {
+			tried += len(remote)
+			if placed, err := sp.tryRunners(state, remote, call); placed {
+				stats.Record(ctx, crossZoneCountMeasure.M(0))
+				return err
+			}
+		}
+
+		if !state.RetryAllBackoff(tried) {
+			break
+		}
+	}
+
+	if err := state.RejectedErr(); err != nil {
+		return err
+	}
+
+	if runnerPoolErr != nil {
+		// If we haven't been able to place the function and we got an error
+		// from the runner pool, return that error (since we don't have
+		// enough runners to handle the current load and the runner pool is
+		// having trouble).
+		state.HandleFindRunnersFailure(runnerPoolErr)
+		return runnerPoolErr
+	}
+	return models.ErrCallTimeoutServerBusy
+}
+
+// tryRunners tries runners round robin until the call is placed
+func (sp *zonePlacer) tryRunners(state *placerTracker, runners []Runner, call RunnerCall) (bool, error) {
+	for j := 0; j < len(runners) && !state.IsDone(); j++ {
+
+		i := atomic.AddUint64(&sp.rrIndex, uint64(1))
+		r := runners[int(i)%len(runners)]
+
+		placed, err := state.TryRunner(r, call)
+		if placed {
+			return true, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+var errCiphertext = errors.New("secrets: ciphertext too short")
+
+// local encrypts with AES-256-GCM under a key it holds, ciphertexts are the
+// random nonce followed by the sealed value
+type local struct {
+	aead cipher.AEAD
+}
+
+// newLocal reads the base64 encoded 32 byte key of a local:///path url, or
+// takes it from the key parameter
+func newLocal(u *url.URL) (Keeper, error) {
+	encoded := u.Query().Get("key")
+	if encoded == "" {
+		if u.Path == "" {
+			return nil, errors.New("secrets: local kms url must name a key file or pass a key, e.g. local:///etc/fn/secrets.key")
+		}
+		b, err := ioutil.ReadFile(u.Path)
+		if err != nil {
+			return nil, fmt.Errorf("secrets: could not read key file: %v", err)
+		}
+		encoded = strings.TrimSpace(string(b))
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: local key must be base64 encoded: %v", err)
+	}
+	return NewLocal(key)
+}
+
+// NewLocal returns a keeper encrypting with AES-256-GCM under a 32 byte key
+func NewLocal(key []byte) (Keeper, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secrets: local key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &local{aead: aead}, nil
+}
+
+func (l *local) Encrypt(ctx context.Context, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, l.aead.NonceSize(), l.aead.NonceSize()+len(plaintext)+l.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return l.aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func (l *local) Decrypt(ctx context.Context, ciphertext, aad []byte) ([]byte, error) {
+	n := l.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, errCiphertext
+	}
+	return l.aead.Open(nil, ciphertext[:n], ciphertext[n:], aad)
+}
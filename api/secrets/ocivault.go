@@ -0,0 +1,128 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/internal/ocisign"
+)
+
+const (
+	defaultOCIConfig  = "~/.oci/config"
+	defaultOCIProfile = "DEFAULT"
+)
+
+// ociVault encrypts with a master encryption key of an oci vault
+type ociVault struct {
+	base   string
+	keyID  string
+	key    *ocisign.APIKey
+	client *http.Client
+}
+
+// newOCIVault returns a keeper for ocivault://<crypto endpoint>/<key ocid>,
+// signing requests with the api key of a profile of an oci cli config file
+func newOCIVault(u *url.URL) (Keeper, error) {
+	keyID := strings.Trim(u.Path, "/")
+	if u.Host == "" || keyID == "" {
+		return nil, errors.New("secrets: oci vault kms url must name the crypto endpoint and the key, e.g. ocivault://<vault>-crypto.kms.us-phoenix-1.oraclecloud.com/ocid1.key.oc1...")
+	}
+	q := u.Query()
+	config := q.Get("config")
+	if config == "" {
+		config = defaultOCIConfig
+	}
+	profile := q.Get("profile")
+	if profile == "" {
+		profile = defaultOCIProfile
+	}
+	key, err := ocisign.LoadAPIKey(config, profile)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: could not load oci api key: %v", err)
+	}
+
+	scheme := "https"
+	if q.Get("ssl") == "false" {
+		scheme = "http"
+	}
+	return &ociVault{
+		base:   scheme + "://" + u.Host + "/20180608/",
+		keyID:  keyID,
+		key:    key,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type ociCryptoRequest struct {
+	KeyID          string            `json:"keyId"`
+	Plaintext      string            `json:"plaintext,omitempty"`
+	Ciphertext     string            `json:"ciphertext,omitempty"`
+	AssociatedData map[string]string `json:"associatedData"`
+}
+
+func (v *ociVault) Encrypt(ctx context.Context, plaintext, aad []byte) ([]byte, error) {
+	var out struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	in := &ociCryptoRequest{
+		KeyID:          v.keyID,
+		Plaintext:      base64.StdEncoding.EncodeToString(plaintext),
+		AssociatedData: map[string]string{"fn": string(aad)},
+	}
+	if err := v.do(ctx, "encrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return []byte(out.Ciphertext), nil
+}
+
+func (v *ociVault) Decrypt(ctx context.Context, ciphertext, aad []byte) ([]byte, error) {
+	var out struct {
+		Plaintext string `json:"plaintext"`
+	}
+	in := &ociCryptoRequest{
+		KeyID:          v.keyID,
+		Ciphertext:     string(ciphertext),
+		AssociatedData: map[string]string{"fn": string(aad)},
+	}
+	if err := v.do(ctx, "decrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(out.Plaintext)
+}
+
+func (v *ociVault) do(ctx context.Context, op string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, v.base+op, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if err := v.key.Sign(req, body); err != nil {
+		return err
+	}
+
+	resp, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("secrets: oci vault %s: %s: %s", op, resp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,116 @@
+// Package secrets encrypts the secrets of apps with a key management service
+// and decrypts them for the containers of their fns.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// Keeper encrypts and decrypts secret values with a key it holds, or that a
+// key management service holds for it. The additional data binds ciphertexts
+// to the secret they were encrypted for, decrypting them with other additional
+// data fails.
+type Keeper interface {
+	Encrypt(ctx context.Context, plaintext, aad []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext, aad []byte) ([]byte, error)
+}
+
+// New returns the keeper of a url, one of:
+//
+//	local:///path/to/key or local://?key=<base64 key>
+//	vault://<host:port>/<transit mount>/keys/<key name>?token=<token>&ssl=false
+//	ocivault://<crypto endpoint>/<key ocid>?config=~/.oci/config&profile=DEFAULT
+func New(ctx context.Context, keeperURL string) (Keeper, error) {
+	u, err := url.Parse(keeperURL)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: bad kms url: %v", err)
+	}
+	switch u.Scheme {
+	case "local":
+		return newLocal(u)
+	case "vault":
+		return newVault(u)
+	case "ocivault":
+		return newOCIVault(u)
+	}
+	return nil, fmt.Errorf("secrets: no kms available for url scheme %q", u.Scheme)
+}
+
+// Seal returns the stored form of a secret of an app, its value encrypted by k
+func Seal(ctx context.Context, k Keeper, appID string, secret *models.Secret) (*models.StoredSecret, error) {
+	ciphertext, err := k.Encrypt(ctx, []byte(secret.Value), aad(appID, secret.Name))
+	if err != nil {
+		return nil, err
+	}
+	return &models.StoredSecret{
+		AppID:      appID,
+		Name:       secret.Name,
+		Mount:      secret.Mount,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+// aad is the additional data of the secret name of app appID, so that the
+// ciphertext of a secret cannot be copied to another secret or app
+func aad(appID, name string) []byte {
+	return []byte(appID + "/" + name)
+}
+
+// Value is a decrypted secret
+type Value struct {
+	Name  string
+	Mount string
+	Value []byte
+}
+
+// Source decrypts the secrets of apps
+type Source struct {
+	store  models.SecretStore
+	keeper Keeper
+}
+
+// NewSource returns a source of the secrets of store, decrypted by keeper
+func NewSource(store models.SecretStore, keeper Keeper) *Source {
+	return &Source{store: store, keeper: keeper}
+}
+
+// GetAppSecrets returns the decrypted secrets of an app
+func (s *Source) GetAppSecrets(ctx context.Context, appID string) ([]Value, error) {
+	stored, err := s.store.GetSecrets(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, 0, len(stored))
+	for _, secret := range stored {
+		plaintext, err := s.keeper.Decrypt(ctx, secret.Ciphertext, aad(appID, secret.Name))
+		if err != nil {
+			return nil, fmt.Errorf("secrets: could not decrypt secret %s: %v", secret.Name, err)
+		}
+		values = append(values, Value{Name: secret.Name, Mount: secret.Mount, Value: plaintext})
+	}
+	return values, nil
+}
+
+// GetAppSecret returns the decrypted value of the secret name of an app, or
+// models.ErrSecretsNotFound
+func (s *Source) GetAppSecret(ctx context.Context, appID, name string) ([]byte, error) {
+	stored, err := s.store.GetSecrets(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range stored {
+		if secret.Name != name {
+			continue
+		}
+		plaintext, err := s.keeper.Decrypt(ctx, secret.Ciphertext, aad(appID, secret.Name))
+		if err != nil {
+			return nil, fmt.Errorf("secrets: could not decrypt secret %s: %v", secret.Name, err)
+		}
+		return plaintext, nil
+	}
+	return nil, models.ErrSecretsNotFound
+}
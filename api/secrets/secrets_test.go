@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func testKey() string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+}
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	k, err := New(ctx, "local://?key="+testKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := k.Encrypt(ctx, []byte("s3cret"), []byte("app/TOKEN"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, []byte("s3cret")) {
+		t.Fatal("expected the value to be encrypted")
+	}
+	plaintext, err := k.Decrypt(ctx, ciphertext, []byte("app/TOKEN"))
+	if err != nil || string(plaintext) != "s3cret" {
+		t.Fatalf("expected the value to be decrypted, got %q %v", plaintext, err)
+	}
+	if _, err := k.Decrypt(ctx, ciphertext, []byte("other/TOKEN")); err == nil {
+		t.Fatal("expected decrypting with other additional data to fail")
+	}
+
+	if _, err := New(ctx, "local://?key="+base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Fatal("expected short keys to be rejected")
+	}
+}
+
+func TestVault(t *testing.T) {
+	// a transit engine which "encrypts" by base64 encoding the plaintext again
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/fn":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{
+				"ciphertext": "vault:v1:" + base64.StdEncoding.EncodeToString([]byte(in["plaintext"])),
+			}})
+		case "/v1/transit/decrypt/fn":
+			b, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(in["ciphertext"], "vault:v1:"))
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": string(b)}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	k, err := New(ctx, "vault://"+host+"/transit/keys/fn?ssl=false&token=root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := k.Encrypt(ctx, []byte("s3cret"), []byte("app/TOKEN"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := k.Decrypt(ctx, ciphertext, []byte("app/TOKEN"))
+	if err != nil || string(plaintext) != "s3cret" {
+		t.Fatalf("expected the value to be decrypted, got %q %v", plaintext, err)
+	}
+	if _, err := k.Decrypt(ctx, ciphertext, []byte("app/OTHER")); err != errAAD {
+		t.Fatalf("expected decrypting for another secret to fail, got %v", err)
+	}
+
+	k, _ = New(ctx, "vault://"+host+"/transit/keys/fn?ssl=false&token=wrong")
+	if _, err := k.Encrypt(ctx, []byte("s3cret"), nil); err == nil {
+		t.Fatal("expected vault errors to be returned")
+	}
+}
+
+func TestSource(t *testing.T) {
+	ctx := context.Background()
+	k, err := New(ctx, "local://?key="+testKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := datastore.NewMockInit([]*models.App{{ID: "app", Name: "app"}, {ID: "other", Name: "other"}})
+	store := ds.(models.SecretStore)
+
+	for _, s := range []*models.Secret{
+		{Name: "TOKEN", Value: "s3cret", Mount: models.SecretMountEnv},
+		{Name: "CERT", Value: "pem", Mount: models.SecretMountFile},
+	} {
+		stored, err := Seal(ctx, k, "app", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.PutSecret(ctx, stored); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	values, err := NewSource(store, k).GetAppSecrets(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[0].Name != "CERT" || string(values[0].Value) != "pem" || values[0].Mount != models.SecretMountFile ||
+		values[1].Name != "TOKEN" || string(values[1].Value) != "s3cret" {
+		t.Fatalf("unexpected secrets %+v", values)
+	}
+
+	// a ciphertext copied to another app does not decrypt
+	secrets, _ := store.GetSecrets(ctx, "app")
+	moved := *secrets[1]
+	moved.AppID = "other"
+	if _, err := store.PutSecret(ctx, &moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSource(store, k).GetAppSecrets(ctx, "other"); err == nil {
+		t.Fatal("expected a secret copied to another app not to decrypt")
+	}
+}
@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var errAAD = errors.New("secrets: ciphertext was encrypted for another secret")
+
+// vault encrypts with a key of a HashiCorp Vault transit secrets engine
+type vault struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+// newVault returns a keeper for vault://<host:port>/<transit mount>/keys/<key name>,
+// authenticated with the token parameter or VAULT_TOKEN
+func newVault(u *url.URL) (Keeper, error) {
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/keys/", 2)
+	if u.Host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.New("secrets: vault kms url must name the transit mount and key, e.g. vault://vault:8200/transit/keys/fn")
+	}
+	q := u.Query()
+	token := q.Get("token")
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("secrets: vault kms requires a token parameter or VAULT_TOKEN")
+	}
+	scheme := "https"
+	if q.Get("ssl") == "false" {
+		scheme = "http"
+	}
+	return &vault{
+		base:   fmt.Sprintf("%s://%s/v1/%s/%%s/%s", scheme, u.Host, parts[0], url.PathEscape(parts[1])),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Encrypt binds aad to the ciphertext by sealing it with the plaintext, as
+// transit keys only take additional data when they are derived or AEAD keys.
+func (v *vault) Encrypt(ctx context.Context, plaintext, aad []byte) ([]byte, error) {
+	var out struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	in := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(withAAD(plaintext, aad))}
+	if err := v.do(ctx, "encrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return []byte(out.Data.Ciphertext), nil
+}
+
+func (v *vault) Decrypt(ctx context.Context, ciphertext, aad []byte) ([]byte, error) {
+	var out struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, "decrypt", map[string]string{"ciphertext": string(ciphertext)}, &out); err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(out.Data.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return withoutAAD(sealed, aad)
+}
+
+func (v *vault) do(ctx context.Context, op string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf(v.base, op), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("secrets: vault %s: %s: %s", op, resp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// withAAD prefixes plaintext with aad and its length
+func withAAD(plaintext, aad []byte) []byte {
+	b := make([]byte, 4, 4+len(aad)+len(plaintext))
+	binary.BigEndian.PutUint32(b, uint32(len(aad)))
+	b = append(b, aad...)
+	return append(b, plaintext...)
+}
+
+// withoutAAD checks the aad sealed by withAAD and strips it
+func withoutAAD(sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < 4 {
+		return nil, errCiphertext
+	}
+	n := binary.BigEndian.Uint32(sealed)
+	if uint64(len(sealed)-4) < uint64(n) {
+		return nil, errCiphertext
+	}
+	if int(n) != len(aad) || subtle.ConstantTimeCompare(sealed[4:4+n], aad) != 1 {
+		return nil, errAAD
+	}
+	return sealed[4+n:], nil
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	pool "github.com/fnproject/fn/api/runnerpool"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// WithAccessLog maps EnvAccessLog and EnvAccessLogFormat, logging a line for
+// every request served to the location to. On lb nodes lines name the runner
+// the call of a request was placed on.
+func WithAccessLog(to, format string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if to == "" {
+			return nil
+		}
+		logger, err := common.NewLogger(to, format, "fn-access")
+		if err != nil {
+			return err
+		}
+		s.Router.Use(accessLogWrap(logger))
+		return nil
+	}
+}
+
+func accessLogWrap(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		placed := new(pool.PlacedRunner)
+		c.Request = c.Request.WithContext(pool.WithPlacedRunner(c.Request.Context(), placed))
+
+		c.Next()
+
+		fields := logrus.Fields{
+			"client_ip":  c.ClientIP(),
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+			"latency_ms": int64(time.Since(start) / time.Millisecond),
+			"bytes_in":   c.Request.ContentLength,
+			"bytes_out":  c.Writer.Size(),
+		}
+		if addr := placed.Address(); addr != "" {
+			fields["runner_addr"] = addr
+		}
+		if fnID := FnIDFromContext(c.Request.Context()); fnID != "" {
+			fields["fn_id"] = fnID
+		}
+		if rid := common.RequestIDFromContext(c.Request.Context()); rid != "" {
+			fields[common.RequestIDContextKey] = rid
+		}
+		logger.WithFields(fields).Info("access")
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+	logger.Formatter = &logrus.JSONFormatter{}
+
+	r := gin.New()
+	r.Use(accessLogWrap(logger))
+	r.POST("/invoke/:fnID", func(c *gin.Context) {
+		c.String(http.StatusAccepted, "hello")
+	})
+
+	req := httptest.NewRequest("POST", "/invoke/myfn", strings.NewReader("ping"))
+	req.RemoteAddr = "10.0.0.1:4242"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a json access log line, got %q: %v", buf.String(), err)
+	}
+	expected := map[string]interface{}{
+		"client_ip": "10.0.0.1",
+		"method":    "POST",
+		"path":      "/invoke/myfn",
+		"status":    float64(http.StatusAccepted),
+		"bytes_in":  float64(4),
+		"bytes_out": float64(5),
+	}
+	for k, v := range expected {
+		if line[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, line[k])
+		}
+	}
+	if _, ok := line["runner_addr"]; ok {
+		t.Errorf("expected no runner for a call that was not placed, got %v", line["runner_addr"])
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+)
+
+type admissionController struct {
+	fnext.AdmissionController
+	policy fnext.AdmissionFailurePolicy
+}
+
+// admission runs the admission controllers of the server as the first app, fn
+// and trigger listener, so that later listeners see admitted objects
+type admission struct {
+	// ds is the datastore below the listeners, to read the objects updated
+	ds          models.Datastore
+	controllers []admissionController
+}
+
+var (
+	_ fnext.AppListener     = new(admission)
+	_ fnext.FnListener      = new(admission)
+	_ fnext.TriggerListener = new(admission)
+)
+
+// AddAdmissionController adds a controller reviewing apps, fns and triggers
+// before they are created or updated, with policy deciding if they are
+// admitted when it fails.
+func (s *Server) AddAdmissionController(ac fnext.AdmissionController, policy fnext.AdmissionFailurePolicy) {
+	s.admission.controllers = append(s.admission.controllers, admissionController{ac, policy})
+}
+
+func (a *admission) admit(ctx context.Context, review *fnext.AdmissionReview) error {
+	for _, ac := range a.controllers {
+		err := ac.Admit(ctx, review)
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(models.APIError); ok {
+			return err
+		}
+		if ac.policy == fnext.AdmissionFailOpen {
+			common.Logger(ctx).WithError(err).Warn("Admission controller failed, admitting")
+			continue
+		}
+		common.Logger(ctx).WithError(err).Error("Admission controller failed, rejecting")
+		return models.ErrAdmissionFailed
+	}
+	return nil
+}
+
+func (a *admission) BeforeAppCreate(ctx context.Context, app *models.App) error {
+	if len(a.controllers) == 0 {
+		return nil
+	}
+	return a.admit(ctx, &fnext.AdmissionReview{Operation: fnext.AdmissionCreate, App: app})
+}
+
+// BeforeAppUpdate reviews the app as updated by the patch app, which is then
+// replaced by the patch giving the admitted app
+func (a *admission) BeforeAppUpdate(ctx context.Context, app *models.App) error {
+	if len(a.controllers) == 0 {
+		return nil
+	}
+	existing, err := a.ds.GetAppByID(ctx, app.ID)
+	if err != nil {
+		return err
+	}
+	updated := existing.Clone()
+	updated.Update(app)
+	if err := a.admit(ctx, &fnext.AdmissionReview{Operation: fnext.AdmissionUpdate, App: updated}); err != nil {
+		return err
+	}
+
+	syslogURL := ""
+	if updated.SyslogURL != nil {
+		syslogURL = *updated.SyslogURL
+	}
+	*app = models.App{
+		ID:          existing.ID,
+		Config:      replaceConfig(existing.Config, updated.Config),
+		Annotations: existing.Annotations.ChangeTo(updated.Annotations),
+		SyslogURL:   &syslogURL,
+	}
+	return nil
+}
+
+func (a *admission) BeforeFnCreate(ctx context.Context, fn *models.Fn) error {
+	if len(a.controllers) == 0 {
+		return nil
+	}
+	return a.admit(ctx, &fnext.AdmissionReview{Operation: fnext.AdmissionCreate, Fn: fn})
+}
+
+// BeforeFnUpdate reviews the fn as updated by the patch fn, which is then
+// replaced by the patch giving the admitted fn
+func (a *admission) BeforeFnUpdate(ctx context.Context, fn *models.Fn) error {
+	if len(a.controllers) == 0 {
+		return nil
+	}
+	existing, err := a.ds.GetFnByID(ctx, fn.ID)
+	if err != nil {
+		return err
+	}
+	updated := existing.Clone()
+	updated.Update(fn)
+	if err := a.admit(ctx, &fnext.AdmissionReview{Operation: fnext.AdmissionUpdate, Fn: updated}); err != nil {
+		return err
+	}
+
+	*fn = *updated
+	fn.ID = existing.ID
+	fn.Config = replaceConfig(existing.Config, updated.Config)
+	fn.Annotations = existing.Annotations.ChangeTo(updated.Annotations)
+	return nil
+}
+
+func (a *admission) BeforeTriggerCreate(ctx context.Context, trigger *models.Trigger) error {
+	if len(a.controllers) == 0 {
+		return nil
+	}
+	return a.admit(ctx, &fnext.AdmissionReview{Operation: fnext.AdmissionCreate, Trigger: trigger})
+}
+
+// BeforeTriggerUpdate reviews the trigger as updated by the patch trigger,
+// which is then replaced by the patch giving the admitted trigger
+func (a *admission) BeforeTriggerUpdate(ctx context.Context, trigger *models.Trigger) error {
+	if len(a.controllers) == 0 {
+		return nil
+	}
+	existing, err := a.ds.GetTriggerByID(ctx, trigger.ID)
+	if err != nil {
+		return err
+	}
+	updated := existing.Clone()
+	updated.Update(trigger)
+	if err := a.admit(ctx, &fnext.AdmissionReview{Operation: fnext.AdmissionUpdate, Trigger: updated}); err != nil {
+		return err
+	}
+
+	*trigger = *updated
+	trigger.ID = existing.ID
+	trigger.Annotations = existing.Annotations.ChangeTo(updated.Annotations)
+	return nil
+}
+
+// the other listener methods admit everything
+
+func (a *admission) AfterAppCreate(ctx context.Context, app *models.App) error          { return nil }
+func (a *admission) AfterAppUpdate(ctx context.Context, app *models.App) error          { return nil }
+func (a *admission) BeforeAppDelete(ctx context.Context, app *models.App) error         { return nil }
+func (a *admission) AfterAppDelete(ctx context.Context, app *models.App) error          { return nil }
+func (a *admission) BeforeAppGet(ctx context.Context, appID string) error               { return nil }
+func (a *admission) AfterAppGet(ctx context.Context, app *models.App) error             { return nil }
+func (a *admission) BeforeAppsList(ctx context.Context, filter *models.AppFilter) error { return nil }
+func (a *admission) AfterAppsList(ctx context.Context, apps []*models.App) error        { return nil }
+func (a *admission) AfterFnCreate(ctx context.Context, fn *models.Fn) error             { return nil }
+func (a *admission) AfterFnUpdate(ctx context.Context, fn *models.Fn) error             { return nil }
+func (a *admission) BeforeFnDelete(ctx context.Context, fnID string) error              { return nil }
+func (a *admission) AfterFnDelete(ctx context.Context, fnID string) error               { return nil }
+func (a *admission) AfterTriggerCreate(ctx context.Context, t *models.Trigger) error    { return nil }
+func (a *admission) AfterTriggerUpdate(ctx context.Context, t *models.Trigger) error    { return nil }
+func (a *admission) BeforeTriggerDelete(ctx context.Context, triggerID string) error    { return nil }
+func (a *admission) AfterTriggerDelete(ctx context.Context, triggerID string) error     { return nil }
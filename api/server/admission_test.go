@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/fnext"
+)
+
+func TestAdmissionWebhooks(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	// the webhook caps the memory of fns and labels the fns it admits
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var review fnext.AdmissionReview
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Fn == nil {
+			json.NewEncoder(w).Encode(&admissionWebhookResponse{Allowed: true})
+			return
+		}
+		if review.Fn.Memory > 256 {
+			json.NewEncoder(w).Encode(&admissionWebhookResponse{Reason: "memory is capped to 256MB"})
+			return
+		}
+		review.Fn.Annotations, _ = review.Fn.Annotations.With("admitted", string(review.Operation))
+		json.NewEncoder(w).Encode(&admissionWebhookResponse{Allowed: true, Fn: review.Fn})
+	}))
+	defer webhook.Close()
+
+	a := &models.App{Name: "a", ID: "app_id"}
+	ds := datastore.NewMockInit([]*models.App{a})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI,
+		WithAdmissionWebhooks([]string{webhook.URL}, fnext.AdmissionFailClosed))
+
+	request := func(method, path, body string) (*httptest.ResponseRecorder, *models.Fn) {
+		_, rec := routerRequest(t, srv.Router, method, path, strings.NewReader(body))
+		var fn models.Fn
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&fn); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, &fn
+	}
+
+	rec, _ := request(http.MethodPost, "/v2/fns", `{"app_id": "app_id", "name": "big", "image": "fnproject/hello", "memory": 512}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "memory is capped") {
+		t.Fatalf("Expected the fn to be denied, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec, fn := request(http.MethodPost, "/v2/fns", `{"app_id": "app_id", "name": "f", "image": "fnproject/hello", "memory": 128}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the fn to be admitted, got %d %s", rec.Code, rec.Body.String())
+	}
+	if v, err := fn.Annotations.GetString("admitted"); err != nil || v != "create" {
+		t.Fatalf("Expected the fn to be mutated by the webhook, got %v", fn.Annotations)
+	}
+
+	// updates are reviewed with the patch applied
+	rec, _ = request(http.MethodPut, "/v2/fns/"+fn.ID, `{"memory": 1024}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the update to be denied, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec, fn = request(http.MethodPut, "/v2/fns/"+fn.ID, `{"config": {"A": "1"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the update to be admitted, got %d %s", rec.Code, rec.Body.String())
+	}
+	if v, _ := fn.Annotations.GetString("admitted"); v != "update" || fn.Memory != 128 || fn.Config["A"] != "1" {
+		t.Fatalf("Expected the update to be applied and mutated, got %+v", fn)
+	}
+
+	for _, tc := range []struct {
+		policy fnext.AdmissionFailurePolicy
+		code   int
+	}{
+		{fnext.AdmissionFailClosed, http.StatusServiceUnavailable},
+		{fnext.AdmissionFailOpen, http.StatusOK},
+	} {
+		srv := testServer(datastore.NewMockInit([]*models.App{a}), &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI,
+			WithAdmissionWebhooks([]string{"http://127.0.0.1:1/unreachable"}, tc.policy))
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/fns", strings.NewReader(`{"app_id": "app_id", "name": "f", "image": "fnproject/hello"}`))
+		if rec.Code != tc.code {
+			t.Fatalf("Policy %d: expected %d with a failing webhook, got %d %s", tc.policy, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+)
+
+const admissionWebhookTimeout = 10 * time.Second
+
+// admissionWebhookResponse is the reply of an admission webhook, it may hold
+// the object of the review mutated
+type admissionWebhookResponse struct {
+	Allowed bool            `json:"allowed"`
+	Reason  string          `json:"reason,omitempty"`
+	App     *models.App     `json:"app,omitempty"`
+	Fn      *models.Fn      `json:"fn,omitempty"`
+	Trigger *models.Trigger `json:"trigger,omitempty"`
+}
+
+// admissionWebhook posts every admission review as JSON to an http endpoint,
+// which replies with an admissionWebhookResponse. Unreachable webhooks and non
+// 2xx responses are failures.
+type admissionWebhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewAdmissionWebhook returns an admission controller delegating to the
+// webhook at url.
+func NewAdmissionWebhook(url string) fnext.AdmissionController {
+	return &admissionWebhook{
+		url:    url,
+		client: &http.Client{Timeout: admissionWebhookTimeout},
+	}
+}
+
+func (w *admissionWebhook) Admit(ctx context.Context, review *fnext.AdmissionReview) error {
+	body, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("admission webhook %s returned %d: %s", w.url, resp.StatusCode, msg)
+	}
+	var res admissionWebhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("admission webhook %s returned an invalid response: %v", w.url, err)
+	}
+	if !res.Allowed {
+		return models.ErrAdmissionDenied(res.Reason)
+	}
+
+	// webhooks may not move objects
+	switch {
+	case review.App != nil && res.App != nil:
+		res.App.ID = review.App.ID
+		*review.App = *res.App
+	case review.Fn != nil && res.Fn != nil:
+		res.Fn.ID, res.Fn.AppID = review.Fn.ID, review.Fn.AppID
+		*review.Fn = *res.Fn
+	case review.Trigger != nil && res.Trigger != nil:
+		res.Trigger.ID, res.Trigger.AppID = review.Trigger.ID, review.Trigger.AppID
+		*review.Trigger = *res.Trigger
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/auth"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleAPIKeyCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	key := &models.APIKey{}
+
+	err := c.BindJSON(key)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	if key.ID != "" {
+		handleErrorResponse(c, models.ErrAPIKeyIDProvided)
+		return
+	}
+	if key.ExpiresAt != nil && !time.Time(*key.ExpiresAt).After(time.Now()) {
+		handleErrorResponse(c, models.ErrAPIKeysInvalidExpiry)
+		return
+	}
+
+	ks, err := s.apiKeyStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := s.bindAPIKey(ctx, key); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if err := s.checkAPIKeyTargets(ctx, key); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	key, err = insertAPIKey(ctx, ks, key)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// bindAPIKey binds key to the namespace and apps of the credentials it is made
+// with and rejects scopes they do not hold, so that keys never grant more than
+// their maker holds. Makers hold the scopes of their credentials and of the
+// roles bound to them on neither an app nor a namespace.
+func (s *Server) bindAPIKey(ctx context.Context, key *models.APIKey) error {
+	id := auth.IdentityFromContext(ctx)
+	if id == nil {
+		return nil
+	}
+
+	if nsID := common.NamespaceIDFromContext(ctx); nsID != "" {
+		if key.NamespaceID != "" && key.NamespaceID != nsID {
+			return models.ErrNamespacesNotFound
+		}
+		key.NamespaceID = nsID
+	}
+	if len(id.AppIDs) > 0 {
+		if len(key.AppIDs) == 0 {
+			key.AppIDs = id.AppIDs
+		}
+	keys:
+		for _, appID := range key.AppIDs {
+			for _, allowed := range id.AppIDs {
+				if allowed == appID {
+					continue keys
+				}
+			}
+			return models.ErrForbidden
+		}
+	}
+
+	granted, err := (&roleAuthorizer{s}).globalScopes(ctx, id)
+	if err != nil {
+		return err
+	}
+	held := &auth.Identity{Scopes: append(granted, id.Scopes...)}
+	for _, sc := range key.Scopes {
+		if !held.HasScope(auth.Scope(sc)) {
+			return models.ErrForbidden
+		}
+	}
+	return nil
+}
+
+// checkAPIKeyTargets checks that the apps and namespace of a key exist, the
+// apps in the namespace of the key if it has one
+func (s *Server) checkAPIKeyTargets(ctx context.Context, key *models.APIKey) error {
+	if key.NamespaceID != "" {
+		nss, err := s.namespaceStore()
+		if err != nil {
+			return err
+		}
+		if _, err := nss.GetNamespaceByID(ctx, key.NamespaceID); err != nil {
+			return err
+		}
+	}
+	for _, appID := range key.AppIDs {
+		app, err := s.datastore.GetAppByID(ctx, appID)
+		if err != nil {
+			return err
+		}
+		if key.NamespaceID != "" && app.NamespaceID != key.NamespaceID {
+			return models.ErrAppsNotFound
+		}
+	}
+	return nil
+}
+
+// insertAPIKey inserts key with a new secret, returning it with its token
+func insertAPIKey(ctx context.Context, ks models.APIKeyStore, key *models.APIKey) (*models.APIKey, error) {
+	secret, hash, err := models.NewAPIKeySecret()
+	if err != nil {
+		return nil, err
+	}
+	key.TokenHash = hash
+
+	key, err = ks.InsertAPIKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	key.Token = models.APIKeyToken(key.ID, secret)
+	return key, nil
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleAPIKeyGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ks, err := s.apiKeyStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	key, err := ks.GetAPIKeyByID(ctx, c.Param(api.ParamAPIKeyID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
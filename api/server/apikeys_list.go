@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleAPIKeyList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.APIKeyFilter{}
+
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	filter.Name = c.Query("name")
+	filter.NamespaceID = c.Query("namespace_id")
+
+	ks, err := s.apiKeyStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	keys, err := ks.GetAPIKeys(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
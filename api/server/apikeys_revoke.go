@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/gin-gonic/gin"
+)
+
+// handleAPIKeyRevoke revokes a key at once, it stays listed as revoked
+func (s *Server) handleAPIKeyRevoke(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ks, err := s.apiKeyStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	_, err = ks.RevokeAPIKey(ctx, c.Param(api.ParamAPIKeyID), common.DateTime(time.Now()))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
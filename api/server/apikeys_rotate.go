@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleAPIKeyRotate replaces a key with a new one of the same name and
+// permissions, revoking the old key once the grace period of the request has
+// passed, at once by default.
+func (s *Server) handleAPIKeyRotate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var grace time.Duration
+	if g := c.Query("grace"); g != "" {
+		var err error
+		grace, err = time.ParseDuration(g)
+		if err != nil || grace < 0 {
+			handleErrorResponse(c, models.ErrAPIKeysInvalidGrace)
+			return
+		}
+	}
+
+	ks, err := s.apiKeyStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	old, err := ks.GetAPIKeyByID(ctx, c.Param(api.ParamAPIKeyID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	now := time.Now()
+	if !old.Active(now) {
+		handleErrorResponse(c, models.ErrAPIKeysRevoked)
+		return
+	}
+
+	key, err := insertAPIKey(ctx, ks, &models.APIKey{
+		Name:        old.Name,
+		Scopes:      old.Scopes,
+		AppIDs:      old.AppIDs,
+		NamespaceID: old.NamespaceID,
+		ExpiresAt:   old.ExpiresAt,
+	})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	_, err = ks.RevokeAPIKey(ctx, old.ID, common.DateTime(now.Add(grace)))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
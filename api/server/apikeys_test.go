@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/auth"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestAPIKeys(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	keys, err := auth.NewStaticKeys([]auth.StaticKey{{Key: "admin-key", Subject: "admin", Scopes: []string{"admin"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mine := &models.App{ID: "app_mine", Name: "mine"}
+	other := &models.App{ID: "app_other", Name: "other"}
+	ds := datastore.NewMockInit([]*models.App{mine, other})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAuth(keys))
+
+	request := func(token, method, path, body string) (int, string) {
+		req := createRequest(t, method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, rec := routerRequest2(t, srv.Router, req)
+		return rec.Code, rec.Body.String()
+	}
+	create := func(path, body string) *models.APIKey {
+		code, res := request("admin-key", http.MethodPost, path, body)
+		if code != http.StatusOK {
+			t.Fatalf("Expected the key to be created, got %d %s", code, res)
+		}
+		var key models.APIKey
+		if err := json.Unmarshal([]byte(res), &key); err != nil {
+			t.Fatal(err)
+		}
+		if key.Token == "" {
+			t.Fatalf("Expected the token of the key to be returned, got %s", res)
+		}
+		return &key
+	}
+
+	for i, body := range []string{
+		`{"name": "ci", "scopes": ["admin"]}`,
+		`{"name": "ci"}`,
+		`{"name": "ci", "scopes": ["write"], "expires_at": "2001-01-01T00:00:00.000Z"}`,
+	} {
+		if code, res := request("admin-key", http.MethodPost, "/v2/keys", body); code != http.StatusBadRequest {
+			t.Errorf("Test %d: expected the key to be rejected, got %d %s", i, code, res)
+		}
+	}
+	if code, res := request("admin-key", http.MethodPost, "/v2/keys", `{"name": "ci", "scopes": ["write"], "app_ids": ["missing"]}`); code != http.StatusNotFound {
+		t.Fatalf("Expected a key for a missing app to be rejected, got %d %s", code, res)
+	}
+
+	ci := create("/v2/keys", `{"name": "ci", "scopes": ["write", "read"], "app_ids": ["`+mine.ID+`"]}`)
+
+	for i, test := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodGet, "/v2/apps/" + mine.ID, ``, http.StatusOK},
+		{http.MethodGet, "/v2/apps?name=" + mine.Name, ``, http.StatusOK},
+		{http.MethodGet, "/v2/apps/" + other.ID, ``, http.StatusForbidden},
+		{http.MethodGet, "/v2/apps", ``, http.StatusForbidden},
+		{http.MethodPost, "/v2/fns", `{"app_id": "` + mine.ID + `", "name": "f", "image": "fnproject/hello"}`, http.StatusOK},
+		{http.MethodPost, "/v2/fns", `{"app_id": "` + other.ID + `", "name": "f", "image": "fnproject/hello"}`, http.StatusForbidden},
+		{http.MethodPost, "/v2/keys", `{"name": "escalate", "scopes": ["write"]}`, http.StatusForbidden},
+	} {
+		if code, res := request(ci.Token, test.method, test.path, test.body); code != test.code {
+			t.Errorf("Test %d: expected status %d for %s %s, got %d %s", i, test.code, test.method, test.path, code, res)
+		}
+	}
+
+	// rotating with a grace period keeps the old token working for a while
+	rotated := create("/v2/keys/"+ci.ID+"/rotate?grace=1h", ``)
+	if rotated.ID == ci.ID || rotated.Name != ci.Name || len(rotated.AppIDs) != 1 {
+		t.Fatalf("Expected a new key with the permissions of the old one, got %+v", rotated)
+	}
+	for _, token := range []string{ci.Token, rotated.Token} {
+		if code, res := request(token, http.MethodGet, "/v2/apps/"+mine.ID, ``); code != http.StatusOK {
+			t.Fatalf("Expected both tokens to work during the grace period, got %d %s", code, res)
+		}
+	}
+
+	// revoking takes over the grace period
+	if code, res := request("admin-key", http.MethodDelete, "/v2/keys/"+ci.ID, ``); code != http.StatusNoContent {
+		t.Fatalf("Expected the key to be revoked, got %d %s", code, res)
+	}
+	if code, _ := request(ci.Token, http.MethodGet, "/v2/apps/"+mine.ID, ``); code != http.StatusUnauthorized {
+		t.Fatalf("Expected a revoked key to be rejected, got %d", code)
+	}
+	if code, res := request("admin-key", http.MethodPost, "/v2/keys/"+ci.ID+"/rotate", ``); code != http.StatusConflict {
+		t.Fatalf("Expected a revoked key not to be rotated, got %d %s", code, res)
+	}
+
+	code, res := request("admin-key", http.MethodGet, "/v2/keys?name=ci", ``)
+	var list models.APIKeyList
+	if err := json.Unmarshal([]byte(res), &list); err != nil || code != http.StatusOK {
+		t.Fatalf("Expected the keys named ci, got %d %s", code, res)
+	}
+	if len(list.Items) != 2 || list.Items[0].RevokedAt == nil || list.Items[1].RevokedAt != nil {
+		t.Fatalf("Expected the revoked key and its replacement, got %s", res)
+	}
+	if strings.Contains(res, "token") {
+		t.Fatalf("Expected listed keys to carry no token, got %s", res)
+	}
+}
+
+func TestAPIKeysAreBoundToTheirMaker(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	keys, err := auth.NewStaticKeys([]auth.StaticKey{{Key: "admin-key", Subject: "admin", Scopes: []string{"admin"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := datastore.NewMockInit()
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAuth(keys))
+
+	request := func(token, method, path, body string) (int, string) {
+		req := createRequest(t, method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, rec := routerRequest2(t, srv.Router, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	code, res := request("admin-key", http.MethodPost, "/v2/namespaces", `{"name": "ns"}`)
+	var ns models.Namespace
+	if err := json.Unmarshal([]byte(res), &ns); err != nil || code != http.StatusOK {
+		t.Fatalf("Expected the namespace to be created, got %d %s", code, res)
+	}
+	// the admin of the namespace holds credentials bound to it
+	keys, err = auth.NewStaticKeys([]auth.StaticKey{{Key: "ns-key", Subject: "ns-admin", Scopes: []string{"admin"}, NamespaceID: ns.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv = testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAuth(keys))
+
+	// keys made with credentials bound to a namespace are bound to it too
+	code, res = request("ns-key", http.MethodPost, "/v2/keys", `{"name": "ci", "scopes": ["write"]}`)
+	var key models.APIKey
+	if err := json.Unmarshal([]byte(res), &key); err != nil || code != http.StatusOK {
+		t.Fatalf("Expected the key to be created, got %d %s", code, res)
+	}
+	if key.NamespaceID != ns.ID {
+		t.Fatalf("Expected the key to be bound to the namespace of its maker, got %+v", key)
+	}
+	if code, res := request("ns-key", http.MethodPost, "/v2/keys", `{"name": "ci", "scopes": ["write"], "namespace_id": "elsewhere"}`); code != http.StatusNotFound {
+		t.Fatalf("Expected a key for another namespace to be rejected, got %d %s", code, res)
+	}
+
+	// keys never hold scopes or apps their maker does not
+	for i, test := range []struct {
+		maker *auth.Identity
+		key   models.APIKey
+		err   error
+	}{
+		{&auth.Identity{Subject: "dev", Scopes: []auth.Scope{auth.ScopeWrite}}, models.APIKey{Scopes: []string{"write"}}, nil},
+		{&auth.Identity{Subject: "dev", Scopes: []auth.Scope{auth.ScopeWrite}}, models.APIKey{Scopes: []string{"write", "invoke"}}, models.ErrForbidden},
+		{&auth.Identity{Subject: "dev", Scopes: []auth.Scope{auth.ScopeAdmin}, AppIDs: []string{"a"}}, models.APIKey{Scopes: []string{"read"}, AppIDs: []string{"b"}}, models.ErrForbidden},
+	} {
+		ctx := auth.WithIdentity(context.Background(), test.maker)
+		if err := srv.bindAPIKey(ctx, &test.key); err != test.err {
+			t.Errorf("Test %d: expected %v, got %v", i, test.err, err)
+		}
+	}
+	key = models.APIKey{Scopes: []string{"read"}}
+	if err := srv.bindAPIKey(auth.WithIdentity(context.Background(), &auth.Identity{Scopes: []auth.Scope{auth.ScopeAdmin}, AppIDs: []string{"a"}}), &key); err != nil || len(key.AppIDs) != 1 || key.AppIDs[0] != "a" {
+		t.Fatalf("Expected the key to be bound to the apps of its maker, got %v %+v", err, key)
+	}
+}
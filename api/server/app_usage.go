@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleAppUsage returns the usage of an app aggregated over windows of
+// time, window is their length in seconds, an hour by default. Usage is
+// aggregated over the app unless by_fn=true or a fn_id is given.
+func (s *Server) handleAppUsage(c *gin.Context) {
+	ctx := c.Request.Context()
+	var err error
+
+	filter := models.UsageFilter{
+		AppID: c.Param(api.ParamAppID),
+		FnID:  c.Query("fn_id"),
+		ByFn:  c.Query("by_fn") == "true",
+	}
+	filter.FromTime, filter.ToTime, err = timeParams(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if w := c.Query("window"); w != "" {
+		secs, err := strconv.ParseInt(w, 10, 64)
+		if err != nil || secs <= 0 {
+			handleErrorResponse(c, models.ErrUsageInvalidWindow)
+			return
+		}
+		filter.Window = time.Duration(secs) * time.Second
+	}
+	if err = filter.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if _, err = s.datastore.GetAppByID(ctx, filter.AppID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	us, ok := s.logstore.(models.UsageStore)
+	if !ok {
+		handleErrorResponse(c, models.ErrUsageUnsupported)
+		return
+	}
+
+	usage, err := us.GetUsage(ctx, &filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
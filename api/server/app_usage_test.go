@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestAppUsage(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	ls := logs.NewMock()
+	srv := testServer(datastore.NewMockInit([]*models.App{app}), &mqs.Mock{}, ls, nil, ServerTypeAPI)
+
+	hour := time.Now().Truncate(time.Hour).Add(-time.Hour)
+	for i, r := range []*models.UsageRecord{
+		{FnID: "f1", DurationMs: 100, MemoryMBMs: 12800, CPUMs: 100, CreatedAt: common.DateTime(hour)},
+		{FnID: "f2", DurationMs: 200, MemoryMBMs: 25600, CPUMs: 200, CreatedAt: common.DateTime(hour.Add(time.Minute))},
+		{FnID: "f1", DurationMs: 100, MemoryMBMs: 12800, CPUMs: 100, CreatedAt: common.DateTime(hour.Add(time.Hour))},
+	} {
+		r.ID, r.AppID = strconv.Itoa(i), app.ID
+		if err := ls.(models.UsageStore).InsertUsageRecord(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	from := strconv.FormatInt(hour.Unix(), 10)
+
+	for _, test := range []struct {
+		query       string
+		invocations []uint64
+		fns         []string
+	}{
+		{"?from_time=" + from, []uint64{2, 1}, []string{"", ""}},
+		{"?from_time=" + from + "&by_fn=true", []uint64{1, 1, 1}, []string{"f1", "f2", "f1"}},
+		{"?from_time=" + from + "&fn_id=f1&window=86400", []uint64{2}, []string{""}},
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/apps/"+app.ID+"/usage"+test.query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected success, got %d %s", test.query, rec.Code, rec.Body.String())
+		}
+		var usage models.UsageList
+		if err := json.NewDecoder(rec.Body).Decode(&usage); err != nil {
+			t.Fatal(err)
+		}
+		if len(usage.Items) != len(test.invocations) {
+			t.Fatalf("%s: expected %d windows, got %+v", test.query, len(test.invocations), usage.Items)
+		}
+		for i, u := range usage.Items {
+			if u.Invocations != test.invocations[i] || u.FnID != test.fns[i] || u.AppID != app.ID {
+				t.Fatalf("%s: unexpected usage %+v", test.query, u)
+			}
+		}
+	}
+
+	for path, code := range map[string]int{
+		"/v2/apps/" + app.ID + "/usage?window=1":              http.StatusBadRequest,
+		"/v2/apps/" + app.ID + "/usage?window=60&from_time=1": http.StatusBadRequest,
+		"/v2/apps/nope/usage":                                 http.StatusNotFound,
+	} {
+		if _, rec := routerRequest(t, srv.Router, http.MethodGet, path, nil); rec.Code != code {
+			t.Errorf("%s: expected %d, got %d", path, code, rec.Code)
+		}
+	}
+}
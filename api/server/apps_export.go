@@ -0,0 +1,371 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// maxBundleSize caps the size of the bundles applied to apps
+const maxBundleSize = 4 * 1024 * 1024
+
+const contentTypeYAML = "application/yaml"
+
+// handleAppExport writes an app with its fns and triggers as a YAML bundle,
+// see models.AppBundle
+func (s *Server) handleAppExport(c *gin.Context) {
+	bundle, err := s.exportApp(c.Request.Context(), c.Param(api.ParamAppID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	writeBundle(c, bundle)
+}
+
+// handleAppImport applies a YAML bundle to an app: the app is given the config
+// of the bundle, and its fns and triggers are created or updated to match
+// those of the bundle by name. Fns and triggers missing from the bundle are
+// only removed with prune=true. Applying the same bundle again changes nothing.
+func (s *Server) handleAppImport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleSize))
+	if err != nil {
+		handleErrorResponse(c, models.ErrBundleInvalid)
+		return
+	}
+	var bundle models.AppBundle
+	if err := yaml.Unmarshal(body, &bundle); err != nil {
+		handleErrorResponse(c, models.ErrBundleInvalid)
+		return
+	}
+	if err := bundle.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	appID := c.Param(api.ParamAppID)
+	if err := s.applyBundle(ctx, appID, &bundle, c.Query("prune") == "true"); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	applied, err := s.exportApp(ctx, appID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	writeBundle(c, applied)
+}
+
+func writeBundle(c *gin.Context, bundle *models.AppBundle) {
+	out, err := yaml.Marshal(bundle)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentTypeYAML, out)
+}
+
+func (s *Server) exportApp(ctx context.Context, appID string) (*models.AppBundle, error) {
+	app, err := s.datastore.GetAppByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &models.AppBundle{
+		Name:        app.Name,
+		Config:      app.Config,
+		Annotations: models.BundleAnnotations(app.Annotations),
+	}
+	if app.SyslogURL != nil {
+		bundle.SyslogURL = *app.SyslogURL
+	}
+
+	if ss, err := s.secretStore(); err == nil {
+		secrets, err := ss.GetSecrets(ctx, appID)
+		if err != nil && err != models.ErrSecretsUnsupported {
+			return nil, err
+		}
+		for _, secret := range secrets {
+			bundle.Secrets = append(bundle.Secrets, models.BundleSecret{Name: secret.Name, Mount: secret.Mount})
+		}
+	}
+
+	fns, err := s.appFns(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	triggers, err := s.appTriggers(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range fns {
+		bfn := models.BundleFn{
+			Name:        fn.Name,
+			Image:       fn.Image,
+			Memory:      fn.Memory,
+			Timeout:     fn.Timeout,
+			IdleTimeout: fn.IdleTimeout,
+			InitTimeout: fn.InitTimeout,
+			Config:      fn.Config,
+			Annotations: models.BundleAnnotations(fn.Annotations),
+		}
+		for _, t := range triggers {
+			if t.FnID == fn.ID {
+				bfn.Triggers = append(bfn.Triggers, models.BundleTrigger{
+					Name:        t.Name,
+					Type:        t.Type,
+					Source:      t.Source,
+					Annotations: models.BundleAnnotations(t.Annotations),
+				})
+			}
+		}
+		bundle.Fns = append(bundle.Fns, bfn)
+	}
+	sort.Slice(bundle.Fns, func(i, j int) bool { return bundle.Fns[i].Name < bundle.Fns[j].Name })
+	return bundle, nil
+}
+
+func (s *Server) applyBundle(ctx context.Context, appID string, bundle *models.AppBundle, prune bool) error {
+	app, err := s.datastore.GetAppByID(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	// secret values are never exported, the app must have them already
+	if len(bundle.Secrets) > 0 {
+		ss, err := s.secretStore()
+		if err != nil {
+			return err
+		}
+		secrets, err := ss.GetSecrets(ctx, appID)
+		if err != nil {
+			return err
+		}
+		have := make(map[string]bool, len(secrets))
+		for _, secret := range secrets {
+			have[secret.Name] = true
+		}
+		var missing []string
+		for _, secret := range bundle.Secrets {
+			if !have[secret.Name] {
+				missing = append(missing, secret.Name)
+			}
+		}
+		if len(missing) > 0 {
+			return models.ErrBundleMissingSecrets(missing)
+		}
+	}
+
+	annotations, err := models.AnnotationsFromBundle(bundle.Annotations)
+	if err != nil {
+		return err
+	}
+	patch := &models.App{
+		ID:          app.ID,
+		Config:      replaceConfig(app.Config, bundle.Config),
+		Annotations: app.Annotations.ChangeTo(annotations),
+		SyslogURL:   &bundle.SyslogURL,
+	}
+	if _, err := s.datastore.UpdateApp(ctx, patch); err != nil {
+		return err
+	}
+
+	fns, err := s.appFns(ctx, appID)
+	if err != nil {
+		return err
+	}
+	triggers, err := s.appTriggers(ctx, appID)
+	if err != nil {
+		return err
+	}
+	existingFns := make(map[string]*models.Fn, len(fns))
+	for _, fn := range fns {
+		existingFns[fn.Name] = fn
+	}
+
+	applied := make(map[string]bool, len(bundle.Fns))
+	for _, bfn := range bundle.Fns {
+		fn, err := s.applyBundleFn(ctx, appID, existingFns[bfn.Name], &bfn)
+		if err != nil {
+			return err
+		}
+		applied[fn.ID] = true
+
+		var fnTriggers []*models.Trigger
+		for _, t := range triggers {
+			if t.FnID == fn.ID {
+				fnTriggers = append(fnTriggers, t)
+			}
+		}
+		if err := s.applyBundleTriggers(ctx, fn, fnTriggers, bfn.Triggers, prune); err != nil {
+			return err
+		}
+	}
+
+	if prune {
+		for _, fn := range fns {
+			if !applied[fn.ID] {
+				if err := s.datastore.RemoveFn(ctx, fn.ID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyBundleFn creates the fn of bfn, or updates existing to match it
+func (s *Server) applyBundleFn(ctx context.Context, appID string, existing *models.Fn, bfn *models.BundleFn) (*models.Fn, error) {
+	annotations, err := models.AnnotationsFromBundle(bfn.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	fn := &models.Fn{
+		Name:           bfn.Name,
+		AppID:          appID,
+		Image:          bfn.Image,
+		ResourceConfig: models.ResourceConfig{Memory: bfn.Memory, Timeout: bfn.Timeout, IdleTimeout: bfn.IdleTimeout, InitTimeout: bfn.InitTimeout},
+		Config:         bfn.Config,
+		Annotations:    annotations,
+	}
+
+	if existing == nil {
+		fn.SetDefaults()
+		created, err := s.datastore.InsertFn(ctx, fn)
+		if err != nil {
+			return nil, err
+		}
+		s.recordFnRevision(ctx, created)
+		return created, nil
+	}
+
+	fn.ID = existing.ID
+	fn.Config = replaceConfig(existing.Config, bfn.Config)
+	fn.Annotations = existing.Annotations.ChangeTo(annotations)
+	updated := existing.Clone()
+	updated.Update(fn)
+	if updated.Equals(existing) {
+		return existing, nil
+	}
+	updated, err = s.datastore.UpdateFn(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	s.recordFnRevision(ctx, updated)
+	return updated, nil
+}
+
+// applyBundleTriggers makes the triggers of fn match those of a bundle,
+// triggers changing type are replaced
+func (s *Server) applyBundleTriggers(ctx context.Context, fn *models.Fn, existing []*models.Trigger, bts []models.BundleTrigger, prune bool) error {
+	byName := make(map[string]*models.Trigger, len(existing))
+	for _, t := range existing {
+		byName[t.Name] = t
+	}
+
+	applied := make(map[string]bool, len(bts))
+	for _, bt := range bts {
+		applied[bt.Name] = true
+		annotations, err := models.AnnotationsFromBundle(bt.Annotations)
+		if err != nil {
+			return err
+		}
+		t := &models.Trigger{
+			Name:        bt.Name,
+			AppID:       fn.AppID,
+			FnID:        fn.ID,
+			Type:        bt.Type,
+			Source:      bt.Source,
+			Annotations: annotations,
+		}
+
+		current, ok := byName[bt.Name]
+		if ok && current.Type != bt.Type {
+			if err := s.datastore.RemoveTrigger(ctx, current.ID); err != nil {
+				return err
+			}
+			ok = false
+		}
+		if !ok {
+			if _, err := s.datastore.InsertTrigger(ctx, t); err != nil {
+				return err
+			}
+			continue
+		}
+
+		t.ID = current.ID
+		t.Annotations = current.Annotations.ChangeTo(annotations)
+		updated := current.Clone()
+		updated.Update(t)
+		if updated.Equals(current) {
+			continue
+		}
+		if _, err := s.datastore.UpdateTrigger(ctx, t); err != nil {
+			return err
+		}
+	}
+
+	if prune {
+		for _, t := range existing {
+			if !applied[t.Name] {
+				if err := s.datastore.RemoveTrigger(ctx, t.ID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) appFns(ctx context.Context, appID string) ([]*models.Fn, error) {
+	var res []*models.Fn
+	filter := &models.FnFilter{AppID: appID, PerPage: 100}
+	for {
+		fns, err := s.datastore.GetFns(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, fns.Items...)
+		if fns.NextCursor == "" {
+			return res, nil
+		}
+		filter.Cursor = fns.NextCursor
+	}
+}
+
+func (s *Server) appTriggers(ctx context.Context, appID string) ([]*models.Trigger, error) {
+	var res []*models.Trigger
+	filter := &models.TriggerFilter{AppID: appID, PerPage: 100}
+	for {
+		triggers, err := s.datastore.GetTriggers(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, triggers.Items...)
+		if triggers.NextCursor == "" {
+			return res, nil
+		}
+		filter.Cursor = triggers.NextCursor
+	}
+}
+
+// replaceConfig returns the patch replacing config by with, updates merge
+// config so keys missing from with are cleared
+func replaceConfig(config, with models.Config) models.Config {
+	patch := make(models.Config, len(config)+len(with))
+	for k := range config {
+		patch[k] = ""
+	}
+	for k, v := range with {
+		patch[k] = v
+	}
+	return patch
+}
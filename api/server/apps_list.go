@@ -15,6 +15,7 @@ func (s *Server) handleAppList(c *gin.Context) {
 	filter.Cursor, filter.PerPage = pageParams(c)
 
 	filter.Name = c.Query("name")
+	filter.NamespaceID = c.Query("namespace_id")
 
 	apps, err := s.datastore.GetApps(ctx, filter)
 	if err != nil {
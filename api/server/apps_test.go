@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -361,3 +362,78 @@ func TestAppUpdate(t *testing.T) {
 
 	}
 }
+
+func TestAppExport(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	src := &models.App{ID: "src_id", Name: "src", Config: models.Config{"ENV": "staging"}}
+	dst := &models.App{ID: "dst_id", Name: "dst", Config: models.Config{"ENV": "prod", "OLD": "x"}}
+	f := &models.Fn{ID: "fn_id", Name: "f", AppID: src.ID, Image: "fnproject/hello:0.0.1", Config: models.Config{"A": "1"}}
+	f.SetDefaults()
+	f.Annotations, _ = models.EmptyAnnotations().With("team", map[string]interface{}{"name": "core"})
+	stale := &models.Fn{ID: "stale_id", Name: "stale", AppID: dst.ID, Image: "fnproject/hello:0.0.1"}
+	stale.SetDefaults()
+	trig := &models.Trigger{ID: "trigger_id", Name: "t", AppID: src.ID, FnID: f.ID, Type: "http", Source: "/t"}
+	secret := &models.StoredSecret{AppID: src.ID, Name: "TOKEN", Mount: models.SecretMountEnv, Ciphertext: []byte("sealed")}
+	ds := datastore.NewMockInit([]*models.App{src, dst}, []*models.Fn{f, stale}, []*models.Trigger{trig}, []*models.StoredSecret{secret})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/apps/src_id/export", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the app to be exported, got %d %s", rec.Code, rec.Body.String())
+	}
+	bundle := rec.Body.String()
+	for _, want := range []string{"name: src", "image: fnproject/hello:0.0.1", "source: /t", "name: TOKEN", "name: core"} {
+		if !strings.Contains(bundle, want) {
+			t.Fatalf("Expected the bundle to contain %q, got\n%s", want, bundle)
+		}
+	}
+
+	// secret values are never exported, they must be set on the target first
+	_, rec = routerRequest(t, srv.Router, http.MethodPut, "/v2/apps/dst_id/export", strings.NewReader(bundle))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "TOKEN") {
+		t.Fatalf("Expected missing secrets to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+	dstSecret := *secret
+	dstSecret.AppID = dst.ID
+	if _, err := ds.(models.SecretStore).PutSecret(context.Background(), &dstSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	var applied string
+	for i := 0; i < 2; i++ {
+		_, rec = routerRequest(t, srv.Router, http.MethodPut, "/v2/apps/dst_id/export?prune=true", strings.NewReader(bundle))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Apply %d: expected the bundle to be applied, got %d %s", i, rec.Code, rec.Body.String())
+		}
+		if i > 0 && rec.Body.String() != applied {
+			t.Fatalf("Expected applying the bundle again to change nothing, got\n%s\nthen\n%s", applied, rec.Body.String())
+		}
+		applied = rec.Body.String()
+	}
+	// the bundle does not carry the name of the app
+	if strings.Replace(applied, "name: dst", "name: src", 1) != bundle {
+		t.Fatalf("Expected the bundle to be applied as exported, got\n%s\nwant\n%s", applied, bundle)
+	}
+
+	app, err := ds.GetAppByID(context.Background(), dst.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Name != "dst" || app.Config["ENV"] != "staging" || app.Config["OLD"] != "" {
+		t.Fatalf("Expected the app config to be replaced, got %+v", app)
+	}
+	if _, err := ds.GetFnByID(context.Background(), stale.ID); err != models.ErrFnsNotFound {
+		t.Fatalf("Expected fns missing from the bundle to be pruned, got %v", err)
+	}
+
+	_, rec = routerRequest(t, srv.Router, http.MethodPut, "/v2/apps/dst_id/export", strings.NewReader("fns: [{name: f}, {name: f}]"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected duplicate fns to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// assets are held in memory by each runner, unless they are fetched from a
+// manifest url, see agent.EnvAssetsManifestURL, the assets put here are lost
+// on restart and each runner must be given the same assets
+
+// handleAssetList returns the assets of the runner and their state
+func (s *Server) handleAssetList(c *gin.Context) {
+	assets, err := s.agent.(agent.AssetManager).Assets()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": assets})
+}
+
+// handleAssetPut adds or replaces the asset in the path, which is downloaded
+// in the background
+func (s *Server) handleAssetPut(c *gin.Context) {
+	var asset models.Asset
+	if err := c.BindJSON(&asset); err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+	if asset.Name == "" {
+		asset.Name = c.Param("name")
+	} else if asset.Name != c.Param("name") {
+		handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, errors.New("Asset name in body does not match path")))
+		return
+	}
+	if err := s.agent.(agent.AssetManager).PutAsset(&asset); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, asset)
+}
+
+func (s *Server) handleAssetDelete(c *gin.Context) {
+	if err := s.agent.(agent.AssetManager).DeleteAsset(c.Param("name")); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.String(http.StatusNoContent, "")
+}
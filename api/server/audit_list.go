@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) auditStore() (models.AuditStore, error) {
+	as, ok := s.logstore.(models.AuditStore)
+	if !ok {
+		return nil, models.ErrAuditUnsupported
+	}
+	return as, nil
+}
+
+// handleAuditList lists the audit records of the log store, requests scoped to
+// a namespace may only list the records of an app of their namespace.
+func (s *Server) handleAuditList(c *gin.Context) {
+	ctx := c.Request.Context()
+	var err error
+
+	filter := models.AuditFilter{
+		Kind:     c.Query("kind"),
+		ObjectID: c.Query("object_id"),
+		AppID:    c.Query("app_id"),
+		Subject:  c.Query("subject"),
+	}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	filter.FromTime, filter.ToTime, err = timeParams(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if err = filter.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if common.NamespaceIDFromContext(ctx) != "" {
+		if filter.AppID == "" {
+			handleErrorResponse(c, models.ErrAppsMissingID)
+			return
+		}
+		if _, err = s.datastore.GetAppByID(ctx, filter.AppID); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	as, err := s.auditStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	records, err := as.GetAuditRecords(ctx, &filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestAuditLog(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAuditSink("logstore"))
+
+	request := func(method, path, body string, v interface{}) {
+		_, rec := routerRequest(t, srv.Router, method, path, strings.NewReader(body))
+		if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+			t.Fatalf("%s %s: expected success, got %d %s", method, path, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var app models.App
+	request(http.MethodPost, "/v2/apps", `{"name": "a"}`, &app)
+	request(http.MethodPut, "/v2/apps/"+app.ID, `{"config": {"A": "1"}}`, nil)
+	var fn models.Fn
+	request(http.MethodPost, "/v2/fns", `{"app_id": "`+app.ID+`", "name": "f", "image": "fnproject/hello"}`, &fn)
+	request(http.MethodDelete, "/v2/fns/"+fn.ID, ``, nil)
+
+	var records models.AuditRecordList
+	request(http.MethodGet, "/v2/audit?app_id="+app.ID, ``, &records)
+	if len(records.Items) != 4 {
+		t.Fatalf("Expected 4 audit records, got %d", len(records.Items))
+	}
+	for i, expected := range []struct{ kind, op string }{
+		{models.ChangeKindFn, models.ChangeOpDelete},
+		{models.ChangeKindFn, models.ChangeOpCreate},
+		{models.ChangeKindApp, models.ChangeOpUpdate},
+		{models.ChangeKindApp, models.ChangeOpCreate},
+	} {
+		if r := records.Items[i]; r.Kind != expected.kind || r.Op != expected.op {
+			t.Fatalf("Record %d: expected %s %s, got %s %s", i, expected.op, expected.kind, r.Op, r.Kind)
+		}
+	}
+
+	update := records.Items[2]
+	if len(update.Before) == 0 || len(update.After) == 0 {
+		t.Fatalf("Expected the update to record the app before and after, got %+v", update)
+	}
+	changed := strings.Join(update.Changed, ",")
+	if !strings.Contains(changed, "config") || strings.Contains(changed, "name") {
+		t.Fatalf("Expected the update to change the config of the app only, got %v", update.Changed)
+	}
+	if deleted := records.Items[0]; deleted.ObjectID != fn.ID || deleted.AppID != app.ID || len(deleted.After) != 0 {
+		t.Fatalf("Expected the delete to record the fn before only, got %+v", deleted)
+	}
+
+	request(http.MethodGet, "/v2/audit?kind=fn&per_page=1", ``, &records)
+	if len(records.Items) != 1 || records.Items[0].Op != models.ChangeOpDelete || records.NextCursor == "" {
+		t.Fatalf("Expected the latest fn record and a cursor, got %+v", records)
+	}
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/audit?kind=route", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected an unknown kind to be rejected, got %d", rec.Code)
+	}
+}
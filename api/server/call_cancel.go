@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleCallCancel cancels a call. A call running on this node is stopped,
+// lb nodes stop placing it and their runners stop running it. Otherwise, with
+// the fn_id of the call, the call is recorded as cancelled so that it is
+// dropped once it is dequeued, if it is still queued.
+func (s *Server) handleCallCancel(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	callID := c.Param(api.ParamCallID)
+	if canceller, ok := s.agent.(agent.CallCanceller); ok {
+		if call := canceller.CancelCall(callID); call != nil {
+			c.JSON(http.StatusAccepted, call)
+			return
+		}
+	}
+
+	fnID := c.Query("fn_id")
+	if fnID == "" || s.datastore == nil || s.logstore == nil {
+		handleErrorResponse(c, models.ErrCallNotFound)
+		return
+	}
+	fn, err := s.datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	call, err := s.logstore.GetCall(ctx, fn.ID, callID)
+	if err == nil {
+		if call.Status != "cancelled" {
+			handleErrorResponse(c, models.ErrCallCompleted)
+			return
+		}
+		c.JSON(http.StatusAccepted, call)
+		return
+	}
+	if err != models.ErrCallNotFound {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	// calls are only stored once they ran, the call is either queued or unknown
+	now := common.DateTime(time.Now())
+	call = &models.Call{
+		ID:          callID,
+		Status:      "cancelled",
+		Error:       models.ErrCallCancelled.Error(),
+		AppID:       fn.AppID,
+		FnID:        fn.ID,
+		CreatedAt:   now,
+		CompletedAt: now,
+	}
+	if err := s.logstore.InsertCall(ctx, call); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, call)
+}
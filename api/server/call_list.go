@@ -37,10 +37,18 @@ func (s *Server) handleCallList(c *gin.Context) {
 		return
 	}
 
+	filter.Status = c.Query("status")
+	filter.ErrorClass = c.Query("error_class")
+	if err = filter.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
 	calls, err := s.logstore.GetCalls(ctx, &filter)
 
 	if err != nil {
 		handleErrorResponse(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, calls)
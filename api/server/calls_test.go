@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -113,6 +114,8 @@ func TestCallList(t *testing.T) {
 	c3 := *call
 	c2.CreatedAt = common.DateTime(time.Now().Add(100 * time.Second))
 	c2.ID = id.New().String()
+	c2.Status = "error"
+	c2.ErrorClass = models.ErrorClassFunction
 	c3.CreatedAt = common.DateTime(time.Now().Add(200 * time.Second))
 	c3.ID = id.New().String()
 
@@ -153,6 +156,11 @@ func TestCallList(t *testing.T) {
 		{"/v2/fns/fn_id/calls?" + rangeTest, "", http.StatusOK, nil, 1, ""},
 		{"/v2/fns/fn_id/calls?from_time=xyz", "", http.StatusBadRequest, models.ErrInvalidFromTime, 0, ""},
 		{"/v2/fns/fn_id/calls?to_time=xyz", "", http.StatusBadRequest, models.ErrInvalidToTime, 0, ""},
+		{"/v2/fns/fn_id/calls?status=error", "", http.StatusOK, nil, 1, ""},
+		{"/v2/fns/fn_id/calls?status=error&error_class=function", "", http.StatusOK, nil, 1, ""},
+		{"/v2/fns/fn_id/calls?error_class=timeout", "", http.StatusOK, nil, 0, ""},
+		{"/v2/fns/fn_id/calls?status=xyz", "", http.StatusBadRequest, models.ErrCallInvalidStatus, 0, ""},
+		{"/v2/fns/fn_id/calls?error_class=xyz", "", http.StatusBadRequest, models.ErrCallInvalidErrorClass, 0, ""},
 
 		// // TODO path isn't url safe w/ '/', so this is weird. hack in for tests
 		// {"/v2/fns/fn_id/calls?path=test2", "", http.StatusOK, nil, 1, ""},
@@ -188,3 +196,48 @@ func TestCallList(t *testing.T) {
 		}
 	}
 }
+
+func TestCallCancel(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	fn := &models.Fn{Name: "myfn", ID: "fn_id", AppID: "app_id"}
+	done := &models.Call{FnID: fn.ID, ID: id.New().String(), Status: "success"}
+	queued := id.New().String()
+
+	ds := datastore.NewMockInit([]*models.Fn{fn})
+	ls := logs.NewMock([]*models.Call{done})
+	srv := testServer(ds, &mqs.Mock{}, ls, nil, ServerTypeAPI)
+
+	for i, test := range []struct {
+		path          string
+		expectedCode  int
+		expectedError error
+	}{
+		{"/v2/calls/" + queued, http.StatusNotFound, models.ErrCallNotFound},
+		{"/v2/calls/" + queued + "?fn_id=missing_fn", http.StatusNotFound, models.ErrFnsNotFound},
+		{"/v2/calls/" + done.ID + "?fn_id=fn_id", http.StatusConflict, models.ErrCallCompleted},
+		{"/v2/calls/" + queued + "?fn_id=fn_id", http.StatusAccepted, nil},
+		{"/v2/calls/" + queued + "?fn_id=fn_id", http.StatusAccepted, nil},
+	} {
+		_, rec := routerRequest(t, srv.Router, "DELETE", test.path, nil)
+		if rec.Code != test.expectedCode {
+			t.Log(rec.Body.String())
+			t.Fatalf("Test %d: Expected status code to be %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if test.expectedError != nil {
+			if resp := getErrorResponse(t, rec); !strings.Contains(resp.Message, test.expectedError.Error()) {
+				t.Fatalf("Test %d: Expected error message to have `%s`, got %s", i, test.expectedError.Error(), resp.Message)
+			}
+		}
+	}
+
+	cancelled, err := models.IsCancelledCall(context.Background(), ls, &models.Call{FnID: fn.ID, ID: queued})
+	if err != nil || !cancelled {
+		t.Fatalf("expected the queued call to be recorded as cancelled, got %v", err)
+	}
+}
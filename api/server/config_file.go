@@ -0,0 +1,227 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type configKind int
+
+const (
+	kindString configKind = iota
+	kindInt
+	kindBool
+	kindList // a list of strings or a string, joined by the sep of its key
+)
+
+// configKey is a setting of a config file, the value of the env var it sets
+type configKey struct {
+	env  string
+	kind configKind
+	sep  string
+}
+
+func strKey(env string) configKey              { return configKey{env: env} }
+func intKey(env string) configKey              { return configKey{env: env, kind: kindInt} }
+func boolKey(env string) configKey             { return configKey{env: env, kind: kindBool} }
+func listKey(env string, sep string) configKey { return configKey{env: env, kind: kindList, sep: sep} }
+
+// configSection is a section of a config file, the name of a setting is its
+// env var in lower case without the FN_ prefix and the prefix of the section
+type configSection struct {
+	prefix string
+	keys   []configKey
+}
+
+// configSchema lists the settings of a config file by section
+var configSchema = map[string]configSection{
+	"server": {keys: []configKey{
+		strKey(EnvNodeType), intKey(EnvPort), intKey(EnvGRPCPort), intKey(EnvGRPCInvokePort),
+		strKey(EnvLogFormat), strKey(EnvLogLevel), strKey(EnvLogDest), strKey(EnvLogPrefix), strKey(EnvLogComponentLevels),
+		strKey(EnvDBURL), strKey(EnvMQURL), strKey(EnvLogDBURL), strKey(EnvRunnerURL), strKey(EnvPublicLoadBalancerURL),
+		intKey(EnvIdempotencyWindow), strKey(EnvResponseCacheURL), strKey(EnvCloudEventsSinkURL),
+		intKey(EnvSchedulerInterval), intKey(EnvEventSourcesInterval), listKey(EnvEventSourceKafkaBrokers, ","),
+		listKey(EnvAPICORSOrigins, ","), listKey(EnvAPICORSHeaders, ","),
+		strKey(EnvZipkinURL), strKey(EnvJaegerURL), strKey(EnvOTLPURL), strKey(EnvMetricsListen), listKey(EnvMetricsExporters, ","), listKey(EnvMetricsTags, ","), listKey(EnvProcessCollectorList, " "),
+		strKey(EnvReloadFile), intKey(EnvShutdownTimeout), strKey(EnvAccessLog), strKey(EnvAccessLogFormat), strKey(EnvRIDHeader),
+		intKey(EnvMaxRequestSize), intKey(EnvReadCacheTTL), intKey(EnvAsyncLease), strKey(EnvSecretsKMSURL),
+		listKey(EnvAdmissionWebhooks, ","), boolKey(EnvAdmissionFailOpen), strKey(EnvAuditSink), strKey(EnvUsageSink),
+		strKey(EnvRunnerRegisterURL), strKey(EnvRunnerAdvertiseAddress), intKey(EnvRunnerHeartbeat), strKey(EnvRunnerZone), listKey(EnvRunnerLabels, ","),
+		strKey(EnvAuthKeysFile), strKey(EnvAuthJWTSecret), strKey(EnvAuthOIDCIssuer), strKey(EnvAuthAudience), listKey(EnvAuthClientCertScopes, ","),
+	}},
+	"agent": {prefix: "AGENT_", keys: []configKey{
+		strKey(agent.EnvInstanceID), intKey(agent.EnvFreezeIdle), intKey(agent.EnvPausedTTL),
+		strKey(agent.EnvEvictionPolicy), intKey(agent.EnvEvictMemPressure), intKey(agent.EnvEvictMemPSI), intKey(agent.EnvMaxInflightCalls),
+		intKey(agent.EnvMemOvercommit), intKey(agent.EnvCPUOvercommit), strKey(agent.EnvPinnedCPUs), strKey(agent.EnvVolumesDir),
+		strKey(agent.EnvAssetsDir), strKey(agent.EnvAssetsManifestURL), intKey(agent.EnvAssetsPoll), intKey(agent.EnvFnMetricsWindow),
+		listKey(agent.EnvPriorityClasses, ","), strKey(agent.EnvDefaultPriorityClass), listKey(agent.EnvChaos, ","), boolKey(agent.EnvChaosEnable),
+		intKey(agent.EnvHotPoll), intKey(agent.EnvHotLauncherTimeout), intKey(agent.EnvHotPullTimeout), intKey(agent.EnvHotStartTimeout),
+		intKey(agent.EnvAsyncChewPoll), intKey(agent.EnvDetachedHeadroom),
+		intKey(agent.EnvMaxResponseSize), intKey(agent.EnvMaxLogSize), intKey(agent.EnvMaxCallLogSize),
+		intKey(agent.EnvMaxTotalCPU), intKey(agent.EnvMaxTotalMemory), intKey(agent.EnvMaxFsSize), intKey(agent.EnvMaxTmpFsInodes),
+		boolKey(agent.EnvEnableNBResourceTracker), boolKey(agent.EnvDisableReadOnlyRootFs), boolKey(agent.EnvDisableDebugUserLogs),
+		boolKey(agent.EnvIOFSEnableTmpfs), strKey(agent.EnvIOFSPath), strKey(agent.EnvIOFSDockerPath), strKey(agent.EnvIOFSOpts),
+		intKey(agent.EnvCheckpointIdle), strKey(agent.EnvCheckpointDir),
+		intKey(agent.EnvPreForkPoolSize), intKey(agent.EnvPreForkPoolMaxSize), strKey(agent.EnvPreForkImage), strKey(agent.EnvPreForkCmd),
+		intKey(agent.EnvPreForkUseOnce), listKey(agent.EnvPreForkNetworks, " "),
+		intKey(agent.EnvPreForkLowWater), intKey(agent.EnvPreForkHighWater), intKey(agent.EnvPreForkScaleInterval),
+	}},
+	"docker": {prefix: "DOCKER_", keys: []configKey{
+		// FN_DOCKER_AUTH is read by the docker driver, see docker.registryFromEnv
+		strKey("FN_DOCKER_AUTH"), listKey(agent.EnvDockerNetworks, " "), strKey(agent.EnvDockerLoadFile), intKey(agent.EnvDockerReapInterval),
+		listKey(agent.EnvBlkioDevices, " "), listKey(agent.EnvDockerDNS, " "), listKey(agent.EnvDockerDNSSearch, " "), listKey(agent.EnvDockerExtraHosts, " "),
+		strKey(agent.EnvHTTPProxy), strKey(agent.EnvHTTPSProxy), strKey(agent.EnvNoProxy),
+		strKey(agent.EnvDockerUser), boolKey(agent.EnvDockerRequireNonRoot),
+		listKey(agent.EnvDockerCapDrop, " "), listKey(agent.EnvDockerCapAdd, " "), listKey(agent.EnvDockerCapAllowed, " "), boolKey(agent.EnvDockerAllowNewPrivs),
+	}},
+	"lb": {prefix: "LB_", keys: []configKey{
+		listKey(EnvRunnerAddresses, ","), strKey(EnvRunnerDiscovery), strKey(EnvRunnerDiscoveryTarget), intKey(EnvRunnerDiscoveryInterval), intKey(EnvRunnerRegistrationTTL),
+		strKey(EnvLBPlacementAlg), intKey(EnvLBBinPackTarget), strKey(EnvLBZone), intKey(EnvLBZoneSpillWait),
+		intKey(EnvLBCircuitBreakerThreshold), intKey(EnvLBCircuitBreakerOpenTimeout),
+		strKey(EnvLBScaleHook), strKey(EnvLBScaleWebhook), intKey(EnvLBScaleMinRunners), intKey(EnvLBScaleMaxRunners),
+		intKey(EnvLBActivatorQueueSize), intKey(EnvLBActivatorTimeout),
+		intKey(EnvLBPlacementAuditSize), intKey(EnvLBMaxFailovers),
+		intKey(EnvLBRunnerDialTimeout), intKey(EnvLBRunnerKeepalive), intKey(EnvLBRunnerKeepaliveTimeout),
+		intKey(EnvLBRunnerWindowSize), intKey(EnvLBRunnerConnWindowSize),
+		intKey(EnvLBRunnerHeartbeat), intKey(EnvLBRunnerHeartbeatMisses),
+	}},
+}
+
+// name returns the name of the setting of k in a section with prefix
+func (k configKey) name(prefix string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(k.env, "FN_"), prefix))
+}
+
+// value returns the env value of v, which must be of the kind of k
+func (k configKey) value(v interface{}) (string, error) {
+	switch k.kind {
+	case kindInt:
+		if i, ok := v.(int); ok {
+			return fmt.Sprint(i), nil
+		}
+		return "", errors.New("must be an integer")
+	case kindBool:
+		if b, ok := v.(bool); ok {
+			return fmt.Sprint(b), nil
+		}
+		return "", errors.New("must be true or false")
+	case kindList:
+		if items, ok := v.([]interface{}); ok {
+			values := make([]string, len(items))
+			for i, item := range items {
+				if !isScalar(item) {
+					return "", errors.New("must be a list of strings")
+				}
+				values[i] = fmt.Sprint(item)
+			}
+			return strings.Join(values, k.sep), nil
+		}
+	}
+	if !isScalar(v) {
+		return "", errors.New("must be a string")
+	}
+	return fmt.Sprint(v), nil
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, int, float64, bool:
+		return true
+	}
+	return false
+}
+
+// parseConfigFile returns the env values of the settings of the YAML config
+// file at path. Sections and settings that are not in configSchema and values
+// of the wrong type are errors.
+func parseConfigFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file map[string]map[string]interface{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	sections := make([]string, 0, len(file))
+	for section := range file {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	env := make(map[string]string)
+	var errs []string
+	for _, section := range sections {
+		schema, ok := configSchema[section]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown section %q", section))
+			continue
+		}
+		keys := make(map[string]configKey, len(schema.keys))
+		for _, k := range schema.keys {
+			keys[k.name(schema.prefix)] = k
+		}
+		for name, v := range file[section] {
+			k, ok := keys[name]
+			if !ok {
+				errs = append(errs, fmt.Sprintf("unknown setting %s.%s", section, name))
+				continue
+			}
+			value, err := k.value(v)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s.%s %v", section, name, err))
+				continue
+			}
+			env[k.env] = value
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("%s: %s", path, strings.Join(errs, ", "))
+	}
+	return env, nil
+}
+
+// LoadConfigFile sets the env of the settings of the YAML config file at
+// path, settings already set in env take precedence over the file. It must
+// be called before NewFromEnv, which reads the settings from env.
+func LoadConfigFile(path string) error {
+	env, err := parseConfigFile(path)
+	if err != nil {
+		return err
+	}
+	for key, value := range env {
+		if _, ok := os.LookupEnv(key); ok {
+			logrus.WithField("setting", key).Debug("Config file setting overridden by env")
+			continue
+		}
+		if _, ok := os.LookupEnv(key + "_FILE"); ok {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateConfigFile checks the config file at path, and the configuration of
+// the agent resulting from it and env, without starting a server
+func ValidateConfigFile(path string) error {
+	if err := LoadConfigFile(path); err != nil {
+		return err
+	}
+	if _, err := agent.NewConfig(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
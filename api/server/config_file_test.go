@@ -0,0 +1,110 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigSchemaNames(t *testing.T) {
+	envs := make(map[string]string)
+	for section, schema := range configSchema {
+		names := make(map[string]bool)
+		for _, k := range schema.keys {
+			name := k.name(schema.prefix)
+			if names[name] {
+				t.Fatalf("Duplicate setting %s.%s", section, name)
+			}
+			names[name] = true
+			if other, ok := envs[k.env]; ok {
+				t.Fatalf("%s is set by sections %s and %s", k.env, other, section)
+			}
+			envs[k.env] = section
+		}
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fn.yaml")
+
+	writeTestFile(t, path, `
+server:
+  node_type: lb
+  port: 8081
+lb:
+  runner_addresses: [a:9190, b:9190]
+  placer: ch
+docker:
+  networks: [fn, monitoring]
+agent:
+  disable_readonly_rootfs: true
+`)
+	env, err := parseConfigFile(path)
+	if err != nil {
+		t.Fatalf("Failed to parse config file %v", err)
+	}
+	expected := map[string]string{
+		EnvNodeType:                  "lb",
+		EnvPort:                      "8081",
+		EnvRunnerAddresses:           "a:9190,b:9190",
+		EnvLBPlacementAlg:            "ch",
+		"FN_DOCKER_NETWORKS":         "fn monitoring",
+		"FN_DISABLE_READONLY_ROOTFS": "true",
+	}
+	if len(env) != len(expected) {
+		t.Fatalf("Unexpected settings %v", env)
+	}
+	for k, v := range expected {
+		if env[k] != v {
+			t.Fatalf("Expected %s=%q, got %q", k, v, env[k])
+		}
+	}
+
+	// env takes precedence over the file
+	for k := range expected {
+		defer envTweaker(k, "")()
+		os.Unsetenv(k)
+	}
+	os.Setenv(EnvPort, "9999")
+	if err := LoadConfigFile(path); err != nil {
+		t.Fatalf("Failed to load config file %v", err)
+	}
+	if os.Getenv(EnvPort) != "9999" || os.Getenv(EnvNodeType) != "lb" {
+		t.Fatalf("Unexpected env %s=%s %s=%s", EnvPort, os.Getenv(EnvPort), EnvNodeType, os.Getenv(EnvNodeType))
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fn.yaml")
+
+	writeTestFile(t, path, `
+server:
+  port: eighty
+  colour: blue
+runner:
+  port: 9190
+agent:
+  disable_readonly_rootfs: 1
+`)
+	_, err = parseConfigFile(path)
+	if err == nil {
+		t.Fatal("Expected an invalid config file")
+	}
+	for _, e := range []string{"server.port must be an integer", "unknown setting server.colour", `unknown section "runner"`, "agent.disable_readonly_rootfs must be true or false"} {
+		if !strings.Contains(err.Error(), e) {
+			t.Fatalf("Expected %q in %v", e, err)
+		}
+	}
+}
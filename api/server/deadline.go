@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// withClientDeadline bounds the context of req by the deadline the client set
+// in models.DeadlineHeader, if any. Calls of gRPC clients already carry theirs.
+// The agents derive the contexts of placement, of the runners and of the
+// container from it, so all of them give up once the deadline passes.
+func withClientDeadline(req *http.Request) (*http.Request, context.CancelFunc, error) {
+	v := req.Header.Get(models.DeadlineHeader)
+	if v == "" {
+		return req, func() {}, nil
+	}
+	deadline, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, nil, models.ErrCallInvalidDeadline
+	}
+	if !deadline.After(time.Now()) {
+		return nil, nil, models.ErrCallDeadlineExceeded
+	}
+	ctx, cancel := context.WithDeadline(req.Context(), deadline)
+	return req.WithContext(ctx), cancel, nil
+}
+
+// deadlineError returns the error of a call that failed because the deadline
+// of its client passed, the agents report these as time outs of the fn or busy
+// servers.
+func deadlineError(req *http.Request, err error) error {
+	if err != nil && req.Context().Err() == context.DeadlineExceeded {
+		return models.ErrCallDeadlineExceeded
+	}
+	return err
+}
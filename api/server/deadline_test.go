@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestClientDeadline(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/invoke/fn", nil)
+	if r, _, err := withClientDeadline(req); err != nil || r != req {
+		t.Fatalf("expected a request without a deadline to be left alone, got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Minute).Round(time.Millisecond)
+	req.Header.Set(models.DeadlineHeader, deadline.Format(time.RFC3339Nano))
+	r, cancel, err := withClientDeadline(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := r.Context().Deadline(); !ok || !d.Equal(deadline) {
+		t.Fatalf("expected the context to have the deadline of the client, got %v", d)
+	}
+	if err := deadlineError(r, models.ErrCallTimeoutServerBusy); err != models.ErrCallTimeoutServerBusy {
+		t.Fatalf("expected errors before the deadline to be kept, got %v", err)
+	}
+	cancel()
+
+	req.Header.Set(models.DeadlineHeader, "tomorrow")
+	if _, _, err := withClientDeadline(req); err != models.ErrCallInvalidDeadline {
+		t.Fatalf("expected an invalid deadline error, got %v", err)
+	}
+	req.Header.Set(models.DeadlineHeader, time.Now().Add(-time.Second).Format(time.RFC3339))
+	if _, _, err := withClientDeadline(req); err != models.ErrCallDeadlineExceeded {
+		t.Fatalf("expected a passed deadline to be rejected, got %v", err)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	<-ctx.Done()
+	if err := deadlineError(req.WithContext(ctx), models.ErrCallTimeoutServerBusy); err != models.ErrCallDeadlineExceeded {
+		t.Fatalf("expected errors after the deadline to be reported as such, got %v", err)
+	}
+}
@@ -6,12 +6,20 @@ package defaultexts
 import (
 	// import all datastore/log/mq modules for runtime config
 	_ "github.com/fnproject/fn/api/agent/drivers/docker"
+	_ "github.com/fnproject/fn/api/datastore/dynamodb"
 	_ "github.com/fnproject/fn/api/datastore/sql"
+	_ "github.com/fnproject/fn/api/datastore/sql/cockroach"
 	_ "github.com/fnproject/fn/api/datastore/sql/mysql"
 	_ "github.com/fnproject/fn/api/datastore/sql/postgres"
 	_ "github.com/fnproject/fn/api/datastore/sql/sqlite"
+	_ "github.com/fnproject/fn/api/logs/bolt"
+	_ "github.com/fnproject/fn/api/logs/elasticsearch"
 	_ "github.com/fnproject/fn/api/logs/s3"
 	_ "github.com/fnproject/fn/api/mqs/bolt"
+	_ "github.com/fnproject/fn/api/mqs/kafka"
 	_ "github.com/fnproject/fn/api/mqs/memory"
+	_ "github.com/fnproject/fn/api/mqs/nats"
+	_ "github.com/fnproject/fn/api/mqs/ocistreaming"
 	_ "github.com/fnproject/fn/api/mqs/redis"
+	_ "github.com/fnproject/fn/api/mqs/sqs"
 )
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRedrive is the number of dead letters a redrive request without ids moves back to the queue
+const maxRedrive = 100
+
+type redriveRequest struct {
+	// IDs of the dead letters to redrive, all of them (up to maxRedrive) if empty
+	IDs []string `json:"ids"`
+}
+
+func (s *Server) deadLetterStore() (models.DeadLetterStore, error) {
+	dl, ok := s.logstore.(models.DeadLetterStore)
+	if !ok {
+		return nil, models.ErrDeadLettersUnsupported
+	}
+	return dl, nil
+}
+
+func (s *Server) handleDeadLetterList(c *gin.Context) {
+	ctx := c.Request.Context()
+	var err error
+
+	fnID := c.Param(api.ParamFnID)
+
+	_, err = s.datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	dl, err := s.deadLetterStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	filter := models.CallFilter{FnID: fnID}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	filter.FromTime, filter.ToTime, err = timeParams(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	calls, err := dl.GetDeadLetters(ctx, &filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calls)
+}
+
+// handleDeadLetterRedrive queues dead letters of a fn again as new async calls
+// with a fresh set of attempts and removes them from the dead letter queue.
+func (s *Server) handleDeadLetterRedrive(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fnID := c.Param(api.ParamFnID)
+
+	_, err := s.datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	dl, err := s.deadLetterStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	var req redriveRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&req); err != nil {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+			return
+		}
+	}
+
+	wanted := make(map[string]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		wanted[id] = true
+	}
+
+	var letters []*models.Call
+	filter := models.CallFilter{FnID: fnID, PerPage: maxRedrive}
+	for {
+		page, err := dl.GetDeadLetters(ctx, &filter)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		for _, call := range page.Items {
+			if len(wanted) == 0 || wanted[call.ID] {
+				letters = append(letters, call)
+			}
+		}
+		if len(wanted) == 0 || len(letters) == len(wanted) || page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	if len(wanted) > len(letters) {
+		handleErrorResponse(c, models.ErrDeadLetterNotFound)
+		return
+	}
+
+	redriven := &models.CallList{Items: make([]*models.Call, 0, len(letters))}
+	for _, letter := range letters {
+		call := *letter
+		call.ID = id.New().String()
+		call.Attempt = 0
+		call.Delay = 0
+		call.Status = "queued"
+		call.Error = ""
+		call.Stats = nil
+		call.CreatedAt = common.DateTime(time.Now())
+		call.StartedAt = common.DateTime(time.Time{})
+		call.CompletedAt = common.DateTime(time.Time{})
+
+		if err := s.lbEnqueue.Enqueue(ctx, &call); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		if err := dl.RemoveDeadLetter(ctx, fnID, letter.ID); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		redriven.Items = append(redriven.Items, &call)
+	}
+
+	c.JSON(http.StatusOK, redriven)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+// pushMQ records the calls pushed to it
+type pushMQ struct {
+	mqs.Mock
+	pushed []*models.Call
+}
+
+func (mq *pushMQ) Push(ctx context.Context, call *models.Call) (*models.Call, error) {
+	mq.pushed = append(mq.pushed, call)
+	return call, nil
+}
+
+func TestDeadLetters(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	fn := &models.Fn{ID: "fn_id", Name: "myfn"}
+	ds := datastore.NewMockInit([]*models.Fn{fn})
+	ls := logs.NewMock()
+	dl := ls.(models.DeadLetterStore)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		letter := &models.Call{ID: id.New().String(), FnID: fn.ID, Type: models.TypeAsync, Attempt: 3,
+			Status: "error", Error: "boom", CreatedAt: common.DateTime(time.Now())}
+		if err := dl.InsertDeadLetter(ctx, letter); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, letter.ID)
+	}
+	mq := &pushMQ{}
+	srv := testServer(ds, mq, ls, nil, ServerTypeAPI)
+
+	list := func() []string {
+		_, rec := routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/dlq", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected the dead letters to be listed, got %d %s", rec.Code, rec.Body.String())
+		}
+		var letters models.CallList
+		if err := json.NewDecoder(rec.Body).Decode(&letters); err != nil {
+			t.Fatal(err)
+		}
+		var res []string
+		for _, c := range letters.Items {
+			res = append(res, c.ID)
+		}
+		return res
+	}
+	if letters := list(); len(letters) != 3 || letters[0] != ids[2] {
+		t.Fatalf("Expected the dead letters newest first, got %v", letters)
+	}
+
+	for i, test := range []struct {
+		method, path, body string
+		expectedCode       int
+		expectedError      error
+	}{
+		{"GET", "/v2/fns/missing_fn/dlq", "", http.StatusNotFound, models.ErrFnsNotFound},
+		{"POST", "/v2/fns/missing_fn/dlq", "", http.StatusNotFound, models.ErrFnsNotFound},
+		{"POST", "/v2/fns/fn_id/dlq", "{", http.StatusBadRequest, models.ErrInvalidJSON},
+		// nothing is redriven if any of the ids is not a dead letter
+		{"POST", "/v2/fns/fn_id/dlq", `{"ids":["` + ids[0] + `","` + id.New().String() + `"]}`, http.StatusNotFound, models.ErrDeadLetterNotFound},
+	} {
+		_, rec := routerRequest(t, srv.Router, test.method, test.path, strings.NewReader(test.body))
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if resp := getErrorResponse(t, rec); !strings.Contains(resp.Message, test.expectedError.Error()) {
+			t.Fatalf("Test %d: Expected error message to have `%s`, got %s", i, test.expectedError, resp.Message)
+		}
+	}
+	if len(mq.pushed) != 0 || len(list()) != 3 {
+		t.Fatalf("Expected nothing to be redriven, got %d calls queued", len(mq.pushed))
+	}
+
+	// redrive some
+	_, rec := routerRequest(t, srv.Router, "POST", "/v2/fns/fn_id/dlq", strings.NewReader(`{"ids":["`+ids[0]+`"]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the dead letter to be redriven, got %d %s", rec.Code, rec.Body.String())
+	}
+	if len(mq.pushed) != 1 {
+		t.Fatalf("Expected the dead letter to be queued, got %d calls queued", len(mq.pushed))
+	}
+	if call := mq.pushed[0]; call.ID == ids[0] || call.Attempt != 0 || call.Status != "queued" || call.Error != "" || call.FnID != fn.ID {
+		t.Fatalf("Expected a new call with fresh attempts, got %+v", call)
+	}
+	if letters := list(); len(letters) != 2 || letters[1] != ids[1] {
+		t.Fatalf("Expected the redriven dead letter to be removed, got %v", letters)
+	}
+
+	// redrive all
+	_, rec = routerRequest(t, srv.Router, "POST", "/v2/fns/fn_id/dlq", nil)
+	var redriven models.CallList
+	if err := json.NewDecoder(rec.Body).Decode(&redriven); err != nil || rec.Code != http.StatusOK || len(redriven.Items) != 2 {
+		t.Fatalf("Expected all dead letters to be redriven, got %d %+v %v", rec.Code, redriven, err)
+	}
+	if letters := list(); len(letters) != 0 || len(mq.pushed) != 3 {
+		t.Fatalf("Expected the dead letter queue to be empty, got %v", letters)
+	}
+}
+
+func TestDeadLettersUnsupported(t *testing.T) {
+	fn := &models.Fn{ID: "fn_id", Name: "myfn"}
+	ds := datastore.NewMockInit([]*models.Fn{fn})
+	// a log store without dead letter queues
+	ls := struct{ models.LogStore }{logs.NewMock()}
+	srv := testServer(ds, &mqs.Mock{}, ls, nil, ServerTypeAPI)
+
+	for _, method := range []string{"GET", "POST"} {
+		_, rec := routerRequest(t, srv.Router, method, "/v2/fns/fn_id/dlq", nil)
+		if rec.Code != http.StatusNotImplemented {
+			t.Fatalf("Expected dead letters to be unsupported, got %d", rec.Code)
+		}
+	}
+}
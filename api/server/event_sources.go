@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/eventsource"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// eventSourceRestartDelay is how long a source which failed waits before it
+// is started again
+const eventSourceRestartDelay = 10 * time.Second
+
+// eventSources runs an event source per trigger with a provider, see
+// eventsource.Provider, and queues async calls for their events. Like the
+// scheduler, only one server of a cluster should run it.
+type eventSources struct {
+	ds      models.Datastore
+	enqueue agent.EnqueueDataAccess
+	cfg     *eventsource.Config
+	running map[string]*runningSource // by trigger id
+}
+
+type runningSource struct {
+	trigger *models.Trigger
+	cancel  context.CancelFunc
+}
+
+func newEventSources(ds models.Datastore, enqueue agent.EnqueueDataAccess, cfg *eventsource.Config) *eventSources {
+	return &eventSources{
+		ds:      ds,
+		enqueue: enqueue,
+		cfg:     cfg,
+		running: make(map[string]*runningSource),
+	}
+}
+
+// run syncs the running sources with the triggers every interval until ctx is done
+func (m *eventSources) run(ctx context.Context, interval time.Duration) {
+	logrus.WithFields(logrus.Fields{"interval": interval, "types": eventsource.Types()}).Info("Starting event sources")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.sync(ctx); err != nil && ctx.Err() == nil {
+			logrus.WithError(err).Error("Failed to sync event sources")
+		}
+		select {
+		case <-ctx.Done():
+			for id, r := range m.running {
+				r.cancel()
+				delete(m.running, id)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync starts the sources of new triggers and stops those of deleted ones,
+// changed triggers are restarted.
+func (m *eventSources) sync(ctx context.Context) error {
+	seen := make(map[string]bool, len(m.running))
+
+	err := forEachApp(ctx, m.ds, func(app *models.App) error {
+		filter := &models.TriggerFilter{AppID: app.ID, PerPage: 100}
+		for {
+			triggers, err := m.ds.GetTriggers(ctx, filter)
+			if err != nil {
+				return err
+			}
+			for _, t := range triggers.Items {
+				p, ok := eventsource.Get(t.Type)
+				if !ok {
+					continue
+				}
+				seen[t.ID] = true
+				if r, ok := m.running[t.ID]; ok {
+					if r.trigger.Equals(t) {
+						continue
+					}
+					r.cancel()
+				}
+				m.start(ctx, p, t)
+			}
+			if triggers.NextCursor == "" {
+				return nil
+			}
+			filter.Cursor = triggers.NextCursor
+		}
+	})
+	if err != nil {
+		// keep the running sources, they may only have been missed
+		return err
+	}
+
+	for id, r := range m.running {
+		if !seen[id] {
+			r.cancel()
+			delete(m.running, id)
+		}
+	}
+	return nil
+}
+
+func (m *eventSources) start(ctx context.Context, p eventsource.Provider, t *models.Trigger) {
+	ctx, cancel := context.WithCancel(ctx)
+	m.running[t.ID] = &runningSource{trigger: t, cancel: cancel}
+
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"trigger_id": t.ID, "fn_id": t.FnID, "type": t.Type, "source": t.Source})
+	deliver := func(ctx context.Context, e *eventsource.Event) error {
+		return m.deliver(ctx, t, e)
+	}
+	go func() {
+		log.Info("Starting event source")
+		for {
+			err := p.Run(ctx, m.cfg, t, deliver)
+			if ctx.Err() != nil {
+				log.Info("Stopped event source")
+				return
+			}
+			log.WithError(err).Error("Event source failed, restarting")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(eventSourceRestartDelay):
+			}
+		}
+	}()
+}
+
+// deliver queues an async call of the fn of t with the event as its request
+func (m *eventSources) deliver(ctx context.Context, t *models.Trigger, e *eventsource.Event) error {
+	app, err := m.ds.GetAppByID(ctx, t.AppID)
+	if err != nil {
+		return err
+	}
+	fn, err := m.ds.GetFnByID(ctx, t.FnID)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/invoke/"+fn.ID, bytes.NewReader(e.Body))
+	if err != nil {
+		return err
+	}
+	for k, vs := range e.Header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Fn-Invoke-Type", models.TypeAsync)
+	req.Header.Set("Fn-Intent", t.Type)
+
+	call, err := agent.NewAsyncCallModel(app, fn, req, 0)
+	if err != nil {
+		return err
+	}
+	call.TriggerID = t.ID
+	common.Logger(ctx).WithField("call_id", call.ID).Debug("Enqueueing event source call")
+	return m.enqueue.Enqueue(ctx, call)
+}
+
+// forEachApp calls f with every app, stopping at the first error
+func forEachApp(ctx context.Context, ds models.Datastore, f func(*models.App) error) error {
+	filter := &models.AppFilter{PerPage: 100}
+	for {
+		apps, err := ds.GetApps(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, app := range apps.Items {
+			if err := f(app); err != nil {
+				return err
+			}
+		}
+		if apps.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = apps.NextCursor
+	}
+}
@@ -3,6 +3,7 @@ package server
 // TODO: it would be nice to move these into the top level folder so people can use these with the "functions" package, eg: functions.ApiHandler
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/fnproject/fn/api"
@@ -60,3 +61,27 @@ func (s *Server) AddAppEndpoint(method, path string, handler fnext.APIAppHandler
 func (s *Server) AddAppEndpointFunc(method, path string, handler func(w http.ResponseWriter, r *http.Request, app *models.App)) {
 	s.AddAppEndpoint(method, path, fnext.APIAppHandlerFunc(handler))
 }
+
+// WorkflowStateStore implements fnext.ExtServer
+func (s *Server) WorkflowStateStore() models.WorkflowStateStore {
+	if ws, ok := s.datastore.(models.WorkflowStateStore); ok {
+		return ws
+	}
+	return unsupportedWorkflowStates{}
+}
+
+// unsupportedWorkflowStates is the WorkflowStateStore of datastores which do
+// not store workflow states
+type unsupportedWorkflowStates struct{}
+
+func (unsupportedWorkflowStates) GetWorkflowState(ctx context.Context, chainID string) (*models.WorkflowState, error) {
+	return nil, models.ErrWorkflowStatesUnsupported
+}
+
+func (unsupportedWorkflowStates) PutWorkflowState(ctx context.Context, state *models.WorkflowState) (*models.WorkflowState, error) {
+	return nil, models.ErrWorkflowStatesUnsupported
+}
+
+func (unsupportedWorkflowStates) RemoveWorkflowState(ctx context.Context, chainID string) error {
+	return models.ErrWorkflowStatesUnsupported
+}
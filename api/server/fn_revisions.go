@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type fnRevisionList struct {
+	Items []*models.FnRevision `json:"items"`
+}
+
+func (s *Server) fnRevisionStore() (models.FnRevisionStore, error) {
+	rs, ok := s.datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs, nil
+}
+
+// recordFnRevision takes a revision of fn, unless its latest revision already
+// deploys the same image and config. The fn is stored by then, failures are
+// logged only.
+func (s *Server) recordFnRevision(ctx context.Context, fn *models.Fn) {
+	rs, err := s.fnRevisionStore()
+	if err != nil {
+		return
+	}
+	revs, err := rs.GetFnRevisions(ctx, fn.ID)
+	if err == nil && len(revs) > 0 && revs[0].Deploys(fn) {
+		return
+	}
+	if err == nil {
+		_, err = rs.InsertFnRevision(ctx, models.NewFnRevision(fn))
+	}
+	if err != nil && err != models.ErrFnRevisionsUnsupported {
+		common.Logger(ctx).WithError(err).WithField("fn_id", fn.ID).Error("Failed to record fn revision")
+	}
+}
+
+// fnRevision returns fn running the revision pinned by the FnRevisionHeader
+// of req, or else drawn from the traffic split of fn, fn itself if neither
+// selects a revision. Revisions are read like fns, through the API server on
+// LB nodes, and are not selected where they are not kept.
+func (s *Server) fnRevision(req *http.Request, fn *models.Fn) (*models.Fn, error) {
+	ctx := req.Context()
+	revID := req.Header.Get(models.FnRevisionHeader)
+	if revID == "" {
+		traffic, err := models.TrafficFromAnnotations(fn.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		revID = traffic.Pick(rand.Intn(100))
+	}
+	if revID == "" {
+		return fn, nil
+	}
+
+	var rev *models.FnRevision
+	var err error = models.ErrFnRevisionsUnsupported
+	if ra, ok := s.lbReadAccess.(agent.FnRevisionReadAccess); ok {
+		rev, err = ra.GetFnRevision(ctx, fn.ID, revID)
+	}
+	if err == models.ErrFnRevisionsUnsupported {
+		common.Logger(ctx).WithFields(logrus.Fields{"fn_id": fn.ID, "revision_id": revID}).Debug("Fn revisions are not kept, invoking the fn as deployed")
+		return fn, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rev.Apply(fn), nil
+}
+
+func (s *Server) handleFnRevisionList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rs, err := s.fnRevisionStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	revs, err := rs.GetFnRevisions(ctx, c.Param(api.ParamFnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &fnRevisionList{Items: revs})
+}
+
+func (s *Server) handleFnRevisionGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rs, err := s.fnRevisionStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	rev, err := rs.GetFnRevision(ctx, c.Param(api.ParamFnID), c.Param(api.ParamRevisionID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// handleFnRevisionRollback deploys the image and config of a past revision of
+// a fn again, as a new revision
+func (s *Server) handleFnRevisionRollback(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rs, err := s.fnRevisionStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	fn, err := s.datastore.GetFnByID(ctx, c.Param(api.ParamFnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	rev, err := rs.GetFnRevision(ctx, fn.ID, c.Param(api.ParamRevisionID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	patch := &models.Fn{ID: fn.ID, Image: rev.Image, ResourceConfig: rev.ResourceConfig, Config: replaceConfig(fn.Config, rev.Config)}
+
+	fnUpdated, err := s.datastore.UpdateFn(ctx, patch)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	s.recordFnRevision(ctx, fnUpdated)
+
+	c.JSON(http.StatusOK, redactFn(fnUpdated))
+}
@@ -28,20 +28,21 @@ func (s *Server) handleFnCreate(c *gin.Context) {
 		handleErrorResponse(c, err)
 		return
 	}
+	s.recordFnRevision(ctx, fnCreated)
 
 	app, err := s.datastore.GetAppByID(ctx, fnCreated.AppID)
 	if err != nil {
 		log.Debugln("Failed to lookup app.")
-		c.JSON(http.StatusOK, fnCreated)
+		c.JSON(http.StatusOK, redactFn(fnCreated))
 		return
 	}
 
 	fnAnnotated, err := s.fnAnnotator.AnnotateFn(c, app, fnCreated)
 	if err != nil {
 		log.Debugln("Failed to annotate fn")
-		c.JSON(http.StatusOK, fnCreated)
+		c.JSON(http.StatusOK, redactFn(fnCreated))
 		return
 	}
 
-	c.JSON(http.StatusOK, fnAnnotated)
+	c.JSON(http.StatusOK, redactFn(fnAnnotated))
 }
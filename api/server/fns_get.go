@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -29,5 +30,12 @@ func (s *Server) handleFnGet(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, f)
+	c.JSON(http.StatusOK, redactFn(f))
+}
+
+// redactFn returns fn without the secrets its annotations may hold
+func redactFn(fn *models.Fn) *models.Fn {
+	redacted := fn.Clone()
+	redacted.Annotations = models.RedactInvokePolicies(fn.Annotations)
+	return redacted
 }
@@ -2,14 +2,18 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/agent/hybrid"
 	"github.com/fnproject/fn/api/datastore"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/logs"
@@ -428,3 +432,151 @@ func TestFnInvokeEndpointAnnotations(t *testing.T) {
 		t.Errorf("unexpected fn val %s", v)
 	}
 }
+
+func TestFnRevisions(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{Name: "a", ID: "app_id"}
+	f := &models.Fn{ID: "fn_id", Name: "f", AppID: a.ID, Image: "fnproject/hello:0.0.1"}
+	f.SetDefaults()
+	ds := datastore.NewMockInit([]*models.App{a}, []*models.Fn{f})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	revisions := func() []*models.FnRevision {
+		_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/fns/fn_id/revisions", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected revisions to be listed, got %d %s", rec.Code, rec.Body.String())
+		}
+		var list fnRevisionList
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		return list.Items
+	}
+
+	for i, body := range []string{
+		`{ "image": "fnproject/hello:0.0.2", "config": {"A": "1"} }`,
+		`{ "annotations": {"k": "v"} }`, // not a deploy
+		`{ "image": "fnproject/hello:0.0.3" }`,
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodPut, "/v2/fns/fn_id", strings.NewReader(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: failed to update fn %d %s", i, rec.Code, rec.Body.String())
+		}
+	}
+
+	revs := revisions()
+	if len(revs) != 2 || revs[0].Number != 2 || revs[0].Image != "fnproject/hello:0.0.3" || revs[1].Config["A"] != "1" {
+		t.Fatalf("Unexpected revisions %+v", revs)
+	}
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/fns/fn_id/revisions/missing", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected a missing revision to be not found, got %d", rec.Code)
+	}
+
+	_, rec = routerRequest(t, srv.Router, http.MethodPost, "/v2/fns/fn_id/revisions/"+revs[1].ID+"/rollback", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected rollback to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+	var fn models.Fn
+	if err := json.NewDecoder(rec.Body).Decode(&fn); err != nil {
+		t.Fatal(err)
+	}
+	if fn.Image != "fnproject/hello:0.0.2" || fn.Config["A"] != "1" {
+		t.Fatalf("Expected the fn to be rolled back, got %+v", fn)
+	}
+	if revs := revisions(); len(revs) != 3 || !revs[0].Deploys(&fn) {
+		t.Fatalf("Expected rollback to take a revision, got %+v", revs)
+	}
+
+	// invocations pinned to a revision run its image
+	req := createRequest(t, http.MethodPost, "/invoke/fn_id", nil)
+	req.Header.Set(models.FnRevisionHeader, revs[0].ID)
+	pinned, err := srv.fnRevision(req, &fn)
+	if err != nil || pinned.Image != revs[0].Image {
+		t.Fatalf("Expected the invocation to run the pinned revision, got %+v %v", pinned, err)
+	}
+
+	// traffic may only be split between revisions of the fn
+	traffic := `{ "annotations": {"` + models.FnTrafficAnnotation + `": {"` + revs[1].ID + `": 120}} }`
+	_, rec = routerRequest(t, srv.Router, http.MethodPut, "/v2/fns/fn_id", strings.NewReader(traffic))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected invalid traffic to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestFnRevisionsOnLB(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{Name: "a", ID: "app_id"}
+	f := &models.Fn{ID: "fn_id", Name: "f", AppID: a.ID, Image: "fnproject/hello:0.0.1"}
+	f.SetDefaults()
+	ds := datastore.NewMockInit([]*models.App{a}, []*models.Fn{f})
+	rev, err := ds.(models.FnRevisionStore).InsertFnRevision(context.Background(), models.NewFnRevision(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := f.Clone()
+	fn.Image = "fnproject/hello:0.0.2"
+
+	// lb nodes read revisions from the API server, or ignore them if it does
+	// not keep them
+	for i, test := range []struct {
+		ds    models.Datastore
+		image string
+	}{
+		{ds, rev.Image},
+		{struct{ models.Datastore }{ds}, fn.Image},
+	} {
+		api := httptest.NewServer(testServer(test.ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI).Router)
+		cl, err := hybrid.NewClient(api.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lb := &Server{nodeType: ServerTypeLB, lbReadAccess: agent.NewCachedDataAccess(cl)}
+
+		pinned := createRequest(t, http.MethodPost, "/invoke/fn_id", nil)
+		pinned.Header.Set(models.FnRevisionHeader, rev.ID)
+		split := fn.Clone()
+		split.Annotations, _ = split.Annotations.With(models.FnTrafficAnnotation, map[string]int{rev.ID: 100})
+
+		for _, invoked := range []struct {
+			req *http.Request
+			fn  *models.Fn
+		}{
+			{pinned, fn},
+			{createRequest(t, http.MethodPost, "/invoke/fn_id", nil), split},
+		} {
+			got, err := lb.fnRevision(invoked.req, invoked.fn)
+			if err != nil || got.Image != test.image {
+				t.Errorf("Test %d: expected the invocation to run %s, got %+v %v", i, test.image, got, err)
+			}
+		}
+		api.Close()
+	}
+
+	// a revision missing from the API server is not found
+	api := httptest.NewServer(testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI).Router)
+	defer api.Close()
+	cl, err := hybrid.NewClient(api.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb := &Server{nodeType: ServerTypeLB, lbReadAccess: agent.NewCachedDataAccess(cl)}
+	req := createRequest(t, http.MethodPost, "/invoke/fn_id", nil)
+	req.Header.Set(models.FnRevisionHeader, "missing")
+	if _, err := lb.fnRevision(req, fn); err != models.ErrFnRevisionsNotFound {
+		t.Fatalf("Expected a missing revision not to be found, got %v", err)
+	}
+}
@@ -36,6 +36,7 @@ func (s *Server) handleFnUpdate(c *gin.Context) {
 		handleErrorResponse(c, err)
 		return
 	}
+	s.recordFnRevision(ctx, fnUpdated)
 
-	c.JSON(http.StatusOK, fnUpdated)
+	c.JSON(http.StatusOK, redactFn(fnUpdated))
 }
@@ -116,6 +116,7 @@ func RegisterAPIViews(tagKeys []string, dist []float64) {
 		common.CreateViewWithTags(apiRequestCountMeasure, view.Count(), reqTags),
 		common.CreateViewWithTags(apiResponseCountMeasure, view.Count(), respTags),
 		common.CreateViewWithTags(apiLatencyMeasure, view.Distribution(dist...), respTags),
+		common.CreateViewWithTags(sloBudgetMeasure, view.LastValue(), []tag.Key{sloFnKey, sloObjectiveKey}),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
@@ -187,7 +188,7 @@ func panicWrap(c *gin.Context) {
 }
 
 func loggerWrap(c *gin.Context) {
-	ctx, _ := common.LoggerWithFields(c.Request.Context(), extractFields(c))
+	ctx, _ := common.LoggerWithFields(common.WithComponent(c.Request.Context(), common.LogComponentServer), extractFields(c))
 
 	if appName := c.Param(api.ParamAppName); appName != "" {
 		c.Set(api.AppName, appName)
@@ -287,3 +288,82 @@ func appIDCheck(c *gin.Context) {
 	}
 	c.Next()
 }
+
+// namespaceStore returns the datastore as a models.NamespaceStore, if it stores
+// namespaces.
+func (s *Server) namespaceStore() (models.NamespaceStore, error) {
+	nss, ok := s.datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss, nil
+}
+
+func (s *Server) roleBindingStore() (models.RoleBindingStore, error) {
+	rbs, ok := s.datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs, nil
+}
+
+func (s *Server) apiKeyStore() (models.APIKeyStore, error) {
+	ks, ok := s.datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks, nil
+}
+
+// apiKeyGetter looks up the API keys of requests in the datastore of s,
+// which may be set after the auth options
+type apiKeyGetter struct {
+	s *Server
+}
+
+func (g apiKeyGetter) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	ks, err := g.s.apiKeyStore()
+	if err != nil {
+		return nil, models.ErrAPIKeysNotFound
+	}
+	return ks.GetAPIKeyByID(ctx, keyID)
+}
+
+func (s *Server) secretStore() (models.SecretStore, error) {
+	ss, ok := s.datastore.(models.SecretStore)
+	if !ok {
+		return nil, models.ErrSecretsUnsupported
+	}
+	return ss, nil
+}
+
+// namespaceScopeWrap scopes the requests which carry a namespace header to the
+// namespace, which must exist. Requests already scoped to a namespace by their
+// credentials may only name that namespace.
+func (s *Server) namespaceScopeWrap(c *gin.Context) {
+	nsID := c.GetHeader(models.NamespaceHeader)
+	if nsID == "" {
+		c.Next()
+		return
+	}
+
+	ctx := c.Request.Context()
+	if scope := common.NamespaceIDFromContext(ctx); scope != "" && scope != nsID {
+		handleErrorResponse(c, models.ErrNamespacesNotFound)
+		c.Abort()
+		return
+	}
+	nss, err := s.namespaceStore()
+	if err == nil {
+		_, err = nss.GetNamespaceByID(ctx, nsID)
+	}
+	if err != nil {
+		handleErrorResponse(c, err)
+		c.Abort()
+		return
+	}
+
+	ctx, _ = common.LoggerWithFields(ctx, logrus.Fields{"namespace_id": nsID})
+	c.Request = c.Request.WithContext(common.WithNamespaceID(ctx, nsID))
+	c.Next()
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fnproject/fn/api/invoke"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/grpcutil/health"
+	"github.com/fnproject/fn/grpcutil/reflection"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcStatusHeader is the header metadata holding the http status of the
+// response of a fn invoked over gRPC
+const grpcStatusHeader = "fn-http-status"
+
+// grpcInvokeService invokes fns for the gRPC invoke front-end, see
+// invoke.FnInvokeServer. Calls are turned into requests to /invoke/:fnID served
+// by handler, so they go through the same authentication, middlewares and
+// limits as the http ones, without a connection or the http encoding.
+type grpcInvokeService struct {
+	handler http.Handler
+}
+
+var _ invoke.FnInvokeServer = new(grpcInvokeService)
+
+func (g *grpcInvokeService) Invoke(ctx context.Context, in *invoke.InvokeRequest) (*invoke.InvokeResponse, error) {
+	req, err := grpcInvokeRequest(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	w := &grpcResponseWriter{ctx: ctx, header: make(http.Header), status: http.StatusOK}
+	g.handler.ServeHTTP(w, req)
+	if w.status >= http.StatusBadRequest {
+		return nil, w.error()
+	}
+	if err := grpc.SetHeader(ctx, w.metadata()); err != nil {
+		return nil, err
+	}
+	return &invoke.InvokeResponse{
+		Body:       w.body.Bytes(),
+		CallId:     w.header.Get("Fn-Call-Id"),
+		StatusCode: int32(w.status),
+	}, nil
+}
+
+func (g *grpcInvokeService) InvokeStream(in *invoke.InvokeRequest, stream invoke.FnInvoke_InvokeStreamServer) error {
+	ctx := stream.Context()
+	req, err := grpcInvokeRequest(ctx, in)
+	if err != nil {
+		return err
+	}
+	w := &grpcResponseWriter{ctx: ctx, header: make(http.Header), status: http.StatusOK, stream: stream}
+	g.handler.ServeHTTP(w, req)
+	if w.err != nil {
+		return w.err
+	}
+	if w.status >= http.StatusBadRequest {
+		return w.error()
+	}
+	// nothing was written, the client still gets the headers
+	return w.commit()
+}
+
+// grpcInvokeRequest builds the invoke request of in. The metadata of the call
+// become its headers and its context carries the deadline of the call.
+func grpcInvokeRequest(ctx context.Context, in *invoke.InvokeRequest) (*http.Request, error) {
+	if in.FnId == "" {
+		return nil, status.Error(codes.InvalidArgument, models.ErrFnsMissingID.Error())
+	}
+	req, err := http.NewRequest(http.MethodPost, "/invoke/"+url.PathEscape(in.FnId), bytes.NewReader(in.Body))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	req = req.WithContext(ctx)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, vs := range md {
+		if skipGRPCMetadata(k) {
+			continue
+		}
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if in.ContentType != "" {
+		req.Header.Set("Content-Type", in.ContentType)
+	}
+	if authority := md.Get(":authority"); len(authority) > 0 {
+		req.Host = authority[0]
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state := info.State
+			req.TLS = &state
+		}
+	}
+	return req, nil
+}
+
+// skipGRPCMetadata returns whether the metadata key k belongs to the gRPC
+// transport rather than to the fn
+func skipGRPCMetadata(k string) bool {
+	switch k {
+	case "content-type", "user-agent", "te":
+		return true
+	}
+	return strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") || strings.HasSuffix(k, "-bin")
+}
+
+// grpcResponseWriter collects the response of an invocation for a gRPC call.
+// Without a stream the body is buffered, with one every write is sent as a
+// chunk, once the headers were sent as header metadata. Error responses are
+// always buffered, they are returned as the status of the call.
+type grpcResponseWriter struct {
+	ctx       context.Context
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	stream    invoke.FnInvoke_InvokeStreamServer
+	committed bool
+	err       error
+}
+
+var _ http.ResponseWriter = new(grpcResponseWriter)
+var _ http.Flusher = new(grpcResponseWriter)
+
+func (w *grpcResponseWriter) Header() http.Header { return w.header }
+
+func (w *grpcResponseWriter) WriteHeader(code int) {
+	if !w.committed {
+		w.status = code
+	}
+}
+
+func (w *grpcResponseWriter) Write(b []byte) (int, error) {
+	if w.stream == nil || w.status >= http.StatusBadRequest {
+		return w.body.Write(b)
+	}
+	if w.err == nil {
+		w.err = w.commit()
+	}
+	if w.err == nil {
+		w.err = w.stream.Send(&invoke.InvokeResponseChunk{Data: b})
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(b), nil
+}
+
+// Flush is a no-op, every write to a stream is sent right away
+func (w *grpcResponseWriter) Flush() {}
+
+func (w *grpcResponseWriter) commit() error {
+	if w.committed {
+		return nil
+	}
+	w.committed = true
+	return w.stream.SendHeader(w.metadata())
+}
+
+// metadata returns the headers of the response as gRPC metadata
+func (w *grpcResponseWriter) metadata() metadata.MD {
+	md := metadata.MD{grpcStatusHeader: []string{strconv.Itoa(w.status)}}
+	for k, vs := range w.header {
+		switch k {
+		case "Content-Length", "Connection", "Transfer-Encoding":
+			continue
+		}
+		md.Append(k, vs...)
+	}
+	return md
+}
+
+// error returns the gRPC status of an error response, with the message of
+// the error if it is one of the api
+func (w *grpcResponseWriter) error() error {
+	msg := strings.TrimSpace(w.body.String())
+	var apiErr models.Error
+	if json.Unmarshal(w.body.Bytes(), &apiErr) == nil && apiErr.Message != "" {
+		msg = apiErr.Message
+	}
+	if msg == "" {
+		msg = http.StatusText(w.status)
+	}
+	grpc.SetTrailer(w.ctx, w.metadata())
+	return status.Error(grpcCode(w.status), msg)
+}
+
+// grpcCode maps the http status of an invocation to a gRPC code
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case models.ErrClientCancel.Code():
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
+
+// serveGRPCInvoke starts the gRPC invoke front-end on addr, serving calls with
+// handler, calling cancel if it fails.
+func (s *Server) serveGRPCInvoke(addr string, handler http.Handler, cancel context.CancelFunc) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	var opts []grpc.ServerOption
+	if tlsConfig := s.svcConfigs[WebServer].TLSConfig; tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	gs := grpc.NewServer(opts...)
+	invoke.RegisterFnInvokeServer(gs, &grpcInvokeService{handler: handler})
+	health.RegisterHealthServer(gs, health.NewServer())
+	reflection.Register(gs)
+
+	go func() {
+		if err := gs.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			logrus.WithError(err).Error("grpc invoke server error")
+			cancel()
+		} else {
+			logrus.Info("grpc invoke server stopped")
+		}
+	}()
+	return gs, nil
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/invoke"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testInvokeStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+	chunks []string
+}
+
+func (s *testInvokeStream) Context() context.Context { return s.ctx }
+func (s *testInvokeStream) SendHeader(md metadata.MD) error {
+	if s.header != nil {
+		return errors.New("header sent twice")
+	}
+	s.header = md
+	return nil
+}
+func (s *testInvokeStream) Send(m *invoke.InvokeResponseChunk) error {
+	s.chunks = append(s.chunks, string(m.Data))
+	return nil
+}
+
+func testInvokeHandler() http.Handler {
+	engine := gin.New()
+	engine.POST("/invoke/:fnID", func(c *gin.Context) {
+		switch c.Param("fnID") {
+		case "missing":
+			handleErrorResponse(c, models.ErrFnsNotFound)
+		case "echo":
+			body, _ := ioutil.ReadAll(c.Request.Body)
+			c.Header("Fn-Call-Id", "call")
+			c.Header("Content-Type", c.Request.Header.Get("Content-Type"))
+			c.Status(http.StatusOK)
+			c.Writer.Write([]byte(c.Request.Header.Get("Fn-Test") + ":"))
+			c.Writer.Flush()
+			c.Writer.Write(body)
+		}
+	})
+	return engine
+}
+
+func TestGRPCInvokeStream(t *testing.T) {
+	svc := &grpcInvokeService{handler: testInvokeHandler()}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("fn-test", "meta", "grpc-timeout", "1S"))
+	stream := &testInvokeStream{ctx: ctx}
+
+	err := svc.InvokeStream(&invoke.InvokeRequest{FnId: "echo", Body: []byte("body"), ContentType: "text/plain"}, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.chunks) != 2 || stream.chunks[0] != "meta:" || stream.chunks[1] != "body" {
+		t.Fatalf("expected the writes of the fn as chunks, got %q", stream.chunks)
+	}
+	if got := stream.header.Get("fn-call-id"); len(got) != 1 || got[0] != "call" {
+		t.Fatalf("expected the call id in the header metadata, got %v", stream.header)
+	}
+	if got := stream.header.Get("content-type"); len(got) != 1 || got[0] != "text/plain" {
+		t.Fatalf("expected the content type of the request to reach the fn, got %v", stream.header)
+	}
+	if got := stream.header.Get(grpcStatusHeader); len(got) != 1 || got[0] != "200" {
+		t.Fatalf("expected the status in the header metadata, got %v", stream.header)
+	}
+
+	stream = &testInvokeStream{ctx: ctx}
+	err = svc.InvokeStream(&invoke.InvokeRequest{FnId: "missing"}, stream)
+	if status.Code(err) != codes.NotFound || status.Convert(err).Message() != models.ErrFnsNotFound.Error() {
+		t.Fatalf("expected a not found status, got %v", err)
+	}
+	if len(stream.chunks) != 0 || stream.header != nil {
+		t.Fatal("expected nothing to be sent for an error")
+	}
+
+	err = svc.InvokeStream(&invoke.InvokeRequest{}, &testInvokeStream{ctx: ctx})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected an invalid argument status without a fn id, got %v", err)
+	}
+}
+
+func TestGRPCCode(t *testing.T) {
+	for httpStatus, code := range map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusUnauthorized:        codes.Unauthenticated,
+		http.StatusTooManyRequests:     codes.ResourceExhausted,
+		http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+		http.StatusServiceUnavailable:  codes.Unavailable,
+		http.StatusBadGateway:          codes.Internal,
+		models.ErrClientCancel.Code():  codes.Canceled,
+		http.StatusInternalServerError: codes.Internal,
+	} {
+		if got := grpcCode(httpStatus); got != code {
+			t.Errorf("expected %d to map to %v, got %v", httpStatus, code, got)
+		}
+	}
+}
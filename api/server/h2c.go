@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+)
+
+// h2cHandler serves HTTP/2 without TLS (h2c) next to HTTP/1.1 on a cleartext
+// listener, for clients and load balancers that talk HTTP/2 with prior
+// knowledge. net/http hands the connection preface of such clients to the
+// handler as a PRI request, the connection is then taken over by an HTTP/2
+// server. Upgrades from HTTP/1.1 (Upgrade: h2c) are not supported, those
+// requests are served as HTTP/1.1. Listeners with TLS negotiate HTTP/2 on
+// their own.
+type h2cHandler struct {
+	handler http.Handler
+	base    *http.Server
+	h2      *http2.Server
+}
+
+func newH2CHandler(handler http.Handler, base *http.Server) http.Handler {
+	return &h2cHandler{handler: handler, base: base, h2: &http2.Server{}}
+}
+
+func (h *h2cHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PRI" || r.URL.Path != "*" || r.Proto != "HTTP/2.0" || len(r.Header) != 0 {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	conn, err := hijackH2C(w)
+	if err != nil {
+		logrus.WithError(err).Debug("h2c connection preface rejected")
+		return
+	}
+	defer conn.Close()
+	h.h2.ServeConn(conn, &http2.ServeConnOpts{BaseConfig: h.base, Handler: h.handler})
+}
+
+// hijackH2C takes over the connection of a PRI request, checking the rest of
+// the preface. The conn returned replays the whole preface, the HTTP/2 server
+// reads it itself.
+func hijackH2C(w http.ResponseWriter) (net.Conn, error) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, http.ErrNotSupported
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	const rest = "SM\r\n\r\n"
+	b := make([]byte, len(rest))
+	if _, err := io.ReadFull(rw, b); err != nil || string(b) != rest {
+		conn.Close()
+		if err == nil {
+			err = http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		return nil, err
+	}
+	return &h2cConn{
+		Conn:   conn,
+		reader: io.MultiReader(strings.NewReader(http2.ClientPreface), rw),
+		writer: rw.Writer,
+	}, nil
+}
+
+// h2cConn is a hijacked connection whose reads start with what was buffered
+type h2cConn struct {
+	net.Conn
+	reader io.Reader
+	writer *bufio.Writer
+}
+
+func (c *h2cConn) Read(p []byte) (int, error) { return c.reader.Read(p) }
+
+func (c *h2cConn) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, c.writer.Flush()
+}
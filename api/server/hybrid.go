@@ -129,12 +129,29 @@ func (s *Server) handleRunnerStart(c *gin.Context) {
 	//// TODO we could either let UpdateCall handle setting to error or do it
 	//// here explicitly
 
-	// TODO change this to only delete message if the status change fails b/c it already ran
-	// after messaging semantics change
-	if err := s.mq.Delete(ctx, &call); err != nil { // TODO change this to take some string(s), not a whole call
+	// calls cancelled while they were queued are dropped
+	cancelled, err := models.IsCancelledCall(ctx, s.logstore, &call)
+	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
+	if cancelled {
+		if err := s.mq.Delete(ctx, &call); err != nil {
+			common.Logger(ctx).WithError(err).Error("error deleting cancelled call from the queue")
+		}
+		handleErrorResponse(c, models.ErrCallCancelled)
+		return
+	}
+
+	// TODO change this to only delete message if the status change fails b/c it already ran
+	// after messaging semantics change
+	// queues keeping reservations alive delete the message once the call finished
+	if !models.KeepsReservations(s.mq) {
+		if err := s.mq.Delete(ctx, &call); err != nil { // TODO change this to take some string(s), not a whole call
+			handleErrorResponse(c, err)
+			return
+		}
+	}
 	//}
 	//handleV1ErrorResponse(c, err)
 	//return
@@ -176,6 +193,9 @@ func (s *Server) handleRunnerFinish(c *gin.Context) {
 		// note: Not returning err here since the job could have already finished successfully.
 	}
 
+	// calls of runners of api nodes complete here, rather than on an agent
+	s.slos.AfterCall(ctx, &call)
+
 	// TODO open this up after we change messaging semantics.
 	// TODO we don't know whether a call is async or sync. we likely need an additional
 	// arg in params for a message id and can detect based on this. for now, delete messages
@@ -184,6 +204,12 @@ func (s *Server) handleRunnerFinish(c *gin.Context) {
 	//common.Logger(ctx).WithError(err).Error("error deleting mq msg")
 	//// note: Not returning err here since the job could have already finished successfully.
 	//}
+	if models.KeepsReservations(s.mq) {
+		// queues keeping reservations succeed deleting calls they do not know, such as sync ones
+		if err := s.mq.Delete(ctx, &call); err != nil {
+			common.Logger(ctx).WithError(err).Error("error deleting mq msg")
+		}
+	}
 
 	c.String(http.StatusNoContent, "")
 }
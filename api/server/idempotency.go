@@ -0,0 +1,154 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idempotentResponse is the response of a call made with an idempotency key.
+// done is closed once the call completed, duplicates wait on it.
+type idempotentResponse struct {
+	key     string
+	done    chan struct{}
+	failed  bool
+	status  int
+	header  http.Header
+	body    []byte
+	size    int
+	expires time.Time
+}
+
+const (
+	// idempotencyMaxEntries and idempotencyMaxBytes bound the responses an
+	// idempotency cache keeps, the oldest are evicted first
+	idempotencyMaxEntries = 10000
+	idempotencyMaxBytes   = 64 * 1024 * 1024
+)
+
+// idempotencyCache remembers the responses of calls made with an idempotency
+// key for a window, so that retried requests are not executed again. The
+// cache is per node: requests retried to another server of a cluster execute
+// the call again. At most maxEntries responses of maxBytes together are kept,
+// older responses are evicted before the end of the window and larger ones are
+// not kept at all.
+type idempotencyCache struct {
+	lock       sync.Mutex
+	window     time.Duration
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	entries    map[string]*list.Element
+	order      *list.List // completed responses, oldest first
+}
+
+func newIdempotencyCache(window time.Duration, maxEntries, maxBytes int) *idempotencyCache {
+	return &idempotencyCache{
+		window:     window,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// begin returns the response for key. If owner is true, the caller must
+// execute the call and then complete or abort the response, otherwise the
+// response belongs to a previous or in flight call.
+func (c *idempotencyCache) begin(key string) (resp *idempotentResponse, owner bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.expire(time.Now())
+
+	if e, ok := c.entries[key]; ok {
+		return e.Value.(*idempotentResponse), false
+	}
+
+	resp = &idempotentResponse{key: key, done: make(chan struct{})}
+	c.entries[key] = &list.Element{Value: resp}
+	return resp, true
+}
+
+// complete stores the response of a call for the window
+func (c *idempotencyCache) complete(resp *idempotentResponse, status int, header http.Header, body []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	resp.status = status
+	resp.header = make(http.Header, len(header))
+	for k, v := range header {
+		resp.header[k] = append([]string(nil), v...)
+	}
+	resp.body = append([]byte(nil), body...)
+	resp.expires = time.Now().Add(c.window)
+	close(resp.done)
+
+	resp.size = len(resp.body)
+	for k, v := range resp.header {
+		resp.size += len(k)
+		for _, s := range v {
+			resp.size += len(s)
+		}
+	}
+	if resp.size > c.maxBytes {
+		// duplicates already waiting still get the response
+		delete(c.entries, resp.key)
+		return
+	}
+	c.entries[resp.key] = c.order.PushBack(resp)
+	c.bytes += resp.size
+	for c.order.Len() > c.maxEntries || c.bytes > c.maxBytes {
+		c.remove(c.order.Front())
+	}
+}
+
+// abort forgets a call that did not complete, so that it can be retried
+func (c *idempotencyCache) abort(resp *idempotentResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	resp.failed = true
+	delete(c.entries, resp.key)
+	close(resp.done)
+}
+
+func (c *idempotencyCache) expire(now time.Time) {
+	for e := c.order.Front(); e != nil; e = c.order.Front() {
+		resp := e.Value.(*idempotentResponse)
+		if now.Before(resp.expires) {
+			return
+		}
+		c.remove(e)
+	}
+}
+
+func (c *idempotencyCache) remove(e *list.Element) {
+	resp := e.Value.(*idempotentResponse)
+	c.order.Remove(e)
+	c.bytes -= resp.size
+	delete(c.entries, resp.key)
+}
+
+// replay waits for the response of a duplicate call and writes it out.
+// It returns false if the call failed and should be executed again.
+func (resp *idempotentResponse) replay(ctx context.Context, w http.ResponseWriter) (bool, error) {
+	select {
+	case <-resp.done:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	if resp.failed {
+		return false, nil
+	}
+
+	for k, v := range resp.header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	w.Header().Set("Fn-Idempotent-Replay", "true")
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+	return true, nil
+}
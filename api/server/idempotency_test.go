@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyCacheReplay(t *testing.T) {
+	c := newIdempotencyCache(time.Minute, 10, 1024)
+
+	resp, owner := c.begin("fn/key")
+	if !owner {
+		t.Fatal("Expected the first call with a key to execute")
+	}
+	dup, owner := c.begin("fn/key")
+	if owner || dup != resp {
+		t.Fatal("Expected a duplicate in flight to wait for the first call")
+	}
+
+	replayed := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		if ok, err := dup.replay(context.Background(), rec); !ok || err != nil {
+			t.Errorf("Expected the duplicate to be replayed, got %v %v", ok, err)
+		}
+		replayed <- rec
+	}()
+	c.complete(resp, http.StatusCreated, http.Header{"Content-Type": {"text/plain"}}, []byte("hello"))
+
+	check := func(rec *httptest.ResponseRecorder) {
+		if rec.Code != http.StatusCreated || rec.Body.String() != "hello" ||
+			rec.Header().Get("Content-Type") != "text/plain" || rec.Header().Get("Fn-Idempotent-Replay") != "true" {
+			t.Fatalf("Unexpected replay %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+		}
+	}
+	check(<-replayed)
+
+	// later duplicates get the stored response
+	again, owner := c.begin("fn/key")
+	if owner {
+		t.Fatal("Expected the completed call to be replayed")
+	}
+	rec := httptest.NewRecorder()
+	if ok, err := again.replay(context.Background(), rec); !ok || err != nil {
+		t.Fatalf("Expected the call to be replayed, got %v %v", ok, err)
+	}
+	check(rec)
+
+	if _, owner := c.begin("fn/other"); !owner {
+		t.Fatal("Expected calls with other keys to execute")
+	}
+}
+
+func TestIdempotencyCacheAbort(t *testing.T) {
+	c := newIdempotencyCache(time.Minute, 10, 1024)
+
+	resp, _ := c.begin("fn/key")
+	dup, _ := c.begin("fn/key")
+	c.abort(resp)
+
+	if ok, err := dup.replay(context.Background(), httptest.NewRecorder()); ok || err != nil {
+		t.Fatalf("Expected the duplicate of a failed call to execute it again, got %v %v", ok, err)
+	}
+	if _, owner := c.begin("fn/key"); !owner {
+		t.Fatal("Expected the failed call to be retried")
+	}
+
+	// duplicates give up waiting with their request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inflight, _ := c.begin("fn/key")
+	if ok, err := inflight.replay(ctx, httptest.NewRecorder()); ok || err != context.Canceled {
+		t.Fatalf("Expected the duplicate to give up, got %v %v", ok, err)
+	}
+}
+
+func TestIdempotencyCacheExpiry(t *testing.T) {
+	c := newIdempotencyCache(time.Minute, 10, 1024)
+
+	resp, _ := c.begin("fn/key")
+	c.complete(resp, http.StatusOK, nil, []byte("hello"))
+
+	c.expire(time.Now().Add(30 * time.Second))
+	if _, owner := c.begin("fn/key"); owner {
+		t.Fatal("Expected the response to be kept within the window")
+	}
+	c.expire(time.Now().Add(2 * time.Minute))
+	if _, owner := c.begin("fn/key"); !owner {
+		t.Fatal("Expected the response to be forgotten after the window")
+	}
+	if c.bytes != 0 || c.order.Len() != 0 {
+		t.Fatalf("Expected nothing to be kept, got %d responses of %d bytes", c.order.Len(), c.bytes)
+	}
+}
+
+func TestIdempotencyCacheEviction(t *testing.T) {
+	c := newIdempotencyCache(time.Minute, 2, 10)
+	complete := func(key, body string) {
+		resp, owner := c.begin(key)
+		if !owner {
+			t.Fatalf("Expected %s to execute", key)
+		}
+		c.complete(resp, http.StatusOK, nil, []byte(body))
+	}
+	kept := func(key string) bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		_, ok := c.entries[key]
+		return ok
+	}
+
+	// too many responses
+	complete("a", "1")
+	complete("b", "2")
+	complete("c", "3")
+	if kept("a") || !kept("b") || !kept("c") {
+		t.Fatal("Expected the oldest response to be evicted for the entry cap")
+	}
+
+	// too many bytes
+	complete("d", "1234567890")
+	if kept("b") || kept("c") || !kept("d") || c.bytes != 10 {
+		t.Fatalf("Expected older responses to be evicted for the byte cap, got %d bytes", c.bytes)
+	}
+
+	// too large a response is not kept, but still returned to its duplicates
+	resp, _ := c.begin("e")
+	dup, _ := c.begin("e")
+	c.complete(resp, http.StatusOK, nil, []byte("12345678901"))
+	if kept("e") || !kept("d") {
+		t.Fatal("Expected the large response not to be kept")
+	}
+	rec := httptest.NewRecorder()
+	if ok, _ := dup.replay(context.Background(), rec); !ok || rec.Body.String() != "12345678901" {
+		t.Fatalf("Expected the waiting duplicate to get the large response, got %q", rec.Body.String())
+	}
+}
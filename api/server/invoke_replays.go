@@ -0,0 +1,46 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// replayFilter remembers the signatures of signed invocations until they are
+// too old to be accepted, see models.ReplayFilter. The filter is per process:
+// each server remembers the signatures it saw in memory, a request replayed
+// to another server of a cluster, or to a restarted server, is only rejected
+// there once its signature is too old.
+type replayFilter struct {
+	lock      sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// replayPruneInterval is how often expired signatures are forgotten
+const replayPruneInterval = time.Minute
+
+func newReplayFilter() *replayFilter {
+	return &replayFilter{seen: make(map[string]time.Time)}
+}
+
+func (f *replayFilter) Seen(sig []byte, expiry time.Time) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(f.lastPrune) > replayPruneInterval {
+		for k, exp := range f.seen {
+			if now.After(exp) {
+				delete(f.seen, k)
+			}
+		}
+		f.lastPrune = now
+	}
+
+	key := string(sig)
+	if exp, ok := f.seen[key]; ok && now.Before(exp) {
+		return true
+	}
+	f.seen[key] = expiry
+	return false
+}
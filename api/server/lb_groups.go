@@ -0,0 +1,140 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
+	"github.com/gin-gonic/gin"
+)
+
+// lb groups are held in memory by each lb, they are lost on restart and each
+// lb of a deployment must be given the same groups
+var (
+	errLBGroupExists   = models.NewAPIError(http.StatusConflict, errors.New("LB group already exists"))
+	errLBGroupNotFound = models.NewAPIError(http.StatusNotFound, errors.New("LB group not found"))
+)
+
+type lbGroupWrapper struct {
+	pool.LBGroup
+	// Runners are the addresses of the runners currently in the group, the
+	// runners of its canary are in CanaryRunners
+	Runners       []string `json:"runners"`
+	CanaryRunners []string `json:"canary_runners,omitempty"`
+}
+
+func bindLBGroup(c *gin.Context) (*pool.LBGroup, bool) {
+	var g pool.LBGroup
+	if err := c.BindJSON(&g); err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return nil, false
+	}
+	return &g, true
+}
+
+func (s *Server) handleLBGroupList(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"items": s.lbGroups.List()})
+}
+
+func (s *Server) handleLBGroupCreate(c *gin.Context) {
+	g, ok := bindLBGroup(c)
+	if !ok {
+		return
+	}
+	if _, exists := s.lbGroups.Get(g.ID); exists {
+		handleErrorResponse(c, errLBGroupExists)
+		return
+	}
+	if err := s.lbGroups.Put(*g); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+// handleLBGroupPut creates or replaces the group in the path
+func (s *Server) handleLBGroupPut(c *gin.Context) {
+	g, ok := bindLBGroup(c)
+	if !ok {
+		return
+	}
+	if g.ID == "" {
+		g.ID = c.Param("groupID")
+	} else if g.ID != c.Param("groupID") {
+		handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, errors.New("LB group id in body does not match path")))
+		return
+	}
+	if err := s.lbGroups.Put(*g); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+// handleLBGroupGet returns a group and the runners it currently selects
+func (s *Server) handleLBGroupGet(c *gin.Context) {
+	g, ok := s.lbGroups.Get(c.Param("groupID"))
+	if !ok {
+		handleErrorResponse(c, errLBGroupNotFound)
+		return
+	}
+	stable, canary, err := pool.LBGroupMembers(c.Request.Context(), s.runnerPool, g.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	res := lbGroupWrapper{LBGroup: g, Runners: make([]string, 0, len(stable))}
+	for _, r := range stable {
+		res.Runners = append(res.Runners, r.Address())
+	}
+	for _, r := range canary {
+		res.CanaryRunners = append(res.CanaryRunners, r.Address())
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// handleLBGroupCanaryPut sets the canary of a group, eg. to change the share
+// of calls going to the canary during a rollout
+func (s *Server) handleLBGroupCanaryPut(c *gin.Context) {
+	var canary pool.LBCanary
+	if err := c.BindJSON(&canary); err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+	s.setLBGroupCanary(c, &canary)
+}
+
+// handleLBGroupCanaryDelete removes the canary of a group, all its runners
+// take all its calls again
+func (s *Server) handleLBGroupCanaryDelete(c *gin.Context) {
+	s.setLBGroupCanary(c, nil)
+}
+
+func (s *Server) setLBGroupCanary(c *gin.Context, canary *pool.LBCanary) {
+	g, err := s.lbGroups.SetCanary(c.Param("groupID"), canary)
+	if err == pool.ErrLBGroupNotFound {
+		err = errLBGroupNotFound
+	}
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+func (s *Server) handleLBGroupDelete(c *gin.Context) {
+	if !s.lbGroups.Delete(c.Param("groupID")) {
+		handleErrorResponse(c, errLBGroupNotFound)
+		return
+	}
+	c.String(http.StatusNoContent, "")
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	pool "github.com/fnproject/fn/api/runnerpool"
+	"github.com/gin-gonic/gin"
+)
+
+type lbStatus struct {
+	// Placer is the placement algorithm of the lb, see EnvLBPlacementAlg
+	Placer string `json:"placer"`
+	*pool.PoolStatus
+}
+
+// handleLBStatus returns the runners of an lb, their health and how calls
+// were recently spread over them
+func (s *Server) handleLBStatus(c *gin.Context) {
+	status, err := pool.Status(c.Request.Context(), s.runnerPool)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	s.reloadLock.Lock()
+	placer := s.placerAlg
+	s.reloadLock.Unlock()
+	c.JSON(http.StatusOK, lbStatus{Placer: placer, PoolStatus: status})
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// logLevels are the log level of a server and the levels of its components
+// set apart from it, see EnvLogComponentLevels
+type logLevels struct {
+	Level string `json:"level,omitempty"`
+	// Components are the levels of components, an empty level makes a
+	// component follow Level again
+	Components map[string]string `json:"components,omitempty"`
+}
+
+func handleLogLevelsGet(c *gin.Context) {
+	c.JSON(http.StatusOK, logLevels{Level: logrus.GetLevel().String(), Components: common.ComponentLogLevels()})
+}
+
+// handleLogLevelsPut sets the log level of the server if given and the levels
+// of the components given, the levels of the components left out are kept.
+// Levels set are lost on restart and on reloads of the log levels.
+func handleLogLevelsPut(c *gin.Context) {
+	var levels logLevels
+	if err := c.BindJSON(&levels); err != nil {
+		handleErrorResponse(c, models.ErrInvalidJSON)
+		return
+	}
+	if levels.Level != "" {
+		if _, err := logrus.ParseLevel(levels.Level); err != nil {
+			handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, err))
+			return
+		}
+	}
+	for component, level := range levels.Components {
+		if !isLogComponent(component) {
+			handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, fmt.Errorf("unknown log component %q, must be one of %s", component, strings.Join(common.LogComponents, ", "))))
+			return
+		}
+		if level == "" {
+			continue
+		}
+		if _, err := logrus.ParseLevel(level); err != nil {
+			handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, err))
+			return
+		}
+	}
+
+	if levels.Level != "" {
+		common.SetLogLevel(levels.Level)
+	}
+	for component, level := range levels.Components {
+		if err := common.SetComponentLogLevel(component, level); err != nil {
+			handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, err))
+			return
+		}
+	}
+	handleLogLevelsGet(c)
+}
+
+func isLogComponent(component string) bool {
+	for _, c := range common.LogComponents {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleLogSearch(c *gin.Context) {
+	ctx := c.Request.Context()
+	var err error
+
+	fnID := c.Param(api.ParamFnID)
+
+	if fnID == "" {
+		handleErrorResponse(c, models.ErrFnsMissingID)
+		return
+	}
+
+	_, err = s.datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	filter := models.LogFilter{FnID: fnID, Contains: c.Query("contains")}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	filter.FromTime, filter.ToTime, err = timeParams(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	logs, err := models.SearchLogs(ctx, s.logstore, &filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
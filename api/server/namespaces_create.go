@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleNamespaceCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ns := &models.Namespace{}
+
+	err := c.BindJSON(ns)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	if ns.ID != "" {
+		handleErrorResponse(c, models.ErrNamespaceIDProvided)
+		return
+	}
+
+	nss, err := s.namespaceStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	ns, err = nss.InsertNamespace(ctx, ns)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ns)
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleNamespaceDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	nss, err := s.namespaceStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	err = nss.RemoveNamespace(ctx, c.Param(api.ParamNamespaceID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
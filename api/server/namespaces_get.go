@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleNamespaceGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	nss, err := s.namespaceStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	ns, err := nss.GetNamespaceByID(ctx, c.Param(api.ParamNamespaceID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ns)
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleNamespaceList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.NamespaceFilter{}
+
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	filter.Name = c.Query("name")
+
+	nss, err := s.namespaceStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	namespaces, err := nss.GetNamespaces(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, namespaces)
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleNamespaceUpdate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ns := &models.Namespace{}
+
+	err := c.BindJSON(ns)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	id := c.Param(api.ParamNamespaceID)
+
+	if ns.ID == "" {
+		ns.ID = id
+	}
+	if ns.ID != id {
+		handleErrorResponse(c, models.ErrNamespacesIDMismatch)
+		return
+	}
+
+	nss, err := s.namespaceStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	ns, err = nss.UpdateNamespace(ctx, ns)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ns)
+}
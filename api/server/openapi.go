@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/openapi"
+	"github.com/fnproject/fn/api/version"
+	"github.com/gin-gonic/gin"
+)
+
+var pageQuery = []string{"cursor", "per_page"}
+
+// openAPIEndpoints describes the handlers of the routes documented by
+// /v2/openapi.json, by handler name. Paths, methods and path parameters are
+// those the handlers are registered with.
+var openAPIEndpoints = map[string]*openapi.Endpoint{
+	"handleOpenAPI": {ID: "GetOpenAPI", Summary: "Get the OpenAPI document of the API", Response: map[string]interface{}{}},
+
+	"handleNamespaceList":   {ID: "ListNamespaces", Summary: "List namespaces", Query: append([]string{"name"}, pageQuery...), Response: models.NamespaceList{}},
+	"handleNamespaceCreate": {ID: "CreateNamespace", Summary: "Create a namespace", Request: models.Namespace{}, Response: models.Namespace{}},
+	"handleNamespaceGet":    {ID: "GetNamespace", Summary: "Get a namespace", Response: models.Namespace{}},
+	"handleNamespaceUpdate": {ID: "UpdateNamespace", Summary: "Update a namespace", Request: models.Namespace{}, Response: models.Namespace{}},
+	"handleNamespaceDelete": {ID: "DeleteNamespace", Summary: "Delete a namespace", Status: http.StatusNoContent},
+
+	"handleRoleBindingList":   {ID: "ListRoleBindings", Summary: "List role bindings", Query: append([]string{"subject", "app_id", "namespace_id"}, pageQuery...), Response: models.RoleBindingList{}},
+	"handleRoleBindingCreate": {ID: "CreateRoleBinding", Summary: "Bind a role to a subject", Request: models.RoleBinding{}, Response: models.RoleBinding{}},
+	"handleRoleBindingGet":    {ID: "GetRoleBinding", Summary: "Get a role binding", Response: models.RoleBinding{}},
+	"handleRoleBindingDelete": {ID: "DeleteRoleBinding", Summary: "Delete a role binding", Status: http.StatusNoContent},
+
+	"handleAPIKeyList":   {ID: "ListAPIKeys", Summary: "List API keys", Query: append([]string{"name", "namespace_id"}, pageQuery...), Response: models.APIKeyList{}},
+	"handleAPIKeyCreate": {ID: "CreateAPIKey", Summary: "Create an API key", Request: models.APIKey{}, Response: models.APIKey{}},
+	"handleAPIKeyGet":    {ID: "GetAPIKey", Summary: "Get an API key", Response: models.APIKey{}},
+	"handleAPIKeyRevoke": {ID: "RevokeAPIKey", Summary: "Revoke an API key", Status: http.StatusNoContent},
+	"handleAPIKeyRotate": {ID: "RotateAPIKey", Summary: "Replace an API key by a new one", Query: []string{"grace"}, Response: models.APIKey{}},
+
+	"handleAppList":   {ID: "ListApps", Summary: "List apps", Query: append([]string{"name", "namespace_id"}, pageQuery...), Response: models.AppList{}},
+	"handleAppCreate": {ID: "CreateApp", Summary: "Create an app", Request: models.App{}, Response: models.App{}},
+	"handleAppGet":    {ID: "GetApp", Summary: "Get an app", Response: models.App{}},
+	"handleAppUpdate": {ID: "UpdateApp", Summary: "Update an app", Request: models.App{}, Response: models.App{}},
+	"handleAppDelete": {ID: "DeleteApp", Summary: "Delete an app", Status: http.StatusNoContent},
+	"handleAppExport": {ID: "ExportApp", Summary: "Export an app as a YAML bundle", Response: models.AppBundle{}, ContentType: contentTypeYAML},
+	"handleAppImport": {ID: "ImportApp", Summary: "Apply a YAML bundle to an app", Query: []string{"prune"}, Request: models.AppBundle{}, Response: models.AppBundle{}, ContentType: contentTypeYAML},
+	"handleAppUsage":  {ID: "GetAppUsage", Summary: "Get the usage of an app over windows of time", Query: []string{"fn_id", "from_time", "to_time", "window", "by_fn"}, Response: models.UsageList{}},
+
+	"handleSecretList":   {ID: "ListSecrets", Summary: "List the secrets of an app", Response: secretList{}},
+	"handleSecretPut":    {ID: "PutSecret", Summary: "Create or update a secret of an app", Request: models.Secret{}, Response: models.Secret{}},
+	"handleSecretDelete": {ID: "DeleteSecret", Summary: "Delete a secret of an app", Status: http.StatusNoContent},
+
+	"handleFnList":             {ID: "ListFns", Summary: "List fns", Query: append([]string{"app_id", "name"}, pageQuery...), Response: models.FnList{}},
+	"handleFnCreate":           {ID: "CreateFn", Summary: "Create a fn", Request: models.Fn{}, Response: models.Fn{}},
+	"handleFnGet":              {ID: "GetFn", Summary: "Get a fn", Response: models.Fn{}},
+	"handleFnUpdate":           {ID: "UpdateFn", Summary: "Update a fn", Request: models.Fn{}, Response: models.Fn{}},
+	"handleFnDelete":           {ID: "DeleteFn", Summary: "Delete a fn", Status: http.StatusNoContent},
+	"handleFnRevisionList":     {ID: "ListFnRevisions", Summary: "List the revisions of a fn", Response: fnRevisionList{}},
+	"handleFnRevisionGet":      {ID: "GetFnRevision", Summary: "Get a revision of a fn", Response: models.FnRevision{}},
+	"handleFnRevisionRollback": {ID: "RollbackFn", Summary: "Roll a fn back to a revision", Response: models.Fn{}},
+	"handleFnSLO":              {ID: "GetFnSLO", Summary: "Get the compliance of a fn with its slo", Response: models.SLOStatus{}},
+
+	"handleAuditList": {ID: "ListAuditRecords", Summary: "List audit records", Query: append([]string{"kind", "object_id", "app_id", "subject"}, pageQuery...), Response: models.AuditRecordList{}},
+
+	"handleTriggerList":   {ID: "ListTriggers", Summary: "List triggers", Query: append([]string{"app_id", "fn_id", "name"}, pageQuery...), Response: models.TriggerList{}},
+	"handleTriggerCreate": {ID: "CreateTrigger", Summary: "Create a trigger", Request: models.Trigger{}, Response: models.Trigger{}},
+	"handleTriggerGet":    {ID: "GetTrigger", Summary: "Get a trigger", Response: models.Trigger{}},
+	"handleTriggerUpdate": {ID: "UpdateTrigger", Summary: "Update a trigger", Request: models.Trigger{}, Response: models.Trigger{}},
+	"handleTriggerDelete": {ID: "DeleteTrigger", Summary: "Delete a trigger", Status: http.StatusNoContent},
+
+	"handleCallCancel":        {ID: "CancelCall", Summary: "Cancel a call", Query: []string{"fn_id"}, Response: models.Call{}, Status: http.StatusAccepted},
+	"handleCallList":          {ID: "ListCalls", Summary: "List the calls of a fn", Query: append([]string{"status", "error_class", "from_time", "to_time"}, pageQuery...), Response: models.CallList{}},
+	"handleCallGet":           {ID: "GetCall", Summary: "Get a call of a fn", Response: models.Call{}},
+	"handleCallLogGet":        {ID: "GetCallLog", Summary: "Get the log of a call", Response: callLog{}},
+	"handleLogSearch":         {ID: "SearchLogs", Summary: "Search the logs of a fn", Query: append([]string{"contains"}, pageQuery...), Response: models.LogMatchList{}},
+	"handleDeadLetterList":    {ID: "ListDeadLetters", Summary: "List the dead letters of a fn", Query: pageQuery, Response: models.CallList{}},
+	"handleDeadLetterRedrive": {ID: "RedriveDeadLetters", Summary: "Enqueue dead letters of a fn again", Request: redriveRequest{}, Response: models.CallList{}},
+	"handleFnInvokeCall":      {ID: "InvokeFn", Summary: "Invoke a fn", Request: "", Response: "", ContentType: "*/*"},
+	"handleFnInvokeWebSocket": {ID: "InvokeFnWebSocket", Summary: "Invoke a fn over a web socket", Status: http.StatusSwitchingProtocols},
+}
+
+// handleOpenAPI serves the OpenAPI document of the routes of the server
+func (s *Server) handleOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, s.openAPIDocument())
+}
+
+// openAPIDocument documents the routes registered with the router whose
+// handlers are described by openAPIEndpoints, and the other routes of the v2
+// API with their paths only. Hybrid runner routes are not part of the API.
+func (s *Server) openAPIDocument() *openapi.Document {
+	b := openapi.NewBuilder("Fn API", version.Version, models.Error{})
+	for _, r := range s.Router.Routes() {
+		if e, ok := openAPIEndpoints[handlerName(r.Handler)]; ok {
+			b.Add(r.Method, r.Path, e)
+			continue
+		}
+		if strings.HasPrefix(r.Path, "/v2/") && !strings.HasPrefix(r.Path, "/v2/runner/") && handlerName(r.Handler) != "goneResponse" {
+			b.Add(r.Method, r.Path, &openapi.Endpoint{})
+		}
+	}
+	return b.Document()
+}
+
+// handlerName returns the name of the function or method named by gin,
+// e.g. handleAppList for github.com/fnproject/fn/api/server.(*Server).handleAppList-fm
+func handlerName(name string) string {
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/api/openapi"
+)
+
+func TestOpenAPI(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	// every route of the API must be described for the document to be of use
+	// to generated clients
+	for _, r := range srv.Router.Routes() {
+		name := handlerName(r.Handler)
+		if !strings.HasPrefix(r.Path, "/v2/") || strings.HasPrefix(r.Path, "/v2/runner/") || name == "goneResponse" {
+			continue
+		}
+		if _, ok := openAPIEndpoints[name]; !ok {
+			t.Errorf("Expected %s %s to be described by openAPIEndpoints, %s is missing", r.Method, r.Path, name)
+		}
+	}
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/openapi.json", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the document, got %d %s", rec.Code, rec.Body.String())
+	}
+	var doc openapi.Document
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != openapi.Version {
+		t.Fatalf("Expected an OpenAPI %s document, got %q", openapi.Version, doc.OpenAPI)
+	}
+
+	item, ok := doc.Paths["/v2/apps/{appID}"]
+	if !ok {
+		t.Fatalf("Expected the path of apps, got %v", doc.Paths)
+	}
+	get := (*item)["get"]
+	if get == nil || get.OperationID != "GetApp" || len(get.Parameters) != 1 || get.Parameters[0].Name != "appID" {
+		t.Fatalf("Expected the operation getting an app, got %+v", get)
+	}
+	if ref := get.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/App" {
+		t.Fatalf("Expected apps to be returned, got %q", ref)
+	}
+	app := doc.Components.Schemas["App"]
+	if app == nil || app.Properties["name"] == nil || app.Properties["created_at"].Format != "date-time" {
+		t.Fatalf("Expected the schema of apps, got %+v", app)
+	}
+	for p := range doc.Paths {
+		if strings.HasPrefix(p, "/v2/runner") {
+			t.Fatalf("Expected the hybrid runner API not to be documented, got %s", p)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handlePlacementAudit returns the placement decisions an lb made for a recent call
+func (s *Server) handlePlacementAudit(c *gin.Context) {
+	audit, ok := s.placementAudit.Get(c.Param("callID"))
+	if !ok {
+		handleErrorResponse(c, models.ErrCallNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, audit)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// rateLimitSweepInterval is how often the state of apps and fns which are
+// back within their limits is dropped
+const rateLimitSweepInterval = time.Minute
+
+// bucket is the token bucket and the number of running invocations of an app
+// or fn on this server
+type bucket struct {
+	tokens  float64
+	last    time.Time
+	running int
+}
+
+func (b *bucket) refill(l *models.RateLimit, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = float64(l.Burst)
+	} else {
+		b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	}
+	b.last = now
+}
+
+// limited is an app or fn whose invocations are limited
+type limited struct {
+	key   string
+	limit *models.RateLimit
+}
+
+// rateLimiter enforces the rate limit annotations of apps and fns, see
+// models.AppRateLimitAnnotation
+type rateLimiter struct {
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket)}
+}
+
+// limitsOf returns the limits that apply to invocations of fn
+func limitsOf(app *models.App, fn *models.Fn) ([]limited, error) {
+	var limits []limited
+	l, err := models.AppRateLimitFromAnnotations(app.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	if l != nil {
+		limits = append(limits, limited{key: "app/" + app.ID, limit: l})
+	}
+	l, err = models.FnRateLimitFromAnnotations(fn.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	if l != nil {
+		limits = append(limits, limited{key: "fn/" + fn.ID, limit: l})
+	}
+	return limits, nil
+}
+
+// acquire admits an invocation of fn, counting it as running if isSync until
+// release is called. Rejected invocations get the rate limit headers set on
+// header and models.ErrRateLimited or models.ErrConcurrencyLimited. Tokens are
+// only taken if all limits admit the invocation.
+func (r *rateLimiter) acquire(header http.Header, app *models.App, fn *models.Fn, isSync bool) (release func(), err error) {
+	limits, err := limitsOf(app, fn)
+	if err != nil || len(limits) == 0 {
+		return func() {}, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	r.sweep(now)
+
+	buckets := make([]*bucket, len(limits))
+	for i, l := range limits {
+		b, ok := r.buckets[l.key]
+		if !ok {
+			b = &bucket{}
+			r.buckets[l.key] = b
+		}
+		buckets[i] = b
+
+		if l.limit.Rate > 0 {
+			b.refill(l.limit, now)
+			if b.tokens < 1 {
+				wait := time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second))
+				setRateLimitHeaders(header, l.limit.Burst, wait)
+				return nil, models.ErrRateLimited
+			}
+		}
+		if isSync && l.limit.MaxConcurrent > 0 && b.running >= l.limit.MaxConcurrent {
+			setRateLimitHeaders(header, l.limit.MaxConcurrent, time.Second)
+			return nil, models.ErrConcurrencyLimited
+		}
+	}
+
+	for i, l := range limits {
+		if l.limit.Rate > 0 {
+			buckets[i].tokens--
+		}
+		if isSync {
+			buckets[i].running++
+		}
+	}
+	if !isSync {
+		return func() {}, nil
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.lock.Lock()
+			for _, b := range buckets {
+				b.running--
+			}
+			r.lock.Unlock()
+		})
+	}, nil
+}
+
+// sweep drops buckets which have nothing running and were not used for a
+// sweep interval, r.lock must be held.
+func (r *rateLimiter) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	r.lastSweep = now
+	for k, b := range r.buckets {
+		// buckets of rates below 1/min may not have refilled, dropping them
+		// only allows one more burst
+		if b.running == 0 && now.Sub(b.last) >= rateLimitSweepInterval {
+			delete(r.buckets, k)
+		}
+	}
+}
+
+// setRateLimitHeaders sets Retry-After and the RateLimit headers of
+// draft-ietf-httpapi-ratelimit-headers on rejected invocations
+func setRateLimitHeaders(header http.Header, limit int, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	header.Set("Retry-After", strconv.Itoa(secs))
+	header.Set("RateLimit-Limit", strconv.Itoa(limit))
+	header.Set("RateLimit-Remaining", "0")
+	header.Set("RateLimit-Reset", strconv.Itoa(secs))
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestRateLimiter(t *testing.T) {
+	app := &models.App{ID: "app", Annotations: models.Annotations{}}
+	fn := &models.Fn{ID: "fn"}
+	fn.Annotations, _ = models.Annotations{}.With(models.FnRateLimitAnnotation, map[string]interface{}{"rate": 0.001, "burst": 3, "max_concurrent": 2})
+	r := newRateLimiter()
+
+	header := http.Header{}
+	release1, err := r.acquire(header, app, fn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2, err := r.acquire(header, app, fn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.acquire(header, app, fn, true); err != models.ErrConcurrencyLimited {
+		t.Fatalf("expected invocations over max_concurrent to be rejected, got %v", err)
+	}
+	if header.Get("Retry-After") == "" || header.Get("RateLimit-Limit") != "2" {
+		t.Fatalf("expected rate limit headers on rejected invocations, got %v", header)
+	}
+	release1()
+	release1() // releasing twice must not free another slot
+	release2()
+
+	// async invocations are not counted as running, the burst of 3 is used up
+	if _, err := r.acquire(http.Header{}, app, fn, false); err != nil {
+		t.Fatal(err)
+	}
+	header = http.Header{}
+	if _, err := r.acquire(header, app, fn, true); err != models.ErrRateLimited {
+		t.Fatalf("expected invocations over the burst to be rate limited, got %v", err)
+	}
+	if header.Get("RateLimit-Limit") != "3" || header.Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("expected rate limit headers on rejected invocations, got %v", header)
+	}
+
+	// the app limit applies to every fn of the app
+	other := &models.Fn{ID: "other"}
+	app.Annotations, _ = app.Annotations.With(models.AppRateLimitAnnotation, map[string]interface{}{"rate": 0.001, "burst": 1})
+	if _, err := r.acquire(http.Header{}, app, other, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.acquire(http.Header{}, app, other, false); err != models.ErrRateLimited {
+		t.Fatalf("expected the app limit to apply to all of its fns, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/auth"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// maxRBACPeekBody is how much of the body of a create request is read to find
+// the app or namespace it creates an object in
+const maxRBACPeekBody = 64 * 1024
+
+// errRBACPeekBody is returned for requests whose body cannot be read to find
+// the app or namespace they target
+var errRBACPeekBody = errors.New("could not read the target of the request from its body")
+
+// roleAuthorizer grants requests the scopes of the roles bound to their
+// subject on the app or namespace they target, see models.RoleBinding. It
+// grants nothing if the datastore does not store role bindings. Identities
+// restricted to apps are granted their own scopes on their apps only.
+//
+// Bindings to an app or namespace, and identities restricted to apps, never
+// grant the endpoints managing credentials and runners of all namespaces, see
+// globalOnly, or their holders could bind themselves roles or make keys
+// beyond their app or namespace.
+type roleAuthorizer struct {
+	s *Server
+}
+
+func (a *roleAuthorizer) Authorize(r *http.Request, id *auth.Identity, scope auth.Scope) (bool, error) {
+	if len(id.AppIDs) > 0 {
+		if globalOnly(r) {
+			return false, nil
+		}
+		return a.authorizeApps(r, id, scope)
+	}
+	if _, ok := a.s.datastore.(models.RoleBindingStore); !ok || id.Subject == "" {
+		return false, nil
+	}
+
+	nsID, appID, err := a.target(r)
+	if err != nil {
+		// only bindings to neither an app nor a namespace cover requests
+		// whose target cannot be found
+		common.Logger(r.Context()).WithError(err).Debug("could not resolve the target of the request")
+		nsID, appID = "", ""
+	}
+	return a.bound(r.Context(), id, scope, nsID, appID, globalOnly(r))
+}
+
+// authorizeApp returns whether id is granted scope on app, directly or
+// through the roles bound to its subject, for handlers finding the app they
+// act on only once their request is decoded
+func (a *roleAuthorizer) authorizeApp(ctx context.Context, id *auth.Identity, scope auth.Scope, app *models.App) (bool, error) {
+	if len(id.AppIDs) > 0 {
+		return appAllowed(id, scope, app.ID), nil
+	}
+	if id.HasScope(scope) {
+		return true, nil
+	}
+	return a.bound(ctx, id, scope, app.NamespaceID, app.ID, false)
+}
+
+// bound returns whether a role bound to the subject of id on the namespace or
+// app given, or on neither, grants scope. Only bindings to neither grant
+// global endpoints.
+func (a *roleAuthorizer) bound(ctx context.Context, id *auth.Identity, scope auth.Scope, nsID, appID string, global bool) (bool, error) {
+	rbs, ok := a.s.datastore.(models.RoleBindingStore)
+	if !ok || id.Subject == "" {
+		return false, nil
+	}
+
+	bindings, err := rbs.GetRoleBindings(ctx, &models.RoleBindingFilter{Subject: id.Subject})
+	if err != nil {
+		return false, err
+	}
+	for _, rb := range bindings.Items {
+		if !rb.Covers(nsID, appID) || (global && (rb.AppID != "" || rb.NamespaceID != "")) {
+			continue
+		}
+		granted := &auth.Identity{Scopes: auth.RoleScopes(rb.Role)}
+		if granted.HasScope(scope) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// globalScopes returns the scopes of the roles bound to the subject of id on
+// neither an app nor a namespace
+func (a *roleAuthorizer) globalScopes(ctx context.Context, id *auth.Identity) ([]auth.Scope, error) {
+	rbs, ok := a.s.datastore.(models.RoleBindingStore)
+	if !ok || id.Subject == "" || len(id.AppIDs) > 0 {
+		return nil, nil
+	}
+	bindings, err := rbs.GetRoleBindings(ctx, &models.RoleBindingFilter{Subject: id.Subject})
+	if err != nil {
+		return nil, err
+	}
+	var scopes []auth.Scope
+	for _, rb := range bindings.Items {
+		if rb.AppID == "" && rb.NamespaceID == "" {
+			scopes = append(scopes, auth.RoleScopes(rb.Role)...)
+		}
+	}
+	return scopes, nil
+}
+
+// globalOnly returns whether r is made to an endpoint managing the role
+// bindings, API keys, audit log or runners of all namespaces
+func globalOnly(r *http.Request) bool {
+	path := r.URL.Path
+	return strings.HasPrefix(path, "/v2/rolebindings") || strings.HasPrefix(path, "/v2/keys") ||
+		path == "/v2/audit" || strings.HasPrefix(path, "/v2/runner/")
+}
+
+func (a *roleAuthorizer) authorizeApps(r *http.Request, id *auth.Identity, scope auth.Scope) (bool, error) {
+	_, appID, err := a.target(r)
+	if err != nil || appID == "" {
+		return false, nil
+	}
+	return appAllowed(id, scope, appID), nil
+}
+
+// appAllowed returns whether id, restricted to apps, is granted scope on appID
+func appAllowed(id *auth.Identity, scope auth.Scope, appID string) bool {
+	if !id.HasScope(scope) {
+		return false
+	}
+	for _, allowed := range id.AppIDs {
+		if allowed == appID {
+			return true
+		}
+	}
+	return false
+}
+
+// target returns the namespace and app a request reads or changes, or the
+// namespace only for requests not bound to an app
+func (a *roleAuthorizer) target(r *http.Request) (nsID, appID string, err error) {
+	ctx := r.Context()
+	ds := a.s.datastore
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	param := func(i int) string {
+		if i < len(path) {
+			return path[i]
+		}
+		return ""
+	}
+
+	// requests creating objects, or invoking fns, in the app or namespace of
+	// their body are not bound to the app or namespace of their query
+	var fromBody bool
+	var ids bodyIDs
+	switch {
+	case param(0) == "invoke" && param(1) == batchInvokePath:
+		if ids, err = peekBody(r, maxBatchInvokeSize); err == nil {
+			appID, err = a.fnApp(r, ids.FnID)
+		}
+	case param(0) == "invoke" && param(1) == fanOutInvokePath:
+		ids, err = peekBody(r, maxBatchInvokeSize)
+		appID, fromBody = ids.AppID, true
+	case param(0) == "invoke" && param(1) == "ws" && param(2) != "":
+		appID, err = a.fnApp(r, param(2))
+	case param(0) == "invoke" && param(1) != "":
+		appID, err = a.fnApp(r, param(1))
+	case (param(0) == "t" || param(0) == "ce") && param(1) != "":
+		appID, err = ds.GetAppID(ctx, param(1))
+	case param(0) == "v2" && param(2) != "":
+		switch param(1) {
+		case "apps":
+			appID = param(2)
+		case "fns":
+			appID, err = a.fnApp(r, param(2))
+		case "triggers":
+			var t *models.Trigger
+			if t, err = ds.GetTriggerByID(ctx, param(2)); err == nil {
+				appID = t.AppID
+			}
+		case "namespaces":
+			return param(2), "", nil
+		}
+	case param(0) == "v2" && param(1) == "apps" && r.URL.Query().Get("name") != "":
+		appID, err = ds.GetAppID(ctx, r.URL.Query().Get("name"))
+	case param(0) == "v2" && r.Method == http.MethodPost:
+		switch param(1) {
+		case "apps":
+			ids, err = peekBody(r, maxRBACPeekBody)
+			nsID, fromBody = ids.NamespaceID, true
+		case "fns", "triggers":
+			ids, err = peekBody(r, maxRBACPeekBody)
+			appID, fromBody = ids.AppID, true
+		}
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if appID == "" && !fromBody {
+		appID = r.URL.Query().Get("app_id")
+	}
+	if appID != "" {
+		app, err := ds.GetAppByID(ctx, appID)
+		if err != nil {
+			return "", "", err
+		}
+		return app.NamespaceID, app.ID, nil
+	}
+
+	if nsID == "" && !fromBody {
+		nsID = r.URL.Query().Get("namespace_id")
+	}
+	if nsID == "" {
+		nsID = r.Header.Get(models.NamespaceHeader)
+	}
+	if nsID == "" {
+		nsID = common.NamespaceIDFromContext(ctx)
+	}
+	return nsID, "", nil
+}
+
+func (a *roleAuthorizer) fnApp(r *http.Request, fnID string) (string, error) {
+	fn, err := a.s.datastore.GetFnByID(r.Context(), fnID)
+	if err != nil {
+		return "", err
+	}
+	return fn.AppID, nil
+}
+
+// bodyIDs are the ids of the app, fn or namespace the body of a request
+// targets
+type bodyIDs struct {
+	AppID       string `json:"app_id"`
+	FnID        string `json:"fn_id"`
+	NamespaceID string `json:"namespace_id"`
+}
+
+// peekBody decodes the ids of the body of r as its handler does, from the
+// first JSON value of the body, which is left to be read again. It fails for
+// bodies which do not decode or are longer than limit, whose target the
+// handler may find elsewhere than in what was read.
+func peekBody(r *http.Request, limit int64) (ids bodyIDs, err error) {
+	if r.Body == nil {
+		return ids, errRBACPeekBody
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), r.Body), r.Body}
+	if err != nil || int64(len(b)) > limit {
+		return ids, errRBACPeekBody
+	}
+	if err := json.NewDecoder(bytes.NewReader(b)).Decode(&ids); err != nil {
+		return ids, errRBACPeekBody
+	}
+	return ids, nil
+}
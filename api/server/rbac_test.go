@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/auth"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestRoleBindings(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	keys, err := auth.NewStaticKeys([]auth.StaticKey{
+		{Key: "admin-key", Subject: "admin", Scopes: []string{"admin"}},
+		{Key: "dev-key", Subject: "dev"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mine := &models.App{ID: "app_mine", Name: "mine"}
+	other := &models.App{ID: "app_other", Name: "other"}
+	ds := datastore.NewMockInit([]*models.App{mine, other})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAuth(keys))
+
+	request := func(key, method, path, body string) (int, string) {
+		req := createRequest(t, method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		_, rec := routerRequest2(t, srv.Router, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	// the developer holds no scopes until bound to a role
+	if code, _ := request("dev-key", http.MethodGet, "/v2/apps/"+mine.ID, ``); code != http.StatusForbidden {
+		t.Fatalf("Expected an unbound subject to be forbidden, got %d", code)
+	}
+	code, body := request("dev-key", http.MethodPost, "/v2/rolebindings", `{"subject": "dev", "role": "developer", "app_id": "`+mine.ID+`"}`)
+	if code != http.StatusForbidden {
+		t.Fatalf("Expected role bindings to need the admin scope, got %d %s", code, body)
+	}
+	code, body = request("admin-key", http.MethodPost, "/v2/rolebindings", `{"subject": "dev", "role": "developer", "app_id": "`+mine.ID+`"}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected the binding to be created, got %d %s", code, body)
+	}
+	var rb models.RoleBinding
+	if err := json.Unmarshal([]byte(body), &rb); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodGet, "/v2/apps/" + mine.ID, ``, http.StatusOK},
+		{http.MethodGet, "/v2/apps/" + other.ID, ``, http.StatusForbidden},
+		{http.MethodGet, "/v2/fns?app_id=" + mine.ID, ``, http.StatusOK},
+		{http.MethodGet, "/v2/apps", ``, http.StatusForbidden},
+		{http.MethodPost, "/v2/fns", `{"app_id": "` + mine.ID + `", "name": "f", "image": "fnproject/hello"}`, http.StatusOK},
+		{http.MethodPost, "/v2/fns", `{"app_id": "` + other.ID + `", "name": "f", "image": "fnproject/hello"}`, http.StatusForbidden},
+		// the app of the body is the one the fn is created in, whatever the query
+		{http.MethodPost, "/v2/fns?app_id=" + mine.ID, `{"app_id": "` + other.ID + `", "name": "g", "image": "fnproject/hello"} x`, http.StatusForbidden},
+		{http.MethodPost, "/v2/fns?app_id=" + mine.ID, `{"app_id": "` + other.ID + `", "name": "g", "image": "fnproject/hello", "config": {"pad": "` + strings.Repeat("a", maxRBACPeekBody) + `"}}`, http.StatusForbidden},
+		{http.MethodPost, "/v2/fns?app_id=" + mine.ID, `{"name": "g", "image": "fnproject/hello"}`, http.StatusForbidden},
+		{http.MethodPost, "/v2/namespaces", `{"name": "ns"}`, http.StatusForbidden},
+		{http.MethodGet, "/v2/rolebindings", ``, http.StatusForbidden},
+	} {
+		if code, body := request("dev-key", test.method, test.path, test.body); code != test.code {
+			t.Errorf("Test %d: expected status %d for %s %s, got %d %s", i, test.code, test.method, test.path, code, body)
+		}
+	}
+
+	code, body = request("admin-key", http.MethodGet, "/v2/rolebindings?subject=dev", ``)
+	var list models.RoleBindingList
+	if err := json.Unmarshal([]byte(body), &list); err != nil || code != http.StatusOK {
+		t.Fatalf("Expected the bindings of the developer, got %d %s", code, body)
+	}
+	if len(list.Items) != 1 || list.Items[0].ID != rb.ID || list.Items[0].AppID != mine.ID {
+		t.Fatalf("Expected the binding to the app, got %+v", list.Items)
+	}
+
+	if code, body := request("admin-key", http.MethodPost, "/v2/rolebindings", `{"subject": "dev", "role": "owner"}`); code != http.StatusBadRequest {
+		t.Fatalf("Expected an unknown role to be rejected, got %d %s", code, body)
+	}
+
+	if code, body := request("admin-key", http.MethodDelete, "/v2/rolebindings/"+rb.ID, ``); code != http.StatusNoContent {
+		t.Fatalf("Expected the binding to be removed, got %d %s", code, body)
+	}
+	if code, _ := request("dev-key", http.MethodGet, "/v2/apps/"+mine.ID, ``); code != http.StatusForbidden {
+		t.Fatalf("Expected the removed binding to grant nothing, got %d", code)
+	}
+}
+
+func TestRoleBindingsDoNotEscalate(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	keys, err := auth.NewStaticKeys([]auth.StaticKey{
+		{Key: "admin-key", Subject: "admin", Scopes: []string{"admin"}},
+		{Key: "app-admin-key", Subject: "app-admin"},
+		{Key: "ns-admin-key", Subject: "ns-admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mine := &models.App{ID: "app_mine", Name: "mine"}
+	ds := datastore.NewMockInit([]*models.App{mine})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAuth(keys))
+
+	request := func(key, method, path, body string) (int, string) {
+		req := createRequest(t, method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		_, rec := routerRequest2(t, srv.Router, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := request("admin-key", http.MethodPost, "/v2/namespaces", `{"name": "ns"}`)
+	var ns models.Namespace
+	if err := json.Unmarshal([]byte(body), &ns); err != nil || code != http.StatusOK {
+		t.Fatalf("Expected the namespace to be created, got %d %s", code, body)
+	}
+	for _, rb := range []string{
+		`{"subject": "app-admin", "role": "admin", "app_id": "` + mine.ID + `"}`,
+		`{"subject": "ns-admin", "role": "admin", "namespace_id": "` + ns.ID + `"}`,
+	} {
+		if code, body := request("admin-key", http.MethodPost, "/v2/rolebindings", rb); code != http.StatusOK {
+			t.Fatalf("Expected the binding to be created, got %d %s", code, body)
+		}
+	}
+
+	for i, test := range []struct {
+		key, method, path, body string
+		code                    int
+	}{
+		// admins of an app or namespace manage what is in it
+		{"app-admin-key", http.MethodGet, "/v2/apps/" + mine.ID, ``, http.StatusOK},
+		{"ns-admin-key", http.MethodGet, "/v2/namespaces/" + ns.ID, ``, http.StatusOK},
+		// but not the credentials of all namespaces
+		{"app-admin-key", http.MethodPost, "/v2/rolebindings?app_id=" + mine.ID, `{"subject": "app-admin", "role": "admin"}`, http.StatusForbidden},
+		{"ns-admin-key", http.MethodPost, "/v2/rolebindings?namespace_id=" + ns.ID, `{"subject": "ns-admin", "role": "admin"}`, http.StatusForbidden},
+		{"ns-admin-key", http.MethodPost, "/v2/rolebindings", `{"subject": "ns-admin", "role": "admin", "namespace_id": "` + ns.ID + `"}`, http.StatusForbidden},
+		{"app-admin-key", http.MethodGet, "/v2/rolebindings?app_id=" + mine.ID, ``, http.StatusForbidden},
+		{"app-admin-key", http.MethodPost, "/v2/keys?app_id=" + mine.ID, `{"name": "k", "scopes": ["write"]}`, http.StatusForbidden},
+		{"ns-admin-key", http.MethodPost, "/v2/keys?namespace_id=" + ns.ID, `{"name": "k", "scopes": ["write"]}`, http.StatusForbidden},
+		{"ns-admin-key", http.MethodGet, "/v2/audit?namespace_id=" + ns.ID, ``, http.StatusForbidden},
+	} {
+		if code, body := request(test.key, test.method, test.path, test.body); code != test.code {
+			t.Errorf("Test %d: expected status %d for %s %s, got %d %s", i, test.code, test.method, test.path, code, body)
+		}
+	}
+
+	code, body = request("admin-key", http.MethodGet, "/v2/rolebindings", ``)
+	var list models.RoleBindingList
+	if err := json.Unmarshal([]byte(body), &list); err != nil || code != http.StatusOK {
+		t.Fatalf("Expected the bindings, got %d %s", code, body)
+	}
+	if len(list.Items) != 2 {
+		t.Fatalf("Expected no bindings to have been made by the admins of the app and namespace, got %+v", list.Items)
+	}
+}
+
+func TestRoleAuthorizerTarget(t *testing.T) {
+	mine := &models.App{ID: "app_mine", Name: "mine", NamespaceID: "ns"}
+	other := &models.App{ID: "app_other", Name: "other"}
+	fn := &models.Fn{ID: "fn_mine", AppID: mine.ID}
+	ds := datastore.NewMockInit([]*models.App{mine, other}, []*models.Fn{fn})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+	a := &roleAuthorizer{srv}
+
+	for i, test := range []struct {
+		method, path, body string
+		nsID, appID        string
+		err                bool
+	}{
+		{http.MethodPost, "/invoke/" + fn.ID, ``, "ns", mine.ID, false},
+		{http.MethodGet, "/invoke/ws/" + fn.ID, ``, "ns", mine.ID, false},
+		{http.MethodPost, "/t/" + mine.Name + "/hello", ``, "ns", mine.ID, false},
+		{http.MethodPost, "/ce/" + mine.Name, ``, "ns", mine.ID, false},
+		{http.MethodPost, "/invoke/batch", `{"fn_id": "` + fn.ID + `", "items": []}`, "ns", mine.ID, false},
+		{http.MethodPost, "/invoke/fanout?app_id=" + mine.ID, `{"app_id": "` + other.ID + `"}`, "", other.ID, false},
+		{http.MethodPost, "/invoke/fanout?app_id=" + mine.ID, `{"app_id": "` + mine.ID + `"`, "", "", true},
+		{http.MethodPost, "/v2/fns?app_id=" + mine.ID, `{"app_id": "` + other.ID + `"} x`, "", other.ID, false},
+		{http.MethodPost, "/v2/fns?app_id=" + mine.ID, `{"name": "f"}`, "", "", false},
+		{http.MethodPost, "/v2/fns?app_id=" + mine.ID, `x`, "", "", true},
+		{http.MethodPost, "/v2/triggers?app_id=" + mine.ID, `{"app_id": "` + other.ID + `", "source": "` + strings.Repeat("a", maxRBACPeekBody) + `"}`, "", "", true},
+		{http.MethodPost, "/v2/apps?namespace_id=ns", `{"name": "a"}`, "", "", false},
+		{http.MethodGet, "/v2/fns?app_id=" + mine.ID, ``, "ns", mine.ID, false},
+	} {
+		req := createRequest(t, test.method, test.path, strings.NewReader(test.body))
+		nsID, appID, err := a.target(req)
+		if (err != nil) != test.err || nsID != test.nsID || appID != test.appID {
+			t.Errorf("Test %d: expected %s %s to target %q %q (error %v), got %q %q %v", i, test.method, test.path, test.nsID, test.appID, test.err, nsID, appID, err)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	pool "github.com/fnproject/fn/api/runnerpool"
+	"github.com/sirupsen/logrus"
+)
+
+// how often the reload file is checked for changes
+const reloadPollInterval = 5 * time.Second
+
+// reloadSetting is a group of settings applied together when any of them
+// changes, by reading them from env
+type reloadSetting struct {
+	keys  []string
+	apply func(ctx context.Context, s *Server) error
+}
+
+// reloadSettings are the settings of a server that can be changed without a
+// restart, by the reload file
+var reloadSettings = []reloadSetting{
+	{keys: []string{EnvLogLevel, EnvLogComponentLevels}, apply: reloadLogLevel},
+	{keys: []string{EnvRunnerAddresses}, apply: reloadRunnerAddresses},
+	{keys: []string{EnvLBPlacementAlg, EnvLBBinPackTarget, EnvLBZone, EnvLBZoneSpillWait}, apply: reloadPlacer},
+	{keys: []string{agent.EnvPreForkLowWater, agent.EnvPreForkHighWater}, apply: reloadPreForkWaterMarks},
+}
+
+// configChange is a setting changed by a reload
+type configChange struct {
+	key, old, new string
+	wasSet        bool
+}
+
+// WithReloadFile maps EnvReloadFile, the settings of the file are read
+// before the options of the server are set by NewFromEnv
+func WithReloadFile(path string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.reloadFile = path
+		return nil
+	}
+}
+
+// parseReloadFile returns the settings of a reload file, which has a
+// FN_NAME=value setting on each line. Empty lines and lines starting with #
+// are ignored.
+func parseReloadFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%s:%d: expected FN_NAME=value", path, n)
+		}
+		key := strings.TrimSpace(line[:i])
+		if reloadSettingOf(key) == nil {
+			return nil, fmt.Errorf("%s:%d: %s cannot be reloaded", path, n, key)
+		}
+		settings[key] = strings.TrimSpace(line[i+1:])
+	}
+	return settings, scanner.Err()
+}
+
+func reloadSettingOf(key string) *reloadSetting {
+	for i := range reloadSettings {
+		for _, k := range reloadSettings[i].keys {
+			if k == key {
+				return &reloadSettings[i]
+			}
+		}
+	}
+	return nil
+}
+
+// loadReloadFile sets the env of the settings of the reload file at path and
+// returns those that changed. Settings no longer in the file keep their value.
+func loadReloadFile(ctx context.Context, path string) ([]configChange, error) {
+	settings, err := parseReloadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []configChange
+	for _, setting := range reloadSettings {
+		for _, key := range setting.keys {
+			value, ok := settings[key]
+			old, wasSet := os.LookupEnv(key)
+			if !ok || (wasSet && value == old) {
+				continue
+			}
+			changes = append(changes, configChange{key: key, old: old, new: value, wasSet: wasSet})
+			if err := os.Setenv(key, value); err != nil {
+				return changes, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// reload applies the settings of the reload file that changed, a setting that
+// cannot be applied is set back to its previous value. Every change applied
+// is logged as an audit entry.
+func (s *Server) reload(ctx context.Context) {
+	log := common.Logger(ctx).WithField("file", s.reloadFile)
+
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	changes, err := loadReloadFile(ctx, s.reloadFile)
+	if err != nil {
+		log.WithError(err).Error("cannot reload configuration")
+	}
+
+	for i := range reloadSettings {
+		setting := &reloadSettings[i]
+		var changed []configChange
+		for _, c := range changes {
+			if reloadSettingOf(c.key) == setting {
+				changed = append(changed, c)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		err := setting.apply(ctx, s)
+		for _, c := range changed {
+			entry := log.WithFields(logrus.Fields{"audit": "config", "setting": c.key, "old": c.old, "new": c.new})
+			if err != nil {
+				c.restore()
+				entry.WithError(err).Error("Rejected configuration change")
+			} else {
+				entry.Info("Applied configuration change")
+			}
+		}
+	}
+}
+
+// watchReloadFile reloads the reload file on SIGHUP and when its modification
+// time changes, until ctx is done
+func (s *Server) watchReloadFile(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(reloadPollInterval)
+	defer ticker.Stop()
+
+	var modTime time.Time
+	if fi, err := os.Stat(s.reloadFile); err == nil {
+		modTime = fi.ModTime()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			s.reload(ctx)
+		case <-ticker.C:
+			fi, err := os.Stat(s.reloadFile)
+			if err != nil || fi.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = fi.ModTime()
+			s.reload(ctx)
+		}
+	}
+}
+
+func reloadLogLevel(ctx context.Context, s *Server) error {
+	common.SetLogLevel(getEnv(EnvLogLevel, DefaultLogLevel))
+	return common.SetComponentLogLevels(getEnv(EnvLogComponentLevels, ""))
+}
+
+func reloadRunnerAddresses(ctx context.Context, s *Server) error {
+	setter, ok := pool.BasePool(s.runnerPool).(pool.RunnerSetter)
+	if !ok {
+		return errors.New("runner addresses are only used by lb nodes with static runner discovery")
+	}
+	addresses := getEnv(EnvRunnerAddresses, "")
+	if addresses == "" {
+		return errors.New("an lb needs at least one runner address")
+	}
+	setter.SetRunners(ctx, strings.Split(addresses, ","))
+	return nil
+}
+
+func reloadPlacer(ctx context.Context, s *Server) error {
+	if s.placer == nil {
+		return errors.New("the placement algorithm is only used by lb nodes")
+	}
+	for _, key := range []string{EnvLBBinPackTarget, EnvLBZoneSpillWait} {
+		if v := getEnv(key, ""); v != "" {
+			if _, err := strconv.Atoi(v); err != nil {
+				return fmt.Errorf("invalid %s: %v", key, err)
+			}
+		}
+	}
+	placer, alg := newPlacer(&s.placerCfg)
+	s.placer.Set(placer)
+	s.placerAlg = alg
+	return nil
+}
+
+func reloadPreForkWaterMarks(ctx context.Context, s *Server) error {
+	tuner, ok := s.agent.(agent.PreForkTuner)
+	if !ok {
+		return errors.New("the pre-fork pool is only used by the agents of full and pure runner nodes")
+	}
+	var marks [2]uint64
+	for i, key := range []string{agent.EnvPreForkLowWater, agent.EnvPreForkHighWater} {
+		if v := getEnv(key, ""); v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", key, err)
+			}
+			marks[i] = n
+		}
+	}
+	return tuner.SetPreForkWaterMarks(marks[0], marks[1])
+}
+
+// restore sets the env of the setting back to its value before the change
+func (c configChange) restore() {
+	if c.wasSet {
+		os.Setenv(c.key, c.old)
+	} else {
+		os.Unsetenv(c.key)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	pool "github.com/fnproject/fn/api/runnerpool"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseReloadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fn.env")
+
+	writeTestFile(t, path, "# lb settings\n\nFN_PLACER = ch\nFN_RUNNER_ADDRESSES=a:9190,b:9190\n")
+	settings, err := parseReloadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to parse reload file %v", err)
+	}
+	if len(settings) != 2 || settings[EnvLBPlacementAlg] != "ch" || settings[EnvRunnerAddresses] != "a:9190,b:9190" {
+		t.Fatalf("Unexpected settings %v", settings)
+	}
+
+	for _, content := range []string{"FN_PLACER\n", "FN_DB_URL=sqlite3:///tmp/fn.db\n"} {
+		writeTestFile(t, path, content)
+		if _, err := parseReloadFile(path); err == nil {
+			t.Fatalf("Expected an error parsing %q", content)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, key := range []string{EnvLBPlacementAlg, EnvRunnerAddresses} {
+		defer envTweaker(key, "")()
+		os.Unsetenv(key)
+	}
+
+	cfg := pool.NewPlacerConfig()
+	s := &Server{
+		reloadFile: filepath.Join(dir, "fn.env"),
+		placer:     pool.NewSwitchPlacer(pool.NewNaivePlacer(&cfg)),
+		placerCfg:  cfg,
+		placerAlg:  "naive",
+	}
+
+	// the runners of an lb without static discovery cannot be changed
+	writeTestFile(t, s.reloadFile, "FN_PLACER=ch\nFN_RUNNER_ADDRESSES=a:9190\n")
+	s.reload(context.Background())
+	if s.placerAlg != "ch" || os.Getenv(EnvLBPlacementAlg) != "ch" {
+		t.Fatalf("Expected the placer to be reloaded, got %v", s.placerAlg)
+	}
+	if _, ok := os.LookupEnv(EnvRunnerAddresses); ok {
+		t.Fatal("Expected the rejected runner addresses to be set back")
+	}
+}
+
+type preForkAgent struct {
+	agent.Agent
+	low, high uint64
+}
+
+func (a *preForkAgent) SetPreForkWaterMarks(low, high uint64) error {
+	a.low, a.high = low, high
+	return nil
+}
+
+func TestReloadPreForkWaterMarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, key := range []string{agent.EnvPreForkLowWater, agent.EnvPreForkHighWater} {
+		defer envTweaker(key, "")()
+		os.Unsetenv(key)
+	}
+
+	a := &preForkAgent{}
+	s := &Server{reloadFile: filepath.Join(dir, "fn.env"), agent: a}
+
+	writeTestFile(t, s.reloadFile, "FN_EXPERIMENTAL_PREFORK_LOW_WATER=2\nFN_EXPERIMENTAL_PREFORK_HIGH_WATER=6\n")
+	s.reload(context.Background())
+	if a.low != 2 || a.high != 6 {
+		t.Fatalf("Expected the water marks to be reloaded, got %d/%d", a.low, a.high)
+	}
+
+	writeTestFile(t, s.reloadFile, "FN_EXPERIMENTAL_PREFORK_LOW_WATER=2\nFN_EXPERIMENTAL_PREFORK_HIGH_WATER=lots\n")
+	s.reload(context.Background())
+	if a.low != 2 || a.high != 6 || os.Getenv(agent.EnvPreForkHighWater) != "6" {
+		t.Fatalf("Expected the invalid water mark to be set back, got %d/%d", a.low, a.high)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/respcache"
+)
+
+// cachedResponse is the response cache lookup of an invocation, key is empty
+// if the response of the invocation is not cached
+type cachedResponse struct {
+	key    string
+	policy *models.ResponseCachePolicy
+}
+
+// lookupResponseCache returns the cached response of a sync invocation of a
+// fn with a response cache, reading the body of req to key it and leaving
+// req with an equal body.
+func (s *Server) lookupResponseCache(req *http.Request, fn *models.Fn) (*cachedResponse, *respcache.Entry, error) {
+	if s.responseCache == nil {
+		return nil, nil, nil
+	}
+	policy, err := models.ResponseCacheFromAnnotations(fn.Annotations)
+	if policy == nil || err != nil {
+		return nil, nil, err
+	}
+	switch req.Header.Get("Fn-Invoke-Type") {
+	case models.TypeAsync, models.TypeDetached:
+		return nil, nil, nil
+	}
+
+	h := sha256.New()
+	io.WriteString(h, fn.ID+"\n"+req.Method+"\n"+req.URL.RequestURI()+"\n")
+	for _, name := range policy.Vary {
+		io.WriteString(h, name+":"+strings.Join(req.Header[name], ",")+"\n")
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	c := &cachedResponse{key: hex.EncodeToString(h.Sum(nil)), policy: policy}
+
+	if strings.Contains(req.Header.Get("Cache-Control"), "no-cache") {
+		return c, nil, nil
+	}
+	entry, err := s.responseCache.Get(req.Context(), c.key)
+	if err != nil {
+		// the fn still serves the request
+		common.Logger(req.Context()).WithError(err).Error("error reading response cache")
+		return c, nil, nil
+	}
+	return c, entry, nil
+}
+
+// store caches the response of an invocation if it succeeded
+func (c *cachedResponse) store(ctx context.Context, store respcache.Store, status int, header http.Header, body []byte) {
+	if status != http.StatusOK {
+		return
+	}
+	entry := &respcache.Entry{
+		Status: status,
+		Header: make(http.Header, len(header)),
+		Body:   append([]byte(nil), body...),
+	}
+	for k, v := range header {
+		if k == "Fn-Call-Id" || k == models.ResponseCacheHeader {
+			continue
+		}
+		entry.Header[k] = append([]string(nil), v...)
+	}
+	if err := store.Set(ctx, c.key, entry, time.Duration(c.policy.TTLSeconds)*time.Second); err != nil {
+		common.Logger(ctx).WithError(err).Error("error writing response cache")
+	}
+}
+
+func writeCachedResponse(resp http.ResponseWriter, entry *respcache.Entry) {
+	for k, v := range entry.Header {
+		resp.Header()[k] = v
+	}
+	resp.Header().Set("Content-Length", strconv.Itoa(len(entry.Body)))
+	resp.Header().Set(models.ResponseCacheHeader, "HIT")
+	resp.WriteHeader(entry.Status)
+	resp.Write(entry.Body)
+}
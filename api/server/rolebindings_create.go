@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleRoleBindingCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rb := &models.RoleBinding{}
+
+	err := c.BindJSON(rb)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	if rb.ID != "" {
+		handleErrorResponse(c, models.ErrRoleBindingIDProvided)
+		return
+	}
+
+	rbs, err := s.roleBindingStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	// bindings to an app belong to its namespace, so that they are listed
+	// and removed with it
+	if rb.AppID != "" {
+		app, err := s.datastore.GetAppByID(ctx, rb.AppID)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		rb.NamespaceID = app.NamespaceID
+	} else if rb.NamespaceID != "" {
+		nss, err := s.namespaceStore()
+		if err == nil {
+			_, err = nss.GetNamespaceByID(ctx, rb.NamespaceID)
+		}
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	rb, err = rbs.InsertRoleBinding(ctx, rb)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rb)
+}
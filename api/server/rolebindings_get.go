@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleRoleBindingGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rbs, err := s.roleBindingStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	rb, err := rbs.GetRoleBindingByID(ctx, c.Param(api.ParamRoleBindingID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rb)
+}
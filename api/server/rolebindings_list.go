@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleRoleBindingList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.RoleBindingFilter{}
+
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	filter.Subject = c.Query("subject")
+	filter.NamespaceID = c.Query("namespace_id")
+	filter.AppID = c.Query("app_id")
+
+	rbs, err := s.roleBindingStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	bindings, err := rbs.GetRoleBindings(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bindings)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/cloudevents"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// cloudEventsClient forwards the events returned by fns to their sink
+var cloudEventsClient = &http.Client{Timeout: 30 * time.Second}
+
+// handleCloudEventCall executes the fn bound to a CloudEvent, for router handlers
+func (s *Server) handleCloudEventCall(c *gin.Context) {
+	err := s.handleCloudEventCall2(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+	}
+}
+
+// handleCloudEventCall2 reads the CloudEvent posted to the endpoint of an app,
+// in structured or binary mode, and invokes the fn of the cloudevent trigger of
+// the app bound to its type and source with the event in binary mode.
+func (s *Server) handleCloudEventCall2(c *gin.Context) error {
+	ctx := c.Request.Context()
+
+	appID, err := s.lbReadAccess.GetAppID(ctx, c.Param(api.ParamAppName))
+	if err != nil {
+		return err
+	}
+	app, err := s.lbReadAccess.GetAppByID(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	event, err := cloudevents.Read(c.Request.Header, c.Request.Body)
+	if err != nil {
+		return models.NewAPIError(http.StatusBadRequest, err)
+	}
+
+	trigger, err := s.lbReadAccess.GetTriggerBySource(ctx, appID, models.TriggerTypeCloudEvent, "/"+event.Type)
+	if err != nil {
+		return err
+	}
+	binding, err := models.CloudEventBindingFromAnnotations(trigger.Annotations)
+	if err != nil {
+		return err
+	}
+	if !binding.Matches(event.Source) {
+		return models.ErrTriggerNotFound
+	}
+
+	fn, err := s.lbReadAccess.GetFnByID(ctx, trigger.FnID)
+	if err != nil {
+		return err
+	}
+
+	// the fn gets the event in binary mode, its data as the body
+	req := c.Request
+	header := make(http.Header)
+	event.WriteBinary(header)
+	header.Set("Fn-Intent", "cloudevent")
+	if t := req.Header.Get("Fn-Invoke-Type"); t != "" {
+		header.Set("Fn-Invoke-Type", t)
+	}
+	req.Header = header
+	req.Body = ioutil.NopCloser(bytes.NewReader(event.Data))
+	req.ContentLength = int64(len(event.Data))
+
+	buf := new(bytes.Buffer)
+	rw := &syncResponseWriter{headers: make(http.Header), status: http.StatusOK, Buffer: buf}
+	if err := s.fnInvoke(rw, req, app, fn, trigger); err != nil {
+		copyHeader(c.Writer.Header(), rw.Header())
+		return err
+	}
+
+	if cloudevents.IsEvent(rw.Header()) && rw.Status() < 300 {
+		reply, err := cloudevents.Read(rw.Header(), buf)
+		if err != nil {
+			return models.NewAPIError(http.StatusBadGateway, fmt.Errorf("fn returned an invalid CloudEvent: %v", err))
+		}
+		sink := binding.Sink
+		if sink == "" {
+			sink = s.cloudEventsSink
+		}
+		if sink != "" {
+			if err := cloudevents.Send(ctx, cloudEventsClient, sink, reply); err != nil {
+				common.Logger(ctx).WithError(err).WithField("sink", sink).Error("error forwarding CloudEvent")
+				return models.NewAPIError(http.StatusBadGateway, err)
+			}
+			c.Header("Fn-Call-Id", rw.Header().Get("Fn-Call-Id"))
+			c.Status(http.StatusAccepted)
+			return nil
+		}
+		// without a sink the event is the reply, in binary mode
+		header := make(http.Header)
+		reply.WriteBinary(header)
+		header.Set("Fn-Call-Id", rw.Header().Get("Fn-Call-Id"))
+		header.Set("Content-Length", strconv.Itoa(len(reply.Data)))
+		copyHeader(c.Writer.Header(), header)
+		c.Writer.WriteHeader(rw.Status())
+		c.Writer.Write(reply.Data)
+		return nil
+	}
+
+	copyHeader(c.Writer.Header(), rw.Header())
+	c.Writer.WriteHeader(rw.Status())
+	io.Copy(c.Writer, buf)
+	return nil
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, vs := range src {
+		dst[k] = vs
+	}
+}
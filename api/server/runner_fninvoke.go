@@ -2,17 +2,19 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/fnproject/fn/api"
 	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/secrets"
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -45,7 +47,15 @@ func (s *syncResponseWriter) Status() int          { return s.status }
 // handleFnInvokeCall executes the function, for router handlers
 func (s *Server) handleFnInvokeCall(c *gin.Context) {
 	fnID := c.Param(api.ParamFnID)
-	ctx, _ := common.LoggerWithFields(c.Request.Context(), logrus.Fields{"fn_id": fnID})
+	switch fnID {
+	case batchInvokePath:
+		s.handleFnInvokeBatch(c)
+		return
+	case fanOutInvokePath:
+		s.handleFnInvokeFanOut(c)
+		return
+	}
+	ctx, _ := common.LoggerWithCall(c.Request.Context(), "", fnID, "")
 	c.Request = c.Request.WithContext(ctx)
 	err := s.handleFnInvokeCall2(c)
 	if err != nil {
@@ -73,7 +83,150 @@ func (s *Server) ServeFnInvoke(c *gin.Context, app *models.App, fn *models.Fn) e
 	return s.fnInvoke(c.Writer, c.Request, app, fn, nil)
 }
 
+// authorizeInvoke authorizes req by the invoke policy of a fn of the app
+// appID, resolving the key of signature policies from the secrets of the app.
+// Servers without the secrets of apps, such as lbs reading them from an API
+// server, do not authorize signed invocations. Signatures are remembered in
+// replays, nil to only check them.
+func (s *Server) authorizeInvoke(req *http.Request, appID string, policy *models.InvokePolicy, replays models.ReplayFilter) error {
+	if policy.Type == models.InvokePolicySignature {
+		ss, err := s.secretStore()
+		if err == nil && s.secretsKeeper == nil {
+			err = models.ErrSecretsNoKMS
+		}
+		var secret []byte
+		if err == nil {
+			secret, err = secrets.NewSource(ss, s.secretsKeeper).GetAppSecret(req.Context(), appID, policy.SecretName)
+		}
+		if err != nil {
+			common.Logger(req.Context()).WithError(err).WithField("secret", policy.SecretName).Error("could not get the key of the invoke policy")
+			return models.ErrInvokeNotAuthorized
+		}
+		policy.Secret = secret
+	}
+	return policy.Authorize(req, time.Now(), replays)
+}
+
+// authorizeFnInvoke authorizes req to invoke fn, through trig if not nil.
+// Requests through triggers must satisfy the policies of both the fn and the
+// trigger.
+func (s *Server) authorizeFnInvoke(req *http.Request, fn *models.Fn, trig *models.Trigger) error {
+	policy, err := models.InvokePolicyFromAnnotations(fn.Annotations)
+	if err != nil {
+		return err
+	}
+	trigPolicy := &models.InvokePolicy{Type: models.InvokePolicyPublic}
+	if trig != nil {
+		trigPolicy, err = models.TriggerInvokePolicyFromAnnotations(trig.Annotations)
+		if err != nil {
+			return err
+		}
+	}
+	// signed bodies are read up to the request size limit of the fn
+	maxRequest, _, err := models.SizeLimitsFromAnnotations(fn.Annotations)
+	if err != nil {
+		return err
+	}
+	policy.MaxBodySize, trigPolicy.MaxBodySize = maxRequest, maxRequest
+
+	// a signature satisfying both is only remembered once, by the check of
+	// the trigger, or it would be a replay of itself
+	fnReplays := models.ReplayFilter(s.invokeReplays)
+	if trigPolicy.Type == models.InvokePolicySignature {
+		fnReplays = nil
+	}
+	if err := s.authorizeInvoke(req, fn.AppID, policy, fnReplays); err != nil {
+		return err
+	}
+	return s.authorizeInvoke(req, fn.AppID, trigPolicy, s.invokeReplays)
+}
+
 func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger) error {
+	if err := s.authorizeFnInvoke(req, fn, trig); err != nil {
+		return err
+	}
+	// tokens are not passed on to the fn
+	req.Header.Del(models.InvokeTokenHeader)
+
+	// invocations may run a past revision of the fn, pinned or by its traffic split
+	fn, err := s.fnRevision(req, fn)
+	if err != nil {
+		return err
+	}
+
+	// identical requests to fns with a response cache get the cached response
+	cached, entry, err := s.lookupResponseCache(req, fn)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		writeCachedResponse(resp, entry)
+		return nil
+	}
+
+	// requests with an idempotency key already seen in the window get the stored response
+	var idem *idempotentResponse
+	if key := req.Header.Get(models.IdempotencyKeyHeader); key != "" && s.idempotency != nil {
+		for idem == nil {
+			r, owner := s.idempotency.begin(fn.ID + "/" + key)
+			if owner {
+				idem = r
+				break
+			}
+			replayed, err := r.replay(req.Context(), resp)
+			if replayed || err != nil {
+				return err
+			}
+			// the first call failed, execute this one instead
+		}
+		defer func() {
+			if idem != nil {
+				s.idempotency.abort(idem)
+			}
+		}()
+	}
+
+	// invocations over the rate limits of their app or fn are rejected before
+	// they take a slot, sync ones count against max_concurrent until they return
+	release, err := s.rateLimits.acquire(resp.Header(), app, fn, req.Header.Get("Fn-Invoke-Type") != models.TypeAsync)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if req.Header.Get("Fn-Invoke-Type") == models.TypeAsync {
+		call, err := s.enqueueAsync(req, app, fn, trig)
+		if err != nil {
+			return err
+		}
+		resp.Header().Set("Fn-Call-Id", call.ID)
+		if idem != nil {
+			s.idempotency.complete(idem, http.StatusAccepted, resp.Header(), nil)
+			idem = nil
+		}
+		resp.WriteHeader(http.StatusAccepted)
+		return nil
+	}
+
+	// the client waits for sync calls only until its deadline, if it set one
+	if req.Header.Get("Fn-Invoke-Type") != models.TypeDetached {
+		var cancel context.CancelFunc
+		req, cancel, err = withClientDeadline(req)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+	}
+
+	stream, err := models.StreamResponseFromAnnotations(fn.Annotations)
+	if err != nil {
+		return err
+	}
+	if stream && req.Header.Get("Fn-Invoke-Type") != models.TypeDetached {
+		// streamed responses are neither cached nor kept for idempotency keys
+		return s.fnInvokeStreaming(resp, req, app, fn, trig)
+	}
+
 	// TODO: we should get rid of the buffers, and stream back (saves memory (+splice), faster (splice), allows streaming, don't have to cap resp size)
 	// buffer the response before writing it out to client to prevent partials from trying to stream
 	buf := bufPool.Get().(*bytes.Buffer)
@@ -99,13 +252,27 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 
 	err = s.agent.Submit(call)
 	if err != nil {
-		return err
+		return deadlineError(req, err)
 	}
 
 	// because we can...
 	writer.Header().Set("Content-Length", strconv.Itoa(int(buf.Len())))
 	writer.Header().Add("Fn-Call-Id", call.Model().ID) // XXX(reed): move to before Submit when adding streaming
 
+	if cached != nil {
+		cached.store(req.Context(), s.responseCache, writer.Status(), writer.Header(), buf.Bytes())
+		writer.Header().Set(models.ResponseCacheHeader, "MISS")
+	}
+
+	if idem != nil {
+		var body []byte
+		if !isDetached {
+			body = buf.Bytes()
+		}
+		s.idempotency.complete(idem, writer.Status(), writer.Header(), body)
+		idem = nil
+	}
+
 	// buffered response writer traps status (so we can add headers), we need to write it still
 	if writer.Status() > 0 {
 		resp.WriteHeader(writer.Status())
@@ -120,6 +287,27 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 	return nil
 }
 
+// enqueueAsync queues an async call for the request, delayed by the number of
+// seconds in the Fn-Delay-Seconds header if set
+func (s *Server) enqueueAsync(req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger) (*models.Call, error) {
+	var delay int
+	if d := req.Header.Get("Fn-Delay-Seconds"); d != "" {
+		var err error
+		if delay, err = strconv.Atoi(d); err != nil || delay < 0 || delay > models.MaxCallDelay {
+			return nil, models.ErrCallInvalidDelay
+		}
+	}
+
+	call, err := agent.NewAsyncCallModel(app, fn, req, int32(delay))
+	if err != nil {
+		return nil, err
+	}
+	if trig != nil {
+		call.TriggerID = trig.ID
+	}
+	return call, s.lbEnqueue.Enqueue(req.Context(), call)
+}
+
 func getCallOptions(req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger, rw http.ResponseWriter) []agent.CallOpt {
 	var opts []agent.CallOpt
 	opts = append(opts, agent.WithWriter(rw)) // XXX (reed): order matters [for now]
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// batchInvokePath is the path of batch invocations under /invoke, which
+	// is taken by the ids of fns in the router. Fn ids are never batch.
+	batchInvokePath = "batch"
+
+	// maxBatchInvokeConcurrency caps the items of a batch invoked at once
+	maxBatchInvokeConcurrency = 32
+
+	// maxBatchInvokeSize caps the size of the body of batch invocations
+	maxBatchInvokeSize = 6 * 1024 * 1024
+)
+
+// handleFnInvokeBatch invokes a fn once per item of a models.BatchInvokeRequest,
+// each item being invoked as a request to /invoke/:fnID would be, and responds
+// with the result of every item. The batch fails only if it is invalid or its
+// fn cannot be found, failed items have the status and error they would have
+// been answered with.
+func (s *Server) handleFnInvokeBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var batch models.BatchInvokeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchInvokeSize)).Decode(&batch); err != nil {
+		handleErrorResponse(c, models.ErrInvalidJSON)
+		return
+	}
+	if err := batch.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	ctx, _ = common.LoggerWithCall(ctx, "", batch.FnID, "")
+	c.Request = c.Request.WithContext(ctx)
+
+	fn, err := s.lbReadAccess.GetFnByID(ctx, batch.FnID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	app, err := s.lbReadAccess.GetAppByID(ctx, fn.AppID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	concurrency := maxBatchInvokeConcurrency
+	if batch.MaxConcurrency > 0 && batch.MaxConcurrency < concurrency {
+		concurrency = batch.MaxConcurrency
+	}
+
+	res := &models.BatchInvokeResponse{Items: make([]*models.BatchInvokeResult, len(batch.Items))}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, item := range batch.Items {
+		if item == nil {
+			item = &models.BatchInvokeItem{}
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, item *models.BatchInvokeItem) {
+			defer func() {
+				if r := recover(); r != nil {
+					common.Logger(ctx).WithField("panic", r).Error("batch invocation item panicked")
+					res.Items[i] = &models.BatchInvokeResult{Status: http.StatusInternalServerError, Error: simpleError(ErrInternalServerError)}
+				}
+				<-slots
+				wg.Done()
+			}()
+			res.Items[i] = s.invokeBatchItem(c.Request, i, app, fn, item)
+		}(i, item)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, res)
+}
+
+// invokeBatchItem invokes fn with the request of item, the i-th of the batch
+// request
+func (s *Server) invokeBatchItem(batchReq *http.Request, i int, app *models.App, fn *models.Fn, item *models.BatchInvokeItem) *models.BatchInvokeResult {
+	ctx := batchReq.Context()
+
+	u := *batchReq.URL
+	u.Path = "/invoke/" + fn.ID
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(item.Body))
+	if err != nil {
+		return &models.BatchInvokeResult{Status: http.StatusInternalServerError, Error: simpleError(ErrInternalServerError)}
+	}
+	req = req.WithContext(ctx)
+	req.RemoteAddr = batchReq.RemoteAddr
+	req.Host = batchReq.Host
+	for k, v := range batchReq.Header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	// the batch is JSON, the items are whatever they say they are
+	req.Header.Del("Content-Type")
+	req.Header.Del("Content-Length")
+	// the signature of the batch does not sign the body of its items, and
+	// items of the same fn must not get each other's response for the key of
+	// the batch, which keys each item on its own when the batch is retried
+	req.Header.Del(models.InvokeSignatureHeader)
+	if key := req.Header.Get(models.IdempotencyKeyHeader); key != "" {
+		req.Header.Set(models.IdempotencyKeyHeader, key+"/"+strconv.Itoa(i))
+	}
+	for k, v := range item.Headers {
+		req.Header[http.CanonicalHeaderKey(k)] = v
+	}
+
+	rw := &syncResponseWriter{
+		headers: make(http.Header),
+		status:  http.StatusOK,
+		Buffer:  new(bytes.Buffer),
+	}
+	if err := s.fnInvoke(rw, req, app, fn, nil); err != nil {
+		rw = &syncResponseWriter{
+			headers: make(http.Header),
+			Buffer:  new(bytes.Buffer),
+		}
+		HandleErrorResponse(ctx, rw, err)
+		var e models.Error
+		json.Unmarshal(rw.Bytes(), &e)
+		return &models.BatchInvokeResult{Status: rw.Status(), Error: &e}
+	}
+
+	return &models.BatchInvokeResult{
+		Status:  rw.Status(),
+		CallID:  rw.Header().Get("Fn-Call-Id"),
+		Headers: rw.Header(),
+		Body:    rw.String(),
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestFnInvokeBatch(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "f", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 64, Timeout: 30, IdleTimeout: 30}}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn})
+	ls := logs.NewMock()
+
+	rnr, cancel := testRunner(t, ds, ls)
+	defer cancel()
+	srv := testServer(ds, &mqs.Mock{}, ls, rnr, ServerTypeFull)
+
+	for i, test := range []struct {
+		body string
+		code int
+	}{
+		{`{"fn_id": "fn_id", "items": []}`, http.StatusBadRequest},
+		{`{"fn_id": "fn_id", "max_concurrency": -1, "items": [{"body": ""}]}`, http.StatusBadRequest},
+		{`{"items": [{"body": ""}]}`, http.StatusBadRequest},
+		{`{"fn_id": "notfn", "items": [{"body": ""}]}`, http.StatusNotFound},
+		{`[]`, http.StatusBadRequest},
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/invoke/batch", strings.NewReader(test.body))
+		if rec.Code != test.code {
+			t.Errorf("Test %d: expected status %d, got %d %s", i, test.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	batch := models.BatchInvokeRequest{FnID: fn.ID, MaxConcurrency: 2}
+	for i := 0; i < 5; i++ {
+		batch.Items = append(batch.Items, &models.BatchInvokeItem{Body: fmt.Sprintf(`{"echoContent": "_trx_%d_", "isDebug": true}`, i)})
+	}
+	// items fail on their own
+	batch.Items = append(batch.Items, &models.BatchInvokeItem{Body: `{"isDebug": true, "isCrash": true}`})
+	body, _ := json.Marshal(&batch)
+
+	_, rec := routerRequest(t, srv.Router, http.MethodPost, "/invoke/batch", strings.NewReader(string(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the batch to be invoked, got %d %s", rec.Code, rec.Body.String())
+	}
+	var res models.BatchInvokeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != len(batch.Items) {
+		t.Fatalf("Expected a result per item, got %d", len(res.Items))
+	}
+	for i, item := range res.Items[:5] {
+		if item.Status != http.StatusOK || item.CallID == "" || !strings.Contains(item.Body, fmt.Sprintf("_trx_%d_", i)) {
+			t.Errorf("Item %d: expected the response of the fn, got %+v", i, item)
+		}
+	}
+	if crashed := res.Items[5]; crashed.Status != http.StatusBadGateway || crashed.Error == nil {
+		t.Errorf("Expected the crashed item to fail, got %+v", crashed)
+	}
+}
+
+func TestFnInvokeBatchIdempotencyKey(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "f", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 64, Timeout: 30, IdleTimeout: 30}}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn})
+	mq := &pushMQ{}
+	srv := testServer(ds, mq, logs.NewMock(), nil, ServerTypeAPI, WithIdempotencyWindow(time.Minute))
+
+	batch := func() *models.BatchInvokeResponse {
+		body := `{"fn_id": "fn_id", "max_concurrency": 1, "items": [{"body": "a"}, {"body": "b"}]}`
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPost, "/invoke/batch", strings.NewReader(body))
+		c.Request.Header.Set("Fn-Invoke-Type", models.TypeAsync)
+		c.Request.Header.Set(models.IdempotencyKeyHeader, "key")
+		srv.handleFnInvokeBatch(c)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected the batch to be invoked, got %d %s", rec.Code, rec.Body.String())
+		}
+		var res models.BatchInvokeResponse
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return &res
+	}
+
+	// items of the same fn are each invoked, once for the key of the batch
+	first := batch()
+	if len(mq.pushed) != 2 || mq.pushed[0].Payload != "a" || mq.pushed[1].Payload != "b" {
+		t.Fatalf("Expected each item to be enqueued, got %+v", mq.pushed)
+	}
+	if first.Items[0].CallID == "" || first.Items[0].CallID == first.Items[1].CallID {
+		t.Fatalf("Expected each item to be its own call, got %+v %+v", first.Items[0], first.Items[1])
+	}
+
+	again := batch()
+	if len(mq.pushed) != 2 {
+		t.Fatalf("Expected a retried batch not to enqueue its items again, got %d", len(mq.pushed))
+	}
+	for i, item := range again.Items {
+		if item.CallID != first.Items[i].CallID {
+			t.Errorf("Item %d: expected the response of the first batch, got %+v", i, item)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/auth"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// fanOutInvokePath is the path of fan-outs under /invoke, see batchInvokePath
+const fanOutInvokePath = "fanout"
+
+// fanOutCompletion is a branch of a fan-out which completed
+type fanOutCompletion struct {
+	i   int
+	res *models.FanOutResult
+}
+
+// handleFnInvokeFanOut invokes the fns of the branches of a
+// models.FanOutRequest in parallel, each branch being invoked as a request to
+// /invoke/:fnID would be, and responds with the results of all branches once
+// they completed. Branches still running when the deadline of the fan-out
+// passes, or once it cannot succeed anymore, are cancelled.
+func (s *Server) handleFnInvokeFanOut(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var fo models.FanOutRequest
+	if err := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchInvokeSize)).Decode(&fo); err != nil {
+		handleErrorResponse(c, models.ErrInvalidJSON)
+		return
+	}
+	if err := fo.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	ctx, _ = common.LoggerWithCall(ctx, fo.AppID, "", "")
+
+	app, err := s.lbReadAccess.GetAppByID(ctx, fo.AppID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	// the app was authorized from what was read of the body before it was
+	// decoded, it is checked again before any fn is invoked
+	if id := auth.IdentityFromContext(ctx); id != nil {
+		ok, err := (&roleAuthorizer{s}).authorizeApp(ctx, id, auth.ScopeInvoke, app)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		if !ok {
+			handleErrorResponse(c, models.ErrForbidden)
+			return
+		}
+	}
+	fns := make([]*models.Fn, len(fo.Branches))
+	for i, b := range fo.Branches {
+		fn, err := s.lbReadAccess.GetFnByID(ctx, b.FnID)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		if fn.AppID != app.ID {
+			handleErrorResponse(c, models.ErrFanOutFnNotInApp)
+			return
+		}
+		fns[i] = fn
+	}
+
+	timeout := fo.Timeout
+	if timeout == 0 {
+		timeout = models.DefaultFanOutTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+	req := c.Request.WithContext(ctx)
+
+	done := make(chan fanOutCompletion, len(fo.Branches))
+	for i, b := range fo.Branches {
+		go func(i int, b *models.FanOutBranch) {
+			var br *models.BatchInvokeResult
+			defer func() {
+				if r := recover(); r != nil {
+					common.Logger(ctx).WithField("panic", r).Error("fan-out branch panicked")
+					br = &models.BatchInvokeResult{Status: http.StatusInternalServerError, Error: simpleError(ErrInternalServerError)}
+				}
+				done <- fanOutCompletion{i, &models.FanOutResult{
+					Token:   b.Token,
+					FnID:    b.FnID,
+					Status:  br.Status,
+					CallID:  br.CallID,
+					Headers: br.Headers,
+					Body:    br.Body,
+					Error:   br.Error,
+				}}
+			}()
+			br = s.invokeBatchItem(req, i, app, fns[i], &models.BatchInvokeItem{Body: b.Body, Headers: b.Headers})
+		}(i, b)
+	}
+
+	minSuccesses := fo.MinSuccesses
+	if minSuccesses == 0 {
+		minSuccesses = len(fo.Branches)
+	}
+	res := &models.FanOutResponse{
+		Completed: make([]string, 0, len(fo.Branches)),
+		Results:   make([]*models.FanOutResult, len(fo.Branches)),
+	}
+	aborted := false
+	for range fo.Branches {
+		d := <-done
+		if !d.res.Succeeded() && ctx.Err() != nil {
+			// the branch was cancelled, rather than failed on its own
+			e := models.ErrFanOutDeadlineExceeded
+			if aborted {
+				e = models.ErrFanOutAborted
+			}
+			d.res.Status, d.res.Error = e.Code(), simpleError(e)
+		}
+		res.Results[d.i] = d.res
+		res.Completed = append(res.Completed, d.res.Token)
+		if d.res.Succeeded() {
+			res.Succeeded++
+			continue
+		}
+		res.Failed++
+		if !aborted && len(fo.Branches)-res.Failed < minSuccesses {
+			aborted = true
+			cancel()
+		}
+	}
+
+	switch {
+	case res.Failed == 0:
+		res.Status = models.FanOutSuccess
+	case res.Succeeded >= minSuccesses:
+		res.Status = models.FanOutPartial
+	default:
+		res.Status = models.FanOutFailure
+	}
+	c.JSON(http.StatusOK, res)
+}
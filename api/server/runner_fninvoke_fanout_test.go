@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/auth"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestFnInvokeFanOut(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	other := &models.App{ID: "other_id", Name: "otherapp"}
+	rc := models.ResourceConfig{Memory: 64, Timeout: 30, IdleTimeout: 30}
+	fn := &models.Fn{ID: "fn_id", Name: "f", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: rc}
+	fn2 := &models.Fn{ID: "fn2_id", Name: "g", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: rc}
+	foreign := &models.Fn{ID: "foreign_id", Name: "f", AppID: other.ID, Image: "fnproject/fn-test-utils", ResourceConfig: rc}
+	ds := datastore.NewMockInit([]*models.App{app, other}, []*models.Fn{fn, fn2, foreign})
+	ls := logs.NewMock()
+
+	rnr, cancel := testRunner(t, ds, ls)
+	defer cancel()
+	srv := testServer(ds, &mqs.Mock{}, ls, rnr, ServerTypeFull)
+
+	for i, test := range []struct {
+		body string
+		code int
+	}{
+		{`{"app_id": "app_id", "branches": []}`, http.StatusBadRequest},
+		{`{"branches": [{"fn_id": "fn_id"}]}`, http.StatusBadRequest},
+		{`{"app_id": "app_id", "branches": [{"token": "a", "fn_id": "fn_id"}, {"token": "a", "fn_id": "fn_id"}]}`, http.StatusBadRequest},
+		{`{"app_id": "app_id", "min_successes": 2, "branches": [{"fn_id": "fn_id"}]}`, http.StatusBadRequest},
+		{`{"app_id": "app_id", "timeout": -1, "branches": [{"fn_id": "fn_id"}]}`, http.StatusBadRequest},
+		{`{"app_id": "app_id", "branches": [{"fn_id": "foreign_id"}]}`, http.StatusBadRequest},
+		{`{"app_id": "app_id", "branches": [{"fn_id": "notfn"}]}`, http.StatusNotFound},
+		{`{"app_id": "notapp", "branches": [{"fn_id": "fn_id"}]}`, http.StatusNotFound},
+		{`[]`, http.StatusBadRequest},
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/invoke/fanout", strings.NewReader(test.body))
+		if rec.Code != test.code {
+			t.Errorf("Test %d: expected status %d, got %d %s", i, test.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	fanOut := func(fo *models.FanOutRequest) *models.FanOutResponse {
+		body, _ := json.Marshal(fo)
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/invoke/fanout", strings.NewReader(string(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected the fan-out to be invoked, got %d %s", rec.Code, rec.Body.String())
+		}
+		var res models.FanOutResponse
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Results) != len(fo.Branches) || len(res.Completed) != len(fo.Branches) {
+			t.Fatalf("Expected a result per branch, got %+v", res)
+		}
+		return &res
+	}
+
+	res := fanOut(&models.FanOutRequest{
+		AppID:        app.ID,
+		MinSuccesses: 2,
+		Branches: []*models.FanOutBranch{
+			{Token: "a", FnID: fn.ID, Body: `{"echoContent": "_a_", "isDebug": true}`},
+			{FnID: fn2.ID, Body: `{"echoContent": "_b_", "isDebug": true}`},
+			{Token: "crash", FnID: fn.ID, Body: `{"isDebug": true, "isCrash": true}`},
+		},
+	})
+	if res.Status != models.FanOutPartial || res.Succeeded != 2 || res.Failed != 1 {
+		t.Errorf("Expected the fan-out to partially succeed, got %+v", res)
+	}
+	if r := res.Results[0]; r.Token != "a" || r.Status != http.StatusOK || r.CallID == "" || !strings.Contains(r.Body, "_a_") {
+		t.Errorf("Expected the response of the first branch, got %+v", r)
+	}
+	if r := res.Results[1]; r.Token != "1" || r.FnID != fn2.ID || !strings.Contains(r.Body, "_b_") {
+		t.Errorf("Expected the response of the second branch, got %+v", r)
+	}
+	if r := res.Results[2]; r.Status != http.StatusBadGateway || r.Error == nil {
+		t.Errorf("Expected the crashed branch to fail, got %+v", r)
+	}
+
+	// branches still running are cancelled once the fan-out failed
+	res = fanOut(&models.FanOutRequest{
+		AppID: app.ID,
+		Branches: []*models.FanOutBranch{
+			{FnID: fn.ID, Body: `{"isDebug": true, "isCrash": true}`},
+			{FnID: fn2.ID, Body: `{"sleepTime": 20000, "isDebug": true}`},
+		},
+	})
+	if res.Status != models.FanOutFailure || res.Completed[0] != "0" {
+		t.Errorf("Expected the fan-out to fail, got %+v", res)
+	}
+	if r := res.Results[1]; r.Status != http.StatusFailedDependency {
+		t.Errorf("Expected the sleeping branch to be cancelled, got %+v", r)
+	}
+
+	// branches still running at the deadline are cancelled
+	res = fanOut(&models.FanOutRequest{
+		AppID:        app.ID,
+		Timeout:      1,
+		MinSuccesses: 1,
+		Branches: []*models.FanOutBranch{
+			{FnID: fn.ID, Body: `{"echoContent": "_a_", "isDebug": true}`},
+			{FnID: fn2.ID, Body: `{"sleepTime": 20000, "isDebug": true}`},
+		},
+	})
+	if res.Status != models.FanOutPartial || res.Results[1].Status != http.StatusGatewayTimeout {
+		t.Errorf("Expected the sleeping branch to time out, got %+v", res)
+	}
+}
+
+func TestFnInvokeFanOutAuthorizesApp(t *testing.T) {
+	mine := &models.App{ID: "app_mine", Name: "mine"}
+	other := &models.App{ID: "app_other", Name: "other"}
+	ds := datastore.NewMockInit([]*models.App{mine, other})
+	if _, err := ds.(models.RoleBindingStore).InsertRoleBinding(context.Background(), &models.RoleBinding{Subject: "dev", Role: models.RoleInvoker, AppID: mine.ID}); err != nil {
+		t.Fatal(err)
+	}
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	for i, test := range []struct {
+		id    *auth.Identity
+		appID string
+		code  int
+	}{
+		{&auth.Identity{Subject: "key", Scopes: []auth.Scope{auth.ScopeInvoke}, AppIDs: []string{mine.ID}}, other.ID, http.StatusForbidden},
+		{&auth.Identity{Subject: "dev"}, other.ID, http.StatusForbidden},
+		{&auth.Identity{Subject: "viewer", Scopes: []auth.Scope{auth.ScopeRead}}, mine.ID, http.StatusForbidden},
+		// once authorized, the fns of the branches are looked up
+		{&auth.Identity{Subject: "key", Scopes: []auth.Scope{auth.ScopeInvoke}, AppIDs: []string{mine.ID}}, mine.ID, http.StatusNotFound},
+		{&auth.Identity{Subject: "dev"}, mine.ID, http.StatusNotFound},
+		{&auth.Identity{Subject: "invoker", Scopes: []auth.Scope{auth.ScopeInvoke}}, other.ID, http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		body := `{"app_id": "` + test.appID + `", "branches": [{"fn_id": "notfn"}]}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/invoke/fanout", strings.NewReader(body))
+		c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), test.id))
+
+		srv.handleFnInvokeFanOut(c)
+		if rec.Code != test.code {
+			t.Errorf("Test %d: expected status %d, got %d %s", i, test.code, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
@@ -8,11 +10,13 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fnproject/fn/api/datastore"
 	"github.com/fnproject/fn/api/logs"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/api/secrets"
 )
 
 func TestBadRequests(t *testing.T) {
@@ -414,3 +418,64 @@ func TestInvokeRunnerMinimalConcurrentHotSync(t *testing.T) {
 		}
 	}
 }
+
+func TestInvokePolicySignatureKeyIsAnAppSecret(t *testing.T) {
+	ctx := context.Background()
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	ds := datastore.NewMockInit([]*models.App{app})
+	kms := "local://?key=" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithSecretsKMS(kms))
+
+	stored, err := secrets.Seal(ctx, srv.secretsKeeper, app.ID, &models.Secret{Name: "HOOK_KEY", Mount: models.SecretMountEnv, Value: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.(models.SecretStore).PutSecret(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	signed := func(secret string) *http.Request {
+		now := time.Now().Unix()
+		req, _ := http.NewRequest("POST", "/invoke/fn_id", strings.NewReader("{}"))
+		req.Header.Set(models.InvokeSignatureHeader, fmt.Sprintf("t=%d,v1=%x", now, models.SignInvoke([]byte(secret), now, []byte("{}"))))
+		return req
+	}
+
+	for i, tc := range []struct {
+		secretName string
+		secret     string
+		err        error
+	}{
+		{"HOOK_KEY", "key", nil},
+		{"HOOK_KEY", "other", models.ErrInvokeNotAuthorized},
+		{"MISSING", "key", models.ErrInvokeNotAuthorized},
+	} {
+		policy := &models.InvokePolicy{Type: models.InvokePolicySignature, SecretName: tc.secretName}
+		if err := srv.authorizeInvoke(signed(tc.secret), app.ID, policy, srv.invokeReplays); err != tc.err {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.err, err)
+		}
+	}
+
+	// a signature satisfying the policies of both a fn and its trigger is
+	// accepted once
+	fnAnnotations, _ := models.EmptyAnnotations().With(models.FnInvokePolicyAnnotation, map[string]interface{}{"type": "signature", "secret_name": "HOOK_KEY"})
+	trigAnnotations, _ := models.EmptyAnnotations().With(models.TriggerInvokePolicyAnnotation, map[string]interface{}{"type": "signature", "secret_name": "HOOK_KEY"})
+	fn := &models.Fn{ID: "fn_id", AppID: app.ID, Annotations: fnAnnotations}
+	trig := &models.Trigger{ID: "trigger_id", AppID: app.ID, FnID: fn.ID, Annotations: trigAnnotations}
+	srv.invokeReplays = newReplayFilter()
+	sig := signed("key").Header.Get(models.InvokeSignatureHeader)
+	for i, expected := range []error{nil, models.ErrInvokeSignatureReplayed} {
+		req := signed("key")
+		req.Header.Set(models.InvokeSignatureHeader, sig)
+		if err := srv.authorizeFnInvoke(req, fn, trig); err != expected {
+			t.Fatalf("Request %d through the trigger: expected %v, got %v", i, expected, err)
+		}
+	}
+
+	// without a kms the key cannot be read
+	srv.secretsKeeper = nil
+	policy := &models.InvokePolicy{Type: models.InvokePolicySignature, SecretName: "HOOK_KEY"}
+	if err := srv.authorizeInvoke(signed("key"), app.ID, policy, srv.invokeReplays); err != models.ErrInvokeNotAuthorized {
+		t.Errorf("Expected signed invocations not to be authorized without a kms, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleFnInvokeWebSocket proxies a WebSocket connection to a hot container of
+// the fn, for router handlers. The connection lasts until either side closes
+// it or the timeout of the fn.
+func (s *Server) handleFnInvokeWebSocket(c *gin.Context) {
+	fnID := c.Param(api.ParamFnID)
+	ctx, _ := common.LoggerWithCall(c.Request.Context(), "", fnID, "")
+	c.Request = c.Request.WithContext(ctx)
+	err := s.handleFnInvokeWebSocket2(c)
+	if err != nil && !c.Writer.Written() {
+		handleErrorResponse(c, err)
+	}
+}
+
+func (s *Server) handleFnInvokeWebSocket2(c *gin.Context) error {
+	req := c.Request
+	if !isWebSocketUpgrade(req) {
+		return models.ErrWebSocketHandshake
+	}
+
+	fn, err := s.lbReadAccess.GetFnByID(c, c.Param(api.ParamFnID))
+	if err != nil {
+		return err
+	}
+	app, err := s.lbReadAccess.GetAppByID(c, fn.AppID)
+	if err != nil {
+		return err
+	}
+
+	policy, err := models.InvokePolicyFromAnnotations(fn.Annotations)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeInvoke(req, fn.AppID, policy, s.invokeReplays); err != nil {
+		return err
+	}
+	req.Header.Del(models.InvokeTokenHeader)
+
+	// a connection counts as a running invocation while it is open
+	release, err := s.rateLimits.acquire(c.Writer.Header(), app, fn, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	opts := append(getCallOptions(req, app, fn, nil, c.Writer), agent.InvokeWebSocket())
+	call, err := s.agent.GetCall(opts...)
+	if err != nil {
+		return err
+	}
+	return s.agent.Submit(call)
+}
+
+// isWebSocketUpgrade returns whether req is the opening handshake of a
+// WebSocket connection, see RFC 6455 section 4.2.1
+func isWebSocketUpgrade(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		headerContainsToken(req.Header, "Connection", "upgrade") &&
+		strings.EqualFold(req.Header.Get("Upgrade"), "websocket") &&
+		req.Header.Get("Sec-WebSocket-Version") == "13" &&
+		req.Header.Get("Sec-WebSocket-Key") != ""
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h[name] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
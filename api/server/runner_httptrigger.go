@@ -76,6 +76,13 @@ func (trw *triggerResponseWriter) Write(b []byte) (int, error) {
 	return trw.inner.Write(b)
 }
 
+// Flush sends what was written so far to the client, for streamed responses
+func (trw *triggerResponseWriter) Flush() {
+	if f, ok := trw.inner.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 	if trw.committed {
 		return
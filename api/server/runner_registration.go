@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/version"
+	"github.com/gin-gonic/gin"
+)
+
+var errRunnerNotRegistered = models.NewAPIError(http.StatusNotFound, errors.New("Runner not registered"))
+
+type runnerRegistrationResponse struct {
+	// TTL is how long the lb keeps the runner without another heartbeat
+	TTL int64 `json:"ttl_msecs"`
+}
+
+// handleRegisterRunner registers a runner with an lb, or records its heartbeat
+func (s *Server) handleRegisterRunner(c *gin.Context) {
+	var reg agent.RunnerRegistration
+	if err := c.BindJSON(&reg); err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+	if err := s.runnerRegistry.Register(reg); err != nil {
+		handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, err))
+		return
+	}
+	c.JSON(http.StatusOK, runnerRegistrationResponse{TTL: int64(s.runnerRegistry.TTL() / time.Millisecond)})
+}
+
+// handleDeregisterRunner removes a runner from an lb, eg. when it shuts down
+func (s *Server) handleDeregisterRunner(c *gin.Context) {
+	if !s.runnerRegistry.Deregister(c.Param("address")) {
+		handleErrorResponse(c, errRunnerNotRegistered)
+		return
+	}
+	c.String(http.StatusNoContent, "")
+}
+
+// handleListRunners returns the runners registered with an lb
+func (s *Server) handleListRunners(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"runners": s.runnerRegistry.Runners()})
+}
+
+// handleFnMetrics returns the demand for each fn, over the runners registered
+// with an lb or on a runner
+func (s *Server) handleFnMetrics(c *gin.Context) {
+	if s.runnerRegistry != nil {
+		c.JSON(http.StatusOK, gin.H{"items": s.runnerRegistry.FnMetrics()})
+		return
+	}
+	metrics, err := s.agent.(agent.FnMetricsReporter).FnMetrics()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": metrics})
+}
+
+// runnerRegistrar registers a runner with an lb and sends its heartbeats
+type runnerRegistrar struct {
+	url      string
+	reg      agent.RunnerRegistration
+	interval time.Duration
+	client   *http.Client
+	// metrics reports the demand for fns on the runner with each heartbeat, if set
+	metrics agent.FnMetricsReporter
+}
+
+// newRunnerRegistrar returns a registrar of the runner serving gRPC on
+// grpcAddr with the lb whose admin server is at lbURL. Without an advertised
+// address, the runner is registered with its host name.
+func newRunnerRegistrar(lbURL, advertise, grpcAddr, zone, labels string, interval time.Duration) (*runnerRegistrar, error) {
+	if _, err := url.Parse(lbURL); err != nil {
+		return nil, err
+	}
+	if advertise == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		_, port, err := net.SplitHostPort(grpcAddr)
+		if err != nil {
+			return nil, err
+		}
+		advertise = net.JoinHostPort(host, port)
+	}
+
+	reg := agent.RunnerRegistration{Address: advertise, Zone: zone, Version: version.Version}
+	if cfg, err := agent.NewConfig(); err == nil {
+		reg.MemoryBytes = cfg.MaxTotalMemory
+		reg.CPUMillis = cfg.MaxTotalCPU
+	}
+	if labels != "" {
+		reg.Labels = make(map[string]string)
+		for _, l := range strings.Split(labels, ",") {
+			kv := strings.SplitN(l, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("invalid runner label %q, expected key=value", l)
+			}
+			reg.Labels[kv[0]] = kv[1]
+		}
+	}
+
+	return &runnerRegistrar{
+		url:      strings.TrimSuffix(lbURL, "/") + "/runners",
+		reg:      reg,
+		interval: interval,
+		client:   &http.Client{Timeout: interval},
+	}, nil
+}
+
+// run registers the runner every interval until ctx is done
+func (r *runnerRegistrar) run(ctx context.Context) {
+	log := common.Logger(ctx).WithField("lb_url", r.url).WithField("runner_addr", r.reg.Address)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for registered := false; ; {
+		err := r.register(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.WithError(err).Warn("Failed to register runner with lb")
+		} else if err == nil && !registered {
+			log.Info("Registered runner with lb")
+		}
+		registered = err == nil
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *runnerRegistrar) register(ctx context.Context) error {
+	reg := r.reg
+	if r.metrics != nil {
+		reg.FnMetrics, _ = r.metrics.FnMetrics()
+	}
+	body, err := json.Marshal(reg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return r.do(req.WithContext(ctx))
+}
+
+// deregister removes the runner from the lb, which stops placing calls on it
+// without waiting for its registration to expire
+func (r *runnerRegistrar) deregister() error {
+	req, err := http.NewRequest(http.MethodDelete, r.url+"/"+url.PathEscape(r.reg.Address), nil)
+	if err != nil {
+		return err
+	}
+	return r.do(req)
+}
+
+func (r *runnerRegistrar) do(req *http.Request) error {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRunnerRegistrar(t *testing.T) {
+	s := &Server{runnerRegistry: agent.NewRunnerRegistry(time.Minute)}
+	admin := gin.New()
+	admin.POST("/runners", s.handleRegisterRunner)
+	admin.GET("/runners", s.handleListRunners)
+	admin.DELETE("/runners/:address", s.handleDeregisterRunner)
+	lb := httptest.NewServer(admin)
+	defer lb.Close()
+
+	r, err := newRunnerRegistrar(lb.URL, "", ":9190", "az1", "gpu=true,tier=batch", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create registrar %v", err)
+	}
+	if err := r.register(context.Background()); err != nil {
+		t.Fatalf("Failed to register runner %v", err)
+	}
+
+	runners := s.runnerRegistry.Runners()
+	if len(runners) != 1 || runners[0].Address != r.reg.Address || runners[0].Zone != "az1" || runners[0].Labels["tier"] != "batch" {
+		t.Fatalf("Unexpected registered runners %+v", runners)
+	}
+
+	if err := r.deregister(); err != nil {
+		t.Fatalf("Failed to deregister runner %v", err)
+	}
+	if runners := s.runnerRegistry.Runners(); len(runners) != 0 {
+		t.Fatalf("Unexpected registered runners %+v", runners)
+	}
+	if err := r.deregister(); err == nil {
+		t.Fatal("Expected deregistering an unknown runner to fail")
+	}
+
+	if _, err := newRunnerRegistrar(lb.URL, "", ":9190", "", "gpu", time.Second); err == nil {
+		t.Fatal("Expected an invalid label to fail")
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// streamingResponseWriter writes the response of a call to the client as the
+// container writes it, flushing every write, see models.FnStreamResponseAnnotation.
+// Headers and status are sent with the first write, since the agent sets
+// the headers of the container only after the status.
+type streamingResponseWriter struct {
+	inner     http.ResponseWriter
+	status    int
+	callID    string
+	committed bool
+}
+
+var _ http.ResponseWriter = new(streamingResponseWriter)
+
+func (w *streamingResponseWriter) Header() http.Header  { return w.inner.Header() }
+func (w *streamingResponseWriter) WriteHeader(code int) { w.status = code }
+func (w *streamingResponseWriter) Status() int          { return w.status }
+
+func (w *streamingResponseWriter) Write(b []byte) (int, error) {
+	w.commit()
+	n, err := w.inner.Write(b)
+	w.Flush()
+	return n, err
+}
+
+// Flush sends what was written so far to the client
+func (w *streamingResponseWriter) Flush() {
+	if f, ok := w.inner.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *streamingResponseWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	// the body may be cut off at the size limit of the fn
+	w.Header().Del("Content-Length")
+	w.Header().Set("Fn-Call-Id", w.callID)
+	w.inner.WriteHeader(w.status)
+}
+
+// fnInvokeStreaming executes a sync call of a fn streaming its response. Errors
+// of the call after the response started are only logged, the client sees a
+// truncated response.
+func (s *Server) fnInvokeStreaming(resp http.ResponseWriter, req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger) error {
+	writer := &streamingResponseWriter{inner: resp, status: http.StatusOK}
+
+	call, err := s.agent.GetCall(getCallOptions(req, app, fn, trig, writer)...)
+	if err != nil {
+		return err
+	}
+	writer.callID = call.Model().ID
+
+	err = s.agent.Submit(call)
+	if writer.committed {
+		if err != nil {
+			common.Logger(req.Context()).WithError(err).Info("Streamed call failed after its response started")
+		}
+		return nil
+	}
+	if err != nil {
+		return deadlineError(req, err)
+	}
+	// nothing was written, send the headers and status
+	writer.commit()
+	return nil
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamingResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &streamingResponseWriter{inner: rec, status: http.StatusOK, callID: "call"}
+
+	// the agent sets the status before the headers of the container
+	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Content-Length", "100")
+	if rec.Code != http.StatusOK || len(rec.Body.Bytes()) != 0 || rec.Flushed {
+		t.Fatal("expected nothing to be sent before the first write")
+	}
+
+	w.Write([]byte("data: 1\n\n"))
+	if !rec.Flushed || rec.Body.String() != "data: 1\n\n" {
+		t.Fatalf("expected the first event to be flushed, got %q", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/event-stream" || rec.Header().Get("Fn-Call-Id") != "call" || rec.Header().Get("Content-Length") != "" {
+		t.Fatalf("unexpected headers %v", rec.Header())
+	}
+
+	rec.Flushed = false
+	w.Write([]byte("data: 2\n\n"))
+	if !rec.Flushed || rec.Body.String() != "data: 1\n\ndata: 2\n\n" {
+		t.Fatalf("expected the second event to be flushed, got %q", rec.Body.String())
+	}
+}
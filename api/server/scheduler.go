@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// scheduler enqueues async calls of fns with a schedule annotation when they
+// are due. Only one server of a cluster should run it, calls would be queued
+// once per scheduler otherwise.
+type scheduler struct {
+	ds      models.Datastore
+	enqueue agent.EnqueueDataAccess
+	next    map[string]time.Time // next run of scheduled fns by fn id
+}
+
+func newScheduler(ds models.Datastore, enqueue agent.EnqueueDataAccess) *scheduler {
+	return &scheduler{
+		ds:      ds,
+		enqueue: enqueue,
+		next:    make(map[string]time.Time),
+	}
+}
+
+// run checks for due fns every interval until ctx is done
+func (s *scheduler) run(ctx context.Context, interval time.Duration) {
+	logrus.WithField("interval", interval).Info("Starting fn scheduler")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.tick(ctx, now); err != nil && ctx.Err() == nil {
+				logrus.WithError(err).Error("Failed to run scheduled fns")
+			}
+		}
+	}
+}
+
+// tick enqueues a call for each scheduled fn whose next run is before now. Fns
+// seen for the first time are scheduled from now on, runs missed while no
+// scheduler was running are not caught up.
+func (s *scheduler) tick(ctx context.Context, now time.Time) error {
+	seen := make(map[string]bool, len(s.next))
+
+	err := s.forEachFn(ctx, func(app *models.App, fn *models.Fn) {
+		sched, ok, err := models.ScheduleFromAnnotations(fn.Annotations)
+		if !ok || err != nil {
+			return
+		}
+		seen[fn.ID] = true
+
+		next, ok := s.next[fn.ID]
+		if !ok {
+			s.next[fn.ID] = sched.Next(now)
+			return
+		}
+		if next.IsZero() || now.Before(next) {
+			return
+		}
+
+		s.next[fn.ID] = sched.Next(now)
+		if err := s.enqueueCall(ctx, app, fn, sched); err != nil {
+			common.Logger(ctx).WithError(err).WithField("fn_id", fn.ID).Error("Failed to enqueue scheduled call")
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for id := range s.next {
+		if !seen[id] {
+			delete(s.next, id)
+		}
+	}
+	return nil
+}
+
+func (s *scheduler) enqueueCall(ctx context.Context, app *models.App, fn *models.Fn, sched *models.FnSchedule) error {
+	req, err := http.NewRequest(http.MethodPost, "/invoke/"+fn.ID, strings.NewReader(sched.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Fn-Invoke-Type", models.TypeAsync)
+
+	call, err := agent.NewAsyncCallModel(app, fn, req, 0)
+	if err != nil {
+		return err
+	}
+	common.Logger(ctx).WithFields(common.CallFields("", fn.ID, call.ID)).Info("Enqueueing scheduled call")
+	return s.enqueue.Enqueue(ctx, call)
+}
+
+// forEachFn calls f with every fn of every app
+func (s *scheduler) forEachFn(ctx context.Context, f func(*models.App, *models.Fn)) error {
+	appFilter := &models.AppFilter{PerPage: 100}
+	for {
+		apps, err := s.ds.GetApps(ctx, appFilter)
+		if err != nil {
+			return err
+		}
+
+		for _, app := range apps.Items {
+			fnFilter := &models.FnFilter{AppID: app.ID, PerPage: 100}
+			for {
+				fns, err := s.ds.GetFns(ctx, fnFilter)
+				if err != nil {
+					return err
+				}
+				for _, fn := range fns.Items {
+					f(app, fn)
+				}
+				if fns.NextCursor == "" {
+					break
+				}
+				fnFilter.Cursor = fns.NextCursor
+			}
+		}
+
+		if apps.NextCursor == "" {
+			return nil
+		}
+		appFilter.Cursor = apps.NextCursor
+	}
+}
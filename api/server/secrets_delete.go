@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleSecretDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ss, err := s.secretStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	err = ss.RemoveSecret(ctx, c.Param(api.ParamAppID), c.Param(api.ParamSecretName))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+type secretList struct {
+	Items []*models.Secret `json:"items"`
+}
+
+// handleSecretList lists the names and mounts of the secrets of an app, never
+// their values
+func (s *Server) handleSecretList(c *gin.Context) {
+	ctx := c.Request.Context()
+	appID := c.Param(api.ParamAppID)
+
+	ss, err := s.secretStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	// reports apps missing, or out of the namespace of the request
+	if _, err := s.datastore.GetAppByID(ctx, appID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	stored, err := ss.GetSecrets(ctx, appID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	res := &secretList{Items: make([]*models.Secret, 0, len(stored))}
+	for _, secret := range stored {
+		res.Items = append(res.Items, &models.Secret{
+			Name:      secret.Name,
+			Mount:     secret.Mount,
+			CreatedAt: secret.CreatedAt,
+			UpdatedAt: secret.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/secrets"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleSecretPut(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	secret := &models.Secret{}
+
+	err := c.BindJSON(secret)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	secret.Name = c.Param(api.ParamSecretName)
+	if err := secret.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	ss, err := s.secretStore()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if s.secretsKeeper == nil {
+		handleErrorResponse(c, models.ErrSecretsNoKMS)
+		return
+	}
+
+	stored, err := secrets.Seal(ctx, s.secretsKeeper, c.Param(api.ParamAppID), secret)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	stored, err = ss.PutSecret(ctx, stored)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.Secret{
+		Name:      stored.Name,
+		Mount:     stored.Mount,
+		CreatedAt: stored.CreatedAt,
+		UpdatedAt: stored.UpdatedAt,
+	})
+}
@@ -16,18 +16,29 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 	"unicode"
 
 	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/agent/hybrid"
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/chaos"
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/common/tracecontext"
 	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/eventsource"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/metering"
 	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/metrics"
 	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/api/otlp"
+	"github.com/fnproject/fn/api/respcache"
 	pool "github.com/fnproject/fn/api/runnerpool"
+	"github.com/fnproject/fn/api/secrets"
 	"github.com/fnproject/fn/api/version"
 	"github.com/fnproject/fn/fnext"
 	"github.com/gin-gonic/gin"
@@ -48,6 +59,10 @@ const (
 	// forcing usage through WithXxx configuration methods and documenting there vs.
 	// expecting users to use os.SetEnv(EnvLogLevel, "debug") // why ?
 
+	// EnvConfigFile is a YAML config file of the settings of fnserver, see
+	// LoadConfigFile. Settings set in env take precedence over the file.
+	EnvConfigFile = "FN_CONFIG_FILE"
+
 	// EnvLogFormat sets the stderr logging format, text or json only
 	EnvLogFormat = "FN_LOG_FORMAT"
 
@@ -64,6 +79,12 @@ const (
 	// EnvLogPrefix is a prefix to affix to each log line.
 	EnvLogPrefix = "FN_LOG_PREFIX"
 
+	// EnvLogComponentLevels sets the log levels of components of the server
+	// apart from EnvLogLevel, as component=level pairs separated by commas,
+	// e.g. agent=debug,driver=warn. Components are agent, driver, lb and
+	// server. They can be changed at runtime on /loglevels of the admin server.
+	EnvLogComponentLevels = "FN_LOG_COMPONENT_LEVELS"
+
 	// EnvMQURL is a url to an MQ service:
 	// possible out-of-the-box schemes: { memory, redis, bolt }
 	EnvMQURL = "FN_MQ_URL"
@@ -76,12 +97,78 @@ const (
 	// possible schemes: { postgres, sqlite3, mysql, s3 }
 	EnvLogDBURL = "FN_LOGSTORE_URL"
 
-	// EnvRunnerURL is a url pointing to an Fn API service.
+	// EnvRunnerURL is a url pointing to an Fn API service. With authentication
+	// enabled on the API service its password is the token of the runner, eg.
+	// http://:token@fn-api:8080
 	EnvRunnerURL = "FN_RUNNER_API_URL"
 
 	// EnvRunnerAddresses is a list of runner urls for an lb to use.
 	EnvRunnerAddresses = "FN_RUNNER_ADDRESSES"
 
+	// EnvIdempotencyWindow is the time in seconds the response of a call made with an
+	// Idempotency-Key header is returned to requests with the same key instead of
+	// executing the fn again, 0 disables idempotency keys. Responses are kept in the
+	// memory of each server, requests retried to another server execute the fn again.
+	EnvIdempotencyWindow = "FN_IDEMPOTENCY_WINDOW_SECS"
+
+	// EnvResponseCacheURL is where the responses of fns with a response cache
+	// annotation are cached, memory://?entries=N&max_bytes=N or redis://host:port
+	EnvResponseCacheURL = "FN_RESPONSE_CACHE_URL"
+
+	// EnvCloudEventsSinkURL is the url the CloudEvents returned by fns bound to
+	// CloudEvents are posted to, unless their trigger sets another sink. Without
+	// a sink the events are returned to the sender of the event.
+	EnvCloudEventsSinkURL = "FN_CLOUDEVENTS_SINK_URL"
+
+	// EnvSchedulerInterval is the time in msecs between two checks for scheduled fns that are
+	// due, 0 disables the scheduler. It should be enabled on a single api or full node.
+	EnvSchedulerInterval = "FN_SCHEDULER_INTERVAL_MSECS"
+
+	// EnvEventSourcesInterval is the time in msecs between two syncs of the event sources
+	// run for kafka, cron and objectstorage triggers with the triggers in the datastore,
+	// 0 disables event sources. It should be enabled on a single api or full node.
+	EnvEventSourcesInterval = "FN_EVENT_SOURCES_INTERVAL_MSECS"
+
+	// EnvEventSourceKafkaBrokers is a comma separated list of the kafka brokers of kafka
+	// triggers whose source names no brokers, e.g. kafka://topic
+	EnvEventSourceKafkaBrokers = "FN_EVENT_SOURCE_KAFKA_BROKERS"
+
+	// EnvRunnerDiscovery selects how an lb discovers runners, options are one of:
+	// { static, dns-srv, kubernetes, registration }, static uses FN_RUNNER_ADDRESSES,
+	// registration uses the runners registered with the admin server of the lb.
+	EnvRunnerDiscovery = "FN_RUNNER_DISCOVERY"
+
+	// EnvRunnerRegistrationTTL is the time in msecs an lb keeps a registered runner
+	// without a heartbeat, 15000 by default.
+	EnvRunnerRegistrationTTL = "FN_RUNNER_REGISTRATION_TTL_MSECS"
+
+	// EnvRunnerRegisterURL is the url of the admin server of the lb a pure runner
+	// registers with, registration is disabled without it.
+	EnvRunnerRegisterURL = "FN_RUNNER_REGISTER_URL"
+
+	// EnvRunnerAdvertiseAddress is the host:port a pure runner registers its gRPC
+	// server as, the host name of the runner and FN_GRPC_PORT by default.
+	EnvRunnerAdvertiseAddress = "FN_RUNNER_ADVERTISE_ADDRESS"
+
+	// EnvRunnerHeartbeat is the time in msecs between two registrations of a pure
+	// runner with its lb, 5000 by default.
+	EnvRunnerHeartbeat = "FN_RUNNER_HEARTBEAT_MSECS"
+
+	// EnvRunnerZone is the zone a pure runner registers in, see EnvLBZone.
+	EnvRunnerZone = "FN_RUNNER_ZONE"
+
+	// EnvRunnerLabels is a comma separated list of key=value labels a pure runner
+	// registers with, e.g. arch=arm64,gpu=true. Lb groups and the node selectors
+	// of fns, see models.FnNodeSelectorAnnotation, select runners by their labels.
+	EnvRunnerLabels = "FN_RUNNER_LABELS"
+
+	// EnvRunnerDiscoveryTarget is what an lb discovers runners from: a SRV record name,
+	// eg. _grpc._tcp.runners.example.com, or a kubernetes namespace/service[:port].
+	EnvRunnerDiscoveryTarget = "FN_RUNNER_DISCOVERY_TARGET"
+
+	// EnvRunnerDiscoveryInterval is the time in msecs between two runner discoveries.
+	EnvRunnerDiscoveryInterval = "FN_RUNNER_DISCOVERY_INTERVAL_MSECS"
+
 	// EnvPublicLoadBalancerURL is the url to inject into trigger responses to get a public url.
 	EnvPublicLoadBalancerURL = "FN_PUBLIC_LB_URL"
 
@@ -95,6 +182,11 @@ const (
 	// EnvGRPCPort is the port to run the grpc server on for a pure-runner node.
 	EnvGRPCPort = "FN_GRPC_PORT"
 
+	// EnvGRPCInvokePort is the port to serve the gRPC invoke front-end on, see the
+	// FnInvoke service of api/invoke. It is off by default, full, lb and runner
+	// nodes can enable it.
+	EnvGRPCInvokePort = "FN_GRPC_INVOKE_PORT"
+
 	// EnvAPICORSOrigins is the list of CORS origins to allow.
 	EnvAPICORSOrigins = "FN_API_CORS_ORIGINS"
 
@@ -107,7 +199,47 @@ const (
 	// EnvJaegerURL is the url of a jaeger node to send traces to.
 	EnvJaegerURL = "FN_JAEGER_URL"
 
-	// EnvRIDHeader is the header name of the incoming request which holds the request ID
+	// EnvOTLPURL is the url of an OpenTelemetry collector to send traces and
+	// metrics to over OTLP/http.
+	EnvOTLPURL = "FN_OTLP_URL"
+
+	// EnvMetricsExporters is a comma separated list of the urls of metrics
+	// backends to export metrics to besides /metrics, statsd, dogstatsd, oci
+	// monitoring or stdout, see metrics.New.
+	EnvMetricsExporters = "FN_METRICS_EXPORTERS"
+
+	// EnvMetricsTags is a comma separated list of key=value tags added to the
+	// metrics of EnvMetricsExporters, e.g. lb_group=blue,region=phx.
+	EnvMetricsTags = "FN_METRICS_TAGS"
+
+	// EnvMetricsListen is the address to serve /metrics on besides the admin
+	// server, e.g. :9090 to keep metrics scraping off the admin port.
+	EnvMetricsListen = "FN_METRICS_LISTEN"
+
+	// EnvReloadFile is a file of FN_NAME=value lines setting the log levels,
+	// runner addresses, placement algorithm and pre-fork pool water marks of
+	// the server. Its settings
+	// override env and are reloaded on SIGHUP or when the file changes, see
+	// reloadSettings. Rate limits are annotations of apps and fns, which take
+	// effect without a reload.
+	EnvReloadFile = "FN_RELOAD_FILE"
+
+	// EnvShutdownTimeout is the time in seconds a stopping server waits for in-flight
+	// requests and calls to complete, 0 waits for them however long they take.
+	EnvShutdownTimeout = "FN_SHUTDOWN_TIMEOUT_SECS"
+
+	// EnvAccessLog is where to write access logs of the requests served to,
+	// stdout, stderr, file:///path or udp:// or tcp:// urls of syslog servers.
+	// Access logs are off if it is not set.
+	EnvAccessLog = "FN_ACCESS_LOG"
+
+	// EnvAccessLogFormat is the format of access logs, json (default) or text.
+	EnvAccessLogFormat = "FN_ACCESS_LOG_FORMAT"
+
+	// EnvRIDHeader is the header name of the incoming request which holds the request ID,
+	// Fn-Request-Id by default. Requests without one are given a new request ID, which is
+	// returned in the same header, logged with each log line about the request and kept
+	// with the calls it makes.
 	EnvRIDHeader = "FN_RID_HEADER"
 
 	// EnvProcessCollectorList is the list of procid's to collect metrics for.
@@ -116,15 +248,139 @@ const (
 	// EnvLBPlacementAlg is the algorithm to place fn calls to fn runners in lb.[0w
 	EnvLBPlacementAlg = "FN_PLACER"
 
+	// EnvLBBinPackTarget is the number of calls in flight the binpack placer fills a runner up to.
+	EnvLBBinPackTarget = "FN_PLACER_BINPACK_TARGET"
+
+	// EnvLBZone is the zone of an lb, the zone placer prefers runners of this zone.
+	// Runners are labelled with their zone as host:port@zone in their address.
+	EnvLBZone = "FN_LB_ZONE"
+
+	// EnvLBZoneSpillWait is the time in msecs the zone placer tries to place a call in
+	// its own zone before trying runners of other zones.
+	EnvLBZoneSpillWait = "FN_LB_ZONE_SPILL_WAIT_MSECS"
+
+	// EnvLBCircuitBreakerThreshold is the percentage of failed placement attempts on a runner above
+	// which an lb skips the runner for a while. Zero (default) disables circuit breakers.
+	EnvLBCircuitBreakerThreshold = "FN_LB_CIRCUIT_BREAKER_THRESHOLD"
+
+	// EnvLBCircuitBreakerOpenTimeout is the time in msecs an lb skips a failing runner before probing it again.
+	EnvLBCircuitBreakerOpenTimeout = "FN_LB_CIRCUIT_BREAKER_OPEN_TIMEOUT_MSECS"
+
+	// EnvLBScaleHook is an executable an lb runs to add or remove runners as demand changes.
+	EnvLBScaleHook = "FN_LB_SCALE_HOOK"
+
+	// EnvLBScaleWebhook is a url an lb posts requests to add or remove runners to.
+	EnvLBScaleWebhook = "FN_LB_SCALE_WEBHOOK"
+
+	// EnvLBScaleMinRunners and EnvLBScaleMaxRunners bound the number of runners an lb scales to.
+	EnvLBScaleMinRunners = "FN_LB_SCALE_MIN_RUNNERS"
+	EnvLBScaleMaxRunners = "FN_LB_SCALE_MAX_RUNNERS"
+
+	// EnvLBActivatorQueueSize is the number of calls an lb holds while its pool has no runners,
+	// eg. while it is scaled to zero and FN_LB_SCALE_HOOK or FN_LB_SCALE_WEBHOOK start runners.
+	// Calls over the limit fail with a 503. Zero (default) disables the queue.
+	EnvLBActivatorQueueSize = "FN_LB_ACTIVATOR_QUEUE_SIZE"
+
+	// EnvLBActivatorTimeout is the time in msecs calls wait in the activator queue, 60000 by default.
+	EnvLBActivatorTimeout = "FN_LB_ACTIVATOR_TIMEOUT_MSECS"
+
+	// EnvLBPlacementAuditSize is the number of recent calls an lb keeps placement audits for,
+	// served on the admin port under /debug/placement/:callID. Zero (default) disables audits.
+	EnvLBPlacementAuditSize = "FN_LB_PLACEMENT_AUDIT_SIZE"
+
+	// EnvLBMaxFailovers is the number of times an lb moves an idempotent call (GET, HEAD or
+	// with an Idempotency-Key) or an async call to another runner after its runner failed
+	// before responding.
+	// Zero (default) returns such failures to the client.
+	EnvLBMaxFailovers = "FN_LB_MAX_FAILOVERS"
+
+	// EnvLBRunnerDialTimeout is the time in msecs an lb waits to connect to a runner, 100 by default.
+	EnvLBRunnerDialTimeout = "FN_LB_RUNNER_DIAL_TIMEOUT_MSECS"
+
+	// EnvLBRunnerKeepalive is the time in msecs a connection to a runner may be idle before the lb
+	// pings the runner, at least 5000. Zero (default) disables pings.
+	EnvLBRunnerKeepalive = "FN_LB_RUNNER_KEEPALIVE_MSECS"
+
+	// EnvLBRunnerKeepaliveTimeout is the time in msecs an lb waits for a runner to answer a ping
+	// before it closes the connection, 20000 by default.
+	EnvLBRunnerKeepaliveTimeout = "FN_LB_RUNNER_KEEPALIVE_TIMEOUT_MSECS"
+
+	// EnvLBRunnerHeartbeat is the time in msecs between the heartbeats an lb sends to the runners running
+	// its calls, 5000 by default. The calls of runners which miss EnvLBRunnerHeartbeatMisses heartbeats in a
+	// row, 3 by default, fail, or fail over to another runner if they are idempotent or async. Zero disables
+	// heartbeats.
+	EnvLBRunnerHeartbeat       = "FN_LB_RUNNER_HEARTBEAT_MSECS"
+	EnvLBRunnerHeartbeatMisses = "FN_LB_RUNNER_HEARTBEAT_MISSES"
+
+	// EnvLBRunnerWindowSize and EnvLBRunnerConnWindowSize are the gRPC flow control windows in bytes
+	// of calls and connections to runners. Zero (default) keeps the gRPC defaults.
+	EnvLBRunnerWindowSize     = "FN_LB_RUNNER_WINDOW_SIZE"
+	EnvLBRunnerConnWindowSize = "FN_LB_RUNNER_CONN_WINDOW_SIZE"
+
+	// EnvAsyncLease is the time in msecs full nodes lease the async calls they run from the queue,
+	// completing them exactly once: calls are only deleted from the queue once they were stored,
+	// and go back to the queue if their lease expires. The lease of running calls is renewed.
+	// The queue must lease its calls: the bolt queue stores its leases, the memory queue loses them
+	// with its calls when the server restarts. Zero (default) deletes calls from the queue when
+	// they start, or when they finish for queues keeping reservations.
+	EnvAsyncLease = "FN_ASYNC_LEASE_MSECS"
+
 	// EnvMaxRequestSize sets the limit in bytes for any API request's length.
 	EnvMaxRequestSize = "FN_MAX_REQUEST_SIZE"
 
+	// EnvReadCacheTTL is the time in msecs apps, fns and triggers read to handle invocations
+	// are cached, 5 seconds by default. Datastores with a change feed also drop changed
+	// objects from the cache as soon as they change.
+	EnvReadCacheTTL = "FN_READ_CACHE_TTL_MSECS"
+
+	// EnvAuthKeysFile is the path of a JSON file listing the API keys requests may
+	// authenticate with, see auth.LoadStaticKeys.
+	EnvAuthKeysFile = "FN_AUTH_KEYS_FILE"
+
+	// EnvAuthJWTSecret is the secret of HMAC signed JWTs requests may authenticate with.
+	EnvAuthJWTSecret = "FN_AUTH_JWT_SECRET"
+
+	// EnvAuthOIDCIssuer is the url of an OpenID Connect issuer whose JWTs requests may
+	// authenticate with.
+	EnvAuthOIDCIssuer = "FN_AUTH_OIDC_ISSUER"
+
+	// EnvAuthAudience is the audience JWTs must be issued for, if set.
+	EnvAuthAudience = "FN_AUTH_AUDIENCE"
+
+	// EnvAuthClientCertScopes enables authenticating requests with verified TLS client
+	// certificates, it lists the scopes granted to certificates whose organizational
+	// units name none, eg. "read,invoke".
+	EnvAuthClientCertScopes = "FN_AUTH_CLIENT_CERT_SCOPES"
+
+	// EnvSecretsKMSURL is the url of the key management service app secrets are
+	// encrypted with, see secrets.New. Secrets cannot be set without one.
+	EnvSecretsKMSURL = "FN_SECRETS_KMS_URL"
+
+	// EnvAdmissionWebhooks is a comma separated list of the urls of admission
+	// webhooks reviewing apps, fns and triggers before they are stored.
+	EnvAdmissionWebhooks = "FN_ADMISSION_WEBHOOKS"
+
+	// EnvAdmissionFailOpen admits objects when an admission webhook fails,
+	// they are rejected by default.
+	EnvAdmissionFailOpen = "FN_ADMISSION_FAIL_OPEN"
+
+	// EnvAuditSink is where the changes made to apps, fns, triggers and secrets
+	// are recorded, see audit.New. Changes are not recorded if it is not set.
+	EnvAuditSink = "FN_AUDIT_SINK"
+
+	// EnvUsageSink is where the usage records of the calls a node runs are
+	// written to, see metering.New. Usage is not metered if it is not set.
+	EnvUsageSink = "FN_USAGE_SINK"
+
 	// DefaultLogFormat is text
 	DefaultLogFormat = "text"
 
 	// DefaultLogLevel is info
 	DefaultLogLevel = "info"
 
+	// DefaultRIDHeader is the header of request IDs, see EnvRIDHeader
+	DefaultRIDHeader = common.RequestIDHeader
+
 	// DefaultLogDest is stderr
 	DefaultLogDest = "stderr"
 
@@ -200,6 +456,8 @@ type Server struct {
 	// TLSConfig and Addr are transferrable from http.Server to GRPC service.
 	// TODO: extend this to cover gRPC options.
 	svcConfigs map[string]*http.Server
+	// address of the gRPC invoke front-end, empty if it is disabled
+	grpcInvokeAddr string
 
 	// Agent enqueue  and read stores
 	lbEnqueue              agent.EnqueueDataAccess
@@ -211,11 +469,39 @@ type Server struct {
 	appListeners           *appListeners
 	fnListeners            *fnListeners
 	triggerListeners       *triggerListeners
+	admission              *admission
 	rootMiddlewares        []fnext.Middleware
 	apiMiddlewares         []fnext.Middleware
 	promExporter           *prometheus.Exporter
+	metricsAddr            string
+	shutdownTimeout        time.Duration
 	triggerAnnotator       TriggerAnnotator
 	fnAnnotator            FnAnnotator
+	placementAudit         *pool.PlacementAuditLog
+	runnerPool             pool.RunnerPool
+	runnerRegistry         *agent.RunnerRegistry
+	lbGroups               *pool.LBGroups
+	registrar              *runnerRegistrar
+	placer                 *pool.SwitchPlacer
+	placerCfg              pool.PlacerConfig
+	reloadFile             string
+	reloadLock             sync.Mutex
+	placerAlg              string // guarded by reloadLock
+	idempotency            *idempotencyCache
+	invokeReplays          *replayFilter
+	responseCache          respcache.Store
+	rateLimits             *rateLimiter
+	cloudEventsSink        string
+	schedulerInterval      time.Duration
+	eventSourcesInterval   time.Duration
+	eventSourcesConfig     *eventsource.Config
+	readCacheTTL           time.Duration
+	asyncLease             time.Duration
+	secretsKeeper          secrets.Keeper
+	auditSinkURL           string
+	usageSinkURL           string
+	meter                  *metering.Meter
+	slos                   *sloTracker
 
 	// Extensions can append to this list of contexts so that cancellations are properly handled.
 	extraCtxs []context.Context
@@ -236,11 +522,23 @@ func nodeTypeFromString(value string) NodeType {
 	}
 }
 
+// NodeTypeFromEnv returns the type of node configured by EnvNodeType
+func NodeTypeFromEnv() NodeType {
+	return nodeTypeFromString(getEnv(EnvNodeType, "")) // default to full
+}
+
 // NewFromEnv creates a new Functions server based on env vars.
 func NewFromEnv(ctx context.Context, opts ...Option) *Server {
 	curDir := pwd()
 	var defaultDB, defaultMQ string
-	nodeType := nodeTypeFromString(getEnv(EnvNodeType, "")) // default to full
+	// settings of the reload file are read like env and override it
+	reloadFile := getEnv(EnvReloadFile, "")
+	if reloadFile != "" {
+		if _, err := loadReloadFile(ctx, reloadFile); err != nil {
+			logrus.WithError(err).Fatal("cannot load reload file")
+		}
+	}
+	nodeType := NodeTypeFromEnv()
 	switch nodeType {
 	case ServerTypeLB: // nothing
 	case ServerTypeRunner: // nothing
@@ -252,19 +550,44 @@ func NewFromEnv(ctx context.Context, opts ...Option) *Server {
 	}
 	opts = append(opts, WithWebPort(getEnvInt(EnvPort, DefaultPort)))
 	opts = append(opts, WithGRPCPort(getEnvInt(EnvGRPCPort, DefaultGRPCPort)))
+	opts = append(opts, WithGRPCInvokePort(getEnvInt(EnvGRPCInvokePort, 0)))
 	opts = append(opts, WithLogFormat(getEnv(EnvLogFormat, DefaultLogFormat)))
 	opts = append(opts, WithLogLevel(getEnv(EnvLogLevel, DefaultLogLevel)))
+	opts = append(opts, WithLogComponentLevels(getEnv(EnvLogComponentLevels, "")))
+	opts = append(opts, WithRIDProvider(&RIDProvider{HeaderName: getEnv(EnvRIDHeader, DefaultRIDHeader), RIDGenerator: common.FnRequestID}))
 	opts = append(opts, WithLogDest(getEnv(EnvLogDest, DefaultLogDest), getEnv(EnvLogPrefix, "")))
 	opts = append(opts, WithZipkin(getEnv(EnvZipkinURL, "")))
 	opts = append(opts, WithJaeger(getEnv(EnvJaegerURL, "")))
+	opts = append(opts, WithOTLP(getEnv(EnvOTLPURL, "")))
 	opts = append(opts, WithPrometheus()) // TODO option to turn this off?
+	opts = append(opts, WithMetricsExportersFromEnv())
+	opts = append(opts, WithMetricsListen(getEnv(EnvMetricsListen, "")))
+	opts = append(opts, WithReloadFile(reloadFile))
+	opts = append(opts, WithShutdownTimeout(time.Duration(getEnvInt(EnvShutdownTimeout, 0))*time.Second))
+	opts = append(opts, WithAccessLog(getEnv(EnvAccessLog, ""), getEnv(EnvAccessLogFormat, "json")))
+	opts = append(opts, WithAuthFromEnv())
+	opts = append(opts, WithReadCacheTTL(time.Duration(getEnvInt(EnvReadCacheTTL, 0))*time.Millisecond))
 	opts = append(opts, WithDBURL(getEnv(EnvDBURL, defaultDB)))
+	opts = append(opts, WithSecretsKMS(getEnv(EnvSecretsKMSURL, "")))
+	opts = append(opts, WithAdmissionWebhooksFromEnv())
+	opts = append(opts, WithAuditSink(getEnv(EnvAuditSink, "")))
+	opts = append(opts, WithUsageSink(getEnv(EnvUsageSink, "")))
 	opts = append(opts, WithMQURL(getEnv(EnvMQURL, defaultMQ)))
 	opts = append(opts, WithLogURL(getEnv(EnvLogDBURL, "")))
+	opts = append(opts, WithAsyncLease(time.Duration(getEnvInt(EnvAsyncLease, 0))*time.Millisecond))
 	opts = append(opts, WithRunnerURL(getEnv(EnvRunnerURL, "")))
 	opts = append(opts, WithType(nodeType))
 
 	opts = append(opts, LimitRequestBody(int64(getEnvInt(EnvMaxRequestSize, 0))))
+	opts = append(opts, WithScheduler(time.Duration(getEnvInt(EnvSchedulerInterval, 0))*time.Millisecond))
+	eventSourcesConfig := &eventsource.Config{}
+	if brokers := getEnv(EnvEventSourceKafkaBrokers, ""); brokers != "" {
+		eventSourcesConfig.KafkaBrokers = strings.Split(brokers, ",")
+	}
+	opts = append(opts, WithEventSources(time.Duration(getEnvInt(EnvEventSourcesInterval, 0))*time.Millisecond, eventSourcesConfig))
+	opts = append(opts, WithIdempotencyWindow(time.Duration(getEnvInt(EnvIdempotencyWindow, 0))*time.Second))
+	opts = append(opts, WithResponseCache(getEnv(EnvResponseCacheURL, "memory://")))
+	opts = append(opts, WithCloudEventsSink(getEnv(EnvCloudEventsSinkURL, "")))
 
 	publicLBURL := getEnv(EnvPublicLoadBalancerURL, "")
 	if publicLBURL != "" {
@@ -316,6 +639,17 @@ func WithGRPCPort(port int) Option {
 	}
 }
 
+// WithGRPCInvokePort maps EnvGRPCInvokePort, 0 disables the gRPC invoke front-end
+func WithGRPCInvokePort(port int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.grpcInvokeAddr = ""
+		if port > 0 {
+			s.grpcInvokeAddr = fmt.Sprintf(":%d", port)
+		}
+		return nil
+	}
+}
+
 // WithLogFormat maps EnvLogFormat
 func WithLogFormat(format string) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -332,6 +666,13 @@ func WithLogLevel(ll string) Option {
 	}
 }
 
+// WithLogComponentLevels maps EnvLogComponentLevels
+func WithLogComponentLevels(levels string) Option {
+	return func(ctx context.Context, s *Server) error {
+		return common.SetComponentLogLevels(levels)
+	}
+}
+
 // WithLogDest maps EnvLogDest
 func WithLogDest(dst, prefix string) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -349,7 +690,7 @@ func WithDBURL(dbURL string) Option {
 				return err
 			}
 			s.datastore = ds
-			s.lbReadAccess = agent.NewCachedDataAccess(s.datastore)
+			s.lbReadAccess = agent.NewCachedDataAccessTTL(s.datastore, s.readCacheTTL)
 		}
 		return nil
 	}
@@ -393,7 +734,7 @@ func WithRunnerURL(runnerURL string) Option {
 			if err != nil {
 				return err
 			}
-			s.lbReadAccess = agent.NewCachedDataAccess(cl)
+			s.lbReadAccess = agent.NewCachedDataAccessTTL(cl, s.readCacheTTL)
 		}
 		return nil
 	}
@@ -428,7 +769,7 @@ func WithDatastore(ds models.Datastore) Option {
 	return func(ctx context.Context, s *Server) error {
 		s.datastore = ds
 		if s.lbReadAccess == nil {
-			s.lbReadAccess = agent.NewCachedDataAccess(ds)
+			s.lbReadAccess = agent.NewCachedDataAccessTTL(ds, s.readCacheTTL)
 		}
 		return nil
 	}
@@ -460,11 +801,60 @@ func WithAgent(agent agent.Agent) Option {
 }
 
 func (s *Server) defaultRunnerPool() (pool.RunnerPool, error) {
+	discovery := getEnv(EnvRunnerDiscovery, "static")
+	if discovery == "static" {
+		return s.staticRunnerPool()
+	}
+
+	interval := time.Duration(getEnvInt(EnvRunnerDiscoveryInterval, 10000)) * time.Millisecond
+	if interval <= 0 {
+		return nil, errors.New("FN_RUNNER_DISCOVERY_INTERVAL_MSECS must be positive")
+	}
+	if discovery == "registration" {
+		s.runnerRegistry = agent.NewRunnerRegistry(time.Duration(getEnvInt(EnvRunnerRegistrationTTL, 15000)) * time.Millisecond)
+		return agent.NewDynamicRunnerPool(s.runnerRegistry, interval, nil, runnerFactory()), nil
+	}
+
+	target := getEnv(EnvRunnerDiscoveryTarget, "")
+	if target == "" {
+		return nil, errors.New("must provide FN_RUNNER_DISCOVERY_TARGET when discovering runners")
+	}
+
+	var provider pool.NodeProvider
+	switch discovery {
+	case "dns-srv":
+		provider = agent.NewDNSSRVNodeProvider(target)
+	case "kubernetes":
+		var err error
+		provider, err = agent.NewKubernetesNodeProvider(target)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid FN_RUNNER_DISCOVERY %q, must be one of static, dns-srv, kubernetes, registration", discovery)
+	}
+	return agent.NewDynamicRunnerPool(provider, interval, nil, runnerFactory()), nil
+}
+
+// runnerFactory returns the factory of runners connected as configured by env
+func runnerFactory() pool.MTLSRunnerFactory {
+	cfg := agent.NewRunnerConnConfig()
+	cfg.DialTimeout = time.Duration(getEnvInt(EnvLBRunnerDialTimeout, int(cfg.DialTimeout/time.Millisecond))) * time.Millisecond
+	cfg.KeepaliveTime = time.Duration(getEnvInt(EnvLBRunnerKeepalive, 0)) * time.Millisecond
+	cfg.KeepaliveTimeout = time.Duration(getEnvInt(EnvLBRunnerKeepaliveTimeout, int(cfg.KeepaliveTimeout/time.Millisecond))) * time.Millisecond
+	cfg.InitialWindowSize = int32(getEnvInt(EnvLBRunnerWindowSize, 0))
+	cfg.InitialConnWindowSize = int32(getEnvInt(EnvLBRunnerConnWindowSize, 0))
+	cfg.HeartbeatInterval = time.Duration(getEnvInt(EnvLBRunnerHeartbeat, int(cfg.HeartbeatInterval/time.Millisecond))) * time.Millisecond
+	cfg.HeartbeatMisses = getEnvInt(EnvLBRunnerHeartbeatMisses, cfg.HeartbeatMisses)
+	return agent.NewGRPCRunnerFactory(cfg)
+}
+
+func (s *Server) staticRunnerPool() (pool.RunnerPool, error) {
 	runnerAddresses := getEnv(EnvRunnerAddresses, "")
 	if runnerAddresses == "" {
 		return nil, errors.New("must provide FN_RUNNER_ADDRESSES  when running in default load-balanced mode")
 	}
-	return agent.DefaultStaticRunnerPool(strings.Split(runnerAddresses, ",")), nil
+	return agent.NewStaticRunnerPool(strings.Split(runnerAddresses, ","), nil, runnerFactory()), nil
 }
 
 // WithLogstoreFromDatastore sets the logstore to the datastore, iff
@@ -499,8 +889,19 @@ func WithFullAgent() Option {
 			return errors.New("full nodes must configure FN_DB_URL, FN_LOG_URL, FN_MQ_URL")
 		}
 		da := agent.NewDirectCallDataAccess(s.logstore, s.mq)
+		if s.asyncLease > 0 {
+			var err error
+			da, err = agent.NewLeasedCallDataAccess(s.logstore, s.mq, s.asyncLease)
+			if err != nil {
+				return err
+			}
+		}
 		dq := agent.NewDirectDequeueAccess(s.mq)
-		s.agent = agent.New(da, agent.WithAsync(dq))
+		agentOpts := []agent.Option{agent.WithAsync(dq), agent.WithChainReads(agent.NewCachedDataAccess(s.datastore))}
+		if ss, ok := s.datastore.(models.SecretStore); ok && s.secretsKeeper != nil {
+			agentOpts = append(agentOpts, agent.WithSecretSource(secrets.NewSource(ss, s.secretsKeeper)))
+		}
+		s.agent = agent.New(da, agentOpts...)
 		return nil
 	}
 }
@@ -541,6 +942,21 @@ func WithAgentFromEnv() Option {
 			}
 			s.agent = prAgent
 			s.extraCtxs = append(s.extraCtxs, cancelCtx)
+
+			if lbURL := getEnv(EnvRunnerRegisterURL, ""); lbURL != "" {
+				heartbeat := time.Duration(getEnvInt(EnvRunnerHeartbeat, 5000)) * time.Millisecond
+				if heartbeat <= 0 {
+					return errors.New("FN_RUNNER_HEARTBEAT_MSECS must be positive")
+				}
+				s.registrar, err = newRunnerRegistrar(lbURL, getEnv(EnvRunnerAdvertiseAddress, ""), s.svcConfigs[GRPCServer].Addr,
+					getEnv(EnvRunnerZone, ""), getEnv(EnvRunnerLabels, ""), heartbeat)
+				if err != nil {
+					return err
+				}
+				if r, ok := s.agent.(agent.FnMetricsReporter); ok {
+					s.registrar.metrics = r
+				}
+			}
 		case ServerTypeLB:
 			s.nodeType = ServerTypeLB
 			runnerURL := getEnv(EnvRunnerURL, "")
@@ -563,19 +979,68 @@ func WithAgentFromEnv() Option {
 			if err != nil {
 				return err
 			}
+			// faults are injected under every other pool, for them to see
+			// the runners delayed and rejecting calls
+			enableChaos, _ := strconv.ParseBool(getEnv(agent.EnvChaosEnable, "false"))
+			faults, err := chaos.FromSpec(getEnv(agent.EnvChaos, ""), enableChaos)
+			if err != nil {
+				return err
+			}
+			if faults != nil {
+				runnerPool = pool.NewChaosPool(runnerPool, faults)
+			}
+
+			var labels pool.RunnerLabels
+			if s.runnerRegistry != nil {
+				labels = s.runnerRegistry.Labels
+			}
+			s.lbGroups = pool.NewLBGroups()
+			runnerPool = pool.NewLBGroupPool(runnerPool, s.lbGroups, labels)
+			runnerPool = pool.NewNodeSelectorPool(runnerPool, labels)
+			runnerPool = pool.NewAntiAffinityPool(runnerPool)
+
+			var provisioner pool.Provisioner
+			if hook := getEnv(EnvLBScaleHook, ""); hook != "" {
+				provisioner = pool.NewExecProvisioner(hook)
+			} else if webhook := getEnv(EnvLBScaleWebhook, ""); webhook != "" {
+				provisioner = pool.NewWebhookProvisioner(webhook)
+			}
+			if provisioner != nil {
+				scalerCfg := pool.NewScalerConfig()
+				scalerCfg.MinRunners = getEnvInt(EnvLBScaleMinRunners, scalerCfg.MinRunners)
+				scalerCfg.MaxRunners = getEnvInt(EnvLBScaleMaxRunners, scalerCfg.MaxRunners)
+				runnerPool = pool.NewScalingPool(runnerPool, provisioner, &scalerCfg)
+			}
+
+			if threshold := getEnvInt(EnvLBCircuitBreakerThreshold, 0); threshold > 0 {
+				breakerCfg := pool.NewCircuitBreakerConfig()
+				breakerCfg.ErrorThreshold = float64(threshold) / 100
+				breakerCfg.OpenTimeout = time.Duration(getEnvInt(EnvLBCircuitBreakerOpenTimeout, int(breakerCfg.OpenTimeout/time.Millisecond))) * time.Millisecond
+				runnerPool = pool.NewCircuitBreakerPool(runnerPool, &breakerCfg)
+			}
+
+			if size := getEnvInt(EnvLBActivatorQueueSize, 0); size > 0 {
+				activatorCfg := pool.NewActivatorConfig()
+				activatorCfg.MaxQueued = size
+				activatorCfg.Timeout = time.Duration(getEnvInt(EnvLBActivatorTimeout, int(activatorCfg.Timeout/time.Millisecond))) * time.Millisecond
+				runnerPool = pool.NewActivatorPool(runnerPool, &activatorCfg)
+			}
 
 			// Select the placement algorithm
 			placerCfg := pool.NewPlacerConfig()
-			var placer pool.Placer
-			switch getEnv(EnvLBPlacementAlg, "") {
-			case "ch":
-				placer = pool.NewCHPlacer(&placerCfg)
-			default:
-				placer = pool.NewNaivePlacer(&placerCfg)
+			if size := getEnvInt(EnvLBPlacementAuditSize, 0); size > 0 {
+				s.placementAudit = pool.NewPlacementAuditLog(size)
+				placerCfg.AuditLog = s.placementAudit
 			}
-
-			s.lbReadAccess = agent.NewCachedDataAccess(cl)
-			s.agent, err = agent.NewLBAgent(cl, runnerPool, placer)
+			placerCfg.MaxFailovers = getEnvInt(EnvLBMaxFailovers, 0)
+			placer, alg := newPlacer(&placerCfg)
+			s.placer = pool.NewSwitchPlacer(placer)
+			s.placerCfg = placerCfg
+			s.placerAlg = alg
+			s.runnerPool = runnerPool
+
+			s.lbReadAccess = agent.NewCachedDataAccessTTL(cl, s.readCacheTTL)
+			s.agent, err = agent.NewLBAgent(cl, runnerPool, s.placer)
 			if err != nil {
 				return errors.New("LBAgent creation failed")
 			}
@@ -586,6 +1051,23 @@ func WithAgentFromEnv() Option {
 	}
 }
 
+// newPlacer returns the placer of the placement algorithm set by env and its name
+func newPlacer(cfg *pool.PlacerConfig) (pool.Placer, string) {
+	alg := getEnv(EnvLBPlacementAlg, "naive")
+	switch alg {
+	case "ch":
+		return pool.NewCHPlacer(cfg), alg
+	case "spread":
+		return pool.NewSpreadPlacer(cfg), alg
+	case "binpack":
+		return pool.NewBinPackPlacer(cfg, getEnvInt(EnvLBBinPackTarget, 10)), alg
+	case "zone":
+		spillWait := time.Duration(getEnvInt(EnvLBZoneSpillWait, 100)) * time.Millisecond
+		return pool.NewZonePlacer(cfg, getEnv(EnvLBZone, ""), spillWait), alg
+	}
+	return pool.NewNaivePlacer(cfg), "naive"
+}
+
 // WithExtraCtx appends a context to the list of contexts the server will watch for cancellations / errors / signals.
 func WithExtraCtx(extraCtx context.Context) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -635,9 +1117,12 @@ func New(ctx context.Context, opts ...Option) *Server {
 	log := common.Logger(ctx)
 	engine := gin.New()
 	s := &Server{
-		Router:      engine,
-		AdminRouter: engine,
-		lbEnqueue:   agent.NewUnsupportedAsyncEnqueueAccess(),
+		Router:        engine,
+		AdminRouter:   engine,
+		lbEnqueue:     agent.NewUnsupportedAsyncEnqueueAccess(),
+		rateLimits:    newRateLimiter(),
+		admission:     new(admission),
+		invokeReplays: newReplayFilter(),
 		svcConfigs: map[string]*http.Server{
 			WebServer:   &http.Server{},
 			AdminServer: &http.Server{},
@@ -721,8 +1206,31 @@ func New(ctx context.Context, opts ...Option) *Server {
 
 	// TODO it's not clear that this is always correct as the read store  won't  get wrapping
 	s.datastore = datastore.Wrap(s.datastore)
-	s.datastore = fnext.NewDatastore(s.datastore, s.appListeners, s.fnListeners, s.triggerListeners)
 	s.logstore = logs.Wrap(s.logstore)
+	if s.auditSinkURL != "" {
+		sink, err := audit.New(ctx, s.auditSinkURL, s.logstore)
+		if err != nil {
+			log.WithError(err).Fatal("Error creating the audit sink")
+		}
+		s.datastore = audit.Wrap(s.datastore, sink)
+	}
+	s.slos = newSLOTracker()
+	if s.agent != nil {
+		s.AddCallListener(s.slos)
+	}
+	if s.usageSinkURL != "" && s.agent != nil {
+		sink, err := metering.New(ctx, s.usageSinkURL, s.logstore)
+		if err != nil {
+			log.WithError(err).Fatal("Error creating the usage sink")
+		}
+		s.meter = metering.NewMeter(sink)
+		s.AddCallListener(s.meter)
+	}
+	s.admission.ds = s.datastore
+	s.AddAppListener(s.admission)
+	s.AddFnListener(s.admission)
+	s.AddTriggerListener(s.admission)
+	s.datastore = fnext.NewDatastore(s.datastore, s.appListeners, s.fnListeners, s.triggerListeners)
 
 	return s
 }
@@ -797,6 +1305,24 @@ func WithoutCallEndpoints() Option {
 	}
 }
 
+// WithMetricsListen maps EnvMetricsListen, metrics are served on addr as well
+// as the admin server if it is set
+func WithMetricsListen(addr string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.metricsAddr = addr
+		return nil
+	}
+}
+
+// WithShutdownTimeout maps EnvShutdownTimeout, once it passes the connections
+// still open are closed and calls still running are abandoned
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.shutdownTimeout = timeout
+		return nil
+	}
+}
+
 // WithJaeger maps EnvJaegerURL
 func WithJaeger(jaegerURL string) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -821,6 +1347,65 @@ func WithJaeger(jaegerURL string) Option {
 	}
 }
 
+// WithOTLP maps EnvOTLPURL
+func WithOTLP(otlpURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		// ex: "http://localhost:4318"
+		if otlpURL == "" {
+			return nil
+		}
+
+		exporter, err := otlp.NewExporter(otlp.Options{
+			Endpoint:    otlpURL,
+			ServiceName: "fn",
+			OnError: func(err error) {
+				logrus.WithError(err).Error("otlp export error")
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error configuring otlp exporter: %v", err)
+		}
+		trace.RegisterExporter(exporter)
+		view.RegisterExporter(exporter)
+		logrus.WithFields(logrus.Fields{"url": otlpURL}).Info("exporting spans and metrics over otlp")
+
+		// TODO don't do this. testing parity.
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+		return nil
+	}
+}
+
+// WithMetricsExporters exports views to the metrics backends of
+// exporterURLs, see metrics.New, with tags added to every metric
+func WithMetricsExporters(exporterURLs []string, tags map[string]string) Option {
+	return func(ctx context.Context, s *Server) error {
+		for _, u := range exporterURLs {
+			exporter, err := metrics.New(u, tags)
+			if err != nil {
+				return err
+			}
+			view.RegisterExporter(exporter)
+			logrus.WithFields(logrus.Fields{"url": u}).Info("exporting metrics")
+		}
+		return nil
+	}
+}
+
+// WithMetricsExportersFromEnv maps EnvMetricsExporters and EnvMetricsTags
+func WithMetricsExportersFromEnv() Option {
+	return func(ctx context.Context, s *Server) error {
+		exporters := getEnv(EnvMetricsExporters, "")
+		if exporters == "" {
+			return nil
+		}
+		tags, err := metrics.ParseTags(getEnv(EnvMetricsTags, ""))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", EnvMetricsTags, err)
+		}
+		return WithMetricsExporters(strings.Split(exporters, ","), tags)(ctx, s)
+	}
+}
+
 // WithZipkin maps EnvZipkinURL
 func WithZipkin(zipkinURL string) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -1001,9 +1586,16 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 
 	installChildReaper()
 
+	// set once stopping, deferred shutdowns below see the context of the
+	// grace period
+	shutdownCtx := context.Background()
+
 	server := s.svcConfigs[WebServer]
 	if server.Handler == nil {
-		server.Handler = &ochttp.Handler{Handler: s.Router}
+		server.Handler = &ochttp.Handler{Handler: s.Router, Propagation: &tracecontext.HTTPFormat{}}
+	}
+	if server.TLSConfig == nil {
+		server.Handler = newH2CHandler(server.Handler, server)
 	}
 
 	go func() {
@@ -1025,7 +1617,10 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 		logrus.WithField("type", s.nodeType).Infof("Fn Admin serving on `%v`", s.svcConfigs[AdminServer].Addr)
 		adminServer := s.svcConfigs[AdminServer]
 		if adminServer.Handler == nil {
-			adminServer.Handler = &ochttp.Handler{Handler: s.AdminRouter}
+			adminServer.Handler = &ochttp.Handler{Handler: s.AdminRouter, Propagation: &tracecontext.HTTPFormat{}}
+		}
+		if adminServer.TLSConfig == nil {
+			adminServer.Handler = newH2CHandler(adminServer.Handler, adminServer)
 		}
 
 		go func() {
@@ -1044,12 +1639,73 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 		}()
 
 		defer func() {
-			if err := adminServer.Shutdown(context.Background()); err != nil {
+			if err := adminServer.Shutdown(shutdownCtx); err != nil {
 				logrus.WithError(err).Error("admin server shutdown error")
 			}
 		}()
 	}
 
+	if s.metricsAddr != "" && s.promExporter != nil {
+		logrus.WithField("type", s.nodeType).Infof("Fn metrics serving on `%v`", s.metricsAddr)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", s.promExporter)
+		metricsServer := &http.Server{Addr: s.metricsAddr, Handler: mux}
+
+		go func() {
+			err := metricsServer.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logrus.WithError(err).Error("metrics server error")
+				cancel()
+			}
+		}()
+
+		defer func() {
+			if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+				logrus.WithError(err).Error("metrics server shutdown error")
+			}
+		}()
+	}
+
+	switch s.nodeType {
+	case ServerTypeFull, ServerTypeLB, ServerTypeRunner:
+		if s.grpcInvokeAddr != "" {
+			logrus.WithField("type", s.nodeType).Infof("Fn gRPC invoke serving on `%v`", s.grpcInvokeAddr)
+			gs, err := s.serveGRPCInvoke(s.grpcInvokeAddr, server.Handler, cancel)
+			if err != nil {
+				logrus.WithError(err).Error("grpc invoke server error")
+				cancel()
+			} else {
+				defer func() { stopGRPC(shutdownCtx, gs) }()
+			}
+		}
+	}
+
+	if w, ok := s.lbReadAccess.(agent.ChangeWatcher); ok && s.datastore != nil {
+		if cf, ok := s.datastore.(models.ChangeFeed); ok {
+			go func() {
+				if err := w.WatchChanges(ctx, cf); err != nil {
+					logrus.WithError(err).Info("datastore changes are not watched, cached reads expire after their ttl")
+				}
+			}()
+		}
+	}
+
+	if s.reloadFile != "" {
+		go s.watchReloadFile(ctx)
+	}
+
+	if s.registrar != nil {
+		go s.registrar.run(ctx)
+	}
+
+	if s.schedulerInterval > 0 {
+		go newScheduler(s.datastore, s.lbEnqueue).run(ctx, s.schedulerInterval)
+	}
+
+	if s.eventSourcesInterval > 0 {
+		go newEventSources(s.datastore, s.lbEnqueue, s.eventSourcesConfig).run(ctx, s.eventSourcesInterval)
+	}
+
 	// listening for signals or listener errors or cancellations on all registered contexts.
 	s.extraCtxs = append(s.extraCtxs, ctx)
 	cases := make([]reflect.SelectCase, len(s.extraCtxs))
@@ -1068,17 +1724,32 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 		}).Debug("Stopping because of closed channel from done context.")
 	}
 
-	// TODO: do not wait forever during graceful shutdown (add graceful shutdown timeout)
-	if err := server.Shutdown(context.Background()); err != nil {
+	if s.registrar != nil {
+		if err := s.registrar.deregister(); err != nil {
+			logrus.WithError(err).Warn("Failed to deregister runner from lb")
+		}
+	}
+
+	logrus.WithField("timeout", s.shutdownTimeout).Info("shutting down, draining in-flight requests")
+	shutdownCtx, cancelShutdown := s.shutdownContext()
+	defer cancelShutdown()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		logrus.WithError(err).Error("server shutdown error")
 	}
 
 	if s.agent != nil {
-		err := s.agent.Close() // after we stop taking requests, wait for all tasks to finish
-		if err != nil {
+		// after we stop taking requests, wait for all tasks to finish
+		err := waitShutdown(shutdownCtx, s.agent.Close)
+		if err == context.DeadlineExceeded {
+			logrus.Warn("shutdown timeout passed, abandoning calls still in flight")
+		} else if err != nil {
 			logrus.WithError(err).Error("Fail to close the agent")
 		}
 	}
+	if s.meter != nil {
+		s.meter.Close()
+	}
 }
 
 func (s *Server) goneResponse(c *gin.Context) {
@@ -1100,6 +1771,36 @@ func (s *Server) bindHandlers(ctx context.Context) {
 	}
 
 	profilerSetup(admin, "/debug")
+	if s.placementAudit != nil {
+		admin.GET("/debug/placement/:callID", s.handlePlacementAudit)
+	}
+	if s.runnerPool != nil {
+		admin.GET("/lb/status", s.handleLBStatus)
+	}
+	if s.lbGroups != nil {
+		admin.GET("/lbgroups", s.handleLBGroupList)
+		admin.POST("/lbgroups", s.handleLBGroupCreate)
+		admin.GET("/lbgroups/:groupID", s.handleLBGroupGet)
+		admin.PUT("/lbgroups/:groupID", s.handleLBGroupPut)
+		admin.DELETE("/lbgroups/:groupID", s.handleLBGroupDelete)
+		admin.PUT("/lbgroups/:groupID/canary", s.handleLBGroupCanaryPut)
+		admin.DELETE("/lbgroups/:groupID/canary", s.handleLBGroupCanaryDelete)
+	}
+	if s.runnerRegistry != nil {
+		admin.POST("/runners", s.handleRegisterRunner)
+		admin.GET("/runners", s.handleListRunners)
+		admin.DELETE("/runners/:address", s.handleDeregisterRunner)
+	}
+	if _, ok := s.agent.(agent.FnMetricsReporter); ok || s.runnerRegistry != nil {
+		admin.GET("/fnmetrics", s.handleFnMetrics)
+	}
+	admin.GET("/loglevels", handleLogLevelsGet)
+	admin.PUT("/loglevels", handleLogLevelsPut)
+	if _, ok := s.agent.(agent.AssetManager); ok {
+		admin.GET("/assets", s.handleAssetList)
+		admin.PUT("/assets/:name", s.handleAssetPut)
+		admin.DELETE("/assets/:name", s.handleAssetDelete)
+	}
 
 	// Pure runners don't have any route, they have grpc
 	switch s.nodeType {
@@ -1108,19 +1809,53 @@ func (s *Server) bindHandlers(ctx context.Context) {
 		cleanv2 := engine.Group("/v2")
 		v2 := cleanv2.Group("")
 		v2.Use(s.apiMiddlewareWrapper())
+		v2.Use(s.namespaceScopeWrap)
 
 		{
+			v2.GET("/openapi.json", s.handleOpenAPI)
+
+			v2.GET("/namespaces", s.handleNamespaceList)
+			v2.POST("/namespaces", s.handleNamespaceCreate)
+			v2.GET("/namespaces/:namespaceID", s.handleNamespaceGet)
+			v2.PUT("/namespaces/:namespaceID", s.handleNamespaceUpdate)
+			v2.DELETE("/namespaces/:namespaceID", s.handleNamespaceDelete)
+
+			v2.GET("/rolebindings", s.handleRoleBindingList)
+			v2.POST("/rolebindings", s.handleRoleBindingCreate)
+			v2.GET("/rolebindings/:bindingID", s.handleRoleBindingGet)
+			v2.DELETE("/rolebindings/:bindingID", s.handleRoleBindingDelete)
+
+			v2.GET("/keys", s.handleAPIKeyList)
+			v2.POST("/keys", s.handleAPIKeyCreate)
+			v2.GET("/keys/:keyID", s.handleAPIKeyGet)
+			v2.DELETE("/keys/:keyID", s.handleAPIKeyRevoke)
+			v2.POST("/keys/:keyID/rotate", s.handleAPIKeyRotate)
+
 			v2.GET("/apps", s.handleAppList)
 			v2.POST("/apps", s.handleAppCreate)
 			v2.GET("/apps/:appID", s.handleAppGet)
 			v2.PUT("/apps/:appID", s.handleAppUpdate)
 			v2.DELETE("/apps/:appID", s.handleAppDelete)
 
+			v2.GET("/apps/:appID/secrets", s.handleSecretList)
+			v2.PUT("/apps/:appID/secrets/:secretName", s.handleSecretPut)
+			v2.DELETE("/apps/:appID/secrets/:secretName", s.handleSecretDelete)
+
+			v2.GET("/apps/:appID/export", s.handleAppExport)
+			v2.GET("/apps/:appID/usage", s.handleAppUsage)
+			v2.PUT("/apps/:appID/export", s.handleAppImport)
+
 			v2.GET("/fns", s.handleFnList)
 			v2.POST("/fns", s.handleFnCreate)
 			v2.GET("/fns/:fnID", s.handleFnGet)
 			v2.PUT("/fns/:fnID", s.handleFnUpdate)
 			v2.DELETE("/fns/:fnID", s.handleFnDelete)
+			v2.GET("/fns/:fnID/revisions", s.handleFnRevisionList)
+			v2.GET("/fns/:fnID/revisions/:revisionID", s.handleFnRevisionGet)
+			v2.POST("/fns/:fnID/revisions/:revisionID/rollback", s.handleFnRevisionRollback)
+			v2.GET("/fns/:fnID/slo", s.handleFnSLO)
+
+			v2.GET("/audit", s.handleAuditList)
 
 			v2.GET("/triggers", s.handleTriggerList)
 			v2.POST("/triggers", s.handleTriggerCreate)
@@ -1130,13 +1865,21 @@ func (s *Server) bindHandlers(ctx context.Context) {
 		}
 
 		if !s.noCallEndpoints {
+			v2.DELETE("/calls/:callID", s.handleCallCancel)
 			v2.GET("/fns/:fnID/calls", s.handleCallList)
 			v2.GET("/fns/:fnID/calls/:callID", s.handleCallGet)
 			v2.GET("/fns/:fnID/calls/:callID/log", s.handleCallLogGet)
+			v2.GET("/fns/:fnID/logs", s.handleLogSearch)
+			v2.GET("/fns/:fnID/dlq", s.handleDeadLetterList)
+			v2.POST("/fns/:fnID/dlq", s.handleDeadLetterRedrive)
 		} else {
+			v2.DELETE("/calls/:callID", s.goneResponse)
 			v2.GET("/fns/:fnID/calls", s.goneResponse)
 			v2.GET("/fns/:fnID/calls/:callID", s.goneResponse)
 			v2.GET("/fns/:fnID/calls/:callID/log", s.goneResponse)
+			v2.GET("/fns/:fnID/logs", s.goneResponse)
+			v2.GET("/fns/:fnID/dlq", s.goneResponse)
+			v2.POST("/fns/:fnID/dlq", s.goneResponse)
 		}
 
 		if !s.noHybridAPI { // Hybrid API - this should only be enabled on API servers
@@ -1156,17 +1899,27 @@ func (s *Server) bindHandlers(ctx context.Context) {
 		}
 	}
 
+	if s.nodeType == ServerTypeLB && !s.noCallEndpoints {
+		// lbs cancel the calls they place
+		lbCalls := engine.Group("/v2/calls")
+		lbCalls.Use(s.apiMiddlewareWrapper())
+		lbCalls.DELETE("/:callID", s.handleCallCancel)
+	}
+
 	switch s.nodeType {
 	case ServerTypeFull, ServerTypeLB, ServerTypeRunner:
 		if !s.noHTTTPTriggerEndpoint {
 			lbTriggerGroup := engine.Group("/t")
 			lbTriggerGroup.Any("/:appName", s.handleHTTPTriggerCall)
 			lbTriggerGroup.Any("/:appName/*triggerSource", s.handleHTTPTriggerCall)
+
+			engine.POST("/ce/:appName", s.handleCloudEventCall)
 		}
 
 		if !s.noFnInvokeEndpoint {
 			lbFnInvokeGroup := engine.Group("/invoke")
 			lbFnInvokeGroup.POST("/:fnID", s.handleFnInvokeCall)
+			lbFnInvokeGroup.GET("/ws/:fnID", s.handleFnInvokeWebSocket)
 		}
 	}
 
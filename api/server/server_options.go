@@ -2,10 +2,19 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/fnproject/fn/api/auth"
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/eventsource"
+	"github.com/fnproject/fn/api/respcache"
+	"github.com/fnproject/fn/api/secrets"
+	"github.com/fnproject/fn/fnext"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -20,7 +29,8 @@ type RIDProvider struct {
 }
 
 // WithRIDProvider will generate request ids for each http request using the
-// given generator.
+// given generator. The request id is returned in the header of the provider
+// and is kept by requests which already have one, from an earlier provider.
 func WithRIDProvider(ridProvider *RIDProvider) Option {
 	return func(ctx context.Context, s *Server) error {
 		s.Router.Use(withRIDProvider(ridProvider))
@@ -30,12 +40,17 @@ func WithRIDProvider(ridProvider *RIDProvider) Option {
 
 func withRIDProvider(ridp *RIDProvider) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		rid := ridp.RIDGenerator(c.Request.Header.Get(ridp.HeaderName))
-		ctx := common.WithRequestID(c.Request.Context(), rid)
-		// We set the rid in the common logger so it is always logged when the common logger is used
-		l := common.Logger(ctx).WithFields(logrus.Fields{common.RequestIDContextKey: rid})
-		ctx = common.WithLogger(ctx, l)
-		c.Request = c.Request.WithContext(ctx)
+		ctx := c.Request.Context()
+		rid := common.RequestIDFromContext(ctx)
+		if rid == "" {
+			rid = ridp.RIDGenerator(c.Request.Header.Get(ridp.HeaderName))
+			ctx = common.WithRequestID(ctx, rid)
+			// We set the rid in the common logger so it is always logged when the common logger is used
+			l := common.Logger(ctx).WithFields(logrus.Fields{common.RequestIDContextKey: rid})
+			ctx = common.WithLogger(ctx, l)
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Header(ridp.HeaderName, rid)
 		c.Next()
 	}
 }
@@ -58,6 +73,218 @@ func LimitRequestBody(max int64) Option {
 	}
 }
 
+// WithIdempotencyWindow makes invocations with an Idempotency-Key header return the
+// response of the first call with the same key for the same fn within window, if it
+// was made to this server.
+func WithIdempotencyWindow(window time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		if window > 0 {
+			s.idempotency = newIdempotencyCache(window, idempotencyMaxEntries, idempotencyMaxBytes)
+		}
+		return nil
+	}
+}
+
+// WithResponseCache caches the responses of fns with a response cache annotation
+// in the store at url, see respcache.New, an empty url disables response caching.
+func WithResponseCache(url string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if url == "" {
+			return nil
+		}
+		store, err := respcache.New(url)
+		if err != nil {
+			return err
+		}
+		s.responseCache = store
+		return nil
+	}
+}
+
+// WithCloudEventsSink sets the url the CloudEvents returned by fns bound to
+// CloudEvents are posted to, see models.TriggerCloudEventAnnotation.
+func WithCloudEventsSink(url string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.cloudEventsSink = url
+		return nil
+	}
+}
+
+// WithEventSources runs the event sources of triggers bound to them, syncing them
+// with the triggers every interval, see eventsource.Provider. A zero interval
+// disables event sources.
+func WithEventSources(interval time.Duration, cfg *eventsource.Config) Option {
+	return func(ctx context.Context, s *Server) error {
+		if interval <= 0 {
+			return nil
+		}
+		if s.datastore == nil || s.mq == nil {
+			return errors.New("event sources require a datastore and a message queue (FN_DB_URL, FN_MQ_URL)")
+		}
+		s.eventSourcesInterval = interval
+		s.eventSourcesConfig = cfg
+		return nil
+	}
+}
+
+// WithReadCacheTTL sets how long apps, fns and triggers read to handle invocations are
+// cached, it must precede the options setting the datastore or runner api url.
+func WithReadCacheTTL(ttl time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.readCacheTTL = ttl
+		return nil
+	}
+}
+
+// WithAsyncLease completes the async calls of full nodes exactly once, leasing
+// them from the queue for ttl, see EnvAsyncLease. It must precede the agent
+// options.
+func WithAsyncLease(ttl time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.asyncLease = ttl
+		return nil
+	}
+}
+
+// WithScheduler runs the scheduler queueing calls of fns with a schedule
+// annotation, checking for due fns every interval.
+func WithScheduler(interval time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		if interval <= 0 {
+			return nil
+		}
+		if s.datastore == nil || s.mq == nil {
+			return errors.New("the scheduler requires a datastore and a message queue (FN_DB_URL, FN_MQ_URL)")
+		}
+		s.schedulerInterval = interval
+		return nil
+	}
+}
+
+// WithAuth makes requests authenticate with one of providers and hold the scope
+// their endpoint needs, see auth.DefaultScope, or be bound to a role granting it
+// on the app or namespace they target if the datastore stores role bindings.
+// Requests may also authenticate with the API keys of the datastore, see
+// models.APIKey. Authentication runs before any other root or API middleware.
+// Without providers requests are not authenticated.
+func WithAuth(providers ...auth.Provider) Option {
+	return func(ctx context.Context, s *Server) error {
+		if len(providers) == 0 {
+			return nil
+		}
+		providers = append([]auth.Provider{auth.NewAPIKeys(apiKeyGetter{s})}, providers...)
+		m := auth.NewAuthorizingMiddleware(nil, &roleAuthorizer{s}, providers...)
+		s.rootMiddlewares = append([]fnext.Middleware{m}, s.rootMiddlewares...)
+		return nil
+	}
+}
+
+// WithAuthFromEnv maps EnvAuthKeysFile, EnvAuthJWTSecret, EnvAuthOIDCIssuer,
+// EnvAuthAudience and EnvAuthClientCertScopes to auth providers.
+func WithAuthFromEnv() Option {
+	return func(ctx context.Context, s *Server) error {
+		var providers []auth.Provider
+		if path := getEnv(EnvAuthKeysFile, ""); path != "" {
+			p, err := auth.LoadStaticKeys(path)
+			if err != nil {
+				return err
+			}
+			providers = append(providers, p)
+		}
+		audience := getEnv(EnvAuthAudience, "")
+		if secret := getEnv(EnvAuthJWTSecret, ""); secret != "" {
+			p, err := auth.NewJWT(auth.JWTConfig{Secret: []byte(secret), Audience: audience})
+			if err != nil {
+				return err
+			}
+			providers = append(providers, p)
+		}
+		if issuer := getEnv(EnvAuthOIDCIssuer, ""); issuer != "" {
+			p, err := auth.NewOIDC(ctx, issuer, audience)
+			if err != nil {
+				return err
+			}
+			providers = append(providers, p)
+		}
+		if scopes := getEnv(EnvAuthClientCertScopes, ""); scopes != "" {
+			providers = append(providers, auth.NewClientCert(auth.ParseScopes(scopes)))
+		}
+
+		if len(providers) > 0 {
+			logrus.WithField("providers", len(providers)).Info("API authentication enabled")
+		}
+		return WithAuth(providers...)(ctx, s)
+	}
+}
+
+// WithSecretsKMS encrypts app secrets with the key management service of
+// kmsURL, see secrets.New. Full agents inject the secrets into the containers
+// of the fns of their app.
+func WithSecretsKMS(kmsURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if kmsURL == "" {
+			return nil
+		}
+		k, err := secrets.New(ctx, kmsURL)
+		if err != nil {
+			return err
+		}
+		s.secretsKeeper = k
+		return nil
+	}
+}
+
+// WithAuditSink records the changes made to apps, fns, triggers and secrets
+// to the sink of sinkURL, see audit.New.
+func WithAuditSink(sinkURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.auditSinkURL = sinkURL
+		return nil
+	}
+}
+
+// WithUsageSink writes the usage records of the calls run by the server to
+// the sink of sinkURL, see metering.New.
+func WithUsageSink(sinkURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.usageSinkURL = sinkURL
+		return nil
+	}
+}
+
+// WithAdmissionWebhooks adds an admission controller for each webhook url,
+// see NewAdmissionWebhook.
+func WithAdmissionWebhooks(urls []string, policy fnext.AdmissionFailurePolicy) Option {
+	return func(ctx context.Context, s *Server) error {
+		for _, url := range urls {
+			s.AddAdmissionController(NewAdmissionWebhook(url), policy)
+		}
+		return nil
+	}
+}
+
+// WithAdmissionWebhooksFromEnv adds the admission webhooks of
+// EnvAdmissionWebhooks, failing as set by EnvAdmissionFailOpen.
+func WithAdmissionWebhooksFromEnv() Option {
+	return func(ctx context.Context, s *Server) error {
+		webhooks := getEnv(EnvAdmissionWebhooks, "")
+		if webhooks == "" {
+			return nil
+		}
+		policy := fnext.AdmissionFailClosed
+		if failOpen := getEnv(EnvAdmissionFailOpen, ""); failOpen != "" {
+			open, err := strconv.ParseBool(failOpen)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", EnvAdmissionFailOpen, err)
+			}
+			if open {
+				policy = fnext.AdmissionFailOpen
+			}
+		}
+		return WithAdmissionWebhooks(strings.Split(webhooks, ","), policy)(ctx, s)
+	}
+}
+
 func limitRequestBody(max int64) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		cl := int64(c.Request.ContentLength)
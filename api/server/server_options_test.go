@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	ridp := &RIDProvider{HeaderName: DefaultRIDHeader, RIDGenerator: common.FnRequestID}
+	// a second provider, as the lb and API server of a full node would be,
+	// keeps the request id of the first
+	srv := testServer(datastore.NewMock(), &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithRIDProvider(ridp), WithRIDProvider(ridp))
+	var rid string
+	srv.Router.GET("/rid", func(c *gin.Context) {
+		rid = common.RequestIDFromContext(c.Request.Context())
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rid", nil))
+	if rid == "" || rec.Header().Get(DefaultRIDHeader) != rid {
+		t.Fatalf("Expected a request id to be generated and returned, got %q %q", rid, rec.Header().Get(DefaultRIDHeader))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/rid", nil)
+	req.Header.Set(DefaultRIDHeader, "rid1")
+	rec = httptest.NewRecorder()
+	srv.Router.ServeHTTP(rec, req)
+	if rid != "rid1" || rec.Header().Get(DefaultRIDHeader) != "rid1" {
+		t.Fatalf("Expected the request id of the request to be kept, got %q %q", rid, rec.Header().Get(DefaultRIDHeader))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 func (s *Server) handleShutdown(halt context.CancelFunc) func(*gin.Context) {
@@ -13,3 +14,38 @@ func (s *Server) handleShutdown(halt context.CancelFunc) func(*gin.Context) {
 		c.JSON(http.StatusOK, "shutting down")
 	}
 }
+
+// shutdownContext returns the context bounding the graceful shutdown of the
+// server, which never expires without a shutdown timeout
+func (s *Server) shutdownContext() (context.Context, context.CancelFunc) {
+	if s.shutdownTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), s.shutdownTimeout)
+}
+
+// waitShutdown runs stop and waits for it to return until ctx is done, in
+// which case stop is left running and the error of ctx is returned
+func waitShutdown(ctx context.Context, stop func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- stop()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopGRPC stops gs gracefully, closing the streams still open once ctx is done
+func stopGRPC(ctx context.Context, gs *grpc.Server) {
+	err := waitShutdown(ctx, func() error {
+		gs.GracefulStop()
+		return nil
+	})
+	if err != nil {
+		gs.Stop()
+	}
+}
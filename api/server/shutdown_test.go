@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitShutdown(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := waitShutdown(ctx, func() error { return nil }); err != nil {
+		t.Fatalf("Expected a stop completing in time to succeed, got %v", err)
+	}
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	err := waitShutdown(ctx, func() error {
+		<-stuck
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected a stop outliving the shutdown timeout to time out, got %v", err)
+	}
+}
+
+func TestShutdownContext(t *testing.T) {
+	s := &Server{}
+	ctx, cancel := s.shutdownContext()
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("Expected no deadline without a shutdown timeout")
+	}
+
+	s.shutdownTimeout = time.Minute
+	ctx, cancel = s.shutdownContext()
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		t.Fatal("Expected the shutdown timeout to set a deadline")
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// sloBuckets is the number of buckets the rolling window of a SLO is split
+// in, calls leave the window a bucket at a time
+const sloBuckets = 60
+
+var (
+	sloBudgetMeasure = stats.Float64("slo_error_budget_remaining", "share of the error budget of the slo of a fn left over its window", "")
+	sloFnKey         = common.MakeKey("fn_id")
+	sloObjectiveKey  = common.MakeKey("objective")
+)
+
+// sloBucket counts the calls of a fn completed in a slice of its window
+type sloBucket struct {
+	slice                 int64 // unix nanos of the start of the slice / its width
+	calls, failures, slow int64
+}
+
+type sloWindow struct {
+	slo     models.SLO
+	buckets [sloBuckets]sloBucket
+}
+
+// sloTracker counts the calls of fns with a SLO which complete on the server,
+// on the agent of full and lb nodes and through the hybrid API of api nodes.
+// Each server only knows of the calls it saw.
+type sloTracker struct {
+	mu  sync.Mutex
+	fns map[string]*sloWindow
+}
+
+var _ fnext.CallListener = new(sloTracker)
+
+func newSLOTracker() *sloTracker {
+	return &sloTracker{fns: make(map[string]*sloWindow)}
+}
+
+// BeforeCall implements fnext.CallListener
+func (t *sloTracker) BeforeCall(ctx context.Context, call *models.Call) error {
+	return nil
+}
+
+// AfterCall implements fnext.CallListener, counting the call against the SLO
+// of its fn and recording the budgets left of the fn
+func (t *sloTracker) AfterCall(ctx context.Context, call *models.Call) error {
+	slo, err := models.SLOFromAnnotations(call.Annotations)
+	if err != nil || slo == nil || call.Status == "cancelled" {
+		return nil
+	}
+	now := time.Now()
+	failed := call.Status != "success"
+	slow := !failed && slo.LatencyMsecs > 0 &&
+		time.Time(call.CompletedAt).Sub(time.Time(call.CreatedAt)) > time.Duration(slo.LatencyMsecs)*time.Millisecond
+
+	t.mu.Lock()
+	w, ok := t.fns[call.FnID]
+	if !ok || w.slo != *slo {
+		// the slo changed, the calls counted were against the previous one
+		w = &sloWindow{slo: *slo}
+		t.fns[call.FnID] = w
+	}
+	b := w.bucket(now)
+	b.calls++
+	if failed {
+		b.failures++
+	}
+	if slow {
+		b.slow++
+	}
+	status := w.status(call.FnID, now)
+	t.mu.Unlock()
+
+	recordSLOStatus(ctx, status)
+	return nil
+}
+
+// status returns the status of fnID against slo, the calls counted against
+// another SLO of the fn are not
+func (t *sloTracker) status(fnID string, slo *models.SLO) *models.SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.fns[fnID]
+	if !ok || w.slo != *slo {
+		return models.NewSLOStatus(fnID, slo, 0, 0, 0)
+	}
+	return w.status(fnID, time.Now())
+}
+
+// width is the duration of a bucket of the window
+func (w *sloWindow) width() int64 {
+	return int64(time.Duration(w.slo.WindowSecs) * time.Second / sloBuckets)
+}
+
+// bucket returns the bucket of now, reset if it last counted an older slice
+func (w *sloWindow) bucket(now time.Time) *sloBucket {
+	slice := now.UnixNano() / w.width()
+	b := &w.buckets[slice%sloBuckets]
+	if b.slice != slice {
+		*b = sloBucket{slice: slice}
+	}
+	return b
+}
+
+func (w *sloWindow) status(fnID string, now time.Time) *models.SLOStatus {
+	slice := now.UnixNano() / w.width()
+	var calls, failures, slow int64
+	for _, b := range w.buckets {
+		if b.slice > slice-sloBuckets {
+			calls += b.calls
+			failures += b.failures
+			slow += b.slow
+		}
+	}
+	slo := w.slo
+	return models.NewSLOStatus(fnID, &slo, calls, failures, slow)
+}
+
+func recordSLOStatus(ctx context.Context, status *models.SLOStatus) {
+	for objective, budget := range map[string]*float64{"availability": status.AvailabilityBudget, "latency": status.LatencyBudget} {
+		if budget == nil {
+			continue
+		}
+		ctx, err := tag.New(ctx, tag.Upsert(sloFnKey, status.FnID), tag.Upsert(sloObjectiveKey, objective))
+		if err != nil {
+			continue
+		}
+		stats.Record(ctx, sloBudgetMeasure.M(*budget))
+	}
+}
+
+// handleFnSLO returns the compliance of the calls of a fn with its SLO over
+// its rolling window, see models.FnSLOAnnotation
+func (s *Server) handleFnSLO(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fn, err := s.datastore.GetFnByID(ctx, c.Param(api.ParamFnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	slo, err := models.SLOFromAnnotations(fn.Annotations)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if slo == nil {
+		handleErrorResponse(c, models.ErrFnsMissingSLO)
+		return
+	}
+
+	status := s.slos.status(fn.ID, slo)
+	recordSLOStatus(ctx, status)
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestFnSLO(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	annotations, _ := models.Annotations{}.With(models.FnSLOAnnotation, json.RawMessage(`{"availability":0.5,"latency_msecs":100,"latency_target":0.5}`))
+	fn := &models.Fn{ID: "fn_id", AppID: app.ID, Name: "myfn", Image: "fnproject/hello", Annotations: annotations}
+	other := &models.Fn{ID: "other_id", AppID: app.ID, Name: "other", Image: "fnproject/hello"}
+	srv := testServer(datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn, other}), &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	now := time.Now()
+	for _, c := range []struct {
+		status  string
+		latency time.Duration
+	}{
+		{"success", time.Millisecond},
+		{"success", time.Millisecond},
+		{"success", time.Second},
+		{"error", time.Millisecond},
+		{"cancelled", time.Millisecond},
+	} {
+		call := &models.Call{
+			FnID:        fn.ID,
+			Status:      c.status,
+			Annotations: annotations,
+			CreatedAt:   common.DateTime(now.Add(-c.latency)),
+			CompletedAt: common.DateTime(now),
+		}
+		srv.slos.AfterCall(context.Background(), call)
+	}
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/fns/"+fn.ID+"/slo", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected success, got %d %s", rec.Code, rec.Body.String())
+	}
+	var status models.SLOStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Calls != 4 || status.Failures != 1 || status.Slow != 1 {
+		t.Fatalf("Expected 4 calls, 1 failed and 1 slow, got %+v", status)
+	}
+	if status.AvailabilityBudget == nil || *status.AvailabilityBudget != 0.5 || status.LatencyBudget == nil || *status.LatencyBudget > 0.34 || *status.LatencyBudget < 0.33 {
+		t.Fatalf("Unexpected budgets %+v", status)
+	}
+
+	// a new slo starts counting afresh
+	fn.Annotations, _ = annotations.With(models.FnSLOAnnotation, json.RawMessage(`{"availability":0.9}`))
+	srv.datastore.UpdateFn(context.Background(), fn)
+	_, rec = routerRequest(t, srv.Router, http.MethodGet, "/v2/fns/"+fn.ID+"/slo", nil)
+	status = models.SLOStatus{}
+	json.NewDecoder(rec.Body).Decode(&status)
+	if rec.Code != http.StatusOK || status.Calls != 0 || status.ErrorBudgetRemaining != 1 {
+		t.Fatalf("Expected no calls against the new slo, got %d %+v", rec.Code, status)
+	}
+
+	for path, code := range map[string]int{
+		"/v2/fns/" + other.ID + "/slo": http.StatusNotFound,
+		"/v2/fns/nope/slo":             http.StatusNotFound,
+	} {
+		if _, rec := routerRequest(t, srv.Router, http.MethodGet, path, nil); rec.Code != code {
+			t.Errorf("%s: expected %d, got %d", path, code, rec.Code)
+		}
+	}
+}
@@ -33,16 +33,16 @@ func (s *Server) handleTriggerCreate(c *gin.Context) {
 	app, err := s.datastore.GetAppByID(ctx, triggerCreated.AppID)
 	if err != nil {
 		log.Debugln(fmt.Errorf("unexpected error - trigger app not available: %s", err))
-		c.JSON(http.StatusOK, triggerCreated)
+		c.JSON(http.StatusOK, redactTrigger(triggerCreated))
 		return
 	}
 
 	triggerAnnotated, err := s.triggerAnnotator.AnnotateTrigger(c, app, triggerCreated)
 	if err != nil {
 		log.Debugln("Failed to annotate trigger on cration")
-		c.JSON(http.StatusOK, triggerCreated)
+		c.JSON(http.StatusOK, redactTrigger(triggerCreated))
 		return
 	}
 
-	c.JSON(http.StatusOK, triggerAnnotated)
+	c.JSON(http.StatusOK, redactTrigger(triggerAnnotated))
 }
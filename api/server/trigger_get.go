@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -30,5 +31,12 @@ func (s *Server) handleTriggerGet(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, trigger)
+	c.JSON(http.StatusOK, redactTrigger(trigger))
+}
+
+// redactTrigger returns t without the secrets its annotations may hold
+func redactTrigger(t *models.Trigger) *models.Trigger {
+	redacted := t.Clone()
+	redacted.Annotations = models.RedactInvokePolicies(t.Annotations)
+	return redacted
 }
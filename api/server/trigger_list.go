@@ -51,7 +51,7 @@ func (s *Server) handleTriggerList(c *gin.Context) {
 			handleErrorResponse(c, err)
 			return
 		}
-		triggers.Items[idx] = newT
+		triggers.Items[idx] = redactTrigger(newT)
 	}
 
 	c.JSON(http.StatusOK, triggers)
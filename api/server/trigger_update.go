@@ -38,5 +38,5 @@ func (s *Server) handleTriggerUpdate(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, triggerUpdated)
+	c.JSON(http.StatusOK, redactTrigger(triggerUpdated))
 }
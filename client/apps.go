@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// AppListOptions filters the apps listed
+type AppListOptions struct {
+	ListOptions
+	Name        string
+	NamespaceID string
+}
+
+// ListApps returns a page of apps, see Apps to iterate over all of them
+func (c *Client) ListApps(ctx context.Context, opts *AppListOptions) (*models.AppList, error) {
+	if opts == nil {
+		opts = &AppListOptions{}
+	}
+	q := opts.query()
+	if opts.Name != "" {
+		q.Set("name", opts.Name)
+	}
+	if opts.NamespaceID != "" {
+		q.Set("namespace_id", opts.NamespaceID)
+	}
+	var list models.AppList
+	if err := c.do(ctx, http.MethodGet, "/v2/apps", q, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// AppIterator iterates over the apps of a list, fetching its pages as needed
+type AppIterator struct {
+	iterator
+	page []*models.App
+}
+
+// Apps returns an iterator over the apps matching opts, from opts.Cursor on
+func (c *Client) Apps(ctx context.Context, opts *AppListOptions) *AppIterator {
+	o := AppListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	it := &AppIterator{}
+	it.iterator = iterator{ctx: ctx, cursor: o.Cursor, fetch: func(ctx context.Context, cursor string) (int, string, error) {
+		o.Cursor = cursor
+		list, err := c.ListApps(ctx, &o)
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = list.Items
+		return len(list.Items), list.NextCursor, nil
+	}}
+	return it
+}
+
+// Next advances to the next app, it returns false when there are no more
+// apps or an error occurred, see Err
+func (it *AppIterator) Next() bool { return it.next() }
+
+// App returns the current app
+func (it *AppIterator) App() *models.App { return it.page[it.i-1] }
+
+// CreateApp creates app and returns it as stored
+func (c *Client) CreateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	var created models.App
+	if err := c.do(ctx, http.MethodPost, "/v2/apps", nil, app, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetApp returns the app appID
+func (c *Client) GetApp(ctx context.Context, appID string) (*models.App, error) {
+	var app models.App
+	if err := c.do(ctx, http.MethodGet, "/v2/apps/"+appID, nil, nil, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// GetAppByName returns the app named name, or a 404 *Error
+func (c *Client) GetAppByName(ctx context.Context, name string) (*models.App, error) {
+	list, err := c.ListApps(ctx, &AppListOptions{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, &Error{StatusCode: http.StatusNotFound, Message: models.ErrAppsNotFound.Error()}
+	}
+	return list.Items[0], nil
+}
+
+// UpdateApp updates the app appID with the fields set in app, config and
+// annotation keys set to empty values are removed
+func (c *Client) UpdateApp(ctx context.Context, appID string, app *models.App) (*models.App, error) {
+	var updated models.App
+	if err := c.do(ctx, http.MethodPut, "/v2/apps/"+appID, nil, app, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteApp deletes the app appID with its fns and triggers
+func (c *Client) DeleteApp(ctx context.Context, appID string) error {
+	return c.do(ctx, http.MethodDelete, "/v2/apps/"+appID, nil, nil, nil)
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// CallListOptions filters the calls listed
+type CallListOptions struct {
+	ListOptions
+	Status     string
+	ErrorClass string
+	// FromTime and ToTime bound the creation time of the calls, to the second
+	FromTime time.Time
+	ToTime   time.Time
+}
+
+// ListCalls returns a page of the calls of the fn fnID, newest first, see
+// Calls to iterate over all of them
+func (c *Client) ListCalls(ctx context.Context, fnID string, opts *CallListOptions) (*models.CallList, error) {
+	if opts == nil {
+		opts = &CallListOptions{}
+	}
+	q := opts.query()
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
+	}
+	if opts.ErrorClass != "" {
+		q.Set("error_class", opts.ErrorClass)
+	}
+	if !opts.FromTime.IsZero() {
+		q.Set("from_time", strconv.FormatInt(opts.FromTime.Unix(), 10))
+	}
+	if !opts.ToTime.IsZero() {
+		q.Set("to_time", strconv.FormatInt(opts.ToTime.Unix(), 10))
+	}
+	var list models.CallList
+	if err := c.do(ctx, http.MethodGet, "/v2/fns/"+fnID+"/calls", q, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// CallIterator iterates over the calls of a list, fetching its pages as needed
+type CallIterator struct {
+	iterator
+	page []*models.Call
+}
+
+// Calls returns an iterator over the calls of the fn fnID matching opts, from
+// opts.Cursor on
+func (c *Client) Calls(ctx context.Context, fnID string, opts *CallListOptions) *CallIterator {
+	o := CallListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	it := &CallIterator{}
+	it.iterator = iterator{ctx: ctx, cursor: o.Cursor, fetch: func(ctx context.Context, cursor string) (int, string, error) {
+		o.Cursor = cursor
+		list, err := c.ListCalls(ctx, fnID, &o)
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = list.Items
+		return len(list.Items), list.NextCursor, nil
+	}}
+	return it
+}
+
+// Next advances to the next call, it returns false when there are no more
+// calls or an error occurred, see Err
+func (it *CallIterator) Next() bool { return it.next() }
+
+// Call returns the current call
+func (it *CallIterator) Call() *models.Call { return it.page[it.i-1] }
+
+// GetCall returns the call callID of the fn fnID
+func (c *Client) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	var call models.Call
+	if err := c.do(ctx, http.MethodGet, "/v2/fns/"+fnID+"/calls/"+callID, nil, nil, &call); err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+// GetCallLog returns the log of the call callID of the fn fnID
+func (c *Client) GetCallLog(ctx context.Context, fnID, callID string) (string, error) {
+	var log struct {
+		Log string `json:"log"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v2/fns/"+fnID+"/calls/"+callID+"/log", nil, nil, &log); err != nil {
+		return "", err
+	}
+	return log.Log, nil
+}
+
+// CancelCall cancels the queued or running call callID of the fn fnID and
+// returns it
+func (c *Client) CancelCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	var call models.Call
+	q := url.Values{"fn_id": {fnID}}
+	if err := c.do(ctx, http.MethodDelete, "/v2/calls/"+callID, q, nil, &call); err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
@@ -0,0 +1,223 @@
+// Package client is a Go client of the Fn API, for managing apps, fns and
+// triggers, reading calls and invoking fns.
+//
+// Requests retry with exponential backoff on network errors and on 429, 502,
+// 503 and 504 responses, and stop when their context is done. Requests which
+// are not idempotent, such as creates and invokes, are only retried if they
+// were rejected before being processed, see Retry.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// errBodyConsumed is returned by the body of requests which cannot be sent
+// again, whose failures are not retried
+var errBodyConsumed = errors.New("request body already sent")
+
+// Client is a client of the Fn API, safe for concurrent use
+type Client struct {
+	endpoint    *url.URL
+	http        *http.Client
+	token       string
+	namespaceID string
+	retry       Retry
+	userAgent   string
+}
+
+// Option configures a Client
+type Option func(*Client) error
+
+// WithHTTPClient makes requests with hc instead of http.DefaultClient
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		c.http = hc
+		return nil
+	}
+}
+
+// WithToken authenticates requests with the bearer token, a static key or an
+// API key
+func WithToken(token string) Option {
+	return func(c *Client) error {
+		c.token = token
+		return nil
+	}
+}
+
+// WithNamespace scopes requests to the namespace nsID
+func WithNamespace(nsID string) Option {
+	return func(c *Client) error {
+		c.namespaceID = nsID
+		return nil
+	}
+}
+
+// WithRetry replaces DefaultRetry, Retry{} disables retries
+func WithRetry(r Retry) Option {
+	return func(c *Client) error {
+		if r.MaxAttempts < 0 || r.MinBackoff < 0 || r.MaxBackoff < r.MinBackoff {
+			return errors.New("invalid retry policy")
+		}
+		c.retry = r
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests
+func WithUserAgent(ua string) Option {
+	return func(c *Client) error {
+		c.userAgent = ua
+		return nil
+	}
+}
+
+// New returns a client of the Fn server at endpoint, e.g.
+// http://localhost:8080
+func New(endpoint string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint %q, must be an http or https url", endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		endpoint:  u,
+		http:      http.DefaultClient,
+		retry:     DefaultRetry,
+		userAgent: "fn-go-client",
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Error is returned for requests the server answered with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("fn: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("fn: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns whether err is a 404 response
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	u := *c.endpoint
+	u.Path += path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// send makes a request with the body produced by body, retrying as c.retry
+// allows, and returns the response on success statuses, or an *Error
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, body func() (io.Reader, error)) (*http.Response, error) {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		var r io.Reader
+		if body != nil {
+			var err error
+			r, err = body()
+			if err == errBodyConsumed {
+				return nil, lastErr
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		req, err := http.NewRequest(method, c.url(path, query), r)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		if c.namespaceID != "" {
+			req.Header.Set(models.NamespaceHeader, c.namespaceID)
+		}
+
+		resp, err := c.http.Do(req)
+		if err == nil && resp.StatusCode < 400 {
+			return resp, nil
+		}
+		if err == nil {
+			err = responseError(resp)
+		}
+		lastErr = err
+		wait, retry := c.retry.backoff(attempt, method, resp)
+		if !retry || ctx.Err() != nil {
+			return nil, err
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do makes a request with in as its JSON body, if not nil, and decodes the
+// JSON body of the response into out, if not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body func() (io.Reader, error)
+	header := http.Header{"Accept": {"application/json"}}
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = func() (io.Reader, error) { return bytes.NewReader(b), nil }
+		header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.send(ctx, method, path, query, header, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func responseError(resp *http.Response) error {
+	defer resp.Body.Close()
+	e := &Error{StatusCode: resp.StatusCode}
+	var body models.Error
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(b, &body) == nil {
+		e.Message = body.Message
+	}
+	return e
+}
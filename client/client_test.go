@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+var testRetry = Retry{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c, err := New(srv.URL, WithToken("secret"), WithRetry(testRetry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestApps(t *testing.T) {
+	apps := []*models.App{{ID: "a1", Name: "one"}, {ID: "a2", Name: "two"}, {ID: "a3", Name: "three"}}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v2/apps":
+			// pages of 2 apps, the cursor is the index of the first one
+			i := 0
+			if r.URL.Query().Get("cursor") == "2" {
+				i = 2
+			}
+			list := &models.AppList{Items: apps[i:]}
+			if i == 0 {
+				list.Items, list.NextCursor = apps[:2], "2"
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/apps":
+			var app models.App
+			json.NewDecoder(r.Body).Decode(&app)
+			app.ID = "new"
+			json.NewEncoder(w).Encode(&app)
+		case r.Method == http.MethodGet && r.URL.Path == "/v2/apps/missing":
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(&models.Error{Message: "App not found"})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	ctx := context.Background()
+
+	var names []string
+	it := c.Apps(ctx, nil)
+	for it.Next() {
+		names = append(names, it.App().Name)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "one,two,three" {
+		t.Fatalf("Expected all pages of apps, got %v", names)
+	}
+
+	app, err := c.CreateApp(ctx, &models.App{Name: "new"})
+	if err != nil || app.ID != "new" || app.Name != "new" {
+		t.Fatalf("Expected the app to be created, got %+v %v", app, err)
+	}
+
+	_, err = c.GetApp(ctx, "missing")
+	if !IsNotFound(err) || !strings.Contains(err.Error(), "App not found") {
+		t.Fatalf("Expected the app not to be found, got %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&attempts, 1)
+		switch r.URL.Path {
+		case "/v2/fns/flaky":
+			if n < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(&models.Fn{ID: "flaky"})
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+	ctx := context.Background()
+
+	if fn, err := c.GetFn(ctx, "flaky"); err != nil || fn.ID != "flaky" || attempts != 3 {
+		t.Fatalf("Expected the fn after 3 attempts, got %+v %v after %d", fn, err, attempts)
+	}
+
+	// creates are not retried on errors which may have come after the fn
+	// was stored
+	attempts = 0
+	if _, err := c.CreateFn(ctx, &models.Fn{Name: "f"}); err == nil || attempts != 1 {
+		t.Fatalf("Expected a single failed attempt, got %v after %d", err, attempts)
+	}
+	attempts = 0
+	if _, err := c.GetFn(ctx, "broken"); err == nil || attempts != 3 {
+		t.Fatalf("Expected %d failed attempts, got %v after %d", testRetry.MaxAttempts, err, attempts)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.GetFn(cancelled, "flaky"); err == nil {
+		t.Fatal("Expected requests with a done context to fail")
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if r.URL.Path != "/invoke/fn1" || r.Header.Get(models.DeadlineHeader) == "" || r.Header.Get(models.IdempotencyKeyHeader) != "k" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Fn-Call-Id", "call1")
+		w.Write([]byte("hello " + string(b)))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	res, err := c.Invoke(ctx, "fn1", strings.NewReader("world"), &InvokeOptions{IdempotencyKey: "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Body) != "hello world" || res.CallID != "call1" || attempts != 2 {
+		t.Fatalf("Expected the body to be sent again after the rejected attempt, got %q %q after %d", res.Body, res.CallID, attempts)
+	}
+
+	// bodies which cannot be read again are not retried
+	attempts = 0
+	_, err = c.Invoke(ctx, "fn1", ioutil.NopCloser(strings.NewReader("world")), &InvokeOptions{IdempotencyKey: "k"})
+	if err == nil || attempts != 1 {
+		t.Fatalf("Expected a single failed attempt, got %v after %d", err, attempts)
+	}
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// FnListOptions filters the fns listed, AppID is required
+type FnListOptions struct {
+	ListOptions
+	AppID string
+	Name  string
+}
+
+// ListFns returns a page of fns, see Fns to iterate over all of them
+func (c *Client) ListFns(ctx context.Context, opts *FnListOptions) (*models.FnList, error) {
+	if opts == nil {
+		opts = &FnListOptions{}
+	}
+	q := opts.query()
+	if opts.AppID != "" {
+		q.Set("app_id", opts.AppID)
+	}
+	if opts.Name != "" {
+		q.Set("name", opts.Name)
+	}
+	var list models.FnList
+	if err := c.do(ctx, http.MethodGet, "/v2/fns", q, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// FnIterator iterates over the fns of a list, fetching its pages as needed
+type FnIterator struct {
+	iterator
+	page []*models.Fn
+}
+
+// Fns returns an iterator over the fns matching opts, from opts.Cursor on
+func (c *Client) Fns(ctx context.Context, opts *FnListOptions) *FnIterator {
+	o := FnListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	it := &FnIterator{}
+	it.iterator = iterator{ctx: ctx, cursor: o.Cursor, fetch: func(ctx context.Context, cursor string) (int, string, error) {
+		o.Cursor = cursor
+		list, err := c.ListFns(ctx, &o)
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = list.Items
+		return len(list.Items), list.NextCursor, nil
+	}}
+	return it
+}
+
+// Next advances to the next fn, it returns false when there are no more fns
+// or an error occurred, see Err
+func (it *FnIterator) Next() bool { return it.next() }
+
+// Fn returns the current fn
+func (it *FnIterator) Fn() *models.Fn { return it.page[it.i-1] }
+
+// CreateFn creates fn in the app fn.AppID and returns it as stored
+func (c *Client) CreateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	var created models.Fn
+	if err := c.do(ctx, http.MethodPost, "/v2/fns", nil, fn, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetFn returns the fn fnID
+func (c *Client) GetFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	var fn models.Fn
+	if err := c.do(ctx, http.MethodGet, "/v2/fns/"+fnID, nil, nil, &fn); err != nil {
+		return nil, err
+	}
+	return &fn, nil
+}
+
+// UpdateFn updates the fn fnID with the fields set in fn, config and
+// annotation keys set to empty values are removed
+func (c *Client) UpdateFn(ctx context.Context, fnID string, fn *models.Fn) (*models.Fn, error) {
+	var updated models.Fn
+	if err := c.do(ctx, http.MethodPut, "/v2/fns/"+fnID, nil, fn, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteFn deletes the fn fnID with its triggers
+func (c *Client) DeleteFn(ctx context.Context, fnID string) error {
+	return c.do(ctx, http.MethodDelete, "/v2/fns/"+fnID, nil, nil, nil)
+}
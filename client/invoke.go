@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// InvokeOptions are the options of an invocation
+type InvokeOptions struct {
+	// Type is models.TypeSync if unset, models.TypeAsync to enqueue the call
+	// or models.TypeDetached to not wait for its response
+	Type string
+	// ContentType is the content type of the body
+	ContentType string
+	// IdempotencyKey makes invocations with the same key run once
+	IdempotencyKey string
+	// Header holds more headers of the request, passed on to the fn
+	Header http.Header
+}
+
+// InvokeStream is the response of an invocation, whose body is read as the
+// fn writes it. The caller must close Body.
+type InvokeStream struct {
+	StatusCode int
+	Header     http.Header
+	// CallID is the id of the call made, see GetCall
+	CallID string
+	Body   io.ReadCloser
+}
+
+// InvokeResponse is the response of an invocation
+type InvokeResponse struct {
+	StatusCode int
+	Header     http.Header
+	// CallID is the id of the call made, see GetCall
+	CallID string
+	Body   []byte
+}
+
+// Invoke invokes the fn fnID with body, which may be nil, and returns its
+// response. The deadline of ctx, if any, is sent as the deadline of the call.
+// Invocations are only retried if body is nil or an io.Seeker.
+func (c *Client) Invoke(ctx context.Context, fnID string, body io.Reader, opts *InvokeOptions) (*InvokeResponse, error) {
+	stream, err := c.InvokeStream(ctx, fnID, body, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Body.Close()
+
+	b, err := ioutil.ReadAll(stream.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &InvokeResponse{
+		StatusCode: stream.StatusCode,
+		Header:     stream.Header,
+		CallID:     stream.CallID,
+		Body:       b,
+	}, nil
+}
+
+// InvokeStream invokes the fn fnID as Invoke does and returns as soon as the
+// response starts, for fns streaming their responses.
+func (c *Client) InvokeStream(ctx context.Context, fnID string, body io.Reader, opts *InvokeOptions) (*InvokeStream, error) {
+	if opts == nil {
+		opts = &InvokeOptions{}
+	}
+	header := http.Header{}
+	for k, v := range opts.Header {
+		header[k] = v
+	}
+	if opts.Type != "" {
+		header.Set("Fn-Invoke-Type", opts.Type)
+	}
+	if opts.ContentType != "" {
+		header.Set("Content-Type", opts.ContentType)
+	}
+	if opts.IdempotencyKey != "" {
+		header.Set(models.IdempotencyKeyHeader, opts.IdempotencyKey)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		header.Set(models.DeadlineHeader, deadline.UTC().Format(time.RFC3339Nano))
+	}
+
+	resp, err := c.send(ctx, http.MethodPost, "/invoke/"+fnID, nil, header, rewind(body))
+	if err != nil {
+		return nil, err
+	}
+	return &InvokeStream{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		CallID:     resp.Header.Get("Fn-Call-Id"),
+		Body:       resp.Body,
+	}, nil
+}
+
+// rewind returns the body of the attempts of a request with body r, which are
+// all sent r from its start if it is an io.Seeker
+func rewind(r io.Reader) func() (io.Reader, error) {
+	if r == nil {
+		return nil
+	}
+	sent := false
+	return func() (io.Reader, error) {
+		if !sent {
+			sent = true
+			return r, nil
+		}
+		s, ok := r.(io.Seeker)
+		if !ok {
+			return nil, errBodyConsumed
+		}
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+// InvokeBatch invokes the fn batch.FnID once per item of batch and returns
+// the result of every item, see models.BatchInvokeRequest. Failed items do
+// not fail the batch. Batches are not retried.
+func (c *Client) InvokeBatch(ctx context.Context, batch *models.BatchInvokeRequest) (*models.BatchInvokeResponse, error) {
+	var res models.BatchInvokeResponse
+	if err := c.do(ctx, http.MethodPost, "/invoke/batch", nil, batch, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// InvokeFanOut invokes the fns of the branches of fo in parallel and returns
+// the results of all branches, see models.FanOutRequest. Whether the fan-out
+// succeeded is told by the status of the response. Fan-outs are not retried.
+func (c *Client) InvokeFanOut(ctx context.Context, fo *models.FanOutRequest) (*models.FanOutResponse, error) {
+	var res models.FanOutResponse
+	if err := c.do(ctx, http.MethodPost, "/invoke/fanout", nil, fo, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
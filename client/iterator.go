@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// ListOptions pages lists, the server picks the page size if PerPage is 0
+type ListOptions struct {
+	Cursor  string
+	PerPage int
+}
+
+func (o *ListOptions) query() url.Values {
+	q := url.Values{}
+	if o == nil {
+		return q
+	}
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	if o.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(o.PerPage))
+	}
+	return q
+}
+
+// iterator walks the pages of a list, fetch lists the page at cursor and
+// returns its length and the cursor of the next page
+type iterator struct {
+	ctx    context.Context
+	fetch  func(ctx context.Context, cursor string) (n int, next string, err error)
+	cursor string
+	i, n   int
+	done   bool
+	err    error
+}
+
+func (it *iterator) next() bool {
+	for it.i >= it.n {
+		if it.done || it.err != nil {
+			return false
+		}
+		n, next, err := it.fetch(it.ctx, it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.i, it.n = 0, n
+		it.cursor = next
+		it.done = next == ""
+	}
+	it.i++
+	return true
+}
+
+// Err returns the error which ended the iteration, if any
+func (it *iterator) Err() error {
+	return it.err
+}
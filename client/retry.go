@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetry is the retry policy of clients made without WithRetry
+var DefaultRetry = Retry{
+	MaxAttempts: 4,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+}
+
+// Retry is the policy of retrying failed requests. Attempt n waits a random
+// time up to MinBackoff*2^(n-1), capped at MaxBackoff, or as long as the
+// Retry-After header of the response asks for if it is longer.
+//
+// GET, PUT and DELETE requests are retried on network errors and 429, 502,
+// 503 and 504 responses. Other requests are only retried on 429 and 503
+// responses, which the server returns before processing them.
+type Retry struct {
+	// MaxAttempts is the number of attempts made, 0 or 1 for no retries
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// backoff returns how long to wait before attempting again a request which
+// failed the attempt-th time with resp, nil on network errors, or false if it is not to be retried
+func (r Retry) backoff(attempt int, method string, resp *http.Response) (time.Duration, bool) {
+	if attempt >= r.MaxAttempts {
+		return 0, false
+	}
+
+	idempotent := method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+	if resp == nil {
+		if !idempotent {
+			return 0, false
+		}
+	} else {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		case http.StatusBadGateway, http.StatusGatewayTimeout:
+			if !idempotent {
+				return 0, false
+			}
+		default:
+			return 0, false
+		}
+	}
+
+	wait := r.MaxBackoff
+	if shift := uint(attempt - 1); shift < 32 && r.MinBackoff<<shift < r.MaxBackoff {
+		wait = r.MinBackoff << shift
+	}
+	if wait > 0 {
+		wait = time.Duration(rand.Int63n(int64(wait)) + 1)
+	}
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && time.Duration(secs)*time.Second > wait {
+			wait = time.Duration(secs) * time.Second
+		}
+	}
+	return wait, true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// TriggerListOptions filters the triggers listed, AppID is required
+type TriggerListOptions struct {
+	ListOptions
+	AppID string
+	FnID  string
+	Name  string
+}
+
+// ListTriggers returns a page of triggers, see Triggers to iterate over all of
+// them
+func (c *Client) ListTriggers(ctx context.Context, opts *TriggerListOptions) (*models.TriggerList, error) {
+	if opts == nil {
+		opts = &TriggerListOptions{}
+	}
+	q := opts.query()
+	if opts.AppID != "" {
+		q.Set("app_id", opts.AppID)
+	}
+	if opts.FnID != "" {
+		q.Set("fn_id", opts.FnID)
+	}
+	if opts.Name != "" {
+		q.Set("name", opts.Name)
+	}
+	var list models.TriggerList
+	if err := c.do(ctx, http.MethodGet, "/v2/triggers", q, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// TriggerIterator iterates over the triggers of a list, fetching its pages as
+// needed
+type TriggerIterator struct {
+	iterator
+	page []*models.Trigger
+}
+
+// Triggers returns an iterator over the triggers matching opts, from
+// opts.Cursor on
+func (c *Client) Triggers(ctx context.Context, opts *TriggerListOptions) *TriggerIterator {
+	o := TriggerListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	it := &TriggerIterator{}
+	it.iterator = iterator{ctx: ctx, cursor: o.Cursor, fetch: func(ctx context.Context, cursor string) (int, string, error) {
+		o.Cursor = cursor
+		list, err := c.ListTriggers(ctx, &o)
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = list.Items
+		return len(list.Items), list.NextCursor, nil
+	}}
+	return it
+}
+
+// Next advances to the next trigger, it returns false when there are no more
+// triggers or an error occurred, see Err
+func (it *TriggerIterator) Next() bool { return it.next() }
+
+// Trigger returns the current trigger
+func (it *TriggerIterator) Trigger() *models.Trigger { return it.page[it.i-1] }
+
+// CreateTrigger creates trigger and returns it as stored
+func (c *Client) CreateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	var created models.Trigger
+	if err := c.do(ctx, http.MethodPost, "/v2/triggers", nil, trigger, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetTrigger returns the trigger triggerID
+func (c *Client) GetTrigger(ctx context.Context, triggerID string) (*models.Trigger, error) {
+	var trigger models.Trigger
+	if err := c.do(ctx, http.MethodGet, "/v2/triggers/"+triggerID, nil, nil, &trigger); err != nil {
+		return nil, err
+	}
+	return &trigger, nil
+}
+
+// UpdateTrigger updates the trigger triggerID with the fields set in trigger
+func (c *Client) UpdateTrigger(ctx context.Context, triggerID string, trigger *models.Trigger) (*models.Trigger, error) {
+	var updated models.Trigger
+	if err := c.do(ctx, http.MethodPut, "/v2/triggers/"+triggerID, nil, trigger, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteTrigger deletes the trigger triggerID
+func (c *Client) DeleteTrigger(ctx context.Context, triggerID string) error {
+	return c.do(ctx, http.MethodDelete, "/v2/triggers/"+triggerID, nil, nil, nil)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/client"
+)
+
+// stage is a number of workers invoking fns back to back for a duration
+type stage struct {
+	concurrency int
+	duration    time.Duration
+}
+
+// parseStages parses a concurrency ramp of stages separated by commas, such
+// as 10:30s,50:1m which runs 10 workers for 30s and then 50 for a minute
+func parseStages(spec string) ([]stage, error) {
+	var stages []stage
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		parts := strings.SplitN(s, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid stage %q, must be concurrency:duration", s)
+		}
+		n, err := strconv.Atoi(parts[0])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid stage %q, concurrency must be a positive number", s)
+		}
+		d, err := time.ParseDuration(parts[1])
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid stage %q, duration must be positive", s)
+		}
+		stages = append(stages, stage{concurrency: n, duration: d})
+	}
+	if len(stages) == 0 {
+		return nil, errors.New("no stages to run")
+	}
+	return stages, nil
+}
+
+// parseSizes parses payload sizes in bytes separated by commas, which may end
+// with k or m for KiB and MiB, such as 0,1k,64k
+func parseSizes(spec string) ([]int, error) {
+	var sizes []int
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		unit := 1
+		switch {
+		case strings.HasSuffix(s, "k"):
+			unit, s = 1024, strings.TrimSuffix(s, "k")
+		case strings.HasSuffix(s, "m"):
+			unit, s = 1024*1024, strings.TrimSuffix(s, "m")
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid payload size %q", s)
+		}
+		sizes = append(sizes, n*unit)
+	}
+	if len(sizes) == 0 {
+		sizes = []int{0}
+	}
+	return sizes, nil
+}
+
+// workload is what the workers of each stage invoke
+type workload struct {
+	client *client.Client
+	fnIDs  []string
+	// async is the fraction of invocations which are async, the rest are sync
+	async float64
+	sizes []int
+	// timeout is the deadline of each invocation
+	timeout time.Duration
+	// seed makes the mix of fns, types and payloads the same across runs
+	seed int64
+}
+
+// result is the outcome of an invocation
+type result struct {
+	stage   int
+	typ     string
+	fnID    string
+	callID  string
+	latency time.Duration
+	// status is the status code of the response, 0 if none was received
+	status int
+	err    error
+}
+
+// run runs stages one after the other and returns the results of all of
+// their invocations
+func (w *workload) run(ctx context.Context, stages []stage) []result {
+	payloads := make([][]byte, len(w.sizes))
+	for i, size := range w.sizes {
+		payloads[i] = bytes.Repeat([]byte("x"), size)
+	}
+
+	var mu sync.Mutex
+	var results []result
+	for i, st := range stages {
+		stageCtx, cancel := context.WithTimeout(ctx, st.duration)
+		var wg sync.WaitGroup
+		for j := 0; j < st.concurrency; j++ {
+			wg.Add(1)
+			go func(rnd *rand.Rand) {
+				defer wg.Done()
+				var local []result
+				for stageCtx.Err() == nil {
+					res := w.invoke(ctx, rnd, payloads)
+					// invocations cut off by the end of the stage are not counted
+					if stageCtx.Err() != nil {
+						break
+					}
+					res.stage = i
+					local = append(local, res)
+				}
+				mu.Lock()
+				results = append(results, local...)
+				mu.Unlock()
+			}(rand.New(rand.NewSource(w.seed + int64(i)*1000003 + int64(j))))
+		}
+		wg.Wait()
+		cancel()
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return results
+}
+
+// invoke makes an invocation of the mix of the workload
+func (w *workload) invoke(ctx context.Context, rnd *rand.Rand, payloads [][]byte) result {
+	res := result{typ: models.TypeSync, fnID: w.fnIDs[rnd.Intn(len(w.fnIDs))]}
+	if rnd.Float64() < w.async {
+		res.typ = models.TypeAsync
+	}
+	payload := payloads[rnd.Intn(len(payloads))]
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := w.client.Invoke(ctx, res.fnID, bytes.NewReader(payload), &client.InvokeOptions{
+		Type:        res.typ,
+		ContentType: "application/octet-stream",
+	})
+	res.latency = time.Since(start)
+	if err != nil {
+		res.err = err
+		var e *client.Error
+		if errors.As(err, &e) {
+			res.status = e.StatusCode
+		}
+		return res
+	}
+	res.status, res.callID = resp.StatusCode, resp.CallID
+	return res
+}
+
+// countColdStarts looks up the calls of the successful sync invocations of
+// results, with at most concurrency lookups at a time, and returns the number
+// of them which started a container per stage. Calls whose container was
+// started for them have a container create timing. Lookups which failed are
+// returned as unknown.
+func (w *workload) countColdStarts(ctx context.Context, results []result, concurrency int) (cold map[int]int, unknown int) {
+	cold = make(map[int]int)
+	var mu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, res := range results {
+		if res.err != nil || res.typ != models.TypeSync || res.callID == "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(res result) {
+			defer func() { <-sem; wg.Done() }()
+			call, err := w.client.GetCall(ctx, res.fnID, res.callID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				unknown++
+				return
+			}
+			if _, ok := call.Timings[models.PhaseContainerCreate]; ok {
+				cold[res.stage]++
+			}
+		}(res)
+	}
+	wg.Wait()
+	return cold, unknown
+}
+
+// summary is the report of the invocations of a type in a stage
+type summary struct {
+	// Stage is the index of the stage from 1, 0 for all stages
+	Stage       int    `json:"stage"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	Type        string `json:"type"`
+	Calls       int    `json:"calls"`
+	Errors      int    `json:"errors"`
+	// Statuses counts the status codes of responses, 0 for invocations
+	// which got none
+	Statuses map[int]int `json:"statuses"`
+	// Throughput is in calls per second
+	Throughput float64 `json:"throughput"`
+	P50        float64 `json:"p50_ms"`
+	P90        float64 `json:"p90_ms"`
+	P95        float64 `json:"p95_ms"`
+	P99        float64 `json:"p99_ms"`
+	Max        float64 `json:"max_ms"`
+	// ColdStarts counts the sync calls which started a container, nil if
+	// they were not counted
+	ColdStarts *int `json:"cold_starts,omitempty"`
+}
+
+// summarize reports results by stage and type, with the total of all stages
+// last. cold are the cold starts per stage, if they were counted.
+func summarize(stages []stage, results []result, cold map[int]int) []summary {
+	var total time.Duration
+	for _, st := range stages {
+		total += st.duration
+	}
+
+	var sums []summary
+	add := func(stageIdx int, concurrency int, elapsed time.Duration, typ string, match func(result) bool) {
+		s := summary{Stage: stageIdx, Concurrency: concurrency, Type: typ, Statuses: make(map[int]int)}
+		var latencies []time.Duration
+		for _, res := range results {
+			if (stageIdx != 0 && res.stage != stageIdx-1) || !match(res) {
+				continue
+			}
+			s.Calls++
+			s.Statuses[res.status]++
+			if res.err != nil {
+				s.Errors++
+			}
+			latencies = append(latencies, res.latency)
+		}
+		if s.Calls == 0 {
+			return
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		s.Throughput = float64(s.Calls) / elapsed.Seconds()
+		s.P50 = msecs(percentile(latencies, 0.5))
+		s.P90 = msecs(percentile(latencies, 0.9))
+		s.P95 = msecs(percentile(latencies, 0.95))
+		s.P99 = msecs(percentile(latencies, 0.99))
+		s.Max = msecs(latencies[len(latencies)-1])
+		if cold != nil && typ != models.TypeAsync {
+			n := cold[stageIdx-1]
+			if stageIdx == 0 {
+				n = 0
+				for _, c := range cold {
+					n += c
+				}
+			}
+			s.ColdStarts = &n
+		}
+		sums = append(sums, s)
+	}
+
+	byType := func(typ string) func(result) bool {
+		return func(res result) bool { return res.typ == typ }
+	}
+	for i, st := range stages {
+		add(i+1, st.concurrency, st.duration, models.TypeSync, byType(models.TypeSync))
+		add(i+1, st.concurrency, st.duration, models.TypeAsync, byType(models.TypeAsync))
+	}
+	add(0, 0, total, "all", func(result) bool { return true })
+	return sums
+}
+
+// percentile returns the nearest rank p percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func msecs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/client"
+)
+
+func TestParse(t *testing.T) {
+	stages, err := parseStages("1:10s, 5:1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stages) != 2 || stages[0] != (stage{1, 10 * time.Second}) || stages[1] != (stage{5, time.Minute}) {
+		t.Fatalf("Unexpected stages %v", stages)
+	}
+	for _, bad := range []string{"", "10", "0:1s", "x:1s", "1:-1s"} {
+		if _, err := parseStages(bad); err == nil {
+			t.Errorf("Expected stages %q to be invalid", bad)
+		}
+	}
+
+	sizes, err := parseSizes("0,10,1k,2M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sizes) != "[0 10 1024 2097152]" {
+		t.Fatalf("Unexpected sizes %v", sizes)
+	}
+	if _, err := parseSizes("1g"); err == nil {
+		t.Error("Expected size 1g to be invalid")
+	}
+}
+
+func TestWorkload(t *testing.T) {
+	var calls int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/invoke/"):
+			id := atomic.AddInt64(&calls, 1)
+			w.Header().Set("Fn-Call-Id", fmt.Sprint(id))
+			if r.Header.Get("Fn-Invoke-Type") == models.TypeAsync {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			// every 10th call fails
+			if id%10 == 0 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+		case strings.HasPrefix(r.URL.Path, "/v2/fns/fn1/calls/"):
+			call := &models.Call{Timings: models.CallTimings{models.PhaseExecution: 1}}
+			// the first call started a container
+			if strings.HasSuffix(r.URL.Path, "/1") {
+				call.Timings[models.PhaseContainerCreate] = 5
+			}
+			json.NewEncoder(w).Encode(call)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, client.WithRetry(client.Retry{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &workload{client: c, fnIDs: []string{"fn1"}, async: 0.5, sizes: []int{0, 1024}, timeout: time.Second, seed: 1}
+	stages := []stage{{1, 50 * time.Millisecond}, {4, 50 * time.Millisecond}}
+
+	results := w.run(context.Background(), stages)
+	cold, unknown := w.countColdStarts(context.Background(), results, 4)
+	if unknown != 0 {
+		t.Fatalf("Expected all calls to be looked up, got %d unknown", unknown)
+	}
+
+	sums := summarize(stages, results, cold)
+	if len(sums) != 5 {
+		t.Fatalf("Expected sync and async summaries of each stage and a total, got %+v", sums)
+	}
+	total := sums[len(sums)-1]
+	if total.Stage != 0 || total.Calls != len(results) || total.ColdStarts == nil {
+		t.Fatalf("Unexpected total %+v", total)
+	}
+	var sync, async, coldStarts int
+	for _, s := range sums[:len(sums)-1] {
+		switch s.Type {
+		case models.TypeSync:
+			sync += s.Calls
+			coldStarts += *s.ColdStarts
+			if s.Errors != s.Statuses[http.StatusBadGateway] || s.Statuses[http.StatusOK] != s.Calls-s.Errors {
+				t.Fatalf("Unexpected sync statuses %+v", s)
+			}
+		case models.TypeAsync:
+			async += s.Calls
+			if s.Errors != 0 || s.ColdStarts != nil {
+				t.Fatalf("Unexpected async summary %+v", s)
+			}
+		}
+		if s.P50 > s.P99 || s.P99 > s.Max {
+			t.Fatalf("Expected percentiles to be ordered, got %+v", s)
+		}
+	}
+	if sync == 0 || async == 0 || sync+async != total.Calls {
+		t.Fatalf("Expected a mix of sync and async calls, got %d sync and %d async of %d", sync, async, total.Calls)
+	}
+	// the first call may have been async, whose cold start is not counted
+	var want int
+	for _, res := range results {
+		if res.callID == "1" && res.typ == models.TypeSync {
+			want = 1
+		}
+	}
+	if coldStarts != want || *total.ColdStarts != want {
+		t.Fatalf("Expected the cold start of the first call to be counted, got %d", coldStarts)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{0: time.Millisecond, 0.5: 50 * time.Millisecond, 0.99: 99 * time.Millisecond, 1: 100 * time.Millisecond} {
+		if got := percentile(latencies, p); got != want {
+			t.Errorf("Expected p%v to be %v, got %v", p*100, want, got)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Command fn-bench drives invocation workloads against an Fn server or lb and
+// reports their latency percentiles, errors and cold starts, so that the
+// performance of releases can be compared.
+//
+// Workers invoke the fns back to back, with a mix of sync and async
+// invocations and payload sizes, in stages of increasing concurrency:
+//
+//	fn-bench -fn 01ABC -stages 10:30s,50:1m,100:1m -async 0.2 -payload-sizes 0,1k,64k
+//
+// Cold starts are counted from the call records of sync calls, which are
+// looked up after the stages have run from the API of -api, the endpoint if
+// unset. Lbs do not serve call records, set -api to a server with the same
+// log store or -cold-starts=false.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fnproject/fn/client"
+)
+
+func main() {
+	endpoint := flag.String("endpoint", envOr("FN_API_URL", "http://localhost:8080"), "url of the server or lb to invoke fns on")
+	api := flag.String("api", "", "url of the server to look up calls on, the endpoint if unset")
+	token := flag.String("token", os.Getenv("FN_TOKEN"), "token to authenticate requests with")
+	fns := flag.String("fn", "", "ids of the fns to invoke, separated by commas, picked at random for each invocation")
+	stagesSpec := flag.String("stages", "10:30s", "stages of concurrency:duration separated by commas, run one after the other")
+	async := flag.Float64("async", 0, "fraction of invocations which are async, between 0 and 1")
+	sizesSpec := flag.String("payload-sizes", "0", "sizes of the payloads of invocations in bytes, with k or m suffixes, separated by commas, picked at random for each invocation")
+	timeout := flag.Duration("timeout", time.Minute, "deadline of each invocation")
+	seed := flag.Int64("seed", 1, "seed of the mix of fns, types and payload sizes")
+	coldStarts := flag.Bool("cold-starts", true, "count the cold starts of sync calls from their call records")
+	jsonOut := flag.Bool("json", false, "report as JSON")
+	flag.Parse()
+
+	if err := run(*endpoint, *api, *token, *fns, *stagesSpec, *async, *sizesSpec, *timeout, *seed, *coldStarts, *jsonOut); err != nil {
+		fmt.Fprintln(os.Stderr, "fn-bench:", err)
+		os.Exit(1)
+	}
+}
+
+func run(endpoint, api, token, fns, stagesSpec string, async float64, sizesSpec string, timeout time.Duration, seed int64, coldStarts, jsonOut bool) error {
+	var fnIDs []string
+	for _, id := range strings.Split(fns, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			fnIDs = append(fnIDs, id)
+		}
+	}
+	if len(fnIDs) == 0 {
+		return fmt.Errorf("no fns to invoke, set -fn")
+	}
+	if async < 0 || async > 1 {
+		return fmt.Errorf("invalid -async %v, must be between 0 and 1", async)
+	}
+	stages, err := parseStages(stagesSpec)
+	if err != nil {
+		return err
+	}
+	sizes, err := parseSizes(sizesSpec)
+	if err != nil {
+		return err
+	}
+
+	// invocations are measured as they are, failures are not retried
+	opts := []client.Option{client.WithRetry(client.Retry{}), client.WithUserAgent("fn-bench")}
+	if token != "" {
+		opts = append(opts, client.WithToken(token))
+	}
+	c, err := client.New(endpoint, opts...)
+	if err != nil {
+		return err
+	}
+	w := &workload{client: c, fnIDs: fnIDs, async: async, sizes: sizes, timeout: timeout, seed: seed}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		fmt.Fprintln(os.Stderr, "fn-bench: interrupted, reporting the invocations made so far")
+		cancel()
+	}()
+
+	results := w.run(ctx, stages)
+
+	var cold map[int]int
+	if coldStarts {
+		if api != "" {
+			if w.client, err = client.New(api, opts...); err != nil {
+				return err
+			}
+		}
+		var unknown int
+		cold, unknown = w.countColdStarts(context.Background(), results, 10)
+		if unknown > 0 {
+			fmt.Fprintf(os.Stderr, "fn-bench: %d calls could not be looked up, cold starts are undercounted\n", unknown)
+		}
+	}
+
+	sums := summarize(stages, results, cold)
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sums)
+	}
+	report(os.Stdout, sums)
+	return nil
+}
+
+// report writes sums as a table
+func report(out io.Writer, sums []summary) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tCONCURRENCY\tTYPE\tCALLS\tERRORS\tCALLS/S\tP50\tP90\tP95\tP99\tMAX\tCOLD STARTS")
+	for _, s := range sums {
+		stage, concurrency, cold := "all", "-", "-"
+		if s.Stage != 0 {
+			stage, concurrency = fmt.Sprint(s.Stage), fmt.Sprint(s.Concurrency)
+		}
+		if s.ColdStarts != nil {
+			cold = fmt.Sprint(*s.ColdStarts)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%.1f\t%.1fms\t%.1fms\t%.1fms\t%.1fms\t%.1fms\t%s\n",
+			stage, concurrency, s.Type, s.Calls, s.Errors, s.Throughput, s.P50, s.P90, s.P95, s.P99, s.Max, cold)
+	}
+	tw.Flush()
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
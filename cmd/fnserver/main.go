@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"os"
 
 	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/agent/drivers/docker"
 	"github.com/fnproject/fn/api/logs/s3"
+	"github.com/fnproject/fn/api/metering"
+	"github.com/fnproject/fn/api/runnerpool"
 	"github.com/fnproject/fn/api/server"
 	// The trace package is imported in several places by different dependencies and if we don't import explicity here it is
 	// initialized every time it is imported and that creates a panic at run time as we register multiple time the handler for
@@ -17,15 +22,41 @@ import (
 )
 
 func main() {
+	configFile := flag.String("config", os.Getenv(server.EnvConfigFile), "YAML config file of the settings of the server, env takes precedence")
+	validateConfig := flag.Bool("validate-config", false, "check the config file and exit without starting the server")
+	flag.Parse()
+
+	if *validateConfig {
+		if *configFile == "" {
+			fmt.Fprintln(os.Stderr, "no config file to validate, set -config or "+server.EnvConfigFile)
+			os.Exit(2)
+		}
+		if err := server.ValidateConfigFile(*configFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(*configFile, "is valid")
+		return
+	}
+	if *configFile != "" {
+		if err := server.LoadConfigFile(*configFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
 	ctx := context.Background()
-	funcServer := server.NewFromEnv(ctx)
 
-	registerViews()
+	// views are registered first, runner pools of lb nodes report their
+	// members as they are created
+	registerViews(server.NodeTypeFromEnv())
+	funcServer := server.NewFromEnv(ctx)
 	funcServer.Start(ctx)
 }
 
-func registerViews() {
-	keys := []string{}
+func registerViews(nodeType server.NodeType) {
+	// calls are broken down by the namespace of their app, for multi-tenant deployments
+	keys := []string{"namespace_id"}
 
 	latencyDist := []float64{1, 10, 50, 100, 250, 500, 1000, 10000, 60000, 120000}
 
@@ -40,15 +71,26 @@ func registerViews() {
 	// 10% granularity buckets
 	cpuDist := []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
 
+	server.RegisterAPIViews(keys, latencyDist)
+
+	// Register s3 log views
+	s3.RegisterViews(keys, latencyDist)
+
+	// Register the views of usage records dropped
+	metering.RegisterViews(keys, latencyDist)
+
+	if nodeType == server.ServerTypeLB {
+		// lb nodes place calls on runners and run no containers themselves
+		agent.RegisterLBAgentViews(keys, latencyDist)
+		runnerpool.RegisterPlacerViews(keys, latencyDist)
+		runnerpool.RegisterRunnerViews(keys, latencyDist)
+		return
+	}
+
 	agent.RegisterAgentViews(keys, latencyDist)
 	agent.RegisterDockerViews(keys, latencyDist, ioDist, ioDist, memoryDist, cpuDist)
 	agent.RegisterContainerViews(keys, latencyDist)
 
 	// Register docker client views
 	docker.RegisterViews(keys, latencyDist)
-
-	// Register s3 log views
-	s3.RegisterViews(keys, latencyDist)
-
-	server.RegisterAPIViews(keys, latencyDist)
 }
@@ -0,0 +1,49 @@
+package fnext
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// AdmissionOperation is the change of an object an AdmissionController reviews
+type AdmissionOperation string
+
+const (
+	// AdmissionCreate reviews an object before it is created
+	AdmissionCreate AdmissionOperation = "create"
+	// AdmissionUpdate reviews an object before it is updated
+	AdmissionUpdate AdmissionOperation = "update"
+)
+
+// AdmissionReview holds the object of a create or update, exactly one of App,
+// Fn and Trigger is set. Updates hold the object as it will be stored, with
+// the patch applied.
+type AdmissionReview struct {
+	Operation AdmissionOperation `json:"operation"`
+	App       *models.App        `json:"app,omitempty"`
+	Fn        *models.Fn         `json:"fn,omitempty"`
+	Trigger   *models.Trigger    `json:"trigger,omitempty"`
+}
+
+// AdmissionController reviews the apps, fns and triggers created and updated
+// through the datastore of the server, eg. to enforce memory ceilings,
+// required annotations or image registries.
+type AdmissionController interface {
+	// Admit may mutate the object of review, the object is stored as left once
+	// all controllers admit it. Errors implementing models.APIError reject the
+	// object, other errors are failures of the controller handled by its
+	// AdmissionFailurePolicy.
+	Admit(ctx context.Context, review *AdmissionReview) error
+}
+
+// AdmissionFailurePolicy decides whether objects are admitted when their
+// AdmissionController fails
+type AdmissionFailurePolicy int
+
+const (
+	// AdmissionFailClosed rejects objects if the controller fails
+	AdmissionFailClosed AdmissionFailurePolicy = iota
+	// AdmissionFailOpen admits objects if the controller fails
+	AdmissionFailOpen
+)
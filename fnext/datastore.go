@@ -3,6 +3,7 @@ package fnext
 import (
 	"context"
 
+	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 )
 
@@ -200,3 +201,182 @@ func (e *extds) RemoveFn(ctx context.Context, fnID string) error {
 	}
 	return nil
 }
+
+// the optional stores of the wrapped Datastore are passed through, they have
+// no listeners
+
+func (e *extds) InsertNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, ok := e.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss.InsertNamespace(ctx, ns)
+}
+
+func (e *extds) UpdateNamespace(ctx context.Context, ns *models.Namespace) (*models.Namespace, error) {
+	nss, ok := e.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss.UpdateNamespace(ctx, ns)
+}
+
+func (e *extds) GetNamespaceByID(ctx context.Context, nsID string) (*models.Namespace, error) {
+	nss, ok := e.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss.GetNamespaceByID(ctx, nsID)
+}
+
+func (e *extds) GetNamespaces(ctx context.Context, filter *models.NamespaceFilter) (*models.NamespaceList, error) {
+	nss, ok := e.Datastore.(models.NamespaceStore)
+	if !ok {
+		return nil, models.ErrNamespacesUnsupported
+	}
+	return nss.GetNamespaces(ctx, filter)
+}
+
+func (e *extds) RemoveNamespace(ctx context.Context, nsID string) error {
+	nss, ok := e.Datastore.(models.NamespaceStore)
+	if !ok {
+		return models.ErrNamespacesUnsupported
+	}
+	return nss.RemoveNamespace(ctx, nsID)
+}
+
+func (e *extds) PutSecret(ctx context.Context, secret *models.StoredSecret) (*models.StoredSecret, error) {
+	ss, ok := e.Datastore.(models.SecretStore)
+	if !ok {
+		return nil, models.ErrSecretsUnsupported
+	}
+	return ss.PutSecret(ctx, secret)
+}
+
+func (e *extds) GetSecrets(ctx context.Context, appID string) ([]*models.StoredSecret, error) {
+	ss, ok := e.Datastore.(models.SecretStore)
+	if !ok {
+		return nil, models.ErrSecretsUnsupported
+	}
+	return ss.GetSecrets(ctx, appID)
+}
+
+func (e *extds) RemoveSecret(ctx context.Context, appID, name string) error {
+	ss, ok := e.Datastore.(models.SecretStore)
+	if !ok {
+		return models.ErrSecretsUnsupported
+	}
+	return ss.RemoveSecret(ctx, appID, name)
+}
+
+func (e *extds) InsertFnRevision(ctx context.Context, rev *models.FnRevision) (*models.FnRevision, error) {
+	rs, ok := e.Datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs.InsertFnRevision(ctx, rev)
+}
+
+func (e *extds) GetFnRevisions(ctx context.Context, fnID string) ([]*models.FnRevision, error) {
+	rs, ok := e.Datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs.GetFnRevisions(ctx, fnID)
+}
+
+func (e *extds) GetFnRevision(ctx context.Context, fnID, revisionID string) (*models.FnRevision, error) {
+	rs, ok := e.Datastore.(models.FnRevisionStore)
+	if !ok {
+		return nil, models.ErrFnRevisionsUnsupported
+	}
+	return rs.GetFnRevision(ctx, fnID, revisionID)
+}
+
+func (e *extds) InsertRoleBinding(ctx context.Context, rb *models.RoleBinding) (*models.RoleBinding, error) {
+	rbs, ok := e.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs.InsertRoleBinding(ctx, rb)
+}
+
+func (e *extds) GetRoleBindingByID(ctx context.Context, rbID string) (*models.RoleBinding, error) {
+	rbs, ok := e.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs.GetRoleBindingByID(ctx, rbID)
+}
+
+func (e *extds) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	rbs, ok := e.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return nil, models.ErrRoleBindingsUnsupported
+	}
+	return rbs.GetRoleBindings(ctx, filter)
+}
+
+func (e *extds) RemoveRoleBinding(ctx context.Context, rbID string) error {
+	rbs, ok := e.Datastore.(models.RoleBindingStore)
+	if !ok {
+		return models.ErrRoleBindingsUnsupported
+	}
+	return rbs.RemoveRoleBinding(ctx, rbID)
+}
+
+func (e *extds) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ks, ok := e.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks.InsertAPIKey(ctx, key)
+}
+
+func (e *extds) GetAPIKeyByID(ctx context.Context, keyID string) (*models.APIKey, error) {
+	ks, ok := e.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks.GetAPIKeyByID(ctx, keyID)
+}
+
+func (e *extds) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	ks, ok := e.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks.GetAPIKeys(ctx, filter)
+}
+
+func (e *extds) RevokeAPIKey(ctx context.Context, keyID string, at common.DateTime) (*models.APIKey, error) {
+	ks, ok := e.Datastore.(models.APIKeyStore)
+	if !ok {
+		return nil, models.ErrAPIKeysUnsupported
+	}
+	return ks.RevokeAPIKey(ctx, keyID, at)
+}
+
+func (e *extds) GetWorkflowState(ctx context.Context, chainID string) (*models.WorkflowState, error) {
+	ws, ok := e.Datastore.(models.WorkflowStateStore)
+	if !ok {
+		return nil, models.ErrWorkflowStatesUnsupported
+	}
+	return ws.GetWorkflowState(ctx, chainID)
+}
+
+func (e *extds) PutWorkflowState(ctx context.Context, state *models.WorkflowState) (*models.WorkflowState, error) {
+	ws, ok := e.Datastore.(models.WorkflowStateStore)
+	if !ok {
+		return nil, models.ErrWorkflowStatesUnsupported
+	}
+	return ws.PutWorkflowState(ctx, state)
+}
+
+func (e *extds) RemoveWorkflowState(ctx context.Context, chainID string) error {
+	ws, ok := e.Datastore.(models.WorkflowStateStore)
+	if !ok {
+		return models.ErrWorkflowStatesUnsupported
+	}
+	return ws.RemoveWorkflowState(ctx, chainID)
+}
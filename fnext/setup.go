@@ -20,6 +20,8 @@ type ExtServer interface {
 	AddAppListener(listener AppListener)
 	// AddCallListener adds a listener that will be invoked around any call invocations.
 	AddCallListener(listener CallListener)
+	// AddAdmissionController adds a controller reviewing apps, fns and triggers before they are created or updated.
+	AddAdmissionController(ac AdmissionController, policy AdmissionFailurePolicy)
 
 	// AddAPIMiddleware add middleware
 	AddAPIMiddleware(m Middleware)
@@ -41,4 +43,8 @@ type ExtServer interface {
 
 	// Datastore returns the Datastore Fn is using
 	Datastore() models.Datastore
+	// WorkflowStateStore returns the store workflow engines keep the state of their executions in, by call
+	// chain, see models.Call.ChainID. Its methods return models.ErrWorkflowStatesUnsupported if the
+	// Datastore does not store workflow states.
+	WorkflowStateStore() models.WorkflowStateStore
 }
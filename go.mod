@@ -41,6 +41,7 @@ require (
 	google.golang.org/api v0.0.0-20181019000435-7fb5a8353b60 // indirect
 	google.golang.org/grpc v1.15.0
 	gopkg.in/go-playground/validator.v8 v8.18.2 // indirect
+	gopkg.in/yaml.v2 v2.2.1
 )
 
 replace (
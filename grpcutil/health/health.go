@@ -0,0 +1,230 @@
+// Package health implements the standard gRPC health checking protocol,
+// grpc.health.v1.Health, so load balancers and tools like grpc_health_probe
+// can probe the grpc servers of fn. The health package of grpc-go is not
+// vendored and fn is built without protoc, the messages of health.proto are
+// maintained here by hand: they only need their protobuf struct tags to be
+// marshalled, keep them in sync with
+// https://github.com/grpc/grpc/blob/master/src/proto/grpc/health/v1/health.proto
+package health
+
+import (
+	"context"
+	"sync"
+
+	proto "github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServingStatus is the health of a service
+type ServingStatus int32
+
+const (
+	Unknown        ServingStatus = 0
+	Serving        ServingStatus = 1
+	NotServing     ServingStatus = 2
+	ServiceUnknown ServingStatus = 3 // Used only by the Watch method.
+)
+
+var servingStatusNames = map[ServingStatus]string{
+	Unknown:        "UNKNOWN",
+	Serving:        "SERVING",
+	NotServing:     "NOT_SERVING",
+	ServiceUnknown: "SERVICE_UNKNOWN",
+}
+
+func (s ServingStatus) String() string { return servingStatusNames[s] }
+
+type HealthCheckRequest struct {
+	Service              string   `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HealthCheckRequest) Reset()         { *m = HealthCheckRequest{} }
+func (m *HealthCheckRequest) String() string { return proto.CompactTextString(m) }
+func (*HealthCheckRequest) ProtoMessage()    {}
+
+type HealthCheckResponse struct {
+	Status               ServingStatus `protobuf:"varint,1,opt,name=status,proto3,enum=grpc.health.v1.HealthCheckResponse_ServingStatus" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *HealthCheckResponse) Reset()         { *m = HealthCheckResponse{} }
+func (m *HealthCheckResponse) String() string { return proto.CompactTextString(m) }
+func (*HealthCheckResponse) ProtoMessage()    {}
+
+// HealthServer is the server API for Health service.
+type HealthServer interface {
+	Check(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
+	Watch(*HealthCheckRequest, Health_WatchServer) error
+}
+
+// RegisterHealthServer registers srv on s as grpc.health.v1.Health
+func RegisterHealthServer(s *grpc.Server, srv HealthServer) {
+	s.RegisterService(&_Health_serviceDesc, srv)
+}
+
+func _Health_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HealthServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.health.v1.Health/Check",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HealthServer).Check(ctx, req.(*HealthCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Health_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(HealthCheckRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HealthServer).Watch(m, &healthWatchServer{stream})
+}
+
+type Health_WatchServer interface {
+	Send(*HealthCheckResponse) error
+	grpc.ServerStream
+}
+
+type healthWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *healthWatchServer) Send(m *HealthCheckResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Health_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "grpc.health.v1.Health",
+	HandlerType: (*HealthServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    _Health_Check_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Health_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "grpc/health/v1/health.proto",
+}
+
+// Server keeps the serving status of the services of a grpc server. The
+// empty service name stands for the server as a whole.
+type Server struct {
+	mu       sync.Mutex
+	statuses map[string]ServingStatus
+	watchers map[string]map[chan ServingStatus]struct{}
+	shutdown bool
+}
+
+var _ HealthServer = new(Server)
+
+// NewServer returns a health server where the server as a whole is serving
+func NewServer() *Server {
+	return &Server{
+		statuses: map[string]ServingStatus{"": Serving},
+		watchers: make(map[string]map[chan ServingStatus]struct{}),
+	}
+}
+
+// Check returns the status of the service of in, NotFound for an unknown one
+func (s *Server) Check(ctx context.Context, in *HealthCheckRequest) (*HealthCheckResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.statuses[in.Service]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	return &HealthCheckResponse{Status: st}, nil
+}
+
+// Watch sends the status of the service of in, and then every change of it
+// until the client goes away.
+func (s *Server) Watch(in *HealthCheckRequest, stream Health_WatchServer) error {
+	updates := make(chan ServingStatus, 1)
+	s.mu.Lock()
+	if st, ok := s.statuses[in.Service]; ok {
+		updates <- st
+	} else {
+		updates <- ServiceUnknown
+	}
+	if s.watchers[in.Service] == nil {
+		s.watchers[in.Service] = make(map[chan ServingStatus]struct{})
+	}
+	s.watchers[in.Service][updates] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers[in.Service], updates)
+		s.mu.Unlock()
+	}()
+
+	var last ServingStatus = -1
+	for {
+		select {
+		case st := <-updates:
+			if st == last {
+				continue
+			}
+			last = st
+			if err := stream.Send(&HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		}
+	}
+}
+
+// SetServingStatus sets the status of service, it is ignored after Shutdown
+func (s *Server) SetServingStatus(service string, st ServingStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return
+	}
+	s.setServingStatusLocked(service, st)
+}
+
+func (s *Server) setServingStatusLocked(service string, st ServingStatus) {
+	s.statuses[service] = st
+	for w := range s.watchers[service] {
+		// only the latest status matters to a watcher
+		select {
+		case <-w:
+		default:
+		}
+		w <- st
+	}
+}
+
+// Shutdown sets every service to NotServing for good, so clients move away
+// from a server that is draining.
+func (s *Server) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdown = true
+	for service := range s.statuses {
+		s.setServingStatusLocked(service, NotServing)
+	}
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealth(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	srv := NewServer()
+	srv.SetServingStatus("Runner", Serving)
+	RegisterHealthServer(gs, srv)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	check := func(service string) (ServingStatus, error) {
+		out := new(HealthCheckResponse)
+		err := conn.Invoke(ctx, "/grpc.health.v1.Health/Check", &HealthCheckRequest{Service: service}, out)
+		return out.Status, err
+	}
+	if st, err := check(""); err != nil || st != Serving {
+		t.Fatalf("expected the server to be serving, got %v %v", st, err)
+	}
+	if _, err := check("Other"); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected an unknown service to be not found, got %v", err)
+	}
+
+	stream, err := conn.NewStream(ctx, &_Health_serviceDesc.Streams[0], "/grpc.health.v1.Health/Watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(&HealthCheckRequest{Service: "Runner"}); err != nil {
+		t.Fatal(err)
+	}
+	stream.CloseSend()
+	recv := func() ServingStatus {
+		out := new(HealthCheckResponse)
+		if err := stream.RecvMsg(out); err != nil {
+			t.Fatal(err)
+		}
+		return out.Status
+	}
+	if st := recv(); st != Serving {
+		t.Fatalf("expected the current status first, got %v", st)
+	}
+
+	srv.Shutdown()
+	if st := recv(); st != NotServing {
+		t.Fatalf("expected watchers to see the shutdown, got %v", st)
+	}
+	srv.SetServingStatus("Runner", Serving)
+	if st, err := check("Runner"); err != nil || st != NotServing {
+		t.Fatalf("expected the status to stay not serving after a shutdown, got %v %v", st, err)
+	}
+}
@@ -0,0 +1,322 @@
+// Package reflection implements the gRPC server reflection protocol,
+// grpc.reflection.v1alpha.ServerReflection, so tools like grpcurl can list
+// and describe the services of the grpc servers of fn. The reflection package
+// of grpc-go is not vendored and fn is built without protoc, the messages of
+// reflection.proto are maintained here by hand: they only need their protobuf
+// struct tags to be marshalled, keep them in sync with
+// https://github.com/grpc/grpc/blob/master/src/proto/grpc/reflection/v1alpha/reflection.proto
+//
+// The oneofs of the protocol are plain fields here, which is the same on the
+// wire: a request without any of them set asks for the list of services.
+// Services are described from the file descriptors registered with the proto
+// package by generated code, services without one are only listed.
+package reflection
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	proto "github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type ServerReflectionRequest struct {
+	Host                      string            `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	FileByFilename            string            `protobuf:"bytes,3,opt,name=file_by_filename,json=fileByFilename,proto3" json:"file_by_filename,omitempty"`
+	FileContainingSymbol      string            `protobuf:"bytes,4,opt,name=file_containing_symbol,json=fileContainingSymbol,proto3" json:"file_containing_symbol,omitempty"`
+	FileContainingExtension   *ExtensionRequest `protobuf:"bytes,5,opt,name=file_containing_extension,json=fileContainingExtension,proto3" json:"file_containing_extension,omitempty"`
+	AllExtensionNumbersOfType string            `protobuf:"bytes,6,opt,name=all_extension_numbers_of_type,json=allExtensionNumbersOfType,proto3" json:"all_extension_numbers_of_type,omitempty"`
+	ListServices              string            `protobuf:"bytes,7,opt,name=list_services,json=listServices,proto3" json:"list_services,omitempty"`
+	XXX_NoUnkeyedLiteral      struct{}          `json:"-"`
+	XXX_unrecognized          []byte            `json:"-"`
+	XXX_sizecache             int32             `json:"-"`
+}
+
+func (m *ServerReflectionRequest) Reset()         { *m = ServerReflectionRequest{} }
+func (m *ServerReflectionRequest) String() string { return proto.CompactTextString(m) }
+func (*ServerReflectionRequest) ProtoMessage()    {}
+
+type ExtensionRequest struct {
+	ContainingType       string   `protobuf:"bytes,1,opt,name=containing_type,json=containingType,proto3" json:"containing_type,omitempty"`
+	ExtensionNumber      int32    `protobuf:"varint,2,opt,name=extension_number,json=extensionNumber,proto3" json:"extension_number,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExtensionRequest) Reset()         { *m = ExtensionRequest{} }
+func (m *ExtensionRequest) String() string { return proto.CompactTextString(m) }
+func (*ExtensionRequest) ProtoMessage()    {}
+
+type ServerReflectionResponse struct {
+	ValidHost                   string                   `protobuf:"bytes,1,opt,name=valid_host,json=validHost,proto3" json:"valid_host,omitempty"`
+	OriginalRequest             *ServerReflectionRequest `protobuf:"bytes,2,opt,name=original_request,json=originalRequest,proto3" json:"original_request,omitempty"`
+	FileDescriptorResponse      *FileDescriptorResponse  `protobuf:"bytes,4,opt,name=file_descriptor_response,json=fileDescriptorResponse,proto3" json:"file_descriptor_response,omitempty"`
+	AllExtensionNumbersResponse *ExtensionNumberResponse `protobuf:"bytes,5,opt,name=all_extension_numbers_response,json=allExtensionNumbersResponse,proto3" json:"all_extension_numbers_response,omitempty"`
+	ListServicesResponse        *ListServiceResponse     `protobuf:"bytes,6,opt,name=list_services_response,json=listServicesResponse,proto3" json:"list_services_response,omitempty"`
+	ErrorResponse               *ErrorResponse           `protobuf:"bytes,7,opt,name=error_response,json=errorResponse,proto3" json:"error_response,omitempty"`
+	XXX_NoUnkeyedLiteral        struct{}                 `json:"-"`
+	XXX_unrecognized            []byte                   `json:"-"`
+	XXX_sizecache               int32                    `json:"-"`
+}
+
+func (m *ServerReflectionResponse) Reset()         { *m = ServerReflectionResponse{} }
+func (m *ServerReflectionResponse) String() string { return proto.CompactTextString(m) }
+func (*ServerReflectionResponse) ProtoMessage()    {}
+
+type FileDescriptorResponse struct {
+	FileDescriptorProto  [][]byte `protobuf:"bytes,1,rep,name=file_descriptor_proto,json=fileDescriptorProto,proto3" json:"file_descriptor_proto,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileDescriptorResponse) Reset()         { *m = FileDescriptorResponse{} }
+func (m *FileDescriptorResponse) String() string { return proto.CompactTextString(m) }
+func (*FileDescriptorResponse) ProtoMessage()    {}
+
+type ExtensionNumberResponse struct {
+	BaseTypeName         string   `protobuf:"bytes,1,opt,name=base_type_name,json=baseTypeName,proto3" json:"base_type_name,omitempty"`
+	ExtensionNumber      []int32  `protobuf:"varint,2,rep,packed,name=extension_number,json=extensionNumber,proto3" json:"extension_number,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExtensionNumberResponse) Reset()         { *m = ExtensionNumberResponse{} }
+func (m *ExtensionNumberResponse) String() string { return proto.CompactTextString(m) }
+func (*ExtensionNumberResponse) ProtoMessage()    {}
+
+type ListServiceResponse struct {
+	Service              []*ServiceResponse `protobuf:"bytes,1,rep,name=service,proto3" json:"service,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ListServiceResponse) Reset()         { *m = ListServiceResponse{} }
+func (m *ListServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*ListServiceResponse) ProtoMessage()    {}
+
+type ServiceResponse struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ServiceResponse) Reset()         { *m = ServiceResponse{} }
+func (m *ServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*ServiceResponse) ProtoMessage()    {}
+
+type ErrorResponse struct {
+	ErrorCode            int32    `protobuf:"varint,1,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	ErrorMessage         string   `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ErrorResponse) Reset()         { *m = ErrorResponse{} }
+func (m *ErrorResponse) String() string { return proto.CompactTextString(m) }
+func (*ErrorResponse) ProtoMessage()    {}
+
+// ServerReflectionServer is the server API for ServerReflection service.
+type ServerReflectionServer interface {
+	ServerReflectionInfo(ServerReflection_ServerReflectionInfoServer) error
+}
+
+type ServerReflection_ServerReflectionInfoServer interface {
+	Send(*ServerReflectionResponse) error
+	Recv() (*ServerReflectionRequest, error)
+	grpc.ServerStream
+}
+
+type serverReflectionInfoServer struct {
+	grpc.ServerStream
+}
+
+func (x *serverReflectionInfoServer) Send(m *ServerReflectionResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *serverReflectionInfoServer) Recv() (*ServerReflectionRequest, error) {
+	m := new(ServerReflectionRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ServerReflection_ServerReflectionInfo_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ServerReflectionServer).ServerReflectionInfo(&serverReflectionInfoServer{stream})
+}
+
+var _ServerReflection_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "grpc.reflection.v1alpha.ServerReflection",
+	HandlerType: (*ServerReflectionServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ServerReflectionInfo",
+			Handler:       _ServerReflection_ServerReflectionInfo_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "grpc_reflection_v1alpha/reflection.proto",
+}
+
+// Register registers the reflection service on s, it describes the services
+// registered on s at the time of the request.
+func Register(s *grpc.Server) {
+	s.RegisterService(&_ServerReflection_serviceDesc, &serverReflection{s: s})
+}
+
+type serverReflection struct {
+	s *grpc.Server
+}
+
+func (r *serverReflection) ServerReflectionInfo(stream ServerReflection_ServerReflectionInfoServer) error {
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		out := &ServerReflectionResponse{ValidHost: in.Host, OriginalRequest: in}
+		switch {
+		case in.FileByFilename != "":
+			out.FileDescriptorResponse, err = fileDescriptorResponse(proto.FileDescriptor(in.FileByFilename))
+		case in.FileContainingSymbol != "":
+			out.FileDescriptorResponse, err = fileDescriptorResponse(r.fileContainingSymbol(in.FileContainingSymbol))
+		case in.FileContainingExtension != nil || in.AllExtensionNumbersOfType != "":
+			err = errorResponse(codes.NotFound, "extensions are not supported")
+		default:
+			out.ListServicesResponse = r.listServices()
+		}
+		if e, ok := err.(*ErrorResponse); ok {
+			out.FileDescriptorResponse = nil
+			out.ErrorResponse = e
+		}
+
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *serverReflection) listServices() *ListServiceResponse {
+	info := r.s.GetServiceInfo()
+	names := make([]string, 0, len(info))
+	for name := range info {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resp := &ListServiceResponse{}
+	for _, name := range names {
+		resp.Service = append(resp.Service, &ServiceResponse{Name: name})
+	}
+	return resp
+}
+
+// fileContainingSymbol returns the compressed descriptor of the file that
+// declares symbol, a service, one of its methods or a message type
+func (r *serverReflection) fileContainingSymbol(symbol string) []byte {
+	for name, info := range r.s.GetServiceInfo() {
+		if symbol != name && !strings.HasPrefix(symbol, name+".") {
+			continue
+		}
+		if file, ok := info.Metadata.(string); ok {
+			return proto.FileDescriptor(file)
+		}
+		return nil
+	}
+
+	t := proto.MessageType(symbol)
+	if t == nil {
+		return nil
+	}
+	if d, ok := reflect.Zero(t).Interface().(interface {
+		Descriptor() ([]byte, []int)
+	}); ok {
+		gz, _ := d.Descriptor()
+		return gz
+	}
+	return nil
+}
+
+// fileDescriptorResponse returns the file descriptor compressed in gz, along
+// with the ones of the files it imports, clients need them to make sense of it
+func fileDescriptorResponse(gz []byte) (*FileDescriptorResponse, error) {
+	if gz == nil {
+		return nil, errorResponse(codes.NotFound, "file not found")
+	}
+	resp := &FileDescriptorResponse{}
+	seen := make(map[string]bool)
+	var add func(gz []byte) error
+	add = func(gz []byte) error {
+		b, err := decompress(gz)
+		if err != nil {
+			return errorResponse(codes.Internal, err.Error())
+		}
+		resp.FileDescriptorProto = append(resp.FileDescriptorProto, b)
+		for _, dep := range fileDependencies(b) {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			if dgz := proto.FileDescriptor(dep); dgz != nil {
+				if err := add(dgz); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return resp, add(gz)
+}
+
+func decompress(gz []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(zr)
+}
+
+// fileDescriptorImports holds the dependency field of a FileDescriptorProto,
+// the files it imports, the descriptor package is not vendored
+type fileDescriptorImports struct {
+	Dependency           []string `protobuf:"bytes,3,rep,name=dependency"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *fileDescriptorImports) Reset()         { *m = fileDescriptorImports{} }
+func (m *fileDescriptorImports) String() string { return proto.CompactTextString(m) }
+func (*fileDescriptorImports) ProtoMessage()    {}
+
+func fileDependencies(b []byte) []string {
+	var f fileDescriptorImports
+	if err := proto.Unmarshal(b, &f); err != nil {
+		return nil
+	}
+	return f.Dependency
+}
+
+func (e *ErrorResponse) Error() string { return e.ErrorMessage }
+
+func errorResponse(code codes.Code, msg string) error {
+	return &ErrorResponse{ErrorCode: int32(code), ErrorMessage: msg}
+}
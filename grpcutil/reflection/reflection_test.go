@@ -0,0 +1,72 @@
+package reflection
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	_ "github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestReflection(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	Register(gs)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stream, err := conn.NewStream(ctx, &_ServerReflection_serviceDesc.Streams[0], "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ask := func(in *ServerReflectionRequest) *ServerReflectionResponse {
+		if err := stream.SendMsg(in); err != nil {
+			t.Fatal(err)
+		}
+		out := new(ServerReflectionResponse)
+		if err := stream.RecvMsg(out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	out := ask(&ServerReflectionRequest{ListServices: "*"})
+	if out.ListServicesResponse == nil || len(out.ListServicesResponse.Service) != 1 ||
+		out.ListServicesResponse.Service[0].Name != _ServerReflection_serviceDesc.ServiceName {
+		t.Fatalf("expected the reflection service to be listed, got %v", out)
+	}
+
+	out = ask(&ServerReflectionRequest{FileByFilename: "google/protobuf/empty.proto"})
+	if out.FileDescriptorResponse == nil || len(out.FileDescriptorResponse.FileDescriptorProto) != 1 {
+		t.Fatalf("expected the descriptor of a registered file, got %v", out)
+	}
+	gz, _ := decompress(proto.FileDescriptor("google/protobuf/empty.proto"))
+	if string(out.FileDescriptorResponse.FileDescriptorProto[0]) != string(gz) {
+		t.Fatal("expected the uncompressed descriptor of the file")
+	}
+
+	out = ask(&ServerReflectionRequest{FileContainingSymbol: "google.protobuf.Empty"})
+	if out.FileDescriptorResponse == nil || len(out.FileDescriptorResponse.FileDescriptorProto) != 1 {
+		t.Fatalf("expected the descriptor of the file of a message, got %v", out)
+	}
+
+	out = ask(&ServerReflectionRequest{FileContainingSymbol: "Unknown"})
+	if out.ErrorResponse == nil || out.ErrorResponse.ErrorCode != int32(codes.NotFound) || out.FileDescriptorResponse != nil {
+		t.Fatalf("expected an unknown symbol to be not found, got %v", out)
+	}
+}
@@ -0,0 +1,55 @@
+package grpcutil
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/common/tracecontext"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceStreamServerInterceptor is a gRPC stream interceptor which starts a span for the stream, as a child of the span in the trace context metadata of the client if any
+func TraceStreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	newStream := grpc_middleware.WrapServerStream(stream)
+	ctx, span := startServerSpan(stream.Context(), info.FullMethod)
+	defer span.End()
+	newStream.WrappedContext = ctx
+	return handler(srv, newStream)
+}
+
+// TraceUnaryServerInterceptor is an unary gRPC interceptor which starts a span for the call, as a child of the span in the trace context metadata of the client if any
+func TraceUnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := startServerSpan(ctx, info.FullMethod)
+	defer span.End()
+	return handler(ctx, req)
+}
+
+// TraceToOutgoingContext adds the span of ctx to the trace context metadata of the outgoing gRPC calls made with the returned context
+func TraceToOutgoingContext(ctx context.Context) context.Context {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return ctx
+	}
+	traceparent, tracestate := tracecontext.Format(span.SpanContext())
+	kv := []string{tracecontext.TraceparentHeader, traceparent}
+	if tracestate != "" {
+		kv = append(kv, tracecontext.TracestateHeader, tracestate)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func startServerSpan(ctx context.Context, method string) (context.Context, *trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if tp := md[tracecontext.TraceparentHeader]; len(tp) > 0 {
+		var ts string
+		if v := md[tracecontext.TracestateHeader]; len(v) > 0 {
+			ts = v[0]
+		}
+		if parent, ok := tracecontext.Parse(tp[0], ts); ok {
+			return trace.StartSpanWithRemoteParent(ctx, method, parent, trace.WithSpanKind(trace.SpanKindServer))
+		}
+	}
+	return trace.StartSpan(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+}
@@ -0,0 +1,48 @@
+package grpcutil
+
+import (
+	"context"
+	"testing"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTracePropagatedInMetadata(t *testing.T) {
+	ctx, parent := trace.StartSpan(context.Background(), "lb", trace.WithSampler(trace.AlwaysSample()))
+	defer parent.End()
+
+	outgoing, _ := metadata.FromOutgoingContext(TraceToOutgoingContext(ctx))
+	incomingCtx := metadata.NewIncomingContext(context.Background(), outgoing)
+
+	var child trace.SpanContext
+	_, err := TraceUnaryServerInterceptor(incomingCtx, nil, &grpc.UnaryServerInfo{FullMethod: "/test"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			child = trace.FromContext(ctx).SpanContext()
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.TraceID != parent.SpanContext().TraceID {
+		t.Fatalf("Expected trace '%s' got '%s'", parent.SpanContext().TraceID, child.TraceID)
+	}
+	if child.SpanID == parent.SpanContext().SpanID {
+		t.Fatal("Expected a new span for the call")
+	}
+}
+
+func TestTraceNotFoundInMetadata(t *testing.T) {
+	if ctx := TraceToOutgoingContext(context.Background()); ctx != context.Background() {
+		t.Fatal("Expected no metadata without a span")
+	}
+	incomingCtx := metadata.NewIncomingContext(context.Background(), metadata.New(nil))
+	TraceUnaryServerInterceptor(incomingCtx, nil, &grpc.UnaryServerInfo{FullMethod: "/test"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			if trace.FromContext(ctx) == nil {
+				t.Fatal("Expected a root span for the call")
+			}
+			return nil, nil
+		})
+}
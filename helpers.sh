@@ -34,6 +34,7 @@ function get_port {
         "fn_system_tests_minio"
         "fn_system_tests_mysql"
         "fn_system_tests_postgres"
+        "fn_basic_tests_cockroach"
     )
 
     local IDX=0
@@ -82,6 +83,17 @@ function spawn_postgres {
     echo "postgres://postgres:root@${HOST}:${PORT}/funcs?sslmode=disable"
 }
 
+function spawn_cockroach {
+    local CONTEXT=$1
+    local PORT=$(get_port ${CONTEXT}_cockroach)
+    local HOST=$(get_host ${CONTEXT}_cockroach)
+    local ID=$(docker run --name ${CONTEXT}_cockroach \
+        -p ${PORT}:26257 \
+        -d cockroachdb/cockroach:v19.2.2 start-single-node --insecure)
+
+    echo "cockroach://root@${HOST}:${PORT}/defaultdb?sslmode=disable"
+}
+
 function spawn_minio {
     local CONTEXT=$1
     local PORT=$(get_port ${CONTEXT}_minio)
@@ -109,7 +121,7 @@ function docker_pull_minio {
 
 function remove_containers {
     local CONTEXT=$1
-    for i in mysql minio postgres
+    for i in mysql minio postgres cockroach
     do
         docker rm -fv ${CONTEXT}_${i} 2>/dev/null || true
     done
@@ -9,6 +9,7 @@ remove_containers ${CONTEXT}
 export GOFLAGS=-mod=vendor
 export POSTGRES_URL=$(spawn_postgres ${CONTEXT})
 export MYSQL_URL=$(spawn_mysql ${CONTEXT})
+export COCKROACH_URL=$(spawn_cockroach ${CONTEXT})
 export MINIO_URL=$(spawn_minio ${CONTEXT})
 export FN_DS_DB_PING_MAX_RETRIES=60
 
@@ -102,6 +102,8 @@ func (c *myCall) StdErr() io.ReadWriteCloser           { return nil }
 func (c *myCall) Model() *models.Call                  { return nil }
 func (c *myCall) GetUserExecutionTime() *time.Duration { return nil }
 func (c *myCall) AddUserExecutionTime(time.Duration)   {}
+func (c *myCall) AddTimings(models.CallTimings)        {}
+func (c *myCall) SetResolvedImage(string, string)      {}
 
 func TestExecuteRunnerStatus(t *testing.T) {
 	buf := setLogBuffer()